@@ -16,6 +16,8 @@
 package cloudwatchlogsinterface
 
 import (
+	"time"
+
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
 )
@@ -42,4 +44,7 @@ type ICloudWatchLogsService interface {
 	PutLogEvents(log log.T, messages []*cloudwatchlogs.InputLogEvent, logGroup, logStream string, sequenceToken *string) (nextSequenceToken *string, err error)
 	IsLogGroupEncryptedWithKMS(log log.T, logGroupName string) bool
 	StreamData(log log.T, logGroupName string, logStreamName string, absoluteFilePath string, isFileComplete bool, isLogStreamCreated bool)
+	SetIsFileComplete(isFileComplete bool)
+	GetIsUploadComplete() bool
+	WaitForUploadComplete(timeout time.Duration) bool
 }
@@ -19,11 +19,13 @@ import (
 	"bufio"
 	"errors"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/cloudwatchlogsinterface"
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/rip"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/client"
@@ -41,6 +43,9 @@ const (
 	NewLineCharacter         = "\n"
 	maxNumberOfEventsPerCall = 4
 
+	// maxUploadBackoffIntervals is the maximum number of upload intervals skipped after consecutive upload failures
+	maxUploadBackoffIntervals = 8
+
 	// Event size - https://docs.aws.amazon.com/AmazonCloudWatch/latest/logs/cloudwatch_limits_cwl.html
 	MessageLengthThresholdInBytes = 200 * 1000
 )
@@ -49,8 +54,12 @@ const (
 type CloudWatchLogsService struct {
 	cloudWatchLogsClient cloudwatchlogsinterface.CloudWatchLogsClient
 	stopPolicy           *sdkutil.StopPolicy
-	IsFileComplete       bool
-	IsUploadComplete     bool
+	uploadFrequency      time.Duration
+
+	// completionLock guards the completion state, StreamData runs concurrently with the callers marking the file complete
+	completionLock sync.Mutex
+	isFileComplete bool
+	uploadComplete chan struct{}
 }
 
 // createCloudWatchStopPolicy creates a new policy for cloudwatchlogs
@@ -80,6 +89,9 @@ func createCloudWatchClientWithConfig(config *aws.Config) cloudwatchlogsinterfac
 	appConfig, _ := appconfig.Config(false)
 	sess := session.New(config)
 	sess.Handlers.Build.PushBack(request.MakeAddToUserAgentHandler(appConfig.Agent.Name, appConfig.Agent.Version))
+	if config.Endpoint == nil {
+		rip.UseFipsEndpoint(sess, aws.StringValue(config.Region), "logs")
+	}
 	return cloudwatchlogs.New(sess)
 }
 
@@ -88,8 +100,7 @@ func NewCloudWatchLogsService() *CloudWatchLogsService {
 	cloudWatchLogsService := CloudWatchLogsService{
 		cloudWatchLogsClient: createCloudWatchClient(),
 		stopPolicy:           createCloudWatchStopPolicy(),
-		IsFileComplete:       false,
-		IsUploadComplete:     false,
+		uploadComplete:       make(chan struct{}),
 	}
 	return &cloudWatchLogsService
 }
@@ -99,8 +110,7 @@ func NewCloudWatchLogsServiceWithCredentials(id, secret string) *CloudWatchLogsS
 	cloudWatchLogsService := CloudWatchLogsService{
 		cloudWatchLogsClient: createCloudWatchClientWithCredentials(id, secret),
 		stopPolicy:           createCloudWatchStopPolicy(),
-		IsFileComplete:       false,
-		IsUploadComplete:     false,
+		uploadComplete:       make(chan struct{}),
 	}
 	return &cloudWatchLogsService
 }
@@ -430,7 +440,7 @@ func (service *CloudWatchLogsService) IsLogGroupEncryptedWithKMS(log log.T, logG
 func (service *CloudWatchLogsService) StreamData(log log.T, logGroupName string, logStreamName string, absoluteFilePath string, isFileComplete bool, isLogStreamCreated bool) {
 	log.Debugf("Uploading logs at %s to CloudWatch", absoluteFilePath)
 
-	service.IsFileComplete = isFileComplete
+	service.SetIsFileComplete(isFileComplete)
 
 	// Keeps track of the last known line number that was successfully uploaded to CloudWatch.
 	var lastKnownLineUploadedToCWL int64 = 0
@@ -440,17 +450,29 @@ func (service *CloudWatchLogsService) StreamData(log log.T, logGroupName string,
 
 	IsLogStreamCreated := isLogStreamCreated
 
+	// Sequence token returned by the last successful upload, fetched again from the log stream when unknown.
+	var sequenceToken *string
+
+	// Number of upload intervals to skip before the next attempt, doubled on every consecutive failure.
+	backoffIntervals, skippedIntervals := 0, 0
+
 	// Initialize timer and set upload frequency.
-	ticker := time.NewTicker(UploadFrequency)
+	ticker := time.NewTicker(service.GetUploadFrequency())
 
 	for range ticker.C {
+		if skippedIntervals < backoffIntervals {
+			skippedIntervals++
+			continue
+		}
+		skippedIntervals = 0
+
 		// Get next message to be uploaded.
 		events, eof := service.getNextMessage(log, absoluteFilePath, &lastKnownLineUploadedToCWL, &currentLineNumber)
 
 		// Exit case determining that the file is complete and has been scanned till EOF.
 		if eof {
 			ticker.Stop()
-			service.IsUploadComplete = true
+			service.setUploadComplete()
 			break
 		}
 
@@ -474,6 +496,7 @@ func (service *CloudWatchLogsService) StreamData(log log.T, logGroupName string,
 			if err := service.CreateLogStream(log, logGroupName, logStreamName); err != nil {
 				log.Errorf("Error Creating Log Stream for CloudWatchLogs output: %v", err)
 				currentLineNumber = lastKnownLineUploadedToCWL
+				backoffIntervals = nextUploadBackoff(backoffIntervals)
 				log.Debug("Failed to upload message to CloudWatch")
 				continue
 			} else {
@@ -481,21 +504,108 @@ func (service *CloudWatchLogsService) StreamData(log log.T, logGroupName string,
 			}
 		}
 
-		sequenceToken := service.GetSequenceTokenForStream(log, logGroupName, logStreamName)
+		if sequenceToken == nil {
+			sequenceToken = service.GetSequenceTokenForStream(log, logGroupName, logStreamName)
+		}
 
-		_, err := service.PutLogEvents(log, events, logGroupName, logStreamName, sequenceToken)
+		nextSequenceToken, err := service.PutLogEvents(log, events, logGroupName, logStreamName, sequenceToken)
 		if err == nil {
 			// Set the last known line to current since the upload was successful.
 			lastKnownLineUploadedToCWL = currentLineNumber
+			sequenceToken = nextSequenceToken
+			backoffIntervals = 0
 			log.Debug("Successfully uploaded message to CloudWatch")
 		} else {
-			// Reset the current line to last known line since the upload failed and retry again in the next iteration.
+			// Reset the current line to last known line since the upload failed and retry again after backing off.
 			currentLineNumber = lastKnownLineUploadedToCWL
+			sequenceToken = nil
+			backoffIntervals = nextUploadBackoff(backoffIntervals)
 			log.Debug("Failed to upload message to CloudWatch")
 		}
 	}
 }
 
+// nextUploadBackoff returns the number of upload intervals to skip after another consecutive upload failure
+func nextUploadBackoff(backoffIntervals int) int {
+	if backoffIntervals == 0 {
+		return 1
+	}
+	if backoffIntervals*2 > maxUploadBackoffIntervals {
+		return maxUploadBackoffIntervals
+	}
+	return backoffIntervals * 2
+}
+
+// SetIsFileComplete marks whether the file streamed by StreamData is complete, ending the streaming once it is uploaded
+func (service *CloudWatchLogsService) SetIsFileComplete(isFileComplete bool) {
+	service.completionLock.Lock()
+	defer service.completionLock.Unlock()
+	service.isFileComplete = isFileComplete
+}
+
+// getIsFileComplete returns whether the file streamed by StreamData is complete
+func (service *CloudWatchLogsService) getIsFileComplete() bool {
+	service.completionLock.Lock()
+	defer service.completionLock.Unlock()
+	return service.isFileComplete
+}
+
+// uploadCompleteChannel returns the channel closed once StreamData has uploaded the complete file
+func (service *CloudWatchLogsService) uploadCompleteChannel() chan struct{} {
+	service.completionLock.Lock()
+	defer service.completionLock.Unlock()
+	if service.uploadComplete == nil {
+		service.uploadComplete = make(chan struct{})
+	}
+	return service.uploadComplete
+}
+
+// setUploadComplete marks the complete file uploaded, releasing the callers waiting for the upload
+func (service *CloudWatchLogsService) setUploadComplete() {
+	uploadComplete := service.uploadCompleteChannel()
+	select {
+	case <-uploadComplete:
+	default:
+		close(uploadComplete)
+	}
+}
+
+// SetUploadFrequency sets how often StreamData uploads new data of the file, UploadFrequency if not positive
+func (service *CloudWatchLogsService) SetUploadFrequency(uploadFrequency time.Duration) {
+	service.uploadFrequency = uploadFrequency
+}
+
+// GetUploadFrequency returns how often StreamData uploads new data of the file
+func (service *CloudWatchLogsService) GetUploadFrequency() time.Duration {
+	if service.uploadFrequency <= 0 {
+		return UploadFrequency
+	}
+	return service.uploadFrequency
+}
+
+// GetIsUploadComplete returns whether StreamData has uploaded the complete file
+func (service *CloudWatchLogsService) GetIsUploadComplete() bool {
+	select {
+	case <-service.uploadCompleteChannel():
+		return true
+	default:
+		return false
+	}
+}
+
+// WaitForUploadComplete blocks until StreamData has uploaded the complete file or the timeout expires,
+// returning whether the upload completed
+func (service *CloudWatchLogsService) WaitForUploadComplete(timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-service.uploadCompleteChannel():
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
 //getNextMessage gets the next message to be uploaded to cloudwatch.
 func (service *CloudWatchLogsService) getNextMessage(log log.T, absoluteFilePath string, lastKnownLineUploadedToCWL *int64, currentLineNumber *int64) (allEvents []*cloudwatchlogs.InputLogEvent, eof bool) {
 	// Open file to read.
@@ -556,7 +666,7 @@ func (service *CloudWatchLogsService) getNextMessage(log log.T, absoluteFilePath
 	}
 
 	// This determines the end of session.
-	if len(message) == 0 && scanner.Err() == nil && service.IsFileComplete {
+	if len(message) == 0 && scanner.Err() == nil && service.getIsFileComplete() {
 		eof = true
 	}
 
@@ -19,9 +19,12 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	cloudwatchlogspublisher_mock "github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/mock"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/rip"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
 	"github.com/stretchr/testify/assert"
@@ -173,7 +176,7 @@ func TestCloudWatchLogsService_getNextMessage(t *testing.T) {
 	service := CloudWatchLogsService{
 		cloudWatchLogsClient: cwLogsClientMock,
 		stopPolicy:           sdkutil.NewStopPolicy("Test", 0),
-		IsFileComplete:       true,
+		isFileComplete:       true,
 	}
 
 	fileName := "cwl_util_test_file"
@@ -257,3 +260,57 @@ func TestCloudWatchLogsService_IsLogGroupEncryptedWithKMS(t *testing.T) {
 	encrypted := service.IsLogGroupEncryptedWithKMS(logMock, "LogGroup")
 	assert.False(t, encrypted)
 }
+
+func TestNextUploadBackoff(t *testing.T) {
+	backoffIntervals := 0
+	for _, expected := range []int{1, 2, 4, 8, 8} {
+		backoffIntervals = nextUploadBackoff(backoffIntervals)
+		assert.Equal(t, expected, backoffIntervals)
+	}
+}
+
+func TestUploadFrequency(t *testing.T) {
+	service := &CloudWatchLogsService{}
+	assert.Equal(t, UploadFrequency, service.GetUploadFrequency())
+
+	service.SetUploadFrequency(time.Second)
+	assert.Equal(t, time.Second, service.GetUploadFrequency())
+
+	service.SetUploadFrequency(0)
+	assert.Equal(t, UploadFrequency, service.GetUploadFrequency())
+}
+
+func TestWaitForUploadComplete(t *testing.T) {
+	service := &CloudWatchLogsService{}
+	assert.False(t, service.GetIsUploadComplete())
+	assert.False(t, service.WaitForUploadComplete(10*time.Millisecond))
+
+	go func() {
+		service.SetIsFileComplete(true)
+		service.setUploadComplete()
+	}()
+	assert.True(t, service.WaitForUploadComplete(time.Second))
+	assert.True(t, service.GetIsUploadComplete())
+	assert.True(t, service.getIsFileComplete())
+
+	// marking the upload complete again has no effect
+	service.setUploadComplete()
+	assert.True(t, service.WaitForUploadComplete(0))
+}
+
+func TestCreateCloudWatchClientUsesFipsEndpoint(t *testing.T) {
+	fipsEnabled := rip.FipsEnabled
+	rip.FipsEnabled = func() bool { return true }
+	defer func() { rip.FipsEnabled = fipsEnabled }()
+
+	platform.SetRegion("us-west-2")
+	client := createCloudWatchClientWithCredentials("id", "secret")
+	assert.Equal(t, "https://logs-fips.us-west-2.amazonaws.com", client.(*cloudwatchlogs.CloudWatchLogs).Endpoint)
+
+	// regions without FIPS endpoints fail instead of using the regular endpoint
+	platform.SetRegion("eu-west-1")
+	client = createCloudWatchClientWithCredentials("id", "secret")
+	_, err := client.DescribeLogGroups(&cloudwatchlogs.DescribeLogGroupsInput{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no FIPS endpoint")
+}
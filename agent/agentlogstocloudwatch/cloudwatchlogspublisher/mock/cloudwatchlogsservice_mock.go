@@ -16,6 +16,8 @@
 package cloudwatchlogspublisher_mock
 
 import (
+	"time"
+
 	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/cloudwatchlogsinterface"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
@@ -162,3 +164,20 @@ func (m *CloudWatchLogsServiceMock) IsLogGroupEncryptedWithKMS(log log.T, logGro
 func (m *CloudWatchLogsServiceMock) StreamData(log log.T, logGroupName string, logStreamName string, absoluteFilePath string, isFileComplete bool, isLogStreamCreated bool) {
 	m.Called(log, logGroupName, logStreamName, absoluteFilePath, isFileComplete, isLogStreamCreated)
 }
+
+// SetIsFileComplete mocks CloudWatchLogsService SetIsFileComplete method
+func (m *CloudWatchLogsServiceMock) SetIsFileComplete(isFileComplete bool) {
+	m.Called(isFileComplete)
+}
+
+// GetIsUploadComplete mocks CloudWatchLogsService GetIsUploadComplete method
+func (m *CloudWatchLogsServiceMock) GetIsUploadComplete() bool {
+	args := m.Called()
+	return args.Get(0).(bool)
+}
+
+// WaitForUploadComplete mocks CloudWatchLogsService WaitForUploadComplete method
+func (m *CloudWatchLogsServiceMock) WaitForUploadComplete(timeout time.Duration) bool {
+	args := m.Called(timeout)
+	return args.Get(0).(bool)
+}
@@ -97,8 +97,39 @@ func DefaultConfig() SsmagentConfig {
 		CommandRetryLimit:   DefaultCommandRetryLimit,
 	}
 	var mgs = MgsConfig{
-		SessionWorkersLimit: DefaultSessionWorkersLimit,
-		StopTimeoutMillis:   DefaultStopTimeoutMillis,
+		SessionWorkersLimit:                        DefaultSessionWorkersLimit,
+		StopTimeoutMillis:                          DefaultStopTimeoutMillis,
+		PortReconnectMaxAttempts:                   DefaultPortReconnectMaxAttempts,
+		PortReconnectMaxIntervalMillis:             DefaultPortReconnectMaxIntervalMillis,
+		PortForwardingTcpNoDelay:                   true,
+		SessionRecordingMaxFileSizeMB:              DefaultSessionRecordingMaxFileSizeMB,
+		SessionRecordingMaxFiles:                   DefaultSessionRecordingMaxFiles,
+		FileTransferMaxFileSizeMB:                  DefaultFileTransferMaxFileSizeMB,
+		SessionEnvironmentVariablesDenylist:        DefaultSessionEnvironmentVariablesDenylist,
+		SessionResizeDebounceMilliseconds:          DefaultSessionResizeDebounceMilliseconds,
+		SessionWindowsCodePage:                     DefaultSessionWindowsCodePage,
+		SessionCompressionThresholdBytes:           DefaultSessionCompressionThresholdBytes,
+		StreamDataPayloadSize:                      DefaultStreamDataPayloadSize,
+		DataChannelRetransmissionWindow:            DefaultDataChannelRetransmissionWindow,
+		DataChannelRetransmissionTimeoutMultiplier: DefaultDataChannelRetransmissionTimeoutMultiplier,
+		DataChannelMaxRetransmissionTimeoutMillis:  DefaultDataChannelMaxRetransmissionTimeoutMillis,
+		WebSocketCompressionEnabled:                true,
+		WebSocketCompressionLevel:                  DefaultWebSocketCompressionLevel,
+		SessionEncryptionCipher:                    SessionEncryptionCipherAESGCM,
+		ControlChannelPingIntervalSeconds:          DefaultWebSocketPingIntervalSeconds,
+		ControlChannelPongTimeoutSeconds:           DefaultWebSocketPongTimeoutSeconds,
+		DataChannelPingIntervalSeconds:             DefaultWebSocketPingIntervalSeconds,
+		DataChannelPongTimeoutSeconds:              DefaultWebSocketPongTimeoutSeconds,
+		ControlChannelRetryInitialDelayMillis:      DefaultControlChannelRetryInitialDelayMillis,
+		ControlChannelRetryMultiplier:              DefaultChannelRetryMultiplier,
+		ControlChannelRetryMaxDelayMillis:          DefaultControlChannelRetryMaxDelayMillis,
+		ControlChannelRetryJitter:                  DefaultChannelRetryJitter,
+		DataChannelRetryInitialDelayMillis:         DefaultDataChannelRetryInitialDelayMillis,
+		DataChannelRetryMultiplier:                 DefaultChannelRetryMultiplier,
+		DataChannelRetryMaxDelayMillis:             DefaultDataChannelRetryMaxDelayMillis,
+		DataChannelRetryJitter:                     DefaultChannelRetryJitter,
+		DataChannelOutageBufferSizeMB:              DefaultDataChannelOutageBufferSizeMB,
+		DataChannelOutageSpillSizeMB:               DefaultDataChannelOutageSpillSizeMB,
 	}
 	var ssm = SsmCfg{
 		HealthFrequencyMinutes:                DefaultSsmHealthFrequencyMinutes,
@@ -108,6 +139,14 @@ func DefaultConfig() SsmagentConfig {
 		AssociationLogsRetentionDurationHours: DefaultAssociationLogsRetentionDurationHours,
 		RunCommandLogsRetentionDurationHours:  DefaultRunCommandLogsRetentionDurationHours,
 		SessionLogsRetentionDurationHours:     DefaultSessionLogsRetentionDurationHours,
+		ParallelStepsLimit:                    DefaultSsmParallelStepsLimit,
+		RunCommandOutputFlushIntervalSeconds:  DefaultRunCommandOutputFlushIntervalSeconds,
+		RunDocumentMaxDepth:                   DefaultRunDocumentMaxDepth,
+		InventoryFullUploadIntervalHours:      DefaultInventoryFullUploadIntervalHours,
+		InventoryNiceness:                     DefaultInventoryNiceness,
+		InventoryIoniceClass:                  DefaultInventoryIoniceClass,
+		InventoryFileOperationsPerSecond:      DefaultInventoryFileOperationsPerSecond,
+		InventoryGathererTimeoutSeconds:       DefaultInventoryGathererTimeoutSeconds,
 	}
 	var agent = AgentInfo{
 		Name:                 "amazon-ssm-agent",
@@ -66,7 +66,261 @@ func parser(config *SsmagentConfig) {
 		config.Ssm.RunCommandLogsRetentionDurationHours,
 		DefaultStateOrchestrationLogsRetentionDurationHoursMin,
 		DefaultRunCommandLogsRetentionDurationHours)
+	config.Ssm.ParallelStepsLimit = getNumericValue(
+		config.Ssm.ParallelStepsLimit,
+		DefaultSsmParallelStepsLimitMin,
+		DefaultSsmParallelStepsLimitMax,
+		DefaultSsmParallelStepsLimit)
+	config.Ssm.RunCommandOutputLogGroup = getStringValue(config.Ssm.RunCommandOutputLogGroup, "")
+	config.Ssm.RunCommandOutputFlushIntervalSeconds = getNumericValue(
+		config.Ssm.RunCommandOutputFlushIntervalSeconds,
+		DefaultRunCommandOutputFlushIntervalSecondsMin,
+		DefaultRunCommandOutputFlushIntervalSecondsMax,
+		DefaultRunCommandOutputFlushIntervalSeconds)
+	config.Ssm.RunCommandOutputSpillS3BucketName = getStringValue(config.Ssm.RunCommandOutputSpillS3BucketName, "")
+	config.Ssm.RunCommandDefaultRunAsUser = getStringValue(config.Ssm.RunCommandDefaultRunAsUser, "")
+	config.Ssm.DocumentWorkerSeccompProfile = getStringValue(config.Ssm.DocumentWorkerSeccompProfile, "")
+	config.Ssm.RunDocumentMaxDepth = getNumericValue(
+		config.Ssm.RunDocumentMaxDepth,
+		DefaultRunDocumentMaxDepthMin,
+		DefaultRunDocumentMaxDepthMax,
+		DefaultRunDocumentMaxDepth)
+	config.Ssm.InventoryFullUploadIntervalHours = getNumericValue(
+		config.Ssm.InventoryFullUploadIntervalHours,
+		DefaultInventoryFullUploadIntervalHoursMin,
+		DefaultInventoryFullUploadIntervalHoursMax,
+		DefaultInventoryFullUploadIntervalHours)
+	config.Ssm.InventoryNiceness = getNumericValue(
+		config.Ssm.InventoryNiceness,
+		DefaultInventoryNicenessMin,
+		DefaultInventoryNicenessMax,
+		DefaultInventoryNiceness)
+	config.Ssm.InventoryIoniceClass = getNumericValue(
+		config.Ssm.InventoryIoniceClass,
+		DefaultInventoryIoniceClassMin,
+		DefaultInventoryIoniceClassMax,
+		DefaultInventoryIoniceClass)
+	config.Ssm.InventoryFileOperationsPerSecond = getNumericValue(
+		config.Ssm.InventoryFileOperationsPerSecond,
+		DefaultInventoryFileOperationsPerSecondMin,
+		DefaultInventoryFileOperationsPerSecondMax,
+		DefaultInventoryFileOperationsPerSecond)
+	config.Ssm.InventoryGathererTimeoutSeconds = getNumericValue(
+		config.Ssm.InventoryGathererTimeoutSeconds,
+		DefaultInventoryGathererTimeoutSecondsMin,
+		DefaultInventoryGathererTimeoutSecondsMax,
+		DefaultInventoryGathererTimeoutSeconds)
+	for name, timeoutSeconds := range config.Ssm.InventoryGathererTimeouts {
+		config.Ssm.InventoryGathererTimeouts[name] = getNumericValue(
+			timeoutSeconds,
+			DefaultInventoryGathererTimeoutSecondsMin,
+			DefaultInventoryGathererTimeoutSecondsMax,
+			config.Ssm.InventoryGathererTimeoutSeconds)
+	}
 
+	// MGS config
+	config.Mgs.PortReconnectMaxAttempts = getNumericValue(
+		config.Mgs.PortReconnectMaxAttempts,
+		DefaultPortReconnectMaxAttemptsMin,
+		DefaultPortReconnectMaxAttemptsMax,
+		DefaultPortReconnectMaxAttempts)
+	config.Mgs.PortReconnectMaxIntervalMillis = getNumericValue(
+		config.Mgs.PortReconnectMaxIntervalMillis,
+		DefaultPortReconnectMaxIntervalMillisMin,
+		DefaultPortReconnectMaxIntervalMillisMax,
+		DefaultPortReconnectMaxIntervalMillis)
+	config.Mgs.PortForwardingReceiveBufferSize = getNumericValue(
+		config.Mgs.PortForwardingReceiveBufferSize,
+		DefaultPortForwardingSocketBufferSizeMin,
+		DefaultPortForwardingSocketBufferSizeMax,
+		DefaultPortForwardingSocketBufferSize)
+	config.Mgs.PortForwardingSendBufferSize = getNumericValue(
+		config.Mgs.PortForwardingSendBufferSize,
+		DefaultPortForwardingSocketBufferSizeMin,
+		DefaultPortForwardingSocketBufferSizeMax,
+		DefaultPortForwardingSocketBufferSize)
+	config.Mgs.SessionRecordingMaxFileSizeMB = getNumericValue(
+		config.Mgs.SessionRecordingMaxFileSizeMB,
+		DefaultSessionRecordingMaxFileSizeMBMin,
+		DefaultSessionRecordingMaxFileSizeMBMax,
+		DefaultSessionRecordingMaxFileSizeMB)
+	config.Mgs.SessionRecordingMaxFiles = getNumericValue(
+		config.Mgs.SessionRecordingMaxFiles,
+		DefaultSessionRecordingMaxFilesMin,
+		DefaultSessionRecordingMaxFilesMax,
+		DefaultSessionRecordingMaxFiles)
+	config.Mgs.SessionIdleTimeoutMinutes = getNumericValue(
+		config.Mgs.SessionIdleTimeoutMinutes,
+		DefaultSessionIdleTimeoutMinutesMin,
+		DefaultSessionIdleTimeoutMinutesMax,
+		DefaultSessionIdleTimeoutMinutes)
+	config.Mgs.FileTransferMaxFileSizeMB = getNumericValue(
+		config.Mgs.FileTransferMaxFileSizeMB,
+		DefaultFileTransferMaxFileSizeMBMin,
+		DefaultFileTransferMaxFileSizeMBMax,
+		DefaultFileTransferMaxFileSizeMB)
+	config.Mgs.SessionInputRateLimitBytesPerSecond = getNumericValue(
+		config.Mgs.SessionInputRateLimitBytesPerSecond,
+		DefaultSessionInputRateLimitBytesPerSecondMin,
+		DefaultSessionInputRateLimitBytesPerSecondMax,
+		DefaultSessionInputRateLimitBytesPerSecond)
+	config.Mgs.SessionMaxInputMessageSize = getNumericValue(
+		config.Mgs.SessionMaxInputMessageSize,
+		DefaultSessionMaxInputMessageSizeMin,
+		DefaultSessionMaxInputMessageSizeMax,
+		DefaultSessionMaxInputMessageSize)
+	config.Mgs.SessionResizeDebounceMilliseconds = getNumericValue(
+		config.Mgs.SessionResizeDebounceMilliseconds,
+		DefaultSessionResizeDebounceMillisecondsMin,
+		DefaultSessionResizeDebounceMillisecondsMax,
+		DefaultSessionResizeDebounceMilliseconds)
+	config.Mgs.SessionMaxTerminalColumns = getNumericValue(
+		config.Mgs.SessionMaxTerminalColumns,
+		DefaultSessionMaxTerminalSizeMin,
+		DefaultSessionMaxTerminalSizeMax,
+		DefaultSessionMaxTerminalSize)
+	config.Mgs.SessionMaxTerminalRows = getNumericValue(
+		config.Mgs.SessionMaxTerminalRows,
+		DefaultSessionMaxTerminalSizeMin,
+		DefaultSessionMaxTerminalSizeMax,
+		DefaultSessionMaxTerminalSize)
+	config.Mgs.SessionWindowsCodePage = getNumericValue(
+		config.Mgs.SessionWindowsCodePage,
+		DefaultSessionWindowsCodePageMin,
+		DefaultSessionWindowsCodePageMax,
+		DefaultSessionWindowsCodePage)
+	config.Mgs.InteractiveCommandsTimeoutSeconds = getNumericValue(
+		config.Mgs.InteractiveCommandsTimeoutSeconds,
+		DefaultInteractiveCommandsTimeoutSecondsMin,
+		DefaultInteractiveCommandsTimeoutSecondsMax,
+		DefaultInteractiveCommandsTimeoutSeconds)
+	config.Mgs.SessionDetachGracePeriodSeconds = getNumericValue(
+		config.Mgs.SessionDetachGracePeriodSeconds,
+		DefaultSessionDetachGracePeriodSecondsMin,
+		DefaultSessionDetachGracePeriodSecondsMax,
+		DefaultSessionDetachGracePeriodSeconds)
+	config.Mgs.SessionCompressionThresholdBytes = getNumericValue(
+		config.Mgs.SessionCompressionThresholdBytes,
+		DefaultSessionCompressionThresholdBytesMin,
+		DefaultSessionCompressionThresholdBytesMax,
+		DefaultSessionCompressionThresholdBytes)
+	config.Mgs.StreamDataPayloadSize = getNumericValue(
+		config.Mgs.StreamDataPayloadSize,
+		DefaultStreamDataPayloadSizeMin,
+		DefaultStreamDataPayloadSizeMax,
+		DefaultStreamDataPayloadSize)
+	config.Mgs.DataChannelRetransmissionWindow = getNumericValue(
+		config.Mgs.DataChannelRetransmissionWindow,
+		DefaultDataChannelRetransmissionWindowMin,
+		DefaultDataChannelRetransmissionWindowMax,
+		DefaultDataChannelRetransmissionWindow)
+	config.Mgs.DataChannelMaxInFlightMessages = getNumericValue(
+		config.Mgs.DataChannelMaxInFlightMessages,
+		DefaultDataChannelMaxInFlightMessagesMin,
+		DefaultDataChannelMaxInFlightMessagesMax,
+		DefaultDataChannelMaxInFlightMessages)
+	config.Mgs.DataChannelRetransmissionTimeoutMultiplier = getNumericValue(
+		config.Mgs.DataChannelRetransmissionTimeoutMultiplier,
+		DefaultDataChannelRetransmissionTimeoutMultiplierMin,
+		DefaultDataChannelRetransmissionTimeoutMultiplierMax,
+		DefaultDataChannelRetransmissionTimeoutMultiplier)
+	config.Mgs.DataChannelMaxRetransmissionTimeoutMillis = getNumericValue(
+		config.Mgs.DataChannelMaxRetransmissionTimeoutMillis,
+		DefaultDataChannelMaxRetransmissionTimeoutMillisMin,
+		DefaultDataChannelMaxRetransmissionTimeoutMillisMax,
+		DefaultDataChannelMaxRetransmissionTimeoutMillis)
+	config.Mgs.WebSocketCompressionLevel = getNumericValue(
+		config.Mgs.WebSocketCompressionLevel,
+		DefaultWebSocketCompressionLevelMin,
+		DefaultWebSocketCompressionLevelMax,
+		DefaultWebSocketCompressionLevel)
+	config.Mgs.ControlChannelPingIntervalSeconds = getNumericValue(
+		config.Mgs.ControlChannelPingIntervalSeconds,
+		DefaultWebSocketPingIntervalSecondsMin,
+		DefaultWebSocketPingIntervalSecondsMax,
+		DefaultWebSocketPingIntervalSeconds)
+	config.Mgs.ControlChannelPongTimeoutSeconds = getNumericValue(
+		config.Mgs.ControlChannelPongTimeoutSeconds,
+		DefaultWebSocketPongTimeoutSecondsMin,
+		DefaultWebSocketPongTimeoutSecondsMax,
+		DefaultWebSocketPongTimeoutSeconds)
+	config.Mgs.DataChannelPingIntervalSeconds = getNumericValue(
+		config.Mgs.DataChannelPingIntervalSeconds,
+		DefaultWebSocketPingIntervalSecondsMin,
+		DefaultWebSocketPingIntervalSecondsMax,
+		DefaultWebSocketPingIntervalSeconds)
+	config.Mgs.DataChannelPongTimeoutSeconds = getNumericValue(
+		config.Mgs.DataChannelPongTimeoutSeconds,
+		DefaultWebSocketPongTimeoutSecondsMin,
+		DefaultWebSocketPongTimeoutSecondsMax,
+		DefaultWebSocketPongTimeoutSeconds)
+	config.Mgs.ControlChannelRetryInitialDelayMillis = getNumericValue(
+		config.Mgs.ControlChannelRetryInitialDelayMillis,
+		DefaultControlChannelRetryInitialDelayMillisMin,
+		DefaultControlChannelRetryInitialDelayMillisMax,
+		DefaultControlChannelRetryInitialDelayMillis)
+	config.Mgs.ControlChannelRetryMultiplier = getFloat64Value(
+		config.Mgs.ControlChannelRetryMultiplier,
+		DefaultChannelRetryMultiplierMin,
+		DefaultChannelRetryMultiplierMax,
+		DefaultChannelRetryMultiplier)
+	config.Mgs.ControlChannelRetryMaxDelayMillis = getNumericValue(
+		config.Mgs.ControlChannelRetryMaxDelayMillis,
+		DefaultControlChannelRetryMaxDelayMillisMin,
+		DefaultControlChannelRetryMaxDelayMillisMax,
+		DefaultControlChannelRetryMaxDelayMillis)
+	config.Mgs.ControlChannelRetryJitter = getFloat64Value(
+		config.Mgs.ControlChannelRetryJitter,
+		DefaultChannelRetryJitterMin,
+		DefaultChannelRetryJitterMax,
+		DefaultChannelRetryJitter)
+	config.Mgs.DataChannelRetryInitialDelayMillis = getNumericValue(
+		config.Mgs.DataChannelRetryInitialDelayMillis,
+		DefaultDataChannelRetryInitialDelayMillisMin,
+		DefaultDataChannelRetryInitialDelayMillisMax,
+		DefaultDataChannelRetryInitialDelayMillis)
+	config.Mgs.DataChannelRetryMultiplier = getFloat64Value(
+		config.Mgs.DataChannelRetryMultiplier,
+		DefaultChannelRetryMultiplierMin,
+		DefaultChannelRetryMultiplierMax,
+		DefaultChannelRetryMultiplier)
+	config.Mgs.DataChannelRetryMaxDelayMillis = getNumericValue(
+		config.Mgs.DataChannelRetryMaxDelayMillis,
+		DefaultDataChannelRetryMaxDelayMillisMin,
+		DefaultDataChannelRetryMaxDelayMillisMax,
+		DefaultDataChannelRetryMaxDelayMillis)
+	config.Mgs.DataChannelRetryJitter = getFloat64Value(
+		config.Mgs.DataChannelRetryJitter,
+		DefaultChannelRetryJitterMin,
+		DefaultChannelRetryJitterMax,
+		DefaultChannelRetryJitter)
+	config.Mgs.DataChannelOutageBufferSizeMB = getNumericValue(
+		config.Mgs.DataChannelOutageBufferSizeMB,
+		DefaultDataChannelOutageBufferSizeMBMin,
+		DefaultDataChannelOutageBufferSizeMBMax,
+		DefaultDataChannelOutageBufferSizeMB)
+	config.Mgs.DataChannelOutageSpillSizeMB = getNumericValue(
+		config.Mgs.DataChannelOutageSpillSizeMB,
+		DefaultDataChannelOutageSpillSizeMBMin,
+		DefaultDataChannelOutageSpillSizeMBMax,
+		DefaultDataChannelOutageSpillSizeMB)
+	// overrides out of range are ignored so that the plugin keeps its default payload size
+	for pluginName, payloadSize := range config.Mgs.StreamDataPayloadSizeOverrides {
+		if payloadSize < DefaultStreamDataPayloadSizeMin || payloadSize > DefaultStreamDataPayloadSizeMax {
+			delete(config.Mgs.StreamDataPayloadSizeOverrides, pluginName)
+		}
+	}
+	// unknown IP address preferences fall back to the system default
+	switch config.Mgs.IPAddressPreference {
+	case IPAddressPreferenceIPv4, IPAddressPreferenceIPv6, IPAddressPreferenceDualStack:
+	default:
+		config.Mgs.IPAddressPreference = ""
+	}
+	switch config.Mgs.SessionEncryptionCipher {
+	case SessionEncryptionCipherAESGCM, SessionEncryptionCipherChaCha20Poly1305:
+	default:
+		config.Mgs.SessionEncryptionCipher = SessionEncryptionCipherAESGCM
+	}
 }
 
 // getStringValue returns the default value if config is empty, else the config value
@@ -93,6 +347,14 @@ func getNumericValue(configValue int, minValue int, maxValue int, defaultValue i
 	return configValue
 }
 
+// getFloat64Value returns the default if config value is below min or above max
+func getFloat64Value(configValue float64, minValue float64, maxValue float64, defaultValue float64) float64 {
+	if configValue < minValue || configValue > maxValue {
+		return defaultValue
+	}
+	return configValue
+}
+
 // getNumeric64Value returns the default if config value is below min or above max
 func getNumeric64Value(configValue int64, minValue int64, maxValue int64, defaultValue int64) int64 {
 	if configValue < minValue || configValue > maxValue {
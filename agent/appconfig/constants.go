@@ -40,6 +40,38 @@ const (
 	DefaultSsmAssociationFrequencyMinutesMin = 5
 	DefaultSsmAssociationFrequencyMinutesMax = 60
 
+	DefaultSsmParallelStepsLimit    = 4
+	DefaultSsmParallelStepsLimitMin = 1
+	DefaultSsmParallelStepsLimitMax = 64
+
+	DefaultRunCommandOutputFlushIntervalSeconds    = 3
+	DefaultRunCommandOutputFlushIntervalSecondsMin = 1
+	DefaultRunCommandOutputFlushIntervalSecondsMax = 60
+
+	DefaultRunDocumentMaxDepth    = 3
+	DefaultRunDocumentMaxDepthMin = 1
+	DefaultRunDocumentMaxDepthMax = 10
+
+	DefaultInventoryFullUploadIntervalHours    = 24
+	DefaultInventoryFullUploadIntervalHoursMin = 1
+	DefaultInventoryFullUploadIntervalHoursMax = 720
+
+	DefaultInventoryNiceness    = 10
+	DefaultInventoryNicenessMin = 0
+	DefaultInventoryNicenessMax = 19
+
+	DefaultInventoryIoniceClass    = 2
+	DefaultInventoryIoniceClassMin = 0
+	DefaultInventoryIoniceClassMax = 3
+
+	DefaultInventoryFileOperationsPerSecond    = 1000
+	DefaultInventoryFileOperationsPerSecondMin = 0
+	DefaultInventoryFileOperationsPerSecondMax = 100000
+
+	DefaultInventoryGathererTimeoutSeconds    = 1800
+	DefaultInventoryGathererTimeoutSecondsMin = 30
+	DefaultInventoryGathererTimeoutSecondsMax = 7200
+
 	//aws-ssm-agent bookkeeping constants
 	DefaultLocationOfPending     = "pending"
 	DefaultLocationOfCurrent     = "current"
@@ -67,6 +99,7 @@ const (
 	FileInventoryRootDirName     = "file"
 	RoleInventoryRootDirName     = "role"
 	InventoryContentHashFileName = "contentHash"
+	InventoryFullUploadFileName  = "fullUpload"
 
 	//aws-ssm-agent bookkeeping constants for failed sent replies
 	RepliesRootDirName = "replies"
@@ -120,6 +153,21 @@ const (
 	// PluginNameAwsRunPowerShellScript is the name of the run powershell script plugin
 	PluginNameAwsRunPowerShellScript = "aws:runPowerShellScript"
 
+	// PluginNameAwsRunPythonScript is the name of the run python script plugin
+	PluginNameAwsRunPythonScript = "aws:runPythonScript"
+
+	// PluginNameAwsRunAnsiblePlaybook is the name of the run ansible playbook plugin
+	PluginNameAwsRunAnsiblePlaybook = "aws:runAnsiblePlaybook"
+
+	// PluginNameAwsRunComplianceScan is the name of the run compliance scan plugin
+	PluginNameAwsRunComplianceScan = "aws:runComplianceScan"
+
+	// PluginNameAwsSystemdService is the name of the systemd service plugin
+	PluginNameAwsSystemdService = "aws:systemdService"
+
+	// PluginNameAwsWindowsService is the name of the windows service plugin
+	PluginNameAwsWindowsService = "aws:windowsService"
+
 	// PluginNameAwsAgentUpdate is the name for agent update plugin
 	PluginNameAwsAgentUpdate = "aws:updateSsmAgent"
 
@@ -129,6 +177,9 @@ const (
 	// PluginDownloadContent is the name for downloadContent plugin
 	PluginDownloadContent = "aws:downloadContent"
 
+	// PluginUploadContent is the name for uploadContent plugin
+	PluginUploadContent = "aws:uploadContent"
+
 	// PluginRunDocument is the name of the run document plugin
 	PluginRunDocument = "aws:runDocument"
 
@@ -159,6 +210,9 @@ const (
 	AppConfigFileName    = "amazon-ssm-agent.json"
 	SeelogConfigFileName = "seelog.xml"
 
+	// TrustedKeysFolderName is the folder in the program folder holding the public keys artifact signatures are verified with
+	TrustedKeysFolderName = "trusted-keys"
+
 	// Output truncation limits
 	MaxStdoutLength = 24000
 	MaxStderrLength = 8000
@@ -167,6 +221,146 @@ const (
 	DefaultSessionWorkersLimit    = 1000
 	DefaultSessionWorkersLimitMin = 1
 
+	// Port plugin reconnection defaults
+	DefaultPortReconnectMaxAttempts          = 5
+	DefaultPortReconnectMaxAttemptsMin       = 1
+	DefaultPortReconnectMaxAttemptsMax       = 100
+	DefaultPortReconnectMaxIntervalMillis    = 5000
+	DefaultPortReconnectMaxIntervalMillisMin = 100
+	DefaultPortReconnectMaxIntervalMillisMax = 1000 * 60
+
+	// Port plugin socket buffer size limits, zero means system default
+	DefaultPortForwardingSocketBufferSize    = 0
+	DefaultPortForwardingSocketBufferSizeMin = 0
+	DefaultPortForwardingSocketBufferSizeMax = 16 * 1024 * 1024
+
+	// Shell session recording defaults
+	DefaultSessionRecordingMaxFileSizeMB    = 10
+	DefaultSessionRecordingMaxFileSizeMBMin = 1
+	DefaultSessionRecordingMaxFileSizeMBMax = 1024
+	DefaultSessionRecordingMaxFiles         = 5
+	DefaultSessionRecordingMaxFilesMin      = 1
+	DefaultSessionRecordingMaxFilesMax      = 100
+
+	// Shell session idle timeout defaults, zero disables the idle timeout
+	DefaultSessionIdleTimeoutMinutes    = 0
+	DefaultSessionIdleTimeoutMinutesMin = 0
+	DefaultSessionIdleTimeoutMinutesMax = 60 * 24
+
+	// File transfer session limits, zero means unlimited
+	DefaultFileTransferMaxFileSizeMB    = 1024
+	DefaultFileTransferMaxFileSizeMBMin = 0
+	DefaultFileTransferMaxFileSizeMBMax = 1024 * 1024
+
+	// Shell session input limits, zero means unlimited
+	DefaultSessionInputRateLimitBytesPerSecond    = 0
+	DefaultSessionInputRateLimitBytesPerSecondMin = 0
+	DefaultSessionInputRateLimitBytesPerSecondMax = 100 * 1024 * 1024
+	DefaultSessionMaxInputMessageSize             = 0
+	DefaultSessionMaxInputMessageSizeMin          = 0
+	DefaultSessionMaxInputMessageSizeMax          = 100 * 1024 * 1024
+
+	// Shell session terminal resizing, a zero debounce applies every resize and a zero size is unlimited
+	DefaultSessionResizeDebounceMilliseconds    = 100
+	DefaultSessionResizeDebounceMillisecondsMin = 0
+	DefaultSessionResizeDebounceMillisecondsMax = 5000
+	DefaultSessionMaxTerminalSize               = 0
+	DefaultSessionMaxTerminalSizeMin            = 0
+	DefaultSessionMaxTerminalSizeMax            = 65535
+
+	// DefaultSessionWindowsCodePage is UTF-8 so that non-ASCII output of Windows shell sessions reaches the client intact
+	DefaultSessionWindowsCodePage    = 65001
+	DefaultSessionWindowsCodePageMin = 0
+	DefaultSessionWindowsCodePageMax = 65535
+
+	// InteractiveCommands session timeout, zero means no timeout
+	DefaultInteractiveCommandsTimeoutSeconds    = 0
+	DefaultInteractiveCommandsTimeoutSecondsMin = 0
+	DefaultInteractiveCommandsTimeoutSecondsMax = 172800
+
+	// Time sessions stay detached waiting for the data channel to reconnect, zero disables detaching
+	DefaultSessionDetachGracePeriodSeconds    = 0
+	DefaultSessionDetachGracePeriodSecondsMin = 0
+	DefaultSessionDetachGracePeriodSecondsMax = 86400
+
+	// Size above which session stream data payloads are compressed
+	DefaultSessionCompressionThresholdBytes    = 512
+	DefaultSessionCompressionThresholdBytesMin = 0
+	DefaultSessionCompressionThresholdBytesMax = 65536
+
+	// Largest stream data payload sent over the session data channel
+	DefaultStreamDataPayloadSize    = 1024
+	DefaultStreamDataPayloadSizeMin = 256
+	DefaultStreamDataPayloadSizeMax = 65536
+
+	// Data channel retransmission tuning
+	DefaultDataChannelRetransmissionWindow               = 100000
+	DefaultDataChannelRetransmissionWindowMin            = 100
+	DefaultDataChannelRetransmissionWindowMax            = 100000
+	DefaultDataChannelMaxInFlightMessages                = 0
+	DefaultDataChannelMaxInFlightMessagesMin             = 0
+	DefaultDataChannelMaxInFlightMessagesMax             = 100000
+	DefaultDataChannelRetransmissionTimeoutMultiplier    = 4
+	DefaultDataChannelRetransmissionTimeoutMultiplierMin = 1
+	DefaultDataChannelRetransmissionTimeoutMultiplierMax = 16
+	DefaultDataChannelMaxRetransmissionTimeoutMillis     = 1000
+	DefaultDataChannelMaxRetransmissionTimeoutMillisMin  = 100
+	DefaultDataChannelMaxRetransmissionTimeoutMillisMax  = 60000
+
+	// Deflate level of WebSocket messages, 1 is best speed and 9 is best compression
+	DefaultWebSocketCompressionLevel    = 1
+	DefaultWebSocketCompressionLevelMin = 1
+	DefaultWebSocketCompressionLevelMax = 9
+
+	// Keepalive of control and data channel connections, high-latency links may need longer pong timeouts
+	DefaultWebSocketPingIntervalSeconds    = 300
+	DefaultWebSocketPingIntervalSecondsMin = 5
+	DefaultWebSocketPingIntervalSecondsMax = 3600
+	DefaultWebSocketPongTimeoutSeconds     = 0
+	DefaultWebSocketPongTimeoutSecondsMin  = 0
+	DefaultWebSocketPongTimeoutSecondsMax  = 3600
+
+	// Reconnection backoff of control and data channels
+	DefaultControlChannelRetryInitialDelayMillis    = 5000
+	DefaultControlChannelRetryInitialDelayMillisMin = 100
+	DefaultControlChannelRetryInitialDelayMillisMax = 300000
+	DefaultControlChannelRetryMaxDelayMillis        = 3600000
+	DefaultControlChannelRetryMaxDelayMillisMin     = 1000
+	DefaultControlChannelRetryMaxDelayMillisMax     = 86400000
+	DefaultDataChannelRetryInitialDelayMillis       = 100
+	DefaultDataChannelRetryInitialDelayMillisMin    = 10
+	DefaultDataChannelRetryInitialDelayMillisMax    = 60000
+	DefaultDataChannelRetryMaxDelayMillis           = 5000
+	DefaultDataChannelRetryMaxDelayMillisMin        = 100
+	DefaultDataChannelRetryMaxDelayMillisMax        = 300000
+	DefaultChannelRetryMultiplier                   = 2.0
+	DefaultChannelRetryMultiplierMin                = 1.0
+	DefaultChannelRetryMultiplierMax                = 10.0
+	DefaultChannelRetryJitter                       = 0.5
+	DefaultChannelRetryJitterMin                    = 0.0
+	DefaultChannelRetryJitterMax                    = 1.0
+
+	// Outage buffering of stream data while data channels reconnect
+	DefaultDataChannelOutageBufferSizeMB    = 16
+	DefaultDataChannelOutageBufferSizeMBMin = 0
+	DefaultDataChannelOutageBufferSizeMBMax = 1024
+	DefaultDataChannelOutageSpillSizeMB     = 0
+	DefaultDataChannelOutageSpillSizeMBMin  = 0
+	DefaultDataChannelOutageSpillSizeMBMax  = 10240
+
+	// IP address preferences of control and data channel connections
+	IPAddressPreferenceIPv4      = "IPv4"
+	IPAddressPreferenceIPv6      = "IPv6"
+	IPAddressPreferenceDualStack = "DualStack"
+
+	// AEAD ciphers of session encryption
+	SessionEncryptionCipherAESGCM           = "AES-GCM"
+	SessionEncryptionCipherChaCha20Poly1305 = "ChaCha20-Poly1305"
+
+	// Command digest modes of shell sessions
+	SessionCommandDigestWithTranscripts = "WithTranscripts"
+	SessionCommandDigestOnly            = "DigestOnly"
+
 	// PluginNameStandardStream is the name for session manager standard stream plugin aka shell.
 	PluginNameStandardStream = "Standard_Stream"
 
@@ -176,6 +370,9 @@ const (
 	// PluginNamePort is the name for session manager port plugin.
 	PluginNamePort = "Port"
 
+	// PluginNameFileTransfer is the name for session manager file transfer plugin.
+	PluginNameFileTransfer = "FileTransfer"
+
 	// Session default RunAs user name
 	DefaultRunAsUserName = "ssm-user"
 )
@@ -194,6 +391,28 @@ var SupportedDocumentVersions = map[string]struct{}{
 	"2.2":   {},
 }
 
+// DefaultSessionEnvironmentVariablesDenylist lists the environment variables session documents may not set,
+// as they change how the shell or the dynamic linker loads code or which programs commands run.
+// Names ending with * deny every variable starting with the rest of the name, such as exported bash functions.
+var DefaultSessionEnvironmentVariablesDenylist = []string{
+	"PATH",
+	"BASH_FUNC_*",
+	"LD_PRELOAD",
+	"LD_LIBRARY_PATH",
+	"LD_AUDIT",
+	"DYLD_INSERT_LIBRARIES",
+	"DYLD_LIBRARY_PATH",
+	"BASH_ENV",
+	"ENV",
+	"IFS",
+	"SHELLOPTS",
+	"PROMPT_COMMAND",
+	"PS4",
+	"HOME",
+	"COMSPEC",
+	"PSModulePath",
+}
+
 // Session Manager Document versions that are supported by this Agent version.
 var SupportedSessionDocumentVersions = map[string]struct{}{
 	"1.0": {},
@@ -29,6 +29,9 @@ const (
 	// PackageLockRoot specifies the directory under which package lock files will reside
 	PackageLockRoot = DefaultProgramFolder + "locks/packages"
 
+	// PackageCacheRoot specifies the default directory of the cache of downloaded packages
+	PackageCacheRoot = DefaultProgramFolder + "packagecache"
+
 	// PackagePlatform is the platform name to use when looking for packages
 	PackagePlatform = "darwin"
 
@@ -31,6 +31,9 @@ const (
 	// PackageLockRoot specifies the directory under which package lock files will reside
 	PackageLockRoot = "/var/lib/amazon/ssm/locks/packages"
 
+	// PackageCacheRoot specifies the default directory of the cache of downloaded packages
+	PackageCacheRoot = "/var/lib/amazon/ssm/packagecache"
+
 	// PackagePlatform is the platform name to use when looking for packages
 	PackagePlatform = "linux"
 
@@ -89,6 +89,9 @@ var PackageRoot string
 // PackageLockRoot specifies the directory under which package lock files will reside
 var PackageLockRoot string
 
+// PackageCacheRoot specifies the default directory of the cache of downloaded packages
+var PackageCacheRoot string
+
 // DaemonRoot specifies the directory where daemon registration information is stored
 var DaemonRoot string
 
@@ -174,6 +177,7 @@ func init() {
 	DefaultDataStorePath = filepath.Join(SSMDataPath, "InstanceData")
 	PackageRoot = filepath.Join(SSMDataPath, "Packages")
 	PackageLockRoot = filepath.Join(SSMDataPath, "Locks\\Packages")
+	PackageCacheRoot = filepath.Join(SSMDataPath, "PackageCache")
 	DaemonRoot = filepath.Join(SSMDataPath, "Daemons")
 	LocalCommandRoot = filepath.Join(SSMDataPath, "LocalCommands")
 	LocalCommandRootSubmitted = filepath.Join(LocalCommandRoot, "Submitted")
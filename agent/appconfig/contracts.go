@@ -41,6 +41,90 @@ type SsmCfg struct {
 	AssociationLogsRetentionDurationHours int
 	RunCommandLogsRetentionDurationHours  int
 	SessionLogsRetentionDurationHours     int
+	// ParallelStepsLimit is the maximum number of steps of a document run concurrently when its steps declare dependsOn
+	ParallelStepsLimit int
+	// RunCommandOutputLogGroup is the CloudWatch log group the output of commands is streamed to
+	// when the command does not enable CloudWatch output itself
+	RunCommandOutputLogGroup string
+	// RunCommandOutputFlushIntervalSeconds is how often the output of running commands is uploaded to CloudWatch Logs
+	RunCommandOutputFlushIntervalSeconds int
+	// RunCommandOutputSpillS3BucketName is the S3 bucket the full output of commands exceeding the result size limit
+	// is uploaded to when the command does not set an output bucket itself
+	RunCommandOutputSpillS3BucketName string
+	// RunCommandDefaultRunAsUser is the user the scripts of commands run as on Linux, empty runs them as the agent user
+	RunCommandDefaultRunAsUser string
+	// RunCommandRunAsAllowedUsers lists the other users documents may run their scripts as with runAsUser,
+	// root must be listed for documents to run scripts as root when a default user is configured
+	RunCommandRunAsAllowedUsers []string
+	// RunCommandElevatedAllowedPlugins lists the plugins which may run as the agent user when RunCommandDefaultRunAsUser
+	// is set. Other plugins which cannot run as the default user, such as aws:runPowerShellScript on Linux, are refused.
+	RunCommandElevatedAllowedPlugins []string
+	// ArtifactSignatureTrustedKeys are the public keys, in addition to the key files in the trusted-keys folder
+	// of the program folder, that signatures of downloaded content and packages may be verified with
+	ArtifactSignatureTrustedKeys []string
+	// DocumentWorkerSandboxPlugins lists the plugins whose documents run in a sandboxed document worker on Linux, with
+	// private mount and PID namespaces, no new privileges and a seccomp filter. Processes started by sandboxed documents
+	// end with the document, including background processes, so plugins of documents detaching processes meant to keep
+	// running must not be listed.
+	DocumentWorkerSandboxPlugins []string
+	// DocumentWorkerSeccompProfile is the path of the seccomp profile of sandboxed document workers, empty uses the default profile
+	DocumentWorkerSeccompProfile string
+	// RunDocumentMaxDepth is how deeply aws:runDocument steps may nest documents
+	RunDocumentMaxDepth int
+	// CommandAuditLogEnabled records every plugin run by documents in the local hash-chained command audit log,
+	// keyed with a key in the agent vault. Root or Administrators can read the key and rewrite the chain, only the copy
+	// published to CommandAuditLogGroup protects the records against them.
+	CommandAuditLogEnabled bool
+	// CommandAuditLogGroup is the CloudWatch log group command audit records are also published to
+	CommandAuditLogGroup string
+	// CustomInventoryScripts are external gatherer scripts run by the custom inventory gatherer, their output is
+	// validated against the declared schema and uploaded with the custom inventory of the instance
+	CustomInventoryScripts []CustomInventoryScript
+	// InventoryDeltaUploadEnabled uploads only the inventory types whose content changed since the last upload,
+	// all types are uploaded again once InventoryFullUploadIntervalHours have passed since the last full upload
+	InventoryDeltaUploadEnabled bool
+	// InventoryFullUploadIntervalHours is how often all inventory types are uploaded when delta uploads are enabled
+	InventoryFullUploadIntervalHours int
+	// WindowsRegistryInventoryKeys are the registry keys and values reported by the Custom:WindowsRegistryKey inventory gatherer
+	WindowsRegistryInventoryKeys []RegistryInventoryKey
+	// InventoryNiceness is the niceness the commands of the application and file inventory gatherers run with,
+	// zero runs them at normal priority. Windows runs them below normal priority when it is not zero.
+	InventoryNiceness int
+	// InventoryIoniceClass is the Linux I/O scheduling class of those commands, 1 realtime, 2 best-effort
+	// at the lowest level and 3 idle, zero keeps the class of the agent
+	InventoryIoniceClass int
+	// InventoryFileOperationsPerSecond limits the files and directories inventory gatherers read per second,
+	// zero reads them without limit
+	InventoryFileOperationsPerSecond int
+	// InventoryGathererTimeoutSeconds is how long an inventory gatherer may run, the inventory collected by the
+	// other gatherers is uploaded when a gatherer times out
+	InventoryGathererTimeoutSeconds int
+	// InventoryGathererTimeouts overrides InventoryGathererTimeoutSeconds for gatherers by name, such as AWS:File
+	InventoryGathererTimeouts map[string]int
+}
+
+// CustomInventoryScript represents an external gatherer script reporting a custom inventory type
+type CustomInventoryScript struct {
+	// TypeName is the custom inventory type reported by the script, it must start with Custom:
+	TypeName string
+	// SchemaVersion is the schema version of the type, such as 1.0
+	SchemaVersion string
+	// Attributes are the attribute names of the entries, output with missing or undeclared attributes is rejected
+	Attributes []string
+	// Path is the script run, it must write a JSON array of entries with string values to stdout
+	Path string
+	// Arguments are passed to the script
+	Arguments []string
+	// TimeoutSeconds stops scripts running longer, zero uses the default timeout
+	TimeoutSeconds int
+}
+
+// RegistryInventoryKey represents registry keys whose values are reported as custom inventory
+type RegistryInventoryKey struct {
+	// Path is the registry key, such as HKEY_LOCAL_MACHINE\SOFTWARE\Vendor\*, wildcards match any key names
+	Path string
+	// ValueNames are the values reported, wildcards match any value names and all values are reported when empty
+	ValueNames []string
 }
 
 // AgentInfo represents metadata for amazon-ssm-agent
@@ -50,6 +134,9 @@ type AgentInfo struct {
 	Region               string
 	OrchestrationRootDir string
 	DownloadRootDir      string
+	// FipsEnabled routes the service clients to FIPS endpoints where available and refuses
+	// session connections that do not use FIPS approved cryptography
+	FipsEnabled bool
 }
 
 // MgsConfig represents configuration for Message Gateway service
@@ -58,6 +145,173 @@ type MgsConfig struct {
 	Endpoint            string
 	StopTimeoutMillis   int64
 	SessionWorkersLimit int
+	// PortForwardingLocalAddress is the source IP address or interface name used for connections opened by the port plugin
+	PortForwardingLocalAddress string
+	// PortForwardingHostOverrides maps destination host names to IP addresses, taking precedence over the system resolver
+	PortForwardingHostOverrides map[string]string
+	// PortForwardingAuditLogEnabled enables audit records of port sessions in the local audit log file
+	PortForwardingAuditLogEnabled bool
+	// PortForwardingAuditLogGroup is the CloudWatch log group port session audit records are also published to
+	PortForwardingAuditLogGroup string
+	// PortForwardingAllowedDevices lists the character devices the port plugin is allowed to forward
+	PortForwardingAllowedDevices []string
+	// PortForwardingAllowedPipes lists the names of the Windows named pipes the port plugin is allowed to forward
+	PortForwardingAllowedPipes []string
+	// PortForwardingAllowedHosts lists the remote hosts the port plugin may connect to besides localhost, which is the only
+	// host allowed when the list is empty
+	PortForwardingAllowedHosts []string
+	// PortSessionsLimit is the maximum number of concurrent port forwarding sessions, zero means unlimited
+	PortSessionsLimit int
+	// SSHSessionsLimit is the maximum number of concurrent SSH sessions, zero means unlimited
+	SSHSessionsLimit int
+	// StandardStreamSessionsLimit is the maximum number of concurrent interactive shell sessions, zero means unlimited
+	StandardStreamSessionsLimit int
+	// PortReconnectMaxAttempts is the number of attempts to reconnect to the port for local port forwarding
+	PortReconnectMaxAttempts int
+	// PortReconnectMaxIntervalMillis is the maximum delay between reconnection attempts
+	PortReconnectMaxIntervalMillis int
+	// PortForwardingTcpNoDelay disables Nagle's algorithm on connections opened by the port plugin
+	PortForwardingTcpNoDelay bool
+	// PortForwardingReceiveBufferSize is the SO_RCVBUF size of connections opened by the port plugin, zero means system default
+	PortForwardingReceiveBufferSize int
+	// PortForwardingSendBufferSize is the SO_SNDBUF size of connections opened by the port plugin, zero means system default
+	PortForwardingSendBufferSize int
+	// SessionRecordingEnabled records the terminal I/O of shell sessions to asciinema cast files in the orchestration directory
+	SessionRecordingEnabled bool
+	// SessionRecordingMaxFileSizeMB is the size after which a new cast file is started
+	SessionRecordingMaxFileSizeMB int
+	// SessionRecordingMaxFiles is the number of cast files kept uncompressed per session, older files are compressed
+	SessionRecordingMaxFiles int
+	// SessionIdleTimeoutMinutes terminates interactive shell sessions without keyboard input for this long, zero disables it
+	SessionIdleTimeoutMinutes int
+	// DefaultShellProfileLinux is run at the start of every interactive shell session on Linux, before the document profile
+	DefaultShellProfileLinux string
+	// DefaultShellProfileWindows is run at the start of every interactive shell session on Windows, before the document profile
+	DefaultShellProfileWindows string
+	// SessionRunAsAllowedUsers lists the users shell sessions may run as, empty allows all users not denied
+	SessionRunAsAllowedUsers []string
+	// SessionRunAsAllowedGroups lists the groups whose members shell sessions may run as
+	SessionRunAsAllowedGroups []string
+	// SessionRunAsDeniedUsers lists the users shell sessions may never run as, taking precedence over the allowed lists
+	SessionRunAsDeniedUsers []string
+	// SessionRunAsDeniedGroups lists the groups whose members shell sessions may never run as
+	SessionRunAsDeniedGroups []string
+	// FileTransferAllowedRootDirectories lists the directories file transfer sessions may use as their root directory,
+	// including their subdirectories. Empty limits file transfer sessions to the home directory of the session user.
+	FileTransferAllowedRootDirectories []string
+	// FileTransferMaxFileSizeMB is the largest file file transfer sessions may receive, zero means unlimited
+	FileTransferMaxFileSizeMB int
+	// SessionEnvironmentVariablesDenylist lists the environment variables session documents may not set
+	SessionEnvironmentVariablesDenylist []string
+	// SessionShellsLinux lists the shells started for Linux sessions in order of preference, sh is used if none is found
+	SessionShellsLinux []string
+	// SessionShellsWindows lists the shells started for Windows sessions in order of preference, powershell is used if none is found
+	SessionShellsWindows []string
+	// SessionInputRateLimitBytesPerSecond throttles the keyboard input written to shell sessions, zero means unlimited
+	SessionInputRateLimitBytesPerSecond int
+	// SessionMaxInputMessageSize is the largest single input message written to shell sessions, zero means unlimited
+	SessionMaxInputMessageSize int
+	// SessionResizeDebounceMilliseconds is how long shell sessions wait for resizing to settle before resizing the terminal
+	SessionResizeDebounceMilliseconds int
+	// SessionMaxTerminalColumns limits the width of shell session terminals, zero means unlimited
+	SessionMaxTerminalColumns int
+	// SessionMaxTerminalRows limits the height of shell session terminals, zero means unlimited
+	SessionMaxTerminalRows int
+	// SessionWindowsCodePage is the console code page of interactive Windows shell sessions, zero keeps the system code page
+	SessionWindowsCodePage int
+	// SessionWindowsLocale is the culture of interactive Windows PowerShell sessions such as en-US, empty keeps the culture of the user
+	SessionWindowsLocale string
+	// InteractiveCommandsTimeoutSeconds terminates InteractiveCommands sessions running longer, zero means no timeout
+	InteractiveCommandsTimeoutSeconds int
+	// SessionBanner is plain text shown at the start of interactive shell sessions, such as a legal notice
+	SessionBanner string
+	// SessionBannerFile is the path of a plain text file shown at the start of interactive shell sessions instead of SessionBanner
+	SessionBannerFile string
+	// SessionRestrictedCommandsEnabled limits shell sessions to running the commands matching SessionAllowedCommands
+	// one at a time instead of starting an interactive shell
+	SessionRestrictedCommandsEnabled bool
+	// SessionAllowedCommands lists regular expressions matched against whole command lines in restricted command mode
+	SessionAllowedCommands []string
+	// SessionDetachGracePeriodSeconds keeps sessions and their processes alive while the data channel reconnects after
+	// dropping, so that the client can resume the session. Sessions not resumed in time are terminated, zero disables detaching.
+	SessionDetachGracePeriodSeconds int
+	// SessionCommandDigestMode logs an HMAC-SHA256 digest of each command line typed in shell sessions to the shell audit log,
+	// either alongside the session transcripts (WithTranscripts) or instead of them (DigestOnly). Empty disables digests.
+	SessionCommandDigestMode string
+	// SessionCommandDigestKeyFile is the file holding the hex encoded HMAC key of command digests, generated when it does not exist
+	SessionCommandDigestKeyFile string
+	// SessionCompressionThresholdBytes is the size above which stream data payloads are compressed when the client
+	// supports compression, smaller interactive payloads are sent as is
+	SessionCompressionThresholdBytes int
+	// StreamDataPayloadSize is the largest stream data payload sent over the data channel by interactive and command sessions,
+	// port forwarding and file transfer sessions use larger payloads for throughput
+	StreamDataPayloadSize int
+	// StreamDataPayloadSizeOverrides sets the payload size of the sessions of a plugin, keyed by plugin name
+	// such as Standard_Stream, InteractiveCommands, Port or FileTransfer
+	StreamDataPayloadSizeOverrides map[string]int
+	// DataChannelRetransmissionWindow is the number of unacknowledged stream data messages kept for retransmission
+	DataChannelRetransmissionWindow int
+	// DataChannelMaxInFlightMessages pauses reading session output while that many stream data messages are unacknowledged,
+	// zero means session output is read regardless of acknowledgements
+	DataChannelMaxInFlightMessages int
+	// DataChannelRetransmissionTimeoutMultiplier scales the round trip time variation added to the round trip time
+	// to get the retransmission timeout
+	DataChannelRetransmissionTimeoutMultiplier int
+	// DataChannelMaxRetransmissionTimeoutMillis caps the retransmission timeout of stream data messages
+	DataChannelMaxRetransmissionTimeoutMillis int
+	// WebSocketCompressionEnabled negotiates permessage-deflate on the control and data channel connections
+	WebSocketCompressionEnabled bool
+	// WebSocketCompressionLevel is the deflate level of messages sent when compression is negotiated, from 1 to 9
+	WebSocketCompressionLevel int
+	// IPAddressPreference selects the IP protocol of control and data channel connections, one of IPv4, IPv6 or DualStack.
+	// IPv6 and DualStack connect to the dual-stack endpoint of the service, empty uses the system default.
+	IPAddressPreference string
+	// EndpointServerName is the TLS server name sent to and verified against the Endpoint,
+	// for private gateways and proxies whose host name differs from the certificate of the service
+	EndpointServerName string
+	// EndpointCertificatePins are base64 encoded SHA-256 hashes of public keys of which at least one
+	// must be in the verified certificate chain of the Endpoint
+	EndpointCertificatePins []string
+	// SessionEncryptionCipher is the AEAD cipher preferred for session encryption, AES-GCM or ChaCha20-Poly1305.
+	// ChaCha20-Poly1305 is faster on processors without AES instructions and is used when the client supports it,
+	// except in FIPS mode.
+	SessionEncryptionCipher string
+	// ControlChannelPingIntervalSeconds is how often pings are sent on the control channel to keep the connection alive
+	ControlChannelPingIntervalSeconds int
+	// ControlChannelPongTimeoutSeconds is how long after a ping the control channel waits for a pong or any message
+	// before the connection is considered dead and reconnected, zero disables dead-connection detection
+	ControlChannelPongTimeoutSeconds int
+	// DataChannelPingIntervalSeconds is how often pings are sent on data channels to keep the connection alive
+	DataChannelPingIntervalSeconds int
+	// DataChannelPongTimeoutSeconds is how long after a ping a data channel waits for a pong or any message
+	// before the connection is considered dead and reconnected, zero disables dead-connection detection
+	DataChannelPongTimeoutSeconds int
+	// ControlChannelRetryInitialDelayMillis is the delay before the first control channel reconnection attempt,
+	// randomized up to twice as long
+	ControlChannelRetryInitialDelayMillis int
+	// ControlChannelRetryMultiplier grows the delay between control channel reconnection attempts
+	ControlChannelRetryMultiplier float64
+	// ControlChannelRetryMaxDelayMillis caps the delay between control channel reconnection attempts
+	ControlChannelRetryMaxDelayMillis int
+	// ControlChannelRetryJitter reduces each delay between control channel reconnection attempts by a random fraction
+	// of up to this ratio from 0 to 1, so that agents behind the same proxy do not reconnect in sync
+	ControlChannelRetryJitter float64
+	// DataChannelRetryInitialDelayMillis is the delay before the first data channel reconnection attempt,
+	// randomized up to twice as long
+	DataChannelRetryInitialDelayMillis int
+	// DataChannelRetryMultiplier grows the delay between data channel reconnection attempts
+	DataChannelRetryMultiplier float64
+	// DataChannelRetryMaxDelayMillis caps the delay between data channel reconnection attempts
+	DataChannelRetryMaxDelayMillis int
+	// DataChannelRetryJitter reduces each delay between data channel reconnection attempts by a random fraction
+	// of up to this ratio from 0 to 1
+	DataChannelRetryJitter float64
+	// DataChannelOutageBufferSizeMB is the memory holding stream data sent while a data channel reconnects, beyond
+	// the retransmission window, replayed in order once it is restored. Zero disables outage buffering.
+	DataChannelOutageBufferSizeMB int
+	// DataChannelOutageSpillSizeMB is the disk space of stream data spilled to files of the session when the outage buffer
+	// is full, zero disables spilling. Spilled stream data is only encrypted if session encryption is enabled.
+	DataChannelOutageSpillSizeMB int
 }
 
 // KmsConfig represents configuration for Key Management Service
@@ -83,6 +337,15 @@ type S3Cfg struct {
 // BirdwatcherCfg represents configuration related to ConfigurePackage Birdwatcher integration
 type BirdwatcherCfg struct {
 	ForceEnable bool
+	// PackageCacheEnabled keeps downloaded packages in the package cache so that they are not downloaded again,
+	// and updates of cached packages are downloaded as binary patches when the package provides them
+	PackageCacheEnabled bool
+	// PackageCacheDirectory is the directory of the package cache, PackageCacheRoot if not set
+	PackageCacheDirectory string
+	// Offline installs packages from the package cache or the mirror directory without contacting the package service
+	Offline bool
+	// PackageMirrorDirectory is a directory of packages in the layout of the package cache, used when offline
+	PackageMirrorDirectory string
 }
 
 // SsmagentConfig stores agent configuration values.
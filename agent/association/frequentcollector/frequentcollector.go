@@ -32,12 +32,22 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/application"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/awscomponent"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/certificate"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/container"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/file"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/gpu"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/instancedetailedinformation"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/kernel"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/languagepackage"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/listeningport"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/localuser"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/network"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/nvmehealth"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/registry"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/registrykey"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/role"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/service"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/systemd"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/windowsUpdate"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
 
@@ -319,13 +329,23 @@ func (collector *FrequentCollector) getSupportedGathererNames() map[string]strin
 func (collector *FrequentCollector) getGathererParameterMap() map[string]string {
 	var paramGathererMap = make(map[string]string)
 	paramGathererMap[strings.ToLower(application.GathererName)] = "applications"
+	paramGathererMap[strings.ToLower(languagepackage.GathererName)] = "languagePackages"
+	paramGathererMap[strings.ToLower(listeningport.GathererName)] = "listeningPorts"
+	paramGathererMap[strings.ToLower(gpu.GathererName)] = "gpus"
+	paramGathererMap[strings.ToLower(nvmehealth.GathererName)] = "nvmeHealth"
+	paramGathererMap[strings.ToLower(localuser.GathererName)] = "localUsers"
 	paramGathererMap[strings.ToLower(awscomponent.GathererName)] = "awsComponents"
 	paramGathererMap[strings.ToLower(file.GathererName)] = "files"
+	paramGathererMap[strings.ToLower(certificate.GathererName)] = "certificates"
 	paramGathererMap[strings.ToLower(network.GathererName)] = "networkConfig"
 	paramGathererMap[strings.ToLower(windowsUpdate.GathererName)] = "windowsUpdates"
 	paramGathererMap[strings.ToLower(service.GathererName)] = "services"
+	paramGathererMap[strings.ToLower(systemd.GathererName)] = "systemdServices"
+	paramGathererMap[strings.ToLower(container.GathererName)] = "containers"
 	paramGathererMap[strings.ToLower(registry.GathererName)] = "windowsRegistry"
+	paramGathererMap[strings.ToLower(registrykey.GathererName)] = "windowsRegistryKeys"
 	paramGathererMap[strings.ToLower(role.GathererName)] = "windowsRoles"
 	paramGathererMap[strings.ToLower(instancedetailedinformation.GathererName)] = "instanceDetailedInformation"
+	paramGathererMap[strings.ToLower(kernel.GathererName)] = "kernelModules"
 	return paramGathererMap
 }
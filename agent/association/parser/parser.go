@@ -102,6 +102,7 @@ func InitializeDocumentState(context context.T,
 		RuntimeConfig: payload.DocumentContent.RuntimeConfig,
 		MainSteps:     payload.DocumentContent.MainSteps,
 		Parameters:    payload.DocumentContent.Parameters,
+		RunAsUser:     payload.DocumentContent.RunAsUser,
 	}
 	return docparser.InitializeDocState(context.Log(), contracts.Association, docContent, documentInfo, parserInfo, payload.Parameters)
 }
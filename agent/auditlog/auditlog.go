@@ -0,0 +1,383 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package auditlog appends audit records to local audit logs and publishes them to CloudWatch Logs.
+//
+// Chained audit logs link every record to the previous one with an HMAC-SHA256 keyed with a per instance key
+// kept in the agent vault, so records cannot be modified, removed or reordered without the key. The vault is
+// readable by root or Administrators, who can still rewrite a chained log; publishing the records to CloudWatch Logs
+// keeps a copy outside the instance and is what protects the audit trail against them.
+package auditlog
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/cloudwatchlogsinterface"
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filelock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/vault/fsvault"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+const (
+	// lockFileExtension is appended to the path of a chained audit log to get the lock serializing its writers
+	lockFileExtension = ".lock"
+
+	// lockTimeoutSeconds is the time after which a lock left behind by a crashed writer is expired
+	lockTimeoutSeconds = 10
+	lockAttempts       = 5
+
+	// readChunkSize is the size of the chunks the end of an audit log is read in to find the last record
+	readChunkSize = 4096
+
+	// chainKeyName is the name of the audit log chain key in the agent vault
+	chainKeyName = "AuditLogChainKey"
+	chainKeySize = 32
+
+	// publishQueueSize is the number of records waiting to be published after which further records are dropped
+	publishQueueSize = 1000
+
+	// publishBatchSize is the maximum number of records published in a single PutLogEvents call
+	publishBatchSize = 100
+)
+
+var vaultExists = fsvault.Exists
+var vaultRetrieve = fsvault.Retrieve
+var vaultStore = fsvault.Store
+
+// Append appends the record as a single line to the audit log, creating the log if needed
+func Append(path string, content string) (err error) {
+	if err = fileutil.MakeDirs(filepath.Dir(path)); err != nil {
+		return
+	}
+
+	var auditFile *os.File
+	if auditFile, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, appconfig.ReadWriteAccess); err != nil {
+		return
+	}
+	defer auditFile.Close()
+
+	_, err = auditFile.WriteString(content + "\n")
+	return
+}
+
+// AppendRolled appends the record like Append, first rolling the audit log over once the record would grow it beyond
+// maxSize bytes. Rolled over logs are named path.1 to path.maxRolls, path.1 being the most recent.
+func AppendRolled(path string, content string, maxSize int64, maxRolls int) (err error) {
+	if exceedsSize(path, int64(len(content)+1), maxSize) {
+		if err = roll(path, int64(len(content)+1), maxSize, maxRolls); err != nil {
+			return
+		}
+	}
+	return Append(path, content)
+}
+
+// exceedsSize returns true if the file would grow beyond maxSize bytes after appending size bytes to it
+func exceedsSize(path string, size int64, maxSize int64) bool {
+	fileInfo, err := os.Stat(path)
+	return err == nil && fileInfo.Size() > 0 && fileInfo.Size()+size > maxSize
+}
+
+// roll renames the audit log to path.1 and shifts the logs rolled over before, removing the oldest one.
+// Concurrent writers are serialized by the lock, and the size is checked again so only one of them rolls the log.
+func roll(path string, size int64, maxSize int64, maxRolls int) (err error) {
+	var unlock func()
+	if unlock, err = lock(path); err != nil {
+		return
+	}
+	defer unlock()
+
+	if !exceedsSize(path, size, maxSize) {
+		return nil
+	}
+	if maxRolls < 1 {
+		return os.Remove(path)
+	}
+	if err = os.Remove(fmt.Sprintf("%s.%d", path, maxRolls)); err != nil && !os.IsNotExist(err) {
+		return
+	}
+	for index := maxRolls - 1; index > 0; index-- {
+		if err = os.Rename(fmt.Sprintf("%s.%d", path, index), fmt.Sprintf("%s.%d", path, index+1)); err != nil && !os.IsNotExist(err) {
+			return
+		}
+	}
+	return os.Rename(path, path+".1")
+}
+
+// AppendChained passes the last record of the audit log to chain and appends the record chain returns as a single line.
+// The log is locked while the record is chained, as the processes writing to it may run concurrently.
+// A last record torn by a crash while it was written is passed as is, and the record is appended on a new line after it.
+func AppendChained(path string, chain func(lastLine string) (content string, err error)) (content string, err error) {
+	if err = fileutil.MakeDirs(filepath.Dir(path)); err != nil {
+		return
+	}
+
+	var unlock func()
+	if unlock, err = lock(path); err != nil {
+		return
+	}
+	defer unlock()
+
+	var auditFile *os.File
+	if auditFile, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, appconfig.ReadWriteAccess); err != nil {
+		return
+	}
+	defer auditFile.Close()
+
+	var lastLine string
+	if lastLine, err = readLastLine(auditFile); err != nil {
+		return
+	}
+	if content, err = chain(lastLine); err != nil {
+		return
+	}
+	var terminated bool
+	if terminated, err = endsWithNewline(auditFile); err != nil {
+		return
+	}
+	if terminated {
+		_, err = auditFile.WriteString(content + "\n")
+	} else {
+		_, err = auditFile.WriteString("\n" + content + "\n")
+	}
+	return
+}
+
+// lock acquires the lock serializing the processes writing to the audit log and returns the function releasing it
+func lock(path string) (unlock func(), err error) {
+	lockPath := path + lockFileExtension
+	ownerId := filelock.GetOwnerIdForProcess()
+	var locked bool
+	if locked, err = filelock.LockFileWithRetry(lockPath, ownerId, lockTimeoutSeconds, lockAttempts); err != nil {
+		return nil, err
+	}
+	if !locked {
+		return nil, fmt.Errorf("unable to acquire audit log lock %s", lockPath)
+	}
+	return func() { filelock.UnlockFile(lockPath, ownerId) }, nil
+}
+
+// ChainKey returns the key chained audit logs are signed with, generating it in the agent vault on first use.
+// It is called by chain functions of AppendChained, whose lock keeps concurrent writers from generating different keys.
+func ChainKey() (key []byte, err error) {
+	var exists bool
+	if exists, err = vaultExists(chainKeyName); err != nil {
+		return
+	}
+	if exists {
+		return vaultRetrieve(chainKeyName)
+	}
+
+	key = make([]byte, chainKeySize)
+	if _, err = rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err = vaultStore(chainKeyName, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of the content with the given key
+func Sign(key []byte, content string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(content))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Publish publishes the record to the given log stream of the log group, creating them if needed
+func Publish(log log.T, cwl cloudwatchlogsinterface.ICloudWatchLogsService, logGroup string, logStream string, content string) (err error) {
+	if err = cwl.CreateLogGroup(log, logGroup); err != nil {
+		return
+	}
+	if err = cwl.CreateLogStream(log, logGroup, logStream); err != nil {
+		return
+	}
+
+	events := []*cloudwatchlogs.InputLogEvent{
+		{
+			Message:   aws.String(content),
+			Timestamp: aws.Int64(time.Now().UnixNano() / int64(time.Millisecond)),
+		},
+	}
+	_, err = cwl.PutLogEvents(log, events, logGroup, logStream, nil)
+	return
+}
+
+// Publisher publishes records to a log group of CloudWatch Logs in the background, so that writers do not wait for
+// CloudWatch Logs. The log group and log streams are created once, and the records queued while a batch is published
+// are published together in the next batch.
+type Publisher struct {
+	log      log.T
+	cwl      cloudwatchlogsinterface.ICloudWatchLogsService
+	logGroup string
+	records  chan publishedRecord
+	pending  sync.WaitGroup
+
+	groupCreated   bool
+	streamsCreated map[string]bool
+}
+
+// publishedRecord is a record queued for a log stream
+type publishedRecord struct {
+	logStream string
+	event     *cloudwatchlogs.InputLogEvent
+}
+
+// NewPublisher returns a publisher of records to the log group, which publishes records until the process exits
+func NewPublisher(log log.T, cwl cloudwatchlogsinterface.ICloudWatchLogsService, logGroup string) *Publisher {
+	publisher := &Publisher{
+		log:            log,
+		cwl:            cwl,
+		logGroup:       logGroup,
+		records:        make(chan publishedRecord, publishQueueSize),
+		streamsCreated: make(map[string]bool),
+	}
+	go publisher.run()
+	return publisher
+}
+
+// Publish queues the record to be published to the log stream, dropping it if the queue is full
+func (publisher *Publisher) Publish(logStream string, content string) {
+	record := publishedRecord{
+		logStream: logStream,
+		event: &cloudwatchlogs.InputLogEvent{
+			Message:   aws.String(content),
+			Timestamp: aws.Int64(time.Now().UnixNano() / int64(time.Millisecond)),
+		},
+	}
+	publisher.pending.Add(1)
+	select {
+	case publisher.records <- record:
+	default:
+		publisher.pending.Done()
+		publisher.log.Errorf("Dropping audit record as the queue of records published to log group %s is full", publisher.logGroup)
+	}
+}
+
+// Flush waits up to the timeout for the queued records to be published, and returns false if they were not
+func (publisher *Publisher) Flush(timeout time.Duration) bool {
+	published := make(chan bool)
+	go func() {
+		publisher.pending.Wait()
+		close(published)
+	}()
+	select {
+	case <-published:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// run publishes the queued records in batches of the records queued for the same log stream
+func (publisher *Publisher) run() {
+	for record := range publisher.records {
+		batch := []publishedRecord{record}
+		for queued := true; queued && len(batch) < publishBatchSize; {
+			select {
+			case next := <-publisher.records:
+				batch = append(batch, next)
+			default:
+				queued = false
+			}
+		}
+
+		var streams []string
+		events := make(map[string][]*cloudwatchlogs.InputLogEvent)
+		for _, record := range batch {
+			if _, ok := events[record.logStream]; !ok {
+				streams = append(streams, record.logStream)
+			}
+			events[record.logStream] = append(events[record.logStream], record.event)
+		}
+		for _, logStream := range streams {
+			if err := publisher.publish(logStream, events[logStream]); err != nil {
+				publisher.log.Errorf("Unable to publish %d audit records to CloudWatch Logs: %v", len(events[logStream]), err)
+			}
+		}
+		for range batch {
+			publisher.pending.Done()
+		}
+	}
+}
+
+// publish publishes the events to the log stream, creating the log group and the log stream if they were not created yet
+func (publisher *Publisher) publish(logStream string, events []*cloudwatchlogs.InputLogEvent) (err error) {
+	if !publisher.groupCreated {
+		if err = publisher.cwl.CreateLogGroup(publisher.log, publisher.logGroup); err != nil {
+			return
+		}
+		publisher.groupCreated = true
+	}
+	if !publisher.streamsCreated[logStream] {
+		if err = publisher.cwl.CreateLogStream(publisher.log, publisher.logGroup, logStream); err != nil {
+			return
+		}
+		publisher.streamsCreated[logStream] = true
+	}
+	_, err = publisher.cwl.PutLogEvents(publisher.log, events, publisher.logGroup, logStream, nil)
+	return
+}
+
+// readLastLine returns the last line of the file, reading the file backwards from its end in chunks
+func readLastLine(file *os.File) (string, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	var tail []byte
+	for offset := info.Size(); offset > 0; {
+		size := int64(readChunkSize)
+		if offset < size {
+			size = offset
+		}
+		offset -= size
+		chunk := make([]byte, size)
+		if _, err = file.ReadAt(chunk, offset); err != nil && err != io.EOF {
+			return "", err
+		}
+		tail = append(chunk, tail...)
+		if index := strings.LastIndex(strings.TrimRight(string(tail), "\n"), "\n"); index >= 0 {
+			return strings.TrimSpace(string(tail[index+1:])), nil
+		}
+	}
+	return strings.TrimSpace(string(tail)), nil
+}
+
+// endsWithNewline returns true if the file is empty or its last line is terminated
+func endsWithNewline(file *os.File) (bool, error) {
+	info, err := file.Stat()
+	if err != nil || info.Size() == 0 {
+		return true, err
+	}
+	last := make([]byte, 1)
+	if _, err = file.ReadAt(last, info.Size()-1); err != nil {
+		return false, err
+	}
+	return last[0] == '\n', nil
+}
@@ -0,0 +1,220 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package auditlog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	cloudwatchlogspublisher_mock "github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/mock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAppend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auditlog")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "logs", "audit.log")
+	assert.NoError(t, Append(path, "record1"))
+	assert.NoError(t, Append(path, "record2"))
+
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "record1\nrecord2\n", string(content))
+}
+
+func TestAppendRolled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auditlog")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// every record fills the log, so each one rolls the previous one over
+	path := filepath.Join(dir, "audit.log")
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, AppendRolled(path, fmt.Sprintf("record%v", i), 8, 2))
+	}
+
+	for path, expected := range map[string]string{path: "record3\n", path + ".1": "record2\n", path + ".2": "record1\n"} {
+		content, err := ioutil.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(content))
+	}
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(path + lockFileExtension)
+	assert.True(t, os.IsNotExist(err))
+
+	// records are appended while they fit
+	assert.NoError(t, AppendRolled(path, "r4", 20, 2))
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "record3\nr4\n", string(content))
+}
+
+func TestAppendChained(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auditlog")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	var lastLines []string
+	for i := 0; i < 3; i++ {
+		content, err := AppendChained(path, func(lastLine string) (string, error) {
+			lastLines = append(lastLines, lastLine)
+			return fmt.Sprintf("record%v", i), nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("record%v", i), content)
+	}
+	assert.Equal(t, []string{"", "record0", "record1"}, lastLines)
+
+	// a failing chain function appends nothing
+	_, err = AppendChained(path, func(lastLine string) (string, error) {
+		return "", fmt.Errorf("invalid record")
+	})
+	assert.Error(t, err)
+	content, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "record0\nrecord1\nrecord2\n", string(content))
+
+	_, err = os.Stat(path + lockFileExtension)
+	assert.True(t, os.IsNotExist(err))
+
+	// a record torn by a crash is passed to the chain function and the next record starts on a new line
+	assert.NoError(t, ioutil.WriteFile(path, []byte("record0\nrec"), 0600))
+	_, err = AppendChained(path, func(lastLine string) (string, error) {
+		assert.Equal(t, "rec", lastLine)
+		return "record1", nil
+	})
+	assert.NoError(t, err)
+	content, err = ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "record0\nrec\nrecord1\n", string(content))
+}
+
+func TestChainKey(t *testing.T) {
+	vault := map[string][]byte{}
+	vaultExists = func(key string) (bool, error) {
+		_, ok := vault[key]
+		return ok, nil
+	}
+	vaultRetrieve = func(key string) ([]byte, error) {
+		return vault[key], nil
+	}
+	vaultStore = func(key string, data []byte) error {
+		vault[key] = data
+		return nil
+	}
+
+	key, err := ChainKey()
+	assert.NoError(t, err)
+	assert.Equal(t, chainKeySize, len(key))
+	assert.Equal(t, key, vault[chainKeyName])
+
+	// the stored key is reused
+	again, err := ChainKey()
+	assert.NoError(t, err)
+	assert.Equal(t, key, again)
+
+	// a vault error is returned instead of generating a new key
+	vaultExists = func(key string) (bool, error) {
+		return false, fmt.Errorf("vault unavailable")
+	}
+	_, err = ChainKey()
+	assert.Error(t, err)
+	assert.Equal(t, key, vault[chainKeyName])
+}
+
+func TestSign(t *testing.T) {
+	// RFC 4231 test case 2
+	assert.Equal(t, "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+		Sign([]byte("Jefe"), "what do ya want for nothing?"))
+	assert.NotEqual(t, Sign([]byte("key1"), "content"), Sign([]byte("key2"), "content"))
+}
+
+func TestPublish(t *testing.T) {
+	mockLog := log.NewMockLog()
+	cwl := new(cloudwatchlogspublisher_mock.CloudWatchLogsServiceMock)
+	cwl.On("CreateLogGroup", mockLog, "audit-group").Return(nil)
+	cwl.On("CreateLogStream", mockLog, "audit-group", "audit-stream").Return(nil)
+	cwl.On("PutLogEvents", mockLog, mock.Anything, "audit-group", "audit-stream", mock.Anything).Return(nil, nil)
+
+	assert.NoError(t, Publish(mockLog, cwl, "audit-group", "audit-stream", "record"))
+	cwl.AssertExpectations(t)
+}
+
+func TestPublisher(t *testing.T) {
+	mockLog := log.NewMockLog()
+	cwl := new(cloudwatchlogspublisher_mock.CloudWatchLogsServiceMock)
+	cwl.On("CreateLogGroup", mockLog, "audit-group").Return(nil).Once()
+	cwl.On("CreateLogStream", mockLog, "audit-group", "stream1").Return(nil).Once()
+	cwl.On("CreateLogStream", mockLog, "audit-group", "stream2").Return(nil).Once()
+	var published []string
+	cwl.On("PutLogEvents", mockLog, mock.Anything, "audit-group", mock.Anything, mock.Anything).Return(nil, nil).Run(func(args mock.Arguments) {
+		for _, event := range args.Get(1).([]*cloudwatchlogs.InputLogEvent) {
+			published = append(published, args.String(3)+":"+*event.Message)
+		}
+	})
+
+	publisher := NewPublisher(mockLog, cwl, "audit-group")
+	publisher.Publish("stream1", "record1")
+	publisher.Publish("stream1", "record2")
+	publisher.Publish("stream2", "record3")
+	assert.True(t, publisher.Flush(time.Second))
+
+	// the log group and log streams are created once
+	publisher.Publish("stream1", "record4")
+	assert.True(t, publisher.Flush(time.Second))
+	cwl.AssertExpectations(t)
+	assert.Equal(t, []string{"stream1:record1", "stream1:record2", "stream2:record3", "stream1:record4"}, published)
+}
+
+func TestPublisherFlushTimesOut(t *testing.T) {
+	mockLog := log.NewMockLog()
+	cwl := new(cloudwatchlogspublisher_mock.CloudWatchLogsServiceMock)
+	unblock := make(chan bool)
+	cwl.On("CreateLogGroup", mockLog, "audit-group").Return(nil).Run(func(args mock.Arguments) {
+		<-unblock
+	})
+	cwl.On("CreateLogStream", mockLog, "audit-group", "stream").Return(nil)
+	cwl.On("PutLogEvents", mockLog, mock.Anything, "audit-group", "stream", mock.Anything).Return(nil, nil)
+
+	publisher := NewPublisher(mockLog, cwl, "audit-group")
+	publisher.Publish("stream", "record")
+	assert.False(t, publisher.Flush(10*time.Millisecond))
+	close(unblock)
+	assert.True(t, publisher.Flush(time.Second))
+}
+
+func TestReadLastLineOfLongRecords(t *testing.T) {
+	file, err := ioutil.TempFile("", "auditlog")
+	assert.NoError(t, err)
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	last := strings.Repeat("b", 2*readChunkSize)
+	file.WriteString(strings.Repeat("a", readChunkSize) + "\n" + last + "\n")
+	line, err := readLastLine(file)
+	assert.NoError(t, err)
+	assert.Equal(t, last, line)
+}
@@ -82,6 +82,7 @@ type CloudWatchConfiguration struct {
 	LogGroupName              string
 	LogStreamPrefix           string
 	LogGroupEncryptionEnabled bool
+	FlushIntervalSeconds      int
 }
 
 // IOConfiguration represents information relevant to the output sources of a command
@@ -90,6 +91,10 @@ type IOConfiguration struct {
 	OutputS3BucketName     string
 	OutputS3KeyPrefix      string
 	CloudWatchConfig       CloudWatchConfiguration
+	// SpillS3BucketName receives the full output of steps exceeding the result size limit when OutputS3BucketName is not set
+	SpillS3BucketName string
+	// DryRun reports the steps that would run and their resolved inputs instead of running them
+	DryRun bool
 }
 
 // DocumentState represents information relevant to a command that gets executed by agent
@@ -117,8 +122,10 @@ func (c *DocumentState) IsAssociation() bool {
 type CancelCommandInfo struct {
 	CancelMessageID string
 	CancelCommandID string
-	Payload         string
-	DebugInfo       string
+	// CancelPluginID is the step to cancel, the whole command is canceled when it is empty
+	CancelPluginID string
+	Payload        string
+	DebugInfo      string
 }
 
 // UpdateDocState updates the current document state
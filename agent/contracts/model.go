@@ -173,14 +173,35 @@ type PluginConfig struct {
 
 // InstancePluginConfig stores plugin configuration
 type InstancePluginConfig struct {
-	Action        string              `json:"action" yaml:"action"` // plugin name
-	Inputs        interface{}         `json:"inputs" yaml:"inputs"` // Properties
-	MaxAttempts   int                 `json:"maxAttempts" yaml:"maxAttempts"`
-	Name          string              `json:"name" yaml:"name"` // unique identifier
-	OnFailure     string              `json:"onFailure" yaml:"onFailure"`
-	Settings      interface{}         `json:"settings" yaml:"settings"`
-	Timeout       int                 `json:"timeoutSeconds" yaml:"timeoutSeconds"`
-	Preconditions map[string][]string `json:"precondition" yaml:"precondition"`
+	Action        string               `json:"action" yaml:"action"` // plugin name
+	Inputs        interface{}          `json:"inputs" yaml:"inputs"` // Properties
+	MaxAttempts   int                  `json:"maxAttempts" yaml:"maxAttempts"`
+	Name          string               `json:"name" yaml:"name"` // unique identifier
+	OnFailure     string               `json:"onFailure" yaml:"onFailure"`
+	Settings      interface{}          `json:"settings" yaml:"settings"`
+	Timeout       int                  `json:"timeoutSeconds" yaml:"timeoutSeconds"`
+	Preconditions map[string][]string  `json:"precondition" yaml:"precondition"`
+	DependsOn     []string             `json:"dependsOn" yaml:"dependsOn"` // names of the steps that must succeed first
+	Retries       int                  `json:"retries" yaml:"retries"`
+	RetryInterval int                  `json:"retryIntervalSeconds" yaml:"retryIntervalSeconds"`
+	LoopUntil     *StepLoopCondition   `json:"loopUntil" yaml:"loopUntil"`
+	ExitCodes     []StepExitCodeStatus `json:"exitCodes" yaml:"exitCodes"`
+}
+
+// StepLoopCondition stores the condition that ends the repeated execution of a step
+type StepLoopCondition struct {
+	ExitCode       *int   `json:"exitCode" yaml:"exitCode"`
+	OutputContains string `json:"outputContains" yaml:"outputContains"`
+	MaxIterations  int    `json:"maxIterations" yaml:"maxIterations"`
+	Interval       int    `json:"intervalSeconds" yaml:"intervalSeconds"`
+}
+
+// StepExitCodeStatus stores the status of a step whose plugin exits with the exit code.
+// Failed statuses are only retried if they are retryable.
+type StepExitCodeStatus struct {
+	ExitCode  int    `json:"exitCode" yaml:"exitCode"`
+	Status    string `json:"status" yaml:"status"`
+	Retryable bool   `json:"retryable" yaml:"retryable"`
 }
 
 // DocumentContent object which represents ssm document content.
@@ -190,18 +211,32 @@ type DocumentContent struct {
 	RuntimeConfig map[string]*PluginConfig `json:"runtimeConfig" yaml:"runtimeConfig"`
 	MainSteps     []*InstancePluginConfig  `json:"mainSteps" yaml:"mainSteps"`
 	Parameters    map[string]*Parameter    `json:"parameters" yaml:"parameters"`
+	// RunAsUser is the user the scripts of the document run as on Linux instead of the default user of the agent
+	RunAsUser string `json:"runAsUser" yaml:"runAsUser"`
+	// OutputFlushIntervalSeconds is how often the output of the document is streamed while it runs instead of the interval of the agent
+	OutputFlushIntervalSeconds int `json:"outputFlushIntervalSeconds" yaml:"outputFlushIntervalSeconds"`
 }
 
 // SessionInputs stores session configuration
 type SessionInputs struct {
-	S3BucketName                string `json:"s3BucketName" yaml:"s3BucketName"`
-	S3KeyPrefix                 string `json:"s3KeyPrefix" yaml:"s3KeyPrefix"`
-	S3EncryptionEnabled         bool   `json:"s3EncryptionEnabled" yaml:"s3EncryptionEnabled"`
-	CloudWatchLogGroupName      string `json:"cloudWatchLogGroupName" yaml:"cloudWatchLogGroupName"`
-	CloudWatchEncryptionEnabled bool   `json:"cloudWatchEncryptionEnabled" yaml:"cloudWatchEncryptionEnabled"`
-	KmsKeyId                    string `json:"kmsKeyId" yaml:"kmsKeyId"`
-	RunAsEnabled                bool   `json:"runAsEnabled" yaml:"runAsEnabled"`
-	RunAsDefaultUser            string `json:"runAsDefaultUser" yaml:"runAsDefaultUser"`
+	S3BucketName                string             `json:"s3BucketName" yaml:"s3BucketName"`
+	S3KeyPrefix                 string             `json:"s3KeyPrefix" yaml:"s3KeyPrefix"`
+	S3EncryptionEnabled         bool               `json:"s3EncryptionEnabled" yaml:"s3EncryptionEnabled"`
+	S3ClientSideKmsKeyId        string             `json:"s3ClientSideKmsKeyId" yaml:"s3ClientSideKmsKeyId"`
+	CloudWatchLogGroupName      string             `json:"cloudWatchLogGroupName" yaml:"cloudWatchLogGroupName"`
+	CloudWatchEncryptionEnabled bool               `json:"cloudWatchEncryptionEnabled" yaml:"cloudWatchEncryptionEnabled"`
+	CloudWatchStreamingEnabled  bool               `json:"cloudWatchStreamingEnabled" yaml:"cloudWatchStreamingEnabled"`
+	KmsKeyId                    string             `json:"kmsKeyId" yaml:"kmsKeyId"`
+	RunAsEnabled                bool               `json:"runAsEnabled" yaml:"runAsEnabled"`
+	RunAsDefaultUser            string             `json:"runAsDefaultUser" yaml:"runAsDefaultUser"`
+	ShellProfile                ShellProfileConfig `json:"shellProfile" yaml:"shellProfile"`
+	EnvironmentVariables        map[string]string  `json:"environmentVariables" yaml:"environmentVariables"`
+}
+
+// ShellProfileConfig represents the commands run at the start of an interactive shell session on each platform.
+type ShellProfileConfig struct {
+	Windows string `json:"windows" yaml:"windows"`
+	Linux   string `json:"linux" yaml:"linux"`
 }
 
 // SessionDocumentContent object which represents ssm session content.
@@ -64,6 +64,10 @@ type ICoreModule interface {
 // These plugins are invoked on demand.
 type IWorkerPlugin IPlugin
 
+// PreconditionExpression is the precondition operator whose operands are expressions over document parameters,
+// instance tags and the platform, evaluated by the executer.
+const PreconditionExpression = "Expression"
+
 // Configuration represents a plugin configuration as in the json format.
 type Configuration struct {
 	Settings                    interface{}
@@ -71,8 +75,10 @@ type Configuration struct {
 	OutputS3KeyPrefix           string
 	OutputS3BucketName          string
 	S3EncryptionEnabled         bool
+	S3ClientSideKmsKeyId        string
 	CloudWatchLogGroup          string
 	CloudWatchEncryptionEnabled bool
+	CloudWatchStreamingEnabled  bool
 	OrchestrationDirectory      string
 	MessageId                   string
 	BookKeepingFileName         string
@@ -81,12 +87,22 @@ type Configuration struct {
 	DefaultWorkingDirectory     string
 	Preconditions               map[string][]string
 	IsPreconditionEnabled       bool
+	DependsOn                   []string
+	Retries                     int
+	RetryIntervalSeconds        int
+	LoopUntil                   *StepLoopCondition
+	ExitCodes                   []StepExitCodeStatus
 	CurrentAssociations         []string
 	SessionId                   string
 	ClientId                    string
 	KmsKeyId                    string
 	RunAsEnabled                bool
 	RunAsUser                   string
+	ShellProfile                ShellProfileConfig
+	EnvironmentVariables        map[string]string
+	DocumentParameters          map[string]interface{}
+	DocumentName                string
+	DocumentHash                string
 }
 
 // Plugin wraps the plugin configuration and plugin result.
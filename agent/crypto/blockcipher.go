@@ -21,12 +21,15 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"golang.org/x/crypto/chacha20poly1305"
 )
 
 const nonceSize = 12
 
 type IBlockCipher interface {
+	SetCipher(cipherName string) error
 	UpdateEncryptionKey(log log.T, cipherTextKey []byte, sessionId string, instanceId string) error
 	EncryptWithAESGCM(plainText []byte) (cipherText []byte, err error)
 	DecryptWithAESGCM(cipherText []byte) (plainText []byte, err error)
@@ -42,6 +45,8 @@ type BlockCipher struct {
 	decryptionKey    []byte
 	encryptionCipher cipher.AEAD
 	decryptionCipher cipher.AEAD
+	// cipherName is the AEAD cipher used with the data keys, AES-GCM unless set otherwise
+	cipherName string
 }
 
 // NewBlockCipher creates a new block cipher
@@ -59,10 +64,27 @@ func NewBlockCipherKMS(log log.T, kmsKeyId string, kmsService IKMSService) (bloc
 	blockCipher = &BlockCipher{
 		kmsKeyId:   kmsKeyId,
 		kmsService: kmsService,
+		cipherName: appconfig.SessionEncryptionCipherAESGCM,
 	}
 	return blockCipher, nil
 }
 
+// SetCipher selects the AEAD cipher used with the data keys, applying it to keys already retrieved.
+func (blockCipher *BlockCipher) SetCipher(cipherName string) (err error) {
+	if _, err = getAEAD(cipherName, make([]byte, KMSKeySizeInBytes/2)); err != nil {
+		return err
+	}
+	blockCipher.cipherName = cipherName
+	if blockCipher.encryptionKey == nil {
+		return nil
+	}
+	if blockCipher.encryptionCipher, err = getAEAD(cipherName, blockCipher.encryptionKey); err != nil {
+		return err
+	}
+	blockCipher.decryptionCipher, err = getAEAD(cipherName, blockCipher.decryptionKey)
+	return err
+}
+
 // UpdateEncryptionKey receives cipherTextBlob and calls kms::Decrypt to receive the encryption data key
 func (blockCipher *BlockCipher) UpdateEncryptionKey(log log.T, cipherTextBlob []byte, sessionId string, instanceId string) error {
 	// NewBlockCipher creates a new instance of BlockCipher
@@ -85,17 +107,26 @@ func (blockCipher *BlockCipher) UpdateEncryptionKey(log log.T, cipherTextBlob []
 	blockCipher.cipherTextKey = cipherTextBlob
 	blockCipher.encryptionKey = plainTextKey[:cryptoKeySizeInBytes]
 	blockCipher.decryptionKey = plainTextKey[cryptoKeySizeInBytes:]
-	if blockCipher.encryptionCipher, err = getAEAD(blockCipher.encryptionKey); err != nil {
+	if blockCipher.encryptionCipher, err = getAEAD(blockCipher.cipherName, blockCipher.encryptionKey); err != nil {
 		return err
 	}
-	if blockCipher.decryptionCipher, err = getAEAD(blockCipher.decryptionKey); err != nil {
+	if blockCipher.decryptionCipher, err = getAEAD(blockCipher.cipherName, blockCipher.decryptionKey); err != nil {
 		return err
 	}
 	return nil
 }
 
-// getAEAD gets AEAD which is a GCM cipher mode providing authenticated encryption with associated data
-func getAEAD(plainTextKey []byte) (aesgcm cipher.AEAD, err error) {
+// getAEAD gets the AEAD cipher providing authenticated encryption with associated data,
+// either AES in GCM mode or ChaCha20-Poly1305
+func getAEAD(cipherName string, plainTextKey []byte) (aesgcm cipher.AEAD, err error) {
+	switch cipherName {
+	case appconfig.SessionEncryptionCipherChaCha20Poly1305:
+		return chacha20poly1305.New(plainTextKey)
+	case appconfig.SessionEncryptionCipherAESGCM, "":
+	default:
+		return nil, fmt.Errorf("Unsupported cipher %s", cipherName)
+	}
+
 	var block cipher.Block
 	if block, err = aes.NewCipher(plainTextKey); err != nil {
 		return nil, fmt.Errorf("Error creating NewCipher, %v", err)
@@ -108,7 +139,7 @@ func getAEAD(plainTextKey []byte) (aesgcm cipher.AEAD, err error) {
 	return aesgcm, nil
 }
 
-// EncryptWithGCM encrypts plain text using AES block cipher GCM mode
+// EncryptWithAESGCM encrypts plain text using the selected AEAD cipher, AES block cipher GCM mode by default
 func (blockCipher *BlockCipher) EncryptWithAESGCM(plainText []byte) (cipherText []byte, err error) {
 	var aesgcm = blockCipher.encryptionCipher
 
@@ -127,7 +158,7 @@ func (blockCipher *BlockCipher) EncryptWithAESGCM(plainText []byte) (cipherText
 	return cipherText, nil
 }
 
-// DecryptWithGCM decrypts cipher text using AES block cipher GCM mode
+// DecryptWithAESGCM decrypts cipher text using the selected AEAD cipher, AES block cipher GCM mode by default
 func (blockCipher *BlockCipher) DecryptWithAESGCM(cipherText []byte) (plainText []byte, err error) {
 	var aesgcm = blockCipher.decryptionCipher
 
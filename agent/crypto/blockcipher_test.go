@@ -18,6 +18,7 @@ import (
 	"encoding/hex"
 	"testing"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/crypto/mocks"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/stretchr/testify/assert"
@@ -82,6 +83,68 @@ func (suite *BlockCipherTestSuite) TestEncryptDecrypt() {
 	suite.mockKMSService.AssertExpectations(suite.T())
 }
 
+// Testing Encrypt and Decrypt functions with ChaCha20-Poly1305
+func (suite *BlockCipherTestSuite) TestEncryptDecryptChaCha20Poly1305() {
+	var encryptionContext = map[string]*string{"aws:ssm:SessionId": &suite.sessionId, "aws:ssm:TargetId": &suite.instanceId}
+	suite.mockKMSService.On("Decrypt", suite.cipherTextKey, encryptionContext).Return(suite.plainTextKey, nil)
+	suite.mockKMSService.On("Decrypt", suite.cipherTextKeyFlipped, encryptionContext).Return(suite.plainTextKeyFlipped, nil)
+
+	blockCipher, err := NewBlockCipherKMS(suite.mockLog, suite.kmsKeyId, &suite.mockKMSService)
+	assert.Nil(suite.T(), err)
+	err = blockCipher.UpdateEncryptionKey(suite.mockLog, suite.cipherTextKey, suite.sessionId, suite.instanceId)
+	assert.Nil(suite.T(), err)
+	// the cipher is applied to keys already retrieved
+	err = blockCipher.SetCipher(appconfig.SessionEncryptionCipherChaCha20Poly1305)
+	assert.Nil(suite.T(), err)
+
+	blockCipherReversed, err := NewBlockCipherKMS(suite.mockLog, suite.kmsKeyId, &suite.mockKMSService)
+	assert.Nil(suite.T(), err)
+	err = blockCipherReversed.SetCipher(appconfig.SessionEncryptionCipherChaCha20Poly1305)
+	assert.Nil(suite.T(), err)
+	err = blockCipherReversed.UpdateEncryptionKey(suite.mockLog, suite.cipherTextKeyFlipped, suite.sessionId, suite.instanceId)
+	assert.Nil(suite.T(), err)
+
+	encryptedData, err := blockCipher.EncryptWithAESGCM(suite.plainTextData)
+	assert.Nil(suite.T(), err)
+	decryptedData, err := blockCipherReversed.DecryptWithAESGCM(encryptedData)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.plainTextData, decryptedData)
+
+	// AES-GCM cannot open ChaCha20-Poly1305 cipher text
+	err = blockCipherReversed.SetCipher(appconfig.SessionEncryptionCipherAESGCM)
+	assert.Nil(suite.T(), err)
+	_, err = blockCipherReversed.DecryptWithAESGCM(encryptedData)
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *BlockCipherTestSuite) TestSetCipherUnsupported() {
+	blockCipher, _ := NewBlockCipherKMS(suite.mockLog, suite.kmsKeyId, &suite.mockKMSService)
+
+	assert.NotNil(suite.T(), blockCipher.SetCipher("DES"))
+}
+
+// Testing the ChaCha20-Poly1305 AEAD with the test vectors of RFC 8439
+func (suite *BlockCipherTestSuite) TestChaCha20Poly1305TestVectors() {
+	key, _ := hex.DecodeString("808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f")
+	nonce, _ := hex.DecodeString("070000004041424344454647")
+	additionalData, _ := hex.DecodeString("50515253c0c1c2c3c4c5c6c7")
+	plainText := []byte("Ladies and Gentlemen of the class of '99: If I could offer you only one tip for the future, sunscreen would be it.")
+	expected, _ := hex.DecodeString("d31a8d34648e60db7b86afbc53ef7ec2a4aded51296e08fea9e2b5a736ee62d63dbea45e8ca9671282fafb69da92728b1a71de0a9e060b2905d6a5b67ecd3b3692ddbd7f2d778b8c9803aee328091b58fab324e4fad675945585808b4831d7bc3ff4def08e4b7a9de576d26586cec64b6116" +
+		"1ae10b594f09e26a7e902ecbd0600691")
+
+	aead, err := getAEAD(appconfig.SessionEncryptionCipherChaCha20Poly1305, key)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), expected, aead.Seal(nil, nonce, plainText, additionalData))
+
+	opened, err := aead.Open(nil, nonce, expected, additionalData)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), plainText, opened)
+
+	expected[0] ^= 1
+	_, err = aead.Open(nil, nonce, expected, additionalData)
+	assert.NotNil(suite.T(), err)
+}
+
 func (suite *BlockCipherTestSuite) TestGetCipherTextKey() {
 	var blockCipher IBlockCipher = &BlockCipher{cipherTextKey: suite.cipherTextKey}
 	assert.Equal(suite.T(), suite.cipherTextKey, blockCipher.GetCipherTextKey())
@@ -0,0 +1,138 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// crypto package provides methods to encrypt and decrypt data
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+const (
+	envelopeVersion   = 1
+	envelopeAlgorithm = "AES-256-GCM"
+)
+
+// EnvelopeHeader describes how the content of an envelope encrypted file was encrypted.
+// The encrypted file holds the 4 byte big endian length of the json encoded header, the header,
+// the nonce and the AES-GCM encrypted content.
+type EnvelopeHeader struct {
+	Version           int               `json:"Version"`
+	Algorithm         string            `json:"Algorithm"`
+	KMSKeyId          string            `json:"KMSKeyId"`
+	CipherTextKey     []byte            `json:"CipherTextKey"`
+	EncryptionContext map[string]string `json:"EncryptionContext"`
+}
+
+// EnvelopeEncryptFile encrypts the source file with a new data key generated under the given KMS key and
+// writes the result to the destination file. Only the encrypted data key is stored with the content.
+func EnvelopeEncryptFile(kmsService IKMSService, kmsKeyId string, encryptionContext map[string]string, srcPath string, dstPath string) (err error) {
+	var plainText []byte
+	if plainText, err = ioutil.ReadFile(srcPath); err != nil {
+		return err
+	}
+
+	var envelope []byte
+	if envelope, err = EnvelopeEncrypt(kmsService, kmsKeyId, encryptionContext, plainText); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dstPath, envelope, appconfig.ReadWriteAccess)
+}
+
+// EnvelopeEncrypt encrypts the plain text with a new data key generated under the given KMS key
+func EnvelopeEncrypt(kmsService IKMSService, kmsKeyId string, encryptionContext map[string]string, plainText []byte) (envelope []byte, err error) {
+	var cipherTextKey, plainTextKey []byte
+	if cipherTextKey, plainTextKey, err = kmsService.GenerateDataKey(kmsKeyId, toKMSEncryptionContext(encryptionContext)); err != nil {
+		return nil, fmt.Errorf("Unable to generate data key, %v", err)
+	}
+
+	aesgcm, err := getAEAD(appconfig.SessionEncryptionCipherAESGCM, plainTextKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, nonceSize)
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("Error when generating nonce for encryption, %v", err)
+	}
+
+	header, err := json.Marshal(EnvelopeHeader{
+		Version:           envelopeVersion,
+		Algorithm:         envelopeAlgorithm,
+		KMSKeyId:          kmsKeyId,
+		CipherTextKey:     cipherTextKey,
+		EncryptionContext: encryptionContext,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	binary.Write(&buffer, binary.BigEndian, uint32(len(header)))
+	buffer.Write(header)
+	buffer.Write(nonce)
+	// the header is authenticated as additional data so that it can't be altered
+	buffer.Write(aesgcm.Seal(nil, nonce, plainText, header))
+	return buffer.Bytes(), nil
+}
+
+// EnvelopeDecrypt decrypts content encrypted by EnvelopeEncrypt
+func EnvelopeDecrypt(kmsService IKMSService, envelope []byte) (plainText []byte, err error) {
+	if len(envelope) < 4 {
+		return nil, fmt.Errorf("Envelope is too short")
+	}
+	headerLength := int(binary.BigEndian.Uint32(envelope[:4]))
+	if len(envelope) < 4+headerLength+nonceSize {
+		return nil, fmt.Errorf("Envelope is too short")
+	}
+	headerBytes := envelope[4 : 4+headerLength]
+	var header EnvelopeHeader
+	if err = json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, fmt.Errorf("Invalid envelope header, %v", err)
+	}
+	if header.Version != envelopeVersion || header.Algorithm != envelopeAlgorithm {
+		return nil, fmt.Errorf("Unsupported envelope version %d with algorithm %s", header.Version, header.Algorithm)
+	}
+
+	var plainTextKey []byte
+	if plainTextKey, err = kmsService.Decrypt(header.CipherTextKey, toKMSEncryptionContext(header.EncryptionContext)); err != nil {
+		return nil, fmt.Errorf("Unable to retrieve data key, %v", err)
+	}
+	aesgcm, err := getAEAD(appconfig.SessionEncryptionCipherAESGCM, plainTextKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := envelope[4+headerLength : 4+headerLength+nonceSize]
+	if plainText, err = aesgcm.Open(nil, nonce, envelope[4+headerLength+nonceSize:], headerBytes); err != nil {
+		return nil, fmt.Errorf("Error decrypting encrypted text, %v", err)
+	}
+	return plainText, nil
+}
+
+// toKMSEncryptionContext converts the encryption context to the form used by the KMS API
+func toKMSEncryptionContext(encryptionContext map[string]string) map[string]*string {
+	kmsEncryptionContext := make(map[string]*string)
+	for key, value := range encryptionContext {
+		value := value
+		kmsEncryptionContext[key] = &value
+	}
+	return kmsEncryptionContext
+}
@@ -0,0 +1,69 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// crypto package provides methods to encrypt and decrypt data
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/crypto/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	envelopeCipherTextKey = []byte("cipherTextKey")
+	envelopePlainTextKey  = bytes.Repeat([]byte{7}, 32)
+	envelopeContext       = map[string]string{"aws:ssm:SessionId": "some-session-id"}
+)
+
+func TestEnvelopeEncryptDecrypt(t *testing.T) {
+	kmsService := &mocks.IKMSService{}
+	kmsService.On("GenerateDataKey", "kmsKeyId", mock.Anything).Return(envelopeCipherTextKey, envelopePlainTextKey, nil)
+	kmsService.On("Decrypt", envelopeCipherTextKey, mock.Anything).Return(envelopePlainTextKey, nil)
+
+	plainText := []byte("session transcript")
+	envelope, err := EnvelopeEncrypt(kmsService, "kmsKeyId", envelopeContext, plainText)
+	assert.NoError(t, err)
+	assert.False(t, bytes.Contains(envelope, plainText))
+	assert.False(t, bytes.Contains(envelope, envelopePlainTextKey))
+
+	decrypted, err := EnvelopeDecrypt(kmsService, envelope)
+	assert.NoError(t, err)
+	assert.Equal(t, plainText, decrypted)
+	kmsService.AssertExpectations(t)
+}
+
+func TestEnvelopeDecryptTamperedContent(t *testing.T) {
+	kmsService := &mocks.IKMSService{}
+	kmsService.On("GenerateDataKey", "kmsKeyId", mock.Anything).Return(envelopeCipherTextKey, envelopePlainTextKey, nil)
+	kmsService.On("Decrypt", envelopeCipherTextKey, mock.Anything).Return(envelopePlainTextKey, nil)
+
+	envelope, err := EnvelopeEncrypt(kmsService, "kmsKeyId", envelopeContext, []byte("session transcript"))
+	assert.NoError(t, err)
+	envelope[len(envelope)-1] ^= 1
+
+	_, err = EnvelopeDecrypt(kmsService, envelope)
+	assert.Error(t, err)
+}
+
+func TestEnvelopeEncryptDataKeyFailure(t *testing.T) {
+	kmsService := &mocks.IKMSService{}
+	kmsService.On("GenerateDataKey", "kmsKeyId", mock.Anything).Return(nil, nil, errors.New("access denied"))
+
+	_, err := EnvelopeEncrypt(kmsService, "kmsKeyId", envelopeContext, []byte("session transcript"))
+	assert.Error(t, err)
+}
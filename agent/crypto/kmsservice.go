@@ -19,6 +19,7 @@ import (
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/rip"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/request"
@@ -33,6 +34,7 @@ const KMSKeySizeInBytes int64 = 64
 
 type IKMSService interface {
 	Decrypt(cipherTextBlob []byte, encryptionContext map[string]*string) (plainText []byte, err error)
+	GenerateDataKey(kmsKeyId string, encryptionContext map[string]*string) (cipherTextKey []byte, plainTextKey []byte, err error)
 }
 
 type KMSService struct {
@@ -53,6 +55,12 @@ func NewKMSService(log log.T) (kmsService *KMSService, err error) {
 		log.Warnf("Failed to load appconfig: %s. Using default config.", err)
 	} else if appConfig.Kms.Endpoint != "" {
 		awsConfig.Endpoint = &appConfig.Kms.Endpoint
+	} else if rip.FipsEnabled() && awsConfig.Region != nil {
+		fipsEndpoint, fipsErr := rip.GetFipsEndpoint(*awsConfig.Region, "kms")
+		if fipsErr != nil {
+			return nil, fipsErr
+		}
+		awsConfig.Endpoint = &fipsEndpoint
 	}
 	agentName = appConfig.Agent.Name
 	agentVersion = appConfig.Agent.Version
@@ -77,3 +85,15 @@ func (kmsService *KMSService) Decrypt(cipherTextBlob []byte, encryptionContext m
 	}
 	return output.Plaintext, nil
 }
+
+// GenerateDataKey generates a new AES-256 data key under the given KMS key and returns it both encrypted and in plaintext
+func (kmsService *KMSService) GenerateDataKey(kmsKeyId string, encryptionContext map[string]*string) (cipherTextKey []byte, plainTextKey []byte, err error) {
+	output, err := kmsService.client.GenerateDataKey(&kms.GenerateDataKeyInput{
+		KeyId:             aws.String(kmsKeyId),
+		KeySpec:           aws.String(kms.DataKeySpecAes256),
+		EncryptionContext: encryptionContext})
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error when generating data key %s", err)
+	}
+	return output.CiphertextBlob, output.Plaintext, nil
+}
@@ -98,6 +98,20 @@ func (_m *IBlockCipher) GetKMSKeyId() string {
 	return r0
 }
 
+// SetCipher provides a mock function with given fields: cipherName
+func (_m *IBlockCipher) SetCipher(cipherName string) error {
+	ret := _m.Called(cipherName)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(cipherName)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdateEncryptionKey provides a mock function with given fields: _a0, cipherTextKey, sessionId, instanceId
 func (_m *IBlockCipher) UpdateEncryptionKey(_a0 log.T, cipherTextKey []byte, sessionId string, instanceId string) error {
 	ret := _m.Called(_a0, cipherTextKey, sessionId, instanceId)
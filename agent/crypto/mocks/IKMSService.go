@@ -42,3 +42,20 @@ func (_m *IKMSService) Decrypt(cipherTextBlob []byte, encryptionContext map[stri
 
 	return r0, r1
 }
+
+// GenerateDataKey provides a mock function with given fields: kmsKeyId, encryptionContext
+func (_m *IKMSService) GenerateDataKey(kmsKeyId string, encryptionContext map[string]*string) ([]byte, []byte, error) {
+	ret := _m.Called(kmsKeyId, encryptionContext)
+
+	var r0 []byte
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]byte)
+	}
+
+	var r1 []byte
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).([]byte)
+	}
+
+	return r0, r1, ret.Error(2)
+}
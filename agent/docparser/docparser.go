@@ -16,6 +16,8 @@
 package docparser
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -32,6 +34,11 @@ import (
 
 const (
 	preconditionSchemaVersion string = "2.2"
+	dependsOnSchemaVersion    string = "2.2"
+
+	maxStepRetries         = 10
+	maxStepLoopIterations  = 100
+	maxStepIntervalSeconds = 3600
 )
 
 // DocumentParserInfo represents the parsed information from the request
@@ -43,6 +50,8 @@ type DocumentParserInfo struct {
 	DocumentId        string
 	DefaultWorkingDir string
 	CloudWatchConfig  contracts.CloudWatchConfiguration
+	SpillS3Bucket     string
+	DryRun            bool
 }
 
 // InitializeDocState is a method to obtain the state of the document.
@@ -59,14 +68,32 @@ func InitializeDocState(log log.T,
 	docState.DocumentInformation = docInfo
 	docState.IOConfig = docContent.GetIOConfiguration(parserInfo)
 
+	// the hash identifies the document as received, before its parameters are resolved
+	documentHash := hashDocument(log, docContent)
+
 	pluginInfo, err := docContent.ParseDocument(log, docInfo, parserInfo, params)
 	if err != nil {
 		return
 	}
+	for i := range pluginInfo {
+		pluginInfo[i].Configuration.DocumentName = docInfo.DocumentName
+		pluginInfo[i].Configuration.DocumentHash = documentHash
+	}
 	docState.InstancePluginsInformation = pluginInfo
 	return docState, nil
 }
 
+// hashDocument returns the hex encoded sha256 hash of the json encoded document content
+func hashDocument(log log.T, docContent IDocumentContent) string {
+	content, err := jsonutil.Marshal(docContent)
+	if err != nil {
+		log.Warnf("Unable to hash document: %v", err)
+		return ""
+	}
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
+}
+
 type IDocumentContent interface {
 	GetSchemaVersion() string
 	GetIOConfiguration(parserInfo DocumentParserInfo) contracts.IOConfiguration
@@ -89,6 +116,8 @@ func (docContent *DocContent) GetIOConfiguration(parserInfo DocumentParserInfo)
 		OutputS3BucketName:     parserInfo.S3Bucket,
 		OutputS3KeyPrefix:      parserInfo.S3Prefix,
 		CloudWatchConfig:       parserInfo.CloudWatchConfig,
+		SpillS3BucketName:      parserInfo.SpillS3Bucket,
+		DryRun:                 parserInfo.DryRun,
 	}
 }
 
@@ -101,11 +130,21 @@ func (docContent *DocContent) ParseDocument(log log.T,
 	if err = validateSchema(docContent.SchemaVersion); err != nil {
 		return
 	}
-	if err = getValidatedParameters(log, params, docContent); err != nil {
+	var validParameters map[string]interface{}
+	if validParameters, err = getValidatedParameters(log, params, docContent); err != nil {
 		return
 	}
 
-	return parseDocumentContent(*docContent, parserInfo)
+	if pluginsInfo, err = parseDocumentContent(*docContent, parserInfo); err != nil {
+		return
+	}
+	// aws:runDocument steps can pass the parameters of the document on to the documents they run
+	for i := range pluginsInfo {
+		if pluginsInfo[i].Configuration.PluginName == appconfig.PluginRunDocument {
+			pluginsInfo[i].Configuration.DocumentParameters = validParameters
+		}
+	}
+	return
 }
 
 // GetSchemaVersion is a method used to get document schema version
@@ -272,6 +311,7 @@ func parsePluginStateForV10Schema(
 			PluginName:              pluginName,
 			PluginID:                pluginName,
 			DefaultWorkingDirectory: defaultWorkingDir,
+			RunAsUser:               docContent.RunAsUser,
 		}
 		pluginConfigurations = append(pluginConfigurations, &config)
 	}
@@ -300,6 +340,16 @@ func parsePluginStateForV20Schema(
 	// set precondition flag based on document schema version
 	isPreconditionEnabled := isPreconditionEnabled(docContent.SchemaVersion)
 
+	if err = validateStepDependencies(docContent); err != nil {
+		return pluginsInfo, err
+	}
+	if err = validateStepRepetition(docContent); err != nil {
+		return pluginsInfo, err
+	}
+	if err = validateStepExitCodes(docContent); err != nil {
+		return pluginsInfo, err
+	}
+
 	// getPluginConfigurations converts from PluginConfig (structure from the MDS message) to plugin.Configuration (structure expected by the plugin)
 	for _, instancePluginConfig := range docContent.MainSteps {
 		pluginName := instancePluginConfig.Action
@@ -315,7 +365,13 @@ func parsePluginStateForV20Schema(
 			PluginID:                instancePluginConfig.Name,
 			Preconditions:           instancePluginConfig.Preconditions,
 			IsPreconditionEnabled:   isPreconditionEnabled,
+			DependsOn:               instancePluginConfig.DependsOn,
+			Retries:                 instancePluginConfig.Retries,
+			RetryIntervalSeconds:    instancePluginConfig.RetryInterval,
+			LoopUntil:               instancePluginConfig.LoopUntil,
+			ExitCodes:               instancePluginConfig.ExitCodes,
 			DefaultWorkingDirectory: defaultWorkingDir,
+			RunAsUser:               docContent.RunAsUser,
 		}
 
 		var plugin contracts.PluginState
@@ -353,14 +409,18 @@ func (sessionDocContent *SessionDocContent) parsePluginStateForStartSession(
 		OutputS3KeyPrefix:           sessionDocContent.Inputs.S3KeyPrefix,
 		OutputS3BucketName:          sessionDocContent.Inputs.S3BucketName,
 		S3EncryptionEnabled:         sessionDocContent.Inputs.S3EncryptionEnabled,
+		S3ClientSideKmsKeyId:        sessionDocContent.Inputs.S3ClientSideKmsKeyId,
 		OrchestrationDirectory:      fileutil.BuildPath(parserInfo.OrchestrationDir, pluginName),
 		ClientId:                    clientId,
 		CloudWatchLogGroup:          sessionDocContent.Inputs.CloudWatchLogGroupName,
 		CloudWatchEncryptionEnabled: sessionDocContent.Inputs.CloudWatchEncryptionEnabled,
+		CloudWatchStreamingEnabled:  sessionDocContent.Inputs.CloudWatchStreamingEnabled,
 		KmsKeyId:                    sessionDocContent.Inputs.KmsKeyId,
 		Properties:                  sessionDocContent.Properties,
 		RunAsEnabled:                sessionDocContent.Inputs.RunAsEnabled,
 		RunAsUser:                   runAsUser,
+		ShellProfile:                sessionDocContent.Inputs.ShellProfile,
+		EnvironmentVariables:        sessionDocContent.Inputs.EnvironmentVariables,
 	}
 
 	var plugin contracts.PluginState
@@ -397,7 +457,8 @@ func validateSchema(documentSchemaVersion string) error {
 }
 
 // getValidatedParameters validates the parameters and modifies the document content by replacing all ssm parameters with their actual values.
-func getValidatedParameters(log log.T, params map[string]interface{}, docContent *DocContent) error {
+// It returns the valid parameters with the default values of the missing ones.
+func getValidatedParameters(log log.T, params map[string]interface{}, docContent *DocContent) (map[string]interface{}, error) {
 
 	//ValidateParameterNames
 	validParameters := parameters.ValidParameters(log, params)
@@ -412,11 +473,11 @@ func getValidatedParameters(log log.T, params map[string]interface{}, docContent
 	log.Info("Validating SSM parameters")
 	// Validates SSM parameters
 	if err := parameterstore.ValidateSSMParameters(log, docContent.Parameters, validParameters); err != nil {
-		return err
+		return nil, err
 	}
 
 	err := replaceValidatedPluginParameters(docContent, validParameters, log)
-	return err
+	return validParameters, err
 }
 
 // replaceValidatedPluginParameters replaces parameters with their values, within the plugin Properties.
@@ -458,6 +519,7 @@ func replaceValidatedPluginParameters(
 			updatedMainSteps[index] = instancePluginConfig
 			updatedMainSteps[index].Settings = parameters.ReplaceParameters(instancePluginConfig.Settings, params, logger)
 			updatedMainSteps[index].Inputs = parameters.ReplaceParameters(instancePluginConfig.Inputs, params, logger)
+			updatedMainSteps[index].Preconditions = replacePreconditionParameters(instancePluginConfig.Preconditions, params, logger)
 
 			logger.Debug("Resolving SSM parameters")
 			// Resolves SSM parameters
@@ -476,6 +538,25 @@ func replaceValidatedPluginParameters(
 	return nil
 }
 
+// replacePreconditionParameters replaces parameters within precondition expressions with string literals of their values.
+func replacePreconditionParameters(preconditions map[string][]string, params map[string]interface{}, logger log.T) map[string][]string {
+	expressions, found := preconditions[contracts.PreconditionExpression]
+	if !found {
+		return preconditions
+	}
+
+	updatedPreconditions := make(map[string][]string)
+	for operator, operands := range preconditions {
+		updatedPreconditions[operator] = operands
+	}
+	updatedExpressions := make([]string, len(expressions))
+	for index, expression := range expressions {
+		updatedExpressions[index] = parameters.ReplaceParametersWithLiterals(expression, params, logger)
+	}
+	updatedPreconditions[contracts.PreconditionExpression] = updatedExpressions
+	return updatedPreconditions
+}
+
 // isPreConditionEnabled checks if precondition support is enabled by checking document schema version
 func isPreconditionEnabled(schemaVersion string) (response bool) {
 	response = false
@@ -489,6 +570,116 @@ func isPreconditionEnabled(schemaVersion string) (response bool) {
 	return response
 }
 
+// validateStepDependencies checks that the dependsOn of the steps are supported by the schema version,
+// refer to steps of the document and do not form a cycle.
+func validateStepDependencies(docContent DocContent) error {
+	dependencies := make(map[string][]string)
+	hasDependencies := false
+	for _, step := range docContent.MainSteps {
+		dependencies[step.Name] = step.DependsOn
+		if len(step.DependsOn) > 0 {
+			hasDependencies = true
+		}
+	}
+	if !hasDependencies {
+		return nil
+	}
+
+	if versionCompare, err := updateutil.VersionCompare(docContent.SchemaVersion, dependsOnSchemaVersion); err != nil || versionCompare < 0 {
+		return fmt.Errorf("dependsOn is not supported by document schema version %s, use schema version %s or later", docContent.SchemaVersion, dependsOnSchemaVersion)
+	}
+	for _, step := range docContent.MainSteps {
+		for _, dependency := range step.DependsOn {
+			if _, found := dependencies[dependency]; !found {
+				return fmt.Errorf("step %s depends on unknown step %s", step.Name, dependency)
+			}
+			if dependency == step.Name {
+				return fmt.Errorf("step %s depends on itself", step.Name)
+			}
+		}
+	}
+
+	// depth first search for a step reached again while its dependencies are being visited
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	var visit func(stepName string) error
+	visit = func(stepName string) error {
+		switch state[stepName] {
+		case visiting:
+			return fmt.Errorf("dependsOn of step %s forms a cycle", stepName)
+		case visited:
+			return nil
+		}
+		state[stepName] = visiting
+		for _, dependency := range dependencies[stepName] {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		state[stepName] = visited
+		return nil
+	}
+	for _, step := range docContent.MainSteps {
+		if err := visit(step.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateStepRepetition checks the retries and loopUntil of the steps are within bounds.
+func validateStepRepetition(docContent DocContent) error {
+	for _, step := range docContent.MainSteps {
+		if step.Retries < 0 || step.Retries > maxStepRetries {
+			return fmt.Errorf("retries of step %s must be between 0 and %d", step.Name, maxStepRetries)
+		}
+		if step.RetryInterval < 0 || step.RetryInterval > maxStepIntervalSeconds {
+			return fmt.Errorf("retryIntervalSeconds of step %s must be between 0 and %d", step.Name, maxStepIntervalSeconds)
+		}
+		if step.LoopUntil == nil {
+			continue
+		}
+		if step.LoopUntil.ExitCode == nil && step.LoopUntil.OutputContains == "" {
+			return fmt.Errorf("loopUntil of step %s must set exitCode or outputContains", step.Name)
+		}
+		if step.LoopUntil.MaxIterations < 0 || step.LoopUntil.MaxIterations > maxStepLoopIterations {
+			return fmt.Errorf("loopUntil maxIterations of step %s must be between 0 and %d", step.Name, maxStepLoopIterations)
+		}
+		if step.LoopUntil.Interval < 0 || step.LoopUntil.Interval > maxStepIntervalSeconds {
+			return fmt.Errorf("loopUntil intervalSeconds of step %s must be between 0 and %d", step.Name, maxStepIntervalSeconds)
+		}
+	}
+	return nil
+}
+
+// validateStepExitCodes checks the exitCodes of the steps map each exit code once to a supported status.
+func validateStepExitCodes(docContent DocContent) error {
+	for _, step := range docContent.MainSteps {
+		exitCodes := make(map[int]bool)
+		for _, exitCodeStatus := range step.ExitCodes {
+			if exitCodes[exitCodeStatus.ExitCode] {
+				return fmt.Errorf("exitCodes of step %s map exit code %d more than once", step.Name, exitCodeStatus.ExitCode)
+			}
+			exitCodes[exitCodeStatus.ExitCode] = true
+
+			switch contracts.ResultStatus(exitCodeStatus.Status) {
+			case contracts.ResultStatusSuccess:
+				if exitCodeStatus.Retryable {
+					return fmt.Errorf("exitCodes of step %s cannot retry exit code %d with status %s", step.Name, exitCodeStatus.ExitCode, exitCodeStatus.Status)
+				}
+			case contracts.ResultStatusFailed:
+			default:
+				return fmt.Errorf("exitCodes of step %s map exit code %d to unsupported status %q, the status must be %s or %s",
+					step.Name, exitCodeStatus.ExitCode, exitCodeStatus.Status, contracts.ResultStatusSuccess, contracts.ResultStatusFailed)
+			}
+		}
+	}
+	return nil
+}
+
 // ParseDocumentNameAndVersion parses the name and version from the document name
 func ParseDocumentNameAndVersion(name string) (docName, docVersion string) {
 	if len(name) == 0 {
@@ -46,6 +46,7 @@ const (
 const parameterdocument = `{"schemaVersion":"1.2","description":"","parameters":{"commands":{"type":"StringList"}},"runtimeConfig":{"aws:runPowerShellScript":{"properties":[{"id":"0.aws:runPowerShellScript","runCommand":"{{ commands }}"}]}}}`
 const invaliddocument = `{"schemaVersion":"1.2","description":"PowerShell.","FOO":"bar"}`
 const testparameters = `{"commands":["date"]}`
+const expressiondocument = `{"schemaVersion":"2.2","description":"","parameters":{"Env":{"type":"String","default":"dev"}},"mainSteps":[{"action":"aws:runShellScript","name":"prodOnly","precondition":{"StringEquals":["platformType","Linux"],"Expression":["{{ Env }} == \"prod\" && platformVersion >= 20.04"]},"inputs":{"runCommand":["date"]}}]}`
 
 var sampleMessageFiles = []string{
 	"testdata/sampleMessageVersion2_0.json",
@@ -144,7 +145,7 @@ func TestInitializeDocState_Valid(t *testing.T) {
 		assert.Error(t, err, "Error occurred when trying to unmarshal valid document")
 	}
 
-	docState, err := InitializeDocState(mockLog, contracts.SendCommand, &testDocContent, contracts.DocumentInfo{}, testParserInfo, nil)
+	docState, err := InitializeDocState(mockLog, contracts.SendCommand, &testDocContent, contracts.DocumentInfo{DocumentName: "AWS-RunShellScript"}, testParserInfo, nil)
 
 	assert.Nil(t, err)
 
@@ -158,6 +159,9 @@ func TestInitializeDocState_Valid(t *testing.T) {
 	assert.Equal(t, testMessageID, pluginInfo[0].Configuration.MessageId)
 	assert.Equal(t, testDocumentID, pluginInfo[0].Configuration.BookKeepingFileName)
 	assert.Equal(t, testWorkingDir, pluginInfo[0].Configuration.DefaultWorkingDirectory)
+	assert.Equal(t, "AWS-RunShellScript", pluginInfo[0].Configuration.DocumentName)
+	assert.Equal(t, hashDocument(mockLog, &testDocContent), pluginInfo[0].Configuration.DocumentHash)
+	assert.Equal(t, 64, len(pluginInfo[0].Configuration.DocumentHash))
 	assert.Equal(t, testLogGroupName, docState.IOConfig.CloudWatchConfig.LogGroupName)
 	assert.Equal(t, testLogStreamPrefix, docState.IOConfig.CloudWatchConfig.LogStreamPrefix)
 }
@@ -426,6 +430,20 @@ func TestParseDocument_ValidParameters(t *testing.T) {
 	assert.NotEqual(t, parsedMessage, originalMessage)
 }
 
+func TestParseDocument_ExpressionPreconditionParameters(t *testing.T) {
+	var testDocContent DocContent
+	err := json.Unmarshal([]byte(expressiondocument), &testDocContent)
+	assert.NoError(t, err, "Error occurred when trying to unmarshal valid document")
+
+	pluginsInfo, err := testDocContent.ParseDocument(log.NewMockLog(), contracts.DocumentInfo{}, DocumentParserInfo{}, map[string]interface{}{"Env": "prod"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(pluginsInfo))
+	preconditions := pluginsInfo[0].Configuration.Preconditions
+	assert.Equal(t, []string{`"prod" == "prod" && platformVersion >= 20.04`}, preconditions[contracts.PreconditionExpression])
+	assert.Equal(t, []string{"platformType", "Linux"}, preconditions["StringEquals"])
+}
+
 func TestParseDocument_ReplaceDefaultParameters(t *testing.T) {
 	mockLog := log.NewMockLog()
 
@@ -479,6 +497,154 @@ func TestIsCrossPlatformEnabledForSchema22(t *testing.T) {
 	assert.True(t, isCrossPlatformEnabled)
 }
 
+func TestParseDocument_DependsOn(t *testing.T) {
+	testDocContent := loadDocContentFromFile(t, "testdata/sampleMessageDependsOn.json")
+
+	pluginsInfo, err := testDocContent.ParseDocument(log.NewMockLog(), contracts.DocumentInfo{}, DocumentParserInfo{}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(pluginsInfo))
+	assert.Empty(t, pluginsInfo[0].Configuration.DependsOn)
+	assert.Equal(t, []string{"install", "download"}, pluginsInfo[2].Configuration.DependsOn)
+}
+
+func TestParseDocument_DependsOnInvalid(t *testing.T) {
+	testCases := []struct {
+		schemaVersion string
+		dependsOn     []string
+		expectedError string
+	}{
+		{"2.0", []string{"install"}, "dependsOn is not supported by document schema version 2.0"},
+		{"2.2", []string{"configure"}, "step start depends on unknown step configure"},
+		{"2.2", []string{"start"}, "step start depends on itself"},
+	}
+
+	for _, testCase := range testCases {
+		testDocContent := loadDocContentFromFile(t, "testdata/sampleMessageDependsOn.json")
+		testDocContent.SchemaVersion = testCase.schemaVersion
+		testDocContent.MainSteps[2].DependsOn = testCase.dependsOn
+
+		_, err := testDocContent.ParseDocument(log.NewMockLog(), contracts.DocumentInfo{}, DocumentParserInfo{}, nil)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), testCase.expectedError)
+	}
+}
+
+func TestParseDocument_DependsOnCycle(t *testing.T) {
+	testDocContent := loadDocContentFromFile(t, "testdata/sampleMessageDependsOn.json")
+	testDocContent.MainSteps[0].DependsOn = []string{"start"}
+
+	_, err := testDocContent.ParseDocument(log.NewMockLog(), contracts.DocumentInfo{}, DocumentParserInfo{}, nil)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "forms a cycle")
+}
+
+func TestParseDocument_StepRepetition(t *testing.T) {
+	exitCode := 0
+	testDocContent := loadDocContentFromFile(t, "testdata/sampleMessageDependsOn.json")
+	testDocContent.MainSteps[0].Retries = 3
+	testDocContent.MainSteps[0].RetryInterval = 10
+	testDocContent.MainSteps[2].LoopUntil = &contracts.StepLoopCondition{ExitCode: &exitCode, MaxIterations: 5}
+
+	pluginsInfo, err := testDocContent.ParseDocument(log.NewMockLog(), contracts.DocumentInfo{}, DocumentParserInfo{}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, pluginsInfo[0].Configuration.Retries)
+	assert.Equal(t, 10, pluginsInfo[0].Configuration.RetryIntervalSeconds)
+	assert.Nil(t, pluginsInfo[0].Configuration.LoopUntil)
+	assert.Equal(t, 5, pluginsInfo[2].Configuration.LoopUntil.MaxIterations)
+}
+
+func TestParseDocument_RunAsUser(t *testing.T) {
+	testDocContent := loadDocContentFromFile(t, "testdata/sampleMessageDependsOn.json")
+	testDocContent.RunAsUser = "app-deployer"
+
+	pluginsInfo, err := testDocContent.ParseDocument(log.NewMockLog(), contracts.DocumentInfo{}, DocumentParserInfo{}, nil)
+
+	assert.NoError(t, err)
+	for _, pluginInfo := range pluginsInfo {
+		assert.Equal(t, "app-deployer", pluginInfo.Configuration.RunAsUser)
+	}
+}
+
+func TestParseDocument_StepRepetitionInvalid(t *testing.T) {
+	testCases := []struct {
+		retries       int
+		loopUntil     *contracts.StepLoopCondition
+		expectedError string
+	}{
+		{-1, nil, "retries of step install must be between 0 and 10"},
+		{11, nil, "retries of step install must be between 0 and 10"},
+		{0, &contracts.StepLoopCondition{MaxIterations: 5}, "loopUntil of step install must set exitCode or outputContains"},
+		{0, &contracts.StepLoopCondition{OutputContains: "ready", MaxIterations: 101}, "loopUntil maxIterations of step install must be between 0 and 100"},
+	}
+
+	for _, testCase := range testCases {
+		testDocContent := loadDocContentFromFile(t, "testdata/sampleMessageDependsOn.json")
+		testDocContent.MainSteps[0].Retries = testCase.retries
+		testDocContent.MainSteps[0].LoopUntil = testCase.loopUntil
+
+		_, err := testDocContent.ParseDocument(log.NewMockLog(), contracts.DocumentInfo{}, DocumentParserInfo{}, nil)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), testCase.expectedError)
+	}
+}
+
+func TestParseDocument_RunDocumentParameters(t *testing.T) {
+	testDocContent := loadDocContentFromFile(t, "testdata/sampleMessageDependsOn.json")
+	testDocContent.MainSteps[1].Action = appconfig.PluginRunDocument
+	testDocContent.Parameters = map[string]*contracts.Parameter{
+		"environment": {ParamType: "String"},
+		"version":     {ParamType: "String", DefaultVal: "1.2"},
+	}
+
+	pluginsInfo, err := testDocContent.ParseDocument(log.NewMockLog(), contracts.DocumentInfo{}, DocumentParserInfo{}, map[string]interface{}{"environment": "prod"})
+
+	assert.NoError(t, err)
+	assert.Nil(t, pluginsInfo[0].Configuration.DocumentParameters)
+	assert.Equal(t, map[string]interface{}{"environment": "prod", "version": "1.2"}, pluginsInfo[1].Configuration.DocumentParameters)
+}
+
+func TestParseDocument_ExitCodes(t *testing.T) {
+	exitCodes := []contracts.StepExitCodeStatus{
+		{ExitCode: 1, Status: "Success"},
+		{ExitCode: 3, Status: "Failed", Retryable: true},
+	}
+	testDocContent := loadDocContentFromFile(t, "testdata/sampleMessageDependsOn.json")
+	testDocContent.MainSteps[1].ExitCodes = exitCodes
+
+	pluginsInfo, err := testDocContent.ParseDocument(log.NewMockLog(), contracts.DocumentInfo{}, DocumentParserInfo{}, nil)
+
+	assert.NoError(t, err)
+	assert.Nil(t, pluginsInfo[0].Configuration.ExitCodes)
+	assert.Equal(t, exitCodes, pluginsInfo[1].Configuration.ExitCodes)
+}
+
+func TestParseDocument_ExitCodesInvalid(t *testing.T) {
+	testCases := []struct {
+		exitCodes     []contracts.StepExitCodeStatus
+		expectedError string
+	}{
+		{[]contracts.StepExitCodeStatus{{ExitCode: 2, Status: "Success"}, {ExitCode: 2, Status: "Failed"}}, "exitCodes of step install map exit code 2 more than once"},
+		{[]contracts.StepExitCodeStatus{{ExitCode: 2, Status: "Skipped"}}, "exitCodes of step install map exit code 2 to unsupported status \"Skipped\""},
+		{[]contracts.StepExitCodeStatus{{ExitCode: 2, Status: "success"}}, "unsupported status"},
+		{[]contracts.StepExitCodeStatus{{ExitCode: 2, Status: "Success", Retryable: true}}, "exitCodes of step install cannot retry exit code 2"},
+	}
+
+	for _, testCase := range testCases {
+		testDocContent := loadDocContentFromFile(t, "testdata/sampleMessageDependsOn.json")
+		testDocContent.MainSteps[0].ExitCodes = testCase.exitCodes
+
+		_, err := testDocContent.ParseDocument(log.NewMockLog(), contracts.DocumentInfo{}, DocumentParserInfo{}, nil)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), testCase.expectedError)
+	}
+}
+
 func TestParseMessageWithParams(t *testing.T) {
 	type testCase struct {
 		Input       string
@@ -575,3 +741,11 @@ func loadMessageFromFile(t *testing.T, fileName string) (testDocContent DocConte
 	}
 	return testDocContent, params
 }
+
+func loadDocContentFromFile(t *testing.T, fileName string) (testDocContent DocContent) {
+	err := json.Unmarshal(loadFile(t, fileName), &testDocContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testDocContent
+}
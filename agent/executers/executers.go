@@ -46,6 +46,16 @@ type T interface {
 	StartExe(log.T, string, io.Writer, io.Writer, task.CancelFlag, string, []string) (*os.Process, int, error)
 }
 
+// EnvironmentExecuter is implemented by the executers which can run commands with additional environment variables.
+type EnvironmentExecuter interface {
+	NewExecuteWithEnvironment(log.T, string, io.Writer, io.Writer, task.CancelFlag, int, string, []string, []string) (int, error)
+}
+
+// RunAsExecuter is implemented by the executers which can run commands as another user with additional environment variables.
+type RunAsExecuter interface {
+	NewExecuteAsUser(log.T, string, io.Writer, io.Writer, task.CancelFlag, int, string, []string, []string, string) (int, error)
+}
+
 // ShellCommandExecuter is specially added for testing purposes
 type ShellCommandExecuter struct {
 }
@@ -173,6 +183,40 @@ func (ShellCommandExecuter) NewExecute(
 	return
 }
 
+// NewExecuteWithEnvironment executes the commands like NewExecute with the environment variables, given as NAME=value pairs,
+// added to the environment of the agent.
+func (ShellCommandExecuter) NewExecuteWithEnvironment(
+	log log.T,
+	workingDir string,
+	stdoutWriter io.Writer,
+	stderrWriter io.Writer,
+	cancelFlag task.CancelFlag,
+	executionTimeout int,
+	commandName string,
+	commandArguments []string,
+	environment []string,
+) (exitCode int, err error) {
+	exitCode, err = ExecuteCommandWithEnvironment(log, cancelFlag, workingDir, stdoutWriter, stderrWriter, executionTimeout, commandName, commandArguments, environment)
+	return
+}
+
+// NewExecuteAsUser executes the commands like NewExecuteWithEnvironment as the given user instead of the agent user.
+func (ShellCommandExecuter) NewExecuteAsUser(
+	log log.T,
+	workingDir string,
+	stdoutWriter io.Writer,
+	stderrWriter io.Writer,
+	cancelFlag task.CancelFlag,
+	executionTimeout int,
+	commandName string,
+	commandArguments []string,
+	environment []string,
+	runAsUser string,
+) (exitCode int, err error) {
+	exitCode, err = ExecuteCommandAsUser(log, cancelFlag, workingDir, stdoutWriter, stderrWriter, executionTimeout, commandName, commandArguments, environment, runAsUser)
+	return
+}
+
 // StartExe starts a list of shell commands in the given working directory.
 // Returns process started, an exit code (0 if successfully launch, 1 if error launching process), and a set of errors.
 // The errors need not be fatal - the output streams may still have data
@@ -254,6 +298,36 @@ func ExecuteCommand(log log.T,
 	commandName string,
 	commandArguments []string,
 ) (exitCode int, err error) {
+	return ExecuteCommandWithEnvironment(log, cancelFlag, workingDir, stdoutWriter, stderrWriter, executionTimeout, commandName, commandArguments, nil)
+}
+
+// ExecuteCommandWithEnvironment executes the given commands like ExecuteCommand with the additional environment variables.
+func ExecuteCommandWithEnvironment(log log.T,
+	cancelFlag task.CancelFlag,
+	workingDir string,
+	stdoutWriter io.Writer,
+	stderrWriter io.Writer,
+	executionTimeout int,
+	commandName string,
+	commandArguments []string,
+	environment []string,
+) (exitCode int, err error) {
+	return ExecuteCommandAsUser(log, cancelFlag, workingDir, stdoutWriter, stderrWriter, executionTimeout, commandName, commandArguments, environment, "")
+}
+
+// ExecuteCommandAsUser executes the given commands like ExecuteCommandWithEnvironment as the given user,
+// or as the agent user if runAsUser is empty.
+func ExecuteCommandAsUser(log log.T,
+	cancelFlag task.CancelFlag,
+	workingDir string,
+	stdoutWriter io.Writer,
+	stderrWriter io.Writer,
+	executionTimeout int,
+	commandName string,
+	commandArguments []string,
+	environment []string,
+	runAsUser string,
+) (exitCode int, err error) {
 
 	stdoutInterruptable, stopStdout := newWriter(stdoutWriter)
 	stderrInterruptable, stopStderr := newWriter(stderrWriter)
@@ -284,6 +358,15 @@ func ExecuteCommand(log log.T,
 
 	// configure environment variables
 	prepareEnvironment(command)
+	command.Env = append(command.Env, environment...)
+
+	if runAsUser != "" {
+		if err = prepareRunAsUser(command, runAsUser); err != nil {
+			log.Errorf("unable to run the command as user %v: %v", runAsUser, err)
+			exitCode = 1
+			return
+		}
+	}
 
 	log.Debug()
 	log.Debugf("Running in directory %v, command: %v %v", workingDir, commandName, commandArguments)
@@ -203,6 +203,21 @@ func TestRunCommand_async(t *testing.T) {
 	}
 }
 
+// TestExecuteCommandWithEnvironment tests that the environment variables are set for the command
+func TestExecuteCommandWithEnvironment(t *testing.T) {
+	instanceTemp := instance
+	instance = &instanceInfoStub{instanceID: testInstanceID, regionName: testRegionName}
+	defer func() { instance = instanceTemp }()
+
+	var stdout, stderr bytes.Buffer
+	exitCode, err := ExecuteCommandWithEnvironment(logger, task.NewChanneledCancelFlag(), "", &stdout, &stderr, 10,
+		"sh", []string{"-c", `echo "$TARGET_HOST $AWS_SSM_INSTANCE_ID"`}, []string{"TARGET_HOST=db.example.com"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "db.example.com "+testInstanceID+"\n", stdout.String())
+}
+
 // TestShellCommandExecuter tests that ShellCommandExecuter (creates local script, redirects outputs to files) works
 func TestShellCommandExecuter(t *testing.T) {
 	instanceTemp := instance
@@ -16,8 +16,11 @@
 package executers
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
+	"os/user"
+	"strconv"
 	"strings"
 	"syscall"
 
@@ -30,6 +33,39 @@ func prepareProcess(command *exec.Cmd) {
 	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 }
 
+// prepareRunAsUser makes the process run with the credentials and home directory of the user
+func prepareRunAsUser(command *exec.Cmd, userName string) error {
+	u, err := user.Lookup(userName)
+	if err != nil {
+		return err
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid uid %v of user %v", u.Uid, userName)
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid gid %v of user %v", u.Gid, userName)
+	}
+	groupIds, err := u.GroupIds()
+	if err != nil {
+		return fmt.Errorf("unable to retrieve the groups of user %v: %v", userName, err)
+	}
+	var groups []uint32
+	for _, groupId := range groupIds {
+		if group, err := strconv.ParseUint(groupId, 10, 32); err == nil {
+			groups = append(groups, uint32(group))
+		}
+	}
+
+	command.SysProcAttr.Credential = &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid), Groups: groups}
+	command.Env = append(command.Env,
+		fmtEnvVariable("HOME", u.HomeDir),
+		fmtEnvVariable("USER", u.Username),
+		fmtEnvVariable("LOGNAME", u.Username))
+	return nil
+}
+
 func killProcess(process *os.Process, signal *timeoutSignal) error {
 	//   NOTE: go only kills the process but not its sub processes.
 	//   The consequence is that command.Wait() does not return, for some reason.
@@ -16,6 +16,7 @@
 package executers
 
 import (
+	"errors"
 	"os"
 	"os/exec"
 )
@@ -28,6 +29,11 @@ func prepareProcess(command *exec.Cmd) {
 	// nothing to do on windows
 }
 
+// prepareRunAsUser returns an error as commands cannot run as another user on windows
+func prepareRunAsUser(command *exec.Cmd, userName string) error {
+	return errors.New("running commands as another user is not supported on windows")
+}
+
 func killProcess(process *os.Process, signal *timeoutSignal) error {
 	// process kill doesn't send proper signal to the process status
 	// Setting the signal to indicate execution was interrupted
@@ -61,6 +61,41 @@ func (m *MockCommandExecuter) NewExecute(
 	return args.Get(0).(int), args.Error(1)
 }
 
+// NewExecuteWithEnvironment is a mocked method that just returns what mock tells it to.
+func (m *MockCommandExecuter) NewExecuteWithEnvironment(
+	log log.T,
+	workingDir string,
+	stdoutWriter io.Writer,
+	stderrWriter io.Writer,
+	cancelFlag task.CancelFlag,
+	executionTimeout int,
+	commandName string,
+	commandArguments []string,
+	environment []string,
+) (exitCode int, err error) {
+	args := m.Called(log, workingDir, stdoutWriter, stderrWriter, cancelFlag, executionTimeout, commandName, commandArguments, environment)
+	log.Infof("args are %v", args)
+	return args.Get(0).(int), args.Error(1)
+}
+
+// NewExecuteAsUser is a mocked method that just returns what mock tells it to.
+func (m *MockCommandExecuter) NewExecuteAsUser(
+	log log.T,
+	workingDir string,
+	stdoutWriter io.Writer,
+	stderrWriter io.Writer,
+	cancelFlag task.CancelFlag,
+	executionTimeout int,
+	commandName string,
+	commandArguments []string,
+	environment []string,
+	runAsUser string,
+) (exitCode int, err error) {
+	args := m.Called(log, workingDir, stdoutWriter, stderrWriter, cancelFlag, executionTimeout, commandName, commandArguments, environment, runAsUser)
+	log.Infof("args are %v", args)
+	return args.Get(0).(int), args.Error(1)
+}
+
 // StartExe is a mocked method that just returns what mock tells it to.
 func (m *MockCommandExecuter) StartExe(log log.T,
 	workingDir string,
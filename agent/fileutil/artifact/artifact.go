@@ -18,8 +18,10 @@ import (
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
 	"fmt"
+	gohash "hash"
 	"io"
 	"net/http"
 	"net/url"
@@ -31,6 +33,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/rip"
 	"github.com/aws/amazon-ssm-agent/agent/s3util"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/aws-sdk-go/aws"
@@ -142,6 +145,18 @@ func awsConfig(log log.T, amazonS3URL s3util.AmazonS3URL) (config *aws.Config, e
 	return config, nil
 }
 
+// newS3Client creates an s3 client for the given config, using the FIPS endpoint of the bucket region
+// when FIPS is enabled and no S3 endpoint is configured
+func newS3Client(config *aws.Config, amazonS3URL s3util.AmazonS3URL) *s3.S3 {
+	appConfig, _ := appconfig.Config(false)
+	sess := session.New(config)
+	sess.Handlers.Build.PushBack(request.MakeAddToUserAgentHandler(appConfig.Agent.Name, appConfig.Agent.Version))
+	if appConfig.S3.Endpoint == "" {
+		rip.UseFipsEndpoint(sess, amazonS3URL.Region, "s3")
+	}
+	return s3.New(sess)
+}
+
 // CanGetS3Object returns true if it is possible to fetch an object because it exists, is not deleted, and read permissions exist for this request
 func CanGetS3Object(log log.T, amazonS3URL s3util.AmazonS3URL) bool {
 	config, _ := awsConfig(log, amazonS3URL)
@@ -153,11 +168,7 @@ func CanGetS3Object(log log.T, amazonS3URL s3util.AmazonS3URL) bool {
 		Key:    aws.String(objectKey),
 	}
 
-	appConfig, _ := appconfig.Config(false)
-	sess := session.New(config)
-	sess.Handlers.Build.PushBack(request.MakeAddToUserAgentHandler(appConfig.Agent.Name, appConfig.Agent.Version))
-
-	s3client := s3.New(sess)
+	s3client := newS3Client(config, amazonS3URL)
 	var res *s3.HeadObjectOutput
 	var err error
 	if res, err = s3client.HeadObject(params); err != nil {
@@ -181,11 +192,7 @@ func ListS3Folders(log log.T, amazonS3URL s3util.AmazonS3URL) (folderNames []str
 		Prefix:    &prefix,
 		Delimiter: aws.String("/"),
 	}
-	appConfig, _ := appconfig.Config(false)
-	sess := session.New(config)
-	sess.Handlers.Build.PushBack(request.MakeAddToUserAgentHandler(appConfig.Agent.Name, appConfig.Agent.Version))
-
-	s3client := s3.New(sess)
+	s3client := newS3Client(config, amazonS3URL)
 	req, resp := s3client.ListObjectsRequest(params)
 	err = req.Send()
 	log.Debugf("ListS3Folders Bucket: %v, Prefix: %v, RequestID: %v", params.Bucket, params.Prefix, req.RequestID)
@@ -224,11 +231,7 @@ func ListS3Directory(log log.T, amazonS3URL s3util.AmazonS3URL) (folderNames []s
 	}
 	log.Debugf("ListS3Object Bucket: %v, Prefix: %v", params.Bucket, params.Prefix)
 
-	appConfig, _ := appconfig.Config(false)
-	sess := session.New(config)
-	sess.Handlers.Build.PushBack(request.MakeAddToUserAgentHandler(appConfig.Agent.Name, appConfig.Agent.Version))
-
-	s3client := s3.New(sess)
+	s3client := newS3Client(config, amazonS3URL)
 	obj, err := s3client.ListObjects(params)
 	if err != nil {
 		log.Errorf("ListS3Directory error %v", err.Error())
@@ -263,11 +266,7 @@ func s3Download(log log.T, amazonS3URL s3util.AmazonS3URL, destFile string) (out
 		}
 		params.IfNoneMatch = aws.String(existingETag)
 	}
-	appConfig, _ := appconfig.Config(false)
-	sess := session.New(config)
-	sess.Handlers.Build.PushBack(request.MakeAddToUserAgentHandler(appConfig.Agent.Name, appConfig.Agent.Version))
-
-	s3client := s3.New(sess)
+	s3client := newS3Client(config, amazonS3URL)
 
 	req, resp := s3client.GetObjectRequest(params)
 	err = req.Send()
@@ -420,6 +419,10 @@ func VerifyHash(log log.T, input DownloadInput, output DownloadOutput) (bool, er
 		// check the sha256 algorithm by default
 		if hashAlgorithm == "" || strings.EqualFold(hashAlgorithm, "sha256") {
 			computedHashValue, err = Sha256HashValue(log, output.LocalFilePath)
+		} else if strings.EqualFold(hashAlgorithm, "sha384") {
+			computedHashValue, err = Sha384HashValue(log, output.LocalFilePath)
+		} else if strings.EqualFold(hashAlgorithm, "sha512") {
+			computedHashValue, err = Sha512HashValue(log, output.LocalFilePath)
 		} else if strings.EqualFold(hashAlgorithm, "md5") {
 			computedHashValue, err = Md5HashValue(log, output.LocalFilePath)
 		} else {
@@ -447,29 +450,26 @@ func VerifyHash(log log.T, input DownloadInput, output DownloadOutput) (bool, er
 
 // Sha256HashValue gets the sha256 hash value
 func Sha256HashValue(log log.T, filePath string) (hash string, err error) {
-	var exists = false
-	exists, err = fileutil.LocalFileExist(filePath)
-	if err != nil || exists == false {
-		return
-	}
+	return hashValue(log, filePath, sha256.New())
+}
 
-	var f *os.File
-	f, err = os.Open(filePath)
-	if err != nil {
-		log.Error(err)
-	}
-	defer f.Close()
-	hasher := sha256.New()
-	if _, err = io.Copy(hasher, f); err != nil {
-		log.Error(err)
-	}
-	hash = hex.EncodeToString(hasher.Sum(nil))
-	log.Debugf("Hash=%v, FilePath=%v", hash, filePath)
-	return
+// Sha384HashValue gets the sha384 hash value
+func Sha384HashValue(log log.T, filePath string) (hash string, err error) {
+	return hashValue(log, filePath, sha512.New384())
+}
+
+// Sha512HashValue gets the sha512 hash value
+func Sha512HashValue(log log.T, filePath string) (hash string, err error) {
+	return hashValue(log, filePath, sha512.New())
 }
 
 // Md5HashValue gets the md5 hash value
 func Md5HashValue(log log.T, filePath string) (hash string, err error) {
+	return hashValue(log, filePath, md5.New())
+}
+
+// hashValue gets the hash value of the file computed by the hasher
+func hashValue(log log.T, filePath string, hasher gohash.Hash) (hash string, err error) {
 	var exists = false
 	exists, err = fileutil.LocalFileExist(filePath)
 	if err != nil || exists == false {
@@ -480,9 +480,9 @@ func Md5HashValue(log log.T, filePath string) (hash string, err error) {
 	f, err = os.Open(filePath)
 	if err != nil {
 		log.Error(err)
+		return
 	}
 	defer f.Close()
-	hasher := md5.New()
 	if _, err = io.Copy(hasher, f); err != nil {
 		log.Error(err)
 	}
@@ -0,0 +1,44 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package artifact
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/rip"
+	"github.com/aws/amazon-ssm-agent/agent/s3util"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewS3ClientUsesFipsEndpoint(t *testing.T) {
+	platform.SetRegion("us-east-1")
+	fipsEnabled := rip.FipsEnabled
+	rip.FipsEnabled = func() bool { return true }
+	defer func() { rip.FipsEnabled = fipsEnabled }()
+
+	amazonS3URL := s3util.AmazonS3URL{Region: "us-west-2", Bucket: "bucket", Key: "key"}
+	config, _ := awsConfig(log.NewMockLog(), amazonS3URL)
+	assert.Equal(t, "https://s3-fips.us-west-2.amazonaws.com", newS3Client(config, amazonS3URL).Endpoint)
+
+	// requests to buckets in regions without FIPS endpoints fail instead of using the regular endpoint
+	amazonS3URL.Region = "eu-west-1"
+	config, _ = awsConfig(log.NewMockLog(), amazonS3URL)
+	_, err := newS3Client(config, amazonS3URL).HeadObject(&s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String("key")})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no FIPS endpoint")
+}
@@ -0,0 +1,176 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package artifact
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// SignatureTypeGpg is a detached OpenPGP signature verified with gpg
+	SignatureTypeGpg = "Gpg"
+	// SignatureTypeSigstore is a sigstore signature verified with cosign
+	SignatureTypeSigstore = "Sigstore"
+
+	gpgCommand    = "gpg"
+	cosignCommand = "cosign"
+)
+
+// Signature is the detached signature of a file and the public key it is verified with
+type Signature struct {
+	// Type is Gpg or Sigstore
+	Type string `json:"type"`
+	// Signature is the ASCII armored OpenPGP signature, or the base64 encoded sigstore signature
+	Signature string `json:"signature"`
+	// PublicKey is the ASCII armored OpenPGP public key, or the PEM encoded sigstore public key.
+	// It must be one of the trusted keys of the agent.
+	PublicKey string `json:"publicKey"`
+}
+
+// Assign method to global variables to allow unittest to override
+var runVerifier = verifierCommand
+var loadTrustedKeys = trustedKeys
+
+// trustedKeysFolder holds the files of the public keys signatures may be verified with, one key per file
+var trustedKeysFolder = filepath.Join(appconfig.DefaultProgramFolder, appconfig.TrustedKeysFolderName)
+
+// Validate checks that the type of the signature is supported and that the signature and public key are set
+func (signature Signature) Validate() error {
+	if signature.Type != SignatureTypeGpg && signature.Type != SignatureTypeSigstore {
+		return fmt.Errorf("signature type must be %v or %v", SignatureTypeGpg, SignatureTypeSigstore)
+	}
+	if strings.TrimSpace(signature.Signature) == "" || strings.TrimSpace(signature.PublicKey) == "" {
+		return fmt.Errorf("signature and publicKey must be set for %v signatures", signature.Type)
+	}
+	return nil
+}
+
+// ValidateSignedChecksums returns an error if a checksum of a signed artifact has an empty algorithm or value.
+// VerifyHash accepts a single empty checksum for backwards compatibility, which must not let a signed artifact
+// pass verification with a checksum that was never compared.
+func ValidateSignedChecksums(checksums map[string]string) error {
+	for hashAlgorithm, hashValue := range checksums {
+		if strings.TrimSpace(hashAlgorithm) == "" || strings.TrimSpace(hashValue) == "" {
+			return fmt.Errorf("checksums of signed artifacts must have an algorithm and a value")
+		}
+	}
+	return nil
+}
+
+// VerifySignature verifies the detached signature of the file with the public key of the signature, which must be
+// configured in ArtifactSignatureTrustedKeys of appconfig or in the trusted keys folder, so that a signature made
+// with a key supplied along with the artifact is rejected.
+// Signatures are verified with gpg or cosign, which must be installed on the instance.
+func VerifySignature(log log.T, filePath string, signature Signature) (err error) {
+	if err = signature.Validate(); err != nil {
+		return err
+	}
+	if !isTrustedKey(loadTrustedKeys(log), signature.PublicKey) {
+		return fmt.Errorf("the public key of the %v signature of %v is not trusted, it must be added to ArtifactSignatureTrustedKeys in appconfig or to %v",
+			signature.Type, filepath.Base(filePath), trustedKeysFolder)
+	}
+
+	workDir, err := ioutil.TempDir("", "signature")
+	if err != nil {
+		return fmt.Errorf("failed to create the directory to verify the signature in: %v", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	signatureFile := filepath.Join(workDir, "signature")
+	publicKeyFile := filepath.Join(workDir, "publickey")
+	if err = ioutil.WriteFile(signatureFile, []byte(signature.Signature), 0600); err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(publicKeyFile, []byte(signature.PublicKey), 0600); err != nil {
+		return err
+	}
+
+	log.Infof("Verifying %v signature of %v", signature.Type, filePath)
+	switch signature.Type {
+	case SignatureTypeGpg:
+		// the public key is imported into a keyring of its own so that only it is trusted
+		homeDir := filepath.Join(workDir, "gnupg")
+		if err = os.Mkdir(homeDir, 0700); err != nil {
+			return err
+		}
+		if err = runVerifier(gpgCommand, "--batch", "--homedir", homeDir, "--import", publicKeyFile); err != nil {
+			return fmt.Errorf("failed to import the public key: %v", err)
+		}
+		err = runVerifier(gpgCommand, "--batch", "--homedir", homeDir, "--verify", signatureFile, filePath)
+	case SignatureTypeSigstore:
+		err = runVerifier(cosignCommand, "verify-blob", "--key", publicKeyFile, "--signature", signatureFile, filePath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to verify the %v signature of %v: %v", signature.Type, filepath.Base(filePath), err)
+	}
+	return nil
+}
+
+// trustedKeys returns the public keys configured in appconfig and the keys in the files of the trusted keys folder
+func trustedKeys(log log.T) (keys []string) {
+	if config, err := appconfig.Config(false); err != nil {
+		log.Errorf("failed to read appconfig: %v", err)
+	} else {
+		keys = append(keys, config.Ssm.ArtifactSignatureTrustedKeys...)
+	}
+
+	files, err := ioutil.ReadDir(trustedKeysFolder)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Errorf("failed to read the trusted keys folder %v: %v", trustedKeysFolder, err)
+		}
+		return keys
+	}
+	for _, file := range files {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+		if content, err := ioutil.ReadFile(filepath.Join(trustedKeysFolder, file.Name())); err != nil {
+			log.Errorf("failed to read the trusted key %v: %v", file.Name(), err)
+		} else {
+			keys = append(keys, string(content))
+		}
+	}
+	return keys
+}
+
+// isTrustedKey returns true if the key is one of the trusted keys, ignoring whitespace such as line endings
+func isTrustedKey(trustedKeys []string, key string) bool {
+	key = strings.Join(strings.Fields(key), "")
+	for _, trustedKey := range trustedKeys {
+		if key != "" && strings.Join(strings.Fields(trustedKey), "") == key {
+			return true
+		}
+	}
+	return false
+}
+
+// verifierCommand runs the verification command, returning an error including its output if it fails
+func verifierCommand(name string, arguments ...string) error {
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("%v must be installed to verify signatures", name)
+	}
+	if out, err := exec.Command(name, arguments...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%v %v", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
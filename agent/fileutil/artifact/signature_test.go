@@ -0,0 +1,169 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package artifact
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestFile writes content to a file in a new temporary directory
+func writeTestFile(t *testing.T, content string) (dir string, path string) {
+	dir, err := ioutil.TempDir("", "artifact")
+	assert.NoError(t, err)
+	path = filepath.Join(dir, "content.txt")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return dir, path
+}
+
+func TestVerifyHash_Sha384Sha512(t *testing.T) {
+	dir, path := writeTestFile(t, "content")
+	defer os.RemoveAll(dir)
+	logger := log.NewMockLog()
+
+	sha384, _ := Sha384HashValue(logger, path)
+	sha512, _ := Sha512HashValue(logger, path)
+	assert.Len(t, sha384, 96)
+	assert.Len(t, sha512, 128)
+
+	output := DownloadOutput{LocalFilePath: path}
+	matched, err := VerifyHash(logger, DownloadInput{SourceChecksums: map[string]string{"sha384": sha384, "SHA512": sha512}}, output)
+	assert.NoError(t, err)
+	assert.True(t, matched)
+
+	matched, err = VerifyHash(logger, DownloadInput{SourceChecksums: map[string]string{"sha512": strings.Repeat("0", 128)}}, output)
+	assert.Error(t, err)
+	assert.False(t, matched)
+
+	matched, err = VerifyHash(logger, DownloadInput{SourceChecksums: map[string]string{"sha384": strings.Repeat("0", 96)}}, output)
+	assert.Error(t, err)
+	assert.False(t, matched)
+}
+
+func TestSignature_Validate(t *testing.T) {
+	assert.NoError(t, Signature{Type: SignatureTypeGpg, Signature: "signature", PublicKey: "key"}.Validate())
+	assert.Error(t, Signature{Type: "X509", Signature: "signature", PublicKey: "key"}.Validate())
+	assert.Error(t, Signature{Type: SignatureTypeSigstore, Signature: "signature"}.Validate())
+}
+
+func TestValidateSignedChecksums(t *testing.T) {
+	assert.NoError(t, ValidateSignedChecksums(nil))
+	assert.NoError(t, ValidateSignedChecksums(map[string]string{"sha512": "0123"}))
+	assert.Error(t, ValidateSignedChecksums(map[string]string{"sha256": ""}))
+	assert.Error(t, ValidateSignedChecksums(map[string]string{"": "0123"}))
+	assert.Error(t, ValidateSignedChecksums(map[string]string{"sha512": "0123", "sha256": " "}))
+}
+
+func TestVerifySignature_Gpg(t *testing.T) {
+	if _, err := exec.LookPath(gpgCommand); err != nil {
+		t.Skip("gpg is not installed")
+	}
+	dir, path := writeTestFile(t, "content")
+	defer os.RemoveAll(dir)
+
+	// sign the file with a new key
+	homeDir := filepath.Join(dir, "gnupg")
+	os.Mkdir(homeDir, 0700)
+	gpg := func(arguments ...string) []byte {
+		out, err := exec.Command(gpgCommand, append([]string{"--batch", "--homedir", homeDir, "--pinentry-mode", "loopback", "--passphrase", ""}, arguments...)...).Output()
+		assert.NoError(t, err)
+		return out
+	}
+	gpg("--quick-gen-key", "Test <test@example.com>", "ed25519", "sign", "never")
+	publicKey := gpg("--armor", "--export")
+	signature := gpg("--armor", "--detach-sign", "--output", "-", path)
+
+	loadTrustedKeys = func(log log.T) []string { return []string{string(publicKey)} }
+	defer func() { loadTrustedKeys = trustedKeys }()
+
+	logger := log.NewMockLog()
+	err := VerifySignature(logger, path, Signature{Type: SignatureTypeGpg, Signature: string(signature), PublicKey: string(publicKey)})
+	assert.NoError(t, err)
+
+	ioutil.WriteFile(path, []byte("tampered"), 0600)
+	err = VerifySignature(logger, path, Signature{Type: SignatureTypeGpg, Signature: string(signature), PublicKey: string(publicKey)})
+	assert.Error(t, err)
+}
+
+func TestVerifySignature_Sigstore(t *testing.T) {
+	var commands [][]string
+	runVerifier = func(name string, arguments ...string) error {
+		commands = append(commands, append([]string{name}, arguments...))
+		return errors.New("invalid signature")
+	}
+	defer func() { runVerifier = verifierCommand }()
+	loadTrustedKeys = func(log log.T) []string { return []string{"-----BEGIN PUBLIC KEY-----\n"} }
+	defer func() { loadTrustedKeys = trustedKeys }()
+
+	err := VerifySignature(log.NewMockLog(), "content.txt", Signature{Type: SignatureTypeSigstore, Signature: "MEUCIQ==", PublicKey: "-----BEGIN PUBLIC KEY-----"})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid signature")
+	assert.Len(t, commands, 1)
+	assert.Equal(t, []string{cosignCommand, "verify-blob", "--key"}, commands[0][:3])
+	assert.Equal(t, "content.txt", commands[0][len(commands[0])-1])
+}
+
+func TestVerifySignature_UntrustedKey(t *testing.T) {
+	if _, err := exec.LookPath(gpgCommand); err != nil {
+		t.Skip("gpg is not installed")
+	}
+	dir, path := writeTestFile(t, "content")
+	defer os.RemoveAll(dir)
+
+	// sign the file with a new key which is valid but not trusted
+	homeDir := filepath.Join(dir, "gnupg")
+	os.Mkdir(homeDir, 0700)
+	gpg := func(arguments ...string) []byte {
+		out, err := exec.Command(gpgCommand, append([]string{"--batch", "--homedir", homeDir, "--pinentry-mode", "loopback", "--passphrase", ""}, arguments...)...).Output()
+		assert.NoError(t, err)
+		return out
+	}
+	gpg("--quick-gen-key", "Test <test@example.com>", "ed25519", "sign", "never")
+	publicKey := gpg("--armor", "--export")
+	signature := gpg("--armor", "--detach-sign", "--output", "-", path)
+
+	trustedFolder := filepath.Join(dir, "trusted-keys")
+	os.Mkdir(trustedFolder, 0700)
+	ioutil.WriteFile(filepath.Join(trustedFolder, "other.asc"), []byte("-----BEGIN PGP PUBLIC KEY BLOCK-----\nother\n"), 0600)
+	trustedKeysFolder = trustedFolder
+	defer func() { trustedKeysFolder = filepath.Join(appconfig.DefaultProgramFolder, appconfig.TrustedKeysFolderName) }()
+
+	err := VerifySignature(log.NewMockLog(), path, Signature{Type: SignatureTypeGpg, Signature: string(signature), PublicKey: string(publicKey)})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not trusted")
+
+	// the same signature verifies once the key is in the trusted keys folder
+	ioutil.WriteFile(filepath.Join(trustedFolder, "test.asc"), publicKey, 0600)
+	err = VerifySignature(log.NewMockLog(), path, Signature{Type: SignatureTypeGpg, Signature: string(signature), PublicKey: string(publicKey)})
+	assert.NoError(t, err)
+}
+
+func TestIsTrustedKey(t *testing.T) {
+	trusted := []string{"-----BEGIN PUBLIC KEY-----\r\nMFkwEw\r\n-----END PUBLIC KEY-----\r\n"}
+
+	assert.True(t, isTrustedKey(trusted, "-----BEGIN PUBLIC KEY-----\nMFkwEw\n-----END PUBLIC KEY-----"))
+	assert.False(t, isTrustedKey(trusted, "-----BEGIN PUBLIC KEY-----\nMFkwEx\n-----END PUBLIC KEY-----"))
+	assert.False(t, isTrustedKey(trusted, ""))
+	assert.False(t, isTrustedKey(nil, trusted[0]))
+}
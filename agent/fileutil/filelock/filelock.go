@@ -170,6 +170,17 @@ func LockFile(lockPath string, ownerId string, timeoutSeconds int) (locked bool,
 	return true, nil
 }
 
+// LockFileWithRetry calls LockFile until the lock is acquired or the given number of attempts is used up.
+// LockFile waits a random time of up to a second before each attempt, so concurrent callers spread out.
+func LockFileWithRetry(lockPath string, ownerId string, timeoutSeconds int, attempts int) (locked bool, err error) {
+	for attempt := 0; attempt < attempts && !locked; attempt++ {
+		if locked, err = LockFile(lockPath, ownerId, timeoutSeconds); err != nil {
+			return false, err
+		}
+	}
+	return locked, nil
+}
+
 func UnlockFile(lockPath string, ownerId string) (hadLock bool, err error) {
 	var contentsRead string
 	if contentsRead, _ = readLockFile(lockPath); contentsRead != ownerId {
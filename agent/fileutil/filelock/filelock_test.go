@@ -174,3 +174,33 @@ func TestParallelLocking(t *testing.T) {
 	// Last, clean up again
 	os.Remove(lockPath)
 }
+
+func TestLockFileWithRetry(t *testing.T) {
+	lockPath := "lock-file.TestLockFileWithRetry.tmp"
+	ownerId1 := "1001"
+	ownerId2 := "1002"
+	timeoutSeconds := 10
+
+	// First, clean up
+	os.Remove(lockPath)
+
+	locked, err := LockFileWithRetry(lockPath, ownerId1, timeoutSeconds, 2)
+	assert.NoError(t, err)
+	assert.True(t, locked)
+
+	// Lock is held by the first owner for all attempts of the second one
+	locked, err = LockFileWithRetry(lockPath, ownerId2, timeoutSeconds, 2)
+	assert.NoError(t, err)
+	assert.False(t, locked)
+
+	hadLock, err := UnlockFile(lockPath, ownerId1)
+	assert.NoError(t, err)
+	assert.True(t, hadLock)
+
+	locked, err = LockFileWithRetry(lockPath, ownerId2, timeoutSeconds, 2)
+	assert.NoError(t, err)
+	assert.True(t, locked)
+
+	// Last, clean up again
+	os.Remove(lockPath)
+}
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
@@ -102,6 +103,9 @@ type DefaultIOHandler struct {
 	stdout   string
 	stderr   string
 	ioConfig contracts.IOConfiguration
+	// locations of stdout and stderr in s3, empty if they were not uploaded
+	stdoutS3Url string
+	stderrS3Url string
 	//refreshassociation and invoker write a different output rather than merging stdout and stderr
 	output interface{}
 
@@ -154,6 +158,10 @@ func (out *DefaultIOHandler) Init(log log.T, filePath ...string) {
 		OutputS3KeyPrefix:      s3KeyPrefix,
 		LogGroupName:           out.ioConfig.CloudWatchConfig.LogGroupName,
 		LogStreamName:          stdOutLogStreamName,
+		FlushInterval:          time.Duration(out.ioConfig.CloudWatchConfig.FlushIntervalSeconds) * time.Second,
+		SpillS3BucketName:      out.ioConfig.SpillS3BucketName,
+		SpillThreshold:         int64(pluginConfig.MaxStdoutLength),
+		S3Url:                  &out.stdoutS3Url,
 	}
 
 	// Initialize console output module
@@ -176,6 +184,10 @@ func (out *DefaultIOHandler) Init(log log.T, filePath ...string) {
 		OutputS3KeyPrefix:      s3KeyPrefix,
 		LogGroupName:           out.ioConfig.CloudWatchConfig.LogGroupName,
 		LogStreamName:          stdErrLogStreamName,
+		FlushInterval:          time.Duration(out.ioConfig.CloudWatchConfig.FlushIntervalSeconds) * time.Second,
+		SpillS3BucketName:      out.ioConfig.SpillS3BucketName,
+		SpillThreshold:         int64(pluginConfig.MaxStdoutLength),
+		S3Url:                  &out.stderrS3Url,
 	}
 
 	// Initialize console error module
@@ -249,6 +261,16 @@ func (out DefaultIOHandler) GetStdout() string {
 	return out.stdout
 }
 
+// GetTruncatedStdout returns the stdout truncated to maxLength, referring to the full stdout if it was uploaded to s3
+func (out DefaultIOHandler) GetTruncatedStdout(maxLength int) string {
+	return truncateWithS3Url(out.stdout, out.stdoutS3Url, maxLength)
+}
+
+// GetTruncatedStderr returns the stderr truncated to maxLength, referring to the full stderr if it was uploaded to s3
+func (out DefaultIOHandler) GetTruncatedStderr(maxLength int) string {
+	return truncateWithS3Url(out.stderr, out.stderrS3Url, maxLength)
+}
+
 // GetExitCode returns the exit code
 func (out DefaultIOHandler) GetExitCode() int {
 	return out.ExitCode
@@ -318,6 +340,13 @@ func (out *DefaultIOHandler) Merge(log log.T, mergeOutput *DefaultIOHandler) {
 	stderrBuffer.WriteString(mergeOutput.GetStderr())
 	out.stderr = stderrBuffer.String()
 
+	if out.stdoutS3Url == "" {
+		out.stdoutS3Url = mergeOutput.stdoutS3Url
+	}
+	if out.stderrS3Url == "" {
+		out.stderrS3Url = mergeOutput.stderrS3Url
+	}
+
 	if out.ExitCode == 0 {
 		out.ExitCode = mergeOutput.GetExitCode()
 	}
@@ -416,6 +445,21 @@ func (out *DefaultIOHandler) AppendErrorf(format string, params ...interface{})
 	}
 }
 
+// truncateWithS3Url truncates the output to maxLength, naming the s3 location of the full output in the suffix
+func truncateWithS3Url(output string, s3Url string, maxLength int) string {
+	suffix := DefaultOutputConfig().OutputTruncatedSuffix
+	if s3Url != "" {
+		suffix = fmt.Sprintf("\n%s full output: %s", suffix, s3Url)
+	}
+	if len(output) <= maxLength {
+		return output
+	}
+	if maxLength <= len(suffix) {
+		return suffix[:maxLength]
+	}
+	return output[:maxLength-len(suffix)] + suffix
+}
+
 // TruncateOutput truncates the output
 func TruncateOutput(stdout string, stderr string, capacity int) (response string) {
 	outputSize := len(stdout)
@@ -15,6 +15,7 @@ package iohandler
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"sync"
@@ -174,3 +175,31 @@ func TestAppendFormat(t *testing.T) {
 	assert.Contains(t, output.GetStdout(), testStringFormatted)
 	assert.Contains(t, output.GetStderr(), testStringFormatted)
 }
+
+func TestGetTruncatedStdoutWithS3Url(t *testing.T) {
+	output := DefaultIOHandler{stdout: longMessage, stderr: "short error", stdoutS3Url: "s3://bucket/prefix/stdout"}
+
+	stdout := output.GetTruncatedStdout(sampleSize)
+	assert.Len(t, stdout, sampleSize)
+	assert.True(t, strings.HasSuffix(stdout, "--output truncated-- full output: s3://bucket/prefix/stdout"))
+	assert.True(t, strings.HasPrefix(stdout, "This is a sample text."))
+
+	assert.Equal(t, "short error", output.GetTruncatedStderr(sampleSize))
+}
+
+func TestGetTruncatedStdoutWithoutS3Url(t *testing.T) {
+	output := DefaultIOHandler{stdout: longMessage}
+
+	stdout := output.GetTruncatedStdout(sampleSize)
+	assert.Len(t, stdout, sampleSize)
+	assert.True(t, strings.HasSuffix(stdout, "--output truncated--"))
+}
+
+func TestMergeKeepsS3Url(t *testing.T) {
+	output := DefaultIOHandler{}
+	output.Merge(log.NewMockLog(), &DefaultIOHandler{stdout: "first"})
+	output.Merge(log.NewMockLog(), &DefaultIOHandler{stdout: "second", stdoutS3Url: "s3://bucket/second/stdout"})
+
+	assert.Equal(t, "first\nsecond", output.GetStdout())
+	assert.Equal(t, "s3://bucket/second/stdout", output.stdoutS3Url)
+}
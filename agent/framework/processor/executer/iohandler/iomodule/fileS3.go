@@ -15,6 +15,7 @@ package iomodule
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -39,6 +40,13 @@ type File struct {
 	OutputS3KeyPrefix      string
 	LogGroupName           string
 	LogStreamName          string
+	// FlushInterval is how often new output is uploaded to CloudWatchLogs while the command runs
+	FlushInterval time.Duration
+	// SpillS3BucketName receives the output when OutputS3BucketName is not set and the output exceeds SpillThreshold bytes
+	SpillS3BucketName string
+	SpillThreshold    int64
+	// S3Url is set to the location of the output once it is uploaded to s3
+	S3Url *string
 }
 
 // Read reads from the stream and writes to the output file, s3 and CloudWatchLogs.
@@ -64,6 +72,7 @@ func (file File) Read(log log.T, reader *io.PipeReader) {
 	defer fileWriter.Close()
 
 	cwl := cloudwatchlogspublisher.NewCloudWatchLogsService()
+	cwl.SetUploadFrequency(file.FlushInterval)
 	if file.LogGroupName != "" {
 		log.Debugf("Received CloudWatch Configs: LogGroupName: %s\n, LogStreamName: %s\n", file.LogGroupName, file.LogStreamName)
 		//Start CWL logging on different go routine
@@ -91,21 +100,34 @@ func (file File) Read(log log.T, reader *io.PipeReader) {
 	}
 
 	// Upload output file to S3
-	if file.OutputS3BucketName != "" && fi.Size() > 0 {
+	if bucketName := file.s3BucketName(fi.Size()); bucketName != "" {
 		s3Key := fileutil.BuildS3Path(file.OutputS3KeyPrefix, file.FileName)
-		if err := s3util.NewAmazonS3Util(log, file.OutputS3BucketName).S3Upload(log, file.OutputS3BucketName, s3Key, filePath); err != nil {
+		if err := s3util.NewAmazonS3Util(log, bucketName).S3Upload(log, bucketName, s3Key, filePath); err != nil {
 			log.Errorf("Failed to upload the output to s3: %v", err)
+		} else if file.S3Url != nil {
+			*file.S3Url = fmt.Sprintf("s3://%s/%s", bucketName, s3Key)
 		}
 	}
 
 	//Block main thread until CloudWatchLogs uploading is complete or until maxCloudWatchUploadRetry is reached
 	//TODO Add unit test to test maxRetry logic
 	if file.LogGroupName != "" {
-		cwl.IsFileComplete = true
-		retry := 0
-		for !cwl.IsUploadComplete && retry < maxCloudWatchUploadRetry {
-			retry++
-			time.Sleep(cloudwatchlogspublisher.UploadFrequency)
+		cwl.SetIsFileComplete(true)
+		if !cwl.WaitForUploadComplete(time.Duration(maxCloudWatchUploadRetry) * cwl.GetUploadFrequency()) {
+			log.Warn("Timed out waiting for the output to be uploaded to CloudWatch")
 		}
 	}
 }
+
+// s3BucketName returns the bucket the output of the size is uploaded to, empty if it is not uploaded.
+func (file File) s3BucketName(size int64) string {
+	switch {
+	case size == 0:
+		return ""
+	case file.OutputS3BucketName != "":
+		return file.OutputS3BucketName
+	case size > file.SpillThreshold:
+		return file.SpillS3BucketName
+	}
+	return ""
+}
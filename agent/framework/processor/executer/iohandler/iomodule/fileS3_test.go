@@ -0,0 +1,33 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package iomodule
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFileS3BucketName tests the bucket the output of the File module is uploaded to
+func TestFileS3BucketName(t *testing.T) {
+	command := File{OutputS3BucketName: "command-bucket", SpillS3BucketName: "spill-bucket", SpillThreshold: 100}
+	spillOnly := File{SpillS3BucketName: "spill-bucket", SpillThreshold: 100}
+
+	assert.Equal(t, "", command.s3BucketName(0))
+	assert.Equal(t, "command-bucket", command.s3BucketName(10))
+	assert.Equal(t, "command-bucket", command.s3BucketName(1000))
+	assert.Equal(t, "", spillOnly.s3BucketName(100))
+	assert.Equal(t, "spill-bucket", spillOnly.s3BucketName(101))
+	assert.Equal(t, "", File{}.s3BucketName(1000))
+}
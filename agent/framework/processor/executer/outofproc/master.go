@@ -13,6 +13,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/channel"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/messaging"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/proc"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/sandbox"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
@@ -32,6 +33,8 @@ type OutOfProcExecuter struct {
 	docState   *contracts.DocumentState
 	ctx        context.T
 	cancelFlag task.CancelFlag
+	//sandboxed is true if the document worker runs in a sandbox
+	sandboxed bool
 }
 
 var channelCreator = func(log log.T, mode channel.Mode, documentID string) (channel.Channel, error, bool) {
@@ -52,6 +55,12 @@ var processCreator = func(name string, argv []string) (proc.OSProcess, error) {
 	return proc.StartProcess(name, argv)
 }
 
+var sandboxSupported = sandbox.Supported
+
+var sandboxedProcessCreator = func(name string, argv []string, seccompProfile string) (proc.OSProcess, error) {
+	return proc.StartSandboxedProcess(name, argv, seccompProfile)
+}
+
 func NewOutOfProcExecuter(ctx context.T) *OutOfProcExecuter {
 	return &OutOfProcExecuter{
 		BasicExecuter: *basicexecuter.NewBasicExecuter(ctx),
@@ -72,12 +81,23 @@ func (e *OutOfProcExecuter) Run(
 	e.ctx = e.ctx.With("[" + documentID + "]")
 	log := e.ctx.Log()
 
+	//documents running sandboxed plugins must run in a sandboxed worker, never in the agent process
+	e.sandboxed = docState.DocumentType != contracts.StartSession && e.isSandboxed()
+	if e.sandboxed && !sandboxSupported() {
+		log.Error("document runs sandboxed plugins but document workers cannot run in a sandbox on this platform")
+		return e.failSandboxedDocument(docStore, "the document runs plugins that must run in a sandbox, which is not supported on this platform")
+	}
+
 	//stopTimer signals messaging routine to stop, it's buffered because it needs to exit if messaging is already stopped and not receiving anymore
 	stopTimer := make(chan bool, 1)
 	//start prepare messaging
 	//if anything fails during the prep stage, use in-proc Runner
 	ipc, err := e.initialize(stopTimer)
-	if err != nil {
+	if err != nil && e.sandboxed {
+		//sandboxed documents must not run in the agent process
+		log.Errorf("failed to start sandboxed document worker: %v", err)
+		return e.failSandboxedDocument(docStore, fmt.Sprintf("failed to start the document worker in a sandbox: %v", err))
+	} else if err != nil {
 		log.Errorf("failed to prepare outofproc executer, falling back to InProc Executer")
 		return e.BasicExecuter.Run(cancelFlag, docStore)
 	} else {
@@ -101,6 +121,16 @@ func (e *OutOfProcExecuter) Run(
 	}
 }
 
+//failSandboxedDocument fails the document without running it, returning a closed channel holding the failed result
+func (e *OutOfProcExecuter) failSandboxedDocument(docStore executer.DocumentStore, errMsg string) chan contracts.DocumentResult {
+	resChan := make(chan contracts.DocumentResult, 1)
+	e.docState.DocumentInformation.DocumentStatus = contracts.ResultStatusFailed
+	resChan <- e.generateUnexpectedFailResult(errMsg)
+	docStore.Save(*e.docState)
+	close(resChan)
+	return resChan
+}
+
 //Executer spins up an ipc transmission worker, it creates a Data processing backend and hands off the backend to the ipc worker
 //ipc worker and data backend act as 2 threads exchange raw json messages, and messaging protocol happened in data backend, data backend is self-contained and exit when command finishes accordingly
 //Executer however does hold a timer to the worker to forcefully termniate both of them
@@ -174,7 +204,13 @@ func (e *OutOfProcExecuter) initialize(stopTimer chan bool) (ipc channel.Channel
 			workerName = appconfig.DefaultDocumentWorker
 		}
 		var process proc.OSProcess
-		if process, err = processCreator(workerName, proc.FormArgv(documentID, instanceID)); err != nil {
+		if e.sandboxed {
+			log.Info("starting document worker in sandbox")
+			process, err = sandboxedProcessCreator(workerName, proc.FormArgv(documentID, instanceID), e.ctx.AppConfig().Ssm.DocumentWorkerSeccompProfile)
+		} else {
+			process, err = processCreator(workerName, proc.FormArgv(documentID, instanceID))
+		}
+		if err != nil {
 			log.Errorf("start process: %v error: %v", workerName, err)
 			//make sure close the channel
 			ipc.Destroy()
@@ -194,6 +230,11 @@ func (e *OutOfProcExecuter) initialize(stopTimer chan bool) (ipc channel.Channel
 	return
 }
 
+//isSandboxed returns true if the document worker must run in a sandbox because the document runs a sandboxed plugin
+func (e *OutOfProcExecuter) isSandboxed() bool {
+	return sandbox.IsSandboxed(e.ctx.AppConfig().Ssm, e.docState.InstancePluginsInformation)
+}
+
 func (e *OutOfProcExecuter) WaitForProcess(stopTimer chan bool, process proc.OSProcess) {
 	log := e.ctx.Log()
 	//TODO revisit this feature, it has done sides of killing the document worker too fast -- the worker might busy doing s3 upload
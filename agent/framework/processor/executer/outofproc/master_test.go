@@ -15,9 +15,11 @@ import (
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/proc"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/sandbox"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 type TestCase struct {
@@ -335,3 +337,98 @@ func assertValueEqual(t *testing.T, a map[string]*contracts.PluginResult, b map[
 		assert.Equal(t, *val, *b[key])
 	}
 }
+
+func createSandboxContext(seccompProfile string) *context.Mock {
+	ctx := new(context.Mock)
+	config := appconfig.SsmagentConfig{
+		Ssm: appconfig.SsmCfg{DocumentWorkerSandboxPlugins: []string{"aws:runScript"}, DocumentWorkerSeccompProfile: seccompProfile},
+	}
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	return ctx
+}
+
+func TestInitializeSandboxedProcess(t *testing.T) {
+	testCase := CreateTestCase()
+	channelMock := new(channelmock.MockedChannel)
+	channelCreator = func(log log.T, mode channel.Mode, documentID string) (channel.Channel, error, bool) {
+		return channelMock, nil, false
+	}
+	processCreator = func(name string, argv []string) (proc.OSProcess, error) {
+		assert.Fail(t, "sandboxed documents must run in a sandboxed worker")
+		return nil, errors.New("unexpected process")
+	}
+	sandboxedProcessCreator = func(name string, argv []string, seccompProfile string) (proc.OSProcess, error) {
+		assert.Equal(t, appconfig.DefaultDocumentWorker, name)
+		assert.Equal(t, []string{testDocumentID, testInstanceID}, argv)
+		assert.Equal(t, "/etc/amazon/ssm/seccomp.json", seccompProfile)
+		return testCase.processMock, nil
+	}
+	defer func() {
+		sandboxedProcessCreator = func(name string, argv []string, seccompProfile string) (proc.OSProcess, error) {
+			return proc.StartSandboxedProcess(name, argv, seccompProfile)
+		}
+	}()
+	exe := &OutOfProcExecuter{
+		ctx:        createSandboxContext("/etc/amazon/ssm/seccomp.json"),
+		docState:   &testCase.docState,
+		cancelFlag: task.NewChanneledCancelFlag(),
+		sandboxed:  true,
+	}
+	stopTimer := make(chan bool)
+
+	testCase.processMock.On("Wait").Return(nil)
+	testCase.processMock.On("Pid").Return(testPid)
+	testCase.processMock.On("StartTime").Return(testStartDateTime)
+	_, err := exe.initialize(stopTimer)
+	assert.NoError(t, err)
+	<-stopTimer
+	testCase.processMock.AssertExpectations(t)
+	assert.Equal(t, testPid, exe.docState.DocumentInformation.ProcInfo.Pid)
+}
+
+func TestRunSandboxedDocumentWithoutWorkerFails(t *testing.T) {
+	sandboxSupported = func() bool { return true }
+	defer func() { sandboxSupported = sandbox.Supported }()
+	testCase := CreateTestCase()
+	channelCreator = func(log log.T, mode channel.Mode, documentID string) (channel.Channel, error, bool) {
+		return nil, errors.New("failed to create channel"), false
+	}
+	testCase.docStore.On("Load").Return(testCase.docState)
+	testCase.docStore.On("Save", mock.Anything).Return()
+	exe := NewOutOfProcExecuter(createSandboxContext(""))
+
+	resChan := exe.Run(task.NewChanneledCancelFlag(), testCase.docStore)
+
+	res, ok := <-resChan
+	assert.True(t, ok)
+	assert.Equal(t, contracts.ResultStatusFailed, res.Status)
+	assert.Contains(t, res.PluginResults["plugin1"].Output, "sandbox")
+	_, ok = <-resChan
+	assert.False(t, ok)
+	testCase.docStore.AssertExpectations(t)
+}
+
+func TestRunSandboxedDocumentOnUnsupportedPlatformFails(t *testing.T) {
+	sandboxSupported = func() bool { return false }
+	defer func() { sandboxSupported = sandbox.Supported }()
+	testCase := CreateTestCase()
+	channelCreator = func(log log.T, mode channel.Mode, documentID string) (channel.Channel, error, bool) {
+		assert.Fail(t, "sandboxed documents must not run without sandbox")
+		return nil, errors.New("unexpected channel"), false
+	}
+	testCase.docStore.On("Load").Return(testCase.docState)
+	testCase.docStore.On("Save", mock.Anything).Return()
+	exe := NewOutOfProcExecuter(createSandboxContext(""))
+
+	resChan := exe.Run(task.NewChanneledCancelFlag(), testCase.docStore)
+
+	res, ok := <-resChan
+	assert.True(t, ok)
+	assert.Equal(t, contracts.ResultStatusFailed, res.Status)
+	assert.Contains(t, res.PluginResults["plugin1"].Output, "not supported on this platform")
+	_, ok = <-resChan
+	assert.False(t, ok)
+	testCase.docStore.AssertExpectations(t)
+}
@@ -60,7 +60,32 @@ func NewExecuterBackend(output chan contracts.DocumentResult, docState *contract
 func (p *ExecuterBackend) start(docState contracts.DocumentState) {
 	startDatagram, _ := CreateDatagram(MessageTypePluginConfig, docState)
 	p.input <- startDatagram
-	p.cancelFlag.Wait()
+
+	//forward the steps canceled while the command runs, the command itself keeps running
+	canceledSteps := make(chan string, defaultBackendChannelSize)
+	if stepCancelFlag, ok := p.cancelFlag.(task.StepCancelFlag); ok {
+		stepCancelFlag.NotifyStepCanceled(func(stepID string) {
+			select {
+			case canceledSteps <- stepID:
+			default:
+			}
+		})
+	}
+	flagSet := make(chan struct{})
+	go func() {
+		p.cancelFlag.Wait()
+		close(flagSet)
+	}()
+	for waiting := true; waiting; {
+		select {
+		case stepID := <-canceledSteps:
+			cancelStepDatagram, _ := CreateDatagram(MessageTypeCancelStep, stepID)
+			p.input <- cancelStepDatagram
+		case <-flagSet:
+			waiting = false
+		}
+	}
+
 	if p.cancelFlag.Canceled() {
 		cancelDatagram, _ := CreateDatagram(MessageTypeCancel, "cancel")
 		p.input <- cancelDatagram
@@ -147,6 +172,16 @@ func (p *WorkerBackend) Process(datagram string) error {
 	case MessageTypeCancel:
 		log.Info("requested cancel the command, setting cancel flag...")
 		p.cancelFlag.Set(task.Canceled)
+	case MessageTypeCancelStep:
+		var stepID string
+		if err := jsonutil.Unmarshal(content, &stepID); err != nil {
+			log.Errorf("failed to unmarshal cancel step message: %v", err)
+			return err
+		}
+		log.Infof("requested cancel step %v, setting its cancel flag...", stepID)
+		if stepCancelFlag, ok := p.cancelFlag.(task.StepCancelFlag); ok {
+			stepCancelFlag.CancelStep(stepID)
+		}
 	default:
 		//TODO add extra logic to check whether plugin has started, if not, stop IPC, or add timeout
 		return errors.New("unsupported message type")
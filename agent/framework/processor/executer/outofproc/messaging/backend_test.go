@@ -128,6 +128,33 @@ func TestExecuterBackendStart_Shutdown(t *testing.T) {
 	<-closed
 }
 
+func TestExecuterBackendStart_CancelStep(t *testing.T) {
+	testCase := CreateTestCase()
+	inputChan := make(chan string)
+	cancel := task.NewChanneledCancelFlag()
+	backend := ExecuterBackend{
+		input:      inputChan,
+		cancelFlag: cancel,
+		stopChan:   make(chan int, 1),
+		docState:   &testCase.docState,
+	}
+	go backend.start(testCase.docState)
+	msgType, _ := ParseDatagram(<-inputChan)
+	assert.Equal(t, MessageType(MessageTypePluginConfig), msgType)
+
+	cancel.CancelStep("plugin2")
+	msgType, content := ParseDatagram(<-inputChan)
+	assert.Equal(t, MessageType(MessageTypeCancelStep), msgType)
+	assert.Equal(t, `"plugin2"`, content)
+
+	cancel.Set(task.Canceled)
+	msgType, _ = ParseDatagram(<-inputChan)
+	assert.Equal(t, MessageType(MessageTypeCancel), msgType)
+	//make sure input is closed
+	_, open := <-inputChan
+	assert.False(t, open)
+}
+
 //test the datagram mashalling v1
 func TestExecuterBackend_ProcessV1(t *testing.T) {
 	testCase := CreateTestCase()
@@ -264,3 +291,16 @@ func assertValueEqual(t *testing.T, a map[string]*contracts.PluginResult, b map[
 		assert.Equal(t, *val, *b[key])
 	}
 }
+
+func TestWorkerBackendCancelStep(t *testing.T) {
+	cancelFlag := task.NewChanneledCancelFlag()
+	backend := WorkerBackend{
+		ctx:        contextMock,
+		cancelFlag: cancelFlag,
+	}
+	datagram, _ := CreateDatagram(MessageTypeCancelStep, "plugin2")
+	assert.NoError(t, backend.Process(datagram))
+	assert.True(t, cancelFlag.StepFlag("plugin2").Canceled())
+	assert.False(t, cancelFlag.StepFlag("plugin1").Canceled())
+	assert.False(t, cancelFlag.Canceled())
+}
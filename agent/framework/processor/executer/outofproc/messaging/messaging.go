@@ -21,6 +21,7 @@ const (
 	MessageTypeComplete     = "complete"
 	MessageTypeReply        = "reply"
 	MessageTypeCancel       = "cancel"
+	MessageTypeCancelStep   = "cancelstep"
 )
 
 var versions = []string{"1.0"}
@@ -22,6 +22,7 @@ import (
 	"os/exec"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/sandbox"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 )
 
@@ -67,6 +68,20 @@ func StartProcess(name string, argv []string) (OSProcess, error) {
 	//TODO connect stdin and stdout to avoid seelog error
 	cmd := exec.Command(name, argv...)
 	prepareProcess(cmd)
+	return startCommand(cmd)
+}
+
+//start a child process like StartProcess, in a sandbox restricted by the seccomp profile, empty for the default profile
+func StartSandboxedProcess(name string, argv []string, seccompProfile string) (OSProcess, error) {
+	cmd := exec.Command(name, argv...)
+	prepareProcess(cmd)
+	if err := sandbox.Prepare(cmd, seccompProfile); err != nil {
+		return nil, err
+	}
+	return startCommand(cmd)
+}
+
+func startCommand(cmd *exec.Cmd) (OSProcess, error) {
 	err := cmd.Start()
 	p := WorkerProcess{
 		cmd,
@@ -0,0 +1,132 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package sandbox runs document workers in a sandbox on Linux.
+//
+// The master starts sandboxed workers in new mount and PID namespaces. Before running any plugin the worker
+// makes its mounts private, mounts a /proc showing only the processes of the sandbox, drops the capabilities
+// documents do not need, sets no_new_privs and installs the seccomp filter of the configured profile. The filter
+// always denies creating or joining namespaces and ptrace.
+//
+// The first process of a sandboxed worker stays PID 1 of the PID namespace: it reaps the processes orphaned by plugins
+// and exits with the worker. The kernel then kills every process left in the namespace, so processes that documents
+// start in the background do not outlive a sandboxed document. Documents detaching processes meant to keep running,
+// such as services started without a service manager, must not run plugins listed in DocumentWorkerSandboxPlugins.
+package sandbox
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+)
+
+const (
+	// envVarSeccompProfile is set for sandboxed workers to the path of their seccomp profile, empty for the default profile
+	envVarSeccompProfile = "AWS_SSM_SANDBOX_SECCOMP_PROFILE"
+
+	// ActionAllow lets the process make the syscall
+	ActionAllow = "SCMP_ACT_ALLOW"
+	// ActionErrno fails the syscall with EPERM
+	ActionErrno = "SCMP_ACT_ERRNO"
+	// ActionKill kills the process making the syscall
+	ActionKill = "SCMP_ACT_KILL"
+	// ActionKillProcess kills the process making the syscall, like ActionKill
+	ActionKillProcess = "SCMP_ACT_KILL_PROCESS"
+)
+
+// Profile is a seccomp profile in the format of Docker seccomp profiles.
+// Only profiles allowing all syscalls except the ones named in their rules are supported.
+type Profile struct {
+	DefaultAction string        `json:"defaultAction"`
+	Syscalls      []SyscallRule `json:"syscalls"`
+}
+
+// SyscallRule is the action taken when a process makes one of the named syscalls
+type SyscallRule struct {
+	Names  []string `json:"names"`
+	Action string   `json:"action"`
+}
+
+// defaultDeniedSyscalls are the syscalls the default profile denies, which documents do not need to administer
+// an instance but would let a compromised document change the kernel, the namespaces or other processes
+var defaultDeniedSyscalls = []string{
+	"acct", "add_key", "adjtimex", "bpf", "chroot", "clock_adjtime", "clock_settime", "delete_module",
+	"fanotify_init", "finit_module", "init_module", "kcmp", "kexec_load", "keyctl", "lookup_dcookie", "mount",
+	"name_to_handle_at", "open_by_handle_at", "perf_event_open", "pivot_root", "process_vm_readv", "process_vm_writev",
+	"ptrace", "quotactl", "reboot", "request_key", "setdomainname", "sethostname", "setns", "settimeofday", "swapoff",
+	"swapon", "syslog", "umount2", "unshare", "userfaultfd", "vhangup",
+}
+
+// DefaultProfile returns the profile of sandboxed workers when no profile is configured
+func DefaultProfile() Profile {
+	return Profile{
+		DefaultAction: ActionAllow,
+		Syscalls:      []SyscallRule{{Names: defaultDeniedSyscalls, Action: ActionErrno}},
+	}
+}
+
+// LoadProfile reads the seccomp profile at the path, or returns the default profile if the path is empty
+func LoadProfile(path string) (profile Profile, err error) {
+	if path == "" {
+		return DefaultProfile(), nil
+	}
+	if err = jsonutil.UnmarshalFile(path, &profile); err != nil {
+		return profile, fmt.Errorf("failed to read seccomp profile %v: %v", path, err)
+	}
+	if err = profile.validate(); err != nil {
+		return profile, fmt.Errorf("invalid seccomp profile %v: %v", path, err)
+	}
+	return profile, nil
+}
+
+// validate returns an error if the profile cannot be turned into a seccomp filter
+func (profile Profile) validate() error {
+	if profile.DefaultAction != ActionAllow {
+		return fmt.Errorf("defaultAction must be %v, only profiles naming the syscalls they deny are supported", ActionAllow)
+	}
+	for _, rule := range profile.Syscalls {
+		if !isAction(rule.Action) {
+			return fmt.Errorf("unsupported action %v", rule.Action)
+		}
+		for _, name := range rule.Names {
+			if !isKnownSyscall(name) {
+				return fmt.Errorf("unsupported syscall %v", name)
+			}
+		}
+	}
+	return nil
+}
+
+// isAction returns true if the action is supported in the rules of profiles
+func isAction(action string) bool {
+	switch action {
+	case ActionAllow, ActionErrno, ActionKill, ActionKillProcess:
+		return true
+	}
+	return false
+}
+
+// IsSandboxed returns true if the document runs one of the plugins the config sandboxes
+func IsSandboxed(config appconfig.SsmCfg, plugins []contracts.PluginState) bool {
+	for _, plugin := range plugins {
+		for _, sandboxed := range config.DocumentWorkerSandboxPlugins {
+			if strings.TrimSpace(sandboxed) == plugin.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
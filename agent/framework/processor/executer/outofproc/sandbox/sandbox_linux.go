@@ -0,0 +1,228 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// envVarCapabilitiesDropped is set when the sandboxed worker executes itself again after dropping its capabilities
+	envVarCapabilitiesDropped = "AWS_SSM_SANDBOX_CAPABILITIES_DROPPED"
+
+	linuxCapabilityVersion3 = 0x20080522
+
+	capSysModule         = 16
+	capSysRawio          = 17
+	capSysPtrace         = 19
+	capSysAdmin          = 21
+	capSysBoot           = 22
+	capSysTime           = 25
+	capAuditControl      = 30
+	capMacOverride       = 32
+	capMacAdmin          = 33
+	capSyslog            = 34
+	capPerfmon           = 38
+	capBpf               = 39
+	capCheckpointRestore = 40
+)
+
+// droppedCapabilities are the capabilities sandboxed workers and the processes they start run without, which documents
+// do not need to administer an instance but would let a compromised document change the kernel or escape the sandbox
+var droppedCapabilities = []uintptr{
+	capSysModule, capSysRawio, capSysPtrace, capSysAdmin, capSysBoot, capSysTime, capAuditControl, capMacOverride,
+	capMacAdmin, capSyslog, capPerfmon, capBpf, capCheckpointRestore,
+}
+
+// capHeader and capData are the struct __user_cap_header_struct and struct __user_cap_data_struct of capget and capset
+type capHeader struct {
+	version uint32
+	pid     int32
+}
+
+type capData struct {
+	effective   uint32
+	permitted   uint32
+	inheritable uint32
+}
+
+// Supported returns true if document workers can run in a sandbox on this platform
+func Supported() bool {
+	_, found := auditArchitectures[runtime.GOARCH]
+	return found
+}
+
+// Prepare makes the command start in new mount and PID namespaces as a sandboxed worker with the seccomp profile.
+// The profile is loaded beforehand so that a broken profile fails the command before the worker starts.
+func Prepare(command *exec.Cmd, seccompProfile string) error {
+	if _, err := LoadProfile(seccompProfile); err != nil {
+		return err
+	}
+	if command.SysProcAttr == nil {
+		command.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	command.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNS | syscall.CLONE_NEWPID
+	if command.Env == nil {
+		command.Env = os.Environ()
+	}
+	command.Env = append(command.Env, envVarSeccompProfile+"="+seccompProfile)
+	return nil
+}
+
+// Enter confines the worker to its sandbox if the master started it as a sandboxed worker.
+// It must be called before the worker runs any plugin; the worker must exit if it fails.
+//
+// Capabilities belong to threads, so the worker drops them on one thread and starts itself again from that thread.
+// The new worker, and every process it starts, runs without them. The first worker stays PID 1 of the sandbox and
+// reaps the orphaned processes of the sandbox until the new worker exits, which ends the sandbox.
+func Enter(log log.T) error {
+	seccompProfile, sandboxed := os.LookupEnv(envVarSeccompProfile)
+	if !sandboxed {
+		return nil
+	}
+
+	profile, err := LoadProfile(seccompProfile)
+	if err != nil {
+		return err
+	}
+	if _, dropped := os.LookupEnv(envVarCapabilitiesDropped); !dropped {
+		var executable string
+		if executable, err = os.Executable(); err != nil {
+			return fmt.Errorf("failed to find the worker executable: %v", err)
+		}
+		// keep the mounts of the sandbox from propagating to the instance
+		if err = unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+			return fmt.Errorf("failed to make mounts private: %v", err)
+		}
+		if err = unix.Mount("proc", "/proc", "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+			return fmt.Errorf("failed to mount /proc: %v", err)
+		}
+		log.Debug("dropping capabilities and starting sandboxed document worker")
+		log.Flush()
+		return runWorkerWithoutCapabilities(executable)
+	}
+	// the processes started by plugins do not need to know about the sandbox
+	os.Unsetenv(envVarSeccompProfile)
+	os.Unsetenv(envVarCapabilitiesDropped)
+
+	if err = unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to set no_new_privs: %v", err)
+	}
+	if err = installFilter(profile); err != nil {
+		return err
+	}
+	log.Infof("document worker entered sandbox with seccomp profile %v", profileName(seccompProfile))
+	return nil
+}
+
+// runWorkerWithoutCapabilities drops the capabilities of the calling thread and starts the worker again from it.
+// The calling process stays PID 1 of the sandbox until the worker exits, then exits with the exit code of the worker.
+// It only returns if it fails.
+func runWorkerWithoutCapabilities(executable string) error {
+	// the thread is never unlocked, it must not run other goroutines once it lost its capabilities
+	runtime.LockOSThread()
+	if err := dropCapabilities(); err != nil {
+		return err
+	}
+
+	// notified before the worker starts so that its exit is not missed
+	signals := make(chan os.Signal, 16)
+	signal.Notify(signals, syscall.SIGCHLD, syscall.SIGTERM, syscall.SIGINT)
+	worker, err := os.StartProcess(executable, os.Args, &os.ProcAttr{
+		Env:   append(os.Environ(), envVarCapabilitiesDropped+"=true"),
+		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr},
+	})
+	if err != nil {
+		signal.Stop(signals)
+		return fmt.Errorf("failed to start sandboxed document worker: %v", err)
+	}
+	os.Exit(reap(worker.Pid, signals))
+	return nil
+}
+
+// reap waits for the processes of the sandbox as they exit, so that processes orphaned by plugins do not remain zombies,
+// and forwards termination signals to the worker. It returns the exit code of the worker once it exits.
+func reap(workerPid int, signals <-chan os.Signal) int {
+	for sig := range signals {
+		if sig != syscall.SIGCHLD {
+			syscall.Kill(workerPid, sig.(syscall.Signal))
+			continue
+		}
+		// SIGCHLD is not queued, a signal may stand for several exited processes
+		for {
+			var status syscall.WaitStatus
+			pid, err := syscall.Wait4(-1, &status, syscall.WNOHANG, nil)
+			if err != nil || pid <= 0 {
+				break
+			}
+			if pid != workerPid {
+				continue
+			}
+			if status.Signaled() {
+				return 128 + int(status.Signal())
+			}
+			return status.ExitStatus()
+		}
+	}
+	return 1
+}
+
+// dropCapabilities removes the dropped capabilities from the bounding, ambient, inheritable, permitted and effective
+// sets of the calling thread, so that neither the thread nor the programs it executes can have them.
+// Capabilities the kernel does not know are ignored.
+func dropCapabilities() error {
+	for _, capability := range droppedCapabilities {
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, capability, 0, 0, 0); err != nil && err != unix.EINVAL {
+			return fmt.Errorf("failed to drop capability %v from the bounding set: %v", capability, err)
+		}
+	}
+	if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0); err != nil && err != unix.EINVAL {
+		return fmt.Errorf("failed to clear the ambient capabilities: %v", err)
+	}
+
+	header := capHeader{version: linuxCapabilityVersion3}
+	var data [2]capData
+	if _, _, errno := unix.RawSyscall(unix.SYS_CAPGET, uintptr(unsafe.Pointer(&header)), uintptr(unsafe.Pointer(&data[0])), 0); errno != 0 {
+		return fmt.Errorf("failed to read capabilities: %v", errno)
+	}
+	for _, capability := range droppedCapabilities {
+		mask := ^(uint32(1) << (capability % 32))
+		data[capability/32].effective &= mask
+		data[capability/32].permitted &= mask
+		data[capability/32].inheritable &= mask
+	}
+	if _, _, errno := unix.RawSyscall(unix.SYS_CAPSET, uintptr(unsafe.Pointer(&header)), uintptr(unsafe.Pointer(&data[0])), 0); errno != 0 {
+		return fmt.Errorf("failed to drop capabilities: %v", errno)
+	}
+	return nil
+}
+
+// profileName returns the name of the profile in logs
+func profileName(seccompProfile string) string {
+	if seccompProfile == "" {
+		return "default"
+	}
+	return seccompProfile
+}
@@ -0,0 +1,43 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build !linux
+
+package sandbox
+
+import (
+	"errors"
+	"os/exec"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// Supported returns true if document workers can run in a sandbox on this platform
+func Supported() bool {
+	return false
+}
+
+// Prepare returns an error as document workers cannot run in a sandbox on this platform
+func Prepare(command *exec.Cmd, seccompProfile string) error {
+	return errors.New("document workers can only run in a sandbox on linux")
+}
+
+// Enter does nothing as document workers are never sandboxed on this platform
+func Enter(log log.T) error {
+	return nil
+}
+
+// isKnownSyscall returns false as seccomp profiles are only supported on linux
+func isKnownSyscall(name string) bool {
+	return false
+}
@@ -0,0 +1,30 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sandbox
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSandboxed(t *testing.T) {
+	plugins := []contracts.PluginState{{Name: appconfig.PluginDownloadContent}, {Name: appconfig.PluginNameAwsRunShellScript}}
+
+	assert.False(t, IsSandboxed(appconfig.SsmCfg{}, plugins))
+	assert.False(t, IsSandboxed(appconfig.SsmCfg{DocumentWorkerSandboxPlugins: []string{appconfig.PluginNameAwsRunPowerShellScript}}, plugins))
+	assert.True(t, IsSandboxed(appconfig.SsmCfg{DocumentWorkerSandboxPlugins: []string{" " + appconfig.PluginNameAwsRunShellScript}}, plugins))
+}
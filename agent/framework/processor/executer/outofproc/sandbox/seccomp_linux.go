@@ -0,0 +1,204 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package sandbox
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"unsafe"
+
+	"golang.org/x/net/bpf"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	seccompSetModeFilter   = 1
+	seccompFilterFlagTsync = 1
+
+	seccompRetKillProcess = 0x80000000
+	seccompRetErrno       = 0x00050000
+	seccompRetAllow       = 0x7fff0000
+
+	// offsets of the fields of struct seccomp_data, the offset of the first argument is the offset of its lower 32 bits
+	// as all supported architectures are little endian
+	seccompDataNrOffset   = 0
+	seccompDataArchOffset = 4
+	seccompDataArg0Offset = 16
+
+	// sysClone3 is the number of clone3 on all supported architectures
+	sysClone3 = 435
+
+	// namespaceCloneFlags are the clone flags creating namespaces
+	namespaceCloneFlags = unix.CLONE_NEWNS | unix.CLONE_NEWCGROUP | unix.CLONE_NEWUTS | unix.CLONE_NEWIPC |
+		unix.CLONE_NEWUSER | unix.CLONE_NEWPID | unix.CLONE_NEWNET
+
+	// x32SyscallBit marks the syscalls of the x32 ABI, which share the audit architecture of amd64
+	x32SyscallBit = 0x40000000
+)
+
+// auditArchitectures are the audit architectures of the syscalls made by the agent on each GOARCH
+var auditArchitectures = map[string]uint32{
+	"386":   0x40000003,
+	"amd64": 0xc000003e,
+	"arm":   0x40000028,
+	"arm64": 0xc00000b7,
+}
+
+// syscallNumbers are the syscalls profiles can name
+var syscallNumbers = map[string]uint32{
+	"acct":              unix.SYS_ACCT,
+	"add_key":           unix.SYS_ADD_KEY,
+	"adjtimex":          unix.SYS_ADJTIMEX,
+	"bpf":               unix.SYS_BPF,
+	"chroot":            unix.SYS_CHROOT,
+	"clock_adjtime":     unix.SYS_CLOCK_ADJTIME,
+	"clock_settime":     unix.SYS_CLOCK_SETTIME,
+	"delete_module":     unix.SYS_DELETE_MODULE,
+	"fanotify_init":     unix.SYS_FANOTIFY_INIT,
+	"finit_module":      unix.SYS_FINIT_MODULE,
+	"init_module":       unix.SYS_INIT_MODULE,
+	"kcmp":              unix.SYS_KCMP,
+	"kexec_load":        unix.SYS_KEXEC_LOAD,
+	"keyctl":            unix.SYS_KEYCTL,
+	"lookup_dcookie":    unix.SYS_LOOKUP_DCOOKIE,
+	"mount":             unix.SYS_MOUNT,
+	"name_to_handle_at": unix.SYS_NAME_TO_HANDLE_AT,
+	"open_by_handle_at": unix.SYS_OPEN_BY_HANDLE_AT,
+	"perf_event_open":   unix.SYS_PERF_EVENT_OPEN,
+	"pivot_root":        unix.SYS_PIVOT_ROOT,
+	"process_vm_readv":  unix.SYS_PROCESS_VM_READV,
+	"process_vm_writev": unix.SYS_PROCESS_VM_WRITEV,
+	"ptrace":            unix.SYS_PTRACE,
+	"quotactl":          unix.SYS_QUOTACTL,
+	"reboot":            unix.SYS_REBOOT,
+	"request_key":       unix.SYS_REQUEST_KEY,
+	"setdomainname":     unix.SYS_SETDOMAINNAME,
+	"sethostname":       unix.SYS_SETHOSTNAME,
+	"setns":             unix.SYS_SETNS,
+	"settimeofday":      unix.SYS_SETTIMEOFDAY,
+	"swapoff":           unix.SYS_SWAPOFF,
+	"swapon":            unix.SYS_SWAPON,
+	"syslog":            unix.SYS_SYSLOG,
+	"umount2":           unix.SYS_UMOUNT2,
+	"unshare":           unix.SYS_UNSHARE,
+	"userfaultfd":       unix.SYS_USERFAULTFD,
+	"vhangup":           unix.SYS_VHANGUP,
+}
+
+// confinementSyscalls are the syscalls denied whatever the profile, which would let a process leave the namespaces
+// of the sandbox, create namespaces of its own or control other processes
+var confinementSyscalls = []string{"ptrace", "setns", "unshare"}
+
+// isKnownSyscall returns true if profiles can name the syscall
+func isKnownSyscall(name string) bool {
+	_, found := syscallNumbers[name]
+	return found
+}
+
+// seccompReturn returns the value the filter returns for the action of a profile
+func seccompReturn(action string) uint32 {
+	switch action {
+	case ActionErrno:
+		return seccompRetErrno | uint32(unix.EPERM)
+	case ActionKill, ActionKillProcess:
+		return seccompRetKillProcess
+	}
+	return seccompRetAllow
+}
+
+// buildFilter returns the seccomp filter of the profile for syscalls of the architecture.
+// Syscalls of other architectures kill the process, as their numbers do not match the profile.
+// Whatever the profile, the confinement syscalls and clone creating namespaces fail with EPERM, and clone3, whose
+// flags the filter cannot read, fails with ENOSYS so that the C library falls back to clone.
+func buildFilter(profile Profile, goarch string) ([]bpf.RawInstruction, error) {
+	auditArch, found := auditArchitectures[goarch]
+	if !found {
+		return nil, fmt.Errorf("seccomp filters are not supported on %v", goarch)
+	}
+	if err := profile.validate(); err != nil {
+		return nil, err
+	}
+
+	program := []bpf.Instruction{
+		bpf.LoadAbsolute{Off: seccompDataArchOffset, Size: 4},
+		bpf.JumpIf{Cond: bpf.JumpEqual, Val: auditArch, SkipTrue: 1},
+		bpf.RetConstant{Val: seccompRetKillProcess},
+		bpf.LoadAbsolute{Off: seccompDataNrOffset, Size: 4},
+	}
+	if goarch == "amd64" {
+		program = append(program,
+			bpf.JumpIf{Cond: bpf.JumpGreaterOrEqual, Val: x32SyscallBit, SkipFalse: 1},
+			bpf.RetConstant{Val: seccompReturn(ActionErrno)})
+	}
+	program = append(program,
+		bpf.JumpIf{Cond: bpf.JumpEqual, Val: sysClone3, SkipFalse: 1},
+		bpf.RetConstant{Val: seccompRetErrno | uint32(unix.ENOSYS)},
+		bpf.JumpIf{Cond: bpf.JumpEqual, Val: unix.SYS_CLONE, SkipFalse: 3},
+		bpf.LoadAbsolute{Off: seccompDataArg0Offset, Size: 4},
+		bpf.JumpIf{Cond: bpf.JumpBitsSet, Val: namespaceCloneFlags, SkipFalse: 1},
+		bpf.RetConstant{Val: seccompReturn(ActionErrno)},
+		bpf.LoadAbsolute{Off: seccompDataNrOffset, Size: 4})
+	for _, name := range confinementSyscalls {
+		program = append(program,
+			bpf.JumpIf{Cond: bpf.JumpEqual, Val: syscallNumbers[name], SkipFalse: 1},
+			bpf.RetConstant{Val: seccompReturn(ActionErrno)})
+	}
+
+	// the first rule naming a syscall decides its action
+	actions := make(map[string]string)
+	for _, rule := range profile.Syscalls {
+		for _, name := range rule.Names {
+			if _, found := actions[name]; !found {
+				actions[name] = rule.Action
+			}
+		}
+	}
+	names := make([]string, 0, len(actions))
+	for name := range actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		program = append(program,
+			bpf.JumpIf{Cond: bpf.JumpEqual, Val: syscallNumbers[name], SkipFalse: 1},
+			bpf.RetConstant{Val: seccompReturn(actions[name])})
+	}
+	program = append(program, bpf.RetConstant{Val: seccompReturn(profile.DefaultAction)})
+	return bpf.Assemble(program)
+}
+
+// installFilter applies the seccomp filter of the profile to all threads of the process.
+// The process must have set no_new_privs before.
+func installFilter(profile Profile) error {
+	filter, err := buildFilter(profile, runtime.GOARCH)
+	if err != nil {
+		return err
+	}
+	sockFilter := make([]unix.SockFilter, len(filter))
+	for i, instruction := range filter {
+		sockFilter[i] = unix.SockFilter{Code: instruction.Op, Jt: instruction.Jt, Jf: instruction.Jf, K: instruction.K}
+	}
+	program := unix.SockFprog{Len: uint16(len(sockFilter)), Filter: &sockFilter[0]}
+	thread, _, errno := unix.Syscall(unix.SYS_SECCOMP, seccompSetModeFilter, seccompFilterFlagTsync, uintptr(unsafe.Pointer(&program)))
+	if errno != 0 {
+		return fmt.Errorf("failed to install seccomp filter: %v", errno)
+	}
+	if thread != 0 {
+		return fmt.Errorf("failed to install seccomp filter on thread %v", thread)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package sandbox
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/bpf"
+	"golang.org/x/sys/unix"
+)
+
+// runFilter returns the action of the filter for the syscall.
+// The virtual machine loads words in network byte order, so the seccomp data is written in big endian.
+func runFilter(t *testing.T, filter []bpf.RawInstruction, nr uint32, auditArch uint32) uint32 {
+	instructions, allDecoded := bpf.Disassemble(filter)
+	assert.True(t, allDecoded)
+	vm, err := bpf.NewVM(instructions)
+	assert.NoError(t, err)
+
+	data := make([]byte, 64)
+	binary.BigEndian.PutUint32(data[seccompDataNrOffset:], nr)
+	binary.BigEndian.PutUint32(data[seccompDataArchOffset:], auditArch)
+	action, err := vm.Run(data)
+	assert.NoError(t, err)
+	return uint32(action)
+}
+
+func TestBuildFilter_DefaultProfile(t *testing.T) {
+	filter, err := buildFilter(DefaultProfile(), "amd64")
+	assert.NoError(t, err)
+
+	amd64 := auditArchitectures["amd64"]
+	assert.Equal(t, uint32(seccompRetErrno|uint32(unix.EPERM)), runFilter(t, filter, syscallNumbers["mount"], amd64))
+	assert.Equal(t, uint32(seccompRetErrno|uint32(unix.EPERM)), runFilter(t, filter, syscallNumbers["ptrace"], amd64))
+	assert.Equal(t, uint32(seccompRetAllow), runFilter(t, filter, unix.SYS_READ, amd64))
+	assert.Equal(t, uint32(seccompRetErrno|uint32(unix.EPERM)), runFilter(t, filter, x32SyscallBit|unix.SYS_READ, amd64))
+	assert.Equal(t, uint32(seccompRetKillProcess), runFilter(t, filter, unix.SYS_READ, auditArchitectures["386"]))
+}
+
+func TestBuildFilter_Profile(t *testing.T) {
+	profile := Profile{
+		DefaultAction: ActionAllow,
+		Syscalls: []SyscallRule{
+			{Names: []string{"reboot"}, Action: ActionKill},
+			{Names: []string{"reboot", "mount"}, Action: ActionErrno},
+			{Names: []string{"ptrace", "unshare"}, Action: ActionAllow},
+		},
+	}
+	filter, err := buildFilter(profile, "amd64")
+	assert.NoError(t, err)
+
+	amd64 := auditArchitectures["amd64"]
+	assert.Equal(t, uint32(seccompRetKillProcess), runFilter(t, filter, syscallNumbers["reboot"], amd64))
+	assert.Equal(t, uint32(seccompRetErrno|uint32(unix.EPERM)), runFilter(t, filter, syscallNumbers["mount"], amd64))
+	// the confinement syscalls are denied even if the profile allows them
+	assert.Equal(t, uint32(seccompRetErrno|uint32(unix.EPERM)), runFilter(t, filter, syscallNumbers["ptrace"], amd64))
+	assert.Equal(t, uint32(seccompRetErrno|uint32(unix.EPERM)), runFilter(t, filter, syscallNumbers["unshare"], amd64))
+
+	_, err = buildFilter(profile, "s390x")
+	assert.Error(t, err)
+}
+
+func TestBuildFilter_Clone(t *testing.T) {
+	filter, err := buildFilter(Profile{DefaultAction: ActionAllow}, runtime.GOARCH)
+	assert.NoError(t, err)
+	instructions, _ := bpf.Disassemble(filter)
+	vm, err := bpf.NewVM(instructions)
+	assert.NoError(t, err)
+
+	runClone := func(nr uint32, flags uint32) uint32 {
+		data := make([]byte, 64)
+		binary.BigEndian.PutUint32(data[seccompDataNrOffset:], nr)
+		binary.BigEndian.PutUint32(data[seccompDataArchOffset:], auditArchitectures[runtime.GOARCH])
+		binary.BigEndian.PutUint32(data[seccompDataArg0Offset:], flags)
+		action, err := vm.Run(data)
+		assert.NoError(t, err)
+		return uint32(action)
+	}
+	assert.Equal(t, uint32(seccompRetAllow), runClone(unix.SYS_CLONE, unix.CLONE_VM|unix.CLONE_VFORK|uint32(unix.SIGCHLD)))
+	assert.Equal(t, uint32(seccompRetAllow), runClone(unix.SYS_CLONE, unix.CLONE_VM|unix.CLONE_FS|unix.CLONE_FILES|unix.CLONE_SIGHAND|unix.CLONE_THREAD))
+	assert.Equal(t, uint32(seccompRetErrno|uint32(unix.EPERM)), runClone(unix.SYS_CLONE, unix.CLONE_NEWUSER|uint32(unix.SIGCHLD)))
+	assert.Equal(t, uint32(seccompRetErrno|uint32(unix.EPERM)), runClone(unix.SYS_CLONE, unix.CLONE_NEWNS|unix.CLONE_NEWPID))
+	assert.Equal(t, uint32(seccompRetErrno|uint32(unix.ENOSYS)), runClone(sysClone3, 0))
+	assert.Equal(t, uint32(seccompRetErrno|uint32(unix.EPERM)), runClone(syscallNumbers["setns"], 0))
+}
+
+func TestDropCapabilities(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("dropping capabilities requires root")
+	}
+	status := make(chan string)
+	go func() {
+		// the thread is never unlocked, it ends with the goroutine
+		runtime.LockOSThread()
+		err := dropCapabilities()
+		assert.NoError(t, err)
+		content, _ := ioutil.ReadFile(fmt.Sprintf("/proc/self/task/%v/status", unix.Gettid()))
+		status <- string(content)
+	}()
+	sets := make(map[string]uint64)
+	for _, line := range strings.Split(<-status, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.HasPrefix(fields[0], "Cap") {
+			sets[fields[0]], _ = strconv.ParseUint(fields[1], 16, 64)
+		}
+	}
+	for _, set := range []string{"CapInh:", "CapPrm:", "CapEff:", "CapBnd:", "CapAmb:"} {
+		assert.Contains(t, sets, set)
+		for _, capability := range droppedCapabilities {
+			assert.Zero(t, sets[set]&(1<<capability), "%v has capability %v", set, capability)
+		}
+	}
+	assert.NotZero(t, sets["CapEff:"]&(1<<capSysModule-1), "capabilities that are not dropped are kept")
+}
+
+func TestLoadProfile(t *testing.T) {
+	profile, err := LoadProfile("")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultProfile(), profile)
+
+	file, _ := ioutil.TempFile("", "seccomp")
+	defer os.Remove(file.Name())
+	file.WriteString(`{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["mount", "umount2"], "action": "SCMP_ACT_ERRNO"}]}`)
+	file.Close()
+	profile, err = LoadProfile(file.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, []SyscallRule{{Names: []string{"mount", "umount2"}, Action: ActionErrno}}, profile.Syscalls)
+
+	invalid := []string{
+		`{"defaultAction": "SCMP_ACT_ERRNO", "syscalls": [{"names": ["read"], "action": "SCMP_ACT_ALLOW"}]}`,
+		`{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["mount"], "action": "SCMP_ACT_TRACE"}]}`,
+		`{"defaultAction": "SCMP_ACT_ALLOW", "syscalls": [{"names": ["not_a_syscall"], "action": "SCMP_ACT_ERRNO"}]}`,
+		`not json`,
+	}
+	for _, content := range invalid {
+		ioutil.WriteFile(file.Name(), []byte(content), 0600)
+		_, err = LoadProfile(file.Name())
+		assert.Error(t, err, content)
+	}
+	_, err = LoadProfile("/nonexistent/seccomp.json")
+	assert.Error(t, err)
+}
+
+func TestPrepare(t *testing.T) {
+	command := exec.Command("true")
+	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	assert.NoError(t, Prepare(command, ""))
+	assert.True(t, command.SysProcAttr.Setpgid)
+	assert.Equal(t, uintptr(syscall.CLONE_NEWNS|syscall.CLONE_NEWPID), command.SysProcAttr.Cloneflags)
+	assert.Contains(t, command.Env, envVarSeccompProfile+"=")
+
+	assert.Error(t, Prepare(exec.Command("true"), "/nonexistent/seccomp.json"))
+}
+
+func TestReap(t *testing.T) {
+	signals := make(chan os.Signal, 16)
+	signal.Notify(signals, syscall.SIGCHLD)
+	defer signal.Stop(signals)
+
+	orphan, err := os.StartProcess("/bin/sh", []string{"sh", "-c", "exit 0"}, &os.ProcAttr{})
+	assert.NoError(t, err)
+	worker, err := os.StartProcess("/bin/sh", []string{"sh", "-c", "sleep 0.2; exit 3"}, &os.ProcAttr{})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, reap(worker.Pid, signals))
+
+	// processes exiting before the worker were reaped
+	_, err = syscall.Wait4(orphan.Pid, nil, syscall.WNOHANG, nil)
+	assert.Equal(t, syscall.ECHILD, err)
+}
+
+func TestReapForwardsTermination(t *testing.T) {
+	signals := make(chan os.Signal, 16)
+	signal.Notify(signals, syscall.SIGCHLD)
+	defer signal.Stop(signals)
+
+	worker, err := os.StartProcess("/bin/sh", []string{"sh", "-c", "trap 'exit 5' TERM; while true; do sleep 0.01; done"}, &os.ProcAttr{})
+	assert.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	signals <- syscall.SIGTERM
+	assert.Equal(t, 5, reap(worker.Pid, signals))
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/channel"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/messaging"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/proc"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/sandbox"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/plugin"
 	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
@@ -40,8 +41,12 @@ var pluginRunner = func(
 func initialize(args []string) (context.T, string, error) {
 	// intialize a light weight logger, use the default seelog config logger
 	logger := ssmlog.SSMLogger(false)
-	// initialize appconfig, use default config
-	config := appconfig.DefaultConfig()
+	// initialize appconfig, plugins such as aws:configurePackage read their settings from it
+	config, err := appconfig.Config(false)
+	if err != nil {
+		logger.Warnf("failed to load appconfig, using default config: %v", err)
+		config = appconfig.DefaultConfig()
+	}
 	logger.Infof("parsing args: %v", args)
 	channelName, instanceID, err := proc.ParseArgv(args)
 	logger.Infof("using channelName %v, instanceID: %v", channelName, instanceID)
@@ -66,6 +71,12 @@ func main() {
 		return
 	}
 	logger.Infof("document: %v worker started", channelName)
+	//confine the worker before it runs any plugin, if the master started it in a sandbox
+	if err = sandbox.Enter(logger); err != nil {
+		logger.Errorf("failed to enter sandbox: %v", err)
+		logger.Close()
+		return
+	}
 	//create channel from the given handle identifier by master
 	ipc, err, _ := channel.CreateFileChannel(logger, channel.ModeWorker, channelName)
 	if err != nil {
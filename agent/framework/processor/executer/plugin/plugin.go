@@ -22,6 +22,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/compliancescan"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurecontainers"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/dockercontainer"
@@ -30,8 +31,12 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/lrpminvoker"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/refreshassociation"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/rundocument"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/runpythonscript"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/runscript"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/updatessmagent"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/uploadcontent"
+	"github.com/aws/amazon-ssm-agent/agent/session/plugins/external"
+	"github.com/aws/amazon-ssm-agent/agent/session/plugins/filetransfer"
 	"github.com/aws/amazon-ssm-agent/agent/session/plugins/interactivecommands"
 	"github.com/aws/amazon-ssm-agent/agent/session/plugins/port"
 	"github.com/aws/amazon-ssm-agent/agent/session/plugins/sessionplugin"
@@ -47,9 +52,14 @@ var allPlugins = map[string]struct{}{
 	appconfig.PluginNameAwsConfigureDaemon:     {},
 	appconfig.PluginNameAwsConfigurePackage:    {},
 	appconfig.PluginNameAwsPowerShellModule:    {},
+	appconfig.PluginNameAwsRunAnsiblePlaybook:  {},
+	appconfig.PluginNameAwsRunComplianceScan:   {},
 	appconfig.PluginNameAwsRunPowerShellScript: {},
+	appconfig.PluginNameAwsRunPythonScript:     {},
 	appconfig.PluginNameAwsRunShellScript:      {},
 	appconfig.PluginNameAwsSoftwareInventory:   {},
+	appconfig.PluginNameAwsSystemdService:      {},
+	appconfig.PluginNameAwsWindowsService:      {},
 	appconfig.PluginNameCloudWatch:             {},
 	appconfig.PluginNameConfigureDocker:        {},
 	appconfig.PluginNameDockerContainer:        {},
@@ -57,6 +67,7 @@ var allPlugins = map[string]struct{}{
 	appconfig.PluginEC2ConfigUpdate:            {},
 	appconfig.PluginNameRefreshAssociation:     {},
 	appconfig.PluginDownloadContent:            {},
+	appconfig.PluginUploadContent:              {},
 	appconfig.PluginRunDocument:                {},
 }
 
@@ -86,6 +97,20 @@ func (f RunPowerShellFactory) Create(context context.T) (runpluginutil.T, error)
 	return runscript.NewRunPowerShellPlugin()
 }
 
+type RunPythonScriptFactory struct {
+}
+
+func (f RunPythonScriptFactory) Create(context context.T) (runpluginutil.T, error) {
+	return runpythonscript.NewPlugin()
+}
+
+type RunComplianceScanFactory struct {
+}
+
+func (f RunComplianceScanFactory) Create(context context.T) (runpluginutil.T, error) {
+	return compliancescan.NewPlugin()
+}
+
 type UpdateAgentFactory struct {
 }
 
@@ -128,6 +153,13 @@ func (d DownloadContentFactory) Create(context context.T) (runpluginutil.T, erro
 	return downloadcontent.NewPlugin()
 }
 
+type UploadContentFactory struct {
+}
+
+func (u UploadContentFactory) Create(context context.T) (runpluginutil.T, error) {
+	return uploadcontent.NewPlugin()
+}
+
 type RunDocumentFactory struct {
 }
 
@@ -160,9 +192,9 @@ func RegisteredWorkerPlugins(context context.T) runpluginutil.PluginRegistry {
 }
 
 // RegisteredSessionWorkerPlugins returns all registered session plugins.
-func RegisteredSessionWorkerPlugins() runpluginutil.PluginRegistry {
+func RegisteredSessionWorkerPlugins(context context.T) runpluginutil.PluginRegistry {
 	once.Do(func() {
-		loadSessionPlugins()
+		loadSessionPlugins(context)
 	})
 	return *registeredPlugins
 }
@@ -189,8 +221,9 @@ func loadWorkers(context context.T) {
 	registeredPlugins = &plugins
 }
 
-// loadSessionPlugins loads all session plugins
-func loadSessionPlugins() {
+// loadSessionPlugins loads all session plugins and then the external session plugins
+// (if there are any conflicting names, the built-in session plugin wins)
+func loadSessionPlugins(context context.T) {
 	var sessionPlugins = runpluginutil.PluginRegistry{}
 
 	standardStreamPluginName := appconfig.PluginNameStandardStream
@@ -202,6 +235,19 @@ func loadSessionPlugins() {
 	portPluginName := appconfig.PluginNamePort
 	sessionPlugins[portPluginName] = SessionPluginFactory{port.NewPlugin}
 
+	fileTransferPluginName := appconfig.PluginNameFileTransfer
+	sessionPlugins[fileTransferPluginName] = SessionPluginFactory{filetransfer.NewPlugin}
+
+	for name, path := range external.DiscoverPlugins(context.Log(), external.PluginsDirectory()) {
+		if _, exists := sessionPlugins[name]; exists {
+			context.Log().Warnf("Ignoring external session plugin %v as it conflicts with a built-in session plugin", name)
+			continue
+		}
+		sessionPlugins[name] = SessionPluginFactory{external.NewPluginFunc(name, path)}
+		runpluginutil.RegisterSessionPlugin(name)
+		context.Log().Infof("Successfully loaded external session plugin %v", name)
+	}
+
 	registeredPlugins = &sessionPlugins
 }
 
@@ -215,6 +261,14 @@ func loadPlatformIndependentPlugins(context context.T) runpluginutil.PluginRegis
 	// registering aws:runPowerShellScript plugin
 	workerPlugins[appconfig.PluginNameAwsRunPowerShellScript] = RunPowerShellFactory{}
 
+	// registering aws:runPythonScript plugin
+	runPythonScriptPluginName := runpythonscript.Name()
+	workerPlugins[runPythonScriptPluginName] = RunPythonScriptFactory{}
+
+	// registering aws:runComplianceScan plugin
+	runComplianceScanPluginName := compliancescan.Name()
+	workerPlugins[runComplianceScanPluginName] = RunComplianceScanFactory{}
+
 	// registering aws:updateSsmAgent plugin
 	updateAgentPluginName := updatessmagent.Name()
 	workerPlugins[updateAgentPluginName] = UpdateAgentFactory{}
@@ -240,6 +294,10 @@ func loadPlatformIndependentPlugins(context context.T) runpluginutil.PluginRegis
 	downloadContentPluginName := downloadcontent.Name()
 	workerPlugins[downloadContentPluginName] = DownloadContentFactory{}
 
+	//registering aws:uploadContent
+	uploadContentPluginName := uploadcontent.Name()
+	workerPlugins[uploadContentPluginName] = UploadContentFactory{}
+
 	//registering aws:runDocument
 	runDocumentPluginName := rundocument.Name()
 	workerPlugins[runDocumentPluginName] = RunDocumentFactory{}
@@ -19,7 +19,9 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/framework/runpluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/runansibleplaybook"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/runscript"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/systemdservice"
 )
 
 type RunShellScriptFactory struct {
@@ -29,10 +31,26 @@ func (f RunShellScriptFactory) Create(context context.T) (runpluginutil.T, error
 	return runscript.NewRunShellPlugin(context.Log())
 }
 
+type SystemdServiceFactory struct {
+}
+
+func (f SystemdServiceFactory) Create(context context.T) (runpluginutil.T, error) {
+	return systemdservice.NewPlugin()
+}
+
+type RunAnsiblePlaybookFactory struct {
+}
+
+func (f RunAnsiblePlaybookFactory) Create(context context.T) (runpluginutil.T, error) {
+	return runansibleplaybook.NewPlugin()
+}
+
 // loadPlatformDependentPlugins registers platform dependent plugins
 func loadPlatformDependentPlugins(context context.T) runpluginutil.PluginRegistry {
 	var workerPlugins = runpluginutil.PluginRegistry{}
 
 	workerPlugins[appconfig.PluginNameAwsRunShellScript] = RunShellScriptFactory{}
+	workerPlugins[systemdservice.Name()] = SystemdServiceFactory{}
+	workerPlugins[runansibleplaybook.Name()] = RunAnsiblePlaybookFactory{}
 	return workerPlugins
 }
@@ -25,6 +25,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/domainjoin"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/psmodule"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/updateec2config"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/windowsservice"
 )
 
 type PsModuleFactory struct {
@@ -55,6 +56,13 @@ func (f UpdateEc2ConfigFactory) Create(context context.T) (runpluginutil.T, erro
 	return updateec2config.NewPlugin(updateec2config.GetUpdatePluginConfig(context))
 }
 
+type WindowsServiceFactory struct {
+}
+
+func (f WindowsServiceFactory) Create(context context.T) (runpluginutil.T, error) {
+	return windowsservice.NewPlugin()
+}
+
 // loadPlatformDependentPlugins registers platform dependent plugins
 func loadPlatformDependentPlugins(context context.T) runpluginutil.PluginRegistry {
 	var workerPlugins = runpluginutil.PluginRegistry{}
@@ -75,6 +83,10 @@ func loadPlatformDependentPlugins(context context.T) runpluginutil.PluginRegistr
 	updateEC2AgentPluginName := updateec2config.Name()
 	workerPlugins[updateEC2AgentPluginName] = UpdateEc2ConfigFactory{}
 
+	// registering aws:windowsService plugin
+	windowsServicePluginName := windowsservice.Name()
+	workerPlugins[windowsServicePluginName] = WindowsServiceFactory{}
+
 	//// registering aws:configureDaemon
 	//configureDaemonPluginName := configuredaemon.Name()
 	//configureDaemonPlugin, err := configuredaemon.NewPlugin(pluginutil.DefaultPluginConfig())
@@ -30,6 +30,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/framework/docmanager"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc"
+	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/longrunning/manager"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/rebooter"
@@ -374,6 +375,24 @@ func processCancelCommand(context context.T, sendCommandPool task.Pool, docState
 		docState.DocumentInformation.DocumentID,
 		docState.DocumentInformation.InstanceID,
 		appconfig.DefaultLocationOfPending, appconfig.DefaultLocationOfCurrent)
+	if docState.CancelInformation.CancelPluginID != "" {
+		cancelStep(log, sendCommandPool, docState)
+	} else {
+		cancelJob(log, sendCommandPool, docState)
+	}
+
+	//persist : commands execution in completed folder (terminal state folder)
+	log.Debugf("Execution of %v is over. Removing interimState file from Current folder", docState.DocumentInformation.MessageID)
+
+	docMgr.RemoveDocumentState(log,
+		docState.DocumentInformation.DocumentID,
+		docState.DocumentInformation.InstanceID,
+		appconfig.DefaultLocationOfCurrent)
+
+}
+
+// cancelJob cancels the whole command
+func cancelJob(log log.T, sendCommandPool task.Pool, docState *contracts.DocumentState) {
 	log.Debugf("Canceling job with id %v...", docState.CancelInformation.CancelMessageID)
 
 	if found := sendCommandPool.Cancel(docState.CancelInformation.CancelMessageID); !found {
@@ -384,15 +403,21 @@ func processCancelCommand(context context.T, sendCommandPool task.Pool, docState
 		docState.CancelInformation.DebugInfo = fmt.Sprintf("Command %v cancelled", docState.CancelInformation.CancelCommandID)
 		docState.DocumentInformation.DocumentStatus = contracts.ResultStatusSuccess
 	}
+}
 
-	//persist : commands execution in completed folder (terminal state folder)
-	log.Debugf("Execution of %v is over. Removing interimState file from Current folder", docState.DocumentInformation.MessageID)
-
-	docMgr.RemoveDocumentState(log,
-		docState.DocumentInformation.DocumentID,
-		docState.DocumentInformation.InstanceID,
-		appconfig.DefaultLocationOfCurrent)
+// cancelStep cancels a single step of the command, the command keeps running the steps after it
+func cancelStep(log log.T, sendCommandPool task.Pool, docState *contracts.DocumentState) {
+	cancelInfo := &docState.CancelInformation
+	log.Debugf("Canceling step %v of job with id %v...", cancelInfo.CancelPluginID, cancelInfo.CancelMessageID)
 
+	if found := sendCommandPool.CancelStep(cancelInfo.CancelMessageID, cancelInfo.CancelPluginID); !found {
+		log.Debugf("Job with id %v not found (possibly completed)", cancelInfo.CancelMessageID)
+		cancelInfo.DebugInfo = fmt.Sprintf("Step %v of command %v couldn't be cancelled", cancelInfo.CancelPluginID, cancelInfo.CancelCommandID)
+		docState.DocumentInformation.DocumentStatus = contracts.ResultStatusFailed
+	} else {
+		cancelInfo.DebugInfo = fmt.Sprintf("Step %v of command %v cancelled", cancelInfo.CancelPluginID, cancelInfo.CancelCommandID)
+		docState.DocumentInformation.DocumentStatus = contracts.ResultStatusSuccess
+	}
 }
 
 //TODO remove this once CloudWatch plugin is reworked
@@ -191,6 +191,24 @@ func TestProcessCancelCommand_Success(t *testing.T) {
 
 }
 
+func TestProcessCancelCommand_Step(t *testing.T) {
+	ctx := context.NewMockDefault()
+	sendCommandPoolMock := new(task.MockedPool)
+	docState := contracts.DocumentState{}
+	docState.CancelInformation.CancelMessageID = "messageID"
+	docState.CancelInformation.CancelCommandID = "commandID"
+	docState.CancelInformation.CancelPluginID = "stuckStep"
+	sendCommandPoolMock.On("CancelStep", "messageID", "stuckStep").Return(true)
+	docMock := new(DocumentMgrMock)
+	docMock.On("MoveDocumentState", mock.Anything, "", "", appconfig.DefaultLocationOfPending, appconfig.DefaultLocationOfCurrent)
+	docMock.On("RemoveDocumentState", mock.Anything, "", "", appconfig.DefaultLocationOfCurrent, mock.Anything)
+	processCancelCommand(ctx, sendCommandPoolMock, &docState, docMock)
+	sendCommandPoolMock.AssertExpectations(t)
+	sendCommandPoolMock.AssertNotCalled(t, "Cancel", mock.Anything)
+	assert.Equal(t, contracts.ResultStatusSuccess, docState.DocumentInformation.DocumentStatus)
+	assert.Equal(t, "Step stuckStep of command commandID cancelled", docState.CancelInformation.DebugInfo)
+}
+
 type DocumentMgrMock struct {
 	mock.Mock
 }
@@ -0,0 +1,167 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher"
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/cloudwatchlogsinterface"
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/auditlog"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// auditLogFileName is the name of the local file command audit records are appended to
+	auditLogFileName = "command_audit.log"
+
+	// auditPublishTimeout is how long the end of a document waits for its audit records to be published to CloudWatch Logs
+	auditPublishTimeout = 10 * time.Second
+)
+
+var auditLogDir = log.DefaultLogDir
+
+// auditPublishers publish the command audit records to CloudWatch Logs in the background, indexed by log group
+var auditPublishers = make(map[string]*auditlog.Publisher)
+var auditPublishersLock sync.Mutex
+
+var auditChainKey = auditlog.ChainKey
+
+var newCloudWatchLogsService = func() cloudwatchlogsinterface.ICloudWatchLogsService {
+	return cloudwatchlogspublisher.NewCloudWatchLogsService()
+}
+
+// commandAuditRecord is the audit record written for every plugin run by a document.
+// Hash is the HMAC-SHA256 of the record encoded without it, keyed with the audit log chain key of the instance.
+// It covers the hash of the previous record, so modifying or removing a record breaks the chain of the records after it.
+type commandAuditRecord struct {
+	Sequence     int64  `json:"sequence"`
+	MessageId    string `json:"messageId"`
+	DocumentName string `json:"documentName"`
+	DocumentHash string `json:"documentHash"`
+	PluginName   string `json:"pluginName"`
+	PluginId     string `json:"pluginId"`
+	Command      string `json:"command"`
+	RunAsUser    string `json:"runAsUser,omitempty"`
+	ExitCode     int    `json:"exitCode"`
+	Status       string `json:"status"`
+	StartTime    string `json:"startTime"`
+	EndTime      string `json:"endTime"`
+	PreviousHash string `json:"previousHash"`
+	// ChainBreak is set on the first record of a new chain started after an invalid last record, e.g. one torn by a crash
+	ChainBreak string `json:"chainBreak,omitempty"`
+	Hash       string `json:"hash,omitempty"`
+}
+
+// writeAuditRecord appends the audit record of the plugin run to the local audit log and,
+// if a log group is configured, queues it to be published to CloudWatch Logs
+func writeAuditRecord(log log.T, ssmConfig appconfig.SsmCfg, config contracts.Configuration, result contracts.PluginResult) {
+	if !ssmConfig.CommandAuditLogEnabled {
+		return
+	}
+
+	// the command is the resolved input of the plugin, i.e. the command lines for the script plugins
+	command, err := jsonutil.Marshal(config.Properties)
+	if err != nil {
+		log.Errorf("Unable to marshal input of plugin %v for the audit record: %v", config.PluginID, err)
+	}
+	record := commandAuditRecord{
+		MessageId:    config.MessageId,
+		DocumentName: config.DocumentName,
+		DocumentHash: config.DocumentHash,
+		PluginName:   config.PluginName,
+		PluginId:     config.PluginID,
+		Command:      command,
+		RunAsUser:    config.RunAsUser,
+		ExitCode:     result.Code,
+		Status:       string(result.Status),
+		StartTime:    result.StartDateTime.UTC().Format(time.RFC3339),
+		EndTime:      result.EndDateTime.UTC().Format(time.RFC3339),
+	}
+
+	content, err := appendAuditRecord(log, record)
+	if err != nil {
+		log.Errorf("Unable to write command audit record: %v", err)
+		return
+	}
+
+	if ssmConfig.CommandAuditLogGroup != "" {
+		auditPublisher(log, ssmConfig.CommandAuditLogGroup).Publish(config.MessageId, content)
+	}
+}
+
+// auditPublisher returns the publisher of the log group, starting it on first use
+func auditPublisher(log log.T, logGroup string) *auditlog.Publisher {
+	auditPublishersLock.Lock()
+	defer auditPublishersLock.Unlock()
+	publisher, ok := auditPublishers[logGroup]
+	if !ok {
+		publisher = auditlog.NewPublisher(log, newCloudWatchLogsService(), logGroup)
+		auditPublishers[logGroup] = publisher
+	}
+	return publisher
+}
+
+// flushAuditRecords waits for the queued command audit records to be published to CloudWatch Logs,
+// so that they are not lost when the document worker exits
+func flushAuditRecords(log log.T) {
+	auditPublishersLock.Lock()
+	defer auditPublishersLock.Unlock()
+	for logGroup, publisher := range auditPublishers {
+		if !publisher.Flush(auditPublishTimeout) {
+			log.Warnf("Command audit records were not published to log group %s within %v", logGroup, auditPublishTimeout)
+		}
+	}
+}
+
+// appendAuditRecord chains the record to the last record of the local audit log and appends it as a single line.
+// If the last record is invalid the record starts a new chain recording the break, so that auditing goes on.
+func appendAuditRecord(log log.T, record commandAuditRecord) (content string, err error) {
+	return auditlog.AppendChained(filepath.Join(auditLogDir, auditLogFileName), func(lastLine string) (string, error) {
+		key, err := auditChainKey()
+		if err != nil {
+			return "", fmt.Errorf("unable to load audit log chain key: %v", err)
+		}
+		if lastLine != "" {
+			var previous commandAuditRecord
+			if err = jsonutil.Unmarshal(lastLine, &previous); err != nil {
+				log.Errorf("Last record of the command audit log is invalid, starting a new chain: %v", err)
+				record.ChainBreak = fmt.Sprintf("last record of the audit log is invalid: %v", err)
+			} else {
+				record.Sequence = previous.Sequence + 1
+				record.PreviousHash = previous.Hash
+			}
+		}
+		if record.Hash, err = hashAuditRecord(key, record); err != nil {
+			return "", err
+		}
+		return jsonutil.Marshal(record)
+	})
+}
+
+// hashAuditRecord returns the hex encoded HMAC-SHA256 of the record encoded without its hash
+func hashAuditRecord(key []byte, record commandAuditRecord) (string, error) {
+	record.Hash = ""
+	content, err := jsonutil.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	return auditlog.Sign(key, content), nil
+}
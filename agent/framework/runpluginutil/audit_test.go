@@ -0,0 +1,194 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/cloudwatchlogsinterface"
+	cloudwatchlogspublisher_mock "github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/mock"
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testAuditChainKey = []byte("audit-chain-key")
+
+// readAuditLog returns the records of the audit log and an error if the hash chain of the records is broken
+func readAuditLog(path string) (records []commandAuditRecord, err error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	previousHash := ""
+	for i, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		var record commandAuditRecord
+		if err = jsonutil.Unmarshal(line, &record); err != nil {
+			return
+		}
+		hash, _ := hashAuditRecord(testAuditChainKey, record)
+		if record.Sequence != int64(i) || record.PreviousHash != previousHash || record.Hash != hash {
+			return records, fmt.Errorf("audit record %v is not chained", i)
+		}
+		previousHash = record.Hash
+		records = append(records, record)
+	}
+	return
+}
+
+func testAuditConfig(pluginID string) contracts.Configuration {
+	return contracts.Configuration{
+		MessageId:    "aws.ssm.command-id.instance-id",
+		DocumentName: "AWS-RunShellScript",
+		DocumentHash: "document-hash",
+		PluginName:   "aws:runShellScript",
+		PluginID:     pluginID,
+		Properties:   map[string]interface{}{"runCommand": []string{"echo " + pluginID}},
+		RunAsUser:    "ssm-user",
+	}
+}
+
+func TestWriteAuditRecordChainsRecords(t *testing.T) {
+	dir, err := ioutil.TempDir("", "commandaudit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	auditLogDir = dir
+	auditChainKey = func() ([]byte, error) {
+		return testAuditChainKey, nil
+	}
+
+	ssmConfig := appconfig.SsmCfg{CommandAuditLogEnabled: true}
+	result := contracts.PluginResult{Code: 1, Status: contracts.ResultStatusFailed, StartDateTime: time.Now(), EndDateTime: time.Now()}
+	for _, pluginID := range []string{"step1", "step2", "step3"} {
+		writeAuditRecord(log.NewMockLog(), ssmConfig, testAuditConfig(pluginID), result)
+	}
+
+	auditLogPath := filepath.Join(dir, auditLogFileName)
+	records, err := readAuditLog(auditLogPath)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(records))
+	assert.Equal(t, "step2", records[1].PluginId)
+	assert.Equal(t, "AWS-RunShellScript", records[1].DocumentName)
+	assert.Equal(t, "document-hash", records[1].DocumentHash)
+	assert.Equal(t, `{"runCommand":["echo step2"]}`, records[1].Command)
+	assert.Equal(t, "ssm-user", records[1].RunAsUser)
+	assert.Equal(t, 1, records[1].ExitCode)
+	assert.Equal(t, string(contracts.ResultStatusFailed), records[1].Status)
+
+	// modifying a record breaks the chain
+	content, _ := ioutil.ReadFile(auditLogPath)
+	ioutil.WriteFile(auditLogPath, []byte(strings.Replace(string(content), "echo step2", "echo other", 1)), appconfig.ReadWriteAccess)
+	_, err = readAuditLog(auditLogPath)
+	assert.Error(t, err)
+
+	// rewriting the whole chain requires the chain key
+	records[1].Command = "echo other"
+	for i := 1; i < len(records); i++ {
+		if i > 1 {
+			records[i].PreviousHash = records[i-1].Hash
+		}
+		records[i].Hash, _ = hashAuditRecord([]byte("other-key"), records[i])
+	}
+	var lines []string
+	for _, record := range records {
+		line, _ := jsonutil.Marshal(record)
+		lines = append(lines, line)
+	}
+	ioutil.WriteFile(auditLogPath, []byte(strings.Join(lines, "\n")+"\n"), appconfig.ReadWriteAccess)
+	_, err = readAuditLog(auditLogPath)
+	assert.Error(t, err)
+}
+
+func TestWriteAuditRecordAfterTruncatedRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "commandaudit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	auditLogDir = dir
+	auditChainKey = func() ([]byte, error) {
+		return testAuditChainKey, nil
+	}
+
+	ssmConfig := appconfig.SsmCfg{CommandAuditLogEnabled: true}
+	writeAuditRecord(log.NewMockLog(), ssmConfig, testAuditConfig("step1"), contracts.PluginResult{})
+	writeAuditRecord(log.NewMockLog(), ssmConfig, testAuditConfig("step2"), contracts.PluginResult{})
+
+	// the worker crashed while writing the last record
+	auditLogPath := filepath.Join(dir, auditLogFileName)
+	content, err := ioutil.ReadFile(auditLogPath)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(auditLogPath, content[:len(content)-20], appconfig.ReadWriteAccess))
+
+	// later records start a new chain recording the break
+	writeAuditRecord(log.NewMockLog(), ssmConfig, testAuditConfig("step3"), contracts.PluginResult{})
+	writeAuditRecord(log.NewMockLog(), ssmConfig, testAuditConfig("step4"), contracts.PluginResult{})
+
+	content, err = ioutil.ReadFile(auditLogPath)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Equal(t, 4, len(lines))
+	segment := strings.Join(lines[2:], "\n")
+	assert.NoError(t, ioutil.WriteFile(auditLogPath, []byte(segment), appconfig.ReadWriteAccess))
+	records, err := readAuditLog(auditLogPath)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, "step3", records[0].PluginId)
+	assert.Contains(t, records[0].ChainBreak, "invalid")
+	assert.Empty(t, records[1].ChainBreak)
+}
+
+func TestWriteAuditRecordPublishesToCloudWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "commandaudit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	auditLogDir = dir
+	auditChainKey = func() ([]byte, error) {
+		return testAuditChainKey, nil
+	}
+
+	mockLog := log.NewMockLog()
+	config := testAuditConfig("step1")
+	cwl := new(cloudwatchlogspublisher_mock.CloudWatchLogsServiceMock)
+	cwl.On("CreateLogGroup", mockLog, "audit-group").Return(nil)
+	cwl.On("CreateLogStream", mockLog, "audit-group", config.MessageId).Return(nil)
+	cwl.On("PutLogEvents", mockLog, mock.Anything, "audit-group", config.MessageId, mock.Anything).Return(nil, nil)
+	newCloudWatchLogsService = func() cloudwatchlogsinterface.ICloudWatchLogsService {
+		return cwl
+	}
+
+	writeAuditRecord(mockLog, appconfig.SsmCfg{CommandAuditLogEnabled: true, CommandAuditLogGroup: "audit-group"}, config, contracts.PluginResult{})
+	flushAuditRecords(mockLog)
+
+	cwl.AssertExpectations(t)
+}
+
+func TestWriteAuditRecordWhenDisabled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "commandaudit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	auditLogDir = dir
+
+	writeAuditRecord(log.NewMockLog(), appconfig.SsmCfg{}, testAuditConfig("step1"), contracts.PluginResult{})
+
+	_, err = os.Stat(filepath.Join(dir, auditLogFileName))
+	assert.True(t, os.IsNotExist(err))
+}
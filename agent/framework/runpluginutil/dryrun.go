@@ -0,0 +1,58 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+)
+
+// dryRunPlugins are the plugins which still run in dry runs, as they only download the content later steps use
+var dryRunPlugins = map[string]struct{}{
+	appconfig.PluginDownloadContent: {},
+}
+
+// isDryRunPlugin returns true if the plugin runs in dry runs
+func isDryRunPlugin(pluginName string) bool {
+	_, found := dryRunPlugins[pluginName]
+	return found
+}
+
+// describeDryRunStep returns the report of a step which would run in the document, with its resolved inputs
+func describeDryRunStep(pluginName string, config contracts.Configuration) string {
+	var description bytes.Buffer
+	fmt.Fprintf(&description, "Dry run: step %s would run plugin %s", config.PluginID, pluginName)
+	if len(config.DependsOn) > 0 {
+		fmt.Fprintf(&description, " after steps %v", config.DependsOn)
+	}
+	if config.Retries > 0 {
+		fmt.Fprintf(&description, " with up to %d retries", config.Retries)
+	}
+	if config.LoopUntil != nil {
+		description.WriteString(" until its loopUntil condition holds")
+	}
+	for _, exitCodeStatus := range config.ExitCodes {
+		fmt.Fprintf(&description, ", exit code %d meaning %s", exitCodeStatus.ExitCode, exitCodeStatus.Status)
+	}
+	inputs, err := jsonutil.MarshalIndent(config.Properties)
+	if err != nil {
+		inputs = fmt.Sprintf("%v", config.Properties)
+	}
+	fmt.Fprintf(&description, "\nInputs:\n%s\n", inputs)
+	return description.String()
+}
@@ -0,0 +1,69 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runpluginutil run plugin utility functions without referencing the actually plugin impl packages
+package runpluginutil
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRunPluginsDryRun(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+
+	ctx := context.NewMockDefault()
+	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
+	pluginRegistry := PluginRegistry{}
+	pluginInstances := make(map[string]*PluginMock)
+
+	steps := []contracts.PluginState{
+		{Name: appconfig.PluginDownloadContent, Id: "download", Configuration: contracts.Configuration{
+			PluginID: "download", IsPreconditionEnabled: true, Properties: map[string]interface{}{"sourceType": "S3"}}},
+		{Name: testPlugin1, Id: "install", Configuration: contracts.Configuration{
+			PluginID: "install", IsPreconditionEnabled: true, Retries: 2, Properties: map[string]interface{}{"runCommand": []string{"install.sh"}}}},
+		{Name: testPlugin2, Id: "windowsOnly", Configuration: contracts.Configuration{
+			PluginID: "windowsOnly", IsPreconditionEnabled: true, Preconditions: map[string][]string{"StringEquals": {"platformType", "Windows"}}}},
+	}
+	for _, step := range steps {
+		pluginInstances[step.Name] = new(PluginMock)
+		pluginFactory := new(PluginFactoryMock)
+		pluginFactory.On("Create", mock.Anything).Return(pluginInstances[step.Name], nil)
+		pluginRegistry[step.Name] = pluginFactory
+	}
+	// only the content is downloaded
+	pluginInstances[appconfig.PluginDownloadContent].On("Execute", mock.Anything, mock.Anything, stepCancelFlag, mock.Anything).Return()
+
+	orchestrationDir, _ := ioutil.TempDir("", "dryrun")
+	defer os.RemoveAll(orchestrationDir)
+	ch := make(chan contracts.PluginResult, len(steps))
+	outputs := RunPlugins(ctx, steps, contracts.IOConfiguration{OrchestrationDirectory: orchestrationDir, DryRun: true}, pluginRegistry, ch, cancelFlag)
+
+	for _, mockPlugin := range pluginInstances {
+		mockPlugin.AssertExpectations(t)
+	}
+	assert.Len(t, ch, len(steps))
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs["install"].Status)
+	assert.Contains(t, outputs["install"].StandardOutput, "Dry run: step install would run plugin "+testPlugin1+" with up to 2 retries")
+	assert.Contains(t, outputs["install"].StandardOutput, `"install.sh"`)
+	assert.Equal(t, contracts.ResultStatusSkipped, outputs["windowsOnly"].Status)
+}
@@ -0,0 +1,337 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runpluginutil run plugin utility functions without referencing the actually plugin impl packages
+package runpluginutil
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+)
+
+// Precondition expressions compare strings and versions with ==, !=, <, <=, > and >=, and combine the comparisons
+// with &&, || and !, e.g. "Env" == "prod" && platformVersion >= 20.04 && tag("Team") != "".
+// The operands are double quoted strings, versions, the variables platformType, platformName and platformVersion,
+// and tag("key"), the value of an instance tag. Document parameters are replaced by string literals before evaluation.
+
+// expressionResolver returns the values of the variables and instance tags of precondition expressions.
+type expressionResolver interface {
+	Variable(name string) (string, error)
+	Tag(key string) (string, error)
+}
+
+// instanceResolver resolves the variables and tags of expressions from the instance.
+type instanceResolver struct {
+	log log.T
+}
+
+// Variable returns the value of the platform variable.
+func (resolver instanceResolver) Variable(name string) (string, error) {
+	switch name {
+	case "platformType":
+		return platform.PlatformType(resolver.log)
+	case "platformName":
+		return platform.PlatformName(resolver.log)
+	case "platformVersion":
+		return platform.PlatformVersion(resolver.log)
+	}
+	return "", fmt.Errorf("unknown variable %s", name)
+}
+
+// Tag returns the value of the instance tag, empty if the tag is not set or not available.
+func (resolver instanceResolver) Tag(key string) (string, error) {
+	value, err := platform.InstanceTag(key)
+	if err != nil {
+		resolver.log.Debugf("instance tag %s is not available: %v", key, err)
+		return "", nil
+	}
+	return value, nil
+}
+
+var versionRegex = regexp.MustCompile(`^[0-9]+(\.[0-9]+)*$`)
+
+// expressionValue is the value of an operand or of a sub expression.
+type expressionValue struct {
+	isBool bool
+	b      bool
+	s      string
+}
+
+// expressionParser evaluates an expression while parsing it by recursive descent.
+type expressionParser struct {
+	tokens   []string
+	position int
+	resolver expressionResolver
+}
+
+// evaluateExpression returns the result of the precondition expression.
+func evaluateExpression(expression string, resolver expressionResolver) (bool, error) {
+	tokens, err := tokenizeExpression(expression)
+	if err != nil {
+		return false, err
+	}
+	parser := &expressionParser{tokens: tokens, resolver: resolver}
+	value, err := parser.parseOr()
+	if err != nil {
+		return false, err
+	}
+	if parser.position < len(parser.tokens) {
+		return false, fmt.Errorf("unexpected %s", parser.tokens[parser.position])
+	}
+	if !value.isBool {
+		return false, fmt.Errorf("expression is not a condition")
+	}
+	return value.b, nil
+}
+
+// tokenizeExpression splits the expression into string literals, versions, names and operators.
+func tokenizeExpression(expression string) (tokens []string, err error) {
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '"':
+			end := i + 1
+			for ; end < len(expression) && expression[end] != '"'; end++ {
+				if expression[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(expression) {
+				return nil, fmt.Errorf("unterminated string %s", expression[i:])
+			}
+			tokens = append(tokens, expression[i:end+1])
+			i = end + 1
+		case c >= '0' && c <= '9', unicode.IsLetter(rune(c)) || c == '_':
+			end := i
+			for ; end < len(expression); end++ {
+				r := rune(expression[end])
+				if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' {
+					break
+				}
+			}
+			tokens = append(tokens, expression[i:end])
+			i = end
+		default:
+			operator := ""
+			for _, candidate := range []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")"} {
+				if strings.HasPrefix(expression[i:], candidate) {
+					operator = candidate
+					break
+				}
+			}
+			if operator == "" {
+				return nil, fmt.Errorf("unexpected character %q", c)
+			}
+			tokens = append(tokens, operator)
+			i += len(operator)
+		}
+	}
+	return tokens, nil
+}
+
+// peek returns the next token, empty at the end of the expression.
+func (parser *expressionParser) peek() string {
+	if parser.position < len(parser.tokens) {
+		return parser.tokens[parser.position]
+	}
+	return ""
+}
+
+// next consumes and returns the next token.
+func (parser *expressionParser) next() (string, error) {
+	token := parser.peek()
+	if token == "" {
+		return "", fmt.Errorf("unexpected end of expression")
+	}
+	parser.position++
+	return token, nil
+}
+
+// parseOr parses conditions combined with ||.
+func (parser *expressionParser) parseOr() (expressionValue, error) {
+	left, err := parser.parseAnd()
+	for err == nil && parser.peek() == "||" {
+		parser.position++
+		var right expressionValue
+		if right, err = parser.parseAnd(); err == nil {
+			left, err = combine(left, right, "||")
+		}
+	}
+	return left, err
+}
+
+// parseAnd parses conditions combined with &&.
+func (parser *expressionParser) parseAnd() (expressionValue, error) {
+	left, err := parser.parseNot()
+	for err == nil && parser.peek() == "&&" {
+		parser.position++
+		var right expressionValue
+		if right, err = parser.parseNot(); err == nil {
+			left, err = combine(left, right, "&&")
+		}
+	}
+	return left, err
+}
+
+// parseNot parses a condition, negated by any number of !.
+func (parser *expressionParser) parseNot() (expressionValue, error) {
+	if parser.peek() != "!" {
+		return parser.parseComparison()
+	}
+	parser.position++
+	value, err := parser.parseNot()
+	if err != nil {
+		return value, err
+	}
+	if !value.isBool {
+		return value, fmt.Errorf("! applies to conditions only")
+	}
+	return expressionValue{isBool: true, b: !value.b}, nil
+}
+
+// parseComparison parses an operand, compared to another one if followed by a comparison operator.
+func (parser *expressionParser) parseComparison() (expressionValue, error) {
+	left, err := parser.parseOperand()
+	if err != nil {
+		return left, err
+	}
+	operator := parser.peek()
+	switch operator {
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return left, nil
+	}
+	parser.position++
+	right, err := parser.parseOperand()
+	if err != nil {
+		return right, err
+	}
+	if left.isBool || right.isBool {
+		return left, fmt.Errorf("%s compares strings and versions only", operator)
+	}
+	return expressionValue{isBool: true, b: compareOperands(left.s, right.s, operator)}, nil
+}
+
+// parseOperand parses a parenthesized expression, a literal, a variable or a tag.
+func (parser *expressionParser) parseOperand() (expressionValue, error) {
+	token, err := parser.next()
+	if err != nil {
+		return expressionValue{}, err
+	}
+	switch {
+	case token == "(":
+		value, err := parser.parseOr()
+		if err != nil {
+			return value, err
+		}
+		if token, err = parser.next(); err != nil || token != ")" {
+			return value, fmt.Errorf("missing )")
+		}
+		return value, nil
+	case strings.HasPrefix(token, `"`):
+		s, err := strconv.Unquote(token)
+		if err != nil {
+			return expressionValue{}, fmt.Errorf("invalid string %s", token)
+		}
+		return expressionValue{s: s}, nil
+	case token == "true" || token == "false":
+		return expressionValue{isBool: true, b: token == "true"}, nil
+	case versionRegex.MatchString(token):
+		return expressionValue{s: token}, nil
+	case token == "tag":
+		var key expressionValue
+		if token, err = parser.next(); err != nil || token != "(" {
+			return key, fmt.Errorf("tag must be followed by (\"key\")")
+		}
+		if key, err = parser.parseOperand(); err != nil || key.isBool {
+			return key, fmt.Errorf("tag key must be a string")
+		}
+		if token, err = parser.next(); err != nil || token != ")" {
+			return key, fmt.Errorf("missing ) after tag key")
+		}
+		s, err := parser.resolver.Tag(key.s)
+		return expressionValue{s: s}, err
+	case unicode.IsLetter(rune(token[0])) || token[0] == '_':
+		s, err := parser.resolver.Variable(token)
+		return expressionValue{s: s}, err
+	}
+	return expressionValue{}, fmt.Errorf("unexpected %s", token)
+}
+
+// combine returns the result of && or || of the conditions.
+func combine(left, right expressionValue, operator string) (expressionValue, error) {
+	if !left.isBool || !right.isBool {
+		return left, fmt.Errorf("%s combines conditions only", operator)
+	}
+	if operator == "&&" {
+		return expressionValue{isBool: true, b: left.b && right.b}, nil
+	}
+	return expressionValue{isBool: true, b: left.b || right.b}, nil
+}
+
+// compareOperands compares versions by their numeric components, and other strings case insensitively
+// for equality and lexically for order.
+func compareOperands(left, right string, operator string) bool {
+	var result int
+	if versionRegex.MatchString(left) && versionRegex.MatchString(right) {
+		result = compareVersions(left, right)
+	} else if strings.EqualFold(left, right) {
+		result = 0
+	} else {
+		result = strings.Compare(left, right)
+	}
+
+	switch operator {
+	case "==":
+		return result == 0
+	case "!=":
+		return result != 0
+	case "<":
+		return result < 0
+	case "<=":
+		return result <= 0
+	case ">":
+		return result > 0
+	}
+	return result >= 0
+}
+
+// compareVersions compares the numeric components of the versions, missing components count as 0.
+func compareVersions(left, right string) int {
+	leftParts := strings.Split(left, ".")
+	rightParts := strings.Split(right, ".")
+	for i := 0; i < len(leftParts) || i < len(rightParts); i++ {
+		var l, r int
+		if i < len(leftParts) {
+			l, _ = strconv.Atoi(leftParts[i])
+		}
+		if i < len(rightParts) {
+			r, _ = strconv.Atoi(rightParts[i])
+		}
+		if l != r {
+			if l < r {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runpluginutil run plugin utility functions without referencing the actually plugin impl packages
+package runpluginutil
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// resolverStub resolves variables and tags from maps.
+type resolverStub struct {
+	variables map[string]string
+	tags      map[string]string
+}
+
+func (resolver resolverStub) Variable(name string) (string, error) {
+	if value, found := resolver.variables[name]; found {
+		return value, nil
+	}
+	return "", fmt.Errorf("unknown variable %s", name)
+}
+
+func (resolver resolverStub) Tag(key string) (string, error) {
+	return resolver.tags[key], nil
+}
+
+var testResolver = resolverStub{
+	variables: map[string]string{
+		"platformType":    "linux",
+		"platformName":    "Ubuntu",
+		"platformVersion": "20.04",
+	},
+	tags: map[string]string{
+		"Env": "prod",
+	},
+}
+
+func TestEvaluateExpression(t *testing.T) {
+	testCases := []struct {
+		expression string
+		expected   bool
+	}{
+		{`"prod" == "prod" && platformVersion >= 20.04`, true},
+		{`"dev" == "prod" && platformVersion >= 20.04`, false},
+		{`platformType == "Linux"`, true},
+		{`platformVersion > 18.10 && platformVersion < 20.10`, true},
+		{`platformVersion >= 20.4.1`, false},
+		{`platformVersion != 20.04.0`, false},
+		{`tag("Env") == "prod" || tag("Team") == "ops"`, true},
+		{`tag("Team") != ""`, false},
+		{`!(platformName == "Ubuntu" && tag("Env") == "dev")`, true},
+		{`platformType == "windows" || platformType == "linux" && platformVersion >= 22.04`, false},
+		{`false || !false`, true},
+	}
+
+	for _, testCase := range testCases {
+		result, err := evaluateExpression(testCase.expression, testResolver)
+		assert.NoError(t, err, testCase.expression)
+		assert.Equal(t, testCase.expected, result, testCase.expression)
+	}
+}
+
+func TestEvaluateExpressionInvalid(t *testing.T) {
+	invalidExpressions := []string{
+		``,
+		`platformVersion`,
+		`"prod" ==`,
+		`"prod" == "prod" &&`,
+		`("prod" == "prod"`,
+		`"prod == "prod"`,
+		`unknownVariable == "prod"`,
+		`tag(Env) == "prod"`,
+		`platformType = "linux"`,
+		`"a" == "a" == "a"`,
+		`!"prod"`,
+	}
+
+	for _, expression := range invalidExpressions {
+		_, err := evaluateExpression(expression, testResolver)
+		assert.Error(t, err, expression)
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runpluginutil run plugin utility functions without referencing the actually plugin impl packages
+package runpluginutil
+
+import (
+	"fmt"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+// Assign method to global variables to allow unittest to override
+var runParallelStep = runStep
+
+// stepCompletion is sent by a step running in parallel once its result is sent.
+type stepCompletion struct {
+	pluginID string
+	result   contracts.PluginResult
+	reboot   bool
+}
+
+// hasStepDependencies returns true if any step of the document declares dependsOn.
+func hasStepDependencies(plugins []contracts.PluginState) bool {
+	for _, pluginState := range plugins {
+		if len(pluginState.Configuration.DependsOn) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// runPluginsInParallel executes the steps of a document declaring dependsOn. A step starts once all the steps it
+// depends on completed, with at most Ssm.ParallelStepsLimit steps running at a time, and is skipped if one of them
+// did not succeed. Steps without dependsOn start right away. No step is started after a step requests a reboot.
+func runPluginsInParallel(
+	context context.T,
+	plugins []contracts.PluginState,
+	ioConfig contracts.IOConfiguration,
+	registry PluginRegistry,
+	resChan chan contracts.PluginResult,
+	cancelFlag task.CancelFlag,
+) (pluginOutputs map[string]*contracts.PluginResult) {
+
+	log := context.Log()
+	pluginOutputs = make(map[string]*contracts.PluginResult)
+
+	// statuses of the completed steps, only read and written by this goroutine
+	completedStatus := make(map[string]contracts.ResultStatus)
+	var pending []contracts.PluginState
+	for _, pluginState := range plugins {
+		if initializePluginOutput(context, pluginState, pluginOutputs) {
+			pending = append(pending, pluginState)
+		} else {
+			completedStatus[pluginState.Id] = pluginOutputs[pluginState.Id].Status
+		}
+	}
+
+	limit := context.AppConfig().Ssm.ParallelStepsLimit
+	if limit <= 0 {
+		limit = appconfig.DefaultSsmParallelStepsLimit
+	}
+	log.Infof("Running document steps in dependency order, up to %d steps at a time", limit)
+
+	completions := make(chan stepCompletion, len(pending))
+	running := 0
+	rebooting := false
+	for {
+		// skipping a step can decide the steps depending on it, so repeat until nothing changes
+		for skipped := true; skipped && !rebooting; {
+			skipped = false
+			var waiting []contracts.PluginState
+			for _, pluginState := range pending {
+				ready, failedDependency := checkStepDependencies(pluginState, pluginOutputs, completedStatus)
+				switch {
+				case failedDependency != "":
+					skipStepForDependency(context, pluginState, failedDependency, pluginOutputs[pluginState.Id], resChan)
+					completedStatus[pluginState.Id] = contracts.ResultStatusSkipped
+					skipped = true
+				case ready && running < limit:
+					running++
+					go func(pluginState contracts.PluginState, pluginOutput *contracts.PluginResult) {
+						r, pluginHandlerFound := runParallelStep(context, pluginState, ioConfig, registry, resChan, cancelFlag, pluginOutput)
+						completions <- stepCompletion{
+							pluginID: pluginState.Id,
+							result:   *pluginOutput,
+							reboot:   pluginHandlerFound && r.Status == contracts.ResultStatusSuccessAndReboot,
+						}
+					}(pluginState, pluginOutputs[pluginState.Id])
+				default:
+					waiting = append(waiting, pluginState)
+				}
+			}
+			pending = waiting
+		}
+
+		if running == 0 {
+			break
+		}
+		completion := <-completions
+		running--
+		completedStatus[completion.pluginID] = completion.result.Status
+		if completion.reboot {
+			log.Infof("Step %v requested a reboot, not starting further steps", completion.pluginID)
+			rebooting = true
+		}
+	}
+
+	// without a reboot, steps left pending wait on each other and can never start
+	if !rebooting {
+		for _, pluginState := range pending {
+			err := fmt.Errorf("Step %v was not executed because its dependencies could not be resolved", pluginState.Id)
+			pluginOutputs[pluginState.Id].Status = contracts.ResultStatusFailed
+			pluginOutputs[pluginState.Id].Error = err.Error()
+			log.Error(err)
+			sendPluginResult(context, pluginOutputs[pluginState.Id], resChan)
+		}
+	}
+	return
+}
+
+// checkStepDependencies returns whether all the dependencies of the step completed successfully,
+// or the name of the first dependency that completed without success or is not a step of the document.
+func checkStepDependencies(
+	pluginState contracts.PluginState,
+	pluginOutputs map[string]*contracts.PluginResult,
+	completedStatus map[string]contracts.ResultStatus) (ready bool, failedDependency string) {
+
+	ready = true
+	for _, dependency := range pluginState.Configuration.DependsOn {
+		if _, found := pluginOutputs[dependency]; !found {
+			return false, dependency
+		}
+		status, completed := completedStatus[dependency]
+		if !completed {
+			ready = false
+			continue
+		}
+		if status != contracts.ResultStatusSuccess {
+			return false, dependency
+		}
+	}
+	return ready, ""
+}
+
+// skipStepForDependency marks the step as skipped because the dependency did not succeed and sends its result.
+func skipStepForDependency(
+	context context.T,
+	pluginState contracts.PluginState,
+	dependency string,
+	pluginOutput *contracts.PluginResult,
+	resChan chan contracts.PluginResult) {
+
+	logMessage := fmt.Sprintf("Step execution skipped because step %v it depends on did not succeed", dependency)
+	context.Log().Infof("%v: %v", pluginState.Id, logMessage)
+	pluginOutput.Status = contracts.ResultStatusSkipped
+	pluginOutput.Code = 0
+	pluginOutput.Output = logMessage
+	sendPluginResult(context, pluginOutput, resChan)
+}
@@ -0,0 +1,155 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runpluginutil run plugin utility functions without referencing the actually plugin impl packages
+package runpluginutil
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+// parallelStep describes a step of a test document run by runParallelSteps.
+type parallelStep struct {
+	name      string
+	dependsOn []string
+	status    contracts.ResultStatus
+}
+
+// runParallelSteps runs the steps with RunPlugins, each step completing with its status after the delay,
+// and returns the outputs, the order the steps started in and the most steps running at the same time.
+func runParallelSteps(t *testing.T, steps []parallelStep, delay time.Duration) (outputs map[string]*contracts.PluginResult, started []string, maxRunning int) {
+	ctx := context.NewMockDefault()
+	var cancelFlag task.CancelFlag = task.NewChanneledCancelFlag()
+	pluginStates := make([]contracts.PluginState, len(steps))
+	statuses := make(map[string]contracts.ResultStatus)
+	for index, step := range steps {
+		statuses[step.name] = step.status
+		pluginStates[index] = contracts.PluginState{
+			Name: step.name,
+			Id:   step.name,
+			Configuration: contracts.Configuration{
+				PluginID:   step.name,
+				PluginName: step.name,
+				DependsOn:  step.dependsOn,
+			},
+		}
+	}
+
+	var mutex sync.Mutex
+	running := 0
+	runParallelStep = func(
+		context context.T,
+		pluginState contracts.PluginState,
+		ioConfig contracts.IOConfiguration,
+		registry PluginRegistry,
+		resChan chan contracts.PluginResult,
+		cancelFlag task.CancelFlag,
+		pluginOutput *contracts.PluginResult) (contracts.PluginResult, bool) {
+
+		mutex.Lock()
+		started = append(started, pluginState.Id)
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mutex.Unlock()
+
+		time.Sleep(delay)
+		pluginOutput.Status = statuses[pluginState.Id]
+		sendPluginResult(context, pluginOutput, resChan)
+
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+		return *pluginOutput, true
+	}
+	defer func() { runParallelStep = runStep }()
+
+	ch := make(chan contracts.PluginResult, len(steps))
+	outputs = RunPlugins(ctx, pluginStates, contracts.IOConfiguration{}, PluginRegistry{}, ch, cancelFlag)
+	close(ch)
+	return
+}
+
+func TestRunPluginsWithDependsOn(t *testing.T) {
+	outputs, started, _ := runParallelSteps(t, []parallelStep{
+		{name: "install", status: contracts.ResultStatusSuccess},
+		{name: "configure", dependsOn: []string{"install"}, status: contracts.ResultStatusSuccess},
+		{name: "download", status: contracts.ResultStatusSuccess},
+		{name: "start", dependsOn: []string{"configure", "download"}, status: contracts.ResultStatusSuccess},
+	}, 0)
+
+	assert.Len(t, started, 4)
+	position := make(map[string]int)
+	for index, name := range started {
+		position[name] = index
+	}
+	assert.True(t, position["install"] < position["configure"])
+	assert.True(t, position["configure"] < position["start"])
+	assert.True(t, position["download"] < position["start"])
+	for _, output := range outputs {
+		assert.Equal(t, contracts.ResultStatusSuccess, output.Status)
+	}
+}
+
+func TestRunPluginsWithDependsOnSkipsAfterFailure(t *testing.T) {
+	outputs, started, _ := runParallelSteps(t, []parallelStep{
+		{name: "install", status: contracts.ResultStatusFailed},
+		{name: "configure", dependsOn: []string{"install"}, status: contracts.ResultStatusSuccess},
+		{name: "start", dependsOn: []string{"configure"}, status: contracts.ResultStatusSuccess},
+		{name: "report", status: contracts.ResultStatusSuccess},
+	}, 0)
+
+	assert.Len(t, started, 2)
+	assert.Contains(t, started, "install")
+	assert.Contains(t, started, "report")
+	assert.Equal(t, contracts.ResultStatusFailed, outputs["install"].Status)
+	assert.Equal(t, contracts.ResultStatusSkipped, outputs["configure"].Status)
+	assert.Contains(t, outputs["configure"].Output, "install")
+	assert.Equal(t, contracts.ResultStatusSkipped, outputs["start"].Status)
+	assert.Contains(t, outputs["start"].Output, "configure")
+	assert.Equal(t, contracts.ResultStatusSuccess, outputs["report"].Status)
+}
+
+func TestRunPluginsWithDependsOnStopsAfterReboot(t *testing.T) {
+	outputs, started, _ := runParallelSteps(t, []parallelStep{
+		{name: "update", status: contracts.ResultStatusSuccessAndReboot},
+		{name: "verify", dependsOn: []string{"update"}, status: contracts.ResultStatusSuccess},
+	}, 0)
+
+	assert.Equal(t, []string{"update"}, started)
+	assert.Equal(t, contracts.ResultStatusSuccessAndReboot, outputs["update"].Status)
+	assert.Equal(t, contracts.ResultStatusNotStarted, outputs["verify"].Status)
+}
+
+func TestRunPluginsWithDependsOnRespectsLimit(t *testing.T) {
+	var steps []parallelStep
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		steps = append(steps, parallelStep{name: name, status: contracts.ResultStatusSuccess})
+	}
+	steps = append(steps, parallelStep{name: "h", dependsOn: []string{"a"}, status: contracts.ResultStatusSuccess})
+
+	outputs, started, maxRunning := runParallelSteps(t, steps, 20*time.Millisecond)
+
+	assert.Len(t, outputs, len(steps))
+	assert.Len(t, started, len(steps))
+	assert.True(t, maxRunning > 1)
+	assert.True(t, maxRunning <= 4)
+}
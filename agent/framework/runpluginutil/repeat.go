@@ -0,0 +1,156 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runpluginutil run plugin utility functions without referencing the actually plugin impl packages
+package runpluginutil
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	// defaultStepLoopIterations is the number of iterations of a loopUntil that does not set maxIterations
+	defaultStepLoopIterations = 10
+
+	// cancelCheckInterval is how often the cancel flag is checked while waiting between attempts
+	cancelCheckInterval = time.Second
+)
+
+// repeatPlugin runs the step with run, retrying failed attempts up to config.Retries times and, when
+// config.LoopUntil is set, running it again until the loop condition holds or the iterations are exhausted.
+func repeatPlugin(
+	log log.T,
+	config contracts.Configuration,
+	cancelFlag task.CancelFlag,
+	run func() contracts.PluginResult) (res contracts.PluginResult) {
+
+	loop := config.LoopUntil
+	maxIterations := 1
+	if loop != nil {
+		maxIterations = defaultStepLoopIterations
+		if loop.MaxIterations > 0 {
+			maxIterations = loop.MaxIterations
+		}
+	}
+
+	for iteration := 1; ; iteration++ {
+		res = retryPlugin(log, config, cancelFlag, run)
+		if loop == nil || !isRepeatable(res.Status) || isLoopConditionMet(loop, res) {
+			return
+		}
+		if iteration >= maxIterations {
+			message := fmt.Sprintf("loopUntil condition of step %s was not met after %d iterations", config.PluginID, iteration)
+			log.Info(message)
+			res.Status = contracts.ResultStatusFailed
+			if res.Code == 0 {
+				res.Code = 1
+			}
+			if output, ok := res.Output.(string); ok && output != "" {
+				message = output + "\n" + message
+			}
+			res.Output = message
+			return
+		}
+
+		log.Infof("loopUntil condition of step %s not met in iteration %d, running again in %d seconds", config.PluginID, iteration, loop.Interval)
+		if !waitUnlessCanceled(cancelFlag, time.Duration(loop.Interval)*time.Second) {
+			return
+		}
+	}
+}
+
+// retryPlugin runs the step with run, retrying failed attempts up to config.Retries times.
+// The exitCodes of the step decide the status of attempts exiting with the exit codes they map.
+func retryPlugin(
+	log log.T,
+	config contracts.Configuration,
+	cancelFlag task.CancelFlag,
+	run func() contracts.PluginResult) (res contracts.PluginResult) {
+
+	for attempt := 1; ; attempt++ {
+		res = run()
+		retryable := isRetryable(res.Status)
+		if exitCodeStatus := findExitCodeStatus(config.ExitCodes, res); exitCodeStatus != nil {
+			log.Infof("Step %s exited with exit code %d, which its exitCodes map to status %s", config.PluginID, res.Code, exitCodeStatus.Status)
+			res.Status = contracts.ResultStatus(exitCodeStatus.Status)
+			retryable = exitCodeStatus.Retryable
+		}
+		if attempt > config.Retries || !retryable {
+			return
+		}
+
+		log.Infof("Step %s failed in attempt %d of %d, retrying in %d seconds", config.PluginID, attempt, config.Retries+1, config.RetryIntervalSeconds)
+		if !waitUnlessCanceled(cancelFlag, time.Duration(config.RetryIntervalSeconds)*time.Second) {
+			return
+		}
+	}
+}
+
+// isRetryable returns true if an attempt with the status is retried.
+func isRetryable(status contracts.ResultStatus) bool {
+	return status == contracts.ResultStatusFailed || status == contracts.ResultStatusTimedOut
+}
+
+// findExitCodeStatus returns the status the exitCodes map the exit code of the result to, or nil if they do not map it.
+// Only results of plugins which ran to completion are mapped, not the ones canceled, timed out or rebooting.
+func findExitCodeStatus(exitCodes []contracts.StepExitCodeStatus, res contracts.PluginResult) *contracts.StepExitCodeStatus {
+	if res.Status != contracts.ResultStatusSuccess && res.Status != contracts.ResultStatusFailed {
+		return nil
+	}
+	for i := range exitCodes {
+		if exitCodes[i].ExitCode == res.Code {
+			return &exitCodes[i]
+		}
+	}
+	return nil
+}
+
+// isRepeatable returns true if the step can run again after an iteration with the status.
+func isRepeatable(status contracts.ResultStatus) bool {
+	return status != contracts.ResultStatusCancelled && status != contracts.ResultStatusSuccessAndReboot
+}
+
+// isLoopConditionMet returns true if the result satisfies all the conditions set in the loopUntil.
+func isLoopConditionMet(loop *contracts.StepLoopCondition, res contracts.PluginResult) bool {
+	if loop.ExitCode != nil && res.Code != *loop.ExitCode {
+		return false
+	}
+	if loop.OutputContains != "" && !strings.Contains(res.StandardOutput, loop.OutputContains) {
+		return false
+	}
+	return true
+}
+
+// waitUnlessCanceled waits for the duration and returns false if the command is canceled or the agent shuts down meanwhile.
+func waitUnlessCanceled(cancelFlag task.CancelFlag, duration time.Duration) bool {
+	deadline := time.Now().Add(duration)
+	for {
+		if cancelFlag.Canceled() || cancelFlag.ShutDown() {
+			return false
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return true
+		}
+		if remaining > cancelCheckInterval {
+			remaining = cancelCheckInterval
+		}
+		time.Sleep(remaining)
+	}
+}
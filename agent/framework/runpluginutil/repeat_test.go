@@ -0,0 +1,152 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runpluginutil run plugin utility functions without referencing the actually plugin impl packages
+package runpluginutil
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+)
+
+// attemptResults returns a run function returning the results in order, repeating the last one, and the number of calls.
+func attemptResults(results ...contracts.PluginResult) (run func() contracts.PluginResult, calls *int) {
+	calls = new(int)
+	run = func() contracts.PluginResult {
+		index := *calls
+		if index >= len(results) {
+			index = len(results) - 1
+		}
+		*calls++
+		return results[index]
+	}
+	return run, calls
+}
+
+func TestRepeatPluginRetriesFailedAttempts(t *testing.T) {
+	config := contracts.Configuration{PluginID: "install", Retries: 2}
+	run, calls := attemptResults(
+		contracts.PluginResult{Status: contracts.ResultStatusFailed, Code: 100},
+		contracts.PluginResult{Status: contracts.ResultStatusSuccess})
+
+	res := repeatPlugin(log.NewMockLog(), config, task.NewChanneledCancelFlag(), run)
+
+	assert.Equal(t, 2, *calls)
+	assert.Equal(t, contracts.ResultStatusSuccess, res.Status)
+}
+
+func TestRepeatPluginStopsAfterRetries(t *testing.T) {
+	config := contracts.Configuration{PluginID: "install", Retries: 2}
+	run, calls := attemptResults(contracts.PluginResult{Status: contracts.ResultStatusFailed, Code: 100})
+
+	res := repeatPlugin(log.NewMockLog(), config, task.NewChanneledCancelFlag(), run)
+
+	assert.Equal(t, 3, *calls)
+	assert.Equal(t, contracts.ResultStatusFailed, res.Status)
+	assert.Equal(t, 100, res.Code)
+}
+
+func TestRepeatPluginDoesNotRetryWhenCanceled(t *testing.T) {
+	config := contracts.Configuration{PluginID: "install", Retries: 2, RetryIntervalSeconds: 60}
+	run, calls := attemptResults(contracts.PluginResult{Status: contracts.ResultStatusFailed})
+	cancelFlag := task.NewChanneledCancelFlag()
+	cancelFlag.Set(task.Canceled)
+
+	repeatPlugin(log.NewMockLog(), config, cancelFlag, run)
+
+	assert.Equal(t, 1, *calls)
+}
+
+func TestRepeatPluginLoopsUntilExitCode(t *testing.T) {
+	exitCode := 0
+	config := contracts.Configuration{
+		PluginID:  "waitForService",
+		LoopUntil: &contracts.StepLoopCondition{ExitCode: &exitCode, OutputContains: "active"},
+	}
+	run, calls := attemptResults(
+		contracts.PluginResult{Status: contracts.ResultStatusFailed, Code: 3, StandardOutput: "inactive"},
+		contracts.PluginResult{Status: contracts.ResultStatusSuccess, Code: 0, StandardOutput: "activating"},
+		contracts.PluginResult{Status: contracts.ResultStatusSuccess, Code: 0, StandardOutput: "active"})
+
+	res := repeatPlugin(log.NewMockLog(), config, task.NewChanneledCancelFlag(), run)
+
+	assert.Equal(t, 3, *calls)
+	assert.Equal(t, contracts.ResultStatusSuccess, res.Status)
+}
+
+func TestRepeatPluginFailsWhenLoopConditionIsNotMet(t *testing.T) {
+	config := contracts.Configuration{
+		PluginID:  "waitForService",
+		LoopUntil: &contracts.StepLoopCondition{OutputContains: "active", MaxIterations: 4},
+	}
+	run, calls := attemptResults(contracts.PluginResult{Status: contracts.ResultStatusSuccess, Output: "stopped"})
+
+	res := repeatPlugin(log.NewMockLog(), config, task.NewChanneledCancelFlag(), run)
+
+	assert.Equal(t, 4, *calls)
+	assert.Equal(t, contracts.ResultStatusFailed, res.Status)
+	assert.Equal(t, 1, res.Code)
+	assert.Contains(t, res.Output, "stopped")
+	assert.Contains(t, res.Output, "not met after 4 iterations")
+}
+
+func TestRepeatPluginMapsExitCodeToSuccess(t *testing.T) {
+	config := contracts.Configuration{
+		PluginID:  "copyFiles",
+		Retries:   2,
+		ExitCodes: []contracts.StepExitCodeStatus{{ExitCode: 1, Status: "Success"}},
+	}
+	run, calls := attemptResults(contracts.PluginResult{Status: contracts.ResultStatusFailed, Code: 1})
+
+	res := repeatPlugin(log.NewMockLog(), config, task.NewChanneledCancelFlag(), run)
+
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, contracts.ResultStatusSuccess, res.Status)
+	assert.Equal(t, 1, res.Code)
+}
+
+func TestRepeatPluginRetriesOnlyRetryableExitCodes(t *testing.T) {
+	config := contracts.Configuration{
+		PluginID: "copyFiles",
+		Retries:  2,
+		ExitCodes: []contracts.StepExitCodeStatus{
+			{ExitCode: 3, Status: "Failed", Retryable: true},
+			{ExitCode: 4, Status: "Failed"},
+		},
+	}
+	run, calls := attemptResults(
+		contracts.PluginResult{Status: contracts.ResultStatusFailed, Code: 3},
+		contracts.PluginResult{Status: contracts.ResultStatusFailed, Code: 4})
+
+	res := repeatPlugin(log.NewMockLog(), config, task.NewChanneledCancelFlag(), run)
+
+	assert.Equal(t, 2, *calls)
+	assert.Equal(t, contracts.ResultStatusFailed, res.Status)
+	assert.Equal(t, 4, res.Code)
+}
+
+func TestRepeatPluginDoesNotMapTimedOutAttempts(t *testing.T) {
+	config := contracts.Configuration{
+		PluginID:  "copyFiles",
+		ExitCodes: []contracts.StepExitCodeStatus{{ExitCode: 1, Status: "Success"}},
+	}
+	run, _ := attemptResults(contracts.PluginResult{Status: contracts.ResultStatusTimedOut, Code: 1})
+
+	res := repeatPlugin(log.NewMockLog(), config, task.NewChanneledCancelFlag(), run)
+
+	assert.Equal(t, contracts.ResultStatusTimedOut, res.Status)
+}
@@ -0,0 +1,52 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// runAsPlugins are the plugins which run the scripts of documents as RunCommandDefaultRunAsUser,
+// or only run the steps of other documents whose plugins are checked in turn
+var runAsPlugins = map[string]struct{}{
+	appconfig.PluginNameAwsRunShellScript: {},
+	appconfig.PluginRunDocument:           {},
+}
+
+// checkElevatedPlugin returns an error if a default runAs user is configured and the plugin cannot run as that user,
+// so that documents cannot use plugins running as the agent user to bypass it. Plugins listed in
+// RunCommandElevatedAllowedPlugins may still run as the agent user.
+func checkElevatedPlugin(ssmConfig appconfig.SsmCfg, pluginName string) error {
+	runAsUser := strings.TrimSpace(ssmConfig.RunCommandDefaultRunAsUser)
+	if runAsUser == "" {
+		return nil
+	}
+	if _, supportsRunAs := runAsPlugins[pluginName]; supportsRunAs {
+		return nil
+	}
+	// session plugins follow the runAs preferences of sessions instead
+	if _, isSessionPlugin := allSessionPlugins[pluginName]; isSessionPlugin {
+		return nil
+	}
+	for _, allowed := range ssmConfig.RunCommandElevatedAllowedPlugins {
+		if strings.TrimSpace(allowed) == pluginName {
+			return nil
+		}
+	}
+	return fmt.Errorf("Plugin %s cannot run as the default runAs user %s and is not permitted to run as the agent user by RunCommandElevatedAllowedPlugins",
+		pluginName, runAsUser)
+}
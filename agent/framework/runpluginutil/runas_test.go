@@ -0,0 +1,81 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runpluginutil
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCheckElevatedPlugin(t *testing.T) {
+	defaultRunAs := appconfig.SsmCfg{RunCommandDefaultRunAsUser: "ssm-user"}
+	allowed := appconfig.SsmCfg{RunCommandDefaultRunAsUser: "ssm-user", RunCommandElevatedAllowedPlugins: []string{appconfig.PluginNameAwsSoftwareInventory}}
+
+	testCases := []struct {
+		name       string
+		ssmConfig  appconfig.SsmCfg
+		pluginName string
+		permitted  bool
+	}{
+		{"no default runAs user", appconfig.SsmCfg{}, appconfig.PluginNameAwsRunPowerShellScript, true},
+		{"runAs plugin", defaultRunAs, appconfig.PluginNameAwsRunShellScript, true},
+		{"nested documents", defaultRunAs, appconfig.PluginRunDocument, true},
+		{"session plugin", defaultRunAs, appconfig.PluginNameStandardStream, true},
+		{"powershell on linux", defaultRunAs, appconfig.PluginNameAwsRunPowerShellScript, false},
+		{"docker", defaultRunAs, appconfig.PluginNameDockerContainer, false},
+		{"ansible", defaultRunAs, appconfig.PluginNameAwsRunAnsiblePlaybook, false},
+		{"compliance scan", defaultRunAs, appconfig.PluginNameAwsRunComplianceScan, false},
+		{"allowed elevated plugin", allowed, appconfig.PluginNameAwsSoftwareInventory, true},
+		{"other plugin with allowlist", allowed, appconfig.PluginNameAwsRunPowerShellScript, false},
+	}
+	for _, tc := range testCases {
+		err := checkElevatedPlugin(tc.ssmConfig, tc.pluginName)
+		assert.Equal(t, tc.permitted, err == nil, tc.name)
+	}
+}
+
+func TestRunPluginsRefusesElevatedPlugin(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+
+	config := appconfig.SsmagentConfig{}
+	config.Ssm.RunCommandDefaultRunAsUser = "ssm-user"
+	ctx := new(context.Mock)
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+
+	plugin := new(PluginMock)
+	pluginFactory := new(PluginFactoryMock)
+	pluginFactory.On("Create", mock.Anything).Return(plugin, nil)
+	pluginRegistry := PluginRegistry{appconfig.PluginNameAwsRunPowerShellScript: pluginFactory}
+	steps := []contracts.PluginState{{
+		Name:          appconfig.PluginNameAwsRunPowerShellScript,
+		Id:            "script",
+		Configuration: contracts.Configuration{PluginID: "script"},
+	}}
+
+	ch := make(chan contracts.PluginResult, len(steps))
+	outputs := RunPlugins(ctx, steps, contracts.IOConfiguration{}, pluginRegistry, ch, task.NewChanneledCancelFlag())
+
+	plugin.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, contracts.ResultStatusFailed, outputs["script"].Status)
+	assert.Contains(t, outputs["script"].Error, "RunCommandElevatedAllowedPlugins")
+}
@@ -15,6 +15,7 @@
 package runpluginutil
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -35,6 +36,7 @@ const (
 	executeStep string = "execute"
 	skipStep    string = "skip"
 	failStep    string = "fail"
+	dryRunStep  string = "dryRun"
 )
 
 // TODO: rename to RCPlugin, this represents RCPlugin interface.
@@ -60,9 +62,14 @@ var allPlugins = map[string]struct{}{
 	appconfig.PluginNameAwsConfigureDaemon:     {},
 	appconfig.PluginNameAwsConfigurePackage:    {},
 	appconfig.PluginNameAwsPowerShellModule:    {},
+	appconfig.PluginNameAwsRunAnsiblePlaybook:  {},
+	appconfig.PluginNameAwsRunComplianceScan:   {},
 	appconfig.PluginNameAwsRunPowerShellScript: {},
+	appconfig.PluginNameAwsRunPythonScript:     {},
 	appconfig.PluginNameAwsRunShellScript:      {},
 	appconfig.PluginNameAwsSoftwareInventory:   {},
+	appconfig.PluginNameAwsSystemdService:      {},
+	appconfig.PluginNameAwsWindowsService:      {},
 	appconfig.PluginNameCloudWatch:             {},
 	appconfig.PluginNameConfigureDocker:        {},
 	appconfig.PluginNameDockerContainer:        {},
@@ -70,6 +77,7 @@ var allPlugins = map[string]struct{}{
 	appconfig.PluginEC2ConfigUpdate:            {},
 	appconfig.PluginNameRefreshAssociation:     {},
 	appconfig.PluginDownloadContent:            {},
+	appconfig.PluginUploadContent:              {},
 	appconfig.PluginRunDocument:                {},
 }
 
@@ -78,6 +86,12 @@ var allSessionPlugins = map[string]struct{}{
 	appconfig.PluginNameStandardStream:      {},
 	appconfig.PluginNameInteractiveCommands: {},
 	appconfig.PluginNamePort:                {},
+	appconfig.PluginNameFileTransfer:        {},
+}
+
+// RegisterSessionPlugin adds an external session plugin to the known session plugins.
+func RegisterSessionPlugin(pluginName string) {
+	allSessionPlugins[pluginName] = struct{}{}
 }
 
 // Assign method to global variables to allow unittest to override
@@ -96,133 +110,198 @@ func RunPlugins(
 	cancelFlag task.CancelFlag,
 ) (pluginOutputs map[string]*contracts.PluginResult) {
 
-	pluginOutputs = make(map[string]*contracts.PluginResult)
+	defer flushAuditRecords(context.Log())
+
+	if hasStepDependencies(plugins) {
+		return runPluginsInParallel(context, plugins, ioConfig, registry, resChan, cancelFlag)
+	}
 
-	//Contains the logStreamPrefix without the pluginID
-	logStreamPrefix := ioConfig.CloudWatchConfig.LogStreamPrefix
+	pluginOutputs = make(map[string]*contracts.PluginResult)
 
 	for _, pluginState := range plugins {
-		pluginID := pluginState.Id     // the identifier of the plugin
-		pluginName := pluginState.Name // the name of the plugin
-		pluginOutput := pluginState.Result
-		pluginOutput.PluginID = pluginID
-		pluginOutput.PluginName = pluginName
-		pluginOutputs[pluginID] = &pluginOutput
-		switch pluginOutput.Status {
-		//TODO properly initialize the plugin status
-		case "":
-			context.Log().Debugf("plugin - %v has empty state, initialize as NotStarted",
-				pluginName)
-			pluginOutput.StartDateTime = time.Now()
-			pluginOutput.Status = contracts.ResultStatusNotStarted
-
-		case contracts.ResultStatusNotStarted, contracts.ResultStatusInProgress:
-			context.Log().Debugf("plugin - %v status %v",
-				pluginName,
-				pluginOutput.Status)
-			pluginOutput.StartDateTime = time.Now()
+		if !initializePluginOutput(context, pluginState, pluginOutputs) {
+			continue
+		}
 
-		case contracts.ResultStatusSuccessAndReboot:
-			context.Log().Debugf("plugin - %v just experienced reboot, reset to InProgress...",
-				pluginName)
-			pluginOutput.Status = contracts.ResultStatusInProgress
+		r, pluginHandlerFound := runStep(context, pluginState, ioConfig, registry, resChan, cancelFlag, pluginOutputs[pluginState.Id])
 
-		default:
-			context.Log().Debugf("plugin - %v already executed, skipping...",
-				pluginName)
-			continue
+		//TODO handle cancelFlag here
+		if pluginHandlerFound && r.Status == contracts.ResultStatusSuccessAndReboot {
+			// do not execute the the next plugin
+			break
 		}
+	}
 
-		context.Log().Debugf("Executing plugin - %v", pluginName)
+	return
+}
 
-		// populate plugin start time and status
-		configuration := pluginState.Configuration
+// initializePluginOutput adds the output of the plugin to pluginOutputs and returns false if the plugin already executed.
+func initializePluginOutput(
+	context context.T,
+	pluginState contracts.PluginState,
+	pluginOutputs map[string]*contracts.PluginResult) bool {
+
+	pluginID := pluginState.Id     // the identifier of the plugin
+	pluginName := pluginState.Name // the name of the plugin
+	pluginOutput := pluginState.Result
+	pluginOutput.PluginID = pluginID
+	pluginOutput.PluginName = pluginName
+	pluginOutputs[pluginID] = &pluginOutput
+	switch pluginOutput.Status {
+	//TODO properly initialize the plugin status
+	case "":
+		context.Log().Debugf("plugin - %v has empty state, initialize as NotStarted",
+			pluginName)
+		pluginOutput.StartDateTime = time.Now()
+		pluginOutput.Status = contracts.ResultStatusNotStarted
+
+	case contracts.ResultStatusNotStarted, contracts.ResultStatusInProgress:
+		context.Log().Debugf("plugin - %v status %v",
+			pluginName,
+			pluginOutput.Status)
+		pluginOutput.StartDateTime = time.Now()
 
-		if ioConfig.OutputS3BucketName != "" {
-			pluginOutputs[pluginID].OutputS3BucketName = ioConfig.OutputS3BucketName
-			if ioConfig.OutputS3KeyPrefix != "" {
-				pluginOutputs[pluginID].OutputS3KeyPrefix = fileutil.BuildS3Path(ioConfig.OutputS3KeyPrefix, pluginName)
+	case contracts.ResultStatusSuccessAndReboot:
+		context.Log().Debugf("plugin - %v just experienced reboot, reset to InProgress...",
+			pluginName)
+		pluginOutput.Status = contracts.ResultStatusInProgress
 
-			}
-		}
-		//Append pluginID to logStreamPrefix. Replace ':' or '*' with '-' since LogStreamNames cannot have those characters
-		if ioConfig.CloudWatchConfig.LogGroupName != "" {
-			ioConfig.CloudWatchConfig.LogStreamPrefix = fmt.Sprintf("%s/%s", logStreamPrefix, pluginID)
-			ioConfig.CloudWatchConfig.LogStreamPrefix = strings.Replace(ioConfig.CloudWatchConfig.LogStreamPrefix, ":", "-", -1)
-			ioConfig.CloudWatchConfig.LogStreamPrefix = strings.Replace(ioConfig.CloudWatchConfig.LogStreamPrefix, "*", "-", -1)
-		}
+	default:
+		context.Log().Debugf("plugin - %v already executed, skipping...",
+			pluginName)
+		return false
+	}
+	return true
+}
 
-		var (
-			r                  contracts.PluginResult
-			pluginFactory      PluginFactory
-			pluginHandlerFound bool
-			isKnown            bool
-			isSupported        bool
-		)
-
-		pluginFactory, pluginHandlerFound = registry[pluginName]
-		isKnown, isSupported, _ = isSupportedPlugin(context.Log(), pluginName)
-		operation, logMessage := getStepExecutionOperation(
-			context.Log(),
-			pluginName,
-			pluginID,
-			isKnown,
-			isSupported,
-			pluginHandlerFound,
-			configuration.IsPreconditionEnabled,
-			configuration.Preconditions)
-
-		switch operation {
-		case executeStep:
-			context.Log().Infof("Running plugin %s", pluginName)
-			r = runPlugin(context, pluginFactory, pluginName, configuration, cancelFlag, ioConfig)
-			pluginOutputs[pluginID].Code = r.Code
-			pluginOutputs[pluginID].Status = r.Status
-			pluginOutputs[pluginID].Error = r.Error
-			pluginOutputs[pluginID].Output = r.Output
-			pluginOutputs[pluginID].StandardOutput = r.StandardOutput
-			pluginOutputs[pluginID].StandardError = r.StandardError
-			pluginOutputs[pluginID].StepName = r.StepName
-
-		case skipStep:
-			context.Log().Info(logMessage)
-			pluginOutputs[pluginID].Status = contracts.ResultStatusSkipped
-			pluginOutputs[pluginID].Code = 0
-			pluginOutputs[pluginID].Output = logMessage
-		case failStep:
-			err := fmt.Errorf(logMessage)
-			pluginOutputs[pluginID].Status = contracts.ResultStatusFailed
-			pluginOutputs[pluginID].Error = err.Error()
-			context.Log().Error(err)
-		default:
-			err := fmt.Errorf("Unknown error, Operation: %s, Plugin name: %s", operation, pluginName)
-			pluginOutputs[pluginID].Status = contracts.ResultStatusFailed
-			pluginOutputs[pluginID].Error = err.Error()
-			context.Log().Error(err)
-		}
+// runStep executes the plugin of the step, records the result in pluginOutput and sends it to resChan.
+func runStep(
+	context context.T,
+	pluginState contracts.PluginState,
+	ioConfig contracts.IOConfiguration,
+	registry PluginRegistry,
+	resChan chan contracts.PluginResult,
+	cancelFlag task.CancelFlag,
+	pluginOutput *contracts.PluginResult) (r contracts.PluginResult, pluginHandlerFound bool) {
 
-		// set end time.
-		pluginOutputs[pluginID].EndDateTime = time.Now()
-		context.Log().Infof("Sending plugin %v completion message", pluginID)
+	pluginID := pluginState.Id
+	pluginName := pluginState.Name
+	context.Log().Debugf("Executing plugin - %v", pluginName)
 
-		// truncate the result and send it back to buffer channel.
-		result := *pluginOutputs[pluginID]
-		pluginConfig := iohandler.DefaultOutputConfig()
-		result.StandardOutput = pluginutil.StringPrefix(result.StandardOutput, pluginConfig.MaxStdoutLength, pluginConfig.OutputTruncatedSuffix)
-		result.StandardError = pluginutil.StringPrefix(result.StandardError, pluginConfig.MaxStdoutLength, pluginConfig.OutputTruncatedSuffix)
-		// send to buffer channel, guaranteed to not block since buffer size is plugin number
-		resChan <- result
+	// the step gets its own cancel flag so it can be canceled without canceling the steps after it
+	if stepCancelFlag, ok := cancelFlag.(task.StepCancelFlag); ok {
+		cancelFlag = stepCancelFlag.StepFlag(pluginID)
+	}
 
-		//TODO handle cancelFlag here
-		if pluginHandlerFound && r.Status == contracts.ResultStatusSuccessAndReboot {
-			// do not execute the the next plugin
-			break
+	// populate plugin start time and status
+	configuration := pluginState.Configuration
+
+	if ioConfig.OutputS3BucketName != "" {
+		pluginOutput.OutputS3BucketName = ioConfig.OutputS3BucketName
+		if ioConfig.OutputS3KeyPrefix != "" {
+			pluginOutput.OutputS3KeyPrefix = fileutil.BuildS3Path(ioConfig.OutputS3KeyPrefix, pluginName)
+
+		}
+	}
+	//Append pluginID to logStreamPrefix. Replace ':' or '*' with '-' since LogStreamNames cannot have those characters
+	if ioConfig.CloudWatchConfig.LogGroupName != "" {
+		ioConfig.CloudWatchConfig.LogStreamPrefix = fmt.Sprintf("%s/%s", ioConfig.CloudWatchConfig.LogStreamPrefix, pluginID)
+		ioConfig.CloudWatchConfig.LogStreamPrefix = strings.Replace(ioConfig.CloudWatchConfig.LogStreamPrefix, ":", "-", -1)
+		ioConfig.CloudWatchConfig.LogStreamPrefix = strings.Replace(ioConfig.CloudWatchConfig.LogStreamPrefix, "*", "-", -1)
+	}
+
+	var (
+		pluginFactory PluginFactory
+		isKnown       bool
+		isSupported   bool
+	)
+
+	pluginFactory, pluginHandlerFound = registry[pluginName]
+	isKnown, isSupported, _ = isSupportedPlugin(context.Log(), pluginName)
+	operation, logMessage := getStepExecutionOperation(
+		context.Log(),
+		pluginName,
+		pluginID,
+		isKnown,
+		isSupported,
+		pluginHandlerFound,
+		configuration.IsPreconditionEnabled,
+		configuration.Preconditions)
+
+	if operation == executeStep {
+		if err := checkElevatedPlugin(context.AppConfig().Ssm, pluginName); err != nil {
+			operation, logMessage = failStep, err.Error()
 		}
 	}
 
+	if operation == executeStep && ioConfig.DryRun && !isDryRunPlugin(pluginName) {
+		operation = dryRunStep
+	}
+
+	switch operation {
+	case dryRunStep:
+		context.Log().Infof("Dry run of plugin %s", pluginName)
+		pluginOutput.Status = contracts.ResultStatusSuccess
+		pluginOutput.Code = 0
+		description := describeDryRunStep(pluginName, configuration)
+		pluginOutput.Output = description
+		pluginOutput.StandardOutput = description
+	case executeStep:
+		context.Log().Infof("Running plugin %s", pluginName)
+		r = repeatPlugin(context.Log(), configuration, cancelFlag, func() contracts.PluginResult {
+			return runPlugin(context, pluginFactory, pluginName, configuration, cancelFlag, ioConfig)
+		})
+		pluginOutput.Code = r.Code
+		pluginOutput.Status = r.Status
+		pluginOutput.Error = r.Error
+		pluginOutput.Output = r.Output
+		pluginOutput.StandardOutput = r.StandardOutput
+		pluginOutput.StandardError = r.StandardError
+		pluginOutput.StepName = r.StepName
+
+	case skipStep:
+		context.Log().Info(logMessage)
+		pluginOutput.Status = contracts.ResultStatusSkipped
+		pluginOutput.Code = 0
+		pluginOutput.Output = logMessage
+	case failStep:
+		err := errors.New(logMessage)
+		pluginOutput.Status = contracts.ResultStatusFailed
+		pluginOutput.Error = err.Error()
+		context.Log().Error(err)
+	default:
+		err := fmt.Errorf("Unknown error, Operation: %s, Plugin name: %s", operation, pluginName)
+		pluginOutput.Status = contracts.ResultStatusFailed
+		pluginOutput.Error = err.Error()
+		context.Log().Error(err)
+	}
+
+	sendPluginResult(context, pluginOutput, resChan)
+	if operation == executeStep {
+		writeAuditRecord(context.Log(), context.AppConfig().Ssm, configuration, *pluginOutput)
+	}
 	return
 }
 
+// sendPluginResult sets the end time of the plugin output and sends the truncated result to resChan.
+func sendPluginResult(context context.T, pluginOutput *contracts.PluginResult, resChan chan contracts.PluginResult) {
+	// set end time.
+	pluginOutput.EndDateTime = time.Now()
+	context.Log().Infof("Sending plugin %v completion message", pluginOutput.PluginID)
+
+	// truncate the result and send it back to buffer channel.
+	result := *pluginOutput
+	pluginConfig := iohandler.DefaultOutputConfig()
+	if len(result.StandardOutput) > pluginConfig.MaxStdoutLength {
+		result.StandardOutput = pluginutil.StringPrefix(result.StandardOutput, pluginConfig.MaxStdoutLength, pluginConfig.OutputTruncatedSuffix)
+	}
+	if len(result.StandardError) > pluginConfig.MaxStdoutLength {
+		result.StandardError = pluginutil.StringPrefix(result.StandardError, pluginConfig.MaxStdoutLength, pluginConfig.OutputTruncatedSuffix)
+	}
+	// send to buffer channel, guaranteed to not block since buffer size is plugin number
+	resChan <- result
+}
+
 func runPlugin(
 	context context.T,
 	factory PluginFactory,
@@ -306,8 +385,10 @@ func runPlugin(
 	res.Code = output.GetExitCode()
 	res.Status = output.GetStatus()
 	res.Output = output.GetOutput()
-	res.StandardOutput = output.GetStdout()
-	res.StandardError = output.GetStderr()
+	// truncate here to refer to the full output in s3, sendPluginResult leaves it unchanged
+	pluginConfig := iohandler.DefaultOutputConfig()
+	res.StandardOutput = output.GetTruncatedStdout(pluginConfig.MaxStdoutLength)
+	res.StandardError = output.GetTruncatedStderr(pluginConfig.MaxStdoutLength)
 
 	return
 }
@@ -429,8 +510,8 @@ func evaluatePreconditions(
 	var isAllowed = true
 	var unrecognizedPreconditionList []string
 
-	// For current release, we only support "StringEquals" operator and "platformType"
-	// operand, so explicitly checking for those and number of operands must be 2
+	// We support "StringEquals" operator with "platformType" operand, where the number of operands must be 2,
+	// and "Expression" operator, whose operands are expressions that must all hold
 	for key, value := range preconditions {
 		switch key {
 		case "StringEquals":
@@ -458,6 +539,17 @@ func evaluatePreconditions(
 					isAllowed = false
 				}
 			}
+		case contracts.PreconditionExpression:
+			for _, expression := range value {
+				result, err := evaluateExpression(expression, instanceResolver{log: log})
+				if err != nil {
+					log.Debugf("Precondition expression %s is invalid: %v", expression, err)
+					unrecognizedPreconditionList = append(unrecognizedPreconditionList, fmt.Sprintf("\"%s\": %s (%v)", key, expression, err))
+				} else if !result {
+					// if the expression doesn't hold, mark step for skip
+					isAllowed = false
+				}
+			}
 		default:
 			// mark for unrecognizedPrecondition (which is a form of failure)
 			unrecognizedPreconditionList = append(unrecognizedPreconditionList, fmt.Sprintf("\"%s\": %v", key, value))
@@ -34,6 +34,9 @@ const (
 	testUnsupportedPlugin = "plugin4"
 )
 
+// stepCancelFlag matches the cancel flag of its own which each step gets from the cancel flag of the document
+var stepCancelFlag = mock.AnythingOfType("*task.stepCancelFlag")
+
 var origIsSupported func(log log.T, pluginName string) (isKnown bool, isSupported bool, message string)
 
 func setIsSupportedMock() {
@@ -95,7 +98,7 @@ func TestRunPluginsWithNewDocument(t *testing.T) {
 			Output:        "",
 		}
 
-		pluginInstances[name].On("Execute", ctx, pluginConfigs[name].Configuration, cancelFlag, mock.Anything).Return()
+		pluginInstances[name].On("Execute", ctx, pluginConfigs[name].Configuration, stepCancelFlag, mock.Anything).Return()
 
 		pluginFactory := new(PluginFactoryMock)
 		pluginFactory.On("Create", mock.Anything).Return(pluginInstances[name], nil)
@@ -266,13 +269,13 @@ func TestRunPluginsWithCancelFlagShutdown(t *testing.T) {
 			EndDateTime:   defaultTime,
 		}
 		if name == testPlugin1 {
-			plugins[name].On("Execute", ctx, pluginState.Configuration, cancelFlag, mock.Anything).Run(func(args mock.Arguments) {
+			plugins[name].On("Execute", ctx, pluginState.Configuration, stepCancelFlag, mock.Anything).Run(func(args mock.Arguments) {
 				flag := args.Get(2).(task.CancelFlag)
 				flag.Set(task.ShutDown)
 			}).Return()
 
 		} else {
-			plugins[name].On("Execute", ctx, pluginState.Configuration, cancelFlag, mock.Anything).Return()
+			plugins[name].On("Execute", ctx, pluginState.Configuration, stepCancelFlag, mock.Anything).Return()
 		}
 		pluginStates[index] = pluginState
 		pluginFactory := new(PluginFactoryMock)
@@ -300,6 +303,53 @@ func TestRunPluginsWithCancelFlagShutdown(t *testing.T) {
 	assert.Equal(t, pluginResults[testPlugin2], outputs[testPlugin2])
 }
 
+func TestRunPluginsWithCanceledStep(t *testing.T) {
+	setIsSupportedMock()
+	defer restoreIsSupported()
+	pluginNames := []string{testPlugin1, testPlugin2}
+	pluginStates := make([]contracts.PluginState, 2)
+	plugins := make(map[string]*PluginMock)
+	pluginRegistry := PluginRegistry{}
+
+	cancelFlag := task.NewChanneledCancelFlag()
+	ctx := context.NewMockDefault()
+	ioConfig := contracts.IOConfiguration{}
+	stepCanceled := make(map[string]bool)
+
+	for index, name := range pluginNames {
+		plugins[name] = new(PluginMock)
+		pluginState := contracts.PluginState{
+			Name:          name,
+			Id:            name,
+			Configuration: contracts.Configuration{PluginID: name, PluginName: name},
+		}
+		stepName := name
+		plugins[name].On("Execute", ctx, pluginState.Configuration, stepCancelFlag, mock.Anything).Run(func(args mock.Arguments) {
+			if stepName == testPlugin1 {
+				// the stuck step is canceled while it runs
+				cancelFlag.CancelStep(testPlugin1)
+			}
+			stepCanceled[stepName] = args.Get(2).(task.CancelFlag).Canceled()
+		}).Return()
+		pluginStates[index] = pluginState
+		pluginFactory := new(PluginFactoryMock)
+		pluginFactory.On("Create", mock.Anything).Return(plugins[name], nil)
+		pluginRegistry[name] = pluginFactory
+	}
+
+	ch := make(chan contracts.PluginResult, 2)
+	RunPlugins(ctx, pluginStates, ioConfig, pluginRegistry, ch, cancelFlag)
+	close(ch)
+
+	for _, mockPlugin := range plugins {
+		mockPlugin.AssertExpectations(t)
+	}
+	assert.True(t, stepCanceled[testPlugin1])
+	// the steps after the canceled step still run
+	assert.False(t, stepCanceled[testPlugin2])
+	assert.False(t, cancelFlag.Canceled())
+}
+
 func TestRunPluginsWithInProgressDocuments(t *testing.T) {
 	setIsSupportedMock()
 	defer restoreIsSupported()
@@ -339,7 +389,7 @@ func TestRunPluginsWithInProgressDocuments(t *testing.T) {
 			pluginState.Result = *pluginResults[name]
 		} else {
 			pluginState.Result.Status = contracts.ResultStatusNotStarted
-			plugins[name].On("Execute", ctx, pluginState.Configuration, cancelFlag, mock.Anything).Return()
+			plugins[name].On("Execute", ctx, pluginState.Configuration, stepCancelFlag, mock.Anything).Return()
 		}
 		pluginStates[index] = pluginState
 		pluginFactory := new(PluginFactoryMock)
@@ -531,7 +581,7 @@ func TestRunPluginsWithCompatiblePrecondition(t *testing.T) {
 		pluginFactory := new(PluginFactoryMock)
 		pluginFactory.On("Create", mock.Anything).Return(pluginInstances[name], nil)
 		pluginRegistry[name] = pluginFactory
-		pluginInstances[name].On("Execute", ctx, pluginConfigs[name].Configuration, cancelFlag, mock.Anything).Return()
+		pluginInstances[name].On("Execute", ctx, pluginConfigs[name].Configuration, stepCancelFlag, mock.Anything).Return()
 
 		pluginConfigs2[index] = pluginConfigs[name]
 	}
@@ -621,7 +671,7 @@ func TestRunPluginsWithCompatiblePreconditionWithValueFirst(t *testing.T) {
 		pluginFactory := new(PluginFactoryMock)
 		pluginFactory.On("Create", mock.Anything).Return(pluginInstances[name], nil)
 		pluginRegistry[name] = pluginFactory
-		pluginInstances[name].On("Execute", ctx, pluginConfigs[name].Configuration, cancelFlag, mock.Anything).Return()
+		pluginInstances[name].On("Execute", ctx, pluginConfigs[name].Configuration, stepCancelFlag, mock.Anything).Return()
 
 		pluginConfigs2[index] = pluginConfigs[name]
 	}
@@ -1387,7 +1437,7 @@ func TestRunPluginsWithUnknownPlugin(t *testing.T) {
 				StartDateTime: defaultTime,
 				EndDateTime:   defaultTime,
 			}
-			pluginInstances[name].On("Execute", ctx, pluginConfigs[name].Configuration, cancelFlag, mock.Anything).Return(*pluginResults[name])
+			pluginInstances[name].On("Execute", ctx, pluginConfigs[name].Configuration, stepCancelFlag, mock.Anything).Return(*pluginResults[name])
 		}
 		pluginFactory := new(PluginFactoryMock)
 		pluginFactory.On("Create", mock.Anything).Return(pluginInstances[name], nil)
@@ -1468,7 +1518,7 @@ func TestRunPluginSuccessWithNonTruncatedResult(t *testing.T) {
 			StandardOutput: "",
 		}
 
-		pluginInstances[name].On("Execute", ctx, pluginConfigs[name].Configuration, cancelFlag, mock.Anything).Return()
+		pluginInstances[name].On("Execute", ctx, pluginConfigs[name].Configuration, stepCancelFlag, mock.Anything).Return()
 
 		pluginFactory := new(PluginFactoryMock)
 		pluginFactory.On("Create", mock.Anything).Return(pluginInstances[name], nil)
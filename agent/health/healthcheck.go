@@ -20,7 +20,9 @@ import (
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/amazon-ssm-agent/agent/session/telemetry"
 	"github.com/aws/amazon-ssm-agent/agent/ssm"
 	"github.com/aws/amazon-ssm-agent/agent/version"
 	"github.com/carlescere/scheduler"
@@ -97,9 +99,31 @@ func (h *HealthCheck) updateHealth() {
 	if _, err = h.service.UpdateInstanceInformation(log, version.Version, "Active", AgentName); err != nil {
 		sdkutil.HandleAwsError(log, err, h.healthCheckStopPolicy)
 	}
+	h.reportSessionQuality()
+	h.reportSessionCrashes()
 	return
 }
 
+// reportSessionQuality logs the network quality of the data channels of active sessions
+func (h *HealthCheck) reportSessionQuality() {
+	log := h.context.Log()
+	for _, quality := range telemetry.ReadAll(log) {
+		if qualityJson, err := jsonutil.Marshal(quality); err == nil {
+			log.Infof("Session data channel quality: %s", qualityJson)
+		}
+	}
+}
+
+// reportSessionCrashes logs the crashes of session plugins recorded since the last report
+func (h *HealthCheck) reportSessionCrashes() {
+	log := h.context.Log()
+	for _, crash := range telemetry.ReadCrashes(log) {
+		if crashJson, err := jsonutil.Marshal(crash); err == nil {
+			log.Errorf("Session plugin crashed: %s", crashJson)
+		}
+	}
+}
+
 // scheduleInMinutes Run Schedule In Minutes
 func (h *HealthCheck) scheduleInMinutes() int {
 	updateHealthFrequencyMins := 5
@@ -16,7 +16,17 @@
 
 package log
 
-import "path/filepath"
+import (
+	"path/filepath"
+	"strconv"
+)
+
+const (
+	// LogFileMaxSize is the size in bytes after which the agent log is rolled over
+	LogFileMaxSize = 30000000
+	// LogFileMaxRolls is the number of rolled over agent logs kept
+	LogFileMaxRolls = 5
+)
 
 func DefaultConfig() []byte {
 	return LoadLog(DefaultLogDir, LogFile)
@@ -37,7 +47,7 @@ func LoadLog(defaultLogDir string, logFile string) []byte {
         <console formatid="fmtinfo"/>
 
         `
-	logConfig += `<rollingfile type="size" filename="` + logFilePath + `" maxsize="30000000" maxrolls="5"/>`
+	logConfig += `<rollingfile type="size" filename="` + logFilePath + `" maxsize="` + strconv.Itoa(LogFileMaxSize) + `" maxrolls="` + strconv.Itoa(LogFileMaxRolls) + `"/>`
 	logConfig += `
 		<filter levels="error,critical" formatid="fmterror">
 		`
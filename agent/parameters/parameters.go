@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"strconv"
 
 	"github.com/aws/amazon-ssm-agent/agent/log"
 )
@@ -120,6 +121,20 @@ func ReplaceParameter(input string, paramName string, paramValue string) string
 	return r.ReplaceAllString(input, paramValue)
 }
 
+// ReplaceParametersWithLiterals replaces all occurrences of "{{ paramName }}" in the input by the value of the
+// parameter as a double quoted string literal, so that the values can be used in precondition expressions.
+func ReplaceParametersWithLiterals(input string, parameters map[string]interface{}, logger log.T) string {
+	for parameterName, parameterValue := range parameters {
+		parameterValueString, err := convertToString(parameterValue)
+		if err != nil {
+			logger.Error(err)
+		}
+		r := regexp.MustCompile(fmt.Sprintf(`{{\s*%v\s*}}`, parameterName))
+		input = r.ReplaceAllLiteralString(input, strconv.Quote(parameterValueString))
+	}
+	return input
+}
+
 // ValidParameters checks if parameter names are valid. Returns valid parameters only.
 func ValidParameters(log log.T, params map[string]interface{}) map[string]interface{} {
 	validParams := make(map[string]interface{})
@@ -223,3 +223,15 @@ func TestConvertToString(t *testing.T) {
 		assert.Equal(t, tst.Output, actual)
 	}
 }
+
+func TestReplaceParametersWithLiterals(t *testing.T) {
+	parameters := map[string]interface{}{
+		"Env":   "prod",
+		"Owner": `team "a$1"`,
+		"Count": 5,
+	}
+
+	actual := ReplaceParametersWithLiterals(`{{ Env }} == "prod" && {{Owner}} != "" && {{ Count }} > 3 && {{ Other }} == ""`, parameters, logger)
+
+	assert.Equal(t, `"prod" == "prod" && "team \"a$1\"" != "" && "5" > 3 && {{ Other }} == ""`, actual)
+}
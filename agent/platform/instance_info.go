@@ -135,6 +135,16 @@ func IsManagedInstance() (bool, error) {
 	return false, nil
 }
 
+// InstanceTag returns the value of the tag of the instance from EC2 Instance Metadata,
+// which only exposes tags of instances that allow tags in instance metadata.
+func InstanceTag(key string) (string, error) {
+	// managed instances have no instance metadata
+	if managedInstance.InstanceID() != "" {
+		return "", fmt.Errorf("instance tags are not available on managed instances")
+	}
+	return metadata.GetMetadata("tags/instance/" + key)
+}
+
 // fetchInstanceID fetches the instance id with the following preference order.
 // 1. managed instance registration
 // 2. EC2 Instance Metadata
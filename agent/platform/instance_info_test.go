@@ -245,3 +245,18 @@ func TestFetchAvailabilityZoneForEc2Instance(t *testing.T) {
 	assert.Equal(t, value, actualOutput)
 	assert.Equal(t, nil, actualError)
 }
+
+func TestInstanceTag(t *testing.T) {
+	// instance tags are read from the metadata of EC2 instances
+	metadata = validMetadata
+	managedInstance = invalidRegistration
+	actualOutput, actualError := InstanceTag("Env")
+	var value, _ = metadata.GetMetadata("tags/instance/Env")
+	assert.Equal(t, value, actualOutput)
+	assert.Nil(t, actualError)
+
+	// managed instances have no instance tags
+	managedInstance = validRegistration
+	_, actualError = InstanceTag("Env")
+	assert.Error(t, actualError)
+}
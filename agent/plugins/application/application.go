@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
@@ -42,6 +44,16 @@ const (
 	defaultWorkingDirectory = ""
 )
 
+const (
+	//SourceType values
+	SourceTypeMsi    = "msi"
+	SourceTypeWinget = "winget"
+	SourceTypeMsix   = "msix"
+)
+
+// validPackageValue matches winget package ids, MSIX package names and versions
+var validPackageValue = regexp.MustCompile(`^[\w\.\-\+]*$`)
+
 // msiExecCommand is the command for installing msi applications
 var msiExecCommand = filepath.Join(os.Getenv("SystemRoot"), "System32", "msiexec.exe")
 
@@ -60,6 +72,12 @@ type ApplicationPluginInput struct {
 	Source         string
 	SourceHash     string
 	SourceHashType string
+	// SourceType is msi, winget or msix, msi by default
+	SourceType string
+	// PackageId is the winget package id or the MSIX package name used to detect installed packages
+	PackageId string
+	// Version pins the version of winget and MSIX packages
+	Version string
 }
 
 // NewPlugin returns a new instance of the plugin.
@@ -118,6 +136,20 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput Application
 		return
 	}
 
+	if err = validatePackageInput(pluginInput); err != nil {
+		output.MarkAsFailed(fmt.Errorf("Validation error, %v", err))
+		return
+	}
+	switch strings.ToLower(pluginInput.SourceType) {
+	case "", SourceTypeMsi:
+	case SourceTypeWinget:
+		p.runWinget(log, pluginInput, defaultWorkingDirectory, cancelFlag, output)
+		return
+	case SourceTypeMsix:
+		p.runMsix(log, pluginInput, defaultWorkingDirectory, cancelFlag, output)
+		return
+	}
+
 	// Get application mode
 	mode, err := getMsiApplicationMode(log, pluginInput)
 	if err != nil {
@@ -162,3 +194,27 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput Application
 		return
 	}
 }
+
+// validatePackageInput checks the source type and the package fields it requires
+func validatePackageInput(pluginInput ApplicationPluginInput) error {
+	switch strings.ToLower(pluginInput.SourceType) {
+	case "", SourceTypeMsi:
+		if pluginInput.PackageId != "" || pluginInput.Version != "" {
+			return fmt.Errorf("PackageId and Version are only supported for %v and %v sources", SourceTypeWinget, SourceTypeMsix)
+		}
+	case SourceTypeWinget:
+		if pluginInput.PackageId == "" {
+			return fmt.Errorf("PackageId is required for %v sources", SourceTypeWinget)
+		}
+	case SourceTypeMsix:
+	default:
+		return fmt.Errorf("SourceType is set to unsupported value: %v", pluginInput.SourceType)
+	}
+	if !validPackageValue.MatchString(pluginInput.PackageId) {
+		return fmt.Errorf("Invalid PackageId %v", pluginInput.PackageId)
+	}
+	if !validPackageValue.MatchString(pluginInput.Version) {
+		return fmt.Errorf("Invalid Version %v", pluginInput.Version)
+	}
+	return nil
+}
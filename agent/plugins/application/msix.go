@@ -0,0 +1,114 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package application implements the application plugin.
+//
+// +build windows
+
+package application
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+// MSIX packages are provisioned for all users as packages added by the SYSTEM account are not registered for anyone.
+const (
+	msixVersionScript   = "(Get-AppxProvisionedPackage -Online | Where-Object DisplayName -eq '%v' | Select-Object -First 1).Version"
+	msixInstallScript   = "Add-AppxProvisionedPackage -Online -PackagePath '%v' -SkipLicense -ErrorAction Stop | Out-Null"
+	msixUninstallScript = "Get-AppxProvisionedPackage -Online | Where-Object DisplayName -eq '%v' | Remove-AppxProvisionedPackage -Online -AllUsers -ErrorAction Stop | Out-Null"
+)
+
+// runMsix provisions the MSIX bundle downloaded from the source, or removes the provisioned package of the input.
+// Installs are skipped when the package, or the pinned version of it, is already provisioned.
+func (p *Plugin) runMsix(log log.T, pluginInput ApplicationPluginInput, workingDirectory string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	var installedVersion string
+	var err error
+	if pluginInput.PackageId != "" {
+		if installedVersion, err = p.msixInstalledVersion(log, pluginInput.PackageId, workingDirectory, cancelFlag); err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+		log.Debugf("package %v installed version %v", pluginInput.PackageId, installedVersion)
+	}
+
+	var script string
+	switch pluginInput.Action {
+	case INSTALL:
+		if installedVersion != "" && (pluginInput.Version == "" || installedVersion == pluginInput.Version) {
+			output.AppendInfof("Package %v %v is already installed", pluginInput.PackageId, installedVersion)
+			output.MarkAsSucceeded()
+			return
+		}
+		downloadOutput, err := pluginutil.DownloadFileFromSource(log, pluginInput.Source, pluginInput.SourceHash, pluginInput.SourceHashType)
+		if err != nil || downloadOutput.IsHashMatched == false || downloadOutput.LocalFilePath == "" {
+			output.MarkAsFailed(fmt.Errorf("failed to download file reliably %v", pluginInput.Source))
+			return
+		}
+		script = fmt.Sprintf(msixInstallScript, quotePowerShellString(downloadOutput.LocalFilePath))
+	case UNINSTALL:
+		if pluginInput.PackageId == "" {
+			output.MarkAsFailed(fmt.Errorf("PackageId is required to uninstall %v packages", SourceTypeMsix))
+			return
+		}
+		if installedVersion == "" || (pluginInput.Version != "" && installedVersion != pluginInput.Version) {
+			output.AppendInfof("Package %v %v is not installed", pluginInput.PackageId, pluginInput.Version)
+			output.MarkAsSucceeded()
+			return
+		}
+		script = fmt.Sprintf(msixUninstallScript, quotePowerShellString(pluginInput.PackageId))
+	default:
+		output.MarkAsFailed(fmt.Errorf("Action %v is not supported for %v sources", pluginInput.Action, SourceTypeMsix))
+		return
+	}
+
+	exitCode, err := p.CommandExecuter.NewExecute(log, workingDirectory, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, defaultApplicationExecutionTimeoutInSeconds, appconfig.PowerShellPluginCommandName, powerShellArguments(script))
+	output.SetExitCode(exitCode)
+	setPackageManagerStatus(pluginInput, exitCode, err, cancelFlag, output)
+}
+
+// msixInstalledVersion returns the version of the provisioned package, or an empty version if it is not provisioned
+func (p *Plugin) msixInstalledVersion(log log.T, packageName string, workingDirectory string, cancelFlag task.CancelFlag) (string, error) {
+	var stdout, stderr bytes.Buffer
+	script := fmt.Sprintf(msixVersionScript, quotePowerShellString(packageName))
+	if _, err := p.CommandExecuter.NewExecute(log, workingDirectory, &stdout, &stderr, cancelFlag, defaultApplicationExecutionTimeoutInSeconds, appconfig.PowerShellPluginCommandName, powerShellArguments(script)); err != nil {
+		return "", fmt.Errorf("failed to get the provisioned version of %v: %v %v", packageName, err, stderr.String())
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// powerShellArguments returns the arguments running the script non interactively
+func powerShellArguments(script string) []string {
+	return []string{"-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-Command", script}
+}
+
+// powerShellQuoteReplacer doubles the characters PowerShell accepts as single quotes, which are the
+// apostrophe and the left, right, low-9 and high-reversed-9 single quotation marks
+var powerShellQuoteReplacer = strings.NewReplacer(
+	"'", "''",
+	"\u2018", "\u2018\u2018",
+	"\u2019", "\u2019\u2019",
+	"\u201A", "\u201A\u201A",
+	"\u201B", "\u201B\u201B")
+
+// quotePowerShellString escapes the value for a single quoted PowerShell string
+func quotePowerShellString(value string) string {
+	return powerShellQuoteReplacer.Replace(value)
+}
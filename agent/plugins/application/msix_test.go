@@ -0,0 +1,30 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package application
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotePowerShellString(t *testing.T) {
+	assert.Equal(t, `C:\packages\app.msix`, quotePowerShellString(`C:\packages\app.msix`))
+	assert.Equal(t, "it''s", quotePowerShellString("it's"))
+	// PowerShell also ends single quoted strings at typographic single quotation marks
+	assert.Equal(t, "a\u2018\u2018b\u2019\u2019c\u201A\u201Ad\u201B\u201Be", quotePowerShellString("a\u2018b\u2019c\u201Ad\u201Be"))
+	assert.Equal(t, "x\u2019\u2019; Remove-Item C:\\ -Recurse; ''", quotePowerShellString("x\u2019; Remove-Item C:\\ -Recurse; '"))
+}
@@ -0,0 +1,150 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package application implements the application plugin.
+//
+// +build windows
+
+package application
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	// wingetNoApplicationsFound is APPINSTALLER_CLI_ERROR_NO_APPLICATIONS_FOUND, returned when no installed package matches
+	wingetNoApplicationsFound uint32 = 0x8A150014
+
+	// wingetPackageAlreadyInstalled is APPINSTALLER_CLI_ERROR_PACKAGE_ALREADY_INSTALLED
+	wingetPackageAlreadyInstalled uint32 = 0x8A15002B
+)
+
+// wingetCommand returns the path of winget, which is not on the path of the SYSTEM account the agent runs as.
+// The latest App Installer in WindowsApps is used in that case.
+var wingetCommand = func() (string, error) {
+	if path, err := exec.LookPath("winget.exe"); err == nil {
+		return path, nil
+	}
+	matches, _ := filepath.Glob(filepath.Join(os.Getenv("ProgramFiles"), "WindowsApps", "Microsoft.DesktopAppInstaller_*_8wekyb3d8bbwe", "winget.exe"))
+	if len(matches) == 0 {
+		return "", errors.New("winget is not installed, App Installer is required for winget sources")
+	}
+	sort.Strings(matches)
+	return matches[len(matches)-1], nil
+}
+
+// runWinget installs or uninstalls the winget package of the input.
+// Installs are skipped when the package, or the pinned version of it, is already installed.
+func (p *Plugin) runWinget(log log.T, pluginInput ApplicationPluginInput, workingDirectory string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	command, err := wingetCommand()
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	installedVersion, installed, err := p.wingetInstalledVersion(log, command, pluginInput.PackageId, workingDirectory, cancelFlag)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	log.Debugf("package %v installed: %v, version %v", pluginInput.PackageId, installed, installedVersion)
+
+	commandArguments := []string{"--id", pluginInput.PackageId, "--exact", "--silent", "--accept-source-agreements", "--disable-interactivity"}
+	switch pluginInput.Action {
+	case INSTALL:
+		if installed && (pluginInput.Version == "" || installedVersion == pluginInput.Version) {
+			output.AppendInfof("Package %v %v is already installed", pluginInput.PackageId, installedVersion)
+			output.MarkAsSucceeded()
+			return
+		}
+		commandArguments = append([]string{"install"}, commandArguments...)
+		commandArguments = append(commandArguments, "--accept-package-agreements")
+		if pluginInput.Version != "" {
+			commandArguments = append(commandArguments, "--version", pluginInput.Version)
+		}
+		if installed {
+			// another version is installed, force the pinned one
+			commandArguments = append(commandArguments, "--force")
+		}
+		if pluginInput.Parameters != "" {
+			commandArguments = append(commandArguments, "--override", pluginInput.Parameters)
+		}
+	case UNINSTALL:
+		if !installed || (pluginInput.Version != "" && installedVersion != pluginInput.Version) {
+			output.AppendInfof("Package %v %v is not installed", pluginInput.PackageId, pluginInput.Version)
+			output.MarkAsSucceeded()
+			return
+		}
+		commandArguments = append([]string{"uninstall"}, commandArguments...)
+	default:
+		output.MarkAsFailed(fmt.Errorf("Action %v is not supported for %v sources", pluginInput.Action, SourceTypeWinget))
+		return
+	}
+
+	exitCode, err := p.CommandExecuter.NewExecute(log, workingDirectory, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, defaultApplicationExecutionTimeoutInSeconds, command, commandArguments)
+	output.SetExitCode(exitCode)
+	if uint32(exitCode) == wingetPackageAlreadyInstalled {
+		output.SetStatus(contracts.ResultStatusSuccess)
+		return
+	}
+	setPackageManagerStatus(pluginInput, exitCode, err, cancelFlag, output)
+}
+
+// wingetInstalledVersion returns the installed version of the package, and false if it is not installed
+func (p *Plugin) wingetInstalledVersion(log log.T, command string, packageId string, workingDirectory string, cancelFlag task.CancelFlag) (version string, installed bool, err error) {
+	var stdout, stderr bytes.Buffer
+	commandArguments := []string{"list", "--id", packageId, "--exact", "--accept-source-agreements", "--disable-interactivity"}
+	exitCode, err := p.CommandExecuter.NewExecute(log, workingDirectory, &stdout, &stderr, cancelFlag, defaultApplicationExecutionTimeoutInSeconds, command, commandArguments)
+	if uint32(exitCode) == wingetNoApplicationsFound {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to list the installed versions of %v: %v %v", packageId, err, stderr.String())
+	}
+	version, installed = parseWingetListVersion(stdout.String(), packageId)
+	return version, installed, nil
+}
+
+// parseWingetListVersion returns the version after the package id in the table printed by winget list
+func parseWingetListVersion(listOutput string, packageId string) (string, bool) {
+	for _, line := range strings.Split(listOutput, "\n") {
+		fields := strings.Fields(line)
+		for i, field := range fields {
+			if strings.EqualFold(field, packageId) && i+1 < len(fields) {
+				return fields[i+1], true
+			}
+		}
+	}
+	return "", false
+}
+
+// setPackageManagerStatus sets the status of a winget or MSIX action from its exit code
+func setPackageManagerStatus(pluginInput ApplicationPluginInput, exitCode int, err error, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	output.SetStatus(pluginutil.GetStatus(exitCode, cancelFlag))
+	if err != nil && output.GetStatus() == contracts.ResultStatusFailed {
+		output.MarkAsFailed(fmt.Errorf("failed to %v %v: %v", strings.ToLower(pluginInput.Action), pluginInput.PackageId, err))
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package compliancescan implements the aws:runComplianceScan plugin
+package compliancescan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/s3util"
+	ssmSvc "github.com/aws/amazon-ssm-agent/agent/ssm"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+const (
+	//Scanner values
+	ScannerInSpec   = "InSpec"
+	ScannerOpenSCAP = "OpenSCAP"
+
+	downloadsDir = "downloads" //Directory under the orchestration directory where the downloaded resource resides
+
+	// customComplianceTypePrefix starts the compliance types which are not reported by Systems Manager itself
+	customComplianceTypePrefix = "Custom:"
+
+	// objectACL gives the bucket owner control of the results uploaded from instances of other accounts
+	objectACL = "bucket-owner-full-control"
+
+	//Compliance statuses and severities of the compliance items
+	statusCompliant        = "COMPLIANT"
+	statusNonCompliant     = "NON_COMPLIANT"
+	severityCritical       = "CRITICAL"
+	severityHigh           = "HIGH"
+	severityMedium         = "MEDIUM"
+	severityLow            = "LOW"
+	severityInformational  = "INFORMATIONAL"
+	severityUnspecified    = "UNSPECIFIED"
+	maxComplianceItemTitle = 500
+)
+
+// Assign method to global variables to allow unittest to override
+var lookPath = exec.LookPath
+var getInstanceID = platform.InstanceID
+
+// scanner runs one kind of scan and converts its results to compliance items
+type scanner interface {
+	// command is the executable of the scanner
+	command() string
+	// arguments returns the arguments scanning the instance and writing the results to the results file
+	arguments(input *ComplianceScanPluginInput, downloadsPath string, resultsPath string) ([]string, error)
+	// scanCompleted returns true if the exit code is of a completed scan, whether or not rules failed
+	scanCompleted(exitCode int) bool
+	// parseResults converts the results file to compliance items
+	parseResults(resultsPath string) ([]*ssm.ComplianceItemEntry, error)
+	// resultsFileName is the name of the results file
+	resultsFileName() string
+}
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	plugin.CommandExecuter = executers.ShellCommandExecuter{}
+	plugin.complianceService = ssmSvc.NewService
+	plugin.uploaderCreator = newUploader
+	return &plugin, nil
+}
+
+// Plugin is the type for the aws:runComplianceScan plugin.
+type Plugin struct {
+	// CommandExecuter is an object that can execute commands.
+	CommandExecuter   executers.T
+	complianceService func() ssmSvc.Service
+	uploaderCreator   func(log log.T, bucketName string) s3manageriface.UploaderAPI
+}
+
+// ComplianceScanPluginInput represents the scan run by the aws:runComplianceScan plugin
+type ComplianceScanPluginInput struct {
+	contracts.PluginInput
+	ID string
+	// Scanner is InSpec or OpenSCAP
+	Scanner string `json:"scanner"`
+	// Profile is the InSpec profile: a path, absolute or relative to the directory aws:downloadContent downloads to, or a URL
+	Profile string `json:"profile"`
+	// DataStream is the SCAP source data stream OpenSCAP evaluates, absolute or relative to the downloads directory
+	DataStream string `json:"dataStream"`
+	// ProfileId is the XCCDF profile of the data stream OpenSCAP evaluates
+	ProfileId string `json:"profileId"`
+	// ComplianceType is the custom compliance type the results are reported as, Custom:<Scanner> by default
+	ComplianceType string `json:"complianceType"`
+	// BucketName and KeyPrefix are where the results file is uploaded to, it is not uploaded if no bucket is set
+	BucketName string `json:"bucketName"`
+	KeyPrefix  string `json:"keyPrefix"`
+	// FailOnNonCompliance fails the step when the instance is not compliant with a rule
+	FailOnNonCompliance bool        `json:"failOnNonCompliance"`
+	TimeoutSeconds      interface{} `json:"timeoutSeconds"`
+}
+
+// Name returns the plugin name
+func Name() string {
+	return appconfig.PluginNameAwsRunComplianceScan
+}
+
+// newUploader returns an uploader to the bucket
+func newUploader(log log.T, bucketName string) s3manageriface.UploaderAPI {
+	return s3util.NewUploader(log, bucketName)
+}
+
+// Execute runs the scan and reports its results to Compliance.
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", Name(), config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+	} else if input, scanner, err := parseAndValidateInput(config.Properties); err != nil {
+		output.MarkAsFailed(err)
+	} else {
+		p.runScan(log, input, scanner, config, cancelFlag, output)
+	}
+}
+
+// parseAndValidateInput parses the plugin properties, checks the inputs of the scanner and defaults the compliance type
+func parseAndValidateInput(rawPluginInput interface{}) (*ComplianceScanPluginInput, scanner, error) {
+	var input ComplianceScanPluginInput
+	if err := jsonutil.Remarshal(rawPluginInput, &input); err != nil {
+		return nil, nil, fmt.Errorf("invalid format in plugin properties %v; \nerror %v", rawPluginInput, err)
+	}
+
+	var scanner scanner
+	switch strings.ToLower(input.Scanner) {
+	case strings.ToLower(ScannerInSpec):
+		if input.Profile == "" {
+			return nil, nil, errors.New("invalid input: profile is required for InSpec scans")
+		}
+		input.Scanner, scanner = ScannerInSpec, inspecScanner{}
+	case strings.ToLower(ScannerOpenSCAP):
+		if input.DataStream == "" || input.ProfileId == "" {
+			return nil, nil, errors.New("invalid input: dataStream and profileId are required for OpenSCAP scans")
+		}
+		input.Scanner, scanner = ScannerOpenSCAP, openscapScanner{}
+	default:
+		return nil, nil, fmt.Errorf("invalid input: scanner must be %v or %v", ScannerInSpec, ScannerOpenSCAP)
+	}
+
+	if input.ComplianceType == "" {
+		input.ComplianceType = customComplianceTypePrefix + input.Scanner
+	} else if !strings.HasPrefix(input.ComplianceType, customComplianceTypePrefix) || len(input.ComplianceType) == len(customComplianceTypePrefix) {
+		return nil, nil, fmt.Errorf("invalid input: complianceType must start with %v", customComplianceTypePrefix)
+	}
+	input.KeyPrefix = strings.Trim(input.KeyPrefix, "/")
+	return &input, scanner, nil
+}
+
+// runScan runs the scanner, reports the compliance of the instance and uploads the results file.
+func (p *Plugin) runScan(log log.T, input *ComplianceScanPluginInput, scanner scanner, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	downloadsPath := filepath.Join(strings.TrimSuffix(config.OrchestrationDirectory, config.PluginID), downloadsDir)
+	orchestrationDir := fileutil.BuildPath(config.OrchestrationDirectory, input.ID)
+	if err := fileutil.MakeDirsWithExecuteAccess(orchestrationDir); err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to create orchestrationDir directory, %v", orchestrationDir))
+		return
+	}
+
+	command, err := lookPath(scanner.command())
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("%v is not installed on this instance, %v was not found", input.Scanner, scanner.command()))
+		return
+	}
+	resultsPath := filepath.Join(orchestrationDir, scanner.resultsFileName())
+	arguments, err := scanner.arguments(input, downloadsPath, resultsPath)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	executionTimeout := pluginutil.ValidateExecutionTimeout(log, input.TimeoutSeconds)
+	log.Debugf("Running %v scan with %v", input.Scanner, arguments)
+	exitCode, err := p.CommandExecuter.NewExecute(log, orchestrationDir, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, command, arguments)
+	output.SetExitCode(exitCode)
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+		return
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+		return
+	} else if !scanner.scanCompleted(exitCode) {
+		if status := pluginutil.GetStatus(exitCode, cancelFlag); status == contracts.ResultStatusTimedOut {
+			output.SetStatus(status)
+		} else {
+			output.MarkAsFailed(fmt.Errorf("%v scan failed with exit code %v: %v", input.Scanner, exitCode, err))
+		}
+		return
+	}
+
+	items, err := scanner.parseResults(resultsPath)
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to parse the %v results: %v", input.Scanner, err))
+		return
+	}
+	instanceID, err := getInstanceID()
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to get the instance id: %v", err))
+		return
+	}
+	if err = p.putComplianceItems(log, instanceID, input.ComplianceType, items); err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to report the compliance of the instance: %v", err))
+		return
+	}
+	if input.BucketName != "" {
+		key := path.Join(input.KeyPrefix, instanceID, scanner.resultsFileName())
+		if err = p.uploadResults(log, input.BucketName, key, resultsPath); err != nil {
+			output.MarkAsFailed(fmt.Errorf("failed to upload the results to s3://%v/%v: %v", input.BucketName, key, err))
+			return
+		}
+		output.AppendInfof("Results uploaded to s3://%v/%v", input.BucketName, key)
+	}
+
+	nonCompliant := 0
+	for _, item := range items {
+		if aws.StringValue(item.Status) == statusNonCompliant {
+			nonCompliant++
+		}
+	}
+	output.AppendInfof("%v reported %v: %v rules compliant, %v rules non compliant", input.Scanner, input.ComplianceType, len(items)-nonCompliant, nonCompliant)
+	if input.FailOnNonCompliance && nonCompliant > 0 {
+		output.MarkAsFailed(fmt.Errorf("the instance is not compliant with %v rules", nonCompliant))
+		return
+	}
+	output.MarkAsSucceeded()
+}
+
+// putComplianceItems replaces the compliance items of the compliance type of the instance
+func (p *Plugin) putComplianceItems(log log.T, instanceID string, complianceType string, items []*ssm.ComplianceItemEntry) error {
+	content, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	contentHash := sha256.Sum256(content)
+	executionTime := time.Now()
+	_, err = p.complianceService().PutComplianceItems(log, &executionTime, "", "", instanceID, complianceType, hex.EncodeToString(contentHash[:]), items)
+	return err
+}
+
+// uploadResults uploads the results file to the object
+func (p *Plugin) uploadResults(log log.T, bucketName string, key string, resultsPath string) error {
+	file, err := os.Open(resultsPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	log.Infof("Uploading %v to s3://%v/%v", resultsPath, bucketName, key)
+	_, err = p.uploaderCreator(log, bucketName).Upload(&s3manager.UploadInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+		Body:   file,
+		ACL:    aws.String(objectACL),
+	})
+	return err
+}
+
+// resolvePath returns the path relative to the downloads directory if it is not absolute
+func resolvePath(downloadsPath string, filePath string) string {
+	if filepath.IsAbs(filePath) {
+		return filePath
+	}
+	return filepath.Join(downloadsPath, filePath)
+}
+
+// complianceItem returns the compliance item of the rule, with the title truncated to the length Compliance accepts
+func complianceItem(id string, title string, status string, severity string, details map[string]string) *ssm.ComplianceItemEntry {
+	if title == "" {
+		title = id
+	}
+	if len(title) > maxComplianceItemTitle {
+		title = title[:maxComplianceItemTitle]
+	}
+	return &ssm.ComplianceItemEntry{
+		Id:       aws.String(id),
+		Title:    aws.String(title),
+		Status:   aws.String(status),
+		Severity: aws.String(severity),
+		Details:  aws.StringMap(details),
+	}
+}
+
+// fileExists returns true if the file or directory exists
+func fileExists(filePath string) bool {
+	_, err := os.Stat(filePath)
+	return err == nil
+}
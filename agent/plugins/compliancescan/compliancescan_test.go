@@ -0,0 +1,194 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package compliancescan
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	iohandlermocks "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
+	multiwritermock "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter/mock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	ssmSvc "github.com/aws/amazon-ssm-agent/agent/ssm"
+	ssmmocks "github.com/aws/amazon-ssm-agent/agent/ssm/mocks"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var logger = log.NewMockLog()
+
+const testInstanceID = "i-1234567890abcdef0"
+
+// fakeUploader records the uploaded objects by key
+type fakeUploader struct {
+	s3manageriface.UploaderAPI
+	objects map[string]s3manager.UploadInput
+}
+
+func (u *fakeUploader) Upload(input *s3manager.UploadInput, options ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error) {
+	u.objects[*input.Key] = *input
+	return &s3manager.UploadOutput{}, nil
+}
+
+func setScannerMocks() func() {
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+	getInstanceID = func() (string, error) { return testInstanceID, nil }
+	return func() {
+		lookPath = exec.LookPath
+		getInstanceID = platform.InstanceID
+	}
+}
+
+func TestParseAndValidateInput(t *testing.T) {
+	input, scanner, err := parseAndValidateInput(map[string]interface{}{"scanner": "inspec", "profile": "baseline", "keyPrefix": "/scans/"})
+	assert.NoError(t, err)
+	assert.Equal(t, ScannerInSpec, input.Scanner)
+	assert.Equal(t, "Custom:InSpec", input.ComplianceType)
+	assert.Equal(t, "scans", input.KeyPrefix)
+	assert.IsType(t, inspecScanner{}, scanner)
+
+	input, scanner, err = parseAndValidateInput(map[string]interface{}{"scanner": "OpenSCAP", "dataStream": "ds.xml", "profileId": "standard", "complianceType": "Custom:CIS"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Custom:CIS", input.ComplianceType)
+	assert.IsType(t, openscapScanner{}, scanner)
+
+	for _, properties := range []map[string]interface{}{
+		{"scanner": "nessus"},
+		{"scanner": "InSpec"},
+		{"scanner": "OpenSCAP", "dataStream": "ds.xml"},
+		{"scanner": "InSpec", "profile": "baseline", "complianceType": "Patch"},
+		{"scanner": "InSpec", "profile": "baseline", "complianceType": "Custom:"},
+	} {
+		_, _, err = parseAndValidateInput(properties)
+		assert.Error(t, err, "%v", properties)
+	}
+}
+
+func TestRunScanReportsAndUploadsResults(t *testing.T) {
+	orchestrationDir, err := ioutil.TempDir("", "compliancescan")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+	defer setScannerMocks()()
+	stepDir := filepath.Join(orchestrationDir, "scan")
+	resultsPath := filepath.Join(stepDir, inspecResultsFile)
+
+	mockExecuter := new(executers.MockCommandExecuter)
+	mockIOHandler := new(iohandlermocks.MockIOHandler)
+	mockCancelFlag := new(task.MockCancelFlag)
+	mockService := new(ssmmocks.Service)
+	stdout := new(multiwritermock.MockDocumentIOMultiWriter)
+	stderr := new(multiwritermock.MockDocumentIOMultiWriter)
+	mockIOHandler.On("GetStdoutWriter").Return(stdout)
+	mockIOHandler.On("GetStderrWriter").Return(stderr)
+	mockExecuter.On("NewExecute", mock.Anything, stepDir, stdout, stderr, mockCancelFlag, mock.Anything, "/usr/bin/inspec",
+		[]string{"exec", "https://example.com/baseline.tar.gz", "--reporter", "cli", "json:" + resultsPath, "--chef-license", "accept-silent", "--no-color"}).
+		Run(func(mock.Arguments) {
+			ioutil.WriteFile(resultsPath, []byte(inspecResults), appconfig.ReadWriteAccess)
+		}).Return(inspecExitFailed, fmt.Errorf("exit status 100")).Once()
+	mockCancelFlag.On("Canceled").Return(false)
+	mockCancelFlag.On("ShutDown").Return(false)
+	mockService.On("PutComplianceItems", mock.Anything, mock.Anything, "", "", testInstanceID, "Custom:Baseline", mock.Anything,
+		mock.MatchedBy(func(items []*ssm.ComplianceItemEntry) bool { return len(items) == 3 })).Return(&ssm.PutComplianceItemsOutput{}, nil).Once()
+	mockIOHandler.On("SetExitCode", inspecExitFailed).Return()
+	mockIOHandler.On("AppendInfof", "Results uploaded to s3://%v/%v", []interface{}{"bucket", "scans/" + testInstanceID + "/" + inspecResultsFile}).Return()
+	mockIOHandler.On("AppendInfof", "%v reported %v: %v rules compliant, %v rules non compliant", []interface{}{ScannerInSpec, "Custom:Baseline", 2, 1}).Return()
+	mockIOHandler.On("MarkAsFailed", mock.Anything).Return()
+
+	uploader := &fakeUploader{objects: map[string]s3manager.UploadInput{}}
+	p := &Plugin{
+		CommandExecuter:   mockExecuter,
+		complianceService: func() ssmSvc.Service { return mockService },
+		uploaderCreator:   func(log log.T, bucketName string) s3manageriface.UploaderAPI { return uploader },
+	}
+	input := &ComplianceScanPluginInput{
+		ID:                  "scan",
+		Scanner:             ScannerInSpec,
+		Profile:             "https://example.com/baseline.tar.gz",
+		ComplianceType:      "Custom:Baseline",
+		BucketName:          "bucket",
+		KeyPrefix:           "scans",
+		FailOnNonCompliance: true,
+	}
+	p.runScan(logger, input, inspecScanner{}, contracts.Configuration{OrchestrationDirectory: orchestrationDir}, mockCancelFlag, mockIOHandler)
+
+	mockExecuter.AssertExpectations(t)
+	mockService.AssertExpectations(t)
+	mockIOHandler.AssertExpectations(t)
+	object, found := uploader.objects["scans/"+testInstanceID+"/"+inspecResultsFile]
+	assert.True(t, found)
+	assert.Equal(t, objectACL, aws.StringValue(object.ACL))
+}
+
+func TestRunScanFailsWhenScanDoesNotComplete(t *testing.T) {
+	orchestrationDir, err := ioutil.TempDir("", "compliancescan")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+	defer setScannerMocks()()
+	downloadsPath := filepath.Join(orchestrationDir, downloadsDir)
+	os.MkdirAll(downloadsPath, appconfig.ReadWriteExecuteAccess)
+	ioutil.WriteFile(filepath.Join(downloadsPath, "ds.xml"), []byte("<ds/>"), appconfig.ReadWriteAccess)
+
+	mockExecuter := new(executers.MockCommandExecuter)
+	mockIOHandler := new(iohandlermocks.MockIOHandler)
+	mockCancelFlag := new(task.MockCancelFlag)
+	mockService := new(ssmmocks.Service)
+	mockIOHandler.On("GetStdoutWriter").Return(new(multiwritermock.MockDocumentIOMultiWriter))
+	mockIOHandler.On("GetStderrWriter").Return(new(multiwritermock.MockDocumentIOMultiWriter))
+	mockExecuter.On("NewExecute", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mockCancelFlag, mock.Anything, "/usr/bin/oscap", mock.Anything).
+		Return(1, fmt.Errorf("exit status 1")).Once()
+	mockCancelFlag.On("Canceled").Return(false)
+	mockCancelFlag.On("ShutDown").Return(false)
+	mockIOHandler.On("SetExitCode", 1).Return()
+	mockIOHandler.On("MarkAsFailed", mock.Anything).Return()
+
+	p := &Plugin{CommandExecuter: mockExecuter, complianceService: func() ssmSvc.Service { return mockService }}
+	input := &ComplianceScanPluginInput{ID: "scan", Scanner: ScannerOpenSCAP, DataStream: "ds.xml", ProfileId: "standard", ComplianceType: "Custom:OpenSCAP"}
+	p.runScan(logger, input, openscapScanner{}, contracts.Configuration{OrchestrationDirectory: orchestrationDir}, mockCancelFlag, mockIOHandler)
+
+	mockExecuter.AssertExpectations(t)
+	mockIOHandler.AssertExpectations(t)
+	mockService.AssertNotCalled(t, "PutComplianceItems")
+}
+
+func TestRunScanFailsWhenScannerIsMissing(t *testing.T) {
+	orchestrationDir, err := ioutil.TempDir("", "compliancescan")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+	lookPath = func(file string) (string, error) { return "", fmt.Errorf("%v not found", file) }
+	defer func() { lookPath = exec.LookPath }()
+
+	mockExecuter := new(executers.MockCommandExecuter)
+	mockIOHandler := new(iohandlermocks.MockIOHandler)
+	mockIOHandler.On("MarkAsFailed", mock.Anything).Return()
+
+	p := &Plugin{CommandExecuter: mockExecuter}
+	input := &ComplianceScanPluginInput{Scanner: ScannerInSpec, Profile: "baseline"}
+	p.runScan(logger, input, inspecScanner{}, contracts.Configuration{OrchestrationDirectory: orchestrationDir}, new(task.MockCancelFlag), mockIOHandler)
+
+	mockIOHandler.AssertExpectations(t)
+	mockExecuter.AssertNotCalled(t, "NewExecute")
+}
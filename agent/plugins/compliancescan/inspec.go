@@ -0,0 +1,127 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package compliancescan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+const (
+	inspecCommand     = "inspec"
+	inspecResultsFile = "inspec-results.json"
+
+	//Exit codes of inspec exec for completed scans
+	inspecExitPassed        = 0
+	inspecExitFailed        = 100
+	inspecExitSkippedPassed = 101
+
+	//Statuses of the results of InSpec controls
+	inspecPassed = "passed"
+	inspecFailed = "failed"
+)
+
+// inspecScanner runs InSpec profiles
+type inspecScanner struct{}
+
+// inspecReport is the part of the json reporter output which is reported to Compliance
+type inspecReport struct {
+	Profiles []struct {
+		Name     string `json:"name"`
+		Controls []struct {
+			ID      string  `json:"id"`
+			Title   string  `json:"title"`
+			Impact  float64 `json:"impact"`
+			Results []struct {
+				Status string `json:"status"`
+			} `json:"results"`
+		} `json:"controls"`
+	} `json:"profiles"`
+}
+
+func (inspecScanner) command() string {
+	return inspecCommand
+}
+
+func (inspecScanner) resultsFileName() string {
+	return inspecResultsFile
+}
+
+// arguments runs the profile, the profile is left as is when it is not downloaded as it may be a URL or a supermarket profile
+func (inspecScanner) arguments(input *ComplianceScanPluginInput, downloadsPath string, resultsPath string) ([]string, error) {
+	profile := input.Profile
+	if !strings.Contains(profile, "://") {
+		if downloadedProfile := resolvePath(downloadsPath, profile); fileExists(downloadedProfile) {
+			profile = downloadedProfile
+		}
+	}
+	return []string{"exec", profile, "--reporter", "cli", "json:" + resultsPath, "--chef-license", "accept-silent", "--no-color"}, nil
+}
+
+func (inspecScanner) scanCompleted(exitCode int) bool {
+	return exitCode == inspecExitPassed || exitCode == inspecExitFailed || exitCode == inspecExitSkippedPassed
+}
+
+// parseResults reports the controls with results, a control is non compliant if any of its tests failed
+func (inspecScanner) parseResults(resultsPath string) ([]*ssm.ComplianceItemEntry, error) {
+	content, err := ioutil.ReadFile(resultsPath)
+	if err != nil {
+		return nil, err
+	}
+	var report inspecReport
+	if err = json.Unmarshal(content, &report); err != nil {
+		return nil, fmt.Errorf("invalid InSpec report: %v", err)
+	}
+
+	items := []*ssm.ComplianceItemEntry{}
+	for _, profile := range report.Profiles {
+		for _, control := range profile.Controls {
+			var passed, failed bool
+			for _, result := range control.Results {
+				passed = passed || result.Status == inspecPassed
+				failed = failed || result.Status == inspecFailed
+			}
+			status := statusCompliant
+			if failed {
+				status = statusNonCompliant
+			} else if !passed {
+				// all the tests of the control were skipped
+				continue
+			}
+			items = append(items, complianceItem(control.ID, control.Title, status, inspecSeverity(control.Impact),
+				map[string]string{"Profile": profile.Name}))
+		}
+	}
+	return items, nil
+}
+
+// inspecSeverity maps the impact of the control to the severity of the compliance item the way InSpec names impacts
+func inspecSeverity(impact float64) string {
+	switch {
+	case impact >= 0.9:
+		return severityCritical
+	case impact >= 0.7:
+		return severityHigh
+	case impact >= 0.4:
+		return severityMedium
+	case impact >= 0.1:
+		return severityLow
+	default:
+		return severityInformational
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package compliancescan
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+const (
+	oscapCommand     = "oscap"
+	oscapResultsFile = "oscap-results.xml"
+
+	//Exit codes of oscap xccdf eval for completed scans
+	oscapExitPassed = 0
+	oscapExitFailed = 2
+
+	oscapRuleResult = "rule-result"
+)
+
+// oscapStatuses maps the XCCDF results to compliance statuses, the results which are not mapped are not reported
+var oscapStatuses = map[string]string{
+	"pass":    statusCompliant,
+	"fixed":   statusCompliant,
+	"fail":    statusNonCompliant,
+	"error":   statusNonCompliant,
+	"unknown": statusNonCompliant,
+}
+
+// oscapSeverities maps the XCCDF severities to compliance severities
+var oscapSeverities = map[string]string{
+	"high":   severityHigh,
+	"medium": severityMedium,
+	"low":    severityLow,
+	"info":   severityInformational,
+}
+
+// openscapScanner evaluates XCCDF profiles of SCAP source data streams
+type openscapScanner struct{}
+
+// oscapRuleResultElement is the rule-result element of the XCCDF test result
+type oscapRuleResultElement struct {
+	IDRef    string `xml:"idref,attr"`
+	Severity string `xml:"severity,attr"`
+	Result   string `xml:"result"`
+}
+
+func (openscapScanner) command() string {
+	return oscapCommand
+}
+
+func (openscapScanner) resultsFileName() string {
+	return oscapResultsFile
+}
+
+func (openscapScanner) arguments(input *ComplianceScanPluginInput, downloadsPath string, resultsPath string) ([]string, error) {
+	dataStream := resolvePath(downloadsPath, input.DataStream)
+	if !fileExists(dataStream) {
+		return nil, fmt.Errorf("data stream %v not found", dataStream)
+	}
+	return []string{"xccdf", "eval", "--profile", input.ProfileId, "--results", resultsPath, dataStream}, nil
+}
+
+func (openscapScanner) scanCompleted(exitCode int) bool {
+	return exitCode == oscapExitPassed || exitCode == oscapExitFailed
+}
+
+// parseResults reports the rule results of the test result, the results file is streamed as it holds the whole benchmark
+func (openscapScanner) parseResults(resultsPath string) ([]*ssm.ComplianceItemEntry, error) {
+	file, err := os.Open(resultsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	items := []*ssm.ComplianceItemEntry{}
+	decoder := xml.NewDecoder(file)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid XCCDF results: %v", err)
+		}
+		element, ok := token.(xml.StartElement)
+		if !ok || element.Name.Local != oscapRuleResult {
+			continue
+		}
+		var ruleResult oscapRuleResultElement
+		if err = decoder.DecodeElement(&ruleResult, &element); err != nil {
+			return nil, fmt.Errorf("invalid XCCDF results: %v", err)
+		}
+		status, found := oscapStatuses[ruleResult.Result]
+		if !found {
+			continue
+		}
+		severity, found := oscapSeverities[ruleResult.Severity]
+		if !found {
+			severity = severityUnspecified
+		}
+		items = append(items, complianceItem(ruleResult.IDRef, "", status, severity, map[string]string{"Result": ruleResult.Result}))
+	}
+	return items, nil
+}
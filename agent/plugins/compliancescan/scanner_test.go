@@ -0,0 +1,134 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package compliancescan
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/assert"
+)
+
+const inspecResults = `{
+  "platform": {"name": "amazon", "release": "2"},
+  "profiles": [{
+    "name": "linux-baseline",
+    "controls": [
+      {"id": "os-01", "title": "Trusted hosts login", "impact": 1.0, "results": [{"status": "passed"}, {"status": "failed"}]},
+      {"id": "os-02", "title": "Check owner and permissions for /etc/shadow", "impact": 0.5, "results": [{"status": "passed"}]},
+      {"id": "os-03", "title": "", "impact": 0.0, "results": [{"status": "passed"}]},
+      {"id": "os-04", "title": "Skipped on this platform", "impact": 0.7, "results": [{"status": "skipped"}]}
+    ]
+  }]
+}`
+
+const oscapResults = `<?xml version="1.0" encoding="UTF-8"?>
+<ds:data-stream-collection xmlns:ds="http://scap.nist.gov/schema/scap/source/1.2">
+  <Benchmark xmlns="http://checklists.nist.gov/xccdf/1.2">
+    <Rule id="xccdf_org.ssgproject.content_rule_no_empty_passwords" severity="high"/>
+    <TestResult id="xccdf_org.open-scap_testresult_standard">
+      <rule-result idref="xccdf_org.ssgproject.content_rule_no_empty_passwords" severity="high" time="2019-01-01T00:00:00">
+        <result>fail</result>
+      </rule-result>
+      <rule-result idref="xccdf_org.ssgproject.content_rule_sshd_disable_root_login" severity="medium">
+        <result>pass</result>
+      </rule-result>
+      <rule-result idref="xccdf_org.ssgproject.content_rule_partition_for_tmp" severity="low">
+        <result>notapplicable</result>
+      </rule-result>
+      <rule-result idref="xccdf_org.ssgproject.content_rule_audit_rules" severity="unknown">
+        <result>error</result>
+      </rule-result>
+    </TestResult>
+  </Benchmark>
+</ds:data-stream-collection>`
+
+func writeResults(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "compliancescan")
+	assert.NoError(t, err)
+	resultsPath := filepath.Join(dir, "results")
+	assert.NoError(t, ioutil.WriteFile(resultsPath, []byte(content), appconfig.ReadWriteAccess))
+	return resultsPath, func() { os.RemoveAll(dir) }
+}
+
+func TestInSpecParseResults(t *testing.T) {
+	resultsPath, cleanup := writeResults(t, inspecResults)
+	defer cleanup()
+
+	items, err := inspecScanner{}.parseResults(resultsPath)
+	assert.NoError(t, err)
+	assert.Equal(t, []*ssm.ComplianceItemEntry{
+		complianceItem("os-01", "Trusted hosts login", statusNonCompliant, severityCritical, map[string]string{"Profile": "linux-baseline"}),
+		complianceItem("os-02", "Check owner and permissions for /etc/shadow", statusCompliant, severityMedium, map[string]string{"Profile": "linux-baseline"}),
+		complianceItem("os-03", "os-03", statusCompliant, severityInformational, map[string]string{"Profile": "linux-baseline"}),
+	}, items)
+}
+
+func TestInSpecParseInvalidResults(t *testing.T) {
+	resultsPath, cleanup := writeResults(t, "not json")
+	defer cleanup()
+
+	_, err := inspecScanner{}.parseResults(resultsPath)
+	assert.Error(t, err)
+}
+
+func TestInSpecArguments(t *testing.T) {
+	downloadsPath, err := ioutil.TempDir("", "compliancescan")
+	assert.NoError(t, err)
+	defer os.RemoveAll(downloadsPath)
+	os.MkdirAll(filepath.Join(downloadsPath, "baseline"), appconfig.ReadWriteExecuteAccess)
+
+	arguments, err := inspecScanner{}.arguments(&ComplianceScanPluginInput{Profile: "baseline"}, downloadsPath, "/results.json")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"exec", filepath.Join(downloadsPath, "baseline"), "--reporter", "cli", "json:/results.json", "--chef-license", "accept-silent", "--no-color"}, arguments)
+
+	arguments, err = inspecScanner{}.arguments(&ComplianceScanPluginInput{Profile: "https://github.com/dev-sec/linux-baseline"}, downloadsPath, "/results.json")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://github.com/dev-sec/linux-baseline", arguments[1])
+
+	arguments, err = inspecScanner{}.arguments(&ComplianceScanPluginInput{Profile: "dev-sec/linux-baseline"}, downloadsPath, "/results.json")
+	assert.NoError(t, err)
+	assert.Equal(t, "dev-sec/linux-baseline", arguments[1])
+}
+
+func TestInSpecSeverity(t *testing.T) {
+	assert.Equal(t, severityCritical, inspecSeverity(0.9))
+	assert.Equal(t, severityHigh, inspecSeverity(0.7))
+	assert.Equal(t, severityMedium, inspecSeverity(0.4))
+	assert.Equal(t, severityLow, inspecSeverity(0.1))
+	assert.Equal(t, severityInformational, inspecSeverity(0))
+}
+
+func TestOpenSCAPParseResults(t *testing.T) {
+	resultsPath, cleanup := writeResults(t, oscapResults)
+	defer cleanup()
+
+	items, err := openscapScanner{}.parseResults(resultsPath)
+	assert.NoError(t, err)
+	assert.Len(t, items, 3)
+	assert.Equal(t, complianceItem("xccdf_org.ssgproject.content_rule_no_empty_passwords", "", statusNonCompliant, severityHigh, map[string]string{"Result": "fail"}), items[0])
+	assert.Equal(t, complianceItem("xccdf_org.ssgproject.content_rule_sshd_disable_root_login", "", statusCompliant, severityMedium, map[string]string{"Result": "pass"}), items[1])
+	assert.Equal(t, complianceItem("xccdf_org.ssgproject.content_rule_audit_rules", "", statusNonCompliant, severityUnspecified, map[string]string{"Result": "error"}), items[2])
+	assert.Equal(t, "xccdf_org.ssgproject.content_rule_audit_rules", aws.StringValue(items[2].Title))
+}
+
+func TestOpenSCAPArgumentsRequireDataStream(t *testing.T) {
+	_, err := openscapScanner{}.arguments(&ComplianceScanPluginInput{DataStream: "missing-ds.xml", ProfileId: "standard"}, os.TempDir(), "/results.xml")
+	assert.Error(t, err)
+}
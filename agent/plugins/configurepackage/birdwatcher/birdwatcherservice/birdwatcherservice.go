@@ -17,6 +17,7 @@ package birdwatcherservice
 import (
 	"errors"
 	"fmt"
+	"os"
 	"reflect"
 	"strings"
 	"time"
@@ -99,25 +100,46 @@ func (ds *PackageService) DownloadManifest(tracer trace.Tracer, packageName stri
 
 // DownloadArtifact downloads the platform matching artifact specified in the manifest
 func (ds *PackageService) DownloadArtifact(tracer trace.Tracer, packageName string, version string) (string, error) {
-	trace := tracer.BeginSection("download artifact")
-	manifest, err := ds.packageArchive.ReadManifestFromCache(packageName, version)
+	file, err := ds.findArtifactFile(tracer, "download artifact", packageName, version)
 	if err != nil {
-		trace.AppendInfof("error when reading the manifest from cache %v", err)
-		manifest, _, err = downloadManifest(tracer, ds, packageName, version)
-		if err != nil {
-			trace.WithError(err).End()
-			return "", fmt.Errorf("failed to download the manifest: %v", err)
-		}
+		return "", err
 	}
+	return downloadFile(ds, tracer, file, packageName, version)
+}
 
-	file, err := ds.findFileFromManifest(tracer, manifest)
+// DownloadArtifactPatch downloads the patch of the platform matching artifact from one of the base versions
+func (ds *PackageService) DownloadArtifactPatch(tracer trace.Tracer, packageName string, version string, baseVersions []string) (packageservice.Patch, error) {
+	file, err := ds.findArtifactFile(tracer, "download artifact patch", packageName, version)
 	if err != nil {
-		trace.WithError(err).End()
-		return "", err
+		return packageservice.Patch{}, err
 	}
 
-	trace.End()
-	return downloadFile(ds, tracer, file, packageName, version)
+	for _, baseVersion := range baseVersions {
+		patchInfo, found := file.Info.Patches[baseVersion]
+		if !found || patchInfo == nil {
+			continue
+		}
+		patchFile := &archive.File{
+			Name: patchInfo.FileName,
+			Info: birdwatcher.FileInfo{
+				Checksums:        patchInfo.Checksums,
+				DownloadLocation: patchInfo.DownloadLocation,
+				Size:             patchInfo.Size,
+			},
+		}
+		filePath, err := downloadFile(ds, tracer, patchFile, packageName, version)
+		if err != nil {
+			return packageservice.Patch{}, err
+		}
+		return packageservice.Patch{
+			FilePath:    filePath,
+			Format:      patchInfo.Format,
+			BaseVersion: baseVersion,
+			Checksums:   file.Info.Checksums,
+			Signature:   file.Info.Signature,
+		}, nil
+	}
+	return packageservice.Patch{}, fmt.Errorf("no patch of %v %v from versions %v", packageName, version, baseVersions)
 }
 
 // ReportResult sents back the result of the install/upgrade/uninstall run back to Birdwatcher
@@ -204,6 +226,29 @@ func downloadManifest(tracer trace.Tracer, ds *PackageService, packageName strin
 	return parsedManifest, isSameAsCache, nil
 }
 
+// findArtifactFile returns the platform matching file of the manifest of the package version
+func (ds *PackageService) findArtifactFile(tracer trace.Tracer, section string, packageName string, version string) (*archive.File, error) {
+	trace := tracer.BeginSection(section)
+	manifest, err := ds.packageArchive.ReadManifestFromCache(packageName, version)
+	if err != nil {
+		trace.AppendInfof("error when reading the manifest from cache %v", err)
+		manifest, _, err = downloadManifest(tracer, ds, packageName, version)
+		if err != nil {
+			trace.WithError(err).End()
+			return nil, fmt.Errorf("failed to download the manifest: %v", err)
+		}
+	}
+
+	file, err := ds.findFileFromManifest(tracer, manifest)
+	if err != nil {
+		trace.WithError(err).End()
+		return nil, err
+	}
+
+	trace.End()
+	return file, nil
+}
+
 func (ds *PackageService) findFileFromManifest(tracer trace.Tracer, manifest *birdwatcher.Manifest) (*archive.File, error) {
 	var fileInfo *birdwatcher.FileInfo
 	var file archive.File
@@ -239,6 +284,11 @@ func downloadFile(ds *PackageService, tracer trace.Tracer, file *archive.File, p
 	if err != nil {
 		return "", err
 	}
+	if file.Info.Signature != nil {
+		if err = artifact.ValidateSignedChecksums(file.Info.Checksums); err != nil {
+			return "", err
+		}
+	}
 	downloadInput := artifact.DownloadInput{
 		SourceURL: sourceUrl,
 		// TODO don't hardcode sha256 - use multiple checksums
@@ -258,6 +308,14 @@ func downloadFile(ds *PackageService, tracer trace.Tracer, file *archive.File, p
 		return "", errors.New(errMessage)
 	}
 
+	if file.Info.Signature != nil {
+		if err = artifact.VerifySignature(log, downloadOutput.LocalFilePath, *file.Info.Signature); err != nil {
+			// remove the file so that it is not used from the download cache
+			os.Remove(downloadOutput.LocalFilePath)
+			return "", err
+		}
+	}
+
 	return downloadOutput.LocalFilePath, nil
 }
 
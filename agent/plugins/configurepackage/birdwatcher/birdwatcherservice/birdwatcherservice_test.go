@@ -1240,3 +1240,61 @@ func TestDownloadArtifact(t *testing.T) {
 		})
 	}
 }
+
+func TestDownloadArtifactPatch(t *testing.T) {
+	manifestStr := `
+	{
+		"packages": {
+			"platformName": {
+				"platformVersion": {
+					"architecture": {
+						"file": "test.zip"
+					}
+				}
+			}
+		},
+		"files": {
+			"test.zip": {
+				"checksums": {"sha256": "artifacthash"},
+				"downloadLocation": "https://example.com/agent",
+				"patches": {
+					"1.0.0": {
+						"format": "zstd",
+						"checksums": {"sha256": "patchhash"},
+						"downloadLocation": "https://example.com/agent-1.0.0.patch"
+					}
+				}
+			}
+		}
+	}
+	`
+	tracer := trace.NewTracer(log.NewMockLog())
+	tracer.BeginSection("test segment root")
+
+	cache := packageservice.ManifestCacheMemNew()
+	cache.WriteManifest("packageName", "2.0.0", []byte(manifestStr))
+	context := map[string]string{"packageName": "packageName", "packageVersion": "2.0.0", "manifest": manifestStr}
+	testArchive := birdwatcherarchive.New(&facade.FacadeStub{}, context)
+	testArchive.SetManifestCache(cache)
+	mockedCollector := envdetect.CollectorMock{}
+	mockedCollector.On("CollectData", mock.Anything).Return(&envdetect.Environment{
+		&osdetect.OperatingSystem{"platformName", "platformVersion", "", "architecture", "", ""},
+		&ec2infradetect.Ec2Infrastructure{"instanceID", "region", "", "availabilityZone", "instanceType"},
+	}, nil)
+	ds := &PackageService{manifestCache: cache, collector: &mockedCollector, packageArchive: testArchive}
+	network := networkMock{downloadOutput: artifact.DownloadOutput{LocalFilePath: "agent.patch"}}
+	birdwatcher.Networkdep = &network
+
+	patch, err := ds.DownloadArtifactPatch(tracer, "packageName", "2.0.0", []string{"0.9.0", "1.0.0"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "agent.patch", patch.FilePath)
+	assert.Equal(t, "zstd", patch.Format)
+	assert.Equal(t, "1.0.0", patch.BaseVersion)
+	assert.Equal(t, map[string]string{"sha256": "artifacthash"}, patch.Checksums)
+	assert.Equal(t, "https://example.com/agent-1.0.0.patch", network.downloadInput.SourceURL)
+	assert.Equal(t, map[string]string{"sha256": "patchhash"}, network.downloadInput.SourceChecksums)
+
+	_, err = ds.DownloadArtifactPatch(tracer, "packageName", "2.0.0", []string{"0.9.0"})
+	assert.Error(t, err)
+}
@@ -19,9 +19,11 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	retry "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher/facade/retryer"
+	"github.com/aws/amazon-ssm-agent/agent/rip"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/amazon-ssm-agent/agent/version"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -44,7 +46,8 @@ func NewBirdwatcherFacade() BirdwatcherFacade {
 	cfg := request.WithRetryer(awsConfig, retryer)
 
 	// overrides ssm client config from appconfig if applicable
-	if appCfg, err := appconfig.Config(false); err == nil {
+	appCfg, appCfgErr := appconfig.Config(false)
+	if appCfgErr == nil {
 		if appCfg.Ssm.Endpoint != "" {
 			cfg.Endpoint = &appCfg.Ssm.Endpoint
 		} else {
@@ -59,6 +62,9 @@ func NewBirdwatcherFacade() BirdwatcherFacade {
 		}
 	}
 	facadeClientSession := session.New(cfg)
+	if appCfgErr == nil && appCfg.Ssm.Endpoint == "" {
+		rip.UseFipsEndpoint(facadeClientSession, aws.StringValue(cfg.Region), "ssm")
+	}
 
 	// Define a request handler with current agentName and version
 	SSMAgentVersionUserAgentHandler := request.NamedHandler{
@@ -0,0 +1,39 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package facade
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/rip"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBirdwatcherFacadeUsesFipsEndpoint(t *testing.T) {
+	fipsEnabled := rip.FipsEnabled
+	rip.FipsEnabled = func() bool { return true }
+	defer func() { rip.FipsEnabled = fipsEnabled }()
+
+	platform.SetRegion("us-west-2")
+	assert.Equal(t, "https://ssm-fips.us-west-2.amazonaws.com", NewBirdwatcherFacade().(*ssm.SSM).Endpoint)
+
+	// regions without FIPS endpoints fail instead of using the regular endpoint
+	platform.SetRegion("eu-west-1")
+	_, err := NewBirdwatcherFacade().GetManifest(&ssm.GetManifestInput{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no FIPS endpoint")
+}
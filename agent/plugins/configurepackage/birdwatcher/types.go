@@ -14,8 +14,26 @@
 
 package birdwatcher
 
+import "github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
+
 // FileInfo contains data for one SSM package
 type FileInfo struct {
+	// Checksums are the checksums of the file by algorithm, e.g. sha256, sha384 or sha512
+	Checksums        map[string]string   `json:"checksums"`
+	DownloadLocation string              `json:"downloadLocation"`
+	Size             int                 `json:"size"`
+	Signature        *artifact.Signature `json:"signature"`
+	// Patches are the binary patches creating the file from the file of earlier versions of the package, by version
+	Patches map[string]*PatchInfo `json:"patches"`
+}
+
+// PatchInfo contains data for a binary patch from the file of an earlier package version
+type PatchInfo struct {
+	// Format is the tool the patch is applied with, bsdiff or zstd
+	Format string `json:"format"`
+	// FileName is the name of the patch attachment of document packages
+	FileName string `json:"file"`
+	// Checksums are the checksums of the patch by algorithm
 	Checksums        map[string]string `json:"checksums"`
 	DownloadLocation string            `json:"downloadLocation"`
 	Size             int               `json:"size"`
@@ -31,6 +31,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher/facade"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/installer"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packagecache"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/ssms3"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
@@ -380,6 +381,20 @@ func checkAlreadyInstalled(
 
 // selectService chooses the implementation of PackageService to use for a given execution of the plugin
 func selectService(tracer trace.Tracer, input *ConfigurePackagePluginInput, localrepo localpackages.Repository, appCfg *appconfig.SsmagentConfig, birdwatcherFacade facade.BirdwatcherFacade, isDocumentArchive *bool) (packageservice.PackageService, error) {
+	if appCfg != nil && appCfg.Birdwatcher.Offline {
+		tracer.CurrentTrace().AppendInfof("Offline mode is enabled, installing packages from the package cache")
+		return packagecache.NewOffline(appCfg.Birdwatcher), nil
+	}
+
+	service, err := selectOnlineService(tracer, input, localrepo, appCfg, birdwatcherFacade, isDocumentArchive)
+	if err != nil || appCfg == nil || !appCfg.Birdwatcher.PackageCacheEnabled {
+		return service, err
+	}
+	return packagecache.New(service, appCfg.Birdwatcher), nil
+}
+
+// selectOnlineService chooses the implementation of PackageService downloading packages from the package repository
+func selectOnlineService(tracer trace.Tracer, input *ConfigurePackagePluginInput, localrepo localpackages.Repository, appCfg *appconfig.SsmagentConfig, birdwatcherFacade facade.BirdwatcherFacade, isDocumentArchive *bool) (packageservice.PackageService, error) {
 	region, _ := platform.Region()
 	serviceEndpoint := input.Repository
 	response := &ssm.GetManifestOutput{}
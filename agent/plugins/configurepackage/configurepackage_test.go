@@ -28,6 +28,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher/facade"
 	facadeMock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/birdwatcher/facade/mocks"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/localpackages"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packagecache"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
 
@@ -781,6 +782,29 @@ func TestSelectService(t *testing.T) {
 	}
 }
 
+func TestSelectService_PackageCache(t *testing.T) {
+	tracer := trace.NewTracer(contextMock.Log())
+	defer tracer.BeginSection("test").End()
+	isDocumentArchive := false
+	localRepo := localpackages.NewRepository()
+	input := &ConfigurePackagePluginInput{Name: "package", Version: "1.2.3.4"}
+	manifest := "manifest"
+	bwfacade := &facade.FacadeStub{GetManifestOutput: &ssm.GetManifestOutput{Manifest: &manifest}}
+
+	appConfig := appconfig.SsmagentConfig{
+		Birdwatcher: appconfig.BirdwatcherCfg{ForceEnable: true, PackageCacheEnabled: true},
+	}
+	result, err := selectService(tracer, input, localRepo, &appConfig, bwfacade, &isDocumentArchive)
+	assert.NoError(t, err)
+	assert.IsType(t, &packagecache.PackageService{}, result)
+	assert.Equal(t, packageservice.PackageServiceName_birdwatcher, result.PackageServiceName())
+
+	appConfig.Birdwatcher.Offline = true
+	result, err = selectService(tracer, input, localRepo, &appConfig, bwfacade, &isDocumentArchive)
+	assert.NoError(t, err)
+	assert.Equal(t, packagecache.PackageServiceName_offline, result.PackageServiceName())
+}
+
 // Integration tests
 func loadFile(t *testing.T, fileName string) (result []byte) {
 	result, err := ioutil.ReadFile(fileName)
@@ -0,0 +1,264 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package packagecache implements a PackageService keeping downloaded packages on disk, so that repeated installs
+// do not download them again and updates can be downloaded as binary patches of a cached version, and installing
+// packages from the cache or a mirror directory when the instance is offline.
+//
+// The cache and mirror directories have the layout
+//
+//	<package name>/<version>/package.zip   the package artifact
+//	<package name>/<version>.json          the package arn and version resolved for the requested version, e.g. latest.json
+//
+// where characters of package names other than letters, digits, '.', '-' and '_' are replaced by '_'.
+package packagecache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+)
+
+const (
+	// PackageServiceName_offline is the name of the package service installing packages offline
+	PackageServiceName_offline = "offline"
+
+	artifactFileName = "package.zip"
+	// resolutionExtension is the extension of the files recording the package version a requested version resolved to
+	resolutionExtension = ".json"
+)
+
+var unsafeNameCharacters = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// resolution is the package arn and version a requested package version resolved to
+type resolution struct {
+	PackageArn string `json:"packageArn"`
+	Version    string `json:"version"`
+}
+
+// PackageService keeps the packages of the wrapped service in the cache directory, or installs them from the
+// cache and mirror directories when it has no service
+type PackageService struct {
+	service         packageservice.PackageService
+	cacheDirectory  string
+	mirrorDirectory string
+}
+
+// New returns a PackageService caching the packages downloaded by the service
+func New(service packageservice.PackageService, config appconfig.BirdwatcherCfg) packageservice.PackageService {
+	return &PackageService{
+		service:        service,
+		cacheDirectory: cacheDirectory(config),
+	}
+}
+
+// NewOffline returns a PackageService installing packages from the cache or the mirror directory
+func NewOffline(config appconfig.BirdwatcherCfg) packageservice.PackageService {
+	return &PackageService{
+		cacheDirectory:  cacheDirectory(config),
+		mirrorDirectory: config.PackageMirrorDirectory,
+	}
+}
+
+// cacheDirectory returns the configured directory of the package cache
+func cacheDirectory(config appconfig.BirdwatcherCfg) string {
+	if config.PackageCacheDirectory != "" {
+		return config.PackageCacheDirectory
+	}
+	return appconfig.PackageCacheRoot
+}
+
+// PackageServiceName returns the name of the wrapped service, or offline
+func (ps *PackageService) PackageServiceName() string {
+	if ps.service == nil {
+		return PackageServiceName_offline
+	}
+	return ps.service.PackageServiceName()
+}
+
+// GetPackageArnAndVersion returns the package name and version the service looks for
+func (ps *PackageService) GetPackageArnAndVersion(packageName string, version string) (string, string) {
+	if ps.service == nil {
+		return packageName, version
+	}
+	return ps.service.GetPackageArnAndVersion(packageName, version)
+}
+
+// DownloadManifest resolves the package version with the service and records it, or reads the recorded version when offline
+func (ps *PackageService) DownloadManifest(tracer trace.Tracer, packageName string, version string) (string, string, bool, error) {
+	if ps.service == nil {
+		found, err := ps.findResolution(packageName, version)
+		if err != nil {
+			return "", "", false, err
+		}
+		tracer.CurrentTrace().AppendInfof("offline: installing %v %v from the package cache", found.PackageArn, found.Version)
+		return found.PackageArn, found.Version, true, nil
+	}
+
+	packageArn, manifestVersion, isSameAsCache, err := ps.service.DownloadManifest(tracer, packageName, version)
+	if err != nil {
+		return packageArn, manifestVersion, isSameAsCache, err
+	}
+	if !isSameAsCache {
+		// the package of the version may have changed with its manifest
+		os.Remove(ps.artifactPath(ps.cacheDirectory, packageArn, manifestVersion))
+	}
+	content, _ := json.Marshal(resolution{PackageArn: packageArn, Version: manifestVersion})
+	if err := writeFile(ps.resolutionPath(ps.cacheDirectory, packageName, version), content); err != nil {
+		tracer.CurrentTrace().AppendErrorf("failed to record the package version in the package cache: %v", err)
+	}
+	return packageArn, manifestVersion, isSameAsCache, nil
+}
+
+// DownloadArtifact returns a copy of the cached package, downloading it with the service if it is not cached
+func (ps *PackageService) DownloadArtifact(tracer trace.Tracer, packageName string, version string) (string, error) {
+	for _, directory := range ps.directories() {
+		cachedPath := ps.artifactPath(directory, packageName, version)
+		if !fileutil.Exists(cachedPath) {
+			continue
+		}
+		tracer.CurrentTrace().AppendInfof("using package %v %v from %v", packageName, version, directory)
+		// the returned file is deleted once it is extracted
+		return copyToTempFile(cachedPath)
+	}
+	if ps.service == nil {
+		return "", fmt.Errorf("package %v %v is not in the package cache or mirror directory", packageName, version)
+	}
+
+	if patchService, ok := ps.service.(packageservice.PatchService); ok {
+		// large packages only transfer the changes from a cached version
+		filePath, err := ps.downloadPatchedArtifact(tracer, patchService, packageName, version)
+		if err == nil {
+			if err = copyFile(filePath, ps.artifactPath(ps.cacheDirectory, packageName, version)); err != nil {
+				tracer.CurrentTrace().AppendErrorf("failed to add the package to the package cache: %v", err)
+			}
+			return filePath, nil
+		}
+		tracer.CurrentTrace().AppendInfof("downloading the full package: %v", err)
+	}
+
+	filePath, err := ps.service.DownloadArtifact(tracer, packageName, version)
+	if err != nil {
+		return filePath, err
+	}
+	if err := copyFile(filePath, ps.artifactPath(ps.cacheDirectory, packageName, version)); err != nil {
+		tracer.CurrentTrace().AppendErrorf("failed to add the package to the package cache: %v", err)
+	}
+	return filePath, nil
+}
+
+// ReportResult reports the result with the service, results are not reported when offline
+func (ps *PackageService) ReportResult(tracer trace.Tracer, result packageservice.PackageResult) error {
+	if ps.service == nil {
+		return nil
+	}
+	return ps.service.ReportResult(tracer, result)
+}
+
+// directories returns the directories packages are looked for in
+func (ps *PackageService) directories() []string {
+	if ps.mirrorDirectory == "" {
+		return []string{ps.cacheDirectory}
+	}
+	return []string{ps.cacheDirectory, ps.mirrorDirectory}
+}
+
+// findResolution returns the recorded package arn and version of the requested version.
+// Without a record, a requested version is used if its package exists, and latest is the only version of the package.
+func (ps *PackageService) findResolution(packageName string, version string) (found resolution, err error) {
+	for _, directory := range ps.directories() {
+		if content, err := ioutil.ReadFile(ps.resolutionPath(directory, packageName, version)); err == nil {
+			if err = json.Unmarshal(content, &found); err != nil {
+				return found, fmt.Errorf("invalid package version record of %v %v in %v: %v", packageName, version, directory, err)
+			}
+			return found, nil
+		}
+	}
+
+	for _, directory := range ps.directories() {
+		if !packageservice.IsLatest(version) {
+			if fileutil.Exists(ps.artifactPath(directory, packageName, version)) {
+				return resolution{PackageArn: packageName, Version: version}, nil
+			}
+			continue
+		}
+		versions, _ := fileutil.GetDirectoryNames(filepath.Join(directory, normalizeName(packageName)))
+		if len(versions) == 1 {
+			return resolution{PackageArn: packageName, Version: versions[0]}, nil
+		} else if len(versions) > 1 {
+			return found, fmt.Errorf("package %v has %v versions in %v, the version to install must be specified", packageName, len(versions), directory)
+		}
+	}
+	return found, fmt.Errorf("package %v %v is not in the package cache or mirror directory", packageName, version)
+}
+
+// artifactPath returns the path of the package of the version in the directory
+func (ps *PackageService) artifactPath(directory string, packageArn string, version string) string {
+	return filepath.Join(directory, normalizeName(packageArn), normalizeName(version), artifactFileName)
+}
+
+// resolutionPath returns the path of the record of the package arn and version the requested version resolved to
+func (ps *PackageService) resolutionPath(directory string, packageName string, version string) string {
+	if packageservice.IsLatest(version) {
+		version = packageservice.Latest
+	}
+	return filepath.Join(directory, normalizeName(packageName), normalizeName(version)+resolutionExtension)
+}
+
+// normalizeName replaces the characters of package names and versions that cannot be used in file names
+func normalizeName(name string) string {
+	return unsafeNameCharacters.ReplaceAllString(name, "_")
+}
+
+// copyToTempFile copies the file to a new temporary file and returns its path
+func copyToTempFile(source string) (string, error) {
+	file, err := ioutil.TempFile("", "package")
+	if err != nil {
+		return "", err
+	}
+	file.Close()
+	if err = copyFile(source, file.Name()); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// copyFile copies the file through a temporary file, so that an interrupted copy does not leave a partial file
+func copyFile(source string, destination string) error {
+	content, err := ioutil.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	return writeFile(destination, content)
+}
+
+// writeFile writes the content to the file through a temporary file in the same directory
+func writeFile(path string, content []byte) error {
+	if err := fileutil.MakeDirs(filepath.Dir(path)); err != nil {
+		return err
+	}
+	temp := path + ".tmp"
+	if err := ioutil.WriteFile(temp, content, appconfig.ReadWriteAccess); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
@@ -0,0 +1,153 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package packagecache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice"
+	packageservice_mock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice/mock"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+	"github.com/stretchr/testify/assert"
+)
+
+const packageArn = "arn:aws:ssm:us-east-1::package/AWSPVDriver"
+
+// writeDownload writes a downloaded package to a temporary file, as the package services do
+func writeDownload(t *testing.T, content string) string {
+	file, err := ioutil.TempFile("", "download")
+	assert.NoError(t, err)
+	file.WriteString(content)
+	file.Close()
+	return file.Name()
+}
+
+func readFile(path string) string {
+	content, _ := ioutil.ReadFile(path)
+	return string(content)
+}
+
+func TestPackageService_CachesDownloads(t *testing.T) {
+	cacheDir, _ := ioutil.TempDir("", "packagecache")
+	defer os.RemoveAll(cacheDir)
+	tracer := trace.NewTracer(log.NewMockLog())
+	defer tracer.BeginSection("test").End()
+
+	service := &packageservice_mock.Mock{}
+	service.On("DownloadManifest", tracer, "AWSPVDriver", "").Return(packageArn, "1.0.0", false, nil).Once()
+	download := writeDownload(t, "package")
+	defer os.Remove(download)
+	service.On("DownloadArtifact", tracer, packageArn, "1.0.0").Return(download, nil).Once()
+
+	cache := New(service, appconfig.BirdwatcherCfg{PackageCacheEnabled: true, PackageCacheDirectory: cacheDir})
+	arn, version, _, err := cache.DownloadManifest(tracer, "AWSPVDriver", "")
+	assert.NoError(t, err)
+	assert.Equal(t, packageArn, arn)
+	assert.Equal(t, "1.0.0", version)
+
+	filePath, err := cache.DownloadArtifact(tracer, arn, version)
+	assert.NoError(t, err)
+	assert.Equal(t, download, filePath)
+
+	// the second download is served from the cache, as a copy which can be deleted after extraction
+	filePath, err = cache.DownloadArtifact(tracer, arn, version)
+	assert.NoError(t, err)
+	defer os.Remove(filePath)
+	assert.NotEqual(t, download, filePath)
+	assert.Equal(t, "package", readFile(filePath))
+	assert.Equal(t, "package", readFile(filepath.Join(cacheDir, "arn_aws_ssm_us-east-1__package_AWSPVDriver", "1.0.0", artifactFileName)))
+	assert.True(t, fileExists(filepath.Join(cacheDir, "AWSPVDriver", "latest.json")))
+	service.AssertExpectations(t)
+}
+
+func TestPackageService_ChangedManifestInvalidatesCache(t *testing.T) {
+	cacheDir, _ := ioutil.TempDir("", "packagecache")
+	defer os.RemoveAll(cacheDir)
+	tracer := trace.NewTracer(log.NewMockLog())
+	defer tracer.BeginSection("test").End()
+
+	cache := New(nil, appconfig.BirdwatcherCfg{PackageCacheDirectory: cacheDir}).(*PackageService)
+	cached := cache.artifactPath(cacheDir, packageArn, "1.0.0")
+	assert.NoError(t, writeFile(cached, []byte("stale")))
+
+	service := &packageservice_mock.Mock{}
+	service.On("DownloadManifest", tracer, "AWSPVDriver", "1.0.0").Return(packageArn, "1.0.0", false, nil)
+	cache.service = service
+	_, _, _, err := cache.DownloadManifest(tracer, "AWSPVDriver", "1.0.0")
+
+	assert.NoError(t, err)
+	assert.False(t, fileExists(cached))
+}
+
+func TestPackageService_Offline(t *testing.T) {
+	cacheDir, _ := ioutil.TempDir("", "packagecache")
+	defer os.RemoveAll(cacheDir)
+	mirrorDir, _ := ioutil.TempDir("", "packagemirror")
+	defer os.RemoveAll(mirrorDir)
+	tracer := trace.NewTracer(log.NewMockLog())
+	defer tracer.BeginSection("test").End()
+
+	offline := NewOffline(appconfig.BirdwatcherCfg{Offline: true, PackageCacheDirectory: cacheDir, PackageMirrorDirectory: mirrorDir}).(*PackageService)
+	assert.NoError(t, writeFile(offline.artifactPath(mirrorDir, "CustomPackage", "2.0.0"), []byte("mirrored")))
+	assert.Equal(t, PackageServiceName_offline, offline.PackageServiceName())
+
+	// latest resolves to the only version of the package
+	arn, version, isSameAsCache, err := offline.DownloadManifest(tracer, "CustomPackage", packageservice.Latest)
+	assert.NoError(t, err)
+	assert.Equal(t, "CustomPackage", arn)
+	assert.Equal(t, "2.0.0", version)
+	assert.True(t, isSameAsCache)
+
+	filePath, err := offline.DownloadArtifact(tracer, arn, version)
+	assert.NoError(t, err)
+	defer os.Remove(filePath)
+	assert.Equal(t, "mirrored", readFile(filePath))
+
+	_, _, _, err = offline.DownloadManifest(tracer, "CustomPackage", "3.0.0")
+	assert.Error(t, err)
+	_, err = offline.DownloadArtifact(tracer, "CustomPackage", "3.0.0")
+	assert.Error(t, err)
+	assert.NoError(t, offline.ReportResult(tracer, packageservice.PackageResult{}))
+}
+
+func TestPackageService_OfflineRecordedVersion(t *testing.T) {
+	cacheDir, _ := ioutil.TempDir("", "packagecache")
+	defer os.RemoveAll(cacheDir)
+	tracer := trace.NewTracer(log.NewMockLog())
+	defer tracer.BeginSection("test").End()
+
+	offline := NewOffline(appconfig.BirdwatcherCfg{PackageCacheDirectory: cacheDir}).(*PackageService)
+	assert.NoError(t, writeFile(offline.artifactPath(cacheDir, packageArn, "1.0.0"), []byte("package")))
+	assert.NoError(t, writeFile(offline.artifactPath(cacheDir, packageArn, "1.1.0"), []byte("package")))
+	assert.NoError(t, writeFile(offline.resolutionPath(cacheDir, "AWSPVDriver", ""), []byte(`{"packageArn": "`+packageArn+`", "version": "1.1.0"}`)))
+
+	arn, version, _, err := offline.DownloadManifest(tracer, "AWSPVDriver", "")
+	assert.NoError(t, err)
+	assert.Equal(t, packageArn, arn)
+	assert.Equal(t, "1.1.0", version)
+
+	// without a record latest is ambiguous when several versions are cached
+	_, _, _, err = offline.DownloadManifest(tracer, packageArn, "")
+	assert.Error(t, err)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
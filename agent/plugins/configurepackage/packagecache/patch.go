@@ -0,0 +1,132 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package packagecache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+)
+
+const (
+	// PatchFormatBsdiff is a patch created with bsdiff and applied with bspatch
+	PatchFormatBsdiff = "bsdiff"
+	// PatchFormatZstd is a patch created with zstd --patch-from and applied with zstd -d --patch-from
+	PatchFormatZstd = "zstd"
+
+	bspatchCommand = "bspatch"
+	zstdCommand    = "zstd"
+)
+
+// Assign method to global variables to allow unittest to override
+var runPatcher = patcherCommand
+
+// downloadPatchedArtifact creates the package artifact by applying a patch to the cached artifact of an earlier version
+func (ps *PackageService) downloadPatchedArtifact(tracer trace.Tracer, patchService packageservice.PatchService, packageName string, version string) (string, error) {
+	baseVersions := ps.cachedVersions(packageName, version)
+	if len(baseVersions) == 0 {
+		return "", fmt.Errorf("no earlier version of %v is cached", packageName)
+	}
+
+	patch, err := patchService.DownloadArtifactPatch(tracer, packageName, version, baseVersions)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(patch.FilePath)
+
+	file, err := ioutil.TempFile("", "package")
+	if err != nil {
+		return "", err
+	}
+	file.Close()
+	filePath := file.Name()
+
+	log := tracer.CurrentTrace().Logger
+	basePath := ps.artifactPath(ps.cacheDirectory, packageName, patch.BaseVersion)
+	tracer.CurrentTrace().AppendInfof("applying %v patch from %v to %v %v", patch.Format, patch.BaseVersion, packageName, version)
+	if err = applyPatch(patch.Format, basePath, patch.FilePath, filePath); err == nil {
+		err = verifyPatchedArtifact(tracer, filePath, patch)
+	}
+	if err != nil {
+		os.Remove(filePath)
+		log.Debugf("failed to create %v %v from the patch: %v", packageName, version, err)
+		return "", err
+	}
+	return filePath, nil
+}
+
+// cachedVersions returns the versions of the package whose artifact is cached, other than the version
+func (ps *PackageService) cachedVersions(packageName string, version string) (versions []string) {
+	directories, _ := fileutil.GetDirectoryNames(filepath.Join(ps.cacheDirectory, normalizeName(packageName)))
+	for _, directory := range directories {
+		if directory != normalizeName(version) && fileutil.Exists(ps.artifactPath(ps.cacheDirectory, packageName, directory)) {
+			versions = append(versions, directory)
+		}
+	}
+	return versions
+}
+
+// applyPatch creates the file by applying the patch to the base file
+func applyPatch(format string, basePath string, patchPath string, filePath string) error {
+	switch format {
+	case PatchFormatBsdiff:
+		return runPatcher(bspatchCommand, basePath, filePath, patchPath)
+	case PatchFormatZstd:
+		// --long=31 allows the large windows patches of multi-gigabyte files are created with
+		return runPatcher(zstdCommand, "--quiet", "--force", "--decompress", "--long=31", "--patch-from="+basePath, patchPath, "-o", filePath)
+	default:
+		return fmt.Errorf("unsupported patch format %v", format)
+	}
+}
+
+// verifyPatchedArtifact verifies the checksums and signature of the artifact created by the patch
+func verifyPatchedArtifact(tracer trace.Tracer, filePath string, patch packageservice.Patch) error {
+	log := tracer.CurrentTrace().Logger
+	if len(patch.Checksums) == 0 {
+		return fmt.Errorf("the package has no checksums to verify the patched artifact with")
+	}
+	if patch.Signature != nil {
+		if err := artifact.ValidateSignedChecksums(patch.Checksums); err != nil {
+			return err
+		}
+	}
+	if matched, err := artifact.VerifyHash(log, artifact.DownloadInput{SourceChecksums: patch.Checksums}, artifact.DownloadOutput{LocalFilePath: filePath}); err != nil {
+		return err
+	} else if !matched {
+		return fmt.Errorf("the patched artifact has no checksum of a supported algorithm")
+	}
+	if patch.Signature != nil {
+		return artifact.VerifySignature(log, filePath, *patch.Signature)
+	}
+	return nil
+}
+
+// patcherCommand runs the patch command, returning an error including its output if it fails
+func patcherCommand(name string, arguments ...string) error {
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("%v must be installed to apply package patches", name)
+	}
+	if out, err := exec.Command(name, arguments...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%v %v", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
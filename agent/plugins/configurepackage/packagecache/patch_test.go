@@ -0,0 +1,133 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package packagecache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice"
+	packageservice_mock "github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/packageservice/mock"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
+	"github.com/stretchr/testify/assert"
+)
+
+// patchServiceMock is a package service which can download patches
+type patchServiceMock struct {
+	packageservice_mock.Mock
+}
+
+func (ps *patchServiceMock) DownloadArtifactPatch(tracer trace.Tracer, packageName string, version string, baseVersions []string) (packageservice.Patch, error) {
+	args := ps.Called(tracer, packageName, version, baseVersions)
+	return args.Get(0).(packageservice.Patch), args.Error(1)
+}
+
+func sha256Hash(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
+}
+
+func TestPackageService_DownloadsZstdPatch(t *testing.T) {
+	if _, err := exec.LookPath(zstdCommand); err != nil {
+		t.Skip("zstd is not installed")
+	}
+	cacheDir, _ := ioutil.TempDir("", "packagecache")
+	defer os.RemoveAll(cacheDir)
+	tracer := trace.NewTracer(log.NewMockLog())
+	defer tracer.BeginSection("test").End()
+
+	cache := New(nil, appconfig.BirdwatcherCfg{PackageCacheDirectory: cacheDir}).(*PackageService)
+	base := strings.Repeat("package version 1\n", 1000)
+	updated := base + "package version 2\n"
+	basePath := cache.artifactPath(cacheDir, packageArn, "1.0.0")
+	assert.NoError(t, writeFile(basePath, []byte(base)))
+
+	// create the patch as the package publisher would
+	updatedPath := writeDownload(t, updated)
+	defer os.Remove(updatedPath)
+	patchPath := updatedPath + ".patch"
+	out, err := exec.Command(zstdCommand, "--quiet", "--patch-from="+basePath, updatedPath, "-o", patchPath).CombinedOutput()
+	assert.NoError(t, err, string(out))
+
+	service := &patchServiceMock{}
+	service.On("DownloadArtifactPatch", tracer, packageArn, "2.0.0", []string{"1.0.0"}).Return(packageservice.Patch{
+		FilePath:    patchPath,
+		Format:      PatchFormatZstd,
+		BaseVersion: "1.0.0",
+		Checksums:   map[string]string{"sha256": sha256Hash(updated)},
+	}, nil)
+	cache.service = service
+
+	filePath, err := cache.DownloadArtifact(tracer, packageArn, "2.0.0")
+
+	assert.NoError(t, err)
+	defer os.Remove(filePath)
+	assert.Equal(t, updated, readFile(filePath))
+	assert.Equal(t, updated, readFile(cache.artifactPath(cacheDir, packageArn, "2.0.0")))
+	assert.False(t, fileExists(patchPath))
+	service.AssertNotCalled(t, "DownloadArtifact", tracer, packageArn, "2.0.0")
+}
+
+func TestPackageService_PatchFailureDownloadsFullPackage(t *testing.T) {
+	cacheDir, _ := ioutil.TempDir("", "packagecache")
+	defer os.RemoveAll(cacheDir)
+	tracer := trace.NewTracer(log.NewMockLog())
+	defer tracer.BeginSection("test").End()
+
+	cache := New(nil, appconfig.BirdwatcherCfg{PackageCacheDirectory: cacheDir}).(*PackageService)
+	assert.NoError(t, writeFile(cache.artifactPath(cacheDir, packageArn, "1.0.0"), []byte("version 1")))
+
+	patchPath := writeDownload(t, "patch")
+	download := writeDownload(t, "version 2")
+	defer os.Remove(download)
+	service := &patchServiceMock{}
+	service.On("DownloadArtifactPatch", tracer, packageArn, "2.0.0", []string{"1.0.0"}).Return(packageservice.Patch{
+		FilePath:    patchPath,
+		Format:      PatchFormatBsdiff,
+		BaseVersion: "1.0.0",
+		Checksums:   map[string]string{"sha256": sha256Hash("version 2")},
+	}, nil)
+	service.On("DownloadArtifact", tracer, packageArn, "2.0.0").Return(download, nil)
+	cache.service = service
+
+	var commands [][]string
+	runPatcher = func(name string, arguments ...string) error {
+		commands = append(commands, append([]string{name}, arguments...))
+		return errors.New("corrupt patch")
+	}
+	defer func() { runPatcher = patcherCommand }()
+
+	filePath, err := cache.DownloadArtifact(tracer, packageArn, "2.0.0")
+
+	assert.NoError(t, err)
+	assert.Equal(t, download, filePath)
+	assert.Len(t, commands, 1)
+	assert.Equal(t, bspatchCommand, commands[0][0])
+	assert.Equal(t, filepath.Join(cacheDir, normalizeName(packageArn), "1.0.0", artifactFileName), commands[0][1])
+	assert.Equal(t, "version 2", readFile(cache.artifactPath(cacheDir, packageArn, "2.0.0")))
+	service.AssertExpectations(t)
+}
+
+func TestApplyPatch_UnsupportedFormat(t *testing.T) {
+	assert.Error(t, applyPatch("xdelta", "base", "patch", "file"))
+}
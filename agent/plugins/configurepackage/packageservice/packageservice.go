@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"sort"
 
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/configurepackage/trace"
 )
 
@@ -49,6 +50,22 @@ type PackageService interface {
 	ReportResult(tracer trace.Tracer, result PackageResult) error
 }
 
+// Patch is a downloaded binary patch creating the artifact of a package version from the artifact of an earlier version
+type Patch struct {
+	FilePath    string
+	Format      string
+	BaseVersion string
+	// Checksums and Signature are verified on the artifact created by the patch
+	Checksums map[string]string
+	Signature *artifact.Signature
+}
+
+// PatchService is implemented by the package services which can download the changes between package versions
+type PatchService interface {
+	// DownloadArtifactPatch downloads a patch to the version from one of the base versions
+	DownloadArtifactPatch(tracer trace.Tracer, packageName string, version string, baseVersions []string) (Patch, error)
+}
+
 const (
 	PackageServiceName_ssms3       = "ssms3"
 	PackageServiceName_birdwatcher = "birdwatcherUsingBirdwatcherArchive"
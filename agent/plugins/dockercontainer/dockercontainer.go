@@ -77,6 +77,10 @@ type DockerContainerPluginInput struct {
 	Env              string
 	User             string
 	Publish          string
+	// Runtime is docker, podman or containerd, the first one installed is used when it is not set
+	Runtime string
+	// Namespace is the containerd namespace of the containers, containerd only
+	Namespace string
 }
 
 // NewPlugin returns a new instance of the plugin.
@@ -139,7 +143,18 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput DockerConta
 		output.MarkAsFailed(err)
 		return
 	}
-	var commandName string = "docker"
+
+	runtime, err := resolveRuntime(log, pluginInput.Runtime)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	globalArguments, err := runtime.globalArguments(pluginInput.Namespace)
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("Validation error, %v", err))
+		return
+	}
+	var commandName string = runtime.command
 	var commandArguments []string
 	switch pluginInput.Action {
 	case CREATE, RUN:
@@ -285,6 +300,7 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput DockerConta
 		return
 	}
 
+	commandArguments = append(globalArguments, commandArguments...)
 	executionTimeout := pluginutil.ValidateExecutionTimeout(log, pluginInput.TimeoutSeconds)
 
 	// Execute Command
@@ -0,0 +1,89 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockercontainer
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	//Runtime values
+	RuntimeDocker     = "docker"
+	RuntimePodman     = "podman"
+	RuntimeContainerd = "containerd"
+)
+
+// runtimeCommands are the docker compatible CLIs of the supported runtimes, containerd is driven through nerdctl
+var runtimeCommands = map[string]string{
+	RuntimeDocker:     "docker",
+	RuntimePodman:     "podman",
+	RuntimeContainerd: "nerdctl",
+}
+
+// detectionOrder is the order runtimes are looked for when the input sets none
+var detectionOrder = []string{RuntimeDocker, RuntimePodman, RuntimeContainerd}
+
+var validNamespace = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_\-\.]*$`)
+
+// Assign method to global variables to allow unittest to override
+var lookPath = exec.LookPath
+
+// containerRuntime is the runtime the actions of the plugin are run with
+type containerRuntime struct {
+	name    string
+	command string
+}
+
+// resolveRuntime returns the requested runtime, or the first runtime installed on the instance if none is requested
+func resolveRuntime(log log.T, runtime string) (containerRuntime, error) {
+	runtime = strings.ToLower(strings.TrimSpace(runtime))
+	if runtime != "" {
+		command, ok := runtimeCommands[runtime]
+		if !ok {
+			return containerRuntime{}, fmt.Errorf("Runtime is set to unsupported value: %v, it must be one of %v", runtime, strings.Join(detectionOrder, ", "))
+		}
+		if _, err := lookPath(command); err != nil {
+			return containerRuntime{}, fmt.Errorf("Runtime %v requires %v which was not found: %v", runtime, command, err)
+		}
+		return containerRuntime{name: runtime, command: command}, nil
+	}
+
+	for _, name := range detectionOrder {
+		command := runtimeCommands[name]
+		if _, err := lookPath(command); err == nil {
+			log.Infof("Detected container runtime %v", name)
+			return containerRuntime{name: name, command: command}, nil
+		}
+	}
+	return containerRuntime{}, fmt.Errorf("No container runtime found, one of %v must be installed", strings.Join(detectionOrder, ", "))
+}
+
+// globalArguments returns the arguments of the runtime CLI placed before the action, the containerd namespace for nerdctl
+func (r containerRuntime) globalArguments(namespace string) ([]string, error) {
+	if namespace == "" {
+		return nil, nil
+	}
+	if r.name != RuntimeContainerd {
+		return nil, fmt.Errorf("Namespace is only supported by the %v runtime", RuntimeContainerd)
+	}
+	if !validNamespace.MatchString(namespace) {
+		return nil, fmt.Errorf("Invalid namespace %v", namespace)
+	}
+	return []string{"--namespace", namespace}, nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockercontainer
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	iohandlermocks "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
+	multiwritermock "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter/mock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// setInstalledCommandsMock makes the commands the only ones found on the path
+func setInstalledCommandsMock(commands ...string) {
+	lookPath = func(file string) (string, error) {
+		for _, command := range commands {
+			if command == file {
+				return "/usr/bin/" + file, nil
+			}
+		}
+		return "", errors.New("executable file not found in $PATH")
+	}
+}
+
+func restoreLookPath() {
+	lookPath = exec.LookPath
+}
+
+func TestResolveRuntime(t *testing.T) {
+	defer restoreLookPath()
+	testCases := []struct {
+		installed   []string
+		runtime     string
+		expected    string
+		expectError bool
+	}{
+		{[]string{"docker", "podman"}, "", RuntimeDocker, false},
+		{[]string{"nerdctl", "podman"}, "", RuntimePodman, false},
+		{[]string{"nerdctl"}, "", RuntimeContainerd, false},
+		{[]string{"docker", "podman"}, "Podman", RuntimePodman, false},
+		{[]string{}, "", "", true},
+		{[]string{"docker"}, "containerd", "", true},
+		{[]string{"docker", "ctr"}, "ctr", "", true},
+	}
+	for _, testCase := range testCases {
+		setInstalledCommandsMock(testCase.installed...)
+		runtime, err := resolveRuntime(log.NewMockLog(), testCase.runtime)
+		assert.Equal(t, testCase.expectError, err != nil, "%+v", testCase)
+		assert.Equal(t, testCase.expected, runtime.name, "%+v", testCase)
+	}
+}
+
+func TestGlobalArguments(t *testing.T) {
+	containerd := containerRuntime{name: RuntimeContainerd, command: "nerdctl"}
+	args, err := containerd.globalArguments("k8s.io")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"--namespace", "k8s.io"}, args)
+
+	args, err = containerd.globalArguments("")
+	assert.NoError(t, err)
+	assert.Empty(t, args)
+
+	_, err = containerd.globalArguments("k8s.io; reboot")
+	assert.Error(t, err)
+
+	_, err = containerRuntime{name: RuntimePodman, command: "podman"}.globalArguments("default")
+	assert.Error(t, err)
+}
+
+func TestRunCommandsWithRuntime(t *testing.T) {
+	defer restoreLookPath()
+	setInstalledCommandsMock("podman", "nerdctl")
+	orchestrationDir, _ := ioutil.TempDir("", "dockercontainer")
+	defer os.RemoveAll(orchestrationDir)
+
+	testCases := []struct {
+		input       DockerContainerPluginInput
+		commandName string
+		arguments   []string
+	}{
+		{DockerContainerPluginInput{Action: PS}, "podman", []string{"ps", "--all"}},
+		{DockerContainerPluginInput{Action: STOP, Container: "web", Runtime: RuntimeContainerd, Namespace: "k8s.io"}, "nerdctl", []string{"--namespace", "k8s.io", "stop", "web"}},
+	}
+	for _, testCase := range testCases {
+		executer := new(executers.MockCommandExecuter)
+		executer.On("NewExecute", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, testCase.commandName, testCase.arguments).Return(0, nil)
+		mockIOHandler := new(iohandlermocks.MockIOHandler)
+		mockIOHandler.On("GetStdoutWriter").Return(new(multiwritermock.MockDocumentIOMultiWriter))
+		mockIOHandler.On("GetStderrWriter").Return(new(multiwritermock.MockDocumentIOMultiWriter))
+		mockIOHandler.On("SetExitCode", 0).Return()
+		mockIOHandler.On("SetStatus", contracts.ResultStatusSuccess).Return()
+
+		p := &Plugin{CommandExecuter: executer}
+		p.runCommands(log.NewMockLog(), "docker", testCase.input, orchestrationDir, task.NewChanneledCancelFlag(), mockIOHandler)
+
+		executer.AssertExpectations(t)
+		mockIOHandler.AssertExpectations(t)
+	}
+}
+
+func TestRunCommandsWithoutRuntime(t *testing.T) {
+	defer restoreLookPath()
+	setInstalledCommandsMock()
+	orchestrationDir, _ := ioutil.TempDir("", "dockercontainer")
+	defer os.RemoveAll(orchestrationDir)
+
+	executer := new(executers.MockCommandExecuter)
+	mockIOHandler := new(iohandlermocks.MockIOHandler)
+	mockIOHandler.On("MarkAsFailed", mock.Anything).Return()
+
+	p := &Plugin{CommandExecuter: executer}
+	p.runCommands(log.NewMockLog(), "docker", DockerContainerPluginInput{Action: PS}, orchestrationDir, task.NewChanneledCancelFlag(), mockIOHandler)
+
+	mockIOHandler.AssertCalled(t, "MarkAsFailed", mock.Anything)
+	executer.AssertNotCalled(t, "NewExecute")
+}
@@ -0,0 +1,114 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+// Package domainjoin implements the domainjoin plugin.
+package domainjoin
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/parameterstore"
+	"github.com/aws/amazon-ssm-agent/agent/ssmparameterresolver"
+)
+
+const (
+	// DjoinBlobFileName is the file the offline domain join blob is written to for djoin.exe
+	DjoinBlobFileName = "odj.txt"
+)
+
+// djoinCommand is the command requesting the offline domain join of the running Windows installation
+var djoinCommand = filepath.Join(os.Getenv("SystemRoot"), "System32", "djoin.exe")
+
+// secureParameterReference matches {{ ssm-secure:parameter-name }}, secrets are referenced through /aws/reference/secretsmanager/
+var secureParameterReference = regexp.MustCompile(`^\s*{{\s*(ssm-secure:[\w-./]+)\s*}}\s*$`)
+
+// Makes command as variables, so that we can mock this for unit tests
+var getSecureParameter = resolveSecureParameter
+
+// runOfflineJoin provisions the domain join from the offline djoin blob of the input.
+// The blob is written to the orchestration directory for djoin.exe and removed once it is loaded.
+func (p *Plugin) runOfflineJoin(log log.T, pluginInput DomainJoinPluginInput, orchestrationDirectory string, out iohandler.IOHandler, utilExe convert) {
+	if len(pluginInput.DirectoryOU) != 0 || len(pluginInput.DnsIpAddresses) != 0 {
+		out.MarkAsFailed(errors.New("directoryOU and dnsIpAddresses cannot be used with djoinBlob, they are set when the blob is provisioned"))
+		return
+	}
+
+	// NOTE: Do not log the blob, it holds the password of the computer account
+	blob, err := getSecureParameter(log, pluginInput.DjoinBlob)
+	if err != nil {
+		out.MarkAsFailed(fmt.Errorf("Failed to get the djoin blob: %v", err))
+		return
+	}
+
+	blobPath := filepath.Join(orchestrationDirectory, DjoinBlobFileName)
+	if err = writeBlobFile(blobPath, blob); err != nil {
+		out.MarkAsFailed(fmt.Errorf("Failed to write the djoin blob: %v", err))
+		return
+	}
+	defer os.Remove(blobPath)
+
+	parameters := []string{"/requestODJ", "/loadfile", blobPath, "/windowspath", os.Getenv("SystemRoot"), "/localos"}
+	if _, err = utilExe(log, djoinCommand, parameters, orchestrationDirectory, orchestrationDirectory, out.GetStdoutWriter(), out.GetStderrWriter(), false); err != nil {
+		out.MarkAsFailed(fmt.Errorf("Offline domain join failed: %v", err))
+		return
+	}
+
+	// the join completes when the instance restarts
+	out.MarkAsSuccessWithReboot()
+}
+
+// writeBlobFile writes the base64 blob as the null terminated UTF-16LE text djoin.exe saves and loads
+func writeBlobFile(path string, blob string) error {
+	encoded := utf16.Encode([]rune(strings.TrimSpace(blob) + "\x00"))
+	content := make([]byte, 2*len(encoded))
+	for i, unit := range encoded {
+		binary.LittleEndian.PutUint16(content[2*i:], unit)
+	}
+	return ioutil.WriteFile(path, content, appconfig.ReadWriteAccess)
+}
+
+// resolveSecureParameter returns the value of the secure string parameter of the reference
+func resolveSecureParameter(log log.T, reference string) (string, error) {
+	match := secureParameterReference.FindStringSubmatch(reference)
+	if len(match) < 2 {
+		return "", errors.New("djoinBlob must reference a secure string parameter as '{{ ssm-secure:parameter-name }}'")
+	}
+
+	service := ssmparameterresolver.NewService()
+	resolverOptions := ssmparameterresolver.ResolveOptions{IgnoreSecureParameters: false}
+	parameters, err := ssmparameterresolver.ResolveParameterReferenceList(&service, log, []string{match[1]}, resolverOptions)
+	if err != nil {
+		return "", fmt.Errorf("Could not resolve ssm parameter - %v. Error - %v", match[1], err)
+	}
+	parameter, found := parameters[match[1]]
+	if !found {
+		return "", fmt.Errorf("ssm parameter %v was not found", match[1])
+	}
+	if parameter.Type != parameterstore.ParamTypeSecureString {
+		return "", fmt.Errorf("ssm parameter %v must be of secure string type, Current type - %v", parameter.Name, parameter.Type)
+	}
+	return parameter.Value, nil
+}
@@ -76,6 +76,9 @@ type DomainJoinPluginInput struct {
 	DirectoryName  string
 	DirectoryOU    string
 	DnsIpAddresses []string
+	// DjoinBlob references the secure string parameter holding an offline domain join blob provisioned with djoin.exe.
+	// The instance joins the domain without reaching a domain controller when the blob is set.
+	DjoinBlob string
 }
 
 // NewPlugin returns a new instance of the plugin.
@@ -145,6 +148,11 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput DomainJoinP
 		return
 	}
 
+	if len(pluginInput.DjoinBlob) != 0 {
+		p.runOfflineJoin(log, pluginInput, orchestrationDirectory, out, utilExe)
+		return
+	}
+
 	// Construct Command line with executable file name and parameters
 	var command string
 	if command, err = makeArgs(log, pluginInput); err != nil {
@@ -20,11 +20,15 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil/filemanager"
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/gitcloneresource"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/gitlabresource"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/gitresource"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/gitresource/privategithub"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/ociresource"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/remoteresource"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/s3resource"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/ssmdocresource"
@@ -43,6 +47,9 @@ const (
 	GitHub      = "GitHub"      //Github represents the source type "GitHub" from where the resource can be downloaded
 	S3          = "S3"          //S3 represents the source type "S3" from where the resource is being downloaded
 	SSMDocument = "SSMDocument" //SSMDocument represents the source type as SSM Document
+	Oci         = "Oci"         //Oci represents the source type "Oci" of artifacts stored in OCI registries such as ECR
+	GitLab      = "GitLab"      //GitLab represents the source type "GitLab" from where the resource can be downloaded
+	Git         = "Git"         //Git represents the source type "Git" of any git repository, cloned over SSH or HTTPS
 
 	downloadsDir = "downloads" //Directory under the orchestration directory where the downloaded resource resides
 
@@ -71,6 +78,10 @@ type DownloadContentPlugin struct {
 	SourceType      string `json:"sourceType"`
 	SourceInfo      string `json:"sourceInfo"`
 	DestinationPath string `json:"destinationPath"`
+	// Checksums are the expected checksums of the downloaded file by algorithm, e.g. sha256, sha384 or sha512
+	Checksums map[string]string `json:"checksums"`
+	// Signature is the detached signature the downloaded file is verified with
+	Signature *artifact.Signature `json:"signature"`
 	// TODO: 08/25/2017 meloniam@ Change the type of SourceInfo and documentParameters to map[string]interface{}
 	// TODO: https://amazon.awsapps.com/workdocs/index.html#/document/7d56a42ea5b040a7c33548d77dc98040f0fb380bbbfb2fd580c861225e2ee1c7
 }
@@ -87,6 +98,12 @@ func newRemoteResource(log log.T, SourceType string, SourceInfo string) (resourc
 		return s3resource.NewS3Resource(log, SourceInfo)
 	case SSMDocument:
 		return ssmdocresource.NewSSMDocResource(SourceInfo)
+	case Oci:
+		return ociresource.NewOCIResource(log, SourceInfo)
+	case GitLab:
+		return gitlabresource.NewGitLabResource(log, SourceInfo, privategithub.NewTokenInfoImpl())
+	case Git:
+		return gitcloneresource.NewGitCloneResource(log, SourceInfo, privategithub.NewTokenInfoImpl())
 	default:
 		return nil, fmt.Errorf("Invalid SourceType - %v", SourceType)
 	}
@@ -154,6 +171,14 @@ func (p *Plugin) runCopyContent(log log.T, input *DownloadContentPlugin, config
 		return
 	}
 
+	if err := verifyContent(log, input, result); err != nil {
+		for _, path := range result.Files {
+			p.filesys.DeleteFile(path)
+		}
+		output.MarkAsFailed(err)
+		return
+	}
+
 	if err := setPermissions(log, result); err != nil {
 		output.MarkAsFailed(fmt.Errorf("Failed to set right permissions to the content. Error - %v", err))
 		return
@@ -178,6 +203,37 @@ func setPermissions(log log.T, result *remoteresource.DownloadResult) error {
 	return nil
 }
 
+// verifyContent verifies the checksums and signature of the downloaded file, if they are specified
+func verifyContent(log log.T, input *DownloadContentPlugin, result *remoteresource.DownloadResult) error {
+	if len(input.Checksums) == 0 && input.Signature == nil {
+		return nil
+	}
+	if len(result.Files) != 1 {
+		return fmt.Errorf("Checksums and signature can only be verified when a single file is downloaded, %v files were downloaded", len(result.Files))
+	}
+	path := result.Files[0]
+
+	if input.Signature != nil {
+		if err := artifact.ValidateSignedChecksums(input.Checksums); err != nil {
+			return err
+		}
+	}
+	if len(input.Checksums) > 0 {
+		downloadInput := artifact.DownloadInput{SourceChecksums: input.Checksums}
+		if _, err := artifact.VerifyHash(log, downloadInput, artifact.DownloadOutput{LocalFilePath: path}); err != nil {
+			return fmt.Errorf("Checksum verification of %v failed - %v", filepath.Base(path), err)
+		}
+		log.Infof("Verified checksums of %v", path)
+	}
+	if input.Signature != nil {
+		if err := artifact.VerifySignature(log, path, *input.Signature); err != nil {
+			return err
+		}
+		log.Infof("Verified signature of %v", path)
+	}
+	return nil
+}
+
 // Name returns the plugin name
 func Name() string {
 	return appconfig.PluginDownloadContent
@@ -205,13 +261,19 @@ func validateInput(input *DownloadContentPlugin) (valid bool, err error) {
 		return false, errors.New("SourceType must be specified")
 	}
 	//ensure all entries are valid
-	if input.SourceType != GitHub && input.SourceType != S3 && input.SourceType != SSMDocument {
+	if input.SourceType != GitHub && input.SourceType != S3 && input.SourceType != SSMDocument && input.SourceType != Oci &&
+		input.SourceType != GitLab && input.SourceType != Git {
 		return false, errors.New("Unsupported source type")
 	}
 	// ensure non-empty source info
 	if input.SourceInfo == "" {
 		return false, errors.New("SourceInfo must be specified")
 	}
+	if input.Signature != nil {
+		if err = input.Signature.Validate(); err != nil {
+			return false, err
+		}
+	}
 	return true, nil
 }
 
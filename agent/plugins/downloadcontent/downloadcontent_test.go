@@ -21,9 +21,12 @@ import (
 	"time"
 
 	"errors"
+	"io/ioutil"
+	"os"
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/artifact"
 	filemock "github.com/aws/amazon-ssm-agent/agent/fileutil/filemanager/mock"
 	iohandlermocks "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
 	"github.com/aws/amazon-ssm-agent/agent/log"
@@ -88,6 +91,43 @@ func TestNewRemoteResource_SSMDocument(t *testing.T) {
 
 }
 
+func TestNewRemoteResource_Oci(t *testing.T) {
+
+	locationInfo := `{
+		"reference" : "123456789012.dkr.ecr.us-east-1.amazonaws.com/tools:1.2"
+		}`
+	remoteresource, err := newRemoteResource(logger, "Oci", locationInfo)
+
+	assert.NotNil(t, remoteresource)
+	assert.NoError(t, err)
+
+}
+
+func TestNewRemoteResource_GitLab(t *testing.T) {
+
+	locationInfo := `{
+		"project" : "group/subgroup/project",
+		"path" : "scripts"
+		}`
+	remoteresource, err := newRemoteResource(logger, "GitLab", locationInfo)
+
+	assert.NotNil(t, remoteresource)
+	assert.NoError(t, err)
+
+}
+
+func TestNewRemoteResource_Git(t *testing.T) {
+
+	locationInfo := `{
+		"repository" : "git@git.example.com:team/project.git"
+		}`
+	remoteresource, err := newRemoteResource(logger, "Git", locationInfo)
+
+	assert.NotNil(t, remoteresource)
+	assert.NoError(t, err)
+
+}
+
 func TestNewPlugin_RunCopyContent(t *testing.T) {
 
 	fileMock := filemock.FileSystemMock{}
@@ -392,6 +432,50 @@ func TestValidateInput_NoLocationInfo(t *testing.T) {
 	assert.Contains(t, err.Error(), "SourceInfo must be specified")
 }
 
+func TestValidateInput_InvalidSignature(t *testing.T) {
+
+	input := DownloadContentPlugin{}
+	input.SourceType = "S3"
+	input.SourceInfo = `{"path": "https://s3.amazonaws.com/test-bucket/fake-key"}`
+	input.Signature = &artifact.Signature{Type: "Md5"}
+
+	result, err := validateInput(&input)
+
+	assert.False(t, result)
+	assert.Error(t, err)
+}
+
+func TestVerifyContent(t *testing.T) {
+	file, _ := ioutil.TempFile("", "downloadcontent")
+	file.WriteString("content")
+	file.Close()
+	defer os.Remove(file.Name())
+
+	input := &DownloadContentPlugin{}
+	result := &remoteresource.DownloadResult{Files: []string{file.Name()}}
+	assert.NoError(t, verifyContent(logger, input, result))
+
+	// sha256 of content
+	input.Checksums = map[string]string{"sha256": "ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73"}
+	assert.NoError(t, verifyContent(logger, input, result))
+
+	input.Checksums = map[string]string{"sha512": "0123"}
+	assert.Error(t, verifyContent(logger, input, result))
+
+	// an empty checksum passes VerifyHash for backwards compatibility, but not for signed content
+	input.Checksums = map[string]string{"sha256": ""}
+	input.Signature = &artifact.Signature{Type: artifact.SignatureTypeGpg, Signature: "signature", PublicKey: "key"}
+	err := verifyContent(logger, input, result)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "algorithm and a value")
+	input.Signature = nil
+
+	result.Files = append(result.Files, file.Name())
+	err = verifyContent(logger, input, result)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "single file")
+}
+
 func TestName(t *testing.T) {
 	assert.Equal(t, "aws:downloadContent", Name())
 }
@@ -0,0 +1,233 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package gitcloneresource implements the methods to access resources from any git repository by cloning it
+package gitcloneresource
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filemanager"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/remoteresource"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/system"
+)
+
+const (
+	gitCommand = "git"
+	gitDir     = ".git"
+	// privateKeyAccess is the permission ssh requires of private key files
+	privateKeyAccess = 0600
+)
+
+// Assign method to global variables to allow unittest to override
+var runGit = git
+
+// TokenAccess resolves the secure parameter holding the private SSH key
+type TokenAccess interface {
+	GetToken(log log.T, tokenInfo string) (string, error)
+}
+
+// GitCloneResource is a struct for the remote resource of type Git
+type GitCloneResource struct {
+	Info       GitCloneInfo
+	privateKey string
+}
+
+// GitCloneInfo represents the sourceInfo type sent by runcommand
+type GitCloneInfo struct {
+	// Repository is the URL of the repository, e.g. git@git.example.com:team/project.git or ssh://git@git.example.com/team/project.git
+	Repository string `json:"repository"`
+	// Ref is the branch or tag, the default branch of the repository if not specified
+	Ref string `json:"ref"`
+	// Path is the file or directory to download, the whole repository if not specified
+	Path string `json:"path"`
+	// PrivateSSHKey is the secure string parameter holding the deploy key, e.g. {{ ssm-secure:deploy-key }}.
+	// Keys in Secrets Manager are referenced as {{ ssm-secure:/aws/reference/secretsmanager/deploy-key }}
+	PrivateSSHKey string `json:"privateSSHKey"`
+	// KnownHosts are the known_hosts entries of the host of the repository, the known hosts of the instance are used if not specified
+	KnownHosts string `json:"knownHosts"`
+}
+
+// NewGitCloneResource is a constructor of type GitCloneResource
+func NewGitCloneResource(log log.T, info string, tokens TokenAccess) (resource *GitCloneResource, err error) {
+	var gitCloneInfo GitCloneInfo
+	if gitCloneInfo, err = parseSourceInfo(info); err != nil {
+		return nil, err
+	}
+
+	resource = &GitCloneResource{Info: gitCloneInfo}
+	if gitCloneInfo.PrivateSSHKey != "" {
+		// NOTE: Do not log the private key
+		if resource.privateKey, err = tokens.GetToken(log, gitCloneInfo.PrivateSSHKey); err != nil {
+			return nil, err
+		}
+	}
+	return resource, nil
+}
+
+// parseSourceInfo unmarshals the information in sourceInfo of type GitCloneInfo and returns it
+func parseSourceInfo(sourceInfo string) (gitCloneInfo GitCloneInfo, err error) {
+	if err = jsonutil.Unmarshal(sourceInfo, &gitCloneInfo); err != nil {
+		return gitCloneInfo, fmt.Errorf("Source Info could not be unmarshalled for source type Git. Please check JSON format of sourceInfo - %v", err.Error())
+	}
+	return gitCloneInfo, nil
+}
+
+// ValidateLocationInfo ensures that the required parameters of SourceInfo are specified
+func (resource *GitCloneResource) ValidateLocationInfo() (valid bool, err error) {
+	if resource.Info.Repository == "" {
+		return false, errors.New("Repository for Git SourceType must be specified")
+	}
+	// values starting with - would be read by git as options
+	if strings.HasPrefix(resource.Info.Repository, "-") || strings.HasPrefix(resource.Info.Ref, "-") {
+		return false, errors.New("Repository and Ref for Git SourceType cannot start with -")
+	}
+	for _, element := range strings.Split(filepath.ToSlash(resource.Info.Path), "/") {
+		if element == ".." {
+			return false, errors.New("Path for Git SourceType must be within the repository")
+		}
+	}
+	return true, nil
+}
+
+// DownloadRemoteResource clones the repository and copies the file or the files of the directory to the destination
+func (resource *GitCloneResource) DownloadRemoteResource(log log.T, filesys filemanager.FileSystem, destPath string) (err error, result *remoteresource.DownloadResult) {
+	if destPath == "" {
+		destPath = appconfig.DownloadRoot
+	}
+
+	workDir, err := ioutil.TempDir("", "gitclone")
+	if err != nil {
+		return fmt.Errorf("Could not create the directory to clone to - %v", err), nil
+	}
+	defer os.RemoveAll(workDir)
+
+	env, err := resource.sshEnvironment(workDir)
+	if err != nil {
+		return err, nil
+	}
+
+	cloneDir := filepath.Join(workDir, "repository")
+	arguments := []string{"clone", "--depth", "1", "--quiet"}
+	if resource.Info.Ref != "" {
+		arguments = append(arguments, "--branch", resource.Info.Ref)
+	}
+	arguments = append(arguments, "--", resource.Info.Repository, cloneDir)
+	log.Infof("Cloning git repository %v", resource.Info.Repository)
+	if err = runGit(log, env, arguments...); err != nil {
+		return err, nil
+	}
+
+	source := filepath.Join(cloneDir, filepath.FromSlash(resource.Info.Path))
+	result = &remoteresource.DownloadResult{}
+	if !filesys.IsDirectory(source) {
+		if !filesys.Exists(source) {
+			return fmt.Errorf("Path %v does not exist in git repository %v", resource.Info.Path, resource.Info.Repository), nil
+		}
+		if (filesys.Exists(destPath) && filesys.IsDirectory(destPath)) || os.IsPathSeparator(destPath[len(destPath)-1]) {
+			destPath = filepath.Join(destPath, filepath.Base(source))
+		}
+		if err = copyFile(log, filesys, source, destPath); err != nil {
+			return err, nil
+		}
+		result.Files = append(result.Files, destPath)
+		return nil, result
+	}
+
+	// all files of the directory are placed under destPath, keeping the structure of its subdirectories
+	err = filepath.Walk(source, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == gitDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			log.Debugf("Skipping %v which is not a regular file", filePath)
+			return nil
+		}
+		relativePath, err := filepath.Rel(source, filePath)
+		if err != nil {
+			return err
+		}
+		destination := filepath.Join(destPath, relativePath)
+		if err = copyFile(log, filesys, filePath, destination); err != nil {
+			return err
+		}
+		result.Files = append(result.Files, destination)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Could not copy the files of git repository %v - %v", resource.Info.Repository, err), nil
+	}
+	return nil, result
+}
+
+// sshEnvironment writes the private key and known hosts to the work directory and
+// returns the environment making git use them for SSH connections
+func (resource *GitCloneResource) sshEnvironment(workDir string) (env []string, err error) {
+	// never prompt for credentials, which cannot be entered
+	env = []string{"GIT_TERMINAL_PROMPT=0"}
+	if resource.privateKey == "" && resource.Info.KnownHosts == "" {
+		return env, nil
+	}
+
+	sshCommand := "ssh -o BatchMode=yes"
+	if resource.privateKey != "" {
+		keyFile := filepath.Join(workDir, "id_deploy")
+		// ssh rejects keys without a trailing newline
+		if err = ioutil.WriteFile(keyFile, []byte(strings.TrimSpace(resource.privateKey)+"\n"), privateKeyAccess); err != nil {
+			return nil, fmt.Errorf("Could not write the private SSH key - %v", err)
+		}
+		sshCommand += fmt.Sprintf(` -i "%v" -o IdentitiesOnly=yes`, filepath.ToSlash(keyFile))
+	}
+	if resource.Info.KnownHosts != "" {
+		knownHostsFile := filepath.Join(workDir, "known_hosts")
+		if err = ioutil.WriteFile(knownHostsFile, []byte(resource.Info.KnownHosts+"\n"), privateKeyAccess); err != nil {
+			return nil, fmt.Errorf("Could not write the known hosts - %v", err)
+		}
+		sshCommand += fmt.Sprintf(` -o UserKnownHostsFile="%v" -o StrictHostKeyChecking=yes`, filepath.ToSlash(knownHostsFile))
+	}
+	return append(env, "GIT_SSH_COMMAND="+sshCommand), nil
+}
+
+// copyFile copies the file of the cloned repository to the destination
+func copyFile(log log.T, filesys filemanager.FileSystem, source string, destination string) error {
+	content, err := ioutil.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	return system.SaveFileContent(log, filesys, destination, string(content))
+}
+
+// git runs git with the arguments and additional environment, returning an error including its output if it fails
+func git(log log.T, env []string, arguments ...string) error {
+	command := exec.Command(gitCommand, arguments...)
+	command.Env = append(os.Environ(), env...)
+	if out, err := command.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v %v failed: %v %v", gitCommand, arguments[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package gitlabresource implements the methods to access resources from GitLab repositories
+package gitlabresource
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filemanager"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/remoteresource"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/system"
+)
+
+const (
+	defaultHost    = "gitlab.com"
+	requestTimeout = 5 * time.Minute
+	// treePageSize is the number of entries requested per page of a repository tree
+	treePageSize = 100
+	// treeTypeBlob is the type of the tree entries that are files
+	treeTypeBlob = "blob"
+)
+
+var errNotFound = errors.New("not found")
+
+// TokenAccess resolves the secure parameter holding the access token
+type TokenAccess interface {
+	GetToken(log log.T, tokenInfo string) (string, error)
+}
+
+// GitLabResource is a struct for the remote resource of type GitLab
+type GitLabResource struct {
+	Info   GitLabInfo
+	client *http.Client
+	token  string
+	// apiURL is the root of the REST API of the GitLab instance
+	apiURL string
+}
+
+// GitLabInfo represents the sourceInfo type sent by runcommand
+type GitLabInfo struct {
+	// Host is the GitLab instance, gitlab.com if not specified
+	Host string `json:"host"`
+	// Project is the full path of the project including its groups and subgroups, e.g. group/subgroup/project
+	Project string `json:"project"`
+	// Path is the file or directory to download, the whole repository if not specified
+	Path string `json:"path"`
+	// Ref is the branch, tag or commit, the default branch of the project if not specified
+	Ref string `json:"ref"`
+	// TokenInfo is the secure string parameter holding a personal, project or deploy token, e.g. {{ ssm-secure:gitlab-token }}
+	TokenInfo string `json:"tokenInfo"`
+}
+
+// treeEntry is a file or directory of a repository tree
+type treeEntry struct {
+	Type string `json:"type"`
+	Path string `json:"path"`
+}
+
+// NewGitLabResource is a constructor of type GitLabResource
+func NewGitLabResource(log log.T, info string, tokens TokenAccess) (gitlab *GitLabResource, err error) {
+	var gitLabInfo GitLabInfo
+	if gitLabInfo, err = parseSourceInfo(info); err != nil {
+		return nil, err
+	}
+	if gitLabInfo.Host == "" {
+		gitLabInfo.Host = defaultHost
+	}
+
+	gitlab = &GitLabResource{
+		Info:   gitLabInfo,
+		client: &http.Client{Timeout: requestTimeout},
+		apiURL: "https://" + gitLabInfo.Host + "/api/v4",
+	}
+	if gitLabInfo.TokenInfo != "" {
+		// NOTE: Do not log the token
+		if gitlab.token, err = tokens.GetToken(log, gitLabInfo.TokenInfo); err != nil {
+			return nil, err
+		}
+	}
+	return gitlab, nil
+}
+
+// parseSourceInfo unmarshals the information in sourceInfo of type GitLabInfo and returns it
+func parseSourceInfo(sourceInfo string) (gitLabInfo GitLabInfo, err error) {
+	if err = jsonutil.Unmarshal(sourceInfo, &gitLabInfo); err != nil {
+		return gitLabInfo, fmt.Errorf("Source Info could not be unmarshalled for source type GitLab. Please check JSON format of sourceInfo - %v", err.Error())
+	}
+	return gitLabInfo, nil
+}
+
+// ValidateLocationInfo ensures that the required parameters of SourceInfo are specified
+func (gitlab *GitLabResource) ValidateLocationInfo() (valid bool, err error) {
+	if gitlab.Info.Project == "" {
+		return false, errors.New("Project for GitLab SourceType must be specified")
+	}
+	if strings.Contains(gitlab.Info.Host, "/") {
+		return false, errors.New("Host for GitLab SourceType must be a host name")
+	}
+	return true, nil
+}
+
+// DownloadRemoteResource downloads the file or the files of the directory from the GitLab repository
+func (gitlab *GitLabResource) DownloadRemoteResource(log log.T, filesys filemanager.FileSystem, destPath string) (err error, result *remoteresource.DownloadResult) {
+	if destPath == "" {
+		destPath = appconfig.DownloadRoot
+	}
+
+	ref := gitlab.Info.Ref
+	if ref == "" {
+		if ref, err = gitlab.getDefaultBranch(log); err != nil {
+			return err, nil
+		}
+	}
+	repositoryPath := strings.Trim(gitlab.Info.Path, "/")
+
+	entries, err := gitlab.listTree(log, repositoryPath, ref)
+	if err != nil {
+		return err, nil
+	}
+
+	result = &remoteresource.DownloadResult{}
+	if len(entries) == 0 {
+		// the path is a file, which has no tree
+		if repositoryPath == "" {
+			return fmt.Errorf("GitLab repository %v has no files at %v", gitlab.Info.Project, ref), nil
+		}
+		if (filesys.Exists(destPath) && filesys.IsDirectory(destPath)) || os.IsPathSeparator(destPath[len(destPath)-1]) {
+			destPath = filepath.Join(destPath, path.Base(repositoryPath))
+		}
+		if err = gitlab.downloadFile(log, filesys, repositoryPath, ref, destPath); err != nil {
+			return err, nil
+		}
+		result.Files = append(result.Files, destPath)
+		return nil, result
+	}
+
+	// all files of the directory are placed under destPath, keeping the structure of its subdirectories
+	for _, entry := range entries {
+		relativePath := strings.TrimPrefix(strings.TrimPrefix(entry.Path, repositoryPath), "/")
+		filePath := filepath.Join(destPath, filepath.FromSlash(relativePath))
+		if err = gitlab.downloadFile(log, filesys, entry.Path, ref, filePath); err != nil {
+			return err, nil
+		}
+		result.Files = append(result.Files, filePath)
+	}
+	return nil, result
+}
+
+// getDefaultBranch returns the default branch of the project
+func (gitlab *GitLabResource) getDefaultBranch(log log.T) (string, error) {
+	content, _, err := gitlab.get(log, gitlab.projectURL(), nil)
+	if err != nil {
+		return "", fmt.Errorf("Could not get GitLab project %v - %v", gitlab.Info.Project, err)
+	}
+	var project struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err = json.Unmarshal(content, &project); err != nil || project.DefaultBranch == "" {
+		return "", fmt.Errorf("Could not get the default branch of GitLab project %v", gitlab.Info.Project)
+	}
+	return project.DefaultBranch, nil
+}
+
+// listTree returns the files under the directory, or no files if the path is not a directory
+func (gitlab *GitLabResource) listTree(log log.T, directory string, ref string) (files []treeEntry, err error) {
+	query := url.Values{}
+	query.Set("ref", ref)
+	query.Set("recursive", "true")
+	query.Set("per_page", fmt.Sprint(treePageSize))
+	if directory != "" {
+		query.Set("path", directory)
+	}
+
+	for page := "1"; page != ""; {
+		query.Set("page", page)
+		content, header, err := gitlab.get(log, gitlab.projectURL()+"/repository/tree", query)
+		if err == errNotFound {
+			return nil, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("Could not list GitLab repository %v at %v - %v", gitlab.Info.Project, directory, err)
+		}
+
+		var entries []treeEntry
+		if err = json.Unmarshal(content, &entries); err != nil {
+			return nil, fmt.Errorf("Could not parse GitLab repository tree - %v", err)
+		}
+		for _, entry := range entries {
+			if entry.Type == treeTypeBlob {
+				files = append(files, entry)
+			}
+		}
+		page = header.Get("X-Next-Page")
+	}
+	return files, nil
+}
+
+// downloadFile downloads the raw content of the repository file and saves it to the destination
+func (gitlab *GitLabResource) downloadFile(log log.T, filesys filemanager.FileSystem, repositoryPath string, ref string, destination string) error {
+	query := url.Values{}
+	query.Set("ref", ref)
+	content, _, err := gitlab.get(log, gitlab.projectURL()+"/repository/files/"+url.PathEscape(repositoryPath)+"/raw", query)
+	if err != nil {
+		return fmt.Errorf("Could not download %v from GitLab repository %v - %v", repositoryPath, gitlab.Info.Project, err)
+	}
+	if err = system.SaveFileContent(log, filesys, destination, string(content)); err != nil {
+		log.Errorf("Error saving file content from GitLab file - %v, %v", repositoryPath, err)
+		return err
+	}
+	return nil
+}
+
+// projectURL returns the API URL of the project, whose full path is encoded as its id
+func (gitlab *GitLabResource) projectURL() string {
+	return gitlab.apiURL + "/projects/" + url.PathEscape(strings.Trim(gitlab.Info.Project, "/"))
+}
+
+// get sends a request to the GitLab API and returns the response content and headers
+func (gitlab *GitLabResource) get(log log.T, requestURL string, query url.Values) (content []byte, header http.Header, err error) {
+	if query != nil {
+		requestURL += "?" + query.Encode()
+	}
+	request, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if gitlab.token != "" {
+		request.Header.Set("PRIVATE-TOKEN", gitlab.token)
+	}
+
+	log.Debugf("Sending request to %v", requestURL)
+	response, err := gitlab.client.Do(request)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil, nil, errNotFound
+	} else if response.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("request failed with status %v", response.Status)
+	}
+	if content, err = ioutil.ReadAll(response.Body); err != nil {
+		return nil, nil, err
+	}
+	return content, response.Header, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package gitlabresource
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filemanager"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var logMock = log.NewMockLog()
+
+type tokenAccessMock struct {
+	mock.Mock
+}
+
+func (m *tokenAccessMock) GetToken(log log.T, tokenInfo string) (string, error) {
+	args := m.Called(log, tokenInfo)
+	return args.String(0), args.Error(1)
+}
+
+const projectPath = "/api/v4/projects/group%2Fsubgroup%2Fproject"
+
+// newGitLab starts a GitLab API serving the files of a project in a subgroup
+func newGitLab(t *testing.T, files map[string]string) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("PRIVATE-TOKEN"))
+		requestPath := r.URL.EscapedPath()
+		switch {
+		case requestPath == projectPath:
+			fmt.Fprint(w, `{"default_branch": "main"}`)
+		case requestPath == projectPath+"/repository/tree":
+			assert.Equal(t, "main", r.URL.Query().Get("ref"))
+			directory := r.URL.Query().Get("path")
+			var entries []string
+			for name := range files {
+				if directory == "" || strings.HasPrefix(name, directory+"/") {
+					entries = append(entries, fmt.Sprintf(`{"type": "blob", "path": "%v"}`, name))
+				}
+			}
+			if len(entries) > 0 {
+				entries = append(entries, fmt.Sprintf(`{"type": "tree", "path": "%v/sub"}`, directory))
+			}
+			fmt.Fprintf(w, "[%v]", strings.Join(entries, ","))
+		case strings.HasPrefix(requestPath, projectPath+"/repository/files/"):
+			// the project id and file path escaped in the request are decoded in r.URL.Path
+			name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v4/projects/group/subgroup/project/repository/files/"), "/raw")
+			content, found := files[name]
+			if !found {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprint(w, content)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func newTestResource(t *testing.T, server *httptest.Server, sourceInfo string) *GitLabResource {
+	tokens := new(tokenAccessMock)
+	tokens.On("GetToken", logMock, "{{ ssm-secure:gitlab-token }}").Return("secret", nil)
+	resource, err := NewGitLabResource(logMock, sourceInfo, tokens)
+	assert.NoError(t, err)
+	resource.client = server.Client()
+	resource.apiURL = server.URL + "/api/v4"
+	return resource
+}
+
+func TestGitLabResource_ValidateLocationInfo(t *testing.T) {
+	resource, err := NewGitLabResource(logMock, `{"project": "group/subgroup/project"}`, nil)
+	assert.NoError(t, err)
+	_, err = resource.ValidateLocationInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, "https://gitlab.com/api/v4", resource.apiURL)
+
+	resource, err = NewGitLabResource(logMock, `{"host": "gitlab.example.com"}`, nil)
+	assert.NoError(t, err)
+	_, err = resource.ValidateLocationInfo()
+	assert.Error(t, err)
+}
+
+func TestGitLabResource_DownloadFile(t *testing.T) {
+	server := newGitLab(t, map[string]string{"scripts/install.sh": "echo install"})
+	defer server.Close()
+
+	destination, _ := ioutil.TempDir("", "gitlab")
+	defer os.RemoveAll(destination)
+
+	resource := newTestResource(t, server, `{"project": "group/subgroup/project", "path": "scripts/install.sh", "tokenInfo": "{{ ssm-secure:gitlab-token }}"}`)
+	err, result := resource.DownloadRemoteResource(logMock, filemanager.FileSystemImpl{}, destination)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(destination, "install.sh")}, result.Files)
+	content, _ := ioutil.ReadFile(filepath.Join(destination, "install.sh"))
+	assert.Equal(t, "echo install", string(content))
+}
+
+func TestGitLabResource_DownloadDirectory(t *testing.T) {
+	server := newGitLab(t, map[string]string{"scripts/install.sh": "echo install", "scripts/sub/run.sh": "echo run", "README.md": "readme"})
+	defer server.Close()
+
+	destination, _ := ioutil.TempDir("", "gitlab")
+	defer os.RemoveAll(destination)
+
+	resource := newTestResource(t, server, `{"project": "group/subgroup/project", "path": "scripts", "tokenInfo": "{{ ssm-secure:gitlab-token }}"}`)
+	err, result := resource.DownloadRemoteResource(logMock, filemanager.FileSystemImpl{}, destination)
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Files, 2)
+	assert.Contains(t, result.Files, filepath.Join(destination, "install.sh"))
+	assert.Contains(t, result.Files, filepath.Join(destination, "sub", "run.sh"))
+	content, _ := ioutil.ReadFile(filepath.Join(destination, "sub", "run.sh"))
+	assert.Equal(t, "echo run", string(content))
+}
+
+func TestGitLabResource_DownloadMissingFile(t *testing.T) {
+	server := newGitLab(t, map[string]string{})
+	defer server.Close()
+
+	resource := newTestResource(t, server, `{"project": "group/subgroup/project", "path": "missing.sh", "ref": "main", "tokenInfo": "{{ ssm-secure:gitlab-token }}"}`)
+	err, _ := resource.DownloadRemoteResource(logMock, filemanager.FileSystemImpl{}, os.TempDir())
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Could not download missing.sh")
+}
@@ -44,13 +44,24 @@ type TokenInfoImpl struct {
 
 // GetOAuthClient is the only method from privategithub package that is accessible to gitresource
 func (t TokenInfoImpl) GetOAuthClient(log log.T, tokenInfo string) (client *http.Client, err error) {
+	// Obtain the token from the secure parameter
+	// Create StaticTokenSource and create oauth client and return it
+	token, err := t.GetToken(log, tokenInfo)
+	if err != nil {
+		return nil, err
+	}
+	return t.gitoauthclient.GetGithubOauthClient(token), nil
+}
+
+// GetToken returns the value of the secure string parameter referenced by tokenInfo.
+// Secrets Manager secrets are referenced through the /aws/reference/secretsmanager/ parameter path.
+func (t TokenInfoImpl) GetToken(log log.T, tokenInfo string) (token string, err error) {
 	// Validate the format of the secure parameter
 	// Make a call to secure string (disable logging) and obtain the token
-	// Create StaticTokenSource and create oauth client and return it
 
 	// Validate the format of token information
 	if valid, err := validateTokenParameter(tokenInfo); !valid {
-		return nil, err
+		return "", err
 	}
 
 	var tokenVal ssmparameterresolver.SsmParameterInfo
@@ -63,7 +74,7 @@ func (t TokenInfoImpl) GetOAuthClient(log log.T, tokenInfo string) (client *http
 	if len(subParam) > 1 {
 		parameterReferences = []string{subParam[1]}
 	} else {
-		return client, errors.New("Something went wrong when trying to extract ssm-secure parameter")
+		return "", errors.New("Something went wrong when trying to extract ssm-secure parameter")
 	}
 
 	resolverOptions := ssmparameterresolver.ResolveOptions{
@@ -73,12 +84,12 @@ func (t TokenInfoImpl) GetOAuthClient(log log.T, tokenInfo string) (client *http
 	// Get the parameter value from parameter store.
 	// NOTE: Do not log the parameter value
 	if tokenMap, err = t.SsmParameter(log, &t.paramAccess, parameterReferences, resolverOptions); err != nil {
-		return nil, fmt.Errorf("Could not resolve ssm parameter - %v. Error - %v", parameterReferences, err)
+		return "", fmt.Errorf("Could not resolve ssm parameter - %v. Error - %v", parameterReferences, err)
 	}
 
 	// Parameter output must be of size 1. Any other number of tokens returned can lead to undesired behavior
 	if len(tokenMap) != 1 {
-		return nil, fmt.Errorf("Invalid number of tokens returned - %v", len(tokenMap))
+		return "", fmt.Errorf("Invalid number of tokens returned - %v", len(tokenMap))
 	}
 
 	//Extracting single value of token contained within tokenMap
@@ -88,9 +99,9 @@ func (t TokenInfoImpl) GetOAuthClient(log log.T, tokenInfo string) (client *http
 
 	// Validating to check if the parameter obtained is a secure string
 	if tokenVal.Type != parameterstore.ParamTypeSecureString {
-		return nil, fmt.Errorf("token-parameter-name %v must be of secure string type, Current type - %v", tokenVal.Name, tokenVal.Type)
+		return "", fmt.Errorf("token-parameter-name %v must be of secure string type, Current type - %v", tokenVal.Name, tokenVal.Type)
 	}
-	return t.gitoauthclient.GetGithubOauthClient(tokenVal.Value), nil
+	return tokenVal.Value, nil
 }
 
 func getSSMParameter(log log.T, paramService ssmparameterresolver.ISsmParameterService, parameterReferences []string,
@@ -52,6 +52,17 @@ func TestTokenInfoImpl_GetOAuthClient_Success(t *testing.T) {
 	oauthclientmock.AssertExpectations(t)
 }
 
+func TestTokenInfoImpl_GetToken_Success(t *testing.T) {
+	tokenInfo := TokenInfoImpl{
+		SsmParameter: getMockedSecureParam,
+	}
+
+	token, err := tokenInfo.GetToken(logMock, `{{ ssm-secure:/aws/reference/secretsmanager/dummysecureparam }}`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "lskjksjgshfg1234jdskjhgvs", token)
+}
+
 func TestValidateTokenParameter(t *testing.T) {
 	var valid bool
 	var err error
@@ -0,0 +1,56 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ociresource
+
+import (
+	"fmt"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/rip"
+	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ecr"
+)
+
+// dependency on ECR for registry credentials
+type ocideps interface {
+	GetECRAuthorizationToken(log log.T, region string, registryID string) (token string, err error)
+}
+
+type ociDepImpl struct{}
+
+// newECRClient creates an ECR client for the region, using the FIPS endpoint of the region when FIPS is enabled
+func newECRClient(region string) *ecr.ECR {
+	sess := session.New(sdkutil.AwsConfig().WithRegion(region))
+	rip.UseFipsEndpoint(sess, region, "ecr")
+	return ecr.New(sess)
+}
+
+// GetECRAuthorizationToken returns the base64 encoded user:password token of the ECR registry, using the instance credentials
+func (ociDepImpl) GetECRAuthorizationToken(log log.T, region string, registryID string) (token string, err error) {
+	ecrService := newECRClient(region)
+
+	log.Debugf("Getting authorization token of ECR registry %v in %v", registryID, region)
+	output, err := ecrService.GetAuthorizationToken(&ecr.GetAuthorizationTokenInput{
+		RegistryIds: []*string{aws.String(registryID)},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(output.AuthorizationData) == 0 || output.AuthorizationData[0].AuthorizationToken == nil {
+		return "", fmt.Errorf("ECR returned no authorization token for registry %v", registryID)
+	}
+	return *output.AuthorizationData[0].AuthorizationToken, nil
+}
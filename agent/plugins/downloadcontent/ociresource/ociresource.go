@@ -0,0 +1,387 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ociresource implements the methods to access artifacts stored in OCI registries such as ECR
+package ociresource
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filemanager"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/remoteresource"
+)
+
+const (
+	mediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	// titleAnnotation is the annotation of a layer naming the file it contains
+	titleAnnotation = "org.opencontainers.image.title"
+	digestAlgorithm = "sha256:"
+
+	requestTimeout = 10 * time.Minute
+	// maxManifestSize is the size of the largest manifest read from a registry
+	maxManifestSize = 4 * 1024 * 1024
+)
+
+var (
+	// ecrRegistryRegex matches ECR registries and captures their account and region
+	ecrRegistryRegex = regexp.MustCompile(`^([0-9]{12})\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?$`)
+	digestRegex      = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+	challengeRegex   = regexp.MustCompile(`(\w+)="([^"]*)"`)
+)
+
+// OCIResource is a struct for the remote resource of type Oci
+type OCIResource struct {
+	Info   OCIInfo
+	ocidep ocideps
+	client *http.Client
+
+	// registryURL is the scheme and host of the registry
+	registryURL string
+	repository  string
+	// reference is the tag or digest of the manifest
+	reference string
+	// authorization is the value of the Authorization header of registry requests
+	authorization string
+}
+
+// OCIInfo represents the sourceInfo type sent by runcommand
+type OCIInfo struct {
+	// Reference of the artifact, e.g. 123456789012.dkr.ecr.us-east-1.amazonaws.com/tools:1.2 or registry/repository@sha256:...
+	Reference string `json:"reference"`
+	// Digest is the expected digest of the manifest of the artifact
+	Digest string `json:"digest"`
+}
+
+// ociManifest is the image manifest listing the layers of an artifact
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+// ociDescriptor describes a layer of an artifact
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// NewOCIResource is a constructor of type OCIResource
+func NewOCIResource(log log.T, info string) (*OCIResource, error) {
+	ociInfo, err := parseSourceInfo(info)
+	if err != nil {
+		return nil, fmt.Errorf("Oci SourceInfo parsing failed. %v", err)
+	}
+
+	resource := &OCIResource{
+		Info:   ociInfo,
+		ocidep: ociDepImpl{},
+		client: &http.Client{Timeout: requestTimeout},
+	}
+	if ociInfo.Reference != "" {
+		var host string
+		if host, resource.repository, resource.reference, err = parseReference(ociInfo.Reference); err != nil {
+			return nil, err
+		}
+		resource.registryURL = "https://" + host
+	}
+	return resource, nil
+}
+
+// parseSourceInfo unmarshals the information in sourceInfo of type OCIInfo and returns it
+func parseSourceInfo(sourceInfo string) (ociInfo OCIInfo, err error) {
+	if err = jsonutil.Unmarshal(sourceInfo, &ociInfo); err != nil {
+		return ociInfo, errors.New("SourceInfo could not be unmarshalled for SourceType Oci. Please check JSON format of SourceInfo")
+	}
+	return ociInfo, nil
+}
+
+// parseReference splits the reference into the registry host, the repository and the tag or digest of the manifest.
+func parseReference(reference string) (host string, repository string, tagOrDigest string, err error) {
+	slash := strings.Index(reference, "/")
+	if slash <= 0 || !strings.ContainsAny(reference[:slash], ".:") {
+		return "", "", "", fmt.Errorf("reference %v must start with the registry host", reference)
+	}
+	host, repository = reference[:slash], reference[slash+1:]
+
+	if at := strings.Index(repository, "@"); at >= 0 {
+		repository, tagOrDigest = repository[:at], repository[at+1:]
+		if !digestRegex.MatchString(tagOrDigest) {
+			return "", "", "", fmt.Errorf("reference %v has an invalid digest", reference)
+		}
+	} else if colon := strings.LastIndex(repository, ":"); colon >= 0 {
+		repository, tagOrDigest = repository[:colon], repository[colon+1:]
+	} else {
+		tagOrDigest = "latest"
+	}
+	if repository == "" || tagOrDigest == "" {
+		return "", "", "", fmt.Errorf("reference %v must name a repository and a tag or digest", reference)
+	}
+	return host, repository, tagOrDigest, nil
+}
+
+// ValidateLocationInfo ensures that the required parameters of SourceInfo are specified
+func (resource *OCIResource) ValidateLocationInfo() (valid bool, err error) {
+	if resource.Info.Reference == "" {
+		return false, errors.New("Reference in SourceInfo must be specified")
+	}
+	if resource.Info.Digest != "" && !digestRegex.MatchString(resource.Info.Digest) {
+		return false, errors.New("Digest in SourceInfo must be a sha256 digest")
+	}
+	return true, nil
+}
+
+// DownloadRemoteResource downloads the layers of the artifact after verifying the digest of its manifest.
+// Each layer is saved as the file named by its title annotation in the destination directory,
+// an artifact with a single layer is saved as the destination if it is not a directory.
+func (resource *OCIResource) DownloadRemoteResource(log log.T, filesys filemanager.FileSystem, destinationPath string) (err error, result *remoteresource.DownloadResult) {
+	if destinationPath == "" {
+		destinationPath = appconfig.DownloadRoot
+	}
+
+	if err = resource.authorize(log); err != nil {
+		return fmt.Errorf("failed to authenticate to registry %v: %v", resource.registryURL, err), nil
+	}
+
+	manifest, err := resource.getManifest(log)
+	if err != nil {
+		return err, nil
+	}
+
+	destinationIsDir := len(manifest.Layers) > 1 ||
+		filesys.Exists(destinationPath) && filesys.IsDirectory(destinationPath) ||
+		os.IsPathSeparator(destinationPath[len(destinationPath)-1])
+
+	result = &remoteresource.DownloadResult{}
+	for _, layer := range manifest.Layers {
+		filePath := destinationPath
+		if destinationIsDir {
+			var fileName string
+			if fileName, err = layerFileName(layer); err != nil {
+				return err, nil
+			}
+			filePath = filepath.Join(destinationPath, fileName)
+		}
+		if err = filesys.MakeDirs(filepath.Dir(filePath)); err != nil {
+			return fmt.Errorf("failed to create directory %v: %v", filepath.Dir(filePath), err), nil
+		}
+		if err = resource.downloadBlob(log, layer, filePath); err != nil {
+			return err, nil
+		}
+		result.Files = append(result.Files, filePath)
+	}
+	return nil, result
+}
+
+// authorize sets the credentials of ECR registries, obtained with the instance credentials.
+// Other registries are accessed anonymously or with the token their authentication challenge asks for.
+func (resource *OCIResource) authorize(log log.T) error {
+	host := strings.TrimPrefix(resource.registryURL, "https://")
+	match := ecrRegistryRegex.FindStringSubmatch(host)
+	if match == nil {
+		return nil
+	}
+	token, err := resource.ocidep.GetECRAuthorizationToken(log, match[2], match[1])
+	if err != nil {
+		return err
+	}
+	resource.authorization = "Basic " + token
+	return nil
+}
+
+// getManifest downloads the manifest of the artifact and verifies its digest.
+func (resource *OCIResource) getManifest(log log.T) (manifest ociManifest, err error) {
+	manifestURL := fmt.Sprintf("%v/v2/%v/manifests/%v", resource.registryURL, resource.repository, resource.reference)
+	response, err := resource.get(log, manifestURL, mediaTypeOCIManifest+", "+mediaTypeDockerManifest)
+	if err != nil {
+		return manifest, err
+	}
+	defer response.Body.Close()
+
+	content, err := ioutil.ReadAll(io.LimitReader(response.Body, maxManifestSize))
+	if err != nil {
+		return manifest, fmt.Errorf("failed to read manifest of %v: %v", resource.Info.Reference, err)
+	}
+
+	checksum := sha256.Sum256(content)
+	digest := digestAlgorithm + hex.EncodeToString(checksum[:])
+	for _, expected := range []string{resource.Info.Digest, response.Header.Get("Docker-Content-Digest")} {
+		if expected != "" && expected != digest {
+			return manifest, fmt.Errorf("digest %v of the manifest of %v does not match the expected digest %v", digest, resource.Info.Reference, expected)
+		}
+	}
+	if strings.HasPrefix(resource.reference, digestAlgorithm) && resource.reference != digest {
+		return manifest, fmt.Errorf("digest %v of the manifest does not match the reference %v", digest, resource.Info.Reference)
+	}
+	log.Infof("Verified manifest %v of %v", digest, resource.Info.Reference)
+
+	if err = json.Unmarshal(content, &manifest); err != nil {
+		return manifest, fmt.Errorf("failed to parse manifest of %v: %v", resource.Info.Reference, err)
+	}
+	if manifest.MediaType != "" && manifest.MediaType != mediaTypeOCIManifest && manifest.MediaType != mediaTypeDockerManifest {
+		return manifest, fmt.Errorf("manifest of %v has unsupported media type %v", resource.Info.Reference, manifest.MediaType)
+	}
+	if len(manifest.Layers) == 0 {
+		return manifest, fmt.Errorf("artifact %v has no layers", resource.Info.Reference)
+	}
+	return manifest, nil
+}
+
+// downloadBlob downloads the layer to the file and verifies its size and digest, removing the file if they do not match.
+func (resource *OCIResource) downloadBlob(log log.T, layer ociDescriptor, filePath string) (err error) {
+	if !digestRegex.MatchString(layer.Digest) {
+		return fmt.Errorf("layer digest %v is not a sha256 digest", layer.Digest)
+	}
+	blobURL := fmt.Sprintf("%v/v2/%v/blobs/%v", resource.registryURL, resource.repository, layer.Digest)
+	response, err := resource.get(log, blobURL, "")
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to create file %v: %v", filePath, err)
+	}
+	defer func() {
+		file.Close()
+		if err != nil {
+			os.Remove(filePath)
+		}
+	}()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), io.LimitReader(response.Body, layer.Size+1))
+	if err != nil {
+		return fmt.Errorf("failed to download layer %v: %v", layer.Digest, err)
+	}
+	if size != layer.Size {
+		return fmt.Errorf("layer %v has %v bytes, expected %v", layer.Digest, size, layer.Size)
+	}
+	if digest := digestAlgorithm + hex.EncodeToString(hash.Sum(nil)); digest != layer.Digest {
+		return fmt.Errorf("digest %v of the layer does not match the expected digest %v", digest, layer.Digest)
+	}
+	log.Infof("Downloaded layer %v to %v", layer.Digest, filePath)
+	return nil
+}
+
+// get sends a GET request to the registry, answering a bearer token authentication challenge once.
+func (resource *OCIResource) get(log log.T, requestURL string, accept string) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		request, err := http.NewRequest(http.MethodGet, requestURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			request.Header.Set("Accept", accept)
+		}
+		if resource.authorization != "" {
+			request.Header.Set("Authorization", resource.authorization)
+		}
+
+		response, err := resource.client.Do(request)
+		if err != nil {
+			return nil, fmt.Errorf("request to %v failed: %v", requestURL, err)
+		}
+		if response.StatusCode == http.StatusOK {
+			return response, nil
+		}
+		response.Body.Close()
+
+		challenge := response.Header.Get("Www-Authenticate")
+		if response.StatusCode != http.StatusUnauthorized || attempt > 1 || !strings.HasPrefix(challenge, "Bearer ") {
+			return nil, fmt.Errorf("request to %v failed with status %v", requestURL, response.Status)
+		}
+		if err = resource.getBearerToken(log, challenge); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// getBearerToken gets the token asked for by the bearer authentication challenge of the registry.
+func (resource *OCIResource) getBearerToken(log log.T, challenge string) error {
+	parameters := make(map[string]string)
+	for _, match := range challengeRegex.FindAllStringSubmatch(challenge, -1) {
+		parameters[match[1]] = match[2]
+	}
+	realm, err := url.Parse(parameters["realm"])
+	if err != nil || realm.Scheme != "https" {
+		return fmt.Errorf("registry requested authentication with invalid realm %v", parameters["realm"])
+	}
+	query := realm.Query()
+	for _, name := range []string{"service", "scope"} {
+		if parameters[name] != "" {
+			query.Set(name, parameters[name])
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	log.Debugf("Getting registry token from %v", realm.Host)
+	request, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(resource.authorization, "Basic ") {
+		request.Header.Set("Authorization", resource.authorization)
+	}
+	response, err := resource.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to get registry token: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get registry token: %v", response.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err = json.NewDecoder(io.LimitReader(response.Body, maxManifestSize)).Decode(&token); err != nil {
+		return fmt.Errorf("failed to parse registry token: %v", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	resource.authorization = "Bearer " + token.Token
+	return nil
+}
+
+// layerFileName returns the name of the file of the layer, its title or its digest if it has no title.
+func layerFileName(layer ociDescriptor) (string, error) {
+	name := layer.Annotations[titleAnnotation]
+	if name == "" {
+		return strings.Replace(layer.Digest, ":", "_", 1), nil
+	}
+	if name != filepath.Base(name) || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("layer title %v is not a file name", name)
+	}
+	return name, nil
+}
@@ -0,0 +1,264 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ociresource
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filemanager"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/rip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var logMock = log.NewMockLog()
+
+type ociDepMock struct {
+	mock.Mock
+}
+
+func (m *ociDepMock) GetECRAuthorizationToken(log log.T, region string, registryID string) (token string, err error) {
+	args := m.Called(log, region, registryID)
+	return args.String(0), args.Error(1)
+}
+
+func digestOf(content string) string {
+	checksum := sha256.Sum256([]byte(content))
+	return digestAlgorithm + hex.EncodeToString(checksum[:])
+}
+
+// newRegistry starts a registry serving the manifest and blobs of the tools repository
+func newRegistry(manifest string, blobs map[string]string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/tools/manifests/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Docker-Content-Digest", digestOf(manifest))
+		fmt.Fprint(w, manifest)
+	})
+	mux.HandleFunc("/v2/tools/blobs/", func(w http.ResponseWriter, r *http.Request) {
+		blob, found := blobs[filepath.Base(r.URL.Path)]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, blob)
+	})
+	return httptest.NewTLSServer(mux)
+}
+
+func newTestResource(server *httptest.Server, reference string) *OCIResource {
+	return &OCIResource{
+		Info:        OCIInfo{Reference: reference},
+		ocidep:      new(ociDepMock),
+		client:      server.Client(),
+		registryURL: server.URL,
+		repository:  "tools",
+		reference:   "1.2",
+	}
+}
+
+func manifestOf(layers ...string) string {
+	return fmt.Sprintf(`{"schemaVersion":2,"mediaType":"%v","layers":[%v]}`, mediaTypeOCIManifest, join(layers))
+}
+
+func layerOf(content string, title string) string {
+	return fmt.Sprintf(`{"mediaType":"application/octet-stream","digest":"%v","size":%v,"annotations":{"%v":"%v"}}`,
+		digestOf(content), len(content), titleAnnotation, title)
+}
+
+func join(items []string) (joined string) {
+	for index, item := range items {
+		if index > 0 {
+			joined += ","
+		}
+		joined += item
+	}
+	return
+}
+
+func TestParseReference(t *testing.T) {
+	digest := digestOf("manifest")
+	testCases := []struct {
+		reference, host, repository, tagOrDigest string
+	}{
+		{"123456789012.dkr.ecr.us-east-1.amazonaws.com/tools:1.2", "123456789012.dkr.ecr.us-east-1.amazonaws.com", "tools", "1.2"},
+		{"localhost:5000/team/tools@" + digest, "localhost:5000", "team/tools", digest},
+		{"registry.example.com/tools", "registry.example.com", "tools", "latest"},
+	}
+	for _, testCase := range testCases {
+		host, repository, tagOrDigest, err := parseReference(testCase.reference)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.host, host)
+		assert.Equal(t, testCase.repository, repository)
+		assert.Equal(t, testCase.tagOrDigest, tagOrDigest)
+	}
+
+	for _, reference := range []string{"tools:1.2", "registry.example.com/tools@sha256:abc", "registry.example.com/"} {
+		_, _, _, err := parseReference(reference)
+		assert.Error(t, err, reference)
+	}
+}
+
+func TestOCIResource_ValidateLocationInfo(t *testing.T) {
+	resource, err := NewOCIResource(logMock, `{"reference": "registry.example.com/tools:1.2"}`)
+	assert.NoError(t, err)
+	_, err = resource.ValidateLocationInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, "https://registry.example.com", resource.registryURL)
+
+	resource, err = NewOCIResource(logMock, `{}`)
+	assert.NoError(t, err)
+	_, err = resource.ValidateLocationInfo()
+	assert.Error(t, err)
+
+	resource, err = NewOCIResource(logMock, `{"reference": "registry.example.com/tools:1.2", "digest": "md5:abc"}`)
+	assert.NoError(t, err)
+	_, err = resource.ValidateLocationInfo()
+	assert.Error(t, err)
+}
+
+func TestOCIResource_AuthorizeECR(t *testing.T) {
+	depMock := new(ociDepMock)
+	depMock.On("GetECRAuthorizationToken", logMock, "us-west-2", "123456789012").Return("dXNlcjpwYXNz", nil)
+	resource := &OCIResource{
+		ocidep:      depMock,
+		registryURL: "https://123456789012.dkr.ecr.us-west-2.amazonaws.com",
+	}
+
+	assert.NoError(t, resource.authorize(logMock))
+	assert.Equal(t, "Basic dXNlcjpwYXNz", resource.authorization)
+	depMock.AssertExpectations(t)
+}
+
+func TestOCIResource_DownloadRemoteResource(t *testing.T) {
+	blobs := map[string]string{digestOf("first"): "first", digestOf("second"): "second"}
+	manifest := manifestOf(layerOf("first", "first.txt"), layerOf("second", "second.txt"))
+	server := newRegistry(manifest, blobs)
+	defer server.Close()
+
+	destination, _ := ioutil.TempDir("", "oci")
+	defer os.RemoveAll(destination)
+
+	resource := newTestResource(server, "registry/tools:1.2")
+	resource.Info.Digest = digestOf(manifest)
+	err, result := resource.DownloadRemoteResource(logMock, filemanager.FileSystemImpl{}, destination)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(destination, "first.txt"), filepath.Join(destination, "second.txt")}, result.Files)
+	content, _ := ioutil.ReadFile(filepath.Join(destination, "second.txt"))
+	assert.Equal(t, "second", string(content))
+}
+
+func TestOCIResource_DownloadSingleLayerToFile(t *testing.T) {
+	server := newRegistry(manifestOf(layerOf("tool", "tool.bin")), map[string]string{digestOf("tool"): "tool"})
+	defer server.Close()
+
+	destination, _ := ioutil.TempDir("", "oci")
+	defer os.RemoveAll(destination)
+
+	filePath := filepath.Join(destination, "renamed.bin")
+	err, result := newTestResource(server, "registry/tools:1.2").DownloadRemoteResource(logMock, filemanager.FileSystemImpl{}, filePath)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filePath}, result.Files)
+}
+
+func TestOCIResource_ManifestDigestMismatch(t *testing.T) {
+	server := newRegistry(manifestOf(layerOf("tool", "tool.bin")), map[string]string{digestOf("tool"): "tool"})
+	defer server.Close()
+
+	resource := newTestResource(server, "registry/tools:1.2")
+	resource.Info.Digest = digestOf("other")
+	err, _ := resource.DownloadRemoteResource(logMock, filemanager.FileSystemImpl{}, os.TempDir())
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not match the expected digest")
+}
+
+func TestOCIResource_LayerDigestMismatch(t *testing.T) {
+	server := newRegistry(manifestOf(layerOf("tool", "tool.bin")), map[string]string{digestOf("tool"): "evil"})
+	defer server.Close()
+
+	destination, _ := ioutil.TempDir("", "oci")
+	defer os.RemoveAll(destination)
+
+	err, _ := newTestResource(server, "registry/tools:1.2").DownloadRemoteResource(logMock, filemanager.FileSystemImpl{}, destination)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not match the expected digest")
+	assert.False(t, filemanager.FileSystemImpl{}.Exists(filepath.Join(destination, "tool.bin")))
+}
+
+func TestOCIResource_BearerChallenge(t *testing.T) {
+	manifest := manifestOf(layerOf("tool", "tool.bin"))
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			assert.Equal(t, "repository:tools:pull", r.URL.Query().Get("scope"))
+			fmt.Fprint(w, `{"token": "secret"}`)
+		case r.Header.Get("Authorization") != "Bearer secret":
+			w.Header().Set("Www-Authenticate", fmt.Sprintf(`Bearer realm="%v/token",service="registry",scope="repository:tools:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+		case filepath.Base(r.URL.Path) == "1.2":
+			fmt.Fprint(w, manifest)
+		default:
+			fmt.Fprint(w, "tool")
+		}
+	}))
+	defer server.Close()
+
+	destination, _ := ioutil.TempDir("", "oci")
+	defer os.RemoveAll(destination)
+
+	err, result := newTestResource(server, "registry/tools:1.2").DownloadRemoteResource(logMock, filemanager.FileSystemImpl{}, destination)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(destination, "tool.bin")}, result.Files)
+}
+
+func TestLayerFileName(t *testing.T) {
+	digest := digestOf("tool")
+	name, err := layerFileName(ociDescriptor{Digest: digest})
+	assert.NoError(t, err)
+	assert.Equal(t, "sha256_"+digest[len(digestAlgorithm):], name)
+
+	for _, title := range []string{"../tool", "dir/tool", `dir\tool`, ".."} {
+		_, err = layerFileName(ociDescriptor{Digest: digest, Annotations: map[string]string{titleAnnotation: title}})
+		assert.Error(t, err, title)
+	}
+}
+
+func TestNewECRClientUsesFipsEndpoint(t *testing.T) {
+	platform.SetRegion("us-east-1")
+	fipsEnabled := rip.FipsEnabled
+	rip.FipsEnabled = func() bool { return true }
+	defer func() { rip.FipsEnabled = fipsEnabled }()
+
+	assert.Equal(t, "https://ecr-fips.us-west-2.amazonaws.com", newECRClient("us-west-2").Endpoint)
+
+	// registries in regions without FIPS endpoints fail instead of using the regular endpoint
+	_, err := ociDepImpl{}.GetECRAuthorizationToken(log.NewMockLog(), "eu-west-1", "123456789012")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no FIPS endpoint")
+}
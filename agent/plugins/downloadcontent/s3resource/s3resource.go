@@ -194,6 +194,9 @@ func (s3 *S3Resource) ValidateLocationInfo() (valid bool, err error) {
 func (s3 *S3Resource) getS3BucketURLString(log log.T) (Url *url.URL, err error) {
 
 	endpoint := s3util.GetS3Endpoint(s3.s3Object.Region)
+	if endpoint == "" {
+		return nil, fmt.Errorf("no S3 endpoint found for region %s", s3.s3Object.Region)
+	}
 	bucketURL := "https://" + endpoint + "/" + s3.s3Object.Bucket
 	return url.Parse(bucketURL)
 }
@@ -18,7 +18,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
@@ -28,8 +30,9 @@ import (
 )
 
 var (
-	lock             sync.RWMutex
-	contentHashStore map[string]string
+	lock               sync.RWMutex
+	contentHashStore   map[string]string
+	lastFullUploadTime time.Time
 )
 
 //TODO: add unit tests
@@ -45,12 +48,15 @@ func machineInfoProvider() (name string, err error) {
 type Optimizer interface {
 	UpdateContentHash(inventoryItemName, hash string) (err error)
 	GetContentHash(inventoryItemName string) (hash string)
+	UpdateLastFullUploadTime(uploadTime time.Time) (err error)
+	GetLastFullUploadTime() (uploadTime time.Time)
 }
 
 // Impl implements content hash optimizations for inventory plugin
 type Impl struct {
-	log      log.T
-	location string //where the content hash data is persisted in file-systems
+	log                log.T
+	location           string //where the content hash data is persisted in file-systems
+	fullUploadLocation string //where the time of the last upload of all inventory types is persisted
 }
 
 func NewOptimizerImpl(context context.T) (*Impl, error) {
@@ -76,7 +82,10 @@ func NewOptimizerImplWithLocation(log log.T, rootDir string, fileName string) (*
 		rootDir,
 		fileName)
 
+	optimizer.fullUploadLocation = filepath.Join(filepath.Dir(optimizer.location), appconfig.InventoryFullUploadFileName)
+
 	contentHashStore = make(map[string]string)
+	lastFullUploadTime = time.Time{}
 
 	//read old content hash values from file
 	if fileutil.Exists(optimizer.location) {
@@ -94,6 +103,16 @@ func NewOptimizerImplWithLocation(log log.T, rootDir string, fileName string) (*
 		}
 	}
 
+	//read the time of the last full upload, all inventory types are uploaded when it is unknown
+	if fileutil.Exists(optimizer.fullUploadLocation) {
+		if content, err = fileutil.ReadAllText(optimizer.fullUploadLocation); err == nil {
+			if lastFullUploadTime, err = time.Parse(time.RFC3339, strings.TrimSpace(content)); err != nil {
+				optimizer.log.Debugf("Unable to read the time of the last full inventory upload - thereby ignoring it")
+				lastFullUploadTime = time.Time{}
+			}
+		}
+	}
+
 	return &optimizer, nil
 }
 
@@ -127,3 +146,23 @@ func (i *Impl) GetContentHash(inventoryItemName string) (hash string) {
 
 	return
 }
+
+func (i *Impl) UpdateLastFullUploadTime(uploadTime time.Time) (err error) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	lastFullUploadTime = uploadTime
+
+	//persist the data in file system
+	if _, err = fileutil.WriteIntoFileWithPermissions(i.fullUploadLocation, uploadTime.UTC().Format(time.RFC3339), appconfig.ReadWriteAccess); err != nil {
+		err = fmt.Errorf("Unable to update the time of the last full upload in file - %v because - %v", i.fullUploadLocation, err.Error())
+	}
+	return
+}
+
+func (i *Impl) GetLastFullUploadTime() (uploadTime time.Time) {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	return lastFullUploadTime
+}
@@ -28,7 +28,9 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/aws/amazon-ssm-agent/agent/rip"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ssm"
@@ -46,6 +48,8 @@ type T interface {
 	SendDataToSSM(context context.T, items []*ssm.InventoryItem) (err error)
 	ConvertToSsmInventoryItems(context context.T, items []model.Item) (optimizedInventoryItems, nonOptimizedInventoryItems []*ssm.InventoryItem, err error)
 	GetDirtySsmInventoryItems(context context.T, items []model.Item) (dirtyInventoryItems []*ssm.InventoryItem, err error)
+	IsFullUploadDue(context context.T) bool
+	UpdateFullUploadTime(context context.T)
 }
 
 type SSMCaller interface {
@@ -90,6 +94,9 @@ func NewInventoryUploader(context context.T) (*InventoryUploader, error) {
 	}
 	sess := session.New(cfg)
 	sess.Handlers.Build.PushBack(request.MakeAddToUserAgentHandler(appCfg.Agent.Name, appCfg.Agent.Version))
+	if err == nil && appCfg.Ssm.Endpoint == "" {
+		rip.UseFipsEndpoint(sess, aws.StringValue(cfg.Region), "ssm")
+	}
 
 	uploader.ssm = ssm.New(sess)
 
@@ -166,6 +173,32 @@ func (u *InventoryUploader) updateContentHash(context context.T, items []*ssm.In
 	}
 }
 
+// IsFullUploadDue returns true if all inventory types must be uploaded, which is always the case unless delta uploads
+// are enabled, in which case all types are uploaded again once the full upload interval has passed
+func (u *InventoryUploader) IsFullUploadDue(context context.T) bool {
+	log := context.Log()
+	ssmCfg := context.AppConfig().Ssm
+	if !ssmCfg.InventoryDeltaUploadEnabled {
+		return true
+	}
+
+	lastFullUploadTime := u.optimizer.GetLastFullUploadTime()
+	interval := time.Duration(ssmCfg.InventoryFullUploadIntervalHours) * time.Hour
+	if time.Since(lastFullUploadTime) >= interval {
+		log.Debugf("Last full inventory upload at %v is older than %v - uploading all inventory types", lastFullUploadTime, interval)
+		return true
+	}
+	return false
+}
+
+// UpdateFullUploadTime records that all inventory types have been uploaded
+func (u *InventoryUploader) UpdateFullUploadTime(context context.T) {
+	log := context.Log()
+	if err := u.optimizer.UpdateLastFullUploadTime(time.Now()); err != nil {
+		log.Errorf("failed to update the time of the last full inventory upload because of - %v", err.Error())
+	}
+}
+
 func calculateCheckSum(data []byte) (checkSum string) {
 	sum := md5.Sum(data)
 	checkSum = base64.StdEncoding.EncodeToString(sum[:])
@@ -18,9 +18,14 @@ import (
 	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/aws/amazon-ssm-agent/agent/rip"
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -236,3 +241,63 @@ func testSendData(t *testing.T, putInventorySucceeds bool) {
 	mockSSM.AssertExpectations(t)
 	mockOptimizer.AssertExpectations(t)
 }
+
+// contextWithDeltaUploads returns a mock context whose appconfig enables delta uploads
+func contextWithDeltaUploads(fullUploadIntervalHours int) *context.Mock {
+	config := appconfig.SsmagentConfig{}
+	config.Ssm.InventoryDeltaUploadEnabled = true
+	config.Ssm.InventoryFullUploadIntervalHours = fullUploadIntervalHours
+	c := new(context.Mock)
+	c.On("Log").Return(log.NewMockLog())
+	c.On("AppConfig").Return(config)
+	return c
+}
+
+func TestIsFullUploadDue(t *testing.T) {
+	mockOptimizer := NewMockDefault()
+	mockOptimizer.On("GetLastFullUploadTime").Return(time.Now().Add(-2 * time.Hour))
+	u := &InventoryUploader{optimizer: mockOptimizer}
+
+	// all inventory types are always uploaded unless delta uploads are enabled
+	assert.True(t, u.IsFullUploadDue(context.NewMockDefault()))
+	assert.False(t, u.IsFullUploadDue(contextWithDeltaUploads(24)))
+	assert.True(t, u.IsFullUploadDue(contextWithDeltaUploads(1)))
+}
+
+func TestIsFullUploadDueWithoutFullUpload(t *testing.T) {
+	mockOptimizer := NewMockDefault()
+	mockOptimizer.On("GetLastFullUploadTime").Return(time.Time{})
+	u := &InventoryUploader{optimizer: mockOptimizer}
+
+	assert.True(t, u.IsFullUploadDue(contextWithDeltaUploads(24)))
+}
+
+func TestUpdateFullUploadTime(t *testing.T) {
+	mockOptimizer := NewMockDefault()
+	mockOptimizer.On("UpdateLastFullUploadTime", mock.AnythingOfType("time.Time")).Return(nil)
+	u := &InventoryUploader{optimizer: mockOptimizer}
+
+	u.UpdateFullUploadTime(context.NewMockDefault())
+
+	mockOptimizer.AssertExpectations(t)
+}
+
+func TestNewInventoryUploaderUsesFipsEndpoint(t *testing.T) {
+	fipsEnabled := rip.FipsEnabled
+	rip.FipsEnabled = func() bool { return true }
+	defer func() { rip.FipsEnabled = fipsEnabled }()
+	// the optimizer is not needed to check the ssm client
+	machineIDProvider = func() (string, error) { return "", errors.New("no machine id") }
+	defer func() { machineIDProvider = machineInfoProvider }()
+
+	platform.SetRegion("us-west-2")
+	u, _ := NewInventoryUploader(context.NewMockDefault())
+	assert.Equal(t, "https://ssm-fips.us-west-2.amazonaws.com", u.ssm.(*ssm.SSM).Endpoint)
+
+	// regions without FIPS endpoints fail instead of using the regular endpoint
+	platform.SetRegion("eu-west-1")
+	u, _ = NewInventoryUploader(context.NewMockDefault())
+	_, err := u.ssm.PutInventory(&ssm.PutInventoryInput{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no FIPS endpoint")
+}
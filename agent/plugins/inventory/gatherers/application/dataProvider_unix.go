@@ -28,6 +28,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/throttle"
 	"github.com/twinj/uuid"
 )
 
@@ -51,6 +52,18 @@ var (
 		// PackageId should be something like ${Filename}, but for some reason that field does not get printed,
 		// so we build PackageId from parts
 		`","PackageId":"` + mark(`${Package}_${Version}_${Architecture}.deb`) + `"},`
+
+	// snap and flatpak commands related constants
+	snapCmd            = "snap"
+	snapArgs           = []string{"list", "--unicode=never", "--color=never"}
+	flatpakCmd         = "flatpak"
+	flatpakArgs        = []string{"list", "--app", "--columns=application,version,branch,origin,arch,name"}
+	flatpakColumnCount = 6
+)
+
+const (
+	snapApplicationType    = "snap"
+	flatpakApplicationType = "flatpak"
 )
 
 func randomString(length int) string {
@@ -64,8 +77,11 @@ func mark(s string) string {
 // decoupling exec.Command for easy testability
 var cmdExecutor = executeCommand
 
+// decoupling exec.LookPath for easy testability
+var lookPath = exec.LookPath
+
 func executeCommand(command string, args ...string) ([]byte, error) {
-	return exec.Command(command, args...).CombinedOutput()
+	return throttle.Command(command, args...).CombinedOutput()
 }
 
 func platformInfoProvider(log log.T) (name string, err error) {
@@ -91,9 +107,113 @@ func collectPlatformDependentApplicationData(context context.T) (appData []model
 		}
 	}
 
+	// snaps and flatpaks are installed next to the packages of the distribution
+	appData = append(appData, getSnapApplicationData(context)...)
+	appData = append(appData, getFlatpakApplicationData(context)...)
 	return
 }
 
+// getSnapApplicationData returns the installed snaps, the tracked channel is reported as release
+// and the revision as part of the package id
+func getSnapApplicationData(context context.T) (data []model.ApplicationData) {
+	/*
+		Sample snap list output:
+
+		Name              Version    Rev    Tracking         Publisher   Notes
+		amazon-ssm-agent  2.3.672.0  1480   latest/stable    aws*        classic
+		core18            20190709   1074   stable           canonical*  base
+	*/
+	output, found := runApplicationCommand(context.Log(), snapCmd, snapArgs)
+	if !found {
+		return
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	header := strings.Fields(lines[0])
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[name] = i
+	}
+	if _, found := columns["Name"]; !found || len(lines) < 2 {
+		return
+	}
+	column := func(fields []string, names ...string) string {
+		for _, name := range names {
+			if i, found := columns[name]; found && i < len(fields) {
+				return fields[i]
+			}
+		}
+		return ""
+	}
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < len(header)-1 {
+			continue
+		}
+		name := column(fields, "Name")
+		item := model.ApplicationData{
+			Name:            name,
+			Version:         column(fields, "Version"),
+			Release:         column(fields, "Tracking"),
+			Publisher:       strings.TrimRight(column(fields, "Publisher", "Developer"), "*"),
+			ApplicationType: snapApplicationType,
+			PackageId:       fmt.Sprintf("%v_%v.snap", name, column(fields, "Rev")),
+			CompType:        componentType(name),
+		}
+		data = append(data, item)
+	}
+	context.Log().Infof("Number of snaps detected - %v", len(data))
+	return
+}
+
+// getFlatpakApplicationData returns the installed flatpak applications, the branch is reported as release
+// and the application ref as package id
+func getFlatpakApplicationData(context context.T) (data []model.ApplicationData) {
+	/*
+		Sample flatpak list output, the columns are tab separated:
+
+		org.gimp.GIMP	2.10.12	stable	flathub	x86_64	GNU Image Manipulation Program
+	*/
+	output, found := runApplicationCommand(context.Log(), flatpakCmd, flatpakArgs)
+	if !found {
+		return
+	}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(strings.TrimRight(line, "\r"), "\t")
+		if len(fields) != flatpakColumnCount {
+			continue
+		}
+		application, version, branch, origin, arch, name := fields[0], fields[1], fields[2], fields[3], fields[4], fields[5]
+		data = append(data, model.ApplicationData{
+			Name:            application,
+			Version:         version,
+			Release:         branch,
+			Publisher:       origin,
+			Architecture:    model.FormatArchitecture(arch),
+			ApplicationType: flatpakApplicationType,
+			Summary:         name,
+			PackageId:       fmt.Sprintf("%v/%v/%v", application, arch, branch),
+			CompType:        componentType(application),
+		})
+	}
+	context.Log().Infof("Number of flatpaks detected - %v", len(data))
+	return
+}
+
+// runApplicationCommand runs the command of an optional package manager, found is false if the
+// package manager is not installed or fails
+func runApplicationCommand(log log.T, command string, args []string) (output string, found bool) {
+	if _, err := lookPath(command); err != nil {
+		return "", false
+	}
+	log.Debugf("Executing command: %v %v", command, args)
+	result, err := cmdExecutor(command, args...)
+	if err != nil {
+		log.Infof("Unable to list %v applications: %v %v", command, err, string(result))
+		return "", false
+	}
+	return string(result), true
+}
+
 // getApplicationData runs a shell command and gets information about all packages/applications
 func getApplicationData(context context.T, command string, args []string) (data []model.ApplicationData, err error) {
 
@@ -17,7 +17,9 @@
 package application
 
 import (
+	"errors"
 	"fmt"
+	"os/exec"
 	"testing"
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
@@ -185,6 +187,8 @@ func TestGetApplicationData(t *testing.T) {
 
 func TestCollectApplicationData(t *testing.T) {
 	mockContext := context.NewMockDefault()
+	lookPath = MockLookPath()
+	defer func() { lookPath = exec.LookPath }()
 
 	// both dpkg and rpm return result without error
 	cmdExecutor = MockTestExecutorWithoutError
@@ -238,3 +242,96 @@ func TestCollectAndMergePackagesPlatformError(t *testing.T) {
 	data := CollectApplicationData(mockContext)
 	assert.Equal(t, len(mockData), len(data), "Wrong number of entries")
 }
+
+const (
+	sampleSnapData = `Name              Version    Rev    Tracking       Publisher   Notes
+amazon-ssm-agent  2.3.672.0  1480   latest/stable  aws*        classic
+core18            20190709   1074   stable         canonical** base
+`
+	sampleFlatpakData = "org.gimp.GIMP\t2.10.12\tstable\tflathub\tx86_64\tGNU Image Manipulation Program\n" +
+		"org.videolan.VLC\t3.0.7\tstable\tflathub\taarch64\tVLC\n"
+)
+
+// MockLookPath finds the given commands only
+func MockLookPath(commands ...string) func(string) (string, error) {
+	return func(file string) (string, error) {
+		for _, command := range commands {
+			if command == file {
+				return "/usr/bin/" + file, nil
+			}
+		}
+		return "", errors.New("not found")
+	}
+}
+
+// MockTestExecutorWithSnapsAndFlatpaks fails dpkg and rpm and lists snaps and flatpaks
+func MockTestExecutorWithSnapsAndFlatpaks(command string, args ...string) ([]byte, error) {
+	switch command {
+	case snapCmd:
+		return []byte(sampleSnapData), nil
+	case flatpakCmd:
+		return []byte(sampleFlatpakData), nil
+	}
+	return MockTestExecutorWithError(command, args...)
+}
+
+func TestCollectSnapAndFlatpakApplicationData(t *testing.T) {
+	mockContext := context.NewMockDefault()
+	cmdExecutor = MockTestExecutorWithSnapsAndFlatpaks
+	lookPath = MockLookPath(snapCmd, flatpakCmd)
+	defer func() { lookPath = exec.LookPath }()
+
+	data := collectPlatformDependentApplicationData(mockContext)
+
+	assert.Equal(t, []model.ApplicationData{
+		{
+			Name:            "amazon-ssm-agent",
+			Version:         "2.3.672.0",
+			Release:         "latest/stable",
+			Publisher:       "aws",
+			ApplicationType: "snap",
+			PackageId:       "amazon-ssm-agent_1480.snap",
+			CompType:        model.AWSComponent,
+		},
+		{
+			Name:            "core18",
+			Version:         "20190709",
+			Release:         "stable",
+			Publisher:       "canonical",
+			ApplicationType: "snap",
+			PackageId:       "core18_1074.snap",
+		},
+		{
+			Name:            "org.gimp.GIMP",
+			Version:         "2.10.12",
+			Release:         "stable",
+			Publisher:       "flathub",
+			Architecture:    model.Arch64Bit,
+			ApplicationType: "flatpak",
+			Summary:         "GNU Image Manipulation Program",
+			PackageId:       "org.gimp.GIMP/x86_64/stable",
+		},
+		{
+			Name:            "org.videolan.VLC",
+			Version:         "3.0.7",
+			Release:         "stable",
+			Publisher:       "flathub",
+			Architecture:    model.FormatArchitecture("aarch64"),
+			ApplicationType: "flatpak",
+			Summary:         "VLC",
+			PackageId:       "org.videolan.VLC/aarch64/stable",
+		},
+	}, data)
+}
+
+func TestCollectSnapApplicationDataWithoutSnaps(t *testing.T) {
+	mockContext := context.NewMockDefault()
+	cmdExecutor = func(command string, args ...string) ([]byte, error) {
+		return []byte("No snaps are installed yet. Try 'snap install hello-world'.\n"), nil
+	}
+	lookPath = MockLookPath(snapCmd)
+	defer func() { lookPath = exec.LookPath }()
+
+	assert.Equal(t, 0, len(getSnapApplicationData(mockContext)))
+	assert.Equal(t, 0, len(getFlatpakApplicationData(mockContext)))
+}
@@ -20,12 +20,12 @@ package application
 import (
 	"encoding/json"
 	"fmt"
-	"os/exec"
 	"runtime"
 	"strings"
 
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/throttle"
 )
 
 const (
@@ -106,7 +106,7 @@ var ArgsToReadRegistryFromWow6432Node = fmt.Sprintf(ArgsToReadRegistryApplicatio
 var cmdExecutor = executeCommand
 
 func executeCommand(command string, args ...string) ([]byte, error) {
-	return exec.Command(command, args...).CombinedOutput()
+	return throttle.Command(command, args...).CombinedOutput()
 }
 
 // collectPlatformDependentApplicationData collects application data for windows platform
@@ -0,0 +1,52 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package certificate
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var testCertificateData = []model.CertificateData{
+	{
+		Subject:      "CN=www.example.com",
+		Issuer:       "CN=Example CA,O=Example",
+		SerialNumber: "1A2B",
+		Thumbprint:   "0F1E2D3C4B5A69788796A5B4C3D2E1F00F1E2D3C",
+		DnsNames:     "www.example.com",
+		NotBefore:    "2019-01-01T00:00:00Z",
+		NotAfter:     "2020-01-01T00:00:00Z",
+		Path:         "/etc/pki/tls/certs/www.example.com.pem",
+	},
+}
+
+func testCollectCertificateData(context context.T, config model.Config) (data []model.CertificateData, err error) {
+	return testCertificateData, nil
+}
+
+func TestGatherer(t *testing.T) {
+	contextMock := context.NewMockDefault()
+	gatherer := Gatherer(contextMock)
+	collectData = testCollectCertificateData
+	defer func() { collectData = collectCertificateData }()
+	item, err := gatherer.Run(contextMock, model.Config{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(item))
+	assert.Equal(t, GathererName, item[0].Name)
+	assert.Equal(t, SchemaVersionOfCertificateGatherer, item[0].SchemaVersion)
+	assert.Equal(t, testCertificateData, item[0].Content)
+}
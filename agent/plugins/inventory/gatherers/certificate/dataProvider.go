@@ -0,0 +1,179 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package certificate
+
+import (
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+// filterObj selects the certificates to collect, either the certificate files of Path or the certificates
+// of the Windows certificate Store, e.g. LocalMachine/My
+type filterObj struct {
+	Path      string
+	Recursive bool
+	Store     string
+}
+
+// CertificateCountLimit keeps the certificate information under the item size limit
+const CertificateCountLimit = 1000
+
+// certificateFileMaxSize skips large files which are not certificates
+const certificateFileMaxSize = 1024 * 1024
+
+const pemCertificateType = "CERTIFICATE"
+
+// certificateFile is a file and the certificates it contains
+type certificateFile struct {
+	path         string
+	certificates []*x509.Certificate
+}
+
+//decoupling for easy testability
+var readFile = ioutil.ReadFile
+var filepathWalk = filepath.Walk
+var readStore = readStoreCertificates
+
+//collectCertificateData returns the certificates of the paths and stores of the configuration
+func collectCertificateData(context context.T, config model.Config) (data []model.CertificateData, err error) {
+	log := context.Log()
+	var filterList []filterObj
+	jsonBody := []byte(strings.Replace(config.Filters, `\`, `/`, -1)) //this is to convert the backslash in windows path to slash
+	if err = json.Unmarshal(jsonBody, &filterList); err != nil {
+		log.Error(err)
+		return
+	}
+
+	data = []model.CertificateData{}
+	collected := map[string]bool{}
+	add := func(path string, certificates []*x509.Certificate) {
+		for _, certificate := range certificates {
+			item := certificateData(path, certificate)
+			if key := item.Path + "|" + item.Thumbprint; !collected[key] && len(data) < CertificateCountLimit {
+				collected[key] = true
+				data = append(data, item)
+			}
+		}
+	}
+
+	for _, filter := range filterList {
+		if filter.Store != "" {
+			store := path.Clean(filter.Store)
+			rawCertificates, storeErr := readStore(log, store)
+			if storeErr != nil {
+				log.Errorf("Unable to read certificate store %v: %v", store, storeErr)
+				continue
+			}
+			for _, raw := range rawCertificates {
+				if certificate, parseErr := x509.ParseCertificate(raw); parseErr == nil {
+					add(store, []*x509.Certificate{certificate})
+				}
+			}
+		}
+		if filter.Path != "" {
+			for _, file := range readCertificateFiles(log, os.ExpandEnv(filter.Path), filter.Recursive) {
+				add(file.path, file.certificates)
+			}
+		}
+	}
+
+	if len(data) == CertificateCountLimit {
+		log.Warnf("Certificate count limit %v reached, the remaining certificates are not collected", CertificateCountLimit)
+	}
+	log.Infof("Collected Certificates %d", len(data))
+	return
+}
+
+// readCertificateFiles returns the certificates of the file or of the files of the directory,
+// files which do not contain certificates are skipped
+func readCertificateFiles(log log.T, root string, recursive bool) (files []certificateFile) {
+	err := filepathWalk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			log.Debugf("Unable to access %v: %v", path, err)
+			return nil
+		}
+		if info.IsDir() {
+			if path != root && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || info.Size() > certificateFileMaxSize {
+			return nil
+		}
+		content, readErr := readFile(path)
+		if readErr != nil {
+			log.Debugf("Unable to read %v: %v", path, readErr)
+			return nil
+		}
+		if parsed := parseCertificates(content); len(parsed) > 0 {
+			files = append(files, certificateFile{path: path, certificates: parsed})
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("Unable to read certificates of %v: %v", root, err)
+	}
+	return
+}
+
+// parseCertificates returns the certificates of the PEM blocks of the content, or the certificate of DER encoded content
+func parseCertificates(content []byte) (certificates []*x509.Certificate) {
+	for rest := content; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if block.Type != pemCertificateType {
+			continue
+		}
+		if certificate, err := x509.ParseCertificate(block.Bytes); err == nil {
+			certificates = append(certificates, certificate)
+		}
+	}
+	if len(certificates) == 0 {
+		if certificate, err := x509.ParseCertificate(content); err == nil {
+			certificates = append(certificates, certificate)
+		}
+	}
+	return
+}
+
+// certificateData returns the inventory data of the certificate, the thumbprint is the sha1 hash Windows displays
+func certificateData(path string, certificate *x509.Certificate) model.CertificateData {
+	thumbprint := sha1.Sum(certificate.Raw)
+	return model.CertificateData{
+		Subject:      certificate.Subject.String(),
+		Issuer:       certificate.Issuer.String(),
+		SerialNumber: strings.ToUpper(certificate.SerialNumber.Text(16)),
+		Thumbprint:   strings.ToUpper(hex.EncodeToString(thumbprint[:])),
+		DnsNames:     strings.Join(certificate.DNSNames, ","),
+		NotBefore:    certificate.NotBefore.UTC().Format(time.RFC3339),
+		NotAfter:     certificate.NotAfter.UTC().Format(time.RFC3339),
+		Path:         path,
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package certificate
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var testNotAfter = time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// createTestCertificate returns a DER encoded self signed certificate
+func createTestCertificate(t *testing.T, commonName string, serial int64) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName, "www." + commonName},
+		NotBefore:    testNotAfter.AddDate(-1, 0, 0),
+		NotAfter:     testNotAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	return der
+}
+
+func pemEncode(der ...[]byte) (content []byte) {
+	for _, block := range der {
+		content = append(content, pem.EncodeToMemory(&pem.Block{Type: pemCertificateType, Bytes: block})...)
+	}
+	return
+}
+
+func TestCollectCertificateDataFromFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certificates")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	subDir := filepath.Join(dir, "sub")
+	assert.NoError(t, os.MkdirAll(subDir, appconfig.ReadWriteExecuteAccess))
+
+	bundle := filepath.Join(dir, "bundle.pem")
+	ioutil.WriteFile(bundle, pemEncode(createTestCertificate(t, "a.example.com", 10), createTestCertificate(t, "b.example.com", 11)), appconfig.ReadWriteAccess)
+	ioutil.WriteFile(filepath.Join(dir, "key.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("key")}), appconfig.ReadWriteAccess)
+	ioutil.WriteFile(filepath.Join(dir, "readme.txt"), []byte("not a certificate"), appconfig.ReadWriteAccess)
+	der := filepath.Join(subDir, "c.cer")
+	ioutil.WriteFile(der, createTestCertificate(t, "c.example.com", 255), appconfig.ReadWriteAccess)
+
+	data, err := collectCertificateData(context.NewMockDefault(), model.Config{Filters: fmt.Sprintf(`[{"Path": %q}]`, filepath.ToSlash(dir))})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(data))
+	assert.Equal(t, "CN=a.example.com", data[0].Subject)
+	assert.Equal(t, "CN=a.example.com", data[0].Issuer)
+	assert.Equal(t, "A", data[0].SerialNumber)
+	assert.Equal(t, "a.example.com,www.a.example.com", data[0].DnsNames)
+	assert.Equal(t, "2030-01-02T03:04:05Z", data[0].NotAfter)
+	assert.Equal(t, 40, len(data[0].Thumbprint))
+	assert.Equal(t, bundle, data[0].Path)
+	assert.Equal(t, "CN=b.example.com", data[1].Subject)
+
+	// recursive filters include the subdirectories and the same certificate of the same file is only reported once
+	data, err = collectCertificateData(context.NewMockDefault(), model.Config{Filters: fmt.Sprintf(`[{"Path": %q, "Recursive": true}, {"Path": %q}]`, filepath.ToSlash(dir), filepath.ToSlash(der))})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(data))
+	assert.Equal(t, "CN=c.example.com", data[2].Subject)
+	assert.Equal(t, "FF", data[2].SerialNumber)
+}
+
+func TestCollectCertificateDataFromStore(t *testing.T) {
+	der := createTestCertificate(t, "store.example.com", 1)
+	readStore = func(log log.T, store string) ([][]byte, error) {
+		if store == "LocalMachine/My" {
+			return [][]byte{der, []byte("invalid")}, nil
+		}
+		return nil, fmt.Errorf("store %v not found", store)
+	}
+	defer func() { readStore = readStoreCertificates }()
+
+	data, err := collectCertificateData(context.NewMockDefault(), model.Config{Filters: `[{"Store": "LocalMachine\\My"}, {"Store": "LocalMachine\\Missing"}]`})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(data))
+	assert.Equal(t, "CN=store.example.com", data[0].Subject)
+	assert.Equal(t, "LocalMachine/My", data[0].Path)
+}
+
+func TestCollectCertificateDataInvalidFilters(t *testing.T) {
+	data, err := collectCertificateData(context.NewMockDefault(), model.Config{Filters: "invalid"})
+	assert.Error(t, err)
+	assert.Nil(t, data)
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package certificate
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	PowershellCmd = "powershell"
+
+	// storeCertificatesScript writes the base64 encoded certificates of the store, one per line
+	storeCertificatesScript = `
+[Console]::OutputEncoding = [System.Text.Encoding]::UTF8
+Get-ChildItem -Path 'Cert:\%v' | Where-Object { $_ -is [System.Security.Cryptography.X509Certificates.X509Certificate2] } | ForEach-Object { [Convert]::ToBase64String($_.RawData) }
+`
+)
+
+// storeNamePattern matches store locations and names such as LocalMachine\My, the name is part of the script
+var storeNamePattern = regexp.MustCompile(`^(LocalMachine|CurrentUser)\\[A-Za-z0-9 _.-]+$`)
+
+var cmdExecutor = executeCommand
+
+func executeCommand(command string, args ...string) ([]byte, error) {
+	return exec.Command(command, args...).Output()
+}
+
+// readStoreCertificates returns the raw certificates of the Windows certificate store
+func readStoreCertificates(log log.T, store string) (certificates [][]byte, err error) {
+	store = strings.Replace(store, "/", `\`, -1)
+	if !storeNamePattern.MatchString(store) {
+		return nil, fmt.Errorf("invalid certificate store %v, expected a store such as LocalMachine\\My", store)
+	}
+
+	var output []byte
+	if output, err = cmdExecutor(PowershellCmd, fmt.Sprintf(storeCertificatesScript, store)); err != nil {
+		return nil, fmt.Errorf("command failed with error: %v", err)
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		raw, decodeErr := base64.StdEncoding.DecodeString(line)
+		if decodeErr != nil {
+			log.Debugf("Unable to decode certificate of store %v: %v", store, decodeErr)
+			continue
+		}
+		certificates = append(certificates, raw)
+	}
+	return
+}
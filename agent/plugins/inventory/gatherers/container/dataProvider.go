@@ -0,0 +1,299 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	// ResourceTypes of the container inventory entries
+	ResourceTypeContainer = "Container"
+	ResourceTypeImage     = "Image"
+
+	// Runtimes the containers and images are collected from
+	RuntimeDocker     = "docker"
+	RuntimeContainerd = "containerd"
+
+	dockerCmd = "docker"
+	ctrCmd    = "ctr"
+
+	// dockerNamespace is the containerd namespace of the docker containers, which are reported by docker
+	dockerNamespace = "moby"
+
+	ctrTaskRunning = "RUNNING"
+	noneValue      = "<none>"
+)
+
+// dockerContainer is the part of the docker inspect output which is reported to inventory
+type dockerContainer struct {
+	Id      string
+	Name    string
+	Created string
+	Image   string
+	State   struct {
+		Status string
+	}
+	Config struct {
+		Image        string
+		ExposedPorts map[string]struct{}
+	}
+}
+
+// dockerImage is the json format of the docker images output
+type dockerImage struct {
+	ID         string
+	Repository string
+	Tag        string
+	Digest     string
+	CreatedAt  string
+}
+
+// ctrContainer is the part of the ctr containers info output which is reported to inventory
+type ctrContainer struct {
+	ID        string
+	Image     string
+	CreatedAt string
+}
+
+// ctrImage is an image listed by ctr images ls
+type ctrImage struct {
+	ref    string
+	digest string
+}
+
+// decoupling exec.LookPath for easy testability
+var lookPath = exec.LookPath
+
+var cmdExecutor = executeCommand
+
+func executeCommand(command string, args ...string) ([]byte, error) {
+	return exec.Command(command, args...).Output()
+}
+
+// collectContainerData collects the running containers and the local images of the container runtimes installed
+// on the instance, a runtime whose data cannot be collected is skipped so the other runtimes are still reported
+func collectContainerData(context context.T, config model.Config) (data []model.ContainerData, err error) {
+	collectors := []struct {
+		runtime string
+		command string
+		collect func(log log.T) ([]model.ContainerData, error)
+	}{
+		{RuntimeDocker, dockerCmd, collectDockerData},
+		{RuntimeContainerd, ctrCmd, collectContainerdData},
+	}
+	log := context.Log()
+
+	data = []model.ContainerData{}
+	for _, collector := range collectors {
+		if _, err := lookPath(collector.command); err != nil {
+			log.Debugf("%v is not installed, skipping %v containers", collector.command, collector.runtime)
+			continue
+		}
+		runtimeData, err := collector.collect(log)
+		if err != nil {
+			log.Warnf("Unable to collect %v containers: %v", collector.runtime, err)
+			continue
+		}
+		data = append(data, runtimeData...)
+	}
+	return
+}
+
+// collectDockerData collects the running docker containers and the docker images
+func collectDockerData(log log.T) (data []model.ContainerData, err error) {
+	output, err := runCommand(log, dockerCmd, "images", "--digests", "--no-trunc", "--format", "{{json .}}")
+	if err != nil {
+		return
+	}
+	digests := map[string]string{}
+	for _, line := range outputLines(output) {
+		var image dockerImage
+		if err = json.Unmarshal([]byte(line), &image); err != nil {
+			return nil, fmt.Errorf("unable to parse docker images output: %v", err)
+		}
+		digest := noneToEmpty(image.Digest)
+		if digest != "" {
+			digests[image.ID] = digest
+		}
+		data = append(data, model.ContainerData{
+			ResourceType: ResourceTypeImage,
+			Runtime:      RuntimeDocker,
+			Id:           image.ID,
+			Name:         imageName(image.Repository, image.Tag),
+			ImageDigest:  digest,
+			CreatedTime:  image.CreatedAt,
+		})
+	}
+
+	if output, err = runCommand(log, dockerCmd, "ps", "--quiet", "--no-trunc"); err != nil {
+		return
+	}
+	containerIds := outputLines(output)
+	if len(containerIds) == 0 {
+		return
+	}
+	if output, err = runCommand(log, dockerCmd, append([]string{"inspect"}, containerIds...)...); err != nil {
+		return
+	}
+	var containers []dockerContainer
+	if err = json.Unmarshal(output, &containers); err != nil {
+		return nil, fmt.Errorf("unable to parse docker inspect output: %v", err)
+	}
+	for _, container := range containers {
+		ports := []string{}
+		for port := range container.Config.ExposedPorts {
+			ports = append(ports, port)
+		}
+		sort.Strings(ports)
+		data = append(data, model.ContainerData{
+			ResourceType: ResourceTypeContainer,
+			Runtime:      RuntimeDocker,
+			Id:           container.Id,
+			Name:         strings.TrimPrefix(container.Name, "/"),
+			Image:        container.Config.Image,
+			ImageId:      container.Image,
+			ImageDigest:  digests[container.Image],
+			Status:       container.State.Status,
+			Ports:        strings.Join(ports, ","),
+			CreatedTime:  container.Created,
+		})
+	}
+	return
+}
+
+// collectContainerdData collects the running containerd containers and the containerd images of all namespaces
+func collectContainerdData(log log.T) (data []model.ContainerData, err error) {
+	output, err := runCommand(log, ctrCmd, "namespaces", "list", "--quiet")
+	if err != nil {
+		return
+	}
+	for _, namespace := range outputLines(output) {
+		if namespace == dockerNamespace {
+			continue
+		}
+		var namespaceData []model.ContainerData
+		if namespaceData, err = collectContainerdNamespaceData(log, namespace); err != nil {
+			return
+		}
+		data = append(data, namespaceData...)
+	}
+	return
+}
+
+// collectContainerdNamespaceData collects the running containers and the images of the containerd namespace
+func collectContainerdNamespaceData(log log.T, namespace string) (data []model.ContainerData, err error) {
+	output, err := runCommand(log, ctrCmd, "--namespace", namespace, "images", "list")
+	if err != nil {
+		return
+	}
+	digests := map[string]string{}
+	for _, image := range parseCtrImages(output) {
+		digests[image.ref] = image.digest
+		data = append(data, model.ContainerData{
+			ResourceType: ResourceTypeImage,
+			Runtime:      RuntimeContainerd,
+			Id:           image.digest,
+			Name:         image.ref,
+			ImageDigest:  image.digest,
+		})
+	}
+
+	if output, err = runCommand(log, ctrCmd, "--namespace", namespace, "tasks", "list"); err != nil {
+		return
+	}
+	for _, containerId := range parseCtrRunningTasks(output) {
+		if output, err = runCommand(log, ctrCmd, "--namespace", namespace, "containers", "info", containerId); err != nil {
+			return
+		}
+		var container ctrContainer
+		if err = json.Unmarshal(output, &container); err != nil {
+			return nil, fmt.Errorf("unable to parse ctr containers info output: %v", err)
+		}
+		data = append(data, model.ContainerData{
+			ResourceType: ResourceTypeContainer,
+			Runtime:      RuntimeContainerd,
+			Id:           container.ID,
+			Name:         namespace + "/" + container.ID,
+			Image:        container.Image,
+			ImageDigest:  digests[container.Image],
+			Status:       strings.ToLower(ctrTaskRunning),
+			CreatedTime:  container.CreatedAt,
+		})
+	}
+	return
+}
+
+// parseCtrImages parses the REF TYPE DIGEST SIZE PLATFORMS LABELS table of ctr images list
+func parseCtrImages(output []byte) (images []ctrImage) {
+	lines := outputLines(output)
+	for i := 1; i < len(lines); i++ {
+		if fields := strings.Fields(lines[i]); len(fields) >= 3 {
+			images = append(images, ctrImage{ref: fields[0], digest: fields[2]})
+		}
+	}
+	return
+}
+
+// parseCtrRunningTasks parses the TASK PID STATUS table of ctr tasks list and returns the ids of the running containers
+func parseCtrRunningTasks(output []byte) (containerIds []string) {
+	lines := outputLines(output)
+	for i := 1; i < len(lines); i++ {
+		if fields := strings.Fields(lines[i]); len(fields) >= 3 && fields[2] == ctrTaskRunning {
+			containerIds = append(containerIds, fields[0])
+		}
+	}
+	return
+}
+
+func runCommand(log log.T, command string, args ...string) (output []byte, err error) {
+	log.Debugf("Executing command: %v %v", command, args)
+	if output, err = cmdExecutor(command, args...); err != nil {
+		err = fmt.Errorf("command %v %v failed: %v", command, strings.Join(args, " "), err)
+	}
+	return
+}
+
+// outputLines returns the non empty lines of the command output
+func outputLines(output []byte) (lines []string) {
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return
+}
+
+func imageName(repository, tag string) string {
+	repository, tag = noneToEmpty(repository), noneToEmpty(tag)
+	if repository == "" || tag == "" {
+		return repository
+	}
+	return repository + ":" + tag
+}
+
+func noneToEmpty(value string) string {
+	if value == noneValue {
+		return ""
+	}
+	return value
+}
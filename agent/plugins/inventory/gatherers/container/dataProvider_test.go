@@ -0,0 +1,154 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package container
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testDockerImages = `{"Containers":"N/A","CreatedAt":"2019-05-01 10:00:00 +0000 UTC","Digest":"sha256:digest1","ID":"sha256:image1","Repository":"nginx","Tag":"latest"}
+{"Containers":"N/A","CreatedAt":"2019-04-01 10:00:00 +0000 UTC","Digest":"<none>","ID":"sha256:image2","Repository":"<none>","Tag":"<none>"}
+`
+	testDockerContainerIds = "container1\n"
+	testDockerInspect      = `[{"Id":"container1","Name":"/web","Created":"2019-05-02T10:00:00Z","Image":"sha256:image1",
+"State":{"Status":"running"},"Config":{"Image":"nginx","ExposedPorts":{"80/tcp":{},"443/tcp":{}}}}]`
+
+	testCtrNamespaces = "default\nmoby\n"
+	testCtrImages     = `REF                            TYPE                                                 DIGEST          SIZE     PLATFORMS   LABELS
+docker.io/library/redis:latest application/vnd.docker.distribution.manifest.v2+json sha256:digest3 29.5 MiB linux/amd64 -
+`
+	testCtrTasks = `TASK      PID     STATUS
+cache     1234    RUNNING
+stopped   0       STOPPED
+`
+	testCtrContainerInfo = `{"ID":"cache","Image":"docker.io/library/redis:latest","CreatedAt":"2019-05-03T10:00:00Z"}`
+)
+
+var testContainerOutputs = map[string]string{
+	"docker images --digests --no-trunc --format {{json .}}": testDockerImages,
+	"docker ps --quiet --no-trunc":                           testDockerContainerIds,
+	"docker inspect container1":                              testDockerInspect,
+	"ctr namespaces list --quiet":                            testCtrNamespaces,
+	"ctr --namespace default images list":                    testCtrImages,
+	"ctr --namespace default tasks list":                     testCtrTasks,
+	"ctr --namespace default containers info cache":          testCtrContainerInfo,
+}
+
+var testContainerData = []model.ContainerData{
+	{
+		ResourceType: ResourceTypeImage,
+		Runtime:      RuntimeDocker,
+		Id:           "sha256:image1",
+		Name:         "nginx:latest",
+		ImageDigest:  "sha256:digest1",
+		CreatedTime:  "2019-05-01 10:00:00 +0000 UTC",
+	},
+	{
+		ResourceType: ResourceTypeImage,
+		Runtime:      RuntimeDocker,
+		Id:           "sha256:image2",
+		CreatedTime:  "2019-04-01 10:00:00 +0000 UTC",
+	},
+	{
+		ResourceType: ResourceTypeContainer,
+		Runtime:      RuntimeDocker,
+		Id:           "container1",
+		Name:         "web",
+		Image:        "nginx",
+		ImageId:      "sha256:image1",
+		ImageDigest:  "sha256:digest1",
+		Status:       "running",
+		Ports:        "443/tcp,80/tcp",
+		CreatedTime:  "2019-05-02T10:00:00Z",
+	},
+	{
+		ResourceType: ResourceTypeImage,
+		Runtime:      RuntimeContainerd,
+		Id:           "sha256:digest3",
+		Name:         "docker.io/library/redis:latest",
+		ImageDigest:  "sha256:digest3",
+	},
+	{
+		ResourceType: ResourceTypeContainer,
+		Runtime:      RuntimeContainerd,
+		Id:           "cache",
+		Name:         "default/cache",
+		Image:        "docker.io/library/redis:latest",
+		ImageDigest:  "sha256:digest3",
+		Status:       "running",
+		CreatedTime:  "2019-05-03T10:00:00Z",
+	},
+}
+
+func mockContainerCommands(outputs map[string]string, installed ...string) func() {
+	cmdExecutor = func(command string, args ...string) ([]byte, error) {
+		if output, found := outputs[command+" "+strings.Join(args, " ")]; found {
+			return []byte(output), nil
+		}
+		return nil, errors.New("exit status 1")
+	}
+	lookPath = func(file string) (string, error) {
+		for _, command := range installed {
+			if command == file {
+				return "/usr/bin/" + file, nil
+			}
+		}
+		return "", errors.New("not found")
+	}
+	return func() {
+		cmdExecutor = executeCommand
+		lookPath = exec.LookPath
+	}
+}
+
+func TestCollectContainerData(t *testing.T) {
+	defer mockContainerCommands(testContainerOutputs, dockerCmd, ctrCmd)()
+
+	data, err := collectContainerData(context.NewMockDefault(), model.Config{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, testContainerData, data)
+}
+
+func TestCollectContainerDataWithoutRuntimes(t *testing.T) {
+	defer mockContainerCommands(testContainerOutputs)()
+
+	data, err := collectContainerData(context.NewMockDefault(), model.Config{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(data))
+}
+
+func TestCollectContainerDataSkipsFailedRuntime(t *testing.T) {
+	outputs := map[string]string{}
+	for command, output := range testContainerOutputs {
+		if !strings.HasPrefix(command, dockerCmd) {
+			outputs[command] = output
+		}
+	}
+	defer mockContainerCommands(outputs, dockerCmd, ctrCmd)()
+
+	data, err := collectContainerData(context.NewMockDefault(), model.Config{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, testContainerData[3:], data)
+}
@@ -147,6 +147,24 @@ func (t *T) Run(context context.T, configuration model.Config) (items []model.It
 		}
 	}
 
+	// Get custom inventory items of the scripts configured in appconfig
+	for _, script := range context.AppConfig().Ssm.CustomInventoryScripts {
+		if _, ok := setTypeName[script.TypeName]; ok {
+			LogError(log, fmt.Errorf("Custom inventory typeName (%v) of script (%v) already exists,"+
+				" please remove the duplicate custom inventory file or script.", script.TypeName, script.Path))
+			continue
+		}
+		if len(items) >= CustomInventoryCountLimit {
+			LogError(log, fmt.Errorf("Total custom inventory type count exceeds limit (%v), skipping script %v",
+				CustomInventoryCountLimit, script.Path))
+			break
+		}
+		if scriptItem, err := getItemFromScript(log, script); err == nil {
+			setTypeName[scriptItem.Name] = true
+			items = append(items, scriptItem)
+		}
+	}
+
 	count := len(items)
 	log.Debugf("Count of custom inventory items : %v.", count)
 	if count == 0 {
@@ -0,0 +1,136 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package custom
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	// DefaultScriptTimeoutSeconds is the timeout of custom inventory scripts which do not declare one
+	DefaultScriptTimeoutSeconds = 60
+)
+
+// decoupling for easy testability
+var runScriptFunc = runScript
+
+// runScript runs the custom inventory script and returns its stdout, the script is killed when it exceeds the timeout
+func runScript(script appconfig.CustomInventoryScript, timeout time.Duration) (output []byte, err error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(script.Path, script.Arguments...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err = cmd.Start(); err != nil {
+		return
+	}
+	timer := time.AfterFunc(timeout, func() { cmd.Process.Kill() })
+	err = cmd.Wait()
+	if !timer.Stop() {
+		return nil, fmt.Errorf("script timed out after %v", timeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("script failed with error: %v, stderr: %v", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// getItemFromScript runs a custom inventory script and converts its output to an inventory item
+// after validating it against the schema declared for the script
+func getItemFromScript(log log.T, script appconfig.CustomInventoryScript) (item model.Item, err error) {
+	declared := model.CustomInventoryItem{TypeName: script.TypeName, SchemaVersion: script.SchemaVersion}
+	if err = validateTypeName(log, declared); err != nil {
+		return
+	}
+	if err = validateSchemaVersion(log, declared); err != nil {
+		return
+	}
+	if len(script.Attributes) == 0 || len(script.Attributes) > AttributeCountLimit {
+		err = fmt.Errorf("Custom inventory script %v must declare between 1 and %v attributes", script.Path, AttributeCountLimit)
+		LogError(log, err)
+		return
+	}
+
+	timeout := time.Duration(script.TimeoutSeconds) * time.Second
+	if script.TimeoutSeconds <= 0 {
+		timeout = DefaultScriptTimeoutSeconds * time.Second
+	}
+	log.Debugf("Running custom inventory script %v for %v", script.Path, script.TypeName)
+	var output []byte
+	if output, err = runScriptFunc(script, timeout); err != nil {
+		LogError(log, fmt.Errorf("Custom inventory script %v failed: %v", script.Path, err))
+		return
+	}
+
+	var entryArray []map[string]interface{}
+	if err = json.Unmarshal(output, &entryArray); err != nil {
+		err = fmt.Errorf("Custom inventory script %v output is not a json array of entries: %v", script.Path, err)
+		LogError(log, err)
+		return
+	}
+	for _, attributes := range entryArray {
+		if err = validateContentEntryAttributes(log, attributes); err != nil {
+			return
+		}
+		if err = validateDeclaredAttributes(log, script, attributes); err != nil {
+			return
+		}
+	}
+	if entryArray == nil {
+		entryArray = []map[string]interface{}{}
+	}
+
+	// CaptureTime must be in UTC so that formatting to RFC3339
+	// Example: 2016-07-30T18:15:37Z
+	item = model.Item{
+		Name:          script.TypeName,
+		SchemaVersion: script.SchemaVersion,
+		Content:       entryArray,
+		CaptureTime:   time.Now().UTC().Format(time.RFC3339),
+	}
+	return
+}
+
+// validateDeclaredAttributes validates that the entry has exactly the attributes declared for the script
+func validateDeclaredAttributes(log log.T, script appconfig.CustomInventoryScript, attributes map[string]interface{}) (err error) {
+	var missing, undeclared []string
+	declared := make(map[string]bool)
+	for _, name := range script.Attributes {
+		declared[name] = true
+		if _, found := attributes[name]; !found {
+			missing = append(missing, name)
+		}
+	}
+	for name := range attributes {
+		if !declared[name] {
+			undeclared = append(undeclared, name)
+		}
+	}
+	if len(missing) > 0 || len(undeclared) > 0 {
+		sort.Strings(undeclared)
+		err = fmt.Errorf("Custom inventory script %v entry does not match the schema of %v, missing attributes: %v, undeclared attributes: %v",
+			script.Path, script.TypeName, missing, undeclared)
+		LogError(log, err)
+	}
+	return
+}
@@ -0,0 +1,140 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package custom
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testScript = appconfig.CustomInventoryScript{
+	TypeName:      "Custom:NginxSite",
+	SchemaVersion: "1.0",
+	Attributes:    []string{"ServerName", "Root"},
+	Path:          "/opt/inventory/nginx-sites.sh",
+}
+
+func mockScriptOutputs(outputs map[string]string) func() {
+	runScriptFunc = func(script appconfig.CustomInventoryScript, timeout time.Duration) ([]byte, error) {
+		if output, found := outputs[script.Path]; found {
+			return []byte(output), nil
+		}
+		return nil, errors.New("exit status 1")
+	}
+	return func() { runScriptFunc = runScript }
+}
+
+// contextWithScripts returns a mock context whose appconfig declares the custom inventory scripts
+func contextWithScripts(scripts ...appconfig.CustomInventoryScript) *context.Mock {
+	config := appconfig.SsmagentConfig{}
+	config.Ssm.CustomInventoryScripts = scripts
+	ctx := new(context.Mock)
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	return ctx
+}
+
+func TestGetItemFromScript(t *testing.T) {
+	defer mockScriptOutputs(map[string]string{
+		testScript.Path: `[{"ServerName": "example.com", "Root": "/var/www/example"}, {"Root": "/var/www/html", "ServerName": "_"}]`,
+	})()
+
+	item, err := getItemFromScript(log.NewMockLog(), testScript)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Custom:NginxSite", item.Name)
+	assert.Equal(t, "1.0", item.SchemaVersion)
+	assert.Equal(t, []map[string]interface{}{
+		{"ServerName": "example.com", "Root": "/var/www/example"},
+		{"ServerName": "_", "Root": "/var/www/html"},
+	}, item.Content)
+}
+
+func TestGetItemFromScriptInvalidOutput(t *testing.T) {
+	outputs := []string{
+		`{"ServerName": "example.com", "Root": "/var/www/example"}`,
+		`[{"ServerName": "example.com"}]`,
+		`[{"ServerName": "example.com", "Root": "/var/www/example", "Port": "80"}]`,
+		`[{"ServerName": "example.com", "Root": 1}]`,
+	}
+	for _, output := range outputs {
+		restore := mockScriptOutputs(map[string]string{testScript.Path: output})
+		_, err := getItemFromScript(log.NewMockLog(), testScript)
+		restore()
+		assert.Error(t, err, output)
+	}
+}
+
+func TestGetItemFromScriptInvalidDeclaration(t *testing.T) {
+	defer mockScriptOutputs(map[string]string{testScript.Path: `[]`})()
+
+	invalidTypeName := testScript
+	invalidTypeName.TypeName = "AWS:NginxSite"
+	invalidSchemaVersion := testScript
+	invalidSchemaVersion.SchemaVersion = "1"
+	noAttributes := testScript
+	noAttributes.Attributes = nil
+
+	for _, script := range []appconfig.CustomInventoryScript{invalidTypeName, invalidSchemaVersion, noAttributes} {
+		_, err := getItemFromScript(log.NewMockLog(), script)
+		assert.Error(t, err)
+	}
+}
+
+func TestRunWithScripts(t *testing.T) {
+	failing := testScript
+	failing.TypeName = "Custom:Failing"
+	failing.Path = "/opt/inventory/failing.sh"
+	duplicate := testScript
+	defer mockScriptOutputs(map[string]string{testScript.Path: `[]`})()
+	readDirFunc = func(dirname string) ([]os.FileInfo, error) { return nil, errors.New("not found") }
+	defer func() { readDirFunc = ReadDir }()
+
+	ctx := contextWithScripts(testScript, failing, duplicate)
+	items, err := Gatherer(ctx).Run(ctx, model.Config{Location: "custom"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, testScript.TypeName, items[0].Name)
+	assert.Equal(t, []map[string]interface{}{}, items[0].Content)
+}
+
+func TestRunScript(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	output, err := runScript(appconfig.CustomInventoryScript{Path: "sh", Arguments: []string{"-c", `echo '[]'`}}, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "[]\n", string(output))
+
+	_, err = runScript(appconfig.CustomInventoryScript{Path: "sh", Arguments: []string{"-c", "echo failed >&2; exit 3"}}, time.Minute)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed")
+
+	_, err = runScript(appconfig.CustomInventoryScript{Path: "sh", Arguments: []string{"-c", "sleep 10"}}, 100*time.Millisecond)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out")
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/throttle"
 )
 
 type filterObj struct {
@@ -113,6 +114,7 @@ func getFiles(log log.T, path string, pattern []string, recursive bool, fileLimi
 	dirScanCount := 0
 	if recursive {
 		err = filepathWalk(path, func(fp string, fi os.FileInfo, err error) error {
+			throttle.WaitFileOperation()
 			if err != nil {
 				LogError(log, err)
 				return nil
@@ -136,6 +138,7 @@ func getFiles(log log.T, path string, pattern []string, recursive bool, fileLimi
 			return nil
 		})
 	} else {
+		throttle.WaitFileOperation()
 		files, readDirErr := readDirFunc(path)
 		if readDirErr != nil {
 			LogError(log, readDirErr)
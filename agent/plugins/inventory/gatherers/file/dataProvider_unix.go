@@ -26,6 +26,7 @@ import (
 
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/throttle"
 )
 
 func expand(str string, mapping func(string) string) (newStr string, err error) {
@@ -36,6 +37,7 @@ func expand(str string, mapping func(string) string) (newStr string, err error)
 //getMetaData gets metadata for the specified file paths
 func getMetaData(log log.T, paths []string) (fileInfo []model.FileData, err error) {
 	for _, p := range paths {
+		throttle.WaitFileOperation()
 		fi, err := os.Stat(p)
 		if err != nil {
 			LogError(log, err)
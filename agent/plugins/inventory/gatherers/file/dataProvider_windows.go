@@ -19,7 +19,6 @@ package file
 import (
 	"encoding/json"
 	"fmt"
-	"os/exec"
 	"strings"
 
 	"path/filepath"
@@ -29,6 +28,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/throttle"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
 	"github.com/twinj/uuid"
 )
@@ -101,7 +101,7 @@ var cmdExecutor = executeCommand
 var writeFileText = writeFile
 
 func executeCommand(command string, args ...string) ([]byte, error) {
-	return exec.Command(command, args...).CombinedOutput()
+	return throttle.Command(command, args...).CombinedOutput()
 }
 
 //expand function expands windows environment variables
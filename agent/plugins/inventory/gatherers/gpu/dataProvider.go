@@ -0,0 +1,79 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package gpu
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	VendorNVIDIA = "NVIDIA"
+	VendorAMD    = "AMD"
+	VendorIntel  = "Intel"
+
+	nvidiaSmiCmd      = "nvidia-smi"
+	nvidiaSmiColumns  = 4
+	nvidiaSmiNotFound = "[Not Supported]"
+)
+
+// nvidiaSmiArgs query the name, driver version, memory in MiB and PCI bus id of the NVIDIA GPUs, one GPU per line
+var nvidiaSmiArgs = []string{"--query-gpu=name,driver_version,memory.total,pci.bus_id", "--format=csv,noheader,nounits"}
+
+// collectGPUData returns the GPUs of the instance ordered by bus id, instances without GPUs report no data
+func collectGPUData(context context.T, config model.Config) (data []model.GPUData, err error) {
+	log := context.Log()
+	data = collectGPUs(log)
+	sort.SliceStable(data, func(i, j int) bool {
+		if data[i].BusId != data[j].BusId {
+			return data[i].BusId < data[j].BusId
+		}
+		return data[i].Name < data[j].Name
+	})
+	log.Infof("Collected GPUs %d", len(data))
+	return
+}
+
+// collectNVIDIAGPUs returns the NVIDIA GPUs reported by nvidia-smi, found is false when nvidia-smi is not
+// installed or the NVIDIA driver is not loaded
+func collectNVIDIAGPUs(log log.T) (data []model.GPUData, found bool) {
+	output, err := cmdExecutor(nvidiaSmiCmd, nvidiaSmiArgs...)
+	if err != nil {
+		log.Debugf("Unable to query NVIDIA GPUs: %v", err)
+		return nil, false
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Split(line, ",")
+		if len(fields) != nvidiaSmiColumns {
+			continue
+		}
+		for i := range fields {
+			if fields[i] = strings.TrimSpace(fields[i]); fields[i] == nvidiaSmiNotFound {
+				fields[i] = ""
+			}
+		}
+		data = append(data, model.GPUData{
+			Name:          fields[0],
+			Vendor:        VendorNVIDIA,
+			DriverVersion: fields[1],
+			MemoryMiB:     fields[2],
+			BusId:         fields[3],
+		})
+	}
+	return data, true
+}
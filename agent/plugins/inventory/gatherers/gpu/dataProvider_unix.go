@@ -0,0 +1,101 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package gpu
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+//decoupling for easy testability
+var (
+	sysClassDrmPath = "/sys/class/drm"
+	sysModulePath   = "/sys/module"
+	cmdExecutor     = executeCommand
+)
+
+// cardPattern matches the DRM cards, not their connectors such as card0-HDMI-A-1
+var cardPattern = regexp.MustCompile(`^card[0-9]+$`)
+
+// pciVendors are the PCI vendor ids of the GPU vendors, other display devices such as the emulated VGA
+// of virtual machines are not reported
+var pciVendors = map[string]string{
+	"0x10de": VendorNVIDIA,
+	"0x1002": VendorAMD,
+	"0x8086": VendorIntel,
+}
+
+func executeCommand(command string, args ...string) ([]byte, error) {
+	return exec.Command(command, args...).Output()
+}
+
+// collectGPUs returns the NVIDIA GPUs reported by nvidia-smi and the other GPUs found in sysfs,
+// NVIDIA GPUs are read from sysfs when nvidia-smi is not available
+func collectGPUs(log log.T) (data []model.GPUData) {
+	data, nvidiaFound := collectNVIDIAGPUs(log)
+	cards, err := ioutil.ReadDir(sysClassDrmPath)
+	if err != nil {
+		log.Debugf("Unable to read %v: %v", sysClassDrmPath, err)
+		return
+	}
+	for _, card := range cards {
+		if !cardPattern.MatchString(card.Name()) {
+			continue
+		}
+		devicePath := filepath.Join(sysClassDrmPath, card.Name(), "device")
+		vendor, found := pciVendors[readAttribute(devicePath, "vendor")]
+		if !found || (vendor == VendorNVIDIA && nvidiaFound) {
+			continue
+		}
+		data = append(data, readCard(devicePath, vendor))
+	}
+	return
+}
+
+// readCard returns the GPU of the DRM card device, the name is the product name exposed by the driver
+// or the PCI device id
+func readCard(devicePath string, vendor string) model.GPUData {
+	gpu := model.GPUData{Vendor: vendor}
+	if gpu.Name = readAttribute(devicePath, "product_name"); gpu.Name == "" {
+		gpu.Name = vendor + " " + readAttribute(devicePath, "device")
+	}
+	if target, err := filepath.EvalSymlinks(devicePath); err == nil {
+		gpu.BusId = filepath.Base(target)
+	}
+	if driver, err := filepath.EvalSymlinks(filepath.Join(devicePath, "driver")); err == nil {
+		gpu.DriverVersion = readAttribute(filepath.Join(sysModulePath, filepath.Base(driver)), "version")
+	}
+	if vram, err := strconv.ParseInt(readAttribute(devicePath, "mem_info_vram_total"), 10, 64); err == nil {
+		gpu.MemoryMiB = strconv.FormatInt(vram/(1024*1024), 10)
+	}
+	return gpu
+}
+
+// readAttribute returns the trimmed content of the sysfs attribute, or empty when it does not exist
+func readAttribute(dir string, name string) string {
+	content, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
@@ -0,0 +1,121 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package gpu
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const testNvidiaSmiOutput = "Tesla T4, 418.87.00, 15109, 00000000:00:1E.0\nTesla T4, 418.87.00, [Not Supported], 00000000:00:1F.0\n"
+
+var testNVIDIAGPUData = []model.GPUData{
+	{Name: "Tesla T4", Vendor: "NVIDIA", DriverVersion: "418.87.00", MemoryMiB: "15109", BusId: "00000000:00:1E.0"},
+	{Name: "Tesla T4", Vendor: "NVIDIA", DriverVersion: "418.87.00", BusId: "00000000:00:1F.0"},
+}
+
+var testAMDGPUData = model.GPUData{Name: "Radeon Pro V520", Vendor: "AMD", DriverVersion: "5.9.20", MemoryMiB: "8192", BusId: "0000:00:1d.0"}
+
+// testCard is a DRM card of the test sysfs
+type testCard struct {
+	name       string
+	busId      string
+	driver     string
+	attributes map[string]string
+}
+
+// createTestSysfs creates the DRM cards, their PCI devices and the driver modules in a temporary directory
+func createTestSysfs(t *testing.T) (dir string) {
+	dir, err := ioutil.TempDir("", "sys")
+	assert.NoError(t, err)
+	cards := []testCard{
+		{"card0", "0000:00:02.0", "", map[string]string{"vendor": "0x1d0f", "device": "0x1111"}},
+		{"card1", "0000:00:1d.0", "amdgpu", map[string]string{"vendor": "0x1002", "device": "0x7362", "product_name": "Radeon Pro V520", "mem_info_vram_total": "8589934592"}},
+		{"card2", "0000:00:1e.0", "nvidia", map[string]string{"vendor": "0x10de", "device": "0x1eb8"}},
+	}
+	for _, card := range cards {
+		devicePath := filepath.Join(dir, "devices", card.busId)
+		assert.NoError(t, os.MkdirAll(devicePath, appconfig.ReadWriteExecuteAccess))
+		for name, value := range card.attributes {
+			assert.NoError(t, ioutil.WriteFile(filepath.Join(devicePath, name), []byte(value+"\n"), appconfig.ReadWriteAccess))
+		}
+		if card.driver != "" {
+			driverPath := filepath.Join(dir, "drivers", card.driver)
+			assert.NoError(t, os.MkdirAll(driverPath, appconfig.ReadWriteExecuteAccess))
+			assert.NoError(t, os.Symlink(driverPath, filepath.Join(devicePath, "driver")))
+		}
+		cardPath := filepath.Join(dir, "class", "drm", card.name)
+		assert.NoError(t, os.MkdirAll(cardPath, appconfig.ReadWriteExecuteAccess))
+		assert.NoError(t, os.Symlink(devicePath, filepath.Join(cardPath, "device")))
+	}
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "class", "drm", "card1-DP-1"), appconfig.ReadWriteExecuteAccess))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "module", "amdgpu"), appconfig.ReadWriteExecuteAccess))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "module", "amdgpu", "version"), []byte("5.9.20\n"), appconfig.ReadWriteAccess))
+
+	sysClassDrmPath = filepath.Join(dir, "class", "drm")
+	sysModulePath = filepath.Join(dir, "module")
+	return
+}
+
+func mockNvidiaSmi(output string, err error) func() {
+	cmdExecutor = func(command string, args ...string) ([]byte, error) {
+		return []byte(output), err
+	}
+	return func() { cmdExecutor = executeCommand }
+}
+
+func TestCollectGPUData(t *testing.T) {
+	dir := createTestSysfs(t)
+	defer os.RemoveAll(dir)
+	defer mockNvidiaSmi(testNvidiaSmiOutput, nil)()
+
+	data, err := collectGPUData(context.NewMockDefault(), model.Config{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, append(testNVIDIAGPUData, testAMDGPUData), data)
+}
+
+func TestCollectGPUDataWithoutNvidiaSmi(t *testing.T) {
+	dir := createTestSysfs(t)
+	defer os.RemoveAll(dir)
+	defer mockNvidiaSmi("", errors.New("executable file not found in $PATH"))()
+
+	data, err := collectGPUData(context.NewMockDefault(), model.Config{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.GPUData{
+		testAMDGPUData,
+		{Name: "NVIDIA 0x1eb8", Vendor: "NVIDIA", BusId: "0000:00:1e.0"},
+	}, data)
+}
+
+func TestCollectGPUDataWithoutGPUs(t *testing.T) {
+	sysClassDrmPath = filepath.Join(os.TempDir(), "missing", "drm")
+	defer mockNvidiaSmi("", errors.New("executable file not found in $PATH"))()
+
+	data, err := collectGPUData(context.NewMockDefault(), model.Config{})
+
+	assert.NoError(t, err)
+	assert.Empty(t, data)
+}
@@ -0,0 +1,109 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+// +build windows
+
+package gpu
+
+import (
+	"encoding/json"
+	"os/exec"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/twinj/uuid"
+)
+
+const PowershellCmd = "powershell"
+
+var (
+	startMarker = "<start" + randomString(8) + ">"
+	endMarker   = "<end" + randomString(8) + ">"
+
+	// videoControllerScript writes the display adapters of WMI except the basic display adapters of Windows,
+	// AdapterRAM is a 32 bit value so the memory of adapters with 4 GiB or more is not accurate
+	videoControllerScript = `
+[Console]::OutputEncoding = [System.Text.Encoding]::UTF8
+$gpus = @()
+Get-WmiObject Win32_VideoController | Where-Object { @("(Standard display types)", "Microsoft") -notcontains $_.AdapterCompatibility } | ForEach-Object {
+	$name = $_.Name
+	$vendor = $_.AdapterCompatibility
+	$driverVersion = $_.DriverVersion
+	$memory = ""
+	if ($_.AdapterRAM) {
+		$memory = [math]::Floor($_.AdapterRAM / 1MB)
+	}
+	$gpus += @"
+{"Name":"` + mark(`$name`) + `","Vendor":"` + mark(`$vendor`) + `","DriverVersion":"$driverVersion","MemoryMiB":"$memory"}
+"@
+}
+[Console]::WriteLine("[" + ($gpus -join ",") + "]")
+`
+)
+
+var cmdExecutor = executeCommand
+
+func executeCommand(command string, args ...string) ([]byte, error) {
+	return exec.Command(command, args...).Output()
+}
+
+func randomString(length int) string {
+	return uuid.NewV4().String()[:length]
+}
+
+func mark(s string) string {
+	return startMarker + s + endMarker
+}
+
+// collectGPUs returns the NVIDIA GPUs reported by nvidia-smi and the other display adapters of WMI,
+// NVIDIA GPUs are read from WMI when nvidia-smi is not available
+func collectGPUs(log log.T) (data []model.GPUData) {
+	data, nvidiaFound := collectNVIDIAGPUs(log)
+	output, err := cmdExecutor(PowershellCmd, videoControllerScript)
+	if err != nil {
+		log.Errorf("Unable to query display adapters: %v", err)
+		return
+	}
+	cleanOutput, err := pluginutil.ReplaceMarkedFields(pluginutil.CleanupNewLines(string(output)), startMarker, endMarker, pluginutil.CleanupJSONField)
+	if err != nil {
+		log.Errorf("Unable to parse display adapters: %v", err)
+		return
+	}
+	var adapters []model.GPUData
+	if err = json.Unmarshal([]byte(cleanOutput), &adapters); err != nil {
+		log.Errorf("Unable to parse display adapters: %v", err)
+		return
+	}
+	for _, adapter := range adapters {
+		adapter.Vendor = vendorName(adapter.Vendor)
+		if adapter.Vendor == VendorNVIDIA && nvidiaFound {
+			continue
+		}
+		data = append(data, adapter)
+	}
+	return
+}
+
+// vendorName returns the vendor names used on Linux for the adapter compatibility of the known GPU vendors
+func vendorName(adapterCompatibility string) string {
+	switch {
+	case strings.Contains(adapterCompatibility, "NVIDIA"):
+		return VendorNVIDIA
+	case strings.Contains(adapterCompatibility, "Advanced Micro Devices"), strings.HasPrefix(adapterCompatibility, "AMD"):
+		return VendorAMD
+	case strings.HasPrefix(adapterCompatibility, "Intel"):
+		return VendorIntel
+	}
+	return adapterCompatibility
+}
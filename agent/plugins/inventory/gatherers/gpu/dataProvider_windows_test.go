@@ -0,0 +1,64 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package gpu
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var testVideoControllerOutput = "[" +
+	`{"Name":"` + mark("NVIDIA Tesla T4") + `","Vendor":"` + mark("NVIDIA") + `","DriverVersion":"26.21.14.4187","MemoryMiB":"4095"},` +
+	`{"Name":"` + mark("Radeon Pro V520") + `","Vendor":"` + mark("Advanced Micro Devices, Inc.") + `","DriverVersion":"27.20.1017.1011","MemoryMiB":"4095"}` +
+	"]"
+
+func createMockTestExecuteCommand(outputs map[string]string, errs map[string]error) func(string, ...string) ([]byte, error) {
+	return func(command string, args ...string) ([]byte, error) {
+		return []byte(outputs[command]), errs[command]
+	}
+}
+
+func TestCollectGPUData(t *testing.T) {
+	cmdExecutor = createMockTestExecuteCommand(map[string]string{
+		nvidiaSmiCmd:  "Tesla T4, 418.87.00, 15109, 00000000:00:1E.0\r\n",
+		PowershellCmd: testVideoControllerOutput,
+	}, nil)
+	defer func() { cmdExecutor = executeCommand }()
+
+	data, err := collectGPUData(context.NewMockDefault(), model.Config{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []model.GPUData{
+		{Name: "Radeon Pro V520", Vendor: "AMD", DriverVersion: "27.20.1017.1011", MemoryMiB: "4095"},
+		{Name: "Tesla T4", Vendor: "NVIDIA", DriverVersion: "418.87.00", MemoryMiB: "15109", BusId: "00000000:00:1E.0"},
+	}, data)
+}
+
+func TestCollectGPUDataWithoutNvidiaSmi(t *testing.T) {
+	cmdExecutor = createMockTestExecuteCommand(map[string]string{PowershellCmd: testVideoControllerOutput}, map[string]error{nvidiaSmiCmd: errors.New("error")})
+	defer func() { cmdExecutor = executeCommand }()
+
+	data, err := collectGPUData(context.NewMockDefault(), model.Config{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(data))
+	assert.Equal(t, "NVIDIA", data[0].Vendor)
+	assert.Equal(t, "26.21.14.4187", data[0].DriverVersion)
+}
@@ -0,0 +1,158 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package kernel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	// ResourceTypes of the kernel inventory entries
+	ResourceTypeModule    = "Module"
+	ResourceTypeParameter = "Parameter"
+
+	// SignatureStates of the kernel modules
+	SignatureStateSigned      = "Signed"
+	SignatureStateUnsigned    = "Unsigned"
+	SignatureStateNotVerified = "NotVerified"
+
+	// unsignedModuleTaint is the taint flag of modules loaded without a valid signature
+	unsignedModuleTaint = "E"
+
+	// ParameterCountLimit keeps the kernel parameters under the item size limit
+	ParameterCountLimit = 1000
+)
+
+//decoupling for easy testability
+var procModulesPath = "/proc/modules"
+var sysModulePath = "/sys/module"
+var procSysPath = "/proc/sys"
+
+//collectKernelData returns the loaded kernel modules when the collection is enabled and
+//the kernel parameters matching the names of the configuration filters
+func collectKernelData(context context.T, config model.Config) (data []model.KernelData, err error) {
+	log := context.Log()
+	data = []model.KernelData{}
+	if config.Collection == model.Enabled {
+		var modules []model.KernelData
+		if modules, err = collectModules(log); err != nil {
+			log.Error(err)
+			return nil, err
+		}
+		data = append(data, modules...)
+	}
+	if config.Filters != "" {
+		var names []string
+		if err = json.Unmarshal([]byte(config.Filters), &names); err != nil {
+			err = fmt.Errorf("kernel parameters must be a list of parameter names: %v", err)
+			log.Error(err)
+			return nil, err
+		}
+		data = append(data, collectParameters(log, names)...)
+	}
+	return
+}
+
+// collectModules returns the modules of /proc/modules, whose lines are formatted as
+// name size refcount dependencies state address [(taints)]
+func collectModules(log log.T) (modules []model.KernelData, err error) {
+	content, err := ioutil.ReadFile(procModulesPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read loaded kernel modules: %v", err)
+	}
+
+	// signatures are only verified by kernels built with module signing, which expose sig_enforce
+	_, sigErr := os.Stat(filepath.Join(sysModulePath, "module", "parameters", "sig_enforce"))
+	verified := sigErr == nil
+
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		module := model.KernelData{
+			ResourceType:   ResourceTypeModule,
+			Name:           fields[0],
+			Version:        readValue(filepath.Join(sysModulePath, fields[0], "version")),
+			SignatureState: SignatureStateNotVerified,
+		}
+		if last := fields[len(fields)-1]; len(fields) > 6 && strings.HasPrefix(last, "(") {
+			module.Taint = strings.Trim(last, "()")
+		}
+		if verified {
+			module.SignatureState = SignatureStateSigned
+			if strings.Contains(module.Taint, unsignedModuleTaint) {
+				module.SignatureState = SignatureStateUnsigned
+			}
+		}
+		modules = append(modules, module)
+	}
+	log.Infof("Collected Kernel Modules %d", len(modules))
+	return
+}
+
+// collectParameters returns the kernel parameters matching the names, names use the sysctl notation
+// (e.g. net.ipv4.ip_forward) and may contain wildcards (e.g. net.ipv4.conf.*.rp_filter)
+func collectParameters(log log.T, names []string) (parameters []model.KernelData) {
+	collected := map[string]bool{}
+	for _, name := range names {
+		pattern := filepath.Join(procSysPath, filepath.FromSlash(strings.Replace(strings.TrimSpace(name), ".", "/", -1)))
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			log.Errorf("Invalid kernel parameter name %v: %v", name, err)
+			continue
+		}
+		for _, path := range paths {
+			relativePath, relErr := filepath.Rel(procSysPath, path)
+			if relErr != nil || collected[relativePath] || len(parameters) >= ParameterCountLimit {
+				continue
+			}
+			if info, statErr := os.Stat(path); statErr != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			value, readErr := ioutil.ReadFile(path)
+			if readErr != nil {
+				// some parameters are write only
+				log.Debugf("Unable to read kernel parameter %v: %v", path, readErr)
+				continue
+			}
+			collected[relativePath] = true
+			parameters = append(parameters, model.KernelData{
+				ResourceType: ResourceTypeParameter,
+				Name:         strings.Replace(filepath.ToSlash(relativePath), "/", ".", -1),
+				Value:        strings.Join(strings.Fields(string(value)), " "),
+			})
+		}
+	}
+	log.Infof("Collected Kernel Parameters %d", len(parameters))
+	return
+}
+
+// readValue returns the trimmed content of the file, or an empty string if it cannot be read
+func readValue(path string) string {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
@@ -0,0 +1,101 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package kernel
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const testProcModules = `nf_nat 36864 1 xt_MASQUERADE, Live 0x0000000000000000
+vboxdrv 487424 2 vboxnetadp,vboxnetflt, Live 0x0000000000000000 (OE)
+`
+
+// createTestKernelFiles creates the proc and sys files of the kernel in a temporary directory
+func createTestKernelFiles(t *testing.T, sigEnforce bool) (dir string) {
+	dir, err := ioutil.TempDir("", "kernel")
+	assert.NoError(t, err)
+	files := map[string]string{
+		"proc/modules":                         testProcModules,
+		"sys/module/vboxdrv/version":           "5.2.26 r128414\n",
+		"proc/sys/net/ipv4/ip_forward":         "0\n",
+		"proc/sys/net/ipv4/conf/all/rp_filter": "1\n",
+		"proc/sys/net/ipv4/conf/lo/rp_filter":  "0\n",
+		"proc/sys/kernel/printk":               "4\t4\t1\t7\n",
+	}
+	if sigEnforce {
+		files["sys/module/module/parameters/sig_enforce"] = "N\n"
+	}
+	for path, content := range files {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), appconfig.ReadWriteExecuteAccess))
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), appconfig.ReadWriteAccess))
+	}
+	procModulesPath = filepath.Join(dir, "proc", "modules")
+	sysModulePath = filepath.Join(dir, "sys", "module")
+	procSysPath = filepath.Join(dir, "proc", "sys")
+	return
+}
+
+func TestCollectKernelData(t *testing.T) {
+	dir := createTestKernelFiles(t, true)
+	defer os.RemoveAll(dir)
+
+	data, err := collectKernelData(context.NewMockDefault(), model.Config{
+		Collection: model.Enabled,
+		Filters:    `["net.ipv4.ip_forward", "net.ipv4.conf.*.rp_filter", "kernel.printk", "net.ipv4.ip_forward", "missing.parameter"]`,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.KernelData{
+		{ResourceType: ResourceTypeModule, Name: "nf_nat", SignatureState: SignatureStateSigned},
+		{ResourceType: ResourceTypeModule, Name: "vboxdrv", Version: "5.2.26 r128414", SignatureState: SignatureStateUnsigned, Taint: "OE"},
+		{ResourceType: ResourceTypeParameter, Name: "net.ipv4.ip_forward", Value: "0"},
+		{ResourceType: ResourceTypeParameter, Name: "net.ipv4.conf.all.rp_filter", Value: "1"},
+		{ResourceType: ResourceTypeParameter, Name: "net.ipv4.conf.lo.rp_filter", Value: "0"},
+		{ResourceType: ResourceTypeParameter, Name: "kernel.printk", Value: "4 4 1 7"},
+	}, data)
+}
+
+func TestCollectKernelDataWithoutModuleSigning(t *testing.T) {
+	dir := createTestKernelFiles(t, false)
+	defer os.RemoveAll(dir)
+
+	data, err := collectKernelData(context.NewMockDefault(), model.Config{Collection: model.Enabled})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(data))
+	assert.Equal(t, SignatureStateNotVerified, data[0].SignatureState)
+	assert.Equal(t, SignatureStateNotVerified, data[1].SignatureState)
+}
+
+func TestCollectKernelDataParametersOnly(t *testing.T) {
+	dir := createTestKernelFiles(t, true)
+	defer os.RemoveAll(dir)
+
+	data, err := collectKernelData(context.NewMockDefault(), model.Config{Filters: `["net.ipv4.ip_forward"]`})
+	assert.NoError(t, err)
+	assert.Equal(t, []model.KernelData{{ResourceType: ResourceTypeParameter, Name: "net.ipv4.ip_forward", Value: "0"}}, data)
+
+	data, err = collectKernelData(context.NewMockDefault(), model.Config{Filters: `{"net.ipv4.ip_forward": true}`})
+	assert.Error(t, err)
+	assert.Nil(t, data)
+}
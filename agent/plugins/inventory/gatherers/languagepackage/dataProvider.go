@@ -0,0 +1,164 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package languagepackage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	pipCmd  = "pip"
+	pip3Cmd = "pip3"
+	npmCmd  = "npm"
+	gemCmd  = "gem"
+
+	gemDefaultVersionPrefix = "default: "
+)
+
+// gemListLine matches the lines of gem list, e.g. rake (12.3.2, 10.5.0)
+var gemListLine = regexp.MustCompile(`^(\S+) \((.+)\)$`)
+
+// pipPackage is the json format of pip list
+type pipPackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// npmList is the json format of npm ls
+type npmList struct {
+	Dependencies map[string]struct {
+		Version string `json:"version"`
+	} `json:"dependencies"`
+}
+
+// packageManager lists the global packages of a language package manager from the output of its command
+type packageManager struct {
+	command string
+	args    []string
+	parse   func(output []byte) ([]model.LanguagePackageData, error)
+}
+
+var packageManagers = []packageManager{
+	{pip3Cmd, []string{"list", "--format=json", "--disable-pip-version-check"}, parsePipList},
+	{pipCmd, []string{"list", "--format=json", "--disable-pip-version-check"}, parsePipList},
+	{npmCmd, []string{"ls", "--global", "--depth=0", "--json"}, parseNpmList},
+	{gemCmd, []string{"list", "--local"}, parseGemList},
+}
+
+// decoupling exec.LookPath for easy testability
+var lookPath = exec.LookPath
+
+var cmdExecutor = executeCommand
+
+func executeCommand(command string, args ...string) ([]byte, error) {
+	return exec.Command(command, args...).Output()
+}
+
+// collectLanguagePackageData collects the globally installed packages of the language package managers of the instance,
+// package managers which are not installed or whose packages cannot be listed are skipped
+func collectLanguagePackageData(context context.T, config model.Config) (data []model.LanguagePackageData, err error) {
+	log := context.Log()
+	data = []model.LanguagePackageData{}
+	collected := map[model.LanguagePackageData]bool{}
+	for _, manager := range packageManagers {
+		packages, listErr := listPackages(log, manager)
+		if listErr != nil {
+			log.Warnf("Unable to list %v packages: %v", manager.command, listErr)
+			continue
+		}
+		for _, item := range packages {
+			// pip and pip3 may list the packages of the same python installation
+			if !collected[item] {
+				collected[item] = true
+				data = append(data, item)
+			}
+		}
+	}
+	log.Infof("Collected Language Packages %d", len(data))
+	return
+}
+
+func listPackages(log log.T, manager packageManager) (packages []model.LanguagePackageData, err error) {
+	if _, err = lookPath(manager.command); err != nil {
+		log.Debugf("%v is not installed", manager.command)
+		return nil, nil
+	}
+
+	log.Debugf("Executing command: %v %v", manager.command, manager.args)
+	output, cmdErr := cmdExecutor(manager.command, manager.args...)
+	if packages, err = manager.parse(output); err != nil {
+		// npm exits with an error for problems such as extraneous packages but still lists the packages
+		if cmdErr != nil {
+			err = fmt.Errorf("command failed with error: %v", cmdErr)
+		}
+		return nil, err
+	}
+	return
+}
+
+func parsePipList(output []byte) (packages []model.LanguagePackageData, err error) {
+	var pipPackages []pipPackage
+	if err = json.Unmarshal(output, &pipPackages); err != nil {
+		return nil, fmt.Errorf("unable to parse pip list output: %v", err)
+	}
+	for _, item := range pipPackages {
+		packages = append(packages, model.LanguagePackageData{Name: item.Name, Version: item.Version, PackageManager: pipCmd})
+	}
+	return
+}
+
+func parseNpmList(output []byte) (packages []model.LanguagePackageData, err error) {
+	var list npmList
+	if err = json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("unable to parse npm ls output: %v", err)
+	}
+	names := []string{}
+	for name := range list.Dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		packages = append(packages, model.LanguagePackageData{Name: name, Version: list.Dependencies[name].Version, PackageManager: npmCmd})
+	}
+	return
+}
+
+// parseGemList reports every installed version of the gems, e.g. rake (12.3.2, default: 10.5.0)
+func parseGemList(output []byte) (packages []model.LanguagePackageData, err error) {
+	for _, line := range strings.Split(string(output), "\n") {
+		match := gemListLine.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		for _, version := range strings.Split(match[2], ",") {
+			// versions of platform specific gems are followed by the platform, e.g. 1.10.3 x86_64-linux
+			fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(version), gemDefaultVersionPrefix))
+			if len(fields) == 0 {
+				continue
+			}
+			version = fields[0]
+			packages = append(packages, model.LanguagePackageData{Name: match[1], Version: version, PackageManager: gemCmd})
+		}
+	}
+	return
+}
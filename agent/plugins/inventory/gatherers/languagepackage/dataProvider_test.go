@@ -0,0 +1,94 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package languagepackage
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testPipOutput = `[{"name": "boto3", "version": "1.9.180"}, {"name": "requests", "version": "2.22.0"}]`
+	testNpmOutput = `{"dependencies": {"npm": {"version": "6.9.0", "from": "npm"}, "event-stream": {"version": "3.3.6"}}}`
+	testGemOutput = `
+*** LOCAL GEMS ***
+
+bigdecimal (default: 1.3.4)
+nokogiri (1.10.3 x86_64-linux)
+rake (12.3.2, 10.5.0)
+`
+)
+
+var testLanguagePackageData = []model.LanguagePackageData{
+	{Name: "boto3", Version: "1.9.180", PackageManager: "pip"},
+	{Name: "requests", Version: "2.22.0", PackageManager: "pip"},
+	{Name: "six", Version: "1.12.0", PackageManager: "pip"},
+	{Name: "event-stream", Version: "3.3.6", PackageManager: "npm"},
+	{Name: "npm", Version: "6.9.0", PackageManager: "npm"},
+	{Name: "bigdecimal", Version: "1.3.4", PackageManager: "gem"},
+	{Name: "nokogiri", Version: "1.10.3", PackageManager: "gem"},
+	{Name: "rake", Version: "12.3.2", PackageManager: "gem"},
+	{Name: "rake", Version: "10.5.0", PackageManager: "gem"},
+}
+
+func mockPackageManagers(outputs map[string]string, errs map[string]error) func() {
+	cmdExecutor = func(command string, args ...string) ([]byte, error) {
+		return []byte(outputs[command]), errs[command]
+	}
+	lookPath = func(file string) (string, error) {
+		if _, found := outputs[file]; found {
+			return "/usr/bin/" + file, nil
+		}
+		return "", errors.New("not found")
+	}
+	return func() {
+		cmdExecutor = executeCommand
+		lookPath = exec.LookPath
+	}
+}
+
+func TestCollectLanguagePackageData(t *testing.T) {
+	defer mockPackageManagers(map[string]string{
+		pip3Cmd: testPipOutput,
+		pipCmd:  `[{"name": "boto3", "version": "1.9.180"}, {"name": "six", "version": "1.12.0"}]`,
+		npmCmd:  testNpmOutput,
+		gemCmd:  testGemOutput,
+	}, map[string]error{
+		npmCmd: errors.New("exit status 1"),
+	})()
+
+	data, err := collectLanguagePackageData(context.NewMockDefault(), model.Config{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, testLanguagePackageData, data)
+}
+
+func TestCollectLanguagePackageDataSkipsFailedPackageManager(t *testing.T) {
+	defer mockPackageManagers(map[string]string{
+		pipCmd: "ERROR: unknown option --format",
+		gemCmd: "rake (12.3.2)",
+	}, map[string]error{
+		pipCmd: errors.New("exit status 2"),
+	})()
+
+	data, err := collectLanguagePackageData(context.NewMockDefault(), model.Config{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.LanguagePackageData{{Name: "rake", Version: "12.3.2", PackageManager: "gem"}}, data)
+}
@@ -0,0 +1,53 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package listeningport
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	// Protocols of the listening sockets
+	ProtocolTCP = "TCP"
+	ProtocolUDP = "UDP"
+)
+
+// collectListeningPortData returns the listening TCP sockets and bound UDP sockets along with their owning process
+func collectListeningPortData(context context.T, config model.Config) (data []model.ListeningPortData, err error) {
+	log := context.Log()
+	if data, err = collectListeningPorts(log); err != nil {
+		log.Errorf("Unable to collect listening ports: %v", err)
+		return nil, err
+	}
+	sort.SliceStable(data, func(i, j int) bool {
+		if data[i].Protocol != data[j].Protocol {
+			return data[i].Protocol < data[j].Protocol
+		}
+		if data[i].LocalPort != data[j].LocalPort {
+			return portNumber(data[i].LocalPort) < portNumber(data[j].LocalPort)
+		}
+		return data[i].LocalAddress < data[j].LocalAddress
+	})
+	log.Infof("Collected Listening Ports %d", len(data))
+	return
+}
+
+func portNumber(port string) int {
+	number, _ := strconv.Atoi(port)
+	return number
+}
@@ -0,0 +1,177 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package listeningport
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	// tcpListenState is the state of listening TCP sockets in /proc/net/tcp
+	tcpListenState = "0A"
+	// udpUnconnectedState is the state of bound UDP sockets which are not connected to a remote address
+	udpUnconnectedState = "07"
+
+	socketLinkPrefix = "socket:["
+)
+
+//decoupling for easy testability
+var procPath = "/proc"
+
+// socketTable is a /proc/net table and the state of the sockets to report from it
+type socketTable struct {
+	file     string
+	protocol string
+	state    string
+}
+
+var socketTables = []socketTable{
+	{"tcp", ProtocolTCP, tcpListenState},
+	{"tcp6", ProtocolTCP, tcpListenState},
+	{"udp", ProtocolUDP, udpUnconnectedState},
+	{"udp6", ProtocolUDP, udpUnconnectedState},
+}
+
+// process is the owning process of a socket
+type process struct {
+	id   string
+	name string
+	path string
+}
+
+// collectListeningPorts reads the sockets of /proc/net and finds their owning process through the socket
+// links of /proc/<pid>/fd, sockets owned by processes the agent cannot inspect are reported without a process
+func collectListeningPorts(log log.T) (data []model.ListeningPortData, err error) {
+	type socket struct {
+		port  model.ListeningPortData
+		inode string
+	}
+	var sockets []socket
+	read := false
+	for _, table := range socketTables {
+		content, readErr := ioutil.ReadFile(filepath.Join(procPath, "net", table.file))
+		if readErr != nil {
+			// tcp6 and udp6 are missing when IPv6 is disabled
+			log.Debugf("Unable to read socket table %v: %v", table.file, readErr)
+			continue
+		}
+		read = true
+		for _, line := range strings.Split(string(content), "\n")[1:] {
+			// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+			fields := strings.Fields(line)
+			if len(fields) < 10 || fields[3] != table.state {
+				continue
+			}
+			address, port, parseErr := parseSocketAddress(fields[1])
+			if parseErr != nil {
+				log.Debugf("Invalid socket address %v: %v", fields[1], parseErr)
+				continue
+			}
+			sockets = append(sockets, socket{
+				port:  model.ListeningPortData{Protocol: table.protocol, LocalAddress: address, LocalPort: port},
+				inode: fields[9],
+			})
+		}
+	}
+	if !read {
+		return nil, fmt.Errorf("unable to read the socket tables of %v", filepath.Join(procPath, "net"))
+	}
+
+	owners := socketOwners(log)
+	data = []model.ListeningPortData{}
+	for _, s := range sockets {
+		if owner, found := owners[s.inode]; found {
+			s.port.ProcessId = owner.id
+			s.port.ProcessName = owner.name
+			s.port.ProcessPath = owner.path
+		}
+		data = append(data, s.port)
+	}
+	return
+}
+
+// parseSocketAddress parses the hex encoded address:port of /proc/net, the address is stored as 32 bit words in host byte order
+func parseSocketAddress(value string) (address, port string, err error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("expected address:port")
+	}
+	raw, err := hex.DecodeString(parts[0])
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return "", "", fmt.Errorf("invalid address %v", parts[0])
+	}
+	// the supported architectures are little endian, so the bytes of every word are reversed
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		for j := 0; j < 4; j++ {
+			ip[i+j] = raw[i+3-j]
+		}
+	}
+	portNumber, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid port %v", parts[1])
+	}
+	return ip.String(), strconv.FormatUint(portNumber, 10), nil
+}
+
+// socketOwners returns the processes owning the sockets by socket inode
+func socketOwners(log log.T) (owners map[string]process) {
+	owners = map[string]process{}
+	entries, err := ioutil.ReadDir(procPath)
+	if err != nil {
+		log.Debugf("Unable to list processes: %v", err)
+		return
+	}
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil || !entry.IsDir() {
+			continue
+		}
+		processPath := filepath.Join(procPath, entry.Name())
+		links, err := ioutil.ReadDir(filepath.Join(processPath, "fd"))
+		if err != nil {
+			continue
+		}
+		var owner *process
+		for _, link := range links {
+			target, err := os.Readlink(filepath.Join(processPath, "fd", link.Name()))
+			if err != nil || !strings.HasPrefix(target, socketLinkPrefix) {
+				continue
+			}
+			inode := strings.TrimSuffix(strings.TrimPrefix(target, socketLinkPrefix), "]")
+			if _, found := owners[inode]; found {
+				// sockets inherited by child processes are reported with their first owner
+				continue
+			}
+			if owner == nil {
+				name, _ := ioutil.ReadFile(filepath.Join(processPath, "comm"))
+				path, _ := os.Readlink(filepath.Join(processPath, "exe"))
+				owner = &process{id: entry.Name(), name: strings.TrimSpace(string(name)), path: path}
+			}
+			owners[inode] = *owner
+		}
+	}
+	return
+}
@@ -0,0 +1,108 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package listeningport
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testProcNetHeader = "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+	testProcNetTCP    = testProcNetHeader +
+		"   0: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 17001 1 0000000000000000 100 0 0 10 0\n" +
+		"   1: 0100007F:0019 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 17002 1 0000000000000000 100 0 0 10 0\n" +
+		"   2: 0F02000A:0016 0A02000A:D431 01 00000000:00000000 02:0008B2DB 00000000     0        0 17003 4 0000000000000000 20 4 31 10 -1\n"
+	testProcNetTCP6 = testProcNetHeader +
+		"   0: 00000000000000000000000000000000:0016 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 17004 1 0000000000000000 100 0 0 10 0\n"
+	testProcNetUDP = testProcNetHeader +
+		"  10: 0100007F:0143 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 17005 2 0000000000000000 0\n"
+)
+
+var testUnixListeningPortData = []model.ListeningPortData{
+	{Protocol: "TCP", LocalAddress: "0.0.0.0", LocalPort: "22", ProcessId: "1042", ProcessName: "sshd", ProcessPath: "/usr/sbin/sshd"},
+	{Protocol: "TCP", LocalAddress: "::", LocalPort: "22", ProcessId: "1042", ProcessName: "sshd", ProcessPath: "/usr/sbin/sshd"},
+	{Protocol: "TCP", LocalAddress: "127.0.0.1", LocalPort: "25"},
+	{Protocol: "UDP", LocalAddress: "127.0.0.1", LocalPort: "323", ProcessId: "612", ProcessName: "chronyd", ProcessPath: "/usr/sbin/chronyd"},
+}
+
+// createTestProc creates the socket tables and the processes owning the sockets in a temporary directory
+func createTestProc(t *testing.T) (dir string) {
+	dir, err := ioutil.TempDir("", "proc")
+	assert.NoError(t, err)
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "net"), appconfig.ReadWriteExecuteAccess))
+	tables := map[string]string{"tcp": testProcNetTCP, "tcp6": testProcNetTCP6, "udp": testProcNetUDP}
+	for name, content := range tables {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "net", name), []byte(content), appconfig.ReadWriteAccess))
+	}
+
+	processes := map[string][]string{
+		"1042": {"sshd", "/usr/sbin/sshd", "socket:[17001]", "socket:[17004]", "/dev/null"},
+		"612":  {"chronyd", "/usr/sbin/chronyd", "socket:[17005]"},
+	}
+	for pid, process := range processes {
+		processPath := filepath.Join(dir, pid)
+		assert.NoError(t, os.MkdirAll(filepath.Join(processPath, "fd"), appconfig.ReadWriteExecuteAccess))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(processPath, "comm"), []byte(process[0]+"\n"), appconfig.ReadWriteAccess))
+		assert.NoError(t, os.Symlink(process[1], filepath.Join(processPath, "exe")))
+		for i, target := range process[2:] {
+			assert.NoError(t, os.Symlink(target, filepath.Join(processPath, "fd", strconv.Itoa(3+i))))
+		}
+	}
+	procPath = dir
+	return
+}
+
+func TestCollectListeningPortData(t *testing.T) {
+	dir := createTestProc(t)
+	defer os.RemoveAll(dir)
+
+	data, err := collectListeningPortData(context.NewMockDefault(), model.Config{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, testUnixListeningPortData, data)
+}
+
+func TestCollectListeningPortDataWithoutSocketTables(t *testing.T) {
+	dir := createTestProc(t)
+	defer os.RemoveAll(dir)
+	os.RemoveAll(filepath.Join(dir, "net"))
+
+	data, err := collectListeningPortData(context.NewMockDefault(), model.Config{})
+
+	assert.Error(t, err)
+	assert.Nil(t, data)
+}
+
+func TestParseSocketAddress(t *testing.T) {
+	address, port, err := parseSocketAddress("B80D01200000000067452301EFCDAB89:01BB")
+	assert.NoError(t, err)
+	assert.Equal(t, "2001:db8::123:4567:89ab:cdef", address)
+	assert.Equal(t, "443", port)
+
+	_, _, err = parseSocketAddress("0100007F")
+	assert.Error(t, err)
+	_, _, err = parseSocketAddress("01007F:0016")
+	assert.Error(t, err)
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package listeningport
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	PowershellCmd = "powershell"
+	NetstatCmd    = "netstat"
+
+	// processScript writes the id, name and path of the processes separated by tabs, one process per line
+	processScript = `
+[Console]::OutputEncoding = [System.Text.Encoding]::UTF8
+Get-WmiObject Win32_Process | ForEach-Object { "$($_.ProcessId)` + "`t" + `$($_.Name)` + "`t" + `$($_.ExecutablePath)" }
+`
+)
+
+// unspecifiedRemoteAddresses are the foreign addresses netstat shows for listening TCP sockets, unlike
+// the state column they are not localized
+var unspecifiedRemoteAddresses = map[string]bool{"0.0.0.0:0": true, "[::]:0": true}
+
+var cmdExecutor = executeCommand
+
+func executeCommand(command string, args ...string) ([]byte, error) {
+	return exec.Command(command, args...).Output()
+}
+
+// collectListeningPorts parses the sockets of netstat and finds their owning process through WMI,
+// which unlike Get-NetTCPConnection is available on all supported versions of Windows
+func collectListeningPorts(log log.T) (data []model.ListeningPortData, err error) {
+	var output []byte
+	if output, err = cmdExecutor(NetstatCmd, "-ano"); err != nil {
+		return nil, fmt.Errorf("command failed with error: %v", err)
+	}
+
+	processes := map[string][]string{}
+	if processOutput, processErr := cmdExecutor(PowershellCmd, processScript); processErr != nil {
+		log.Debugf("Unable to list processes: %v", processErr)
+	} else {
+		for _, line := range strings.Split(string(processOutput), "\n") {
+			if fields := strings.Split(strings.TrimRight(line, "\r"), "\t"); len(fields) == 3 {
+				processes[fields[0]] = fields[1:]
+			}
+		}
+	}
+
+	data = []model.ListeningPortData{}
+	for _, line := range strings.Split(string(output), "\n") {
+		// Proto Local-Address Foreign-Address [State] PID, UDP sockets have no state
+		fields := strings.Fields(line)
+		var port model.ListeningPortData
+		var pid string
+		switch {
+		case len(fields) == 5 && fields[0] == ProtocolTCP && unspecifiedRemoteAddresses[fields[2]]:
+			port.Protocol, pid = ProtocolTCP, fields[4]
+		case len(fields) == 4 && fields[0] == ProtocolUDP:
+			port.Protocol, pid = ProtocolUDP, fields[3]
+		default:
+			continue
+		}
+		separator := strings.LastIndex(fields[1], ":")
+		if separator < 0 {
+			continue
+		}
+		port.LocalAddress = strings.Trim(fields[1][:separator], "[]")
+		port.LocalPort = fields[1][separator+1:]
+		port.ProcessId = pid
+		if process, found := processes[pid]; found {
+			port.ProcessName, port.ProcessPath = process[0], process[1]
+		}
+		data = append(data, port)
+	}
+	return
+}
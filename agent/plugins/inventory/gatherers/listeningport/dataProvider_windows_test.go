@@ -0,0 +1,83 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package listeningport
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testNetstatOutput = `
+Active Connections
+
+  Proto  Local Address          Foreign Address        State           PID
+  TCP    0.0.0.0:135            0.0.0.0:0              LISTENING       900
+  TCP    10.0.0.5:3389          10.0.0.9:50123         ESTABLISHED     1200
+  TCP    [::]:3389              [::]:0                 LISTENING       1200
+  UDP    0.0.0.0:123            *:*                                    1400
+  UDP    [fe80::1%4]:546        *:*                                    1400
+`
+	testProcessOutput = "900\tsvchost.exe\tC:\\Windows\\system32\\svchost.exe\r\n1200\tsvchost.exe\tC:\\Windows\\System32\\svchost.exe\r\n"
+)
+
+var testWindowsListeningPortData = []model.ListeningPortData{
+	{Protocol: "TCP", LocalAddress: "0.0.0.0", LocalPort: "135", ProcessId: "900", ProcessName: "svchost.exe", ProcessPath: `C:\Windows\system32\svchost.exe`},
+	{Protocol: "TCP", LocalAddress: "::", LocalPort: "3389", ProcessId: "1200", ProcessName: "svchost.exe", ProcessPath: `C:\Windows\System32\svchost.exe`},
+	{Protocol: "UDP", LocalAddress: "0.0.0.0", LocalPort: "123", ProcessId: "1400"},
+	{Protocol: "UDP", LocalAddress: "fe80::1%4", LocalPort: "546", ProcessId: "1400"},
+}
+
+func createMockTestExecuteCommand(outputs map[string]string, errs map[string]error) func(string, ...string) ([]byte, error) {
+	return func(command string, args ...string) ([]byte, error) {
+		return []byte(outputs[command]), errs[command]
+	}
+}
+
+func TestCollectListeningPortData(t *testing.T) {
+	cmdExecutor = createMockTestExecuteCommand(map[string]string{NetstatCmd: testNetstatOutput, PowershellCmd: testProcessOutput}, nil)
+	defer func() { cmdExecutor = executeCommand }()
+
+	data, err := collectListeningPortData(context.NewMockDefault(), model.Config{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, testWindowsListeningPortData, data)
+}
+
+func TestCollectListeningPortDataWithoutProcesses(t *testing.T) {
+	cmdExecutor = createMockTestExecuteCommand(map[string]string{NetstatCmd: testNetstatOutput}, map[string]error{PowershellCmd: errors.New("error")})
+	defer func() { cmdExecutor = executeCommand }()
+
+	data, err := collectListeningPortData(context.NewMockDefault(), model.Config{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(data))
+	assert.Equal(t, "", data[0].ProcessName)
+}
+
+func TestCollectListeningPortDataCmdErr(t *testing.T) {
+	cmdExecutor = createMockTestExecuteCommand(map[string]string{}, map[string]error{NetstatCmd: errors.New("error")})
+	defer func() { cmdExecutor = executeCommand }()
+
+	data, err := collectListeningPortData(context.NewMockDefault(), model.Config{})
+
+	assert.NotNil(t, err)
+	assert.Nil(t, data)
+}
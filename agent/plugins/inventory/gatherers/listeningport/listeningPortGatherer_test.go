@@ -0,0 +1,44 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package listeningport
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var testListeningPortData = []model.ListeningPortData{
+	{Protocol: "TCP", LocalAddress: "0.0.0.0", LocalPort: "22", ProcessId: "1042", ProcessName: "sshd", ProcessPath: "/usr/sbin/sshd"},
+	{Protocol: "UDP", LocalAddress: "127.0.0.1", LocalPort: "323", ProcessId: "612", ProcessName: "chronyd", ProcessPath: "/usr/sbin/chronyd"},
+}
+
+func testCollectListeningPortData(context context.T, config model.Config) (data []model.ListeningPortData, err error) {
+	return testListeningPortData, nil
+}
+
+func TestGatherer(t *testing.T) {
+	contextMock := context.NewMockDefault()
+	gatherer := Gatherer(contextMock)
+	collectData = testCollectListeningPortData
+	defer func() { collectData = collectListeningPortData }()
+	item, err := gatherer.Run(contextMock, model.Config{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(item))
+	assert.Equal(t, GathererName, item[0].Name)
+	assert.Equal(t, SchemaVersionOfListeningPortGatherer, item[0].SchemaVersion)
+	assert.Equal(t, testListeningPortData, item[0].Content)
+}
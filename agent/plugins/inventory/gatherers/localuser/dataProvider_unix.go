@@ -0,0 +1,192 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package localuser
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	// lastlogRecordSize is the size of the lastlog records, a 32 bit time followed by the 32 byte line and 256 byte host
+	lastlogRecordSize = 292
+
+	// shadowNeverExpires is the maximum password age set by tools for passwords that never expire
+	shadowNeverExpires = 99999
+
+	// lockedPasswordPrefix prefixes the password hash of accounts locked with passwd -l or usermod -L
+	lockedPasswordPrefix = "!"
+
+	secondsPerDay = 24 * 60 * 60
+)
+
+//decoupling for easy testability
+var passwdPath = "/etc/passwd"
+var groupPath = "/etc/group"
+var shadowPath = "/etc/shadow"
+var lastlogPath = "/var/log/lastlog"
+
+// shadowEntry is the password aging information of /etc/shadow, the times are in days since the epoch
+type shadowEntry struct {
+	password    string
+	lastChanged string
+	maxAge      string
+	expire      string
+}
+
+// collectLocalUserData returns the accounts of /etc/passwd along with their groups, last login and password state
+func collectLocalUserData(context context.T, config model.Config) (data []model.LocalUserData, err error) {
+	log := context.Log()
+	passwd, err := ioutil.ReadFile(passwdPath)
+	if err != nil {
+		err = fmt.Errorf("unable to read local users: %v", err)
+		log.Error(err)
+		return nil, err
+	}
+	groupNames, memberships := readGroups(log)
+	shadow := readShadow(log)
+
+	var lastlog *os.File
+	if lastlog, err = os.Open(lastlogPath); err != nil {
+		log.Debugf("Unable to read last logins: %v", err)
+		err = nil
+	} else {
+		defer lastlog.Close()
+	}
+
+	today := time.Now().Unix() / secondsPerDay
+	data = []model.LocalUserData{}
+	for _, line := range strings.Split(string(passwd), "\n") {
+		// name:password:uid:gid:gecos:home:shell
+		fields := strings.Split(strings.TrimSpace(line), ":")
+		if len(fields) < 7 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		user := model.LocalUserData{
+			Name:          fields[0],
+			Id:            fields[2],
+			Groups:        strings.Join(userGroups(fields[0], groupNames[fields[3]], memberships), ","),
+			Shell:         fields[6],
+			HomeDirectory: fields[5],
+		}
+		if uid, parseErr := strconv.ParseUint(fields[2], 10, 32); parseErr == nil && lastlog != nil {
+			user.LastLogin = readLastLogin(lastlog, uid)
+		}
+		if entry, found := shadow[user.Name]; found {
+			setPasswordState(&user, entry, today)
+		}
+		data = append(data, user)
+	}
+	log.Infof("Collected Local Users %d", len(data))
+	return
+}
+
+// readGroups returns the names of the groups by id and the supplementary groups of the users
+func readGroups(log log.T) (groupNames map[string]string, memberships map[string][]string) {
+	groupNames = map[string]string{}
+	memberships = map[string][]string{}
+	content, err := ioutil.ReadFile(groupPath)
+	if err != nil {
+		log.Debugf("Unable to read local groups: %v", err)
+		return
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		// name:password:gid:members
+		fields := strings.Split(strings.TrimSpace(line), ":")
+		if len(fields) < 4 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		groupNames[fields[2]] = fields[0]
+		for _, member := range strings.Split(fields[3], ",") {
+			if member != "" {
+				memberships[member] = append(memberships[member], fields[0])
+			}
+		}
+	}
+	return
+}
+
+// userGroups returns the primary group of the user followed by its supplementary groups
+func userGroups(name, primaryGroup string, memberships map[string][]string) (groups []string) {
+	if primaryGroup != "" {
+		groups = append(groups, primaryGroup)
+	}
+	for _, group := range memberships[name] {
+		if group != primaryGroup {
+			groups = append(groups, group)
+		}
+	}
+	return
+}
+
+// readShadow returns the password aging information of the users, the shadow file is only readable by root
+func readShadow(log log.T) (entries map[string]shadowEntry) {
+	entries = map[string]shadowEntry{}
+	content, err := ioutil.ReadFile(shadowPath)
+	if err != nil {
+		log.Debugf("Unable to read password information: %v", err)
+		return
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		// name:password:lastchg:min:max:warn:inactive:expire:reserved
+		fields := strings.Split(strings.TrimSpace(line), ":")
+		if len(fields) < 8 {
+			continue
+		}
+		entries[fields[0]] = shadowEntry{password: fields[1], lastChanged: fields[2], maxAge: fields[4], expire: fields[7]}
+	}
+	return
+}
+
+// setPasswordState sets the disabled and password expiry flags of the user from its shadow entry
+func setPasswordState(user *model.LocalUserData, entry shadowEntry, today int64) {
+	expire, expireErr := strconv.ParseInt(entry.expire, 10, 64)
+	disabled := strings.HasPrefix(entry.password, lockedPasswordPrefix) || (expireErr == nil && expire <= today)
+
+	maxAge, maxAgeErr := strconv.ParseInt(entry.maxAge, 10, 64)
+	neverExpires := maxAgeErr != nil || maxAge >= shadowNeverExpires
+
+	// a last change of 0 forces the user to change the password at the next login
+	lastChanged, lastChangedErr := strconv.ParseInt(entry.lastChanged, 10, 64)
+	expired := lastChangedErr == nil && (lastChanged == 0 || (!neverExpires && lastChanged+maxAge < today))
+
+	user.Disabled = strconv.FormatBool(disabled)
+	user.PasswordExpired = strconv.FormatBool(expired)
+	user.PasswordNeverExpires = strconv.FormatBool(neverExpires)
+}
+
+// readLastLogin returns the last login of the user from its lastlog record, or an empty string if the user never logged in
+func readLastLogin(lastlog *os.File, uid uint64) string {
+	record := make([]byte, 4)
+	if _, err := lastlog.ReadAt(record, int64(uid)*lastlogRecordSize); err != nil {
+		return ""
+	}
+	// the records are written in the byte order of the host, the supported architectures are little endian
+	loginTime := binary.LittleEndian.Uint32(record)
+	if loginTime == 0 {
+		return ""
+	}
+	return time.Unix(int64(loginTime), 0).UTC().Format(time.RFC3339)
+}
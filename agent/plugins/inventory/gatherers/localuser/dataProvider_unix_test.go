@@ -0,0 +1,111 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package localuser
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testPasswd = `root:x:0:0:root:/root:/bin/bash
+# comment
+ec2-user:x:1000:1000:EC2 Default User:/home/ec2-user:/bin/bash
+deploy:x:1001:1001::/home/deploy:/bin/sh
+nobody:x:65534:65534:Nobody:/:/sbin/nologin
+`
+	testGroup = `root:x:0:
+wheel:x:10:ec2-user,deploy
+ec2-user:x:1000:
+deploy:x:1001:deploy
+docker:x:992:ec2-user
+`
+)
+
+var testLocalUserData = []model.LocalUserData{
+	{Name: "root", Id: "0", Groups: "root", Shell: "/bin/bash", HomeDirectory: "/root", LastLogin: "2019-06-01T10:20:30Z", Disabled: "true", PasswordExpired: "false", PasswordNeverExpires: "true"},
+	{Name: "ec2-user", Id: "1000", Groups: "ec2-user,wheel,docker", Shell: "/bin/bash", HomeDirectory: "/home/ec2-user", Disabled: "false", PasswordExpired: "true", PasswordNeverExpires: "false"},
+	{Name: "deploy", Id: "1001", Groups: "deploy,wheel", Shell: "/bin/sh", HomeDirectory: "/home/deploy", Disabled: "true", PasswordExpired: "true", PasswordNeverExpires: "true"},
+	{Name: "nobody", Id: "65534", Groups: "", Shell: "/sbin/nologin", HomeDirectory: "/"},
+}
+
+// createTestUserFiles creates the account files in a temporary directory
+func createTestUserFiles(t *testing.T) (dir string) {
+	dir, err := ioutil.TempDir("", "localuser")
+	assert.NoError(t, err)
+	today := time.Now().Unix() / secondsPerDay
+	shadow := fmt.Sprintf(`root:!locked:%v:0:99999:7:::
+ec2-user:$6$hash:%v:0:90:7:::
+deploy:$6$hash:0:0::7::%v:
+`, today, today-91, today-1)
+
+	lastlog := make([]byte, 2*lastlogRecordSize)
+	binary.LittleEndian.PutUint32(lastlog, uint32(time.Date(2019, 6, 1, 10, 20, 30, 0, time.UTC).Unix()))
+
+	files := map[string][]byte{"passwd": []byte(testPasswd), "group": []byte(testGroup), "shadow": []byte(shadow), "lastlog": lastlog}
+	for name, content := range files {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), content, appconfig.ReadWriteAccess))
+	}
+	passwdPath = filepath.Join(dir, "passwd")
+	groupPath = filepath.Join(dir, "group")
+	shadowPath = filepath.Join(dir, "shadow")
+	lastlogPath = filepath.Join(dir, "lastlog")
+	return
+}
+
+func TestCollectLocalUserData(t *testing.T) {
+	dir := createTestUserFiles(t)
+	defer os.RemoveAll(dir)
+
+	data, err := collectLocalUserData(context.NewMockDefault(), model.Config{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, testLocalUserData, data)
+}
+
+func TestCollectLocalUserDataWithoutShadow(t *testing.T) {
+	dir := createTestUserFiles(t)
+	defer os.RemoveAll(dir)
+	os.Remove(shadowPath)
+	os.Remove(lastlogPath)
+
+	data, err := collectLocalUserData(context.NewMockDefault(), model.Config{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(data))
+	assert.Equal(t, model.LocalUserData{Name: "root", Id: "0", Groups: "root", Shell: "/bin/bash", HomeDirectory: "/root"}, data[0])
+}
+
+func TestCollectLocalUserDataWithoutPasswd(t *testing.T) {
+	dir := createTestUserFiles(t)
+	defer os.RemoveAll(dir)
+	os.Remove(passwdPath)
+
+	data, err := collectLocalUserData(context.NewMockDefault(), model.Config{})
+
+	assert.Error(t, err)
+	assert.Nil(t, data)
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package localuser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/twinj/uuid"
+)
+
+var (
+	startMarker = "<start" + randomString(8) + ">"
+	endMarker   = "<end" + randomString(8) + ">"
+
+	// localUserScript lists the local accounts through ADSI, which unlike Get-LocalUser is available on all
+	// supported versions of Windows, user flags 0x2 and 0x10000 are the account disabled and password never expires flags
+	localUserScript = `
+[Console]::OutputEncoding = [System.Text.Encoding]::UTF8
+$computer = [ADSI]"WinNT://$env:COMPUTERNAME,computer"
+$jsonObj = @()
+foreach($u in ($computer.Children | Where-Object { $_.SchemaClassName -eq 'user' })) {
+$Name = $u.Name.Value
+$Id = (New-Object System.Security.Principal.SecurityIdentifier($u.objectSid.Value, 0)).Value
+$Groups = ($u.Groups() | ForEach-Object { $_.GetType().InvokeMember('Name', 'GetProperty', $null, $_, $null) }) -join ','
+$HomeDirectory = $u.HomeDirectory.Value
+$LastLogin = ''
+try { $LastLogin = $u.LastLogin.Value.ToUniversalTime().ToString("yyyy-MM-dd'T'HH:mm:ss'Z'") } catch {}
+$Flags = $u.UserFlags.Value
+$Disabled = ([bool]($Flags -band 0x2)).ToString().ToLower()
+$PasswordExpired = ([bool]$u.PasswordExpired.Value).ToString().ToLower()
+$PasswordNeverExpires = ([bool]($Flags -band 0x10000)).ToString().ToLower()
+$jsonObj += @"
+{"Name": "` + mark(`$Name`) + `", "Id": "$Id", "Groups": "` + mark(`$Groups`) + `", "HomeDirectory": "` + mark(`$HomeDirectory`) + `",
+"LastLogin": "$LastLogin", "Disabled": "$Disabled", "PasswordExpired": "$PasswordExpired", "PasswordNeverExpires": "$PasswordNeverExpires"}
+"@
+}
+$result = $jsonObj -join ","
+$result = "[" + $result + "]"
+[Console]::WriteLine($result)
+`
+)
+
+const (
+	PowershellCmd = "powershell"
+)
+
+func randomString(length int) string {
+	return uuid.NewV4().String()[:length]
+}
+
+func mark(s string) string {
+	return startMarker + s + endMarker
+}
+
+var cmdExecutor = executeCommand
+
+func executeCommand(command string, args ...string) ([]byte, error) {
+	return exec.Command(command, args...).CombinedOutput()
+}
+
+// collectLocalUserData returns the local accounts along with their groups, last login and password state
+func collectLocalUserData(context context.T, config model.Config) (data []model.LocalUserData, err error) {
+	log := context.Log()
+	var output []byte
+	if output, err = cmdExecutor(PowershellCmd, localUserScript); err != nil {
+		log.Debugf("Command Stderr: %v", string(output))
+		err = fmt.Errorf("command failed with error: %v", err)
+		log.Error(err)
+		return nil, err
+	}
+
+	var cleanOutput string
+	if cleanOutput, err = pluginutil.ReplaceMarkedFields(pluginutil.CleanupNewLines(string(output)), startMarker, endMarker, pluginutil.CleanupJSONField); err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	if err = json.Unmarshal([]byte(cleanOutput), &data); err != nil {
+		err = fmt.Errorf("unable to parse command output - %v", err)
+		log.Error(err)
+		return nil, err
+	}
+	log.Infof("Collected Local Users %d", len(data))
+	return
+}
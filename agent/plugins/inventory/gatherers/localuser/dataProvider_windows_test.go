@@ -0,0 +1,70 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package localuser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var testLocalUserOutput = `[{"Name": "Administrator", "Id": "S-1-5-21-1-2-3-500", "Groups": "Administrators", "HomeDirectory": "",
+"LastLogin": "2019-06-01T10:20:30Z", "Disabled": "false", "PasswordExpired": "false", "PasswordNeverExpires": "true"},{"Name": "Guest", "Id": "S-1-5-21-1-2-3-501", "Groups": "Guests", "HomeDirectory": "",
+"LastLogin": "", "Disabled": "true", "PasswordExpired": "false", "PasswordNeverExpires": "true"}]`
+
+var testLocalUserData = []model.LocalUserData{
+	{Name: "Administrator", Id: "S-1-5-21-1-2-3-500", Groups: "Administrators", LastLogin: "2019-06-01T10:20:30Z", Disabled: "false", PasswordExpired: "false", PasswordNeverExpires: "true"},
+	{Name: "Guest", Id: "S-1-5-21-1-2-3-501", Groups: "Guests", Disabled: "true", PasswordExpired: "false", PasswordNeverExpires: "true"},
+}
+
+func createMockTestExecuteCommand(output string, err error) func(string, ...string) ([]byte, error) {
+	return func(string, ...string) ([]byte, error) {
+		return []byte(output), err
+	}
+}
+
+func TestCollectLocalUserData(t *testing.T) {
+	cmdExecutor = createMockTestExecuteCommand(testLocalUserOutput, nil)
+	defer func() { cmdExecutor = executeCommand }()
+
+	data, err := collectLocalUserData(context.NewMockDefault(), model.Config{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, testLocalUserData, data)
+}
+
+func TestCollectLocalUserDataCmdErr(t *testing.T) {
+	cmdExecutor = createMockTestExecuteCommand("", errors.New("error"))
+	defer func() { cmdExecutor = executeCommand }()
+
+	data, err := collectLocalUserData(context.NewMockDefault(), model.Config{})
+
+	assert.NotNil(t, err)
+	assert.Nil(t, data)
+}
+
+func TestCollectLocalUserDataInvalidOutput(t *testing.T) {
+	cmdExecutor = createMockTestExecuteCommand("invalid", nil)
+	defer func() { cmdExecutor = executeCommand }()
+
+	data, err := collectLocalUserData(context.NewMockDefault(), model.Config{})
+
+	assert.NotNil(t, err)
+	assert.Nil(t, data)
+}
@@ -20,13 +20,23 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/application"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/awscomponent"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/billinginfo"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/certificate"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/container"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/custom"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/file"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/gpu"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/instancedetailedinformation"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/kernel"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/languagepackage"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/listeningport"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/localuser"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/network"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/nvmehealth"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/registry"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/registrykey"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/role"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/service"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/systemd"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/windowsUpdate"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
 )
@@ -60,12 +70,22 @@ func InitializeGatherers(context context.T) (SupportedGatherer, InstalledGathere
 		custom.GathererName:                      custom.Gatherer(context),
 		network.GathererName:                     network.Gatherer(context),
 		billinginfo.GathererName:                 billinginfo.Gatherer(context),
+		certificate.GathererName:                 certificate.Gatherer(context),
+		container.GathererName:                   container.Gatherer(context),
 		windowsUpdate.GathererName:               windowsUpdate.Gatherer(context),
 		file.GathererName:                        file.Gatherer(context),
 		instancedetailedinformation.GathererName: instancedetailedinformation.Gatherer(context),
+		kernel.GathererName:                      kernel.Gatherer(context),
+		languagepackage.GathererName:             languagepackage.Gatherer(context),
+		listeningport.GathererName:               listeningport.Gatherer(context),
+		gpu.GathererName:                         gpu.Gatherer(context),
+		nvmehealth.GathererName:                  nvmehealth.Gatherer(context),
+		localuser.GathererName:                   localuser.Gatherer(context),
 		role.GathererName:                        role.Gatherer(context),
 		service.GathererName:                     service.Gatherer(context),
+		systemd.GathererName:                     systemd.Gatherer(context),
 		registry.GathererName:                    registry.Gatherer(context),
+		registrykey.GathererName:                 registrykey.Gatherer(context),
 	}
 
 	for key := range installedGatherer {
@@ -20,10 +20,19 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/application"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/awscomponent"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/billinginfo"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/certificate"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/container"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/custom"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/file"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/gpu"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/instancedetailedinformation"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/kernel"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/languagepackage"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/listeningport"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/localuser"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/network"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/nvmehealth"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/systemd"
 )
 
 var supportedGathererNames = []string{
@@ -31,7 +40,16 @@ var supportedGathererNames = []string{
 	awscomponent.GathererName,
 	custom.GathererName,
 	billinginfo.GathererName,
+	certificate.GathererName,
+	container.GathererName,
 	network.GathererName,
 	file.GathererName,
 	instancedetailedinformation.GathererName,
+	kernel.GathererName,
+	languagepackage.GathererName,
+	listeningport.GathererName,
+	gpu.GathererName,
+	nvmehealth.GathererName,
+	localuser.GathererName,
+	systemd.GathererName,
 }
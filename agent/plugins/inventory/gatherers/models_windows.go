@@ -21,11 +21,19 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/application"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/awscomponent"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/billinginfo"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/certificate"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/container"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/custom"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/file"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/gpu"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/instancedetailedinformation"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/languagepackage"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/listeningport"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/localuser"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/network"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/nvmehealth"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/registry"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/registrykey"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/role"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/service"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/windowsUpdate"
@@ -37,10 +45,18 @@ var supportedGathererNames = []string{
 	custom.GathererName,
 	network.GathererName,
 	billinginfo.GathererName,
+	certificate.GathererName,
+	container.GathererName,
 	windowsUpdate.GathererName,
 	file.GathererName,
 	instancedetailedinformation.GathererName,
+	languagepackage.GathererName,
+	listeningport.GathererName,
+	gpu.GathererName,
+	nvmehealth.GathererName,
+	localuser.GathererName,
 	role.GathererName,
 	service.GathererName,
 	registry.GathererName,
+	registrykey.GathererName,
 }
@@ -0,0 +1,36 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nvmehealth
+
+import (
+	"sort"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+// collectNVMeHealthData returns the health of the NVMe drives ordered by device, a drive whose health
+// information can not be read is reported without it
+func collectNVMeHealthData(context context.T, config model.Config) (data []model.NVMeHealthData, err error) {
+	log := context.Log()
+	if data, err = collectDrives(log); err != nil {
+		log.Errorf("Unable to collect NVMe drives: %v", err)
+		return nil, err
+	}
+	sort.SliceStable(data, func(i, j int) bool {
+		return data[i].Device < data[j].Device
+	})
+	log.Infof("Collected NVMe drives %d", len(data))
+	return
+}
@@ -0,0 +1,126 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package nvmehealth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	nvmeCmd = "nvme"
+
+	// kelvinOffset converts the composite temperature of the SMART log, which is in Kelvin, to Celsius
+	kelvinOffset = 273
+)
+
+// nvmeList is the output of nvme list
+type nvmeList struct {
+	Devices []struct {
+		DevicePath   string
+		ModelNumber  string
+		SerialNumber string
+		Firmware     string
+	}
+}
+
+// smartLog is the output of nvme smart-log, newer versions of nvme-cli name percent_used percentage_used
+type smartLog struct {
+	CriticalWarning json.Number `json:"critical_warning"`
+	Temperature     json.Number `json:"temperature"`
+	AvailableSpare  json.Number `json:"avail_spare"`
+	PercentUsed     json.Number `json:"percent_used"`
+	PercentageUsed  json.Number `json:"percentage_used"`
+	MediaErrors     json.Number `json:"media_errors"`
+	PowerOnHours    json.Number `json:"power_on_hours"`
+}
+
+var cmdExecutor = executeCommand
+
+// decoupling exec.LookPath for easy testability
+var lookPath = exec.LookPath
+
+func executeCommand(command string, args ...string) ([]byte, error) {
+	return exec.Command(command, args...).Output()
+}
+
+// collectDrives returns the NVMe drives listed by nvme-cli with their SMART health information, a drive with
+// several namespaces is reported once. Instances without nvme-cli report no drives.
+func collectDrives(log log.T) (data []model.NVMeHealthData, err error) {
+	data = []model.NVMeHealthData{}
+	if _, lookErr := lookPath(nvmeCmd); lookErr != nil {
+		log.Debugf("nvme-cli is not installed, no NVMe drives are reported")
+		return
+	}
+
+	var output []byte
+	if output, err = cmdExecutor(nvmeCmd, "list", "-o", "json"); err != nil {
+		return nil, fmt.Errorf("command failed with error: %v", err)
+	}
+	var list nvmeList
+	if len(output) > 0 {
+		if err = json.Unmarshal(output, &list); err != nil {
+			return nil, fmt.Errorf("unable to parse command output - %v", err)
+		}
+	}
+
+	reported := map[string]bool{}
+	for _, device := range list.Devices {
+		if reported[device.SerialNumber] {
+			continue
+		}
+		reported[device.SerialNumber] = true
+
+		drive := model.NVMeHealthData{
+			Device:          device.DevicePath,
+			Model:           device.ModelNumber,
+			SerialNumber:    device.SerialNumber,
+			FirmwareVersion: device.Firmware,
+		}
+		if health, healthErr := readSmartLog(device.DevicePath); healthErr != nil {
+			log.Debugf("Unable to read the SMART log of %v: %v", device.DevicePath, healthErr)
+		} else {
+			drive.PercentageUsed = health.PercentUsed.String()
+			if drive.PercentageUsed == "" {
+				drive.PercentageUsed = health.PercentageUsed.String()
+			}
+			drive.AvailableSpare = health.AvailableSpare.String()
+			drive.MediaErrors = health.MediaErrors.String()
+			drive.PowerOnHours = health.PowerOnHours.String()
+			drive.CriticalWarning = health.CriticalWarning.String()
+			if kelvin, parseErr := strconv.Atoi(health.Temperature.String()); parseErr == nil {
+				drive.TemperatureCelsius = strconv.Itoa(kelvin - kelvinOffset)
+			}
+		}
+		data = append(data, drive)
+	}
+	return
+}
+
+// readSmartLog returns the SMART / health information log of the device
+func readSmartLog(device string) (health smartLog, err error) {
+	var output []byte
+	if output, err = cmdExecutor(nvmeCmd, "smart-log", device, "-o", "json"); err != nil {
+		return health, fmt.Errorf("command failed with error: %v", err)
+	}
+	err = json.Unmarshal(output, &health)
+	return
+}
@@ -0,0 +1,151 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package nvmehealth
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testNvmeListOutput = `{
+  "Devices" : [
+    {
+      "NameSpace" : 1,
+      "DevicePath" : "/dev/nvme1n1",
+      "Firmware" : "0",
+      "Index" : 1,
+      "ModelNumber" : "Amazon EC2 NVMe Instance Storage",
+      "SerialNumber" : "AWS1A2B3C4D5E6F7G8H9",
+      "UsedBytes" : 3750000000000
+    },
+    {
+      "NameSpace" : 1,
+      "DevicePath" : "/dev/nvme0n1",
+      "Firmware" : "1.0",
+      "Index" : 0,
+      "ModelNumber" : "Amazon Elastic Block Store",
+      "SerialNumber" : "vol0123456789abcdef0",
+      "UsedBytes" : 8589934592
+    },
+    {
+      "NameSpace" : 2,
+      "DevicePath" : "/dev/nvme1n2",
+      "Firmware" : "0",
+      "Index" : 2,
+      "ModelNumber" : "Amazon EC2 NVMe Instance Storage",
+      "SerialNumber" : "AWS1A2B3C4D5E6F7G8H9",
+      "UsedBytes" : 0
+    }
+  ]
+}`
+	testSmartLogOutput = `{
+  "critical_warning" : 0,
+  "temperature" : 311,
+  "avail_spare" : 100,
+  "spare_thresh" : 10,
+  "percent_used" : 3,
+  "data_units_read" : 9487142,
+  "power_on_hours" : 1432,
+  "media_errors" : 0
+}`
+)
+
+var testUnixNVMeHealthData = []model.NVMeHealthData{
+	{
+		Device:          "/dev/nvme0n1",
+		Model:           "Amazon Elastic Block Store",
+		SerialNumber:    "vol0123456789abcdef0",
+		FirmwareVersion: "1.0",
+	},
+	{
+		Device:             "/dev/nvme1n1",
+		Model:              "Amazon EC2 NVMe Instance Storage",
+		SerialNumber:       "AWS1A2B3C4D5E6F7G8H9",
+		FirmwareVersion:    "0",
+		PercentageUsed:     "3",
+		AvailableSpare:     "100",
+		TemperatureCelsius: "38",
+		MediaErrors:        "0",
+		PowerOnHours:       "1432",
+		CriticalWarning:    "0",
+	},
+}
+
+// mockNvmeCli returns the outputs of nvme-cli by subcommand and device, other invocations fail
+func mockNvmeCli(outputs map[string]string) func() {
+	lookPath = func(file string) (string, error) { return "/usr/sbin/" + file, nil }
+	cmdExecutor = func(command string, args ...string) ([]byte, error) {
+		key := strings.Join(args[:len(args)-2], " ")
+		if output, found := outputs[key]; found {
+			return []byte(output), nil
+		}
+		return nil, errors.New("exit status 1")
+	}
+	return func() {
+		lookPath = exec.LookPath
+		cmdExecutor = executeCommand
+	}
+}
+
+func TestCollectNVMeHealthData(t *testing.T) {
+	defer mockNvmeCli(map[string]string{
+		"list":                   testNvmeListOutput,
+		"smart-log /dev/nvme1n1": testSmartLogOutput,
+	})()
+
+	data, err := collectNVMeHealthData(context.NewMockDefault(), model.Config{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, testUnixNVMeHealthData, data)
+}
+
+func TestCollectNVMeHealthDataPercentageUsed(t *testing.T) {
+	defer mockNvmeCli(map[string]string{
+		"list":                   testNvmeListOutput,
+		"smart-log /dev/nvme1n1": strings.Replace(testSmartLogOutput, "percent_used", "percentage_used", 1),
+	})()
+
+	data, err := collectNVMeHealthData(context.NewMockDefault(), model.Config{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "3", data[1].PercentageUsed)
+}
+
+func TestCollectNVMeHealthDataWithoutNvmeCli(t *testing.T) {
+	defer mockNvmeCli(map[string]string{})()
+	lookPath = func(file string) (string, error) { return "", errors.New("executable file not found in $PATH") }
+
+	data, err := collectNVMeHealthData(context.NewMockDefault(), model.Config{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.NVMeHealthData{}, data)
+}
+
+func TestCollectNVMeHealthDataCmdErr(t *testing.T) {
+	defer mockNvmeCli(map[string]string{})()
+
+	data, err := collectNVMeHealthData(context.NewMockDefault(), model.Config{})
+
+	assert.Error(t, err)
+	assert.Nil(t, data)
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package nvmehealth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/twinj/uuid"
+)
+
+const PowershellCmd = "powershell"
+
+var (
+	startMarker = "<start" + randomString(8) + ">"
+	endMarker   = "<end" + randomString(8) + ">"
+
+	// physicalDiskScript writes the NVMe disks of the storage module with their reliability counters, Windows
+	// reports neither the available spare nor the critical warning of the drives
+	physicalDiskScript = `
+[Console]::OutputEncoding = [System.Text.Encoding]::UTF8
+$drives = @()
+Get-PhysicalDisk | Where-Object { @("17", "NVMe") -contains "$($_.BusType)" } | ForEach-Object {
+	$device = "\\.\PHYSICALDRIVE" + $_.DeviceId
+	$model = $_.FriendlyName
+	$serialNumber = "$($_.SerialNumber)".Trim()
+	$firmwareVersion = $_.FirmwareVersion
+	$counter = $_ | Get-StorageReliabilityCounter -ErrorAction SilentlyContinue
+	$wear = $counter.Wear
+	$temperature = $counter.Temperature
+	$mediaErrors = $counter.ReadErrorsUncorrected
+	$powerOnHours = $counter.PowerOnHours
+	$drives += @"
+{"Device":"` + mark(`$device`) + `","Model":"` + mark(`$model`) + `","SerialNumber":"` + mark(`$serialNumber`) + `","FirmwareVersion":"` + mark(`$firmwareVersion`) + `","PercentageUsed":"$wear","TemperatureCelsius":"$temperature","MediaErrors":"$mediaErrors","PowerOnHours":"$powerOnHours"}
+"@
+}
+[Console]::WriteLine("[" + ($drives -join ",") + "]")
+`
+)
+
+var cmdExecutor = executeCommand
+
+func executeCommand(command string, args ...string) ([]byte, error) {
+	return exec.Command(command, args...).Output()
+}
+
+func randomString(length int) string {
+	return uuid.NewV4().String()[:length]
+}
+
+func mark(s string) string {
+	return startMarker + s + endMarker
+}
+
+// collectDrives returns the NVMe disks with the reliability counters of the storage module
+func collectDrives(log log.T) (data []model.NVMeHealthData, err error) {
+	var output []byte
+	if output, err = cmdExecutor(PowershellCmd, physicalDiskScript); err != nil {
+		return nil, fmt.Errorf("command failed with error: %v", err)
+	}
+	var cleanOutput string
+	if cleanOutput, err = pluginutil.ReplaceMarkedFields(pluginutil.CleanupNewLines(string(output)), startMarker, endMarker, pluginutil.CleanupJSONField); err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal([]byte(cleanOutput), &data); err != nil {
+		return nil, fmt.Errorf("unable to parse command output - %v", err)
+	}
+	return
+}
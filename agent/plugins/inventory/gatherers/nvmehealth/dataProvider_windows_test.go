@@ -0,0 +1,62 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package nvmehealth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var testPhysicalDiskOutput = "[" +
+	`{"Device":"` + mark(`\\.\PHYSICALDRIVE1`) + `","Model":"` + mark("NVMe Amazon EC2 NVMe") + `","SerialNumber":"` + mark("AWS1A2B3C4D5E6F7G8H9") + `","FirmwareVersion":"` + mark("0") + `","PercentageUsed":"3","TemperatureCelsius":"38","MediaErrors":"0","PowerOnHours":"1432"}` +
+	"]"
+
+func TestCollectNVMeHealthData(t *testing.T) {
+	cmdExecutor = func(command string, args ...string) ([]byte, error) {
+		return []byte(testPhysicalDiskOutput), nil
+	}
+	defer func() { cmdExecutor = executeCommand }()
+
+	data, err := collectNVMeHealthData(context.NewMockDefault(), model.Config{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []model.NVMeHealthData{{
+		Device:             `\\.\PHYSICALDRIVE1`,
+		Model:              "NVMe Amazon EC2 NVMe",
+		SerialNumber:       "AWS1A2B3C4D5E6F7G8H9",
+		FirmwareVersion:    "0",
+		PercentageUsed:     "3",
+		TemperatureCelsius: "38",
+		MediaErrors:        "0",
+		PowerOnHours:       "1432",
+	}}, data)
+}
+
+func TestCollectNVMeHealthDataCmdErr(t *testing.T) {
+	cmdExecutor = func(command string, args ...string) ([]byte, error) {
+		return nil, errors.New("error")
+	}
+	defer func() { cmdExecutor = executeCommand }()
+
+	data, err := collectNVMeHealthData(context.NewMockDefault(), model.Config{})
+
+	assert.NotNil(t, err)
+	assert.Nil(t, data)
+}
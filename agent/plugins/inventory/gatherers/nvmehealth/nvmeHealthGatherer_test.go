@@ -0,0 +1,54 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package nvmehealth
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var testNVMeHealthData = []model.NVMeHealthData{
+	{
+		Device:             "/dev/nvme0n1",
+		Model:              "Amazon Elastic Block Store",
+		SerialNumber:       "vol0123456789abcdef0",
+		FirmwareVersion:    "1.0",
+		PercentageUsed:     "0",
+		AvailableSpare:     "100",
+		TemperatureCelsius: "0",
+		MediaErrors:        "0",
+		PowerOnHours:       "0",
+		CriticalWarning:    "0",
+	},
+}
+
+func testCollectNVMeHealthData(context context.T, config model.Config) (data []model.NVMeHealthData, err error) {
+	return testNVMeHealthData, nil
+}
+
+func TestGatherer(t *testing.T) {
+	contextMock := context.NewMockDefault()
+	gatherer := Gatherer(contextMock)
+	collectData = testCollectNVMeHealthData
+	defer func() { collectData = collectNVMeHealthData }()
+	item, err := gatherer.Run(contextMock, model.Config{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(item))
+	assert.Equal(t, GathererName, item[0].Name)
+	assert.Equal(t, SchemaVersionOfNVMeHealthGatherer, item[0].SchemaVersion)
+	assert.Equal(t, testNVMeHealthData, item[0].Content)
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package registrykey
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/twinj/uuid"
+)
+
+const (
+	PowershellCmd      = "powershell"
+	MaxValueCountLimit = 250
+	ValueLimitExceeded = "ValueLimitExceeded"
+)
+
+var ValueCountLimitExceeded = errors.New("Exceeded registry value count limit")
+
+func randomString(length int) string {
+	return uuid.NewV4().String()[:length]
+}
+
+var cmdExecutor = executeCommand
+
+func executeCommand(command string, args ...string) ([]byte, error) {
+	return exec.Command(command, args...).CombinedOutput()
+}
+
+// quote returns the value as a powershell single quoted string literal, in which only single quotes are special
+func quote(value string) string {
+	return "'" + strings.Replace(value, "'", "''", -1) + "'"
+}
+
+// collectRegistryKeyData returns the values of the registry keys configured in appconfig
+func collectRegistryKeyData(context context.T, config model.Config) (data []model.RegistryData, err error) {
+	log := context.Log()
+	data = []model.RegistryData{}
+	for _, key := range context.AppConfig().Ssm.WindowsRegistryInventoryKeys {
+		var values []model.RegistryData
+		if values, err = collectKeyValues(log, key, MaxValueCountLimit-len(data)); err != nil {
+			if err == ValueCountLimitExceeded {
+				log.Errorf("Number of registry values collected exceeded limit %v", MaxValueCountLimit)
+				return nil, err
+			}
+			log.Errorf("Unable to collect registry values of %v: %v", key.Path, err)
+			err = nil
+			continue
+		}
+		data = append(data, values...)
+	}
+	log.Infof("Collected %d registry values", len(data))
+	return
+}
+
+// collectKeyValues returns the values of the registry keys matching the configured key
+func collectKeyValues(log log.T, key appconfig.RegistryInventoryKey, valueLimit int) (values []model.RegistryData, err error) {
+	var valueNames []string
+	for _, name := range key.ValueNames {
+		valueNames = append(valueNames, quote(name))
+	}
+	script := fmt.Sprintf(registryKeyScript, valueLimit, strings.Join(valueNames, ","), quote("Registry::"+key.Path))
+
+	var output []byte
+	if output, err = cmdExecutor(PowershellCmd, script); err != nil {
+		log.Debugf("Command Stderr: %v", string(output))
+		return nil, fmt.Errorf("command failed with error: %v", err)
+	}
+
+	var cleanOutput string
+	if cleanOutput, err = pluginutil.ReplaceMarkedFields(pluginutil.CleanupNewLines(string(output)), startMarker, endMarker, pluginutil.CleanupJSONField); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(cleanOutput) == ValueLimitExceeded {
+		return nil, ValueCountLimitExceeded
+	}
+	if err = json.Unmarshal([]byte(cleanOutput), &values); err != nil {
+		return nil, fmt.Errorf("unable to parse command output - %v", err)
+	}
+	return
+}
@@ -0,0 +1,138 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package registrykey
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var testRegistryKeyData = []model.RegistryData{
+	{
+		KeyPath:   `HKEY_LOCAL_MACHINE\SOFTWARE\Contoso\Agent`,
+		ValueName: "Version",
+		ValueType: "REG_SZ",
+		Value:     "2.3.1",
+	},
+	{
+		KeyPath:   `HKEY_LOCAL_MACHINE\SOFTWARE\Contoso\Agent`,
+		ValueName: "Certificate",
+		ValueType: "REG_BINARY",
+		Value:     "BinaryValue",
+	},
+}
+
+var testKey = appconfig.RegistryInventoryKey{
+	Path:       `HKEY_LOCAL_MACHINE\SOFTWARE\Contoso\Agent`,
+	ValueNames: []string{"Version", "Cert*"},
+}
+
+var testKeyOutput = "[" +
+	`{"KeyPath":"` + mark(`HKEY_LOCAL_MACHINE\SOFTWARE\Contoso\Agent`) + `","Value":"` + mark("2.3.1") + `","ValueName":"` + mark("Version") + `","ValueType":"REG_SZ"},` +
+	`{"KeyPath":"` + mark(`HKEY_LOCAL_MACHINE\SOFTWARE\Contoso\Agent`) + `","Value":"` + mark("BinaryValue") + `","ValueName":"` + mark("Certificate") + `","ValueType":"REG_BINARY"}` +
+	"]"
+
+// contextWithKeys returns a mock context whose appconfig declares the registry keys
+func contextWithKeys(keys ...appconfig.RegistryInventoryKey) *context.Mock {
+	config := appconfig.SsmagentConfig{}
+	config.Ssm.WindowsRegistryInventoryKeys = keys
+	ctx := new(context.Mock)
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+	return ctx
+}
+
+// mockScriptOutputs returns the output for the script containing the key path, the executed scripts are recorded
+func mockScriptOutputs(outputs map[string]string, scripts *[]string) func() {
+	cmdExecutor = func(command string, args ...string) ([]byte, error) {
+		script := args[0]
+		*scripts = append(*scripts, script)
+		for path, output := range outputs {
+			if strings.Contains(script, quote("Registry::"+path)) {
+				return []byte(output), nil
+			}
+		}
+		return nil, errors.New("exit status 1")
+	}
+	return func() { cmdExecutor = executeCommand }
+}
+
+func TestCollectRegistryKeyData(t *testing.T) {
+	var scripts []string
+	defer mockScriptOutputs(map[string]string{testKey.Path: testKeyOutput}, &scripts)()
+
+	data, err := collectRegistryKeyData(contextWithKeys(testKey), model.Config{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, testRegistryKeyData, data)
+	assert.Equal(t, 1, len(scripts))
+	assert.Contains(t, scripts[0], "$valueLimit = 250")
+	assert.Contains(t, scripts[0], "$valueNames = @('Version','Cert*')")
+}
+
+func TestCollectRegistryKeyDataQuotesPath(t *testing.T) {
+	key := appconfig.RegistryInventoryKey{Path: `HKEY_LOCAL_MACHINE\SOFTWARE\O'Brien's "App"; $(Remove-Item)`}
+	var scripts []string
+	defer mockScriptOutputs(map[string]string{key.Path: "[]"}, &scripts)()
+
+	data, err := collectRegistryKeyData(contextWithKeys(key), model.Config{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []model.RegistryData{}, data)
+	assert.Contains(t, scripts[0], `-Path 'Registry::HKEY_LOCAL_MACHINE\SOFTWARE\O''Brien''s "App"; $(Remove-Item)'`)
+	assert.Contains(t, scripts[0], "$valueNames = @()")
+}
+
+func TestCollectRegistryKeyDataSkipsFailingKey(t *testing.T) {
+	failing := appconfig.RegistryInventoryKey{Path: `HKEY_LOCAL_MACHINE\SOFTWARE\Missing`}
+	var scripts []string
+	defer mockScriptOutputs(map[string]string{testKey.Path: testKeyOutput}, &scripts)()
+
+	data, err := collectRegistryKeyData(contextWithKeys(failing, testKey), model.Config{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, testRegistryKeyData, data)
+	assert.Equal(t, 2, len(scripts))
+	assert.Contains(t, scripts[1], "$valueLimit = 250")
+}
+
+func TestCollectRegistryKeyDataLimitExceeded(t *testing.T) {
+	other := appconfig.RegistryInventoryKey{Path: `HKEY_LOCAL_MACHINE\SOFTWARE\Contoso\Other`}
+	var scripts []string
+	defer mockScriptOutputs(map[string]string{testKey.Path: testKeyOutput, other.Path: ValueLimitExceeded + "\r\n"}, &scripts)()
+
+	data, err := collectRegistryKeyData(contextWithKeys(testKey, other), model.Config{})
+
+	assert.Equal(t, ValueCountLimitExceeded, err)
+	assert.Nil(t, data)
+	assert.Contains(t, scripts[1], "$valueLimit = 248")
+}
+
+func TestCollectRegistryKeyDataNoKeys(t *testing.T) {
+	var scripts []string
+	defer mockScriptOutputs(map[string]string{}, &scripts)()
+
+	data, err := collectRegistryKeyData(contextWithKeys(), model.Config{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []model.RegistryData{}, data)
+	assert.Equal(t, 0, len(scripts))
+}
@@ -0,0 +1,67 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package registrykey
+
+var (
+	startMarker = "<start" + randomString(8) + ">"
+	endMarker   = "<end" + randomString(8) + ">"
+
+	// registryKeyScript writes the values of the registry keys matching the path, which may contain wildcards,
+	// it is formatted with the value limit, the value name patterns and the path as powershell string literals
+	registryKeyScript = `
+[Console]::OutputEncoding = [System.Text.Encoding]::UTF8
+$valueLimit = %d
+$valueNames = @(%s)
+$entries = @()
+
+function Get-Type-Name($typeName) {
+	switch($typeName) {
+		"Binary" { return "REG_BINARY" }
+		"DWord" { return "REG_DWORD" }
+		"ExpandString" { return "REG_EXPAND_SZ" }
+		"MultiString" { return "REG_MULTI_SZ" }
+		"QWord" { return "REG_QWORD" }
+		"String" { return "REG_SZ" }
+		default { return $typeName }
+	}
+}
+
+foreach ($key in @(Get-Item -Path %s -ErrorAction SilentlyContinue)) {
+	foreach ($valueName in $key.GetValueNames()) {
+		if ($valueNames.Count -gt 0 -and @($valueNames | Where-Object { $valueName -like $_ }).Count -eq 0) {
+			continue
+		}
+		if ($entries.Count -ge $valueLimit) {
+			[Console]::WriteLine("ValueLimitExceeded")
+			exit
+		}
+		$value = $key.GetValue($valueName)
+		$valueType = $key.GetValueKind($valueName)
+		if ($valueType.ToString() -eq "Binary") {
+			$value = "BinaryValue"
+		}
+		$valueTypeName = Get-Type-Name $valueType
+		$keyName = $key.Name
+		$entries += @"
+{"KeyPath":"` + mark(`$keyName`) + `","Value":"` + mark(`$value`) + `","ValueName":"` + mark(`$valueName`) + `","ValueType":"$valueTypeName"}
+"@
+	}
+}
+[Console]::WriteLine("[" + ($entries -join ",") + "]")
+`
+)
+
+func mark(s string) string {
+	return startMarker + s + endMarker
+}
@@ -0,0 +1,108 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package systemd
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+)
+
+const (
+	systemctlCmd = "systemctl"
+
+	// templateUnitSuffix is the suffix of template unit files, which cannot be queried without an instance name
+	templateUnitSuffix = "@.service"
+)
+
+// unitProperties are the properties of the service units which are reported to inventory
+var unitProperties = []string{"Id", "Description", "UnitFileState", "FragmentPath", "LoadState", "ActiveState", "SubState"}
+
+// decoupling exec.LookPath for easy testability
+var lookPath = exec.LookPath
+
+var cmdExecutor = executeCommand
+
+func executeCommand(command string, args ...string) ([]byte, error) {
+	return exec.Command(command, args...).Output()
+}
+
+// collectSystemdServiceData collects the installed systemd service units with their enablement and active state,
+// no data is collected on instances which do not run systemd
+func collectSystemdServiceData(context context.T, config model.Config) (data []model.SystemdServiceData, err error) {
+	log := context.Log()
+	data = []model.SystemdServiceData{}
+	if _, lookErr := lookPath(systemctlCmd); lookErr != nil {
+		log.Debugf("%v is not installed, no systemd services to collect", systemctlCmd)
+		return
+	}
+
+	output, err := runSystemctl(log, "list-unit-files", "--type=service", "--no-legend", "--no-pager")
+	if err != nil {
+		return nil, err
+	}
+	units := []string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 && !strings.HasSuffix(fields[0], templateUnitSuffix) {
+			units = append(units, fields[0])
+		}
+	}
+	if len(units) == 0 {
+		return
+	}
+
+	args := append([]string{"show", "--no-pager", "--property=" + strings.Join(unitProperties, ",")}, units...)
+	if output, err = runSystemctl(log, args...); err != nil {
+		return nil, err
+	}
+	return parseUnitProperties(string(output)), nil
+}
+
+// parseUnitProperties parses the Key=Value blocks of systemctl show, the blocks of the units are separated by empty lines
+func parseUnitProperties(output string) (data []model.SystemdServiceData) {
+	data = []model.SystemdServiceData{}
+	for _, block := range strings.Split(strings.Replace(output, "\r\n", "\n", -1), "\n\n") {
+		properties := map[string]string{}
+		for _, line := range strings.Split(block, "\n") {
+			if parts := strings.SplitN(line, "=", 2); len(parts) == 2 {
+				properties[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+			}
+		}
+		if properties["Id"] == "" {
+			continue
+		}
+		data = append(data, model.SystemdServiceData{
+			Name:          properties["Id"],
+			Description:   properties["Description"],
+			UnitFileState: properties["UnitFileState"],
+			UnitFilePath:  properties["FragmentPath"],
+			LoadState:     properties["LoadState"],
+			ActiveState:   properties["ActiveState"],
+			SubState:      properties["SubState"],
+		})
+	}
+	return
+}
+
+func runSystemctl(log log.T, args ...string) (output []byte, err error) {
+	log.Debugf("Executing command: %v %v", systemctlCmd, args)
+	if output, err = cmdExecutor(systemctlCmd, args...); err != nil {
+		err = fmt.Errorf("command %v %v failed: %v", systemctlCmd, strings.Join(args, " "), err)
+	}
+	return
+}
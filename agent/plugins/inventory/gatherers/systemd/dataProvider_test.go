@@ -0,0 +1,136 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package systemd
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testUnitFiles = `amazon-ssm-agent.service                   enabled
+getty@.service                             enabled
+sshd.service                               enabled
+tmp.service                                masked
+`
+	testUnitProperties = `Id=amazon-ssm-agent.service
+Description=amazon-ssm-agent
+LoadState=loaded
+ActiveState=active
+SubState=running
+FragmentPath=/etc/systemd/system/amazon-ssm-agent.service
+UnitFileState=enabled
+
+Id=sshd.service
+Description=OpenSSH server daemon, key=value
+LoadState=loaded
+ActiveState=inactive
+SubState=dead
+FragmentPath=/usr/lib/systemd/system/sshd.service
+UnitFileState=disabled
+
+Id=tmp.service
+Description=tmp.service
+LoadState=masked
+ActiveState=inactive
+SubState=dead
+FragmentPath=
+UnitFileState=masked
+`
+)
+
+var testSystemdServiceData = []model.SystemdServiceData{
+	{
+		Name:          "amazon-ssm-agent.service",
+		Description:   "amazon-ssm-agent",
+		UnitFileState: "enabled",
+		UnitFilePath:  "/etc/systemd/system/amazon-ssm-agent.service",
+		LoadState:     "loaded",
+		ActiveState:   "active",
+		SubState:      "running",
+	},
+	{
+		Name:          "sshd.service",
+		Description:   "OpenSSH server daemon, key=value",
+		UnitFileState: "disabled",
+		UnitFilePath:  "/usr/lib/systemd/system/sshd.service",
+		LoadState:     "loaded",
+		ActiveState:   "inactive",
+		SubState:      "dead",
+	},
+	{
+		Name:          "tmp.service",
+		Description:   "tmp.service",
+		UnitFileState: "masked",
+		LoadState:     "masked",
+		ActiveState:   "inactive",
+		SubState:      "dead",
+	},
+}
+
+func mockSystemctl(installed bool, outputs map[string]string) func() {
+	cmdExecutor = func(command string, args ...string) ([]byte, error) {
+		if output, found := outputs[strings.Join(args, " ")]; found {
+			return []byte(output), nil
+		}
+		return nil, errors.New("exit status 1")
+	}
+	lookPath = func(file string) (string, error) {
+		if installed {
+			return "/usr/bin/" + file, nil
+		}
+		return "", errors.New("not found")
+	}
+	return func() {
+		cmdExecutor = executeCommand
+		lookPath = exec.LookPath
+	}
+}
+
+func TestCollectSystemdServiceData(t *testing.T) {
+	defer mockSystemctl(true, map[string]string{
+		"list-unit-files --type=service --no-legend --no-pager": testUnitFiles,
+		"show --no-pager --property=Id,Description,UnitFileState,FragmentPath,LoadState,ActiveState,SubState " +
+			"amazon-ssm-agent.service sshd.service tmp.service": testUnitProperties,
+	})()
+
+	data, err := collectSystemdServiceData(context.NewMockDefault(), model.Config{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, testSystemdServiceData, data)
+}
+
+func TestCollectSystemdServiceDataWithoutSystemd(t *testing.T) {
+	defer mockSystemctl(false, map[string]string{})()
+
+	data, err := collectSystemdServiceData(context.NewMockDefault(), model.Config{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(data))
+}
+
+func TestCollectSystemdServiceDataCmdErr(t *testing.T) {
+	defer mockSystemctl(true, map[string]string{})()
+
+	data, err := collectSystemdServiceData(context.NewMockDefault(), model.Config{})
+
+	assert.NotNil(t, err)
+	assert.Nil(t, data)
+}
@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -34,15 +35,26 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/application"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/awscomponent"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/billinginfo"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/certificate"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/container"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/custom"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/file"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/gpu"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/instancedetailedinformation"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/kernel"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/languagepackage"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/listeningport"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/localuser"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/network"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/nvmehealth"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/registry"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/registrykey"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/role"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/service"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/systemd"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers/windowsUpdate"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/throttle"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -59,21 +71,41 @@ const (
 	errorMsgForInabilityToSendDataToSSM       = "inventory data could not be uploaded to Systems Manager. Additional troubleshooting information - %v"
 	msgWhenNoDataToReturnForInventoryPlugin   = "Inventory policy has been successfully applied but there is no inventory data to upload to SSM"
 	successfulMsgForInventoryPlugin           = "Inventory policy has been successfully applied and collected inventory data has been uploaded to SSM"
+	msgWhenInventoryDataUnchanged             = "Inventory policy has been successfully applied and collected inventory data has not changed since the last upload to SSM"
+	errorMsgForFailedGatherer                 = "%v did not collect inventory data, the data of the other inventory types is still uploaded - %v"
+	errorMsgForAllGatherersFailed             = "none of the inventory gatherers collected inventory data"
 )
 
+// gathererResult is the inventory collected by a gatherer or the error it encountered
+type gathererResult struct {
+	items []model.Item
+	err   error
+}
+
 // PluginInput represents configuration which is applied to inventory plugin during execution.
 type PluginInput struct {
 	contracts.PluginInput
 	Applications                string
+	LanguagePackages            string
+	LocalUsers                  string
 	AWSComponents               string
 	NetworkConfig               string
+	ListeningPorts              string
+	GPUs                        string
+	NVMeHealth                  string
 	BillingInfo                 string
+	Containers                  string
 	Files                       string
+	Certificates                string
 	WindowsRoles                string
 	Services                    string
+	SystemdServices             string
 	WindowsRegistry             string
+	WindowsRegistryKeys         string
 	WindowsUpdates              string
 	InstanceDetailedInformation string
+	KernelModules               string
+	KernelParameters            string
 	CustomInventory             string
 	CustomInventoryDirectory    string
 }
@@ -142,6 +174,7 @@ func (p *Plugin) ApplyInventoryPolicy(context context.T, inventoryInput PluginIn
 	log := p.context.Log()
 	var optimizedInventoryItems, nonOptimizedInventoryItems []*ssm.InventoryItem
 	var items []model.Item
+	var gathererErrors map[string]error
 	var err error
 
 	//map of all valid gatherers & respective configs to run
@@ -156,12 +189,15 @@ func (p *Plugin) ApplyInventoryPolicy(context context.T, inventoryInput PluginIn
 	}
 
 	//execute all eligible gatherers with their respective config
-	if items, err = p.RunGatherers(gatherers); err != nil {
+	if items, gathererErrors, err = p.RunGatherers(gatherers); err != nil {
 		log.Info(err.Error())
 		output.SetExitCode(1)
 		output.AppendError(err.Error())
 		return
 	}
+	if !reportGathererErrors(output, gatherers, gathererErrors) {
+		return
+	}
 
 	//check if there is data to send to SSM
 	if len(items) == 0 {
@@ -176,6 +212,12 @@ func (p *Plugin) ApplyInventoryPolicy(context context.T, inventoryInput PluginIn
 	d, _ := json.Marshal(items)
 	log.Debugf("Collected Inventory data: %v", string(d))
 
+	//with delta uploads only the inventory types which changed are sent until all types are due to be uploaded again
+	if !p.uploader.IsFullUploadDue(context) {
+		p.uploadChangedInventoryItems(context, items, output)
+		return
+	}
+
 	if optimizedInventoryItems, nonOptimizedInventoryItems, err = p.uploader.ConvertToSsmInventoryItems(p.context, items); err != nil {
 		log.Infof("Encountered error in converting data to SSM InventoryItems - %v. Skipping upload to SSM", err.Error())
 		output.SetExitCode(1)
@@ -204,6 +246,8 @@ func (p *Plugin) ApplyInventoryPolicy(context context.T, inventoryInput PluginIn
 		}
 	}
 
+	p.uploader.UpdateFullUploadTime(context)
+
 	log.Infof("%v uploaded inventory data to SSM", Name())
 	output.SetExitCode(0)
 	output.AppendInfo(successfulMsgForInventoryPlugin)
@@ -211,22 +255,58 @@ func (p *Plugin) ApplyInventoryPolicy(context context.T, inventoryInput PluginIn
 	return
 }
 
+// uploadChangedInventoryItems uploads the inventory types whose content changed since they were last uploaded,
+// PutInventory is not called when no inventory type changed
+func (p *Plugin) uploadChangedInventoryItems(context context.T, items []model.Item, output iohandler.IOHandler) {
+	log := p.context.Log()
+
+	var dirtyItems []*ssm.InventoryItem
+	var err error
+
+	if dirtyItems, err = p.uploader.GetDirtySsmInventoryItems(context, items); err != nil {
+		log.Infof("Encountered error in collecting changed inventory items - %v. Skipping upload to SSM", err.Error())
+		output.SetExitCode(1)
+		output.AppendError(err.Error())
+		return
+	}
+
+	if len(dirtyItems) == 0 {
+		log.Info(msgWhenInventoryDataUnchanged)
+		output.SetExitCode(0)
+		output.AppendInfo(msgWhenInventoryDataUnchanged)
+		return
+	}
+
+	if err = p.uploader.SendDataToSSM(context, dirtyItems); err != nil {
+		propagateSSMError(output, err, log)
+		return
+	}
+
+	log.Infof("%v uploaded %v changed inventory types to SSM", Name(), len(dirtyItems))
+	output.SetExitCode(0)
+	output.AppendInfo(successfulMsgForInventoryPlugin)
+}
+
 // ApplyInventoryFrequentCollector applies frequent collector regarding which gatherers to run
 func (p Plugin) ApplyInventoryFrequentCollector(context context.T, gatherers map[gatherers.T]model.Config, output iohandler.IOHandler) {
 	log := p.context.Log()
 
 	var dirtyItems []*ssm.InventoryItem
 	var items []model.Item
+	var gathererErrors map[string]error
 	var err error
 
 	//execute all specified gatherers with their respective config
-	if items, err = p.RunGatherers(gatherers); err != nil {
+	if items, gathererErrors, err = p.RunGatherers(gatherers); err != nil {
 		log.Debugf("failed at RunGatherers, error : %#v", err)
 		log.Info(err.Error())
 		output.SetExitCode(1)
 		output.AppendError(err.Error())
 		return
 	}
+	if !reportGathererErrors(output, gatherers, gathererErrors) {
+		return
+	}
 
 	//check if there is data to send to SSM
 	if len(items) == 0 {
@@ -266,6 +346,26 @@ func (p Plugin) ApplyInventoryFrequentCollector(context context.T, gatherers map
 	return
 }
 
+// reportGathererErrors appends the errors of the gatherers which did not collect inventory data to the output,
+// it returns false and fails the plugin when none of the gatherers collected data
+func reportGathererErrors(output iohandler.IOHandler, gatherers map[gatherers.T]model.Config, gathererErrors map[string]error) bool {
+	var names []string
+	for name := range gathererErrors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		output.AppendErrorf(errorMsgForFailedGatherer, name, gathererErrors[name])
+	}
+
+	if len(gatherers) > 0 && len(gathererErrors) == len(gatherers) {
+		output.SetExitCode(1)
+		output.AppendError(errorMsgForAllGatherersFailed)
+		return false
+	}
+	return true
+}
+
 // shouldRetryWithNonOptimizedData will return true if the Exception occurred is one of ItemContentMismatchException
 // or InvalidItemContentException and will retry sending data to SSM. It will return false, if any other error occurs.
 func shouldRetryWithNonOptimizedData(err error, log log.T) bool {
@@ -361,6 +461,23 @@ func (p *Plugin) validateCustomGatherer(context context.T, collectionPolicy, loc
 	return
 }
 
+// validateKernelGatherer enables the kernel gatherer if the kernel modules are enabled or kernel parameters are given
+func (p *Plugin) validateKernelGatherer(context context.T, collectionPolicy, parameters string) (status bool, gatherer gatherers.T, policy model.Config, err error) {
+
+	if collectionPolicy == model.Enabled || parameters != "" {
+		if status, gatherer, err = p.CanGathererRun(context, kernel.GathererName); err != nil {
+			return
+		}
+
+		// check if gatherer can run - if not then no need to set policy
+		if status {
+			policy = model.Config{Collection: collectionPolicy, Filters: parameters}
+		}
+	}
+
+	return
+}
+
 // ValidateInventoryInput validates inventory input and returns a map of eligible gatherers & their corresponding config.
 // It throws an error if gatherer is not recognized/installed.
 func (p *Plugin) ValidateInventoryInput(context context.T, input PluginInput) (configuredGatherers map[gatherers.T]model.Config, err error) {
@@ -376,18 +493,27 @@ func (p *Plugin) ValidateInventoryInput(context context.T, input PluginInput) (c
 
 	predefinedGatherers := map[string]string{
 		application.GathererName:                 input.Applications,
+		languagepackage.GathererName:             input.LanguagePackages,
+		localuser.GathererName:                   input.LocalUsers,
 		awscomponent.GathererName:                input.AWSComponents,
 		role.GathererName:                        input.WindowsRoles,
 		service.GathererName:                     input.Services,
+		systemd.GathererName:                     input.SystemdServices,
 		network.GathererName:                     input.NetworkConfig,
+		listeningport.GathererName:               input.ListeningPorts,
+		gpu.GathererName:                         input.GPUs,
+		nvmehealth.GathererName:                  input.NVMeHealth,
 		billinginfo.GathererName:                 input.BillingInfo,
+		container.GathererName:                   input.Containers,
 		windowsUpdate.GathererName:               input.WindowsUpdates,
 		instancedetailedinformation.GathererName: input.InstanceDetailedInformation,
+		registrykey.GathererName:                 input.WindowsRegistryKeys,
 	}
 
 	predefinedGatherersWithFilters := map[string]string{
-		file.GathererName:     input.Files,
-		registry.GathererName: input.WindowsRegistry,
+		file.GathererName:        input.Files,
+		registry.GathererName:    input.WindowsRegistry,
+		certificate.GathererName: input.Certificates,
 	}
 
 	//NOTE:
@@ -412,6 +538,14 @@ func (p *Plugin) ValidateInventoryInput(context context.T, input PluginInput) (c
 		}
 	}
 
+	//checking kernel gatherer
+	if canGathererRun, gatherer, cfg, err = p.validateKernelGatherer(context, input.KernelModules, input.KernelParameters); err != nil {
+		log.Errorf("Error while validating gatherer %v", err.Error())
+		return
+	} else if canGathererRun {
+		configuredGatherers[gatherer] = cfg
+	}
+
 	//checking custom gatherer
 	if canGathererRun, gatherer, cfg, err = p.validateCustomGatherer(context, input.CustomInventory, input.CustomInventoryDirectory); err != nil {
 		log.Errorf("Error while validating gatherer %v", err.Error())
@@ -423,9 +557,10 @@ func (p *Plugin) ValidateInventoryInput(context context.T, input PluginInput) (c
 	return
 }
 
-// RunGatherers execute given array of gatherers and accordingly returns. It returns error if gatherer is not
-// registered or if at any stage the data returned breaches size limit
-func (p *Plugin) RunGatherers(gatherers map[gatherers.T]model.Config) (items []model.Item, err error) {
+// RunGatherers execute given array of gatherers and accordingly returns. A gatherer which fails or does not finish
+// within its timeout does not stop the other gatherers, its error is returned by gatherer name in gathererErrors.
+// It returns error if at any stage the data returned breaches size limit
+func (p *Plugin) RunGatherers(gatherers map[gatherers.T]model.Config) (items []model.Item, gathererErrors map[string]error, err error) {
 
 	//NOTE: Currently all gatherers will be invoked in synchronous & sequential fashion.
 	//Parallel execution of gatherers hinges upon inventory plugin becoming a long running plugin - which will be
@@ -435,30 +570,36 @@ func (p *Plugin) RunGatherers(gatherers map[gatherers.T]model.Config) (items []m
 
 	log := p.context.Log()
 
+	// gatherers run at the background priority configured for inventory collection
+	throttle.Configure(p.context.AppConfig().Ssm)
+
+	gathererErrors = make(map[string]error)
+
 	for gatherer, config := range gatherers {
 		name := gatherer.Name()
 		log.Infof("Invoking gatherer - %v", name)
 		start := time.Now()
 
-		if gItems, err = gatherer.Run(p.context, config); err != nil {
-			err = fmt.Errorf("Encountered error while executing %v. Error - %v", name, err.Error())
-			break
+		var gathererErr error
+		if gItems, gathererErr = p.runGatherer(gatherer, config, p.gathererTimeout(name)); gathererErr != nil {
+			log.Errorf("Encountered error while executing %v. Error - %v", name, gathererErr.Error())
+			gathererErrors[name] = gathererErr
+			continue
+		}
 
-		} else {
-			elapsed := time.Since(start)
-			log.Infof("execution time for gatherer - %v: %s", name, elapsed)
-
-			items = append(items, gItems...)
-
-			//TODO: Each gatherer shall check each item's size and stop collecting if size exceed immediately
-			//TODO: only check the total item size at this function, whenever total size exceed, stop
-			//TODO: immediately and raise association error
-			//return error if collected data breaches size limit
-			for _, v := range gItems {
-				if !p.VerifyInventoryDataSize(v, items) {
-					err = log.Errorf("the size of the collected data exceeded the maximum allowable size")
-					break
-				}
+		elapsed := time.Since(start)
+		log.Infof("execution time for gatherer - %v: %s", name, elapsed)
+
+		items = append(items, gItems...)
+
+		//TODO: Each gatherer shall check each item's size and stop collecting if size exceed immediately
+		//TODO: only check the total item size at this function, whenever total size exceed, stop
+		//TODO: immediately and raise association error
+		//return error if collected data breaches size limit
+		for _, v := range gItems {
+			if !p.VerifyInventoryDataSize(v, items) {
+				err = log.Errorf("the size of the collected data exceeded the maximum allowable size")
+				return
 			}
 		}
 	}
@@ -466,6 +607,44 @@ func (p *Plugin) RunGatherers(gatherers map[gatherers.T]model.Config) (items []m
 	return
 }
 
+// runGatherer runs the gatherer and returns an error when it panics or does not finish within the timeout,
+// a gatherer which times out is asked to stop and the data it collects afterwards is discarded
+func (p *Plugin) runGatherer(gatherer gatherers.T, config model.Config, timeout time.Duration) ([]model.Item, error) {
+	done := make(chan gathererResult, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- gathererResult{err: fmt.Errorf("gatherer panicked - %v", r)}
+			}
+		}()
+		items, err := gatherer.Run(p.context, config)
+		done <- gathererResult{items: items, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.items, result.err
+	case <-time.After(timeout):
+		if err := gatherer.RequestStop(contracts.StopTypeSoftStop); err != nil {
+			p.context.Log().Debugf("Unable to stop gatherer %v - %v", gatherer.Name(), err)
+		}
+		return nil, fmt.Errorf("gatherer did not finish within %v", timeout)
+	}
+}
+
+// gathererTimeout returns how long the gatherer may run, timeouts configured for the gatherer override the default
+func (p *Plugin) gathererTimeout(name string) time.Duration {
+	ssmConfig := p.context.AppConfig().Ssm
+	timeoutSeconds := ssmConfig.InventoryGathererTimeoutSeconds
+	if override, found := ssmConfig.InventoryGathererTimeouts[name]; found {
+		timeoutSeconds = override
+	}
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = appconfig.DefaultInventoryGathererTimeoutSeconds
+	}
+	return time.Duration(timeoutSeconds) * time.Second
+}
+
 // VerifyInventoryDataSize returns true if size of collected inventory data is within size restrictions placed by SSM,
 // else false.
 func (p *Plugin) VerifyInventoryDataSize(item model.Item, items []model.Item) bool {
@@ -18,14 +18,18 @@ import (
 	"bytes"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/gatherers"
 	"github.com/aws/amazon-ssm-agent/agent/plugins/inventory/model"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 // MockInventoryPlugin returns mock inventory plugin
@@ -102,6 +106,7 @@ func TestRunGatherers(t *testing.T) {
 	var err error
 	var sGatherers, iGatherers []string
 	var items []model.Item
+	var gathererErrors map[string]error
 	errorFreeGathererName := "ErrorFree-1"
 	errorProneGathererName := "ErrorProne-1"
 
@@ -136,9 +141,10 @@ func TestRunGatherers(t *testing.T) {
 	//set expectations for errorFree gatherer.
 	errorFreeGatherer.On("Name").Return(errorFreeGathererName)
 	errorFreeGatherer.On("Run", p.context, config).Return(data, nil)
-	items, err = p.RunGatherers(testGathererConfig)
+	items, gathererErrors, err = p.RunGatherers(testGathererConfig)
 
 	assert.Nil(t, err, "%v shouldn't throw errors", errorFreeGatherer)
+	assert.Empty(t, gathererErrors)
 	assert.NotEqual(t, 0, len(items), "%v is expected to return at least few inventory items", errorFreeGatherer)
 
 	//testing running multiple gatherers out of which one throws an error
@@ -150,9 +156,82 @@ func TestRunGatherers(t *testing.T) {
 	errorProneGatherer.On("Name").Return(errorProneGathererName)
 	e := fmt.Errorf("Fake error executing %v", errorProneGatherer)
 	errorProneGatherer.On("Run", p.context, config).Return(data, e)
-	items, err = p.RunGatherers(testGathererConfig)
+	items, gathererErrors, err = p.RunGatherers(testGathererConfig)
 
-	assert.NotNil(t, err, "%v should throw errors", errorProneGatherer)
+	//the inventory of the error free gatherer is still returned
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]error{errorProneGathererName: e}, gathererErrors)
+	assert.Equal(t, data, items)
+}
+
+func TestRunGatherersTimeout(t *testing.T) {
+	slowGathererName := "Slow-1"
+	config := appconfig.SsmagentConfig{}
+	config.Ssm.InventoryGathererTimeouts = map[string]int{slowGathererName: 1}
+	ctx := new(context.Mock)
+	ctx.On("Log").Return(log.NewMockLog())
+	ctx.On("AppConfig").Return(config)
+
+	p, _ := MockInventoryPlugin(nil, nil)
+	p.context = ctx
+	gathererConfig := model.Config{Collection: "Enabled"}
+	data := MockInventoryItems()
+
+	slowGatherer := gatherers.NewMockDefault()
+	slowGatherer.On("Name").Return(slowGathererName)
+	slowGatherer.On("Run", p.context, gathererConfig).After(time.Minute).Return(data, nil)
+	slowGatherer.On("RequestStop", contracts.StopTypeSoftStop).Return(nil)
+	panickingGatherer := gatherers.NewMockDefault()
+	panickingGatherer.On("Name").Return("Panicking-1")
+	panickingGatherer.On("Run", p.context, gathererConfig).Run(func(mock.Arguments) { panic("unexpected") })
+	errorFreeGatherer := gatherers.NewMockDefault()
+	errorFreeGatherer.On("Name").Return("ErrorFree-1")
+	errorFreeGatherer.On("Run", p.context, gathererConfig).Return(data, nil)
+
+	items, gathererErrors, err := p.RunGatherers(map[gatherers.T]model.Config{
+		slowGatherer:      gathererConfig,
+		panickingGatherer: gathererConfig,
+		errorFreeGatherer: gathererConfig,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, data, items)
+	assert.Equal(t, 2, len(gathererErrors))
+	assert.Contains(t, gathererErrors[slowGathererName].Error(), "did not finish within 1s")
+	assert.Contains(t, gathererErrors["Panicking-1"].Error(), "unexpected")
+	slowGatherer.AssertCalled(t, "RequestStop", contracts.StopTypeSoftStop)
+}
+
+func TestGathererTimeout(t *testing.T) {
+	config := appconfig.SsmagentConfig{}
+	config.Ssm.InventoryGathererTimeoutSeconds = 600
+	config.Ssm.InventoryGathererTimeouts = map[string]int{"AWS:File": 3600}
+	ctx := new(context.Mock)
+	ctx.On("AppConfig").Return(config)
+	p := &Plugin{context: ctx}
+
+	assert.Equal(t, time.Hour, p.gathererTimeout("AWS:File"))
+	assert.Equal(t, 10*time.Minute, p.gathererTimeout("AWS:Application"))
+
+	p.context = context.NewMockDefault()
+	assert.Equal(t, appconfig.DefaultInventoryGathererTimeoutSeconds*time.Second, p.gathererTimeout("AWS:Application"))
+}
+
+func TestReportGathererErrors(t *testing.T) {
+	failing := gatherers.NewMockDefault()
+	succeeding := gatherers.NewMockDefault()
+	configured := map[gatherers.T]model.Config{failing: {}, succeeding: {}}
+	gathererErrors := map[string]error{"AWS:File": fmt.Errorf("gatherer did not finish within 30m0s")}
+
+	output := iohandler.NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{})
+	assert.True(t, reportGathererErrors(output, configured, gathererErrors))
+	assert.Equal(t, 0, output.GetExitCode())
+	assert.Equal(t, fmt.Sprintf(errorMsgForFailedGatherer, "AWS:File", gathererErrors["AWS:File"]), output.GetStderr())
+
+	output = iohandler.NewDefaultIOHandler(log.NewMockLog(), contracts.IOConfiguration{})
+	assert.False(t, reportGathererErrors(output, map[gatherers.T]model.Config{failing: {}}, gathererErrors))
+	assert.Equal(t, 1, output.GetExitCode())
+	assert.Contains(t, output.GetStderr(), errorMsgForAllGatherersFailed)
 }
 
 func TestVerifyInventoryDataSize(t *testing.T) {
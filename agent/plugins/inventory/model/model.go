@@ -130,6 +130,110 @@ type ServiceData struct {
 	StartType          string
 }
 
+// ContainerData captures all attributes present in AWS:Container inventory type, the entries are either
+// containers or local images of a container runtime
+type ContainerData struct {
+	ResourceType string
+	Runtime      string
+	Id           string
+	Name         string
+	Image        string
+	ImageId      string
+	ImageDigest  string
+	Status       string
+	Ports        string
+	CreatedTime  string
+}
+
+// SystemdServiceData captures all attributes present in AWS:SystemdService inventory type
+type SystemdServiceData struct {
+	Name          string
+	Description   string
+	UnitFileState string
+	UnitFilePath  string
+	LoadState     string
+	ActiveState   string
+	SubState      string
+}
+
+// CertificateData captures all attributes present in AWS:Certificate inventory type
+type CertificateData struct {
+	Subject      string
+	Issuer       string
+	SerialNumber string
+	Thumbprint   string
+	DnsNames     string
+	NotBefore    string
+	NotAfter     string
+	Path         string
+}
+
+// KernelData captures all attributes present in AWS:Kernel inventory type, the entries are either
+// loaded kernel modules or kernel parameters
+type KernelData struct {
+	ResourceType   string
+	Name           string
+	Version        string
+	SignatureState string
+	Taint          string
+	Value          string
+}
+
+// LanguagePackageData captures all attributes present in AWS:LanguagePackage inventory type
+type LanguagePackageData struct {
+	Name           string
+	Version        string
+	PackageManager string
+}
+
+// LocalUserData captures all attributes present in AWS:LocalUser inventory type
+type LocalUserData struct {
+	Name                 string
+	Id                   string
+	Groups               string
+	Shell                string
+	HomeDirectory        string
+	LastLogin            string
+	Disabled             string
+	PasswordExpired      string
+	PasswordNeverExpires string
+}
+
+// ListeningPortData captures all attributes present in Custom:ListeningPort inventory type, custom inventory
+// types only support string attributes
+type ListeningPortData struct {
+	Protocol     string
+	LocalAddress string
+	LocalPort    string
+	ProcessId    string
+	ProcessName  string
+	ProcessPath  string
+}
+
+// GPUData captures all attributes present in Custom:GPU inventory type, the video memory is reported in MiB
+type GPUData struct {
+	Name          string
+	Vendor        string
+	DriverVersion string
+	MemoryMiB     string
+	BusId         string
+}
+
+// NVMeHealthData captures all attributes present in Custom:NVMeHealth inventory type, the attributes are reported
+// from the SMART / health information log of the drive, temperatures are in Celsius
+type NVMeHealthData struct {
+	Device             string
+	Model              string
+	SerialNumber       string
+	FirmwareVersion    string
+	PercentageUsed     string
+	AvailableSpare     string
+	TemperatureCelsius string
+	MediaErrors        string
+	PowerOnHours       string
+	CriticalWarning    string
+}
+
 type RegistryData struct {
 	ValueName string
 	ValueType string
@@ -0,0 +1,92 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package throttle runs inventory collection at background priority, it lowers the CPU and I/O priority of the
+// commands run by gatherers and limits the rate at which gatherers read the file system.
+package throttle
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// settings are the priority and rate limit applied to inventory collection
+type settings struct {
+	niceness    int
+	ioniceClass int
+}
+
+// limiter spaces file system operations evenly, an interval of zero does not limit them
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+var (
+	current     settings
+	settingsMu  sync.RWMutex
+	fileLimiter = &limiter{}
+
+	// decoupling for easy testability
+	now   = time.Now
+	sleep = time.Sleep
+)
+
+// Configure sets the priority and file operation rate of inventory collection from the agent configuration
+func Configure(config appconfig.SsmCfg) {
+	settingsMu.Lock()
+	current = settings{niceness: config.InventoryNiceness, ioniceClass: config.InventoryIoniceClass}
+	settingsMu.Unlock()
+
+	var interval time.Duration
+	if config.InventoryFileOperationsPerSecond > 0 {
+		interval = time.Second / time.Duration(config.InventoryFileOperationsPerSecond)
+	}
+	fileLimiter.setInterval(interval)
+}
+
+// getSettings returns the configured priority
+func getSettings() settings {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return current
+}
+
+// WaitFileOperation blocks until the configured rate allows gatherers to read another file or directory
+func WaitFileOperation() {
+	fileLimiter.wait()
+}
+
+func (l *limiter) setInterval(interval time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.interval = interval
+	l.next = time.Time{}
+}
+
+func (l *limiter) wait() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.interval == 0 {
+		return
+	}
+	t := now()
+	if l.next.After(t) {
+		sleep(l.next.Sub(t))
+		t = l.next
+	}
+	l.next = t.Add(l.interval)
+}
@@ -0,0 +1,76 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package throttle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockClock returns a clock advanced by sleep and the recorded sleeps
+func mockClock() (sleeps *[]time.Duration, restore func()) {
+	sleeps = &[]time.Duration{}
+	clock := time.Date(2019, 7, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	sleep = func(d time.Duration) {
+		*sleeps = append(*sleeps, d)
+		clock = clock.Add(d)
+	}
+	return sleeps, func() {
+		now = time.Now
+		sleep = time.Sleep
+	}
+}
+
+func TestWaitFileOperation(t *testing.T) {
+	sleeps, restore := mockClock()
+	defer restore()
+	Configure(appconfig.SsmCfg{InventoryFileOperationsPerSecond: 100})
+	defer Configure(appconfig.SsmCfg{})
+
+	for i := 0; i < 3; i++ {
+		WaitFileOperation()
+	}
+
+	assert.Equal(t, []time.Duration{10 * time.Millisecond, 10 * time.Millisecond}, *sleeps)
+}
+
+func TestWaitFileOperationAfterIdle(t *testing.T) {
+	sleeps, restore := mockClock()
+	defer restore()
+	Configure(appconfig.SsmCfg{InventoryFileOperationsPerSecond: 10})
+	defer Configure(appconfig.SsmCfg{})
+
+	WaitFileOperation()
+	sleep(time.Second)
+	WaitFileOperation()
+
+	// the operation after the idle second does not wait
+	assert.Equal(t, []time.Duration{time.Second}, *sleeps)
+}
+
+func TestWaitFileOperationUnlimited(t *testing.T) {
+	sleeps, restore := mockClock()
+	defer restore()
+	Configure(appconfig.SsmCfg{})
+
+	for i := 0; i < 3; i++ {
+		WaitFileOperation()
+	}
+
+	assert.Empty(t, *sleeps)
+}
@@ -0,0 +1,57 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package throttle
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+const (
+	niceCmd   = "nice"
+	ioniceCmd = "ionice"
+
+	// ioniceBestEffortClass is run at the lowest level of the class
+	ioniceBestEffortClass = 2
+	ioniceLowestLevel     = 7
+)
+
+// decoupling exec.LookPath for easy testability
+var lookPath = exec.LookPath
+
+// Command returns the command run through ionice and nice at the configured priority,
+// the priority is not lowered when those tools are not installed
+func Command(name string, arg ...string) *exec.Cmd {
+	s := getSettings()
+	var prefix []string
+	if s.ioniceClass > 0 {
+		if _, err := lookPath(ioniceCmd); err == nil {
+			prefix = append(prefix, ioniceCmd, "-c", strconv.Itoa(s.ioniceClass))
+			if s.ioniceClass == ioniceBestEffortClass {
+				prefix = append(prefix, "-n", strconv.Itoa(ioniceLowestLevel))
+			}
+		}
+	}
+	if s.niceness > 0 {
+		if _, err := lookPath(niceCmd); err == nil {
+			prefix = append(prefix, niceCmd, "-n", strconv.Itoa(s.niceness))
+		}
+	}
+	if len(prefix) == 0 {
+		return exec.Command(name, arg...)
+	}
+	return exec.Command(prefix[0], append(append(prefix[1:], name), arg...)...)
+}
@@ -0,0 +1,59 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build darwin freebsd linux netbsd openbsd
+
+package throttle
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockLookPath(installed ...string) func() {
+	lookPath = func(file string) (string, error) {
+		for _, name := range installed {
+			if name == file {
+				return "/usr/bin/" + file, nil
+			}
+		}
+		return "", errors.New("executable file not found in $PATH")
+	}
+	return func() { lookPath = exec.LookPath }
+}
+
+func TestCommand(t *testing.T) {
+	defer mockLookPath(niceCmd, ioniceCmd)()
+	defer Configure(appconfig.SsmCfg{})
+
+	Configure(appconfig.SsmCfg{InventoryNiceness: 10, InventoryIoniceClass: 2})
+	assert.Equal(t, []string{"ionice", "-c", "2", "-n", "7", "nice", "-n", "10", "rpm", "-qa"}, Command("rpm", "-qa").Args)
+
+	Configure(appconfig.SsmCfg{InventoryNiceness: 19, InventoryIoniceClass: 3})
+	assert.Equal(t, []string{"ionice", "-c", "3", "nice", "-n", "19", "rpm", "-qa"}, Command("rpm", "-qa").Args)
+
+	Configure(appconfig.SsmCfg{})
+	assert.Equal(t, []string{"rpm", "-qa"}, Command("rpm", "-qa").Args)
+}
+
+func TestCommandWithoutIonice(t *testing.T) {
+	defer mockLookPath(niceCmd)()
+	defer Configure(appconfig.SsmCfg{})
+
+	Configure(appconfig.SsmCfg{InventoryNiceness: 10, InventoryIoniceClass: 2})
+	assert.Equal(t, []string{"nice", "-n", "10", "dpkg-query", "-W"}, Command("dpkg-query", "-W").Args)
+}
@@ -0,0 +1,34 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package throttle
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// belowNormalPriorityClass is the BELOW_NORMAL_PRIORITY_CLASS process creation flag
+const belowNormalPriorityClass = 0x00004000
+
+// Command returns the command created below normal priority when a niceness is configured,
+// Windows has no per process I/O class so the ionice class is ignored
+func Command(name string, arg ...string) *exec.Cmd {
+	cmd := exec.Command(name, arg...)
+	if getSettings().niceness > 0 {
+		cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: belowNormalPriorityClass}
+	}
+	return cmd
+}
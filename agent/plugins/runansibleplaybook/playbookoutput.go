@@ -0,0 +1,120 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runansibleplaybook
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const (
+	taskPrefix   = "TASK ["
+	recapPrefix  = "PLAY RECAP"
+	resultMarker = "=> "
+
+	// maxFailureMessageLength truncates the messages of failed tasks which may hold whole command outputs
+	maxFailureMessageLength = 500
+)
+
+// failurePrefixes start the lines of the default callback reporting failed tasks and unreachable hosts
+var failurePrefixes = []string{"fatal: [", "failed: [", "unreachable: ["}
+
+// taskFailure is a task which failed on a host
+type taskFailure struct {
+	Task    string
+	Host    string
+	Message string
+}
+
+// playbookOutputParser is a writer following the output of the default callback of ansible-playbook.
+// It records the task running when each failure is reported.
+type playbookOutputParser struct {
+	partial  string
+	task     string
+	failures []taskFailure
+}
+
+// Write parses the complete lines of the output, the last partial line is kept until it is completed
+func (p *playbookOutputParser) Write(b []byte) (int, error) {
+	p.partial += string(b)
+	for {
+		index := strings.IndexByte(p.partial, '\n')
+		if index < 0 {
+			break
+		}
+		p.parseLine(strings.TrimRight(p.partial[:index], "\r"))
+		p.partial = p.partial[index+1:]
+	}
+	return len(b), nil
+}
+
+// Flush parses the last line of the output if it was not terminated
+func (p *playbookOutputParser) Flush() {
+	if p.partial != "" {
+		p.parseLine(p.partial)
+		p.partial = ""
+	}
+}
+
+// Failures returns the failed tasks in the order they were reported
+func (p *playbookOutputParser) Failures() []taskFailure {
+	return p.failures
+}
+
+func (p *playbookOutputParser) parseLine(line string) {
+	if strings.HasPrefix(line, taskPrefix) {
+		if end := strings.LastIndex(line, "]"); end > len(taskPrefix) {
+			p.task = line[len(taskPrefix):end]
+		}
+		return
+	}
+	if strings.HasPrefix(line, recapPrefix) {
+		p.task = ""
+		return
+	}
+	for _, prefix := range failurePrefixes {
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		end := strings.Index(line, "]")
+		if end < len(prefix) {
+			return
+		}
+		p.failures = append(p.failures, taskFailure{
+			Task:    p.task,
+			Host:    line[len(prefix):end],
+			Message: failureMessage(line[end+1:]),
+		})
+		return
+	}
+}
+
+// failureMessage returns the msg of the result printed after the failure, or the result itself if it has none
+func failureMessage(result string) string {
+	message := result
+	if index := strings.Index(result, resultMarker); index >= 0 {
+		message = strings.TrimSpace(result[index+len(resultMarker):])
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(message), &fields); err == nil {
+			if msg, ok := fields["msg"].(string); ok {
+				message = msg
+			}
+		}
+	}
+	message = strings.TrimSpace(message)
+	if len(message) > maxFailureMessageLength {
+		message = message[:maxFailureMessageLength] + "..."
+	}
+	return message
+}
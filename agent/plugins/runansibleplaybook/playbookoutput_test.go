@@ -0,0 +1,72 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runansibleplaybook
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const playbookOutput = `
+PLAY [all] *********************************************************************
+
+TASK [Gathering Facts] *********************************************************
+ok: [localhost]
+
+TASK [Install nginx] ***********************************************************
+fatal: [localhost]: FAILED! => {"changed": false, "msg": "No package matching 'nginx' found available, installed or updated", "rc": 126}
+
+TASK [Create users] ************************************************************
+failed: [localhost] (item=deploy) => {"ansible_loop_var": "item", "changed": false, "item": "deploy", "msg": "useradd: group 'ops' does not exist"}
+fatal: [web-1]: UNREACHABLE! => {"changed": false, "msg": "Failed to connect to the host via ssh", "unreachable": true}
+
+PLAY RECAP *********************************************************************
+localhost                  : ok=1    changed=0    unreachable=0    failed=1    skipped=0    rescued=0    ignored=0
+`
+
+func TestPlaybookOutputParser(t *testing.T) {
+	parser := &playbookOutputParser{}
+	// the output is written in chunks which split lines
+	for _, chunk := range []string{playbookOutput[:200], playbookOutput[200:450], playbookOutput[450:]} {
+		n, err := parser.Write([]byte(chunk))
+		assert.NoError(t, err)
+		assert.Equal(t, len(chunk), n)
+	}
+	parser.Flush()
+
+	assert.Equal(t, []taskFailure{
+		{Task: "Install nginx", Host: "localhost", Message: "No package matching 'nginx' found available, installed or updated"},
+		{Task: "Create users", Host: "localhost", Message: "useradd: group 'ops' does not exist"},
+		{Task: "Create users", Host: "web-1", Message: "Failed to connect to the host via ssh"},
+	}, parser.Failures())
+}
+
+func TestPlaybookOutputParserWithoutFailures(t *testing.T) {
+	parser := &playbookOutputParser{}
+	parser.Write([]byte("TASK [Gathering Facts] ***\nok: [localhost]\n\nPLAY RECAP ***\nlocalhost : ok=1 failed=0"))
+	parser.Flush()
+
+	assert.Empty(t, parser.Failures())
+}
+
+func TestFailureMessage(t *testing.T) {
+	assert.Equal(t, "boom", failureMessage(`: FAILED! => {"msg": "boom"}`))
+	assert.Equal(t, `{"rc": 1}`, failureMessage(`: FAILED! => {"rc": 1}`))
+	assert.Equal(t, "not json", failureMessage(`: FAILED! => not json`))
+
+	message := failureMessage(`: FAILED! => {"msg": "` + strings.Repeat("x", 2*maxFailureMessageLength) + `"}`)
+	assert.Equal(t, maxFailureMessageLength+len("..."), len(message))
+}
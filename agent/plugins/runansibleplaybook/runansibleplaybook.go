@@ -0,0 +1,307 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runansibleplaybook implements the aws:runAnsiblePlaybook plugin
+package runansibleplaybook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/downloadcontent/gitresource/privategithub"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	downloadsDir = "downloads" //Directory under the orchestration directory where the downloaded resource resides
+
+	playbookName      = "playbook.yml"
+	vaultPasswordName = "vault-password"
+	rolesDir          = "roles"
+	collectionsDir    = "collections"
+
+	ansiblePlaybookCommand = "ansible-playbook"
+	ansibleGalaxyCommand   = "ansible-galaxy"
+
+	// defaultRolesPath and defaultCollectionsPath are searched after the roles and collections of the requirements
+	defaultRolesPath       = "~/.ansible/roles:/usr/share/ansible/roles:/etc/ansible/roles"
+	defaultCollectionsPath = "~/.ansible/collections:/usr/share/ansible/collections"
+)
+
+// environment is set for every ansible command: plain output the failures are parsed from, and no retry files
+var environment = []string{"ANSIBLE_NOCOLOR=1", "ANSIBLE_RETRY_FILES_ENABLED=false"}
+
+// Assign method to global variables to allow unittest to override
+var lookPath = exec.LookPath
+
+// TokenAccess resolves the secure parameter holding the vault password
+type TokenAccess interface {
+	GetToken(log log.T, tokenInfo string) (token string, err error)
+}
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	plugin.CommandExecuter = executers.ShellCommandExecuter{}
+	plugin.tokens = privategithub.NewTokenInfoImpl()
+	return &plugin, nil
+}
+
+// Plugin is the type for the aws:runAnsiblePlaybook plugin.
+type Plugin struct {
+	// CommandExecuter is an object that can execute commands.
+	CommandExecuter executers.T
+	tokens          TokenAccess
+}
+
+// RunAnsiblePlaybookPluginInput represents the playbook run by the aws:runAnsiblePlaybook plugin
+type RunAnsiblePlaybookPluginInput struct {
+	contracts.PluginInput
+	ID string
+	// Playbook are the lines of an inline playbook
+	Playbook []string `json:"playbook"`
+	// PlaybookFile is the path of a playbook, absolute or relative to the directory aws:downloadContent downloads to
+	PlaybookFile string `json:"playbookFile"`
+	// Requirements is the path of a requirements.yml listing the roles and collections installed before the playbook runs
+	Requirements string `json:"requirements"`
+	// ExtraVariables are passed to the playbook as extra vars
+	ExtraVariables map[string]string `json:"extraVariables"`
+	// Check runs the playbook in check mode, without changing the instance
+	Check bool `json:"check"`
+	// VaultPassword is the secure string parameter holding the vault password, e.g. {{ ssm-secure:vault-password }}.
+	// Passwords in Secrets Manager are referenced as {{ ssm-secure:/aws/reference/secretsmanager/vault-password }}
+	VaultPassword    string      `json:"vaultPassword"`
+	WorkingDirectory string      `json:"workingDirectory"`
+	TimeoutSeconds   interface{} `json:"timeoutSeconds"`
+}
+
+// Name returns the plugin name
+func Name() string {
+	return appconfig.PluginNameAwsRunAnsiblePlaybook
+}
+
+// Execute runs the playbook against the instance and returns its output.
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", Name(), config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+	} else if input, err := parseAndValidateInput(config.Properties); err != nil {
+		output.MarkAsFailed(err)
+	} else {
+		p.runPlaybook(log, input, config, cancelFlag, output)
+	}
+}
+
+// parseAndValidateInput parses the plugin properties and checks that exactly one of playbook and playbookFile is set
+func parseAndValidateInput(rawPluginInput interface{}) (*RunAnsiblePlaybookPluginInput, error) {
+	var input RunAnsiblePlaybookPluginInput
+	if err := jsonutil.Remarshal(rawPluginInput, &input); err != nil {
+		return nil, fmt.Errorf("invalid format in plugin properties %v; \nerror %v", rawPluginInput, err)
+	}
+
+	if len(input.Playbook) == 0 && input.PlaybookFile == "" {
+		return nil, fmt.Errorf("invalid input: either playbook or playbookFile must be set")
+	}
+	if len(input.Playbook) > 0 && input.PlaybookFile != "" {
+		return nil, fmt.Errorf("invalid input: playbook and playbookFile cannot both be set")
+	}
+	return &input, nil
+}
+
+// runPlaybook installs the requirements of the playbook and runs it against the instance.
+func (p *Plugin) runPlaybook(log log.T, input *RunAnsiblePlaybookPluginInput, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	downloadsPath := filepath.Join(strings.TrimSuffix(config.OrchestrationDirectory, config.PluginID), downloadsDir)
+	workingDir := config.DefaultWorkingDirectory
+	if filepath.IsAbs(input.WorkingDirectory) {
+		workingDir = input.WorkingDirectory
+	} else if path := filepath.Join(downloadsPath, input.WorkingDirectory); fileutil.Exists(path) {
+		workingDir = path
+	}
+
+	orchestrationDir := fileutil.BuildPath(config.OrchestrationDirectory, input.ID)
+	if err := fileutil.MakeDirsWithExecuteAccess(orchestrationDir); err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to create orchestrationDir directory, %v", orchestrationDir))
+		return
+	}
+
+	playbookPath := input.PlaybookFile
+	if playbookPath == "" {
+		playbookPath = filepath.Join(orchestrationDir, playbookName)
+		if err := pluginutil.CreateScriptFile(log, playbookPath, input.Playbook, fileutil.ByteOrderMarkSkip); err != nil {
+			output.MarkAsFailed(fmt.Errorf("failed to create playbook file. %v", err))
+			return
+		}
+	} else if !filepath.IsAbs(playbookPath) {
+		playbookPath = filepath.Join(downloadsPath, playbookPath)
+	}
+	if !fileutil.Exists(playbookPath) {
+		output.MarkAsFailed(fmt.Errorf("playbook %v was not found", playbookPath))
+		return
+	}
+
+	ansiblePlaybook, err := lookPath(ansiblePlaybookCommand)
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("ansible is not installed on this instance, %v was not found", ansiblePlaybookCommand))
+		return
+	}
+
+	executionTimeout := pluginutil.ValidateExecutionTimeout(log, input.TimeoutSeconds)
+	commandEnvironment := environment
+	if input.Requirements != "" {
+		requirementsPath := input.Requirements
+		if !filepath.IsAbs(requirementsPath) {
+			requirementsPath = filepath.Join(downloadsPath, requirementsPath)
+		}
+		if commandEnvironment, err = p.installRequirements(log, requirementsPath, orchestrationDir, workingDir, executionTimeout, cancelFlag, output); err != nil {
+			p.setFailure(err, cancelFlag, output)
+			return
+		}
+	}
+
+	arguments := []string{playbookPath, "--inventory", "localhost,", "--connection", "local"}
+	if input.Check {
+		arguments = append(arguments, "--check")
+	}
+	if len(input.ExtraVariables) > 0 {
+		extraVariables, err := json.Marshal(input.ExtraVariables)
+		if err != nil {
+			output.MarkAsFailed(fmt.Errorf("invalid extraVariables: %v", err))
+			return
+		}
+		arguments = append(arguments, "--extra-vars", string(extraVariables))
+	}
+	if input.VaultPassword != "" {
+		vaultPasswordPath := filepath.Join(orchestrationDir, vaultPasswordName)
+		// NOTE: Do not log the vault password
+		if err = p.writeVaultPassword(log, input.VaultPassword, vaultPasswordPath); err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+		defer os.Remove(vaultPasswordPath)
+		arguments = append(arguments, "--vault-password-file", vaultPasswordPath)
+	}
+
+	log.Debugf("Running playbook %v in workingDirectory %v", playbookPath, workingDir)
+	parser := &playbookOutputParser{}
+	exitCode, err := p.execute(log, workingDir, io.MultiWriter(parser, output.GetStdoutWriter()), output.GetStderrWriter(), cancelFlag, executionTimeout, ansiblePlaybook, arguments, commandEnvironment)
+	parser.Flush()
+
+	output.SetExitCode(exitCode)
+	output.SetStatus(pluginutil.GetStatus(exitCode, cancelFlag))
+	for _, failure := range parser.Failures() {
+		output.AppendErrorf("Task %v failed on %v: %v", failure.Task, failure.Host, failure.Message)
+	}
+	if err != nil {
+		p.setFailure(fmt.Errorf("failed to run playbook: %v", err), cancelFlag, output)
+	}
+}
+
+// installRequirements installs the roles and collections of the requirements file into the orchestration directory
+// and returns the environment the playbook finds them with.
+func (p *Plugin) installRequirements(
+	log log.T,
+	requirementsPath string,
+	orchestrationDir string,
+	workingDir string,
+	executionTimeout int,
+	cancelFlag task.CancelFlag,
+	output iohandler.IOHandler) ([]string, error) {
+
+	if !fileutil.Exists(requirementsPath) {
+		return nil, fmt.Errorf("requirements %v were not found", requirementsPath)
+	}
+	ansibleGalaxy, err := lookPath(ansibleGalaxyCommand)
+	if err != nil {
+		return nil, fmt.Errorf("%v was not found, it is required to install requirements", ansibleGalaxyCommand)
+	}
+
+	rolesPath := filepath.Join(orchestrationDir, rolesDir)
+	collectionsPath := filepath.Join(orchestrationDir, collectionsDir)
+	log.Infof("Installing requirements %v", requirementsPath)
+	for _, arguments := range [][]string{
+		{"role", "install", "--role-file", requirementsPath, "--roles-path", rolesPath},
+		{"collection", "install", "--requirements-file", requirementsPath, "--collections-path", collectionsPath},
+	} {
+		if exitCode, err := p.execute(log, workingDir, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, ansibleGalaxy, arguments, environment); err != nil || exitCode != 0 {
+			return nil, fmt.Errorf("failed to install requirements: exit code %v, %v", exitCode, err)
+		}
+	}
+
+	return append([]string{
+		"ANSIBLE_ROLES_PATH=" + rolesPath + ":" + defaultRolesPath,
+		"ANSIBLE_COLLECTIONS_PATHS=" + collectionsPath + ":" + defaultCollectionsPath,
+	}, environment...), nil
+}
+
+// writeVaultPassword writes the vault password of the secure parameter to a file only the agent can read
+func (p *Plugin) writeVaultPassword(log log.T, vaultPassword string, path string) error {
+	password, err := p.tokens.GetToken(log, vaultPassword)
+	if err != nil {
+		return fmt.Errorf("failed to get the vault password: %v", err)
+	}
+	if err = ioutil.WriteFile(path, []byte(password), appconfig.ReadWriteAccess); err != nil {
+		return fmt.Errorf("failed to write the vault password: %v", err)
+	}
+	return nil
+}
+
+// execute runs the command with the environment
+func (p *Plugin) execute(
+	log log.T,
+	workingDir string,
+	stdoutWriter io.Writer,
+	stderrWriter io.Writer,
+	cancelFlag task.CancelFlag,
+	executionTimeout int,
+	commandName string,
+	commandArguments []string,
+	commandEnvironment []string) (int, error) {
+
+	environmentExecuter, ok := p.CommandExecuter.(executers.EnvironmentExecuter)
+	if !ok {
+		return 1, fmt.Errorf("%v cannot set environment variables", Name())
+	}
+	return environmentExecuter.NewExecuteWithEnvironment(log, workingDir, stdoutWriter, stderrWriter, cancelFlag, executionTimeout, commandName, commandArguments, commandEnvironment)
+}
+
+// setFailure marks the plugin as failed unless it was canceled, timed out or requested a reboot.
+func (p *Plugin) setFailure(err error, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	switch status := output.GetStatus(); {
+	case cancelFlag.ShutDown():
+		output.MarkAsShutdown()
+	case cancelFlag.Canceled():
+		output.MarkAsCancelled()
+	case status == contracts.ResultStatusTimedOut || status == contracts.ResultStatusSuccessAndReboot:
+	default:
+		output.MarkAsFailed(err)
+	}
+}
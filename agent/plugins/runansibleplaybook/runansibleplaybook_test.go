@@ -0,0 +1,180 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runansibleplaybook implements the aws:runAnsiblePlaybook plugin
+package runansibleplaybook
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	iohandlermocks "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
+	multiwritermock "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter/mock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var logger = log.NewMockLog()
+
+// tokenAccessStub returns the tokens of the references
+type tokenAccessStub map[string]string
+
+func (t tokenAccessStub) GetToken(log log.T, tokenInfo string) (string, error) {
+	if token, found := t[tokenInfo]; found {
+		return token, nil
+	}
+	return "", fmt.Errorf("parameter %v not found", tokenInfo)
+}
+
+func setAnsibleInstalledMock() {
+	lookPath = func(file string) (string, error) { return "/usr/bin/" + file, nil }
+}
+
+func TestParseAndValidateInput(t *testing.T) {
+	input, err := parseAndValidateInput(map[string]interface{}{
+		"playbookFile":   "site.yml",
+		"requirements":   "requirements.yml",
+		"extraVariables": map[string]interface{}{"env": "prod"},
+		"check":          true,
+		"vaultPassword":  "{{ ssm-secure:vault }}",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "site.yml", input.PlaybookFile)
+	assert.Equal(t, "requirements.yml", input.Requirements)
+	assert.Equal(t, map[string]string{"env": "prod"}, input.ExtraVariables)
+	assert.True(t, input.Check)
+	assert.Equal(t, "{{ ssm-secure:vault }}", input.VaultPassword)
+
+	_, err = parseAndValidateInput(map[string]interface{}{})
+	assert.Error(t, err)
+
+	_, err = parseAndValidateInput(map[string]interface{}{"playbook": []interface{}{"- hosts: all"}, "playbookFile": "site.yml"})
+	assert.Error(t, err)
+}
+
+func TestRunPlaybookWithRequirementsAndVault(t *testing.T) {
+	orchestrationDir, err := ioutil.TempDir("", "runansibleplaybook")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+	setAnsibleInstalledMock()
+	defer func() { lookPath = exec.LookPath }()
+	downloadsPath := filepath.Join(orchestrationDir, downloadsDir)
+	os.MkdirAll(downloadsPath, appconfig.ReadWriteExecuteAccess)
+	ioutil.WriteFile(filepath.Join(downloadsPath, "requirements.yml"), []byte("roles: []"), appconfig.ReadWriteAccess)
+
+	stepDir := filepath.Join(orchestrationDir, "site")
+	playbookPath := filepath.Join(stepDir, playbookName)
+	requirementsPath := filepath.Join(downloadsPath, "requirements.yml")
+	rolesPath := filepath.Join(stepDir, rolesDir)
+	collectionsPath := filepath.Join(stepDir, collectionsDir)
+	vaultPasswordPath := filepath.Join(stepDir, vaultPasswordName)
+	playbookEnvironment := append([]string{
+		"ANSIBLE_ROLES_PATH=" + rolesPath + ":" + defaultRolesPath,
+		"ANSIBLE_COLLECTIONS_PATHS=" + collectionsPath + ":" + defaultCollectionsPath,
+	}, environment...)
+
+	mockExecuter := new(executers.MockCommandExecuter)
+	mockIOHandler := new(iohandlermocks.MockIOHandler)
+	mockCancelFlag := new(task.MockCancelFlag)
+	stdout := new(multiwritermock.MockDocumentIOMultiWriter)
+	stderr := new(multiwritermock.MockDocumentIOMultiWriter)
+	stdout.On("WriteString", mock.Anything).Return(0, nil)
+
+	mockIOHandler.On("GetStdoutWriter").Return(stdout)
+	mockIOHandler.On("GetStderrWriter").Return(stderr)
+	mockExecuter.On("NewExecuteWithEnvironment", mock.Anything, downloadsPath, stdout, stderr, mockCancelFlag, mock.Anything, "/usr/bin/ansible-galaxy",
+		[]string{"role", "install", "--role-file", requirementsPath, "--roles-path", rolesPath}, environment).Return(0, nil).Once()
+	mockExecuter.On("NewExecuteWithEnvironment", mock.Anything, downloadsPath, stdout, stderr, mockCancelFlag, mock.Anything, "/usr/bin/ansible-galaxy",
+		[]string{"collection", "install", "--requirements-file", requirementsPath, "--collections-path", collectionsPath}, environment).Return(0, nil).Once()
+	mockExecuter.On("NewExecuteWithEnvironment", mock.Anything, downloadsPath, mock.Anything, stderr, mockCancelFlag, mock.Anything, "/usr/bin/ansible-playbook",
+		[]string{playbookPath, "--inventory", "localhost,", "--connection", "local", "--check", "--extra-vars", `{"env":"prod"}`, "--vault-password-file", vaultPasswordPath},
+		playbookEnvironment).Run(func(args mock.Arguments) {
+		password, err := ioutil.ReadFile(vaultPasswordPath)
+		assert.NoError(t, err)
+		assert.Equal(t, "s3cret", string(password))
+		io.WriteString(args.Get(2).(io.Writer), "TASK [Install nginx] ***\nfatal: [localhost]: FAILED! => {\"msg\": \"No package nginx\"}\n")
+	}).Return(2, fmt.Errorf("exit status 2")).Once()
+	mockCancelFlag.On("Canceled").Return(false)
+	mockCancelFlag.On("ShutDown").Return(false)
+	mockIOHandler.On("SetExitCode", 2).Return()
+	mockIOHandler.On("SetStatus", contracts.ResultStatusFailed).Return()
+	mockIOHandler.On("GetStatus").Return(contracts.ResultStatusFailed)
+	mockIOHandler.On("AppendErrorf", "Task %v failed on %v: %v", []interface{}{"Install nginx", "localhost", "No package nginx"}).Return()
+	mockIOHandler.On("MarkAsFailed", mock.Anything).Return()
+
+	p := &Plugin{CommandExecuter: mockExecuter, tokens: tokenAccessStub{"{{ ssm-secure:vault }}": "s3cret"}}
+	input := &RunAnsiblePlaybookPluginInput{
+		ID:             "site",
+		Playbook:       []string{"- hosts: all", "  tasks: []"},
+		Requirements:   "requirements.yml",
+		ExtraVariables: map[string]string{"env": "prod"},
+		Check:          true,
+		VaultPassword:  "{{ ssm-secure:vault }}",
+	}
+	p.runPlaybook(logger, input, contracts.Configuration{OrchestrationDirectory: orchestrationDir, DefaultWorkingDirectory: "/tmp"}, mockCancelFlag, mockIOHandler)
+
+	mockExecuter.AssertExpectations(t)
+	mockIOHandler.AssertExpectations(t)
+	assert.False(t, fileExists(vaultPasswordPath), "the vault password is removed after the run")
+}
+
+func TestRunPlaybookFailsWhenAnsibleIsMissing(t *testing.T) {
+	orchestrationDir, err := ioutil.TempDir("", "runansibleplaybook")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+	lookPath = func(file string) (string, error) { return "", fmt.Errorf("%v not found", file) }
+	defer func() { lookPath = exec.LookPath }()
+
+	mockIOHandler := new(iohandlermocks.MockIOHandler)
+	mockIOHandler.On("MarkAsFailed", mock.Anything).Return()
+
+	p := &Plugin{CommandExecuter: new(executers.MockCommandExecuter)}
+	input := &RunAnsiblePlaybookPluginInput{Playbook: []string{"- hosts: all"}}
+	p.runPlaybook(logger, input, contracts.Configuration{OrchestrationDirectory: orchestrationDir}, new(task.MockCancelFlag), mockIOHandler)
+
+	mockIOHandler.AssertExpectations(t)
+}
+
+func TestRunPlaybookFailsWhenVaultPasswordIsMissing(t *testing.T) {
+	orchestrationDir, err := ioutil.TempDir("", "runansibleplaybook")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+	setAnsibleInstalledMock()
+	defer func() { lookPath = exec.LookPath }()
+
+	mockExecuter := new(executers.MockCommandExecuter)
+	mockIOHandler := new(iohandlermocks.MockIOHandler)
+	mockIOHandler.On("MarkAsFailed", mock.Anything).Return()
+
+	p := &Plugin{CommandExecuter: mockExecuter, tokens: tokenAccessStub{}}
+	input := &RunAnsiblePlaybookPluginInput{Playbook: []string{"- hosts: all"}, VaultPassword: "{{ ssm-secure:missing }}"}
+	p.runPlaybook(logger, input, contracts.Configuration{OrchestrationDirectory: orchestrationDir}, new(task.MockCancelFlag), mockIOHandler)
+
+	mockIOHandler.AssertExpectations(t)
+	mockExecuter.AssertNotCalled(t, "NewExecuteWithEnvironment")
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
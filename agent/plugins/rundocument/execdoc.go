@@ -36,7 +36,7 @@ type ExecDocument interface {
 		s3Bucket string, s3KeyPrefix string, messageID string, documentID string, defaultWorkingDirectory string,
 		params map[string]interface{}) (pluginsInfo []contracts.PluginState, err error)
 	ExecuteDocument(config contracts.Configuration, context context.T, pluginInput []contracts.PluginState, documentID string,
-		documentCreatedDate string) (chan contracts.DocumentResult, error)
+		documentCreatedDate string, cancelFlag task.CancelFlag) (chan contracts.DocumentResult, error)
 }
 
 type ExecDocumentImpl struct {
@@ -70,9 +70,10 @@ func (exec ExecDocumentImpl) ParseDocument(log log.T, documentRaw []byte, orches
 	return
 }
 
-// ExecuteDocument is responsible to execute the sub-documents that are created or downloaded by the executeCommand plugin
+// ExecuteDocument is responsible to execute the sub-documents that are created or downloaded by the executeCommand plugin.
+// The sub-document is canceled when the cancelFlag of the parent document is.
 func (exec ExecDocumentImpl) ExecuteDocument(config contracts.Configuration, context context.T, pluginInput []contracts.PluginState, documentID string,
-	documentCreatedDate string) (resultChannels chan contracts.DocumentResult, err error) {
+	documentCreatedDate string, cancelFlag task.CancelFlag) (resultChannels chan contracts.DocumentResult, err error) {
 	log := context.Log()
 	log.Info("Running sub-document")
 
@@ -100,8 +101,15 @@ func (exec ExecDocumentImpl) ExecuteDocument(config contracts.Configuration, con
 	}
 	docStore := executer.NewDocumentFileStore(context, documentID, instanceID, appconfig.DefaultLocationOfCurrent,
 		&docState, docmanager.NewDocumentFileMgr(appconfig.DefaultDataStorePath, appconfig.DefaultDocumentRootDirName, appconfig.DefaultLocationOfState))
-	cancelFlag := task.NewChanneledCancelFlag()
-	resultChannels = exec.DocExecutor.Run(cancelFlag, &docStore)
+	subDocumentCancelFlag := task.NewChanneledCancelFlag()
+	go func() {
+		cancelState := cancelFlag.Wait()
+		if cancelFlag.Canceled() {
+			log.Infof("Canceling sub-document %v", documentID)
+			subDocumentCancelFlag.Set(cancelState)
+		}
+	}()
+	resultChannels = exec.DocExecutor.Run(subDocumentCancelFlag, &docStore)
 
 	return resultChannels, nil
 }
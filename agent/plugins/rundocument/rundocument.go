@@ -34,6 +34,7 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"strings"
@@ -43,9 +44,8 @@ import (
 )
 
 const (
-	executeCommandMaxDepth = 3 //Maximum depth of document execution
-	jsonExtension          = ".json"
-	yamlExtension          = ".yaml"
+	jsonExtension = ".json"
+	yamlExtension = ".yaml"
 
 	SSMDocumentType = "SSMDocument"
 	LocalPathType   = "LocalPath"
@@ -76,6 +76,10 @@ type RunDocumentPluginInput struct {
 	DocumentType       string      `json:"documentType"`
 	DocumentPath       string      `json:"documentPath"`
 	DocumentParameters interface{} `json:"documentParameters"`
+	// PassParameters passes all parameters of the parent document to the child document
+	PassParameters bool `json:"passParameters"`
+	// ParameterMapping maps parameters of the child document to the parameters of the parent document whose values they get
+	ParameterMapping map[string]string `json:"parameterMapping"`
 }
 
 // ExecutePluginDepth is the struct that is sent through to the sub-documents to maintain the depth of execution
@@ -106,12 +110,12 @@ func (p *Plugin) execute(context context.T, config contracts.Configuration, canc
 	} else if input, err := parseAndValidateInput(config.Properties); err != nil {
 		output.MarkAsFailed(err)
 	} else {
-		p.runDocument(context, input, config, output)
+		p.runDocument(context, input, config, cancelFlag, output)
 	}
 }
 
 // runCopyContent figures out the type of location, downloads the resource, saves it on disk and returns information required for it
-func (p *Plugin) runDocument(context context.T, input *RunDocumentPluginInput, config contracts.Configuration, output iohandler.IOHandler) {
+func (p *Plugin) runDocument(context context.T, input *RunDocumentPluginInput, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
 
 	log := context.Log()
 	//Run aws:runDocument plugin
@@ -121,6 +125,10 @@ func (p *Plugin) runDocument(context context.T, input *RunDocumentPluginInput, c
 	var err error
 	//Set the depth of execution to be 1 for the first level execution
 	execDepth := 1
+	maxDepth := context.AppConfig().Ssm.RunDocumentMaxDepth
+	if maxDepth <= 0 {
+		maxDepth = appconfig.DefaultRunDocumentMaxDepth
+	}
 	// Getting the current depth of execution and checking against maximum depth
 	if config.Settings != nil {
 		if settings, ok := config.Settings.(*ExecutePluginDepth); !ok {
@@ -129,9 +137,9 @@ func (p *Plugin) runDocument(context context.T, input *RunDocumentPluginInput, c
 			return
 		} else {
 			execDepth = settings.executeCommandDepth + 1
-			if execDepth > executeCommandMaxDepth {
+			if execDepth > maxDepth {
 				output.MarkAsFailed(fmt.Errorf("Maximum depth for document execution exceeded. "+
-					"Maximum depth permitted - %v and current depth - %v", executeCommandMaxDepth, execDepth))
+					"Maximum depth permitted - %v and current depth - %v", maxDepth, execDepth))
 				return
 			}
 		}
@@ -141,6 +149,7 @@ func (p *Plugin) runDocument(context context.T, input *RunDocumentPluginInput, c
 	if input.DocumentType == SSMDocumentType {
 		if documentPath, err = p.downloadDocumentFromSSM(log, config, input); err != nil {
 			output.MarkAsFailed(err)
+			return
 		}
 	} else {
 		if filepath.IsAbs(input.DocumentPath) {
@@ -151,7 +160,12 @@ func (p *Plugin) runDocument(context context.T, input *RunDocumentPluginInput, c
 			documentPath = filepath.Join(orchestrationDir, downloadsDir, input.DocumentPath)
 		}
 	}
-	if pluginsInfo, err = p.prepareDocumentForExecution(log, documentPath, config, input.DocumentParameters); err != nil {
+	inherited, err := inheritedParameters(config.DocumentParameters, input)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	if pluginsInfo, err = p.prepareDocumentForExecution(log, documentPath, config, input.DocumentParameters, inherited); err != nil {
 		output.MarkAsFailed(fmt.Errorf("There was an error while preparing documents - %v", err.Error()))
 		return
 	}
@@ -163,8 +177,9 @@ func (p *Plugin) runDocument(context context.T, input *RunDocumentPluginInput, c
 
 	var resultsChannel chan contracts.DocumentResult
 	var pluginOutput map[string]*contracts.PluginResult
-	if resultsChannel, err = p.execDoc.ExecuteDocument(config, context, pluginsInfo, config.BookKeepingFileName, times.ToIso8601UTC(time.Now())); err != nil {
+	if resultsChannel, err = p.execDoc.ExecuteDocument(config, context, pluginsInfo, config.BookKeepingFileName, times.ToIso8601UTC(time.Now()), cancelFlag); err != nil {
 		output.MarkAsFailed(fmt.Errorf("There was an error while running documents - %v", err.Error()))
+		return
 	}
 	for res := range resultsChannel {
 		if res.LastPlugin == "" {
@@ -174,8 +189,17 @@ func (p *Plugin) runDocument(context context.T, input *RunDocumentPluginInput, c
 	}
 	if pluginOutput == nil {
 		output.MarkAsFailed(errors.New("No output obtained from executing document"))
+		return
 	}
-	for _, pluginOut := range pluginOutput {
+	aggregateResults(pluginsInfo, pluginOutput, output)
+}
+
+// aggregateResults adds the outputs of the steps of the child document to the output in the order of the steps,
+// merges their statuses and reports the exit code of the first failed step
+func aggregateResults(pluginsInfo []contracts.PluginState, pluginOutput map[string]*contracts.PluginResult, output iohandler.IOHandler) {
+	var summary []string
+	failedExitCode := 0
+	for _, pluginOut := range orderResults(pluginsInfo, pluginOutput) {
 		if pluginOut.StandardOutput != "" {
 			// separating the append so that the output is on a new line
 			output.AppendInfof("%v", pluginOut.StandardOutput)
@@ -188,9 +212,59 @@ func (p *Plugin) runDocument(context context.T, input *RunDocumentPluginInput, c
 			output.MarkAsFailed(errors.New(pluginOut.Error))
 		}
 		output.SetStatus(contracts.MergeResultStatus(output.GetStatus(), pluginOut.Status))
+		if pluginOut.Status == contracts.ResultStatusFailed && failedExitCode == 0 {
+			failedExitCode = pluginOut.Code
+		}
+		summary = append(summary, fmt.Sprintf("%v: %v", pluginOut.PluginID, pluginOut.Status))
+	}
+	output.AppendInfof("Steps of the child document - %v", strings.Join(summary, ", "))
+	if output.GetStatus() == contracts.ResultStatusFailed && failedExitCode != 0 {
+		output.SetExitCode(failedExitCode)
 	}
 }
 
+// orderResults returns the results of the steps in the order of the steps, followed by any other results sorted by step id
+func orderResults(pluginsInfo []contracts.PluginState, pluginOutput map[string]*contracts.PluginResult) []*contracts.PluginResult {
+	results := make([]*contracts.PluginResult, 0, len(pluginOutput))
+	ordered := make(map[string]bool)
+	for _, pluginInfo := range pluginsInfo {
+		if result, found := pluginOutput[pluginInfo.Id]; found && !ordered[pluginInfo.Id] {
+			results = append(results, result)
+			ordered[pluginInfo.Id] = true
+		}
+	}
+	var remaining []string
+	for id := range pluginOutput {
+		if !ordered[id] {
+			remaining = append(remaining, id)
+		}
+	}
+	sort.Strings(remaining)
+	for _, id := range remaining {
+		results = append(results, pluginOutput[id])
+	}
+	return results
+}
+
+// inheritedParameters returns the parameters of the parent document the child document gets: all of them with
+// passParameters, and the ones the parameterMapping maps to parameters of the child document
+func inheritedParameters(parentParameters map[string]interface{}, input *RunDocumentPluginInput) (map[string]interface{}, error) {
+	inherited := make(map[string]interface{})
+	if input.PassParameters {
+		for name, value := range parentParameters {
+			inherited[name] = value
+		}
+	}
+	for childName, parentName := range input.ParameterMapping {
+		value, found := parentParameters[parentName]
+		if !found {
+			return nil, fmt.Errorf("parameterMapping maps %v to %v, which is not a parameter of the document", childName, parentName)
+		}
+		inherited[childName] = value
+	}
+	return inherited, nil
+}
+
 func (p *Plugin) downloadDocumentFromSSM(log log.T, config contracts.Configuration, input *RunDocumentPluginInput) (string, error) {
 	var err error
 	// Downloads folder for download path
@@ -221,8 +295,12 @@ func (p *Plugin) downloadDocumentFromSSM(log log.T, config contracts.Configurati
 }
 
 // PrepareDocumentForExecution parses the raw content of the document, validates it and returns a PluginState that can be executed.
-func (p *Plugin) prepareDocumentForExecution(log log.T, pathToFile string, config contracts.Configuration, params interface{}) (pluginsInfo []contracts.PluginState, err error) {
+// The params override the parameters inherited from the parent document.
+func (p *Plugin) prepareDocumentForExecution(log log.T, pathToFile string, config contracts.Configuration, params interface{}, inherited map[string]interface{}) (pluginsInfo []contracts.PluginState, err error) {
 	parameters := make(map[string]interface{})
+	for k, v := range inherited {
+		parameters[k] = v
+	}
 	if params != nil {
 		switch params := params.(type) {
 		case string:
@@ -26,9 +26,11 @@ import (
 
 	"io/ioutil"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	filemock "github.com/aws/amazon-ssm-agent/agent/fileutil/filemanager/mock"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	iohandlermocks "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
 	executermocks "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/mock"
 	"github.com/aws/amazon-ssm-agent/agent/log"
@@ -96,7 +98,7 @@ func TestExecDocumentImpl_ExecuteDocumentFailure(t *testing.T) {
 		DocExecutor: execMock,
 	}
 	conf := createStubConfiguration("orch", "bucket", "prefix", "1234-1234-1234", "directory")
-	_, err := exec.ExecuteDocument(conf, contextMock, pluginInput, documentId, "time")
+	_, err := exec.ExecuteDocument(conf, contextMock, pluginInput, documentId, "time", task.NewChanneledCancelFlag())
 
 	assert.NoError(t, err)
 }
@@ -125,7 +127,7 @@ func TestExecDocumentImpl_ExecuteDocumentSuccess(t *testing.T) {
 		DocExecutor: execMock,
 	}
 	conf := createStubConfiguration("orch", "bucket", "prefix", "1234-1234-1234", "directory")
-	_, err := exec.ExecuteDocument(conf, contextMock, pluginInput, documentId, "time")
+	_, err := exec.ExecuteDocument(conf, contextMock, pluginInput, documentId, "time", task.NewChanneledCancelFlag())
 
 	assert.NoError(t, err)
 }
@@ -151,7 +153,7 @@ func TestExecDocumentImpl_ExecuteDocumentWithMultiplePlugin(t *testing.T) {
 	exec := ExecDocumentImpl{
 		DocExecutor: execMock,
 	}
-	_, err := exec.ExecuteDocument(conf, contextMock, pluginInput, documentId, "time")
+	_, err := exec.ExecuteDocument(conf, contextMock, pluginInput, documentId, "time", task.NewChanneledCancelFlag())
 
 	assert.NoError(t, err)
 }
@@ -178,7 +180,7 @@ func TestExecutePlugin_PrepareDocumentForExecution(t *testing.T) {
 		execDoc: execMock,
 	}
 
-	_, err := p.prepareDocumentForExecution(logMock, "document/name.json", conf, "")
+	_, err := p.prepareDocumentForExecution(logMock, "document/name.json", conf, "", nil)
 
 	assert.NoError(t, err)
 	fileMock.AssertExpectations(t)
@@ -199,7 +201,7 @@ func TestExecutePlugin_PrepareDocumentForExecutionFail(t *testing.T) {
 		execDoc: execMock,
 	}
 
-	_, err := p.prepareDocumentForExecution(logMock, "document/name.json", conf, "")
+	_, err := p.prepareDocumentForExecution(logMock, "document/name.json", conf, "", nil)
 
 	assert.Error(t, err)
 	assert.Equal(t, fmt.Errorf("File is empty!"), err)
@@ -231,7 +233,7 @@ param2: world`
 		execDoc: execMock,
 	}
 
-	_, err := p.prepareDocumentForExecution(logMock, "document/doc-name.json", conf, params)
+	_, err := p.prepareDocumentForExecution(logMock, "document/doc-name.json", conf, params, nil)
 
 	assert.NoError(t, err)
 	fileMock.AssertExpectations(t)
@@ -263,7 +265,7 @@ func TestExecuteImpl_PrepareDocumentForExecutionParametersJSON(t *testing.T) {
 		execDoc: execMock,
 	}
 
-	_, err := p.prepareDocumentForExecution(logMock, "document/doc-name.yaml", conf, params)
+	_, err := p.prepareDocumentForExecution(logMock, "document/doc-name.yaml", conf, params, nil)
 
 	assert.NoError(t, err)
 	fileMock.AssertExpectations(t)
@@ -344,9 +346,10 @@ func TestPlugin_RunDocument(t *testing.T) {
 
 	fileMock.On("ReadFile", "/var/tmp/docLocation/docname.json").Return(content, nil)
 	execMock.On("ParseDocument", contextMock.Log(), []byte(content), conf.OrchestrationDirectory, conf.OutputS3BucketName, conf.OutputS3KeyPrefix, conf.MessageId, conf.PluginID, conf.DefaultWorkingDirectory, parameters).Return(plugins, nil)
-	execMock.On("ExecuteDocument", contextMock, plugins, conf.BookKeepingFileName, mock.Anything).Return(resChan, nil)
+	execMock.On("ExecuteDocument", contextMock, plugins, conf.BookKeepingFileName, mock.Anything, mock.Anything).Return(resChan, nil)
 	mockIOHandler.On("GetStatus").Return(contracts.ResultStatusSuccess)
 	mockIOHandler.On("SetStatus", contracts.ResultStatusSuccess).Return()
+	mockIOHandler.On("AppendInfof", "Steps of the child document - %v", []interface{}{"aws:runDocument: Success"}).Return()
 
 	p := Plugin{
 		filesys: fileMock,
@@ -404,9 +407,10 @@ func TestPlugin_RunDocumentFromSSMDocument(t *testing.T) {
 	fileMock.On("WriteFile", "orch/downloads/RunShellScript.json", content).Return(nil)
 	fileMock.On("ReadFile", "orch/downloads/RunShellScript.json").Return(content, nil)
 	execMock.On("ParseDocument", contextMock.Log(), []byte(content), conf.OrchestrationDirectory, conf.OutputS3BucketName, conf.OutputS3KeyPrefix, conf.MessageId, conf.PluginID, conf.DefaultWorkingDirectory, parameters).Return(plugins, nil)
-	execMock.On("ExecuteDocument", contextMock, plugins, conf.BookKeepingFileName, mock.Anything).Return(resChan, nil)
+	execMock.On("ExecuteDocument", contextMock, plugins, conf.BookKeepingFileName, mock.Anything, mock.Anything).Return(resChan, nil)
 	mockIOHandler.On("GetStatus").Return(contracts.ResultStatusSuccess)
 	mockIOHandler.On("SetStatus", contracts.ResultStatusSuccess).Return()
+	mockIOHandler.On("AppendInfof", "Steps of the child document - %v", []interface{}{"aws:runDocument: Success"}).Return()
 
 	var input RunDocumentPluginInput
 	input.DocumentType = "SSMDocument"
@@ -419,7 +423,7 @@ func TestPlugin_RunDocumentFromSSMDocument(t *testing.T) {
 		execDoc: execMock,
 	}
 
-	p.runDocument(contextMock, &input, conf, mockIOHandler)
+	p.runDocument(contextMock, &input, conf, createMockCancelFlag(), mockIOHandler)
 
 	execMock.AssertExpectations(t)
 	fileMock.AssertExpectations(t)
@@ -462,9 +466,10 @@ func TestPlugin_RunDocumentFromAbsLocalPath(t *testing.T) {
 
 	fileMock.On("ReadFile", "/var/tmp/document/docName.json").Return(content, nil)
 	execMock.On("ParseDocument", contextMock.Log(), []byte(content), conf.OrchestrationDirectory, conf.OutputS3BucketName, conf.OutputS3KeyPrefix, conf.MessageId, conf.PluginID, conf.DefaultWorkingDirectory, parameters).Return(plugins, nil)
-	execMock.On("ExecuteDocument", contextMock, plugins, conf.BookKeepingFileName, mock.Anything).Return(resChan, nil)
+	execMock.On("ExecuteDocument", contextMock, plugins, conf.BookKeepingFileName, mock.Anything, mock.Anything).Return(resChan, nil)
 	mockIOHandler.On("GetStatus").Return(contracts.ResultStatusSuccess)
 	mockIOHandler.On("SetStatus", contracts.ResultStatusSuccess).Return()
+	mockIOHandler.On("AppendInfof", "Steps of the child document - %v", []interface{}{"aws:runDocument: Success"}).Return()
 
 	var input RunDocumentPluginInput
 	input.DocumentType = "LocalPath"
@@ -476,7 +481,7 @@ func TestPlugin_RunDocumentFromAbsLocalPath(t *testing.T) {
 		execDoc: execMock,
 	}
 
-	p.runDocument(contextMock, &input, conf, mockIOHandler)
+	p.runDocument(contextMock, &input, conf, createMockCancelFlag(), mockIOHandler)
 
 	execMock.AssertExpectations(t)
 	fileMock.AssertExpectations(t)
@@ -651,3 +656,103 @@ func loadFile(t *testing.T, fileName string) (result []byte) {
 	}
 	return
 }
+
+func TestInheritedParameters(t *testing.T) {
+	parentParameters := map[string]interface{}{"environment": "prod", "versions": []interface{}{"1.2", "1.3"}}
+
+	inherited, err := inheritedParameters(parentParameters, &RunDocumentPluginInput{})
+	assert.NoError(t, err)
+	assert.Empty(t, inherited)
+
+	inherited, err = inheritedParameters(parentParameters, &RunDocumentPluginInput{PassParameters: true})
+	assert.NoError(t, err)
+	assert.Equal(t, parentParameters, inherited)
+
+	inherited, err = inheritedParameters(parentParameters, &RunDocumentPluginInput{ParameterMapping: map[string]string{"targetEnvironment": "environment"}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"targetEnvironment": "prod"}, inherited)
+
+	_, err = inheritedParameters(parentParameters, &RunDocumentPluginInput{ParameterMapping: map[string]string{"region": "targetRegion"}})
+	assert.Error(t, err)
+}
+
+func TestPrepareDocumentForExecutionOverridesInheritedParameters(t *testing.T) {
+	execMock := NewExecMock()
+	fileMock := filemock.FileSystemMock{}
+	conf := createStubConfiguration("orch", "bucket", "prefix", "1234-1234-1234", "directory")
+	content := "content"
+	inherited := map[string]interface{}{"environment": "prod", "version": "1.2"}
+	expectedParameters := map[string]interface{}{"environment": "staging", "version": "1.2"}
+
+	fileMock.On("ReadFile", "document/name.json").Return(content, nil)
+	execMock.On("ParseDocument", logMock, []byte(content), conf.OrchestrationDirectory, conf.OutputS3BucketName, conf.OutputS3KeyPrefix, conf.MessageId, conf.PluginID, conf.DefaultWorkingDirectory, expectedParameters).Return([]contracts.PluginState{}, nil)
+	p := Plugin{
+		filesys: fileMock,
+		execDoc: execMock,
+	}
+
+	_, err := p.prepareDocumentForExecution(logMock, "document/name.json", conf, map[string]interface{}{"environment": "staging"}, inherited)
+
+	assert.NoError(t, err)
+	execMock.AssertExpectations(t)
+	assert.Equal(t, "prod", inherited["environment"])
+}
+
+func TestAggregateResults(t *testing.T) {
+	pluginsInfo := []contracts.PluginState{{Id: "install"}, {Id: "configure"}, {Id: "verify"}}
+	pluginOutput := map[string]*contracts.PluginResult{
+		"verify":    {PluginID: "verify", Status: contracts.ResultStatusFailed, Code: 3},
+		"configure": {PluginID: "configure", Status: contracts.ResultStatusFailed, Code: 2, StandardOutput: "configuring"},
+		"install":   {PluginID: "install", Status: contracts.ResultStatusSuccess, StandardOutput: "installing"},
+	}
+	output := iohandler.NewDefaultIOHandler(logMock, contracts.IOConfiguration{})
+
+	aggregateResults(pluginsInfo, pluginOutput, output)
+
+	assert.Equal(t, contracts.ResultStatusFailed, output.GetStatus())
+	assert.Equal(t, 2, output.GetExitCode())
+	assert.Equal(t, "installing\nconfiguring\nSteps of the child document - install: Success, configure: Failed, verify: Failed", output.GetStdout())
+}
+
+func TestExecDocumentImpl_ExecuteDocumentPropagatesCancel(t *testing.T) {
+	execMock := executermocks.NewMockExecuter()
+	mockObj := new(InstanceMock)
+	mockObj.On("InstanceID", mock.Anything, mock.Anything).Return("instanceID", nil)
+	instance = mockObj
+
+	var subDocumentCancelFlag task.CancelFlag
+	execMock.On("Run", mock.AnythingOfType("*task.ChanneledCancelFlag"), mock.AnythingOfType("*executer.DocumentFileStore")).Return(make(chan contracts.DocumentResult)).Run(func(args mock.Arguments) {
+		subDocumentCancelFlag = args.Get(0).(task.CancelFlag)
+	})
+	exec := ExecDocumentImpl{
+		DocExecutor: execMock,
+	}
+	cancelFlag := task.NewChanneledCancelFlag()
+	conf := createStubConfiguration("orch", "bucket", "prefix", "1234-1234-1234", "directory")
+
+	_, err := exec.ExecuteDocument(conf, contextMock, []contracts.PluginState{plugin}, "documentId", "time", cancelFlag)
+	assert.NoError(t, err)
+	assert.False(t, subDocumentCancelFlag.Canceled())
+
+	cancelFlag.Set(task.Canceled)
+	assert.Equal(t, task.Canceled, subDocumentCancelFlag.Wait())
+}
+
+func TestPlugin_RunDocumentMaxDepthFromConfig(t *testing.T) {
+	config := appconfig.DefaultConfig()
+	config.Ssm.RunDocumentMaxDepth = 1
+	ctx := new(context.Mock)
+	ctx.On("Log").Return(logMock)
+	ctx.On("AppConfig").Return(config)
+	ctx.On("With", mock.AnythingOfType("string")).Return(ctx)
+	mockIOHandler := new(iohandlermocks.MockIOHandler)
+	conf := createStubConfiguration("orch", "bucket", "prefix", "1234-1234-1234", "directory")
+	conf.Settings = createStubExecutionDepth(1)
+
+	mockIOHandler.On("MarkAsFailed", fmt.Errorf("Maximum depth for document execution exceeded. Maximum depth permitted - 1 and current depth - 2")).Return()
+	p := Plugin{}
+
+	p.runDocument(ctx, &RunDocumentPluginInput{DocumentType: LocalPathType, DocumentPath: "doc.json"}, conf, createMockCancelFlag(), mockIOHandler)
+
+	mockIOHandler.AssertExpectations(t)
+}
@@ -0,0 +1,225 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runpythonscript implements the aws:runPythonScript plugin
+package runpythonscript
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/plugins/pluginutil"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	downloadsDir = "downloads" //Directory under the orchestration directory where the downloaded resource resides
+
+	scriptName       = "_script.py"
+	requirementsName = "requirements.txt"
+	venvDir          = "venv"
+)
+
+// Assign method to global variables to allow unittest to override
+var lookPath = exec.LookPath
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	plugin.CommandExecuter = executers.ShellCommandExecuter{}
+	return &plugin, nil
+}
+
+// Plugin is the type for the aws:runPythonScript plugin.
+type Plugin struct {
+	// CommandExecuter is an object that can execute commands.
+	CommandExecuter executers.T
+}
+
+// RunPythonScriptPluginInput represents the script run by the aws:runPythonScript plugin
+type RunPythonScriptPluginInput struct {
+	contracts.PluginInput
+	ID string
+	// RunCommand are the lines of an inline script
+	RunCommand []string `json:"runCommand"`
+	// ScriptPath is the path of a script, absolute or relative to the directory aws:downloadContent downloads to
+	ScriptPath string `json:"scriptPath"`
+	// Arguments are passed to the script
+	Arguments []string `json:"arguments"`
+	// Requirements are pip requirements installed into a virtual environment the script runs in
+	Requirements     []string    `json:"requirements"`
+	WorkingDirectory string      `json:"workingDirectory"`
+	TimeoutSeconds   interface{} `json:"timeoutSeconds"`
+}
+
+// Name returns the plugin name
+func Name() string {
+	return appconfig.PluginNameAwsRunPythonScript
+}
+
+// Execute runs the python script and returns its output.
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", Name(), config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+	} else if input, err := parseAndValidateInput(config.Properties); err != nil {
+		output.MarkAsFailed(err)
+	} else {
+		p.runScript(log, input, config, cancelFlag, output)
+	}
+}
+
+// parseAndValidateInput parses the plugin properties and checks that exactly one of runCommand and scriptPath is set
+func parseAndValidateInput(rawPluginInput interface{}) (*RunPythonScriptPluginInput, error) {
+	var input RunPythonScriptPluginInput
+	if err := jsonutil.Remarshal(rawPluginInput, &input); err != nil {
+		return nil, fmt.Errorf("invalid format in plugin properties %v; \nerror %v", rawPluginInput, err)
+	}
+
+	if len(input.RunCommand) == 0 && input.ScriptPath == "" {
+		return nil, fmt.Errorf("invalid input: either runCommand or scriptPath must be set")
+	}
+	if len(input.RunCommand) > 0 && input.ScriptPath != "" {
+		return nil, fmt.Errorf("invalid input: runCommand and scriptPath cannot both be set")
+	}
+	for _, requirement := range input.Requirements {
+		if strings.TrimSpace(requirement) == "" || strings.ContainsAny(requirement, "\r\n") {
+			return nil, fmt.Errorf("invalid input: requirement %q must be a single non empty line", requirement)
+		}
+	}
+	return &input, nil
+}
+
+// runScript creates the virtual environment if requirements are set and runs the script with its arguments.
+func (p *Plugin) runScript(log log.T, input *RunPythonScriptPluginInput, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	downloadsPath := filepath.Join(strings.TrimSuffix(config.OrchestrationDirectory, config.PluginID), downloadsDir)
+
+	workingDir := config.DefaultWorkingDirectory
+	if filepath.IsAbs(input.WorkingDirectory) {
+		workingDir = input.WorkingDirectory
+	} else if path := filepath.Join(downloadsPath, input.WorkingDirectory); fileutil.Exists(path) {
+		workingDir = path
+	}
+
+	orchestrationDir := fileutil.BuildPath(config.OrchestrationDirectory, input.ID)
+	if err := fileutil.MakeDirsWithExecuteAccess(orchestrationDir); err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to create orchestrationDir directory, %v", orchestrationDir))
+		return
+	}
+
+	scriptPath := input.ScriptPath
+	if scriptPath == "" {
+		scriptPath = filepath.Join(orchestrationDir, scriptName)
+		if err := pluginutil.CreateScriptFile(log, scriptPath, input.RunCommand, fileutil.ByteOrderMarkSkip); err != nil {
+			output.MarkAsFailed(fmt.Errorf("failed to create script file. %v", err))
+			return
+		}
+	} else if !filepath.IsAbs(scriptPath) {
+		scriptPath = filepath.Join(downloadsPath, scriptPath)
+	}
+	if !fileutil.Exists(scriptPath) {
+		output.MarkAsFailed(fmt.Errorf("script %v was not found", scriptPath))
+		return
+	}
+
+	python, err := findPython()
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	executionTimeout := pluginutil.ValidateExecutionTimeout(log, input.TimeoutSeconds)
+	if len(input.Requirements) > 0 {
+		if python, err = p.createVirtualEnvironment(log, python, orchestrationDir, input.Requirements, workingDir, executionTimeout, cancelFlag, output); err != nil {
+			p.setFailure(err, cancelFlag, output)
+			return
+		}
+	}
+
+	log.Debugf("Running python script %v with %v in workingDirectory %v", scriptPath, python, workingDir)
+	exitCode, err := p.CommandExecuter.NewExecute(log, workingDir, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, python, append([]string{scriptPath}, input.Arguments...))
+
+	output.SetExitCode(exitCode)
+	output.SetStatus(pluginutil.GetStatus(exitCode, cancelFlag))
+	if err != nil {
+		p.setFailure(fmt.Errorf("failed to run python script: %v", err), cancelFlag, output)
+	}
+}
+
+// createVirtualEnvironment creates a virtual environment in the orchestration directory, installs the requirements
+// into it and returns the path of its interpreter.
+func (p *Plugin) createVirtualEnvironment(
+	log log.T,
+	python string,
+	orchestrationDir string,
+	requirements []string,
+	workingDir string,
+	executionTimeout int,
+	cancelFlag task.CancelFlag,
+	output iohandler.IOHandler) (string, error) {
+
+	venvPath := filepath.Join(orchestrationDir, venvDir)
+	log.Infof("Creating virtual environment %v", venvPath)
+	if exitCode, err := p.CommandExecuter.NewExecute(log, workingDir, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, python, []string{"-m", "venv", venvPath}); err != nil || exitCode != 0 {
+		return "", fmt.Errorf("failed to create virtual environment %v: exit code %v, %v", venvPath, exitCode, err)
+	}
+
+	requirementsPath := filepath.Join(orchestrationDir, requirementsName)
+	if err := pluginutil.CreateScriptFile(log, requirementsPath, requirements, fileutil.ByteOrderMarkSkip); err != nil {
+		return "", fmt.Errorf("failed to create requirements file. %v", err)
+	}
+
+	venvPython := venvInterpreter(venvPath)
+	log.Infof("Installing requirements %v", requirements)
+	if exitCode, err := p.CommandExecuter.NewExecute(log, workingDir, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, venvPython, []string{"-m", "pip", "install", "--disable-pip-version-check", "-r", requirementsPath}); err != nil || exitCode != 0 {
+		return "", fmt.Errorf("failed to install requirements: exit code %v, %v", exitCode, err)
+	}
+	return venvPython, nil
+}
+
+// setFailure marks the plugin as failed unless it was canceled, timed out or requested a reboot.
+func (p *Plugin) setFailure(err error, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	switch status := output.GetStatus(); {
+	case cancelFlag.ShutDown():
+		output.MarkAsShutdown()
+	case cancelFlag.Canceled():
+		output.MarkAsCancelled()
+	case status == contracts.ResultStatusTimedOut || status == contracts.ResultStatusSuccessAndReboot:
+	default:
+		output.MarkAsFailed(err)
+	}
+}
+
+// findPython returns the path of the first python interpreter found in the PATH.
+func findPython() (string, error) {
+	for _, name := range pythonInterpreters {
+		if path, err := lookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("python is not installed on this instance, none of %v was found", strings.Join(pythonInterpreters, ", "))
+}
@@ -0,0 +1,158 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runpythonscript implements the aws:runPythonScript plugin
+package runpythonscript
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	iohandlermocks "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
+	multiwritermock "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter/mock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var logger = log.NewMockLog()
+
+func TestParseAndValidateInput(t *testing.T) {
+	input, err := parseAndValidateInput(map[string]interface{}{
+		"runCommand":   []interface{}{"import requests", "print(requests.__version__)"},
+		"requirements": []interface{}{"requests==2.22.0"},
+		"arguments":    []interface{}{"--verbose"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"import requests", "print(requests.__version__)"}, input.RunCommand)
+	assert.Equal(t, []string{"requests==2.22.0"}, input.Requirements)
+	assert.Equal(t, []string{"--verbose"}, input.Arguments)
+
+	_, err = parseAndValidateInput(map[string]interface{}{})
+	assert.Error(t, err)
+
+	_, err = parseAndValidateInput(map[string]interface{}{"runCommand": []interface{}{"print(1)"}, "scriptPath": "main.py"})
+	assert.Error(t, err)
+
+	_, err = parseAndValidateInput(map[string]interface{}{"scriptPath": "main.py", "requirements": []interface{}{"requests\n-e ."}})
+	assert.Error(t, err)
+}
+
+func TestFindPython(t *testing.T) {
+	defer func() { lookPath = exec.LookPath }()
+
+	lookPath = func(file string) (string, error) {
+		if file == pythonInterpreters[len(pythonInterpreters)-1] {
+			return "/usr/bin/" + file, nil
+		}
+		return "", fmt.Errorf("%v not found", file)
+	}
+	python, err := findPython()
+	assert.NoError(t, err)
+	assert.Equal(t, "/usr/bin/"+pythonInterpreters[len(pythonInterpreters)-1], python)
+
+	lookPath = func(file string) (string, error) { return "", fmt.Errorf("%v not found", file) }
+	_, err = findPython()
+	assert.Error(t, err)
+}
+
+func TestRunScriptWithRequirements(t *testing.T) {
+	orchestrationDir, err := ioutil.TempDir("", "runpythonscript")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+	lookPath = func(file string) (string, error) { return "/usr/bin/python3", nil }
+	defer func() { lookPath = exec.LookPath }()
+
+	mockExecuter := new(executers.MockCommandExecuter)
+	mockIOHandler := new(iohandlermocks.MockIOHandler)
+	mockCancelFlag := new(task.MockCancelFlag)
+	stdout := new(multiwritermock.MockDocumentIOMultiWriter)
+	stderr := new(multiwritermock.MockDocumentIOMultiWriter)
+	venvPath := filepath.Join(orchestrationDir, venvDir)
+	scriptPath := filepath.Join(orchestrationDir, scriptName)
+
+	mockIOHandler.On("GetStdoutWriter").Return(stdout)
+	mockIOHandler.On("GetStderrWriter").Return(stderr)
+	mockExecuter.On("NewExecute", mock.Anything, "/tmp", stdout, stderr, mockCancelFlag, mock.Anything, "/usr/bin/python3", []string{"-m", "venv", venvPath}).Return(0, nil).Once()
+	mockExecuter.On("NewExecute", mock.Anything, "/tmp", stdout, stderr, mockCancelFlag, mock.Anything, venvInterpreter(venvPath),
+		[]string{"-m", "pip", "install", "--disable-pip-version-check", "-r", filepath.Join(orchestrationDir, requirementsName)}).Return(0, nil).Once()
+	mockExecuter.On("NewExecute", mock.Anything, "/tmp", stdout, stderr, mockCancelFlag, mock.Anything, venvInterpreter(venvPath), []string{scriptPath, "prod"}).Return(0, nil).Once()
+	mockCancelFlag.On("Canceled").Return(false)
+	mockCancelFlag.On("ShutDown").Return(false)
+	mockIOHandler.On("SetExitCode", 0).Return()
+	mockIOHandler.On("SetStatus", contracts.ResultStatusSuccess).Return()
+
+	p := &Plugin{CommandExecuter: mockExecuter}
+	input := &RunPythonScriptPluginInput{RunCommand: []string{"import sys", "print(sys.argv[1])"}, Arguments: []string{"prod"}, Requirements: []string{"boto3"}}
+	p.runScript(logger, input, contracts.Configuration{OrchestrationDirectory: orchestrationDir, DefaultWorkingDirectory: "/tmp"}, mockCancelFlag, mockIOHandler)
+
+	mockExecuter.AssertExpectations(t)
+	mockIOHandler.AssertExpectations(t)
+	script, err := ioutil.ReadFile(scriptPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(script), "print(sys.argv[1])")
+	requirements, err := ioutil.ReadFile(filepath.Join(orchestrationDir, requirementsName))
+	assert.NoError(t, err)
+	assert.Contains(t, string(requirements), "boto3")
+}
+
+func TestRunScriptFailsWhenRequirementsFail(t *testing.T) {
+	orchestrationDir, err := ioutil.TempDir("", "runpythonscript")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+	lookPath = func(file string) (string, error) { return "/usr/bin/python3", nil }
+	defer func() { lookPath = exec.LookPath }()
+
+	mockExecuter := new(executers.MockCommandExecuter)
+	mockIOHandler := new(iohandlermocks.MockIOHandler)
+	mockCancelFlag := new(task.MockCancelFlag)
+	stdout := new(multiwritermock.MockDocumentIOMultiWriter)
+	stderr := new(multiwritermock.MockDocumentIOMultiWriter)
+
+	mockIOHandler.On("GetStdoutWriter").Return(stdout)
+	mockIOHandler.On("GetStderrWriter").Return(stderr)
+	mockExecuter.On("NewExecute", mock.Anything, "/tmp", stdout, stderr, mockCancelFlag, mock.Anything, "/usr/bin/python3", mock.Anything).Return(1, nil).Once()
+	mockCancelFlag.On("Canceled").Return(false)
+	mockCancelFlag.On("ShutDown").Return(false)
+	mockIOHandler.On("GetStatus").Return(contracts.ResultStatus(""))
+	mockIOHandler.On("MarkAsFailed", mock.Anything).Return()
+
+	p := &Plugin{CommandExecuter: mockExecuter}
+	input := &RunPythonScriptPluginInput{RunCommand: []string{"print(1)"}, Requirements: []string{"boto3"}}
+	p.runScript(logger, input, contracts.Configuration{OrchestrationDirectory: orchestrationDir, DefaultWorkingDirectory: "/tmp"}, mockCancelFlag, mockIOHandler)
+
+	mockExecuter.AssertExpectations(t)
+	mockIOHandler.AssertExpectations(t)
+}
+
+func TestRunScriptFailsWhenScriptIsMissing(t *testing.T) {
+	orchestrationDir, err := ioutil.TempDir("", "runpythonscript")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+
+	mockIOHandler := new(iohandlermocks.MockIOHandler)
+	mockIOHandler.On("MarkAsFailed", mock.Anything).Return()
+
+	p := &Plugin{CommandExecuter: new(executers.MockCommandExecuter)}
+	input := &RunPythonScriptPluginInput{ScriptPath: "scripts/missing.py"}
+	p.runScript(logger, input, contracts.Configuration{OrchestrationDirectory: orchestrationDir}, new(task.MockCancelFlag), mockIOHandler)
+
+	mockIOHandler.AssertExpectations(t)
+}
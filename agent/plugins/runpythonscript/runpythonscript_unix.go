@@ -0,0 +1,27 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build darwin freebsd linux netbsd openbsd
+
+// Package runpythonscript implements the aws:runPythonScript plugin
+package runpythonscript
+
+import "path/filepath"
+
+// pythonInterpreters are the names of the python interpreters in order of preference
+var pythonInterpreters = []string{"python3", "python"}
+
+// venvInterpreter returns the path of the interpreter of the virtual environment
+func venvInterpreter(venvPath string) string {
+	return filepath.Join(venvPath, "bin", "python")
+}
@@ -0,0 +1,63 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runscript
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+const (
+	// maxEnvironmentValueLength is the longest value of an environment variable, the limit of Windows
+	maxEnvironmentValueLength = 32767
+	// maxEnvironmentSize is the largest total size of the NAME=value pairs of the environment variables
+	maxEnvironmentSize = 128 * 1024
+)
+
+// environmentVariableName matches the names of environment variables scripts can be given
+var environmentVariableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// environmentVariables validates the environment variables of the input and returns them as NAME=value pairs sorted by name
+func environmentVariables(environment map[string]string) ([]string, error) {
+	pairs := make([]string, 0, len(environment))
+	size := 0
+	for name, value := range environment {
+		if !environmentVariableName.MatchString(name) {
+			return nil, fmt.Errorf("invalid environment variable name %q, names must consist of letters, digits and underscores and not start with a digit", name)
+		}
+		for _, denied := range appconfig.DefaultSessionEnvironmentVariablesDenylist {
+			if strings.EqualFold(name, denied) || (strings.HasSuffix(denied, "*") && strings.HasPrefix(strings.ToUpper(name), strings.TrimSuffix(denied, "*"))) {
+				return nil, fmt.Errorf("environment variable %v cannot be set by documents", name)
+			}
+		}
+		if strings.ContainsRune(value, '\x00') {
+			return nil, fmt.Errorf("value of environment variable %v contains a null character", name)
+		}
+		if len(value) > maxEnvironmentValueLength {
+			return nil, fmt.Errorf("value of environment variable %v is longer than %v characters", name, maxEnvironmentValueLength)
+		}
+		pair := name + "=" + value
+		size += len(pair)
+		pairs = append(pairs, pair)
+	}
+	if size > maxEnvironmentSize {
+		return nil, fmt.Errorf("environment variables are larger than %v bytes", maxEnvironmentSize)
+	}
+	sort.Strings(pairs)
+	return pairs, nil
+}
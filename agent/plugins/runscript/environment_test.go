@@ -0,0 +1,103 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runscript
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	iohandlermocks "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
+	multiwritermock "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter/mock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEnvironmentVariables(t *testing.T) {
+	env, err := environmentVariables(map[string]string{"TARGET_HOST": "db.example.com", "_count": "3; rm -rf /"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"TARGET_HOST=db.example.com", "_count=3; rm -rf /"}, env)
+
+	env, err = environmentVariables(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, env)
+
+	invalid := []map[string]string{
+		{"1VALUE": "value"},
+		{"NAME=VALUE": "value"},
+		{"": "value"},
+		{"LD_PRELOAD": "/tmp/lib.so"},
+		{"PATH": "/tmp:/usr/bin"},
+		{"bash_func_ls": "() { /tmp/evil; }"},
+		{"NAME": "a\x00b"},
+		{"NAME": strings.Repeat("a", maxEnvironmentValueLength+1)},
+		{"A": strings.Repeat("a", maxEnvironmentValueLength), "B": strings.Repeat("b", maxEnvironmentValueLength),
+			"C": strings.Repeat("c", maxEnvironmentValueLength), "D": strings.Repeat("d", maxEnvironmentValueLength),
+			"E": strings.Repeat("e", maxEnvironmentValueLength)},
+	}
+	for _, environment := range invalid {
+		_, err = environmentVariables(environment)
+		assert.Error(t, err)
+	}
+}
+
+func TestRunCommandsWithEnvironment(t *testing.T) {
+	orchestrationDir, _ := ioutil.TempDir("", "runscript")
+	defer os.RemoveAll(orchestrationDir)
+
+	mockCancelFlag := new(task.MockCancelFlag)
+	mockExecuter := new(executers.MockCommandExecuter)
+	mockIOHandler := new(iohandlermocks.MockIOHandler)
+	p := &Plugin{CommandExecuter: mockExecuter, Name: "aws:runShellScript", ScriptName: "_script.sh", ShellCommand: "sh", ShellArguments: []string{"-c"}}
+
+	stdout := new(multiwritermock.MockDocumentIOMultiWriter)
+	stderr := new(multiwritermock.MockDocumentIOMultiWriter)
+	mockIOHandler.On("GetStdoutWriter").Return(stdout)
+	mockIOHandler.On("GetStderrWriter").Return(stderr)
+	mockIOHandler.On("SetExitCode", 0).Return()
+	mockIOHandler.On("SetStatus", contracts.ResultStatusSuccess).Return()
+	mockCancelFlag.On("Canceled").Return(false)
+	mockCancelFlag.On("ShutDown").Return(false)
+	mockExecuter.On("NewExecuteWithEnvironment", mock.Anything, mock.Anything, stdout, stderr, mockCancelFlag, mock.Anything, "sh", mock.Anything,
+		[]string{"TARGET_HOST=db.example.com"}).Return(0, nil)
+
+	input := RunScriptPluginInput{
+		RunCommand:  []string{`echo "$TARGET_HOST"`},
+		ID:          "0.aws:runShellScript",
+		Environment: map[string]string{"TARGET_HOST": "db.example.com"},
+	}
+	p.runCommands(log.NewMockLog(), "aws:runShellScript", input, orchestrationDir, orchestrationDir, "", mockCancelFlag, mockIOHandler)
+
+	mockExecuter.AssertExpectations(t)
+	mockIOHandler.AssertExpectations(t)
+}
+
+func TestRunCommandsWithInvalidEnvironment(t *testing.T) {
+	mockExecuter := new(executers.MockCommandExecuter)
+	mockIOHandler := new(iohandlermocks.MockIOHandler)
+	p := &Plugin{CommandExecuter: mockExecuter, Name: "aws:runShellScript", ScriptName: "_script.sh", ShellCommand: "sh"}
+	mockIOHandler.On("MarkAsFailed", mock.Anything).Return()
+
+	input := RunScriptPluginInput{RunCommand: []string{"env"}, Environment: map[string]string{"BASH_ENV": "/tmp/init.sh"}}
+	p.runCommands(log.NewMockLog(), "aws:runShellScript", input, os.TempDir(), os.TempDir(), "", new(task.MockCancelFlag), mockIOHandler)
+
+	mockIOHandler.AssertExpectations(t)
+	mockExecuter.AssertNotCalled(t, "NewExecute")
+	mockExecuter.AssertNotCalled(t, "NewExecuteWithEnvironment")
+}
@@ -0,0 +1,83 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runscript implements the RunScript plugin.
+package runscript
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+)
+
+const (
+	// powerShellVersionWindows selects Windows PowerShell 5.1, installed with Windows
+	powerShellVersionWindows = "5.1"
+	// powerShellVersionCore selects PowerShell 7, the pwsh engine installed separately on Windows and Linux
+	powerShellVersionCore = "7"
+)
+
+// Assign method to global variables to allow unittest to override
+var lookPath = exec.LookPath
+var fileExists = fileutil.Exists
+
+// pwshLocations returns the locations PowerShell 7 is installed to by default on the platform.
+func pwshLocations(goos string) []string {
+	if goos == "windows" {
+		return []string{filepath.Join(os.Getenv("ProgramFiles"), "PowerShell", "7", "pwsh.exe")}
+	}
+	return []string{"/usr/bin/pwsh", "/usr/local/bin/pwsh", "/opt/microsoft/powershell/7/pwsh"}
+}
+
+// selectShellCommand returns the command the script is run with, the PowerShell engine of the version if it is set.
+func (p *Plugin) selectShellCommand(powerShellVersion string) (string, error) {
+	if powerShellVersion == "" {
+		return p.ShellCommand, nil
+	}
+	if !p.SupportsPowerShellVersion {
+		return "", fmt.Errorf("PowerShellVersion is not supported by %v", p.Name)
+	}
+	return findPowerShell(powerShellVersion, runtime.GOOS)
+}
+
+// findPowerShell returns the path of the PowerShell engine of the version on the platform,
+// or an error if the version is unknown or not installed.
+func findPowerShell(powerShellVersion string, goos string) (string, error) {
+	switch powerShellVersion {
+	case powerShellVersionWindows:
+		if goos != "windows" {
+			return "", fmt.Errorf("PowerShell %v is only available on Windows, use PowerShellVersion %v", powerShellVersionWindows, powerShellVersionCore)
+		}
+		if !fileExists(appconfig.PowerShellPluginCommandName) {
+			return "", fmt.Errorf("PowerShell %v was not found at %v", powerShellVersionWindows, appconfig.PowerShellPluginCommandName)
+		}
+		return appconfig.PowerShellPluginCommandName, nil
+
+	case powerShellVersionCore:
+		if path, err := lookPath("pwsh"); err == nil {
+			return path, nil
+		}
+		for _, path := range pwshLocations(goos) {
+			if fileExists(path) {
+				return path, nil
+			}
+		}
+		return "", fmt.Errorf("PowerShell %v (pwsh) is not installed on this instance", powerShellVersionCore)
+	}
+	return "", fmt.Errorf("unsupported PowerShellVersion %v, expected %v or %v", powerShellVersion, powerShellVersionWindows, powerShellVersionCore)
+}
@@ -0,0 +1,101 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runscript implements the RunScript plugin.
+package runscript
+
+import (
+	"fmt"
+	"os/exec"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubPowerShellLookup makes the files in installed the only ones found, and pwsh found in the PATH at pathPwsh if it is set.
+func stubPowerShellLookup(pathPwsh string, installed ...string) func() {
+	lookPath = func(file string) (string, error) {
+		if pathPwsh == "" {
+			return "", fmt.Errorf("%v not found", file)
+		}
+		return pathPwsh, nil
+	}
+	fileExists = func(path string) bool {
+		for _, file := range installed {
+			if file == path {
+				return true
+			}
+		}
+		return false
+	}
+	return func() {
+		lookPath = exec.LookPath
+		fileExists = fileutil.Exists
+	}
+}
+
+func TestFindPowerShellCore(t *testing.T) {
+	defer stubPowerShellLookup("/usr/local/bin/pwsh")()
+	path, err := findPowerShell("7", "linux")
+	assert.NoError(t, err)
+	assert.Equal(t, "/usr/local/bin/pwsh", path)
+
+	stubPowerShellLookup("", "/opt/microsoft/powershell/7/pwsh")
+	path, err = findPowerShell("7", "linux")
+	assert.NoError(t, err)
+	assert.Equal(t, "/opt/microsoft/powershell/7/pwsh", path)
+
+	stubPowerShellLookup("")
+	_, err = findPowerShell("7", "linux")
+	assert.Error(t, err)
+}
+
+func TestFindWindowsPowerShell(t *testing.T) {
+	defer stubPowerShellLookup("", appconfig.PowerShellPluginCommandName)()
+	path, err := findPowerShell("5.1", "windows")
+	assert.NoError(t, err)
+	assert.Equal(t, appconfig.PowerShellPluginCommandName, path)
+
+	_, err = findPowerShell("5.1", "linux")
+	assert.Error(t, err)
+
+	stubPowerShellLookup("")
+	_, err = findPowerShell("5.1", "windows")
+	assert.Error(t, err)
+
+	_, err = findPowerShell("6", "windows")
+	assert.Error(t, err)
+}
+
+func TestSelectShellCommand(t *testing.T) {
+	defer stubPowerShellLookup("/usr/bin/pwsh")()
+	shell := &Plugin{Name: "aws:runShellScript", ShellCommand: "sh"}
+	powershell := &Plugin{Name: "aws:runPowerShellScript", ShellCommand: "powershell", SupportsPowerShellVersion: true}
+
+	command, err := powershell.selectShellCommand("")
+	assert.NoError(t, err)
+	assert.Equal(t, "powershell", command)
+
+	command, err = powershell.selectShellCommand("7")
+	assert.NoError(t, err)
+	assert.Equal(t, "/usr/bin/pwsh", command)
+
+	command, err = shell.selectShellCommand("")
+	assert.NoError(t, err)
+	assert.Equal(t, "sh", command)
+
+	_, err = shell.selectShellCommand("7")
+	assert.Error(t, err)
+}
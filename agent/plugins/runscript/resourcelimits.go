@@ -0,0 +1,121 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runscript implements the RunScript plugin.
+package runscript
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+)
+
+const (
+	systemdRunCommand = "systemd-run"
+	// systemdRuntimeDir exists when systemd is the init system of the instance
+	systemdRuntimeDir = "/run/systemd/system"
+
+	minIOWeight = 1
+	maxIOWeight = 10000
+)
+
+var (
+	// memoryMaxRegex matches a number of bytes with an optional K, M, G or T suffix, or a percentage of the physical memory
+	memoryMaxRegex = regexp.MustCompile(`^([0-9]+[KMGT]?|[0-9]+(\.[0-9]+)?%)$`)
+	// cpuQuotaRegex matches a percentage of the time of a single CPU, above 100% for more than one CPU
+	cpuQuotaRegex = regexp.MustCompile(`^[0-9]+%$`)
+)
+
+// Assign method to global variables to allow unittest to override
+var isSystemdAvailable = systemdAvailable
+
+// ResourceLimits are the limits of the transient systemd scope a shell script runs in.
+type ResourceLimits struct {
+	// MemoryMax is the memory the script can use, e.g. 512M or 25%
+	MemoryMax string
+	// CPUQuota is the CPU time the script can use relative to a single CPU, e.g. 50% or 200%
+	CPUQuota string
+	// TasksMax is the number of processes and threads the script can run
+	TasksMax int
+	// IOWeight is the block IO weight of the script, between 1 and 10000
+	IOWeight int
+}
+
+// isSet returns true if any limit is set.
+func (limits ResourceLimits) isSet() bool {
+	return limits != ResourceLimits{}
+}
+
+// validate returns an error if a limit is not in the format systemd expects.
+func (limits ResourceLimits) validate() error {
+	if limits.MemoryMax != "" && !memoryMaxRegex.MatchString(limits.MemoryMax) {
+		return fmt.Errorf("invalid MemoryMax %v, expected bytes with an optional K, M, G or T suffix, or a percentage", limits.MemoryMax)
+	}
+	if limits.CPUQuota != "" && !cpuQuotaRegex.MatchString(limits.CPUQuota) {
+		return fmt.Errorf("invalid CPUQuota %v, expected a percentage", limits.CPUQuota)
+	}
+	if limits.TasksMax < 0 {
+		return fmt.Errorf("invalid TasksMax %v, expected a positive number", limits.TasksMax)
+	}
+	if limits.IOWeight != 0 && (limits.IOWeight < minIOWeight || limits.IOWeight > maxIOWeight) {
+		return fmt.Errorf("invalid IOWeight %v, expected a number between %v and %v", limits.IOWeight, minIOWeight, maxIOWeight)
+	}
+	return nil
+}
+
+// systemdRunArguments returns the arguments of systemd-run that start the command in a transient scope with the limits.
+// The scope keeps the script attached to the agent, so its output and exit code are read as without limits.
+func (limits ResourceLimits) systemdRunArguments(commandName string, commandArguments []string) []string {
+	arguments := []string{"--scope", "--quiet"}
+	if limits.MemoryMax != "" {
+		arguments = append(arguments, "--property=MemoryMax="+limits.MemoryMax)
+	}
+	if limits.CPUQuota != "" {
+		arguments = append(arguments, "--property=CPUQuota="+limits.CPUQuota)
+	}
+	if limits.TasksMax > 0 {
+		arguments = append(arguments, fmt.Sprintf("--property=TasksMax=%d", limits.TasksMax))
+	}
+	if limits.IOWeight > 0 {
+		arguments = append(arguments, fmt.Sprintf("--property=IOWeight=%d", limits.IOWeight))
+	}
+	arguments = append(arguments, "--", commandName)
+	return append(arguments, commandArguments...)
+}
+
+// systemdAvailable returns true if systemd runs the instance and systemd-run can be found.
+func systemdAvailable() bool {
+	if _, err := os.Stat(systemdRuntimeDir); err != nil {
+		return false
+	}
+	_, err := exec.LookPath(systemdRunCommand)
+	return err == nil
+}
+
+// checkResourceLimits returns an error if the plugin cannot run the script with the limits.
+func (p *Plugin) checkResourceLimits(limits ResourceLimits) error {
+	if !limits.isSet() {
+		return nil
+	}
+	if !p.SupportsResourceLimits {
+		return fmt.Errorf("ResourceLimits are not supported by %v", p.Name)
+	}
+	if err := limits.validate(); err != nil {
+		return err
+	}
+	if !isSystemdAvailable() {
+		return fmt.Errorf("ResourceLimits require %v, which is not available on this instance", systemdRunCommand)
+	}
+	return nil
+}
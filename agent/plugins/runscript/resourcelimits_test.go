@@ -0,0 +1,83 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package runscript implements the RunScript plugin.
+package runscript
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceLimitsValidate(t *testing.T) {
+	valid := []ResourceLimits{
+		{},
+		{MemoryMax: "512M"},
+		{MemoryMax: "1073741824"},
+		{MemoryMax: "12.5%"},
+		{CPUQuota: "50%"},
+		{CPUQuota: "200%"},
+		{TasksMax: 100, IOWeight: 10000},
+	}
+	for _, limits := range valid {
+		assert.NoError(t, limits.validate(), "%+v", limits)
+	}
+
+	invalid := []ResourceLimits{
+		{MemoryMax: "512MB"},
+		{MemoryMax: "-1"},
+		{CPUQuota: "0.5"},
+		{TasksMax: -1},
+		{IOWeight: 10001},
+		{IOWeight: -5},
+	}
+	for _, limits := range invalid {
+		assert.Error(t, limits.validate(), "%+v", limits)
+	}
+}
+
+func TestSystemdRunArguments(t *testing.T) {
+	limits := ResourceLimits{MemoryMax: "512M", CPUQuota: "50%", TasksMax: 64, IOWeight: 100}
+
+	arguments := limits.systemdRunArguments("sh", []string{"-c", "/var/lib/amazon/ssm/_script.sh"})
+
+	assert.Equal(t, []string{
+		"--scope", "--quiet",
+		"--property=MemoryMax=512M",
+		"--property=CPUQuota=50%",
+		"--property=TasksMax=64",
+		"--property=IOWeight=100",
+		"--", "sh", "-c", "/var/lib/amazon/ssm/_script.sh",
+	}, arguments)
+	assert.Equal(t, []string{"--scope", "--quiet", "--property=TasksMax=8", "--", "sh"}, ResourceLimits{TasksMax: 8}.systemdRunArguments("sh", nil))
+}
+
+func TestCheckResourceLimits(t *testing.T) {
+	systemd := true
+	isSystemdAvailable = func() bool { return systemd }
+	defer func() { isSystemdAvailable = systemdAvailable }()
+
+	shell := &Plugin{Name: "aws:runShellScript", SupportsResourceLimits: true}
+	powershell := &Plugin{Name: "aws:runPowerShellScript"}
+	limits := ResourceLimits{MemoryMax: "1G"}
+
+	assert.NoError(t, shell.checkResourceLimits(ResourceLimits{}))
+	assert.NoError(t, powershell.checkResourceLimits(ResourceLimits{}))
+	assert.NoError(t, shell.checkResourceLimits(limits))
+	assert.Error(t, powershell.checkResourceLimits(limits))
+	assert.Error(t, shell.checkResourceLimits(ResourceLimits{CPUQuota: "half"}))
+
+	systemd = false
+	assert.Error(t, shell.checkResourceLimits(limits))
+}
@@ -0,0 +1,118 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runscript
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// runAsScriptDirPrefix is the prefix of the temporary directories scripts are copied to for other users
+const runAsScriptDirPrefix = "ssm-runas"
+
+// Assign method to global variables to allow unittest to override
+var lookupUser = user.Lookup
+var currentUser = user.Current
+
+// selectRunAsUser returns the user the scripts of the document run as, or an empty string to run them as the agent user.
+// Documents run as the configured default user unless they choose one of the users the agent allows with runAsUser,
+// so documents can only run scripts as root if root is the default user or is allowed explicitly.
+func (p *Plugin) selectRunAsUser(ssmConfig appconfig.SsmCfg, documentUser string) (string, error) {
+	runAsUser := strings.TrimSpace(ssmConfig.RunCommandDefaultRunAsUser)
+	if documentUser = strings.TrimSpace(documentUser); documentUser != "" && documentUser != runAsUser {
+		if !containsUser(ssmConfig.RunCommandRunAsAllowedUsers, documentUser) {
+			return "", fmt.Errorf("documents are not permitted to run scripts as user %v", documentUser)
+		}
+		runAsUser = documentUser
+	}
+	if runAsUser == "" {
+		return "", nil
+	}
+	if !p.SupportsRunAs {
+		// the default user only applies to the plugins which can run scripts as other users, the plugin runner
+		// only runs the others when RunCommandElevatedAllowedPlugins permits them to run as the agent user
+		if documentUser != "" {
+			return "", fmt.Errorf("runAsUser is not supported by %v", p.Name)
+		}
+		return "", nil
+	}
+	if agentUser, err := currentUser(); err == nil && agentUser.Username == runAsUser {
+		return "", nil
+	}
+	if _, err := lookupUser(runAsUser); err != nil {
+		return "", fmt.Errorf("RunAs user %v does not exist: %v", runAsUser, err)
+	}
+	return runAsUser, nil
+}
+
+// containsUser returns true if the list contains the user name
+func containsUser(userNames []string, userName string) bool {
+	for _, name := range userNames {
+		if strings.TrimSpace(name) == userName {
+			return true
+		}
+	}
+	return false
+}
+
+// shareScriptWithUser copies the script to a new temporary directory owned by the user, as the orchestration
+// directory is only accessible to the agent, and returns the path of the copy.
+func shareScriptWithUser(scriptPath string, userName string) (sharedPath string, err error) {
+	uid, gid, err := userIDs(userName)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := ioutil.ReadFile(scriptPath)
+	if err != nil {
+		return "", err
+	}
+	dir, err := ioutil.TempDir("", runAsScriptDirPrefix)
+	if err != nil {
+		return "", err
+	}
+	sharedPath = filepath.Join(dir, filepath.Base(scriptPath))
+	if err = ioutil.WriteFile(sharedPath, content, appconfig.ReadWriteExecuteAccess); err == nil {
+		if err = os.Chown(sharedPath, uid, gid); err == nil {
+			err = os.Chown(dir, uid, gid)
+		}
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("unable to share the script with user %v: %v", userName, err)
+	}
+	return sharedPath, nil
+}
+
+// userIDs returns the uid and gid of the user
+func userIDs(userName string) (uid int, gid int, err error) {
+	u, err := lookupUser(userName)
+	if err != nil {
+		return 0, 0, err
+	}
+	if uid, err = strconv.Atoi(u.Uid); err != nil {
+		return 0, 0, fmt.Errorf("invalid uid %v of user %v", u.Uid, userName)
+	}
+	if gid, err = strconv.Atoi(u.Gid); err != nil {
+		return 0, 0, fmt.Errorf("invalid gid %v of user %v", u.Gid, userName)
+	}
+	return uid, gid, nil
+}
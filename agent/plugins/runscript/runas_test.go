@@ -0,0 +1,135 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runscript
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	iohandlermocks "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
+	multiwritermock "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/multiwriter/mock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// setUsersMock makes the users of the tests exist with the uid and gid of the test process, and the agent run as root
+func setUsersMock() {
+	lookupUser = func(userName string) (*user.User, error) {
+		if userName == "missing-user" {
+			return nil, user.UnknownUserError(userName)
+		}
+		return &user.User{Username: userName, Uid: fmt.Sprint(os.Getuid()), Gid: fmt.Sprint(os.Getgid())}, nil
+	}
+	currentUser = func() (*user.User, error) {
+		return &user.User{Username: "root", Uid: "0", Gid: "0"}, nil
+	}
+}
+
+func restoreUsers() {
+	lookupUser = user.Lookup
+	currentUser = user.Current
+}
+
+func TestSelectRunAsUser(t *testing.T) {
+	setUsersMock()
+	defer restoreUsers()
+
+	p := &Plugin{Name: appconfig.PluginNameAwsRunShellScript, SupportsRunAs: true}
+	testCases := []struct {
+		ssmConfig    appconfig.SsmCfg
+		documentUser string
+		expectedUser string
+		expectError  bool
+	}{
+		// without configuration scripts run as the agent user
+		{appconfig.SsmCfg{}, "", "", false},
+		{appconfig.SsmCfg{RunCommandDefaultRunAsUser: "ssm-user"}, "", "ssm-user", false},
+		{appconfig.SsmCfg{RunCommandDefaultRunAsUser: "ssm-user"}, "ssm-user", "ssm-user", false},
+		// documents may only choose the allowed users
+		{appconfig.SsmCfg{RunCommandDefaultRunAsUser: "ssm-user"}, "root", "", true},
+		{appconfig.SsmCfg{RunCommandDefaultRunAsUser: "ssm-user", RunCommandRunAsAllowedUsers: []string{"root"}}, "root", "", false},
+		{appconfig.SsmCfg{RunCommandRunAsAllowedUsers: []string{"app-deployer"}}, "app-deployer", "app-deployer", false},
+		{appconfig.SsmCfg{RunCommandRunAsAllowedUsers: []string{"app-deployer"}}, "APP-DEPLOYER", "", true},
+		{appconfig.SsmCfg{RunCommandRunAsAllowedUsers: []string{"missing-user"}}, "missing-user", "", true},
+		{appconfig.SsmCfg{RunCommandDefaultRunAsUser: "missing-user"}, "", "", true},
+	}
+	for _, testCase := range testCases {
+		runAsUser, err := p.selectRunAsUser(testCase.ssmConfig, testCase.documentUser)
+		assert.Equal(t, testCase.expectError, err != nil, "%+v", testCase)
+		assert.Equal(t, testCase.expectedUser, runAsUser, "%+v", testCase)
+	}
+}
+
+func TestSelectRunAsUser_NotSupported(t *testing.T) {
+	p := &Plugin{Name: appconfig.PluginNameAwsRunPowerShellScript}
+
+	runAsUser, err := p.selectRunAsUser(appconfig.SsmCfg{RunCommandDefaultRunAsUser: "ssm-user"}, "")
+	assert.NoError(t, err)
+	assert.Empty(t, runAsUser)
+
+	_, err = p.selectRunAsUser(appconfig.SsmCfg{RunCommandRunAsAllowedUsers: []string{"Administrator"}}, "Administrator")
+	assert.Error(t, err)
+}
+
+func TestRunCommandsAsUser(t *testing.T) {
+	setUsersMock()
+	defer restoreUsers()
+	orchestrationDir, _ := ioutil.TempDir("", "runscript")
+	defer os.RemoveAll(orchestrationDir)
+
+	mockCancelFlag := new(task.MockCancelFlag)
+	mockExecuter := new(executers.MockCommandExecuter)
+	mockIOHandler := new(iohandlermocks.MockIOHandler)
+	p := &Plugin{CommandExecuter: mockExecuter, Name: appconfig.PluginNameAwsRunShellScript, ScriptName: "_script.sh", ShellCommand: "sh", ShellArguments: []string{"-c"},
+		ByteOrderMark: fileutil.ByteOrderMarkSkip, SupportsRunAs: true}
+
+	stdout := new(multiwritermock.MockDocumentIOMultiWriter)
+	stderr := new(multiwritermock.MockDocumentIOMultiWriter)
+	mockIOHandler.On("GetStdoutWriter").Return(stdout)
+	mockIOHandler.On("GetStderrWriter").Return(stderr)
+	mockIOHandler.On("SetExitCode", 0).Return()
+	mockIOHandler.On("SetStatus", contracts.ResultStatusSuccess).Return()
+	mockCancelFlag.On("Canceled").Return(false)
+	mockCancelFlag.On("ShutDown").Return(false)
+
+	var sharedScript string
+	mockExecuter.On("NewExecuteAsUser", mock.Anything, mock.Anything, stdout, stderr, mockCancelFlag, mock.Anything, "sh", mock.Anything,
+		[]string{}, "ssm-user").Return(0, nil).Run(func(args mock.Arguments) {
+		commandArguments := args.Get(7).([]string)
+		sharedScript = commandArguments[len(commandArguments)-1]
+		content, err := ioutil.ReadFile(sharedScript)
+		assert.NoError(t, err)
+		assert.Equal(t, "whoami\n", string(content))
+	})
+
+	input := RunScriptPluginInput{RunCommand: []string{"whoami"}, ID: "0.aws:runShellScript"}
+	p.runCommands(log.NewMockLog(), "aws:runShellScript", input, orchestrationDir, orchestrationDir, "ssm-user", mockCancelFlag, mockIOHandler)
+
+	mockExecuter.AssertExpectations(t)
+	mockIOHandler.AssertExpectations(t)
+	assert.False(t, strings.HasPrefix(sharedScript, orchestrationDir))
+	_, err := os.Stat(filepath.Dir(sharedScript))
+	assert.True(t, os.IsNotExist(err))
+}
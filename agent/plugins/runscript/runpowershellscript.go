@@ -41,6 +41,8 @@ func NewRunPowerShellPlugin() (*runPowerShellPlugin, error) {
 			ShellArguments:  strings.Split(appconfig.PowerShellPluginCommandArgs, " "),
 			ByteOrderMark:   fileutil.ByteOrderMarkEmit,
 			CommandExecuter: executers.ShellCommandExecuter{},
+
+			SupportsPowerShellVersion: true,
 		},
 	}
 
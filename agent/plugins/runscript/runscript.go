@@ -16,6 +16,7 @@ package runscript
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 
 	"strings"
@@ -45,6 +46,12 @@ type Plugin struct {
 	ShellCommand   string
 	ShellArguments []string
 	ByteOrderMark  fileutil.ByteOrderMark
+	// SupportsResourceLimits is true if the plugin can run scripts in a systemd scope with ResourceLimits
+	SupportsResourceLimits bool
+	// SupportsPowerShellVersion is true if the plugin can run scripts with the PowerShell engine chosen by PowerShellVersion
+	SupportsPowerShellVersion bool
+	// SupportsRunAs is true if the plugin can run scripts as other users than the agent user
+	SupportsRunAs bool
 }
 
 // RunScriptPluginInput represents one set of commands executed by the RunScript plugin.
@@ -54,6 +61,18 @@ type RunScriptPluginInput struct {
 	ID               string
 	WorkingDirectory string
 	TimeoutSeconds   interface{}
+	ResourceLimits   ResourceLimits
+	// PowerShellVersion selects Windows PowerShell 5.1 or PowerShell 7 to run the commands of runPowerShellScript
+	PowerShellVersion string
+	// Environment are environment variables set for the commands, so that parameters need not be interpolated into them
+	Environment map[string]string
+	// CreateWorkingDirectory creates the absolute WorkingDirectory if it does not exist instead of running in the default directory
+	CreateWorkingDirectory bool
+	// WorkingDirectoryOwner owns a created working directory, by default the user the commands run as,
+	// which is also the only owner permitted when the commands run as another user than the agent
+	WorkingDirectoryOwner string
+	// WorkingDirectoryMode are the octal permissions of a created working directory, by default 0755
+	WorkingDirectoryMode string
 }
 
 // Execute runs multiple sets of commands and returns their outputs.
@@ -67,14 +86,16 @@ func (p *Plugin) Execute(context context.T, config contracts.Configuration, canc
 		output.MarkAsShutdown()
 	} else if cancelFlag.Canceled() {
 		output.MarkAsCancelled()
+	} else if runAsUser, err := p.selectRunAsUser(context.AppConfig().Ssm, config.RunAsUser); err != nil {
+		output.MarkAsFailed(err)
 	} else {
-		p.runCommandsRawInput(log, config.PluginID, config.Properties, config.OrchestrationDirectory, config.DefaultWorkingDirectory, cancelFlag, output)
+		p.runCommandsRawInput(log, config.PluginID, config.Properties, config.OrchestrationDirectory, config.DefaultWorkingDirectory, runAsUser, cancelFlag, output)
 	}
 }
 
 // runCommandsRawInput executes one set of commands and returns their output.
 // The input is in the default json unmarshal format (e.g. map[string]interface{}).
-func (p *Plugin) runCommandsRawInput(log log.T, pluginID string, rawPluginInput interface{}, orchestrationDirectory string, defaultWorkingDirectory string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+func (p *Plugin) runCommandsRawInput(log log.T, pluginID string, rawPluginInput interface{}, orchestrationDirectory string, defaultWorkingDirectory string, runAsUser string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
 	var pluginInput RunScriptPluginInput
 	err := jsonutil.Remarshal(rawPluginInput, &pluginInput)
 	if err != nil {
@@ -82,16 +103,47 @@ func (p *Plugin) runCommandsRawInput(log log.T, pluginID string, rawPluginInput
 		output.MarkAsFailed(errorString)
 		return
 	}
-	p.runCommands(log, pluginID, pluginInput, orchestrationDirectory, defaultWorkingDirectory, cancelFlag, output)
+	p.runCommands(log, pluginID, pluginInput, orchestrationDirectory, defaultWorkingDirectory, runAsUser, cancelFlag, output)
 }
 
 // runCommands executes one set of commands and returns their output.
-func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPluginInput, orchestrationDirectory string, defaultWorkingDirectory string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+// The commands run as runAsUser, or as the agent user if it is empty.
+func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPluginInput, orchestrationDirectory string, defaultWorkingDirectory string, runAsUser string, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
 	var err error
 	var workingDir string
 
+	if err = p.checkResourceLimits(pluginInput.ResourceLimits); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	commandName, err := p.selectShellCommand(pluginInput.PowerShellVersion)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
+	environment, err := environmentVariables(pluginInput.Environment)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+
 	if filepath.IsAbs(pluginInput.WorkingDirectory) {
 		workingDir = pluginInput.WorkingDirectory
+		if err = validateWorkingDirectory(workingDir); err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+		if pluginInput.CreateWorkingDirectory {
+			if err = createWorkingDirectory(log, workingDir, pluginInput.WorkingDirectoryOwner, runAsUser, pluginInput.WorkingDirectoryMode); err != nil {
+				output.MarkAsFailed(err)
+				return
+			}
+		}
+	} else if pluginInput.CreateWorkingDirectory {
+		output.MarkAsFailed(fmt.Errorf("createWorkingDirectory requires an absolute workingDirectory, got %q", pluginInput.WorkingDirectory))
+		return
 	} else {
 		orchestrationDir := strings.TrimSuffix(orchestrationDirectory, pluginID)
 		// The Document path is expected to have the name of the document
@@ -121,15 +173,48 @@ func (p *Plugin) runCommands(log log.T, pluginID string, pluginInput RunScriptPl
 		return
 	}
 
+	if runAsUser != "" {
+		log.Infof("Running commands as user %v", runAsUser)
+		if scriptPath, err = shareScriptWithUser(scriptPath, runAsUser); err != nil {
+			output.MarkAsFailed(err)
+			return
+		}
+		defer os.RemoveAll(filepath.Dir(scriptPath))
+	}
+
 	// Set execution time
 	executionTimeout := pluginutil.ValidateExecutionTimeout(log, pluginInput.TimeoutSeconds)
 
-	// Construct Command Name and Arguments
-	commandName := p.ShellCommand
+	// Construct Command Arguments
 	commandArguments := append(p.ShellArguments, scriptPath)
+	if pluginInput.ResourceLimits.isSet() {
+		log.Infof("Running commands with resource limits %+v", pluginInput.ResourceLimits)
+		commandArguments = pluginInput.ResourceLimits.systemdRunArguments(commandName, commandArguments)
+		commandName = systemdRunCommand
+		if runAsUser != "" {
+			// systemd-run needs the privileges of the agent, it starts the scope as the user itself
+			commandArguments = append([]string{"--uid=" + runAsUser}, commandArguments...)
+			runAsUser = ""
+		}
+	}
 
 	// Execute Command
-	exitCode, err := p.CommandExecuter.NewExecute(log, workingDir, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, commandName, commandArguments)
+	var exitCode int
+	if runAsUser != "" {
+		if runAsExecuter, ok := p.CommandExecuter.(executers.RunAsExecuter); ok {
+			exitCode, err = runAsExecuter.NewExecuteAsUser(log, workingDir, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, commandName, commandArguments, environment, runAsUser)
+		} else {
+			output.MarkAsFailed(fmt.Errorf("%v cannot run commands as another user", p.Name))
+			return
+		}
+	} else if len(environment) == 0 {
+		exitCode, err = p.CommandExecuter.NewExecute(log, workingDir, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, commandName, commandArguments)
+	} else if environmentExecuter, ok := p.CommandExecuter.(executers.EnvironmentExecuter); ok {
+		exitCode, err = environmentExecuter.NewExecuteWithEnvironment(log, workingDir, output.GetStdoutWriter(), output.GetStderrWriter(), cancelFlag, executionTimeout, commandName, commandArguments, environment)
+	} else {
+		output.MarkAsFailed(fmt.Errorf("%v cannot set environment variables", p.Name))
+		return
+	}
 
 	// Set output status
 	output.SetExitCode(exitCode)
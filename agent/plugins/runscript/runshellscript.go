@@ -41,6 +41,9 @@ func NewRunShellPlugin(log log.T) (*runShellPlugin, error) {
 			ShellArguments:  shellArgs,
 			ByteOrderMark:   fileutil.ByteOrderMarkSkip,
 			CommandExecuter: executers.ShellCommandExecuter{},
+
+			SupportsResourceLimits: true,
+			SupportsRunAs:          true,
 		},
 	}
 
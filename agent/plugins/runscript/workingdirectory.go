@@ -0,0 +1,163 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runscript
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// defaultWorkingDirectoryMode is the mode of created working directories when the input sets none
+const defaultWorkingDirectoryMode os.FileMode = 0755
+
+// forbiddenWorkingDirectories returns the directories of the agent which commands may not run in.
+// It is a function as the directories are only known after the appconfig package is initialized on Windows.
+var forbiddenWorkingDirectories = func() []string {
+	return []string{appconfig.DefaultDataStorePath, appconfig.DefaultProgramFolder, appconfig.EC2ConfigDataStorePath}
+}
+
+// validateWorkingDirectory returns an error if the absolute working directory is inside a directory of the agent.
+// Symbolic links are resolved so that links to the directories of the agent are rejected as well.
+func validateWorkingDirectory(workingDir string) error {
+	resolvedDir := resolvePath(workingDir)
+	for _, forbiddenDir := range forbiddenWorkingDirectories() {
+		if forbiddenDir == "" {
+			continue
+		}
+		if isInside(resolvedDir, resolvePath(forbiddenDir)) {
+			return fmt.Errorf("working directory %v is not permitted, it is inside the agent directory %v", workingDir, forbiddenDir)
+		}
+	}
+	return nil
+}
+
+// resolvePath returns the absolute path with the symbolic links of its longest existing ancestor resolved
+func resolvePath(path string) string {
+	path = filepath.Clean(path)
+	missing := ""
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(resolved, missing)
+		}
+		if filepath.Dir(dir) == dir {
+			return path
+		}
+		missing = filepath.Join(filepath.Base(dir), missing)
+	}
+}
+
+// isInside returns true if the path is the directory or inside it
+func isInside(path string, dir string) bool {
+	if runtime.GOOS == "windows" {
+		path, dir = strings.ToLower(path), strings.ToLower(dir)
+	}
+	relative, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator))
+}
+
+// parseWorkingDirectoryMode returns the permissions of the octal mode, or the default mode if it is empty
+func parseWorkingDirectoryMode(mode string) (os.FileMode, error) {
+	if mode = strings.TrimSpace(mode); mode == "" {
+		return defaultWorkingDirectoryMode, nil
+	}
+	value, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || value > uint64(os.ModePerm) {
+		return 0, fmt.Errorf("invalid workingDirectoryMode %v, it must be octal permissions such as 0755", mode)
+	}
+	return os.FileMode(value), nil
+}
+
+// createWorkingDirectory creates the working directory with the mode and owner if it does not exist.
+// Existing directories are left as they are; the owner is the agent user if it is empty.
+// When the commands run as runAsUser, the directory is owned by that user and is only created where the user
+// could create it, so that documents confined to the user cannot have the agent create directories for them
+// in places only root may write to.
+func createWorkingDirectory(log log.T, workingDir string, owner string, runAsUser string, mode string) error {
+	permissions, err := parseWorkingDirectoryMode(mode)
+	if err != nil {
+		return err
+	}
+	if runAsUser != "" {
+		if owner != "" && owner != runAsUser {
+			return fmt.Errorf("workingDirectoryOwner %v is not permitted, the commands run as %v which must own the working directory", owner, runAsUser)
+		}
+		owner = runAsUser
+	}
+	if info, err := os.Stat(workingDir); err == nil {
+		if !info.IsDir() {
+			return fmt.Errorf("working directory %v is not a directory", workingDir)
+		}
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if owner != "" && runtime.GOOS == "windows" {
+		return fmt.Errorf("workingDirectoryOwner is not supported on %v", runtime.GOOS)
+	}
+
+	uid, gid := -1, -1
+	if owner != "" {
+		if uid, gid, err = userIDs(owner); err != nil {
+			return fmt.Errorf("invalid workingDirectoryOwner %v: %v", owner, err)
+		}
+	}
+	missingDirs, parentDir := missingDirectories(workingDir)
+	if runAsUser != "" {
+		parentInfo, err := os.Stat(parentDir)
+		if err != nil {
+			return err
+		}
+		if !parentInfo.IsDir() || !isWritableBy(parentInfo, uid, gid) {
+			return fmt.Errorf("working directory %v cannot be created, %v is not writable by user %v", workingDir, parentDir, runAsUser)
+		}
+	}
+
+	log.Infof("Creating working directory %v", workingDir)
+	if err = os.MkdirAll(workingDir, permissions); err != nil {
+		return fmt.Errorf("failed to create working directory %v: %v", workingDir, err)
+	}
+	// the mode given to MkdirAll is reduced by the umask
+	if err = os.Chmod(workingDir, permissions); err != nil {
+		return fmt.Errorf("failed to set the mode of working directory %v: %v", workingDir, err)
+	}
+	if owner != "" {
+		for _, dir := range missingDirs {
+			if err = os.Chown(dir, uid, gid); err != nil {
+				return fmt.Errorf("failed to set the owner of working directory %v: %v", dir, err)
+			}
+		}
+	}
+	return nil
+}
+
+// missingDirectories returns the directories of the path which do not exist, starting with the path,
+// and the longest existing ancestor of the path
+func missingDirectories(path string) (missing []string, existing string) {
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		if _, err := os.Lstat(dir); err == nil || filepath.Dir(dir) == dir {
+			return missing, dir
+		}
+		missing = append(missing, dir)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package runscript
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/executers"
+	iohandlermocks "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// setForbiddenDirectoryMock makes the directory the only forbidden working directory
+func setForbiddenDirectoryMock(dir string) {
+	forbiddenWorkingDirectories = func() []string {
+		return []string{dir, ""}
+	}
+}
+
+func restoreForbiddenDirectories() {
+	forbiddenWorkingDirectories = func() []string {
+		return []string{appconfig.DefaultDataStorePath, appconfig.DefaultProgramFolder, appconfig.EC2ConfigDataStorePath}
+	}
+}
+
+func TestValidateWorkingDirectory(t *testing.T) {
+	root, _ := ioutil.TempDir("", "workingdirectory")
+	defer os.RemoveAll(root)
+	agentDir := filepath.Join(root, "agent")
+	os.MkdirAll(filepath.Join(agentDir, "data"), appconfig.ReadWriteExecuteAccess)
+	setForbiddenDirectoryMock(agentDir + string(filepath.Separator))
+	defer restoreForbiddenDirectories()
+
+	assert.Error(t, validateWorkingDirectory(agentDir))
+	assert.Error(t, validateWorkingDirectory(filepath.Join(agentDir, "data")))
+	assert.Error(t, validateWorkingDirectory(filepath.Join(agentDir, "missing", "dir")))
+	assert.Error(t, validateWorkingDirectory(filepath.Join(root, "other", "..", "agent", "data")))
+	assert.NoError(t, validateWorkingDirectory(root))
+	assert.NoError(t, validateWorkingDirectory(filepath.Join(root, "agent-scripts")))
+	assert.NoError(t, validateWorkingDirectory(filepath.Join(root, "..agent")))
+
+	if runtime.GOOS != "windows" {
+		link := filepath.Join(root, "link")
+		assert.NoError(t, os.Symlink(agentDir, link))
+		assert.Error(t, validateWorkingDirectory(link))
+		assert.Error(t, validateWorkingDirectory(filepath.Join(link, "data", "new")))
+	}
+}
+
+func TestParseWorkingDirectoryMode(t *testing.T) {
+	testCases := []struct {
+		mode        string
+		expected    os.FileMode
+		expectError bool
+	}{
+		{"", defaultWorkingDirectoryMode, false},
+		{"0700", 0700, false},
+		{"750", 0750, false},
+		{"1777", 0, true},
+		{"0789", 0, true},
+		{"rwx", 0, true},
+	}
+	for _, testCase := range testCases {
+		mode, err := parseWorkingDirectoryMode(testCase.mode)
+		assert.Equal(t, testCase.expectError, err != nil, testCase.mode)
+		assert.Equal(t, testCase.expected, mode, testCase.mode)
+	}
+}
+
+func TestCreateWorkingDirectory(t *testing.T) {
+	root, _ := ioutil.TempDir("", "workingdirectory")
+	defer os.RemoveAll(root)
+	workingDir := filepath.Join(root, "scripts", "app")
+
+	assert.NoError(t, createWorkingDirectory(log.NewMockLog(), workingDir, "", "", "0700"))
+	info, err := os.Stat(workingDir)
+	assert.NoError(t, err)
+	assert.True(t, info.IsDir())
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+	}
+
+	// existing directories are left as they are
+	assert.NoError(t, createWorkingDirectory(log.NewMockLog(), workingDir, "", "", "0755"))
+	info, _ = os.Stat(workingDir)
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+	}
+
+	file := filepath.Join(root, "file")
+	ioutil.WriteFile(file, []byte{}, appconfig.ReadWriteAccess)
+	assert.Error(t, createWorkingDirectory(log.NewMockLog(), file, "", "", ""))
+	assert.Error(t, createWorkingDirectory(log.NewMockLog(), filepath.Join(root, "invalid"), "", "", "888"))
+}
+
+func TestCreateWorkingDirectory_Owner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ownership is not supported on windows")
+	}
+	setUsersMock()
+	defer restoreUsers()
+	root, _ := ioutil.TempDir("", "workingdirectory")
+	defer os.RemoveAll(root)
+
+	assert.NoError(t, createWorkingDirectory(log.NewMockLog(), filepath.Join(root, "owned"), "ssm-user", "", ""))
+	assert.Error(t, createWorkingDirectory(log.NewMockLog(), filepath.Join(root, "missing"), "missing-user", "", ""))
+}
+
+func TestRunCommandsWorkingDirectoryFailures(t *testing.T) {
+	root, _ := ioutil.TempDir("", "workingdirectory")
+	defer os.RemoveAll(root)
+	setForbiddenDirectoryMock(filepath.Join(root, "agent"))
+	defer restoreForbiddenDirectories()
+
+	p := &Plugin{CommandExecuter: new(executers.MockCommandExecuter), Name: appconfig.PluginNameAwsRunShellScript, ScriptName: "_script.sh", ShellCommand: "sh"}
+	inputs := []RunScriptPluginInput{
+		{RunCommand: []string{"ls"}, WorkingDirectory: filepath.Join(root, "agent", "data")},
+		{RunCommand: []string{"ls"}, WorkingDirectory: filepath.Join(root, "agent", "new"), CreateWorkingDirectory: true},
+		{RunCommand: []string{"ls"}, WorkingDirectory: "relative", CreateWorkingDirectory: true},
+		{RunCommand: []string{"ls"}, WorkingDirectory: filepath.Join(root, "new"), CreateWorkingDirectory: true, WorkingDirectoryMode: "u+rwx"},
+	}
+	for _, input := range inputs {
+		mockIOHandler := new(iohandlermocks.MockIOHandler)
+		mockIOHandler.On("MarkAsFailed", mock.Anything).Return()
+		p.runCommands(log.NewMockLog(), "aws:runShellScript", input, root, root, "", new(task.MockCancelFlag), mockIOHandler)
+		mockIOHandler.AssertCalled(t, "MarkAsFailed", mock.Anything)
+	}
+	_, err := os.Stat(filepath.Join(root, "agent"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(root, "new"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,38 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build darwin freebsd linux netbsd openbsd
+
+package runscript
+
+import (
+	"os"
+	"syscall"
+)
+
+// isWritableBy returns true if the permissions of the file let the user with the uid and gid write to it
+func isWritableBy(info os.FileInfo, uid int, gid int) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+	mode := info.Mode().Perm()
+	switch {
+	case int(stat.Uid) == uid:
+		return mode&0200 != 0
+	case int(stat.Gid) == gid:
+		return mode&0020 != 0
+	default:
+		return mode&0002 != 0
+	}
+}
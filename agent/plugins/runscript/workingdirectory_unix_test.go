@@ -0,0 +1,62 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build darwin freebsd linux netbsd openbsd
+
+package runscript
+
+import (
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateWorkingDirectory_RunAsUser(t *testing.T) {
+	lookupUser = func(userName string) (*user.User, error) {
+		return &user.User{Username: userName, Uid: "12345", Gid: "12345"}, nil
+	}
+	defer restoreUsers()
+	root, _ := ioutil.TempDir("", "workingdirectory")
+	defer os.RemoveAll(root)
+	os.Chmod(root, 0755)
+
+	// the directory may only be owned by the user the commands run as
+	err := createWorkingDirectory(log.NewMockLog(), filepath.Join(root, "other"), "root", "ssm-user", "0777")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "workingDirectoryOwner root is not permitted")
+
+	// the agent does not create directories where the user could not
+	err = createWorkingDirectory(log.NewMockLog(), filepath.Join(root, ".ssh"), "", "ssm-user", "0777")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is not writable by user ssm-user")
+	_, err = os.Stat(filepath.Join(root, ".ssh"))
+	assert.True(t, os.IsNotExist(err))
+
+	if os.Getuid() != 0 {
+		t.Skip("changing the owner of directories requires root")
+	}
+	os.Chmod(root, 0777)
+	workingDir := filepath.Join(root, "scripts", "app")
+	assert.NoError(t, createWorkingDirectory(log.NewMockLog(), workingDir, "ssm-user", "ssm-user", "0700"))
+	for _, dir := range []string{workingDir, filepath.Dir(workingDir)} {
+		info, err := os.Stat(dir)
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(12345), info.Sys().(*syscall.Stat_t).Uid, dir)
+	}
+}
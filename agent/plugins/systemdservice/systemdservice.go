@@ -0,0 +1,232 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package systemdservice implements the aws:systemdService plugin
+package systemdservice
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	downloadsDir = "downloads" //Directory under the orchestration directory where the downloaded resource resides
+
+	systemctlCommand = "systemctl"
+	// unitFileDir is the directory of the unit files installed by the administrator
+	unitFileDir = "/etc/systemd/system"
+	// unitFileAccess is the permission of installed unit files
+	unitFileAccess = 0644
+
+	// actions applied to the unit
+	ActionStart   = "start"
+	ActionStop    = "stop"
+	ActionRestart = "restart"
+	ActionReload  = "reload"
+	ActionEnable  = "enable"
+	ActionDisable = "disable"
+)
+
+var unitNameRegex = regexp.MustCompile(`^[A-Za-z0-9:_.@\\-]+$`)
+
+// Assign method to global variables to allow unittest to override
+var runSystemctl = systemctl
+var unitFilePath = installedUnitFilePath
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	return &plugin, nil
+}
+
+// Plugin is the type for the aws:systemdService plugin.
+type Plugin struct {
+}
+
+// SystemdServicePluginInput represents the unit managed by the aws:systemdService plugin
+type SystemdServicePluginInput struct {
+	contracts.PluginInput
+	// Name is the name of the unit, .service is appended if it has no unit type suffix
+	Name string `json:"name"`
+	// Actions are applied to the unit in order
+	Actions []string `json:"actions"`
+	// DaemonReload reloads the systemd configuration before the actions
+	DaemonReload bool `json:"daemonReload"`
+	// UnitFile is installed to /etc/systemd/system before the actions
+	UnitFile *UnitFile `json:"unitFile"`
+}
+
+// UnitFile is the unit file installed by the plugin
+type UnitFile struct {
+	// Content is the content of the unit file
+	Content string `json:"content"`
+	// SourcePath is the path of the unit file, absolute or relative to the directory aws:downloadContent downloads to
+	SourcePath string `json:"sourcePath"`
+	// Sha256 is the expected checksum of the unit file
+	Sha256 string `json:"sha256"`
+}
+
+// Name returns the plugin name
+func Name() string {
+	return appconfig.PluginNameAwsSystemdService
+}
+
+// Execute manages the unit and reports its ActiveState.
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started with configuration %v", Name(), config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+	} else if input, err := parseAndValidateInput(config.Properties); err != nil {
+		output.MarkAsFailed(err)
+	} else if err = p.manageUnit(log, input, config, output); err != nil {
+		output.MarkAsFailed(err)
+	} else {
+		output.MarkAsSucceeded()
+	}
+}
+
+// parseAndValidateInput parses the plugin properties and validates the unit name, actions and unit file
+func parseAndValidateInput(rawPluginInput interface{}) (*SystemdServicePluginInput, error) {
+	var input SystemdServicePluginInput
+	if err := jsonutil.Remarshal(rawPluginInput, &input); err != nil {
+		return nil, fmt.Errorf("invalid format in plugin properties %v; \nerror %v", rawPluginInput, err)
+	}
+
+	if !unitNameRegex.MatchString(input.Name) || strings.HasPrefix(input.Name, "-") {
+		return nil, fmt.Errorf("invalid input: invalid unit name %q", input.Name)
+	}
+	if filepath.Ext(input.Name) == "" {
+		input.Name = input.Name + ".service"
+	}
+	for _, action := range input.Actions {
+		switch action {
+		case ActionStart, ActionStop, ActionRestart, ActionReload, ActionEnable, ActionDisable:
+		default:
+			return nil, fmt.Errorf("invalid input: unknown action %q", action)
+		}
+	}
+	if input.UnitFile != nil && (input.UnitFile.Content == "") == (input.UnitFile.SourcePath == "") {
+		return nil, fmt.Errorf("invalid input: exactly one of content and sourcePath must be set for the unit file")
+	}
+	return &input, nil
+}
+
+// manageUnit installs the unit file, applies the actions and reports the ActiveState of the unit.
+func (p *Plugin) manageUnit(log log.T, input *SystemdServicePluginInput, config contracts.Configuration, output iohandler.IOHandler) error {
+	daemonReload := input.DaemonReload
+	if input.UnitFile != nil {
+		changed, err := installUnitFile(log, input.Name, input.UnitFile, config)
+		if err != nil {
+			return err
+		}
+		if changed {
+			output.AppendInfof("Installed unit file %v", unitFilePath(input.Name))
+			daemonReload = true
+		} else {
+			output.AppendInfof("Unit file %v is up to date", unitFilePath(input.Name))
+		}
+	}
+
+	if daemonReload {
+		if _, err := runSystemctl(log, "daemon-reload"); err != nil {
+			return err
+		}
+		output.AppendInfo("Reloaded the systemd configuration")
+	}
+
+	for _, action := range input.Actions {
+		if _, err := runSystemctl(log, action, input.Name); err != nil {
+			return err
+		}
+		output.AppendInfof("Ran %v on %v", action, input.Name)
+	}
+
+	activeState, err := runSystemctl(log, "show", "--property=ActiveState", input.Name)
+	if err != nil {
+		return err
+	}
+	output.AppendInfof("ActiveState: %v", strings.TrimPrefix(strings.TrimSpace(activeState), "ActiveState="))
+	return nil
+}
+
+// installUnitFile writes the unit file to the unit file directory if its content differs, and returns whether it did.
+func installUnitFile(log log.T, unitName string, unitFile *UnitFile, config contracts.Configuration) (changed bool, err error) {
+	content := []byte(unitFile.Content)
+	if unitFile.SourcePath != "" {
+		sourcePath := unitFile.SourcePath
+		if !filepath.IsAbs(sourcePath) {
+			sourcePath = filepath.Join(strings.TrimSuffix(config.OrchestrationDirectory, config.PluginID), downloadsDir, sourcePath)
+		}
+		if content, err = ioutil.ReadFile(sourcePath); err != nil {
+			return false, fmt.Errorf("failed to read unit file %v: %v", sourcePath, err)
+		}
+	}
+
+	if unitFile.Sha256 != "" {
+		checksum := sha256.Sum256(content)
+		if actual := hex.EncodeToString(checksum[:]); !strings.EqualFold(actual, unitFile.Sha256) {
+			return false, fmt.Errorf("checksum %v of the unit file does not match the expected checksum %v", actual, unitFile.Sha256)
+		}
+	}
+
+	path := unitFilePath(unitName)
+	if existing, err := ioutil.ReadFile(path); err == nil && bytes.Equal(existing, content) {
+		log.Debugf("Unit file %v is up to date", path)
+		return false, nil
+	}
+
+	log.Infof("Installing unit file %v", path)
+	if err = ioutil.WriteFile(path, content, unitFileAccess); err != nil {
+		return false, fmt.Errorf("failed to write unit file %v: %v", path, err)
+	}
+	// WriteFile keeps the permission of an existing file
+	if err = os.Chmod(path, unitFileAccess); err != nil {
+		return false, fmt.Errorf("failed to set the permission of unit file %v: %v", path, err)
+	}
+	return true, nil
+}
+
+// installedUnitFilePath returns the path the unit file of the unit is installed to.
+func installedUnitFilePath(unitName string) string {
+	return filepath.Join(unitFileDir, unitName)
+}
+
+// systemctl runs systemctl with the arguments and returns its output, or an error including the output if it fails.
+func systemctl(log log.T, arguments ...string) (string, error) {
+	log.Debugf("Running %v %v", systemctlCommand, strings.Join(arguments, " "))
+	out, err := exec.Command(systemctlCommand, arguments...).CombinedOutput()
+	if err != nil {
+		return string(out), fmt.Errorf("%v %v failed: %v %v", systemctlCommand, strings.Join(arguments, " "), err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
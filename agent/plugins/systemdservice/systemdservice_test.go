@@ -0,0 +1,139 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package systemdservice implements the aws:systemdService plugin
+package systemdservice
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleUnitFile = "[Unit]\nDescription=Sample\n\n[Service]\nExecStart=/usr/bin/sample\n"
+
+var logger = log.NewMockLog()
+
+// stubSystemd records the systemctl commands and installs unit files to a temporary directory.
+func stubSystemd(t *testing.T) (commands *[]string, unitDir string, restore func()) {
+	unitDir, err := ioutil.TempDir("", "systemdservice")
+	assert.NoError(t, err)
+	commands = &[]string{}
+	runSystemctl = func(log log.T, arguments ...string) (string, error) {
+		*commands = append(*commands, strings.Join(arguments, " "))
+		if arguments[0] == "show" {
+			return "ActiveState=active\n", nil
+		}
+		return "", nil
+	}
+	unitFilePath = func(unitName string) string { return filepath.Join(unitDir, unitName) }
+	return commands, unitDir, func() {
+		runSystemctl = systemctl
+		unitFilePath = installedUnitFilePath
+		os.RemoveAll(unitDir)
+	}
+}
+
+func TestParseAndValidateInput(t *testing.T) {
+	input, err := parseAndValidateInput(map[string]interface{}{"name": "nginx", "actions": []interface{}{"enable", "start"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "nginx.service", input.Name)
+	assert.Equal(t, []string{"enable", "start"}, input.Actions)
+
+	input, err = parseAndValidateInput(map[string]interface{}{"name": "backup.timer"})
+	assert.NoError(t, err)
+	assert.Equal(t, "backup.timer", input.Name)
+
+	invalid := []map[string]interface{}{
+		{"name": ""},
+		{"name": "nginx; reboot"},
+		{"name": "--now"},
+		{"name": "nginx", "actions": []interface{}{"mask"}},
+		{"name": "nginx", "unitFile": map[string]interface{}{}},
+		{"name": "nginx", "unitFile": map[string]interface{}{"content": sampleUnitFile, "sourcePath": "nginx.service"}},
+	}
+	for _, properties := range invalid {
+		_, err = parseAndValidateInput(properties)
+		assert.Error(t, err, "%v", properties)
+	}
+}
+
+func TestManageUnitInstallsUnitFile(t *testing.T) {
+	commands, unitDir, restore := stubSystemd(t)
+	defer restore()
+	checksum := sha256.Sum256([]byte(sampleUnitFile))
+	input := &SystemdServicePluginInput{
+		Name:     "sample.service",
+		Actions:  []string{ActionEnable, ActionRestart},
+		UnitFile: &UnitFile{Content: sampleUnitFile, Sha256: hex.EncodeToString(checksum[:])},
+	}
+
+	output := iohandler.NewDefaultIOHandler(logger, contracts.IOConfiguration{})
+	assert.NoError(t, new(Plugin).manageUnit(logger, input, contracts.Configuration{}, output))
+
+	assert.Equal(t, []string{"daemon-reload", "enable sample.service", "restart sample.service", "show --property=ActiveState sample.service"}, *commands)
+	content, err := ioutil.ReadFile(filepath.Join(unitDir, "sample.service"))
+	assert.NoError(t, err)
+	assert.Equal(t, sampleUnitFile, string(content))
+	assert.Contains(t, output.GetStdout(), "ActiveState: active")
+
+	// installing the same unit file again neither changes it nor reloads the configuration
+	*commands = nil
+	output = iohandler.NewDefaultIOHandler(logger, contracts.IOConfiguration{})
+	assert.NoError(t, new(Plugin).manageUnit(logger, input, contracts.Configuration{}, output))
+	assert.Equal(t, []string{"enable sample.service", "restart sample.service", "show --property=ActiveState sample.service"}, *commands)
+	assert.Contains(t, output.GetStdout(), "is up to date")
+}
+
+func TestManageUnitRejectsChecksumMismatch(t *testing.T) {
+	commands, unitDir, restore := stubSystemd(t)
+	defer restore()
+	input := &SystemdServicePluginInput{
+		Name:     "sample.service",
+		Actions:  []string{ActionStart},
+		UnitFile: &UnitFile{Content: sampleUnitFile, Sha256: strings.Repeat("0", 64)},
+	}
+
+	output := iohandler.NewDefaultIOHandler(logger, contracts.IOConfiguration{})
+	assert.Error(t, new(Plugin).manageUnit(logger, input, contracts.Configuration{}, output))
+
+	assert.Empty(t, *commands)
+	_, err := os.Stat(filepath.Join(unitDir, "sample.service"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestManageUnitReadsDownloadedUnitFile(t *testing.T) {
+	commands, _, restore := stubSystemd(t)
+	defer restore()
+	orchestrationDir, err := ioutil.TempDir("", "orchestration")
+	assert.NoError(t, err)
+	defer os.RemoveAll(orchestrationDir)
+	assert.NoError(t, os.MkdirAll(filepath.Join(orchestrationDir, downloadsDir), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(orchestrationDir, downloadsDir, "sample.service"), []byte(sampleUnitFile), 0600))
+
+	input := &SystemdServicePluginInput{Name: "sample.service", UnitFile: &UnitFile{SourcePath: "sample.service"}}
+	config := contracts.Configuration{OrchestrationDirectory: filepath.Join(orchestrationDir, "installService"), PluginID: "installService"}
+
+	output := iohandler.NewDefaultIOHandler(logger, contracts.IOConfiguration{})
+	assert.NoError(t, new(Plugin).manageUnit(logger, input, config, output))
+	assert.Equal(t, []string{"daemon-reload", "show --property=ActiveState sample.service"}, *commands)
+}
@@ -0,0 +1,283 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package uploadcontent implements the aws:uploadContent plugin
+package uploadcontent
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/s3util"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+)
+
+const (
+	// EncryptionAES256 encrypts the uploaded objects with keys managed by S3
+	EncryptionAES256 = "AES256"
+	// EncryptionKMS encrypts the uploaded objects with a KMS key
+	EncryptionKMS = "aws:kms"
+
+	downloadsDir = "downloads" //Directory under the orchestration directory where relative source paths are resolved
+
+	// objectACL gives the bucket owner control of the objects uploaded from instances of other accounts
+	objectACL = "bucket-owner-full-control"
+
+	// minPartSizeMB and maxPartSizeMB are the smallest and largest parts of multipart uploads S3 accepts
+	minPartSizeMB = int(s3manager.MinUploadPartSize / 1024 / 1024)
+	maxPartSizeMB = 5 * 1024
+	// maxTags is the most tags S3 accepts on an object
+	maxTags = 10
+)
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	plugin.uploaderCreator = newUploader
+	return &plugin, nil
+}
+
+// Plugin is the type for the aws:uploadContent plugin.
+type Plugin struct {
+	uploaderCreator func(log log.T, bucketName string) s3manageriface.UploaderAPI
+}
+
+// UploadContentPluginInput is a struct that holds the parameters sent through send command
+type UploadContentPluginInput struct {
+	contracts.PluginInput
+	// SourcePath is the file or directory to upload, relative paths are resolved in the downloads directory of the document
+	SourcePath string `json:"sourcePath"`
+	BucketName string `json:"bucketName"`
+	// KeyPrefix is prepended to the names of the uploaded files, or to their paths relative to a source directory
+	KeyPrefix string `json:"keyPrefix"`
+	// ServerSideEncryption is AES256 or aws:kms, aws:kms by default when a KmsKeyId is set
+	ServerSideEncryption string            `json:"serverSideEncryption"`
+	KmsKeyId             string            `json:"kmsKeyId"`
+	Metadata             map[string]string `json:"metadata"`
+	Tags                 map[string]string `json:"tags"`
+	// PartSizeMB is the size of the parts of multipart uploads, by default the part size of the SDK
+	PartSizeMB int `json:"partSizeMB"`
+}
+
+// newUploader returns an uploader to the bucket
+func newUploader(log log.T, bucketName string) s3manageriface.UploaderAPI {
+	return s3util.NewUploader(log, bucketName)
+}
+
+// Execute uploads the source files to S3.
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Info("Plugin aws:uploadContent started with configuration", config)
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+	} else if input, err := parseAndValidateInput(config.Properties); err != nil {
+		output.MarkAsFailed(err)
+	} else {
+		p.runUploadContent(log, input, config, cancelFlag, output)
+	}
+}
+
+// runUploadContent uploads the source file, or the files in the source directory, to the bucket
+func (p *Plugin) runUploadContent(log log.T, input *UploadContentPluginInput, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	sourcePath := input.SourcePath
+	if !filepath.IsAbs(sourcePath) {
+		orchestrationDir := strings.TrimSuffix(config.OrchestrationDirectory, config.PluginID)
+		sourcePath = filepath.Join(orchestrationDir, downloadsDir, sourcePath)
+	}
+
+	files, err := listFiles(log, sourcePath, input.KeyPrefix)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	if len(files) == 0 {
+		output.MarkAsFailed(fmt.Errorf("No files to upload in %v", sourcePath))
+		return
+	}
+
+	uploader := p.uploaderCreator(log, input.BucketName)
+	for _, file := range files {
+		if cancelFlag.ShutDown() {
+			output.MarkAsShutdown()
+			return
+		} else if cancelFlag.Canceled() {
+			output.MarkAsCancelled()
+			return
+		}
+		if err = uploadFile(log, uploader, input, file.path, file.key); err != nil {
+			output.MarkAsFailed(fmt.Errorf("Failed to upload %v to s3://%v/%v - %v", file.path, input.BucketName, file.key, err))
+			return
+		}
+		output.AppendInfof("Uploaded %v to s3://%v/%v", file.path, input.BucketName, file.key)
+	}
+
+	output.AppendInfof("Content uploaded to s3://%v/%v", input.BucketName, input.KeyPrefix)
+	output.MarkAsSucceeded()
+}
+
+// uploadFile uploads the file to the object with the encryption, metadata and tags of the input
+func uploadFile(log log.T, uploader s3manageriface.UploaderAPI, input *UploadContentPluginInput, filePath string, key string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	params := &s3manager.UploadInput{
+		Bucket: aws.String(input.BucketName),
+		Key:    aws.String(key),
+		Body:   file,
+		ACL:    aws.String(objectACL),
+	}
+	if input.ServerSideEncryption != "" {
+		params.ServerSideEncryption = aws.String(input.ServerSideEncryption)
+	}
+	if input.KmsKeyId != "" {
+		params.SSEKMSKeyId = aws.String(input.KmsKeyId)
+	}
+	if len(input.Metadata) > 0 {
+		params.Metadata = aws.StringMap(input.Metadata)
+	}
+	if len(input.Tags) > 0 {
+		tags := url.Values{}
+		for name, value := range input.Tags {
+			tags.Set(name, value)
+		}
+		params.Tagging = aws.String(tags.Encode())
+	}
+
+	log.Infof("Uploading %v to s3://%v/%v", filePath, input.BucketName, key)
+	_, err = uploader.Upload(params, func(u *s3manager.Uploader) {
+		if input.PartSizeMB > 0 {
+			u.PartSize = int64(input.PartSizeMB) * 1024 * 1024
+		}
+	})
+	return err
+}
+
+// uploadFileInfo is a file to upload and the key of its object
+type uploadFileInfo struct {
+	path string
+	key  string
+}
+
+// listFiles returns the files to upload with their keys: the source file, or the regular files in the source directory
+// and its subdirectories keyed by their relative paths. Symbolic links in source directories are not followed.
+func listFiles(log log.T, sourcePath string, keyPrefix string) (files []uploadFileInfo, err error) {
+	info, err := os.Stat(sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("Source path %v cannot be uploaded - %v", sourcePath, err)
+	}
+	if !info.IsDir() {
+		return []uploadFileInfo{{path: sourcePath, key: objectKey(keyPrefix, filepath.Base(sourcePath))}}, nil
+	}
+
+	err = filepath.Walk(sourcePath, func(filePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fileInfo.IsDir() {
+			return nil
+		}
+		if !fileInfo.Mode().IsRegular() {
+			log.Infof("Skipping %v which is not a regular file", filePath)
+			return nil
+		}
+		relativePath, err := filepath.Rel(sourcePath, filePath)
+		if err != nil {
+			return err
+		}
+		files = append(files, uploadFileInfo{path: filePath, key: objectKey(keyPrefix, filepath.ToSlash(relativePath))})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list the files in %v - %v", sourcePath, err)
+	}
+	return files, nil
+}
+
+// objectKey returns the key of the object of the file with the prefix
+func objectKey(keyPrefix string, name string) string {
+	if keyPrefix == "" {
+		return name
+	}
+	return path.Join(keyPrefix, name)
+}
+
+// Name returns the plugin name
+func Name() string {
+	return appconfig.PluginUploadContent
+}
+
+// parseAndValidateInput parses the input json file and also validates its inputs
+func parseAndValidateInput(rawPluginInput interface{}) (*UploadContentPluginInput, error) {
+	var input UploadContentPluginInput
+	if err := jsonutil.Remarshal(rawPluginInput, &input); err != nil {
+		return nil, fmt.Errorf("invalid format in plugin properties %v; \nerror %v", rawPluginInput, err)
+	}
+
+	if err := validateInput(&input); err != nil {
+		return nil, fmt.Errorf("invalid input: %v", err)
+	}
+
+	return &input, nil
+}
+
+// validateInput ensures the plugin input matches the defined schema, and defaults the encryption to KMS with a key
+func validateInput(input *UploadContentPluginInput) error {
+	if input.SourcePath == "" {
+		return errors.New("SourcePath must be specified")
+	}
+	if input.BucketName == "" {
+		return errors.New("BucketName must be specified")
+	}
+	input.KeyPrefix = strings.Trim(input.KeyPrefix, "/")
+
+	if input.KmsKeyId != "" && input.ServerSideEncryption == "" {
+		input.ServerSideEncryption = EncryptionKMS
+	}
+	switch input.ServerSideEncryption {
+	case "", EncryptionAES256:
+		if input.KmsKeyId != "" {
+			return fmt.Errorf("KmsKeyId requires ServerSideEncryption %v", EncryptionKMS)
+		}
+	case EncryptionKMS:
+	default:
+		return fmt.Errorf("Unsupported ServerSideEncryption %v, it must be %v or %v", input.ServerSideEncryption, EncryptionAES256, EncryptionKMS)
+	}
+
+	if input.PartSizeMB != 0 && (input.PartSizeMB < minPartSizeMB || input.PartSizeMB > maxPartSizeMB) {
+		return fmt.Errorf("PartSizeMB must be between %v and %v", minPartSizeMB, maxPartSizeMB)
+	}
+	if len(input.Tags) > maxTags {
+		return fmt.Errorf("At most %v tags can be set on uploaded objects", maxTags)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package uploadcontent implements the aws:uploadContent plugin
+package uploadcontent
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	iohandlermocks "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// uploadedObject is an object uploaded to the fake uploader
+type uploadedObject struct {
+	input    s3manager.UploadInput
+	content  string
+	partSize int64
+}
+
+// fakeUploader records the uploaded objects by key
+type fakeUploader struct {
+	s3manageriface.UploaderAPI
+	objects map[string]uploadedObject
+	err     error
+}
+
+func (u *fakeUploader) Upload(input *s3manager.UploadInput, options ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error) {
+	if u.err != nil {
+		return nil, u.err
+	}
+	uploader := s3manager.Uploader{PartSize: s3manager.DefaultUploadPartSize}
+	for _, option := range options {
+		option(&uploader)
+	}
+	content, _ := ioutil.ReadAll(input.Body)
+	u.objects[*input.Key] = uploadedObject{input: *input, content: string(content), partSize: uploader.PartSize}
+	return &s3manager.UploadOutput{}, nil
+}
+
+func newTestPlugin(uploader *fakeUploader) *Plugin {
+	return &Plugin{uploaderCreator: func(log log.T, bucketName string) s3manageriface.UploaderAPI {
+		return uploader
+	}}
+}
+
+func createTestFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		filePath := filepath.Join(root, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filePath), appconfig.ReadWriteExecuteAccess))
+		assert.NoError(t, ioutil.WriteFile(filePath, []byte(content), appconfig.ReadWriteAccess))
+	}
+}
+
+func TestUploadDirectory(t *testing.T) {
+	orchestrationDir, _ := ioutil.TempDir("", "uploadcontent")
+	defer os.RemoveAll(orchestrationDir)
+	createTestFiles(t, filepath.Join(orchestrationDir, downloadsDir, "build"), map[string]string{
+		"app.jar":           "jar",
+		"logs/build.log":    "log",
+		"logs/test/out.txt": "out",
+	})
+
+	uploader := &fakeUploader{objects: map[string]uploadedObject{}}
+	config := contracts.Configuration{
+		PluginID:               "upload",
+		OrchestrationDirectory: filepath.Join(orchestrationDir, "upload"),
+		Properties: map[string]interface{}{
+			"sourcePath": "build",
+			"bucketName": "artifacts",
+			"keyPrefix":  "/builds/42/",
+			"kmsKeyId":   "alias/artifacts",
+			"metadata":   map[string]interface{}{"commit": "abc123"},
+			"tags":       map[string]interface{}{"team": "build & release"},
+			"partSizeMB": 16,
+		},
+	}
+	mockIOHandler := new(iohandlermocks.MockIOHandler)
+	mockIOHandler.On("AppendInfof", mock.Anything, mock.Anything).Return()
+	mockIOHandler.On("MarkAsSucceeded").Return()
+
+	newTestPlugin(uploader).Execute(context.NewMockDefault(), config, task.NewChanneledCancelFlag(), mockIOHandler)
+
+	mockIOHandler.AssertCalled(t, "MarkAsSucceeded")
+	keys := []string{}
+	for key := range uploader.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	assert.Equal(t, []string{"builds/42/app.jar", "builds/42/logs/build.log", "builds/42/logs/test/out.txt"}, keys)
+
+	object := uploader.objects["builds/42/logs/build.log"]
+	assert.Equal(t, "log", object.content)
+	assert.Equal(t, "artifacts", *object.input.Bucket)
+	assert.Equal(t, EncryptionKMS, *object.input.ServerSideEncryption)
+	assert.Equal(t, "alias/artifacts", *object.input.SSEKMSKeyId)
+	assert.Equal(t, "abc123", *object.input.Metadata["commit"])
+	assert.Equal(t, "team=build+%26+release", *object.input.Tagging)
+	assert.Equal(t, "bucket-owner-full-control", *object.input.ACL)
+	assert.Equal(t, int64(16*1024*1024), object.partSize)
+}
+
+func TestUploadFile(t *testing.T) {
+	sourceDir, _ := ioutil.TempDir("", "uploadcontent")
+	defer os.RemoveAll(sourceDir)
+	createTestFiles(t, sourceDir, map[string]string{"bundle.zip": "zip"})
+
+	uploader := &fakeUploader{objects: map[string]uploadedObject{}}
+	input := &UploadContentPluginInput{SourcePath: filepath.Join(sourceDir, "bundle.zip"), BucketName: "support"}
+	mockIOHandler := new(iohandlermocks.MockIOHandler)
+	mockIOHandler.On("AppendInfof", mock.Anything, mock.Anything).Return()
+	mockIOHandler.On("MarkAsSucceeded").Return()
+
+	newTestPlugin(uploader).runUploadContent(log.NewMockLog(), input, contracts.Configuration{}, task.NewChanneledCancelFlag(), mockIOHandler)
+
+	mockIOHandler.AssertCalled(t, "MarkAsSucceeded")
+	object, found := uploader.objects["bundle.zip"]
+	assert.True(t, found)
+	assert.Equal(t, "zip", object.content)
+	assert.Nil(t, object.input.ServerSideEncryption)
+	assert.Nil(t, object.input.Tagging)
+	assert.Equal(t, s3manager.DefaultUploadPartSize, object.partSize)
+}
+
+func TestUploadFailures(t *testing.T) {
+	sourceDir, _ := ioutil.TempDir("", "uploadcontent")
+	defer os.RemoveAll(sourceDir)
+	os.MkdirAll(filepath.Join(sourceDir, "empty"), appconfig.ReadWriteExecuteAccess)
+	createTestFiles(t, sourceDir, map[string]string{"bundle.zip": "zip"})
+
+	testCases := []struct {
+		sourcePath string
+		uploadErr  error
+	}{
+		{filepath.Join(sourceDir, "missing"), nil},
+		{filepath.Join(sourceDir, "empty"), nil},
+		{filepath.Join(sourceDir, "bundle.zip"), errors.New("AccessDenied")},
+	}
+	for _, testCase := range testCases {
+		uploader := &fakeUploader{objects: map[string]uploadedObject{}, err: testCase.uploadErr}
+		input := &UploadContentPluginInput{SourcePath: testCase.sourcePath, BucketName: "support"}
+		mockIOHandler := new(iohandlermocks.MockIOHandler)
+		mockIOHandler.On("MarkAsFailed", mock.Anything).Return()
+
+		newTestPlugin(uploader).runUploadContent(log.NewMockLog(), input, contracts.Configuration{}, task.NewChanneledCancelFlag(), mockIOHandler)
+
+		mockIOHandler.AssertCalled(t, "MarkAsFailed", mock.Anything)
+	}
+}
+
+func TestUploadCanceled(t *testing.T) {
+	sourceDir, _ := ioutil.TempDir("", "uploadcontent")
+	defer os.RemoveAll(sourceDir)
+	createTestFiles(t, sourceDir, map[string]string{"bundle.zip": "zip"})
+
+	uploader := &fakeUploader{objects: map[string]uploadedObject{}}
+	input := &UploadContentPluginInput{SourcePath: sourceDir, BucketName: "support"}
+	cancelFlag := task.NewChanneledCancelFlag()
+	cancelFlag.Set(task.Canceled)
+	mockIOHandler := new(iohandlermocks.MockIOHandler)
+	mockIOHandler.On("MarkAsCancelled").Return()
+
+	newTestPlugin(uploader).runUploadContent(log.NewMockLog(), input, contracts.Configuration{}, cancelFlag, mockIOHandler)
+
+	mockIOHandler.AssertCalled(t, "MarkAsCancelled")
+	assert.Empty(t, uploader.objects)
+}
+
+func TestValidateInput(t *testing.T) {
+	testCases := []struct {
+		input       UploadContentPluginInput
+		encryption  string
+		expectError bool
+	}{
+		{UploadContentPluginInput{SourcePath: "logs", BucketName: "support"}, "", false},
+		{UploadContentPluginInput{SourcePath: "logs", BucketName: "support", ServerSideEncryption: EncryptionAES256}, EncryptionAES256, false},
+		{UploadContentPluginInput{SourcePath: "logs", BucketName: "support", KmsKeyId: "alias/logs"}, EncryptionKMS, false},
+		{UploadContentPluginInput{SourcePath: "logs", BucketName: "support", PartSizeMB: 5}, "", false},
+		{UploadContentPluginInput{BucketName: "support"}, "", true},
+		{UploadContentPluginInput{SourcePath: "logs"}, "", true},
+		{UploadContentPluginInput{SourcePath: "logs", BucketName: "support", ServerSideEncryption: EncryptionAES256, KmsKeyId: "alias/logs"}, "", true},
+		{UploadContentPluginInput{SourcePath: "logs", BucketName: "support", ServerSideEncryption: "aws:kms:dsse"}, "", true},
+		{UploadContentPluginInput{SourcePath: "logs", BucketName: "support", PartSizeMB: 4}, "", true},
+		{UploadContentPluginInput{SourcePath: "logs", BucketName: "support", PartSizeMB: 5121}, "", true},
+		{UploadContentPluginInput{SourcePath: "logs", BucketName: "support", Tags: map[string]string{
+			"1": "", "2": "", "3": "", "4": "", "5": "", "6": "", "7": "", "8": "", "9": "", "10": "", "11": ""}}, "", true},
+	}
+	for _, testCase := range testCases {
+		input := testCase.input
+		err := validateInput(&input)
+		assert.Equal(t, testCase.expectError, err != nil, "%+v", testCase.input)
+		if err == nil {
+			assert.Equal(t, testCase.encryption, input.ServerSideEncryption)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build windows
+
+// Package windowsservice implements the aws:windowsService plugin
+package windowsservice
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const (
+	// stateChangeTimeout is how long a service is waited for to start or stop
+	stateChangeTimeout = 60 * time.Second
+	statePollInterval  = 500 * time.Millisecond
+
+	// action types of SC_ACTION
+	scActionNone    = 0
+	scActionRestart = 1
+	scActionReboot  = 2
+)
+
+// serviceFailureActions is the SERVICE_FAILURE_ACTIONS structure of the recovery settings of a service
+type serviceFailureActions struct {
+	ResetPeriod  uint32
+	RebootMsg    *uint16
+	Command      *uint16
+	ActionsCount uint32
+	Actions      *scAction
+}
+
+// scAction is the SC_ACTION structure of a recovery action, the delay is in milliseconds
+type scAction struct {
+	Type  uint32
+	Delay uint32
+}
+
+var startupTypes = map[uint32]string{
+	windows.SERVICE_BOOT_START:   "Boot",
+	windows.SERVICE_SYSTEM_START: "System",
+	windows.SERVICE_AUTO_START:   StartupTypeAutomatic,
+	windows.SERVICE_DEMAND_START: StartupTypeManual,
+	windows.SERVICE_DISABLED:     StartupTypeDisabled,
+}
+
+var states = map[svc.State]string{
+	svc.Stopped:         StateStopped,
+	svc.StartPending:    "StartPending",
+	svc.StopPending:     "StopPending",
+	svc.Running:         StateRunning,
+	svc.ContinuePending: "ContinuePending",
+	svc.PausePending:    "PausePending",
+	svc.Paused:          "Paused",
+}
+
+var recoveryActionTypes = map[uint32]string{
+	scActionNone:    RecoveryNone,
+	scActionRestart: RecoveryRestart,
+	scActionReboot:  RecoveryReboot,
+}
+
+// windowsServiceManager manages services through the service control manager
+type windowsServiceManager struct {
+	manager *mgr.Mgr
+}
+
+// connectServiceManager connects to the service control manager of the instance.
+func connectServiceManager() (serviceManager, error) {
+	manager, err := mgr.Connect()
+	if err != nil {
+		return nil, err
+	}
+	return &windowsServiceManager{manager: manager}, nil
+}
+
+// Close disconnects from the service control manager.
+func (m *windowsServiceManager) Close() {
+	m.manager.Disconnect()
+}
+
+// withService opens the service and calls action with it.
+func (m *windowsServiceManager) withService(name string, action func(service *mgr.Service) error) error {
+	service, err := m.manager.OpenService(name)
+	if err != nil {
+		return err
+	}
+	defer service.Close()
+	return action(service)
+}
+
+// Query returns the state and configuration of the service.
+func (m *windowsServiceManager) Query(name string) (status serviceStatus, err error) {
+	err = m.withService(name, func(service *mgr.Service) error {
+		serviceStatus, err := service.Query()
+		if err != nil {
+			return err
+		}
+		config, err := service.Config()
+		if err != nil {
+			return err
+		}
+		recovery, err := queryRecovery(service.Handle)
+		if err != nil {
+			return err
+		}
+		status.State = states[serviceStatus.State]
+		status.StartupType = startupTypes[config.StartType]
+		status.Account = config.ServiceStartName
+		status.Recovery = recovery
+		return nil
+	})
+	return
+}
+
+// Start starts the service and waits until it runs.
+func (m *windowsServiceManager) Start(name string) error {
+	return m.withService(name, func(service *mgr.Service) error {
+		if err := service.Start(); err != nil {
+			return err
+		}
+		return waitForState(service, svc.Running)
+	})
+}
+
+// Stop stops the service and waits until it stopped.
+func (m *windowsServiceManager) Stop(name string) error {
+	return m.withService(name, func(service *mgr.Service) error {
+		if _, err := service.Control(svc.Stop); err != nil {
+			return err
+		}
+		return waitForState(service, svc.Stopped)
+	})
+}
+
+// SetStartupType changes the startup type of the service.
+func (m *windowsServiceManager) SetStartupType(name string, startupType string) error {
+	return m.withService(name, func(service *mgr.Service) error {
+		config, err := service.Config()
+		if err != nil {
+			return err
+		}
+		for value, text := range startupTypes {
+			if text == startupType {
+				config.StartType = value
+				return service.UpdateConfig(config)
+			}
+		}
+		return fmt.Errorf("unknown startup type %v", startupType)
+	})
+}
+
+// SetLogonAccount changes the account the service runs as.
+func (m *windowsServiceManager) SetLogonAccount(name string, account LogonAccount) error {
+	return m.withService(name, func(service *mgr.Service) error {
+		config, err := service.Config()
+		if err != nil {
+			return err
+		}
+		config.ServiceStartName = account.Account
+		config.Password = account.Password
+		return service.UpdateConfig(config)
+	})
+}
+
+// SetRecovery changes the recovery actions of the service.
+func (m *windowsServiceManager) SetRecovery(name string, recovery Recovery) error {
+	return m.withService(name, func(service *mgr.Service) error {
+		actions := make([]scAction, len(recovery.Actions))
+		for index, action := range recovery.Actions {
+			for value, text := range recoveryActionTypes {
+				if text == action.Type {
+					actions[index].Type = value
+				}
+			}
+			actions[index].Delay = uint32(action.DelaySeconds * 1000)
+		}
+		failureActions := serviceFailureActions{
+			ResetPeriod:  uint32(recovery.ResetPeriodSeconds),
+			ActionsCount: uint32(len(actions)),
+		}
+		if len(actions) > 0 {
+			failureActions.Actions = &actions[0]
+		}
+		return windows.ChangeServiceConfig2(service.Handle, windows.SERVICE_CONFIG_FAILURE_ACTIONS, (*byte)(unsafe.Pointer(&failureActions)))
+	})
+}
+
+// queryRecovery returns the recovery actions of the service.
+func queryRecovery(handle windows.Handle) (recovery Recovery, err error) {
+	size := uint32(1024)
+	var buffer []byte
+	for {
+		buffer = make([]byte, size)
+		err = windows.QueryServiceConfig2(handle, windows.SERVICE_CONFIG_FAILURE_ACTIONS, &buffer[0], size, &size)
+		if err == nil {
+			break
+		}
+		if err != windows.ERROR_INSUFFICIENT_BUFFER || size <= uint32(len(buffer)) {
+			return recovery, err
+		}
+	}
+
+	failureActions := (*serviceFailureActions)(unsafe.Pointer(&buffer[0]))
+	recovery.ResetPeriodSeconds = int(failureActions.ResetPeriod)
+	if failureActions.ActionsCount == 0 || failureActions.Actions == nil {
+		return recovery, nil
+	}
+	actions := (*[1 << 10]scAction)(unsafe.Pointer(failureActions.Actions))[:failureActions.ActionsCount:failureActions.ActionsCount]
+	for _, action := range actions {
+		recovery.Actions = append(recovery.Actions, RecoveryAction{
+			Type:         recoveryActionTypes[action.Type],
+			DelaySeconds: int(action.Delay / 1000),
+		})
+	}
+	return recovery, nil
+}
+
+// waitForState waits until the service reaches the state or the timeout elapses.
+func waitForState(service *mgr.Service, state svc.State) error {
+	deadline := time.Now().Add(stateChangeTimeout)
+	for {
+		status, err := service.Query()
+		if err != nil {
+			return err
+		}
+		if status.State == state {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("service is %v after %v, expected %v", states[status.State], stateChangeTimeout, states[state])
+		}
+		time.Sleep(statePollInterval)
+	}
+}
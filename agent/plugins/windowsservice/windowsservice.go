@@ -0,0 +1,280 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package windowsservice implements the aws:windowsService plugin
+package windowsservice
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+const (
+	// service states
+	StateRunning = "Running"
+	StateStopped = "Stopped"
+
+	// startup types
+	StartupTypeAutomatic = "Automatic"
+	StartupTypeManual    = "Manual"
+	StartupTypeDisabled  = "Disabled"
+
+	// recovery action types
+	RecoveryNone    = "None"
+	RecoveryRestart = "Restart"
+	RecoveryReboot  = "Reboot"
+
+	// maxRecoveryActions is the number of failures Windows configures distinct actions for in the services console
+	maxRecoveryActions = 3
+)
+
+// serviceManager reads and changes the state and configuration of services
+type serviceManager interface {
+	Query(name string) (serviceStatus, error)
+	Start(name string) error
+	Stop(name string) error
+	SetStartupType(name string, startupType string) error
+	SetLogonAccount(name string, account LogonAccount) error
+	SetRecovery(name string, recovery Recovery) error
+	Close()
+}
+
+// serviceStatus is the state and configuration of a service
+type serviceStatus struct {
+	State       string
+	StartupType string
+	Account     string
+	Recovery    Recovery
+}
+
+// Assign method to global variables to allow unittest to override
+var newServiceManager = connectServiceManager
+
+// NewPlugin returns a new instance of the plugin.
+func NewPlugin() (*Plugin, error) {
+	var plugin Plugin
+	return &plugin, nil
+}
+
+// Plugin is the type for the aws:windowsService plugin.
+type Plugin struct {
+}
+
+// WindowsServicePluginInput represents the desired state and configuration of a service, unset fields are left unchanged
+type WindowsServicePluginInput struct {
+	contracts.PluginInput
+	Name        string        `json:"name"`
+	State       string        `json:"state"`
+	StartupType string        `json:"startupType"`
+	Recovery    *Recovery     `json:"recovery"`
+	Logon       *LogonAccount `json:"logon"`
+}
+
+// Recovery are the actions taken by the service control manager when the service fails
+type Recovery struct {
+	// Actions are taken on the first, second and subsequent failures
+	Actions []RecoveryAction `json:"actions"`
+	// ResetPeriodSeconds is the time without failure after which the failure count is reset
+	ResetPeriodSeconds int `json:"resetPeriodSeconds"`
+}
+
+// RecoveryAction is the action taken on a failure of the service
+type RecoveryAction struct {
+	Type         string `json:"type"`
+	DelaySeconds int    `json:"delaySeconds"`
+}
+
+// LogonAccount is the account the service runs as
+type LogonAccount struct {
+	Account string `json:"account"`
+	// Password of the account, empty for built-in and managed service accounts. Use a SecureString parameter.
+	Password string `json:"password"`
+}
+
+// Name returns the plugin name
+func Name() string {
+	return appconfig.PluginNameAwsWindowsService
+}
+
+// Execute brings the service to the desired state and reports the drift it corrected.
+func (p *Plugin) Execute(context context.T, config contracts.Configuration, cancelFlag task.CancelFlag, output iohandler.IOHandler) {
+	log := context.Log()
+	log.Infof("%v started", Name())
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+		return
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+		return
+	}
+
+	input, err := parseAndValidateInput(config.Properties)
+	if err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	manager, err := newServiceManager()
+	if err != nil {
+		output.MarkAsFailed(fmt.Errorf("failed to connect to the service control manager: %v", err))
+		return
+	}
+	defer manager.Close()
+
+	if err = ensureService(log, manager, input, output); err != nil {
+		output.MarkAsFailed(err)
+		return
+	}
+	output.MarkAsSucceeded()
+}
+
+// parseAndValidateInput parses the plugin properties and validates the desired state of the service
+func parseAndValidateInput(rawPluginInput interface{}) (*WindowsServicePluginInput, error) {
+	var input WindowsServicePluginInput
+	if err := jsonutil.Remarshal(rawPluginInput, &input); err != nil {
+		return nil, fmt.Errorf("invalid format in plugin properties; \nerror %v", err)
+	}
+
+	if strings.TrimSpace(input.Name) == "" {
+		return nil, fmt.Errorf("invalid input: name must be set")
+	}
+	switch input.State {
+	case "", StateRunning, StateStopped:
+	default:
+		return nil, fmt.Errorf("invalid input: state must be %v or %v", StateRunning, StateStopped)
+	}
+	switch input.StartupType {
+	case "", StartupTypeAutomatic, StartupTypeManual, StartupTypeDisabled:
+	default:
+		return nil, fmt.Errorf("invalid input: startupType must be %v, %v or %v", StartupTypeAutomatic, StartupTypeManual, StartupTypeDisabled)
+	}
+	if input.State == StateRunning && input.StartupType == StartupTypeDisabled {
+		return nil, fmt.Errorf("invalid input: a Disabled service cannot be Running")
+	}
+	if input.Recovery != nil {
+		if len(input.Recovery.Actions) > maxRecoveryActions {
+			return nil, fmt.Errorf("invalid input: at most %v recovery actions can be set", maxRecoveryActions)
+		}
+		if input.Recovery.ResetPeriodSeconds < 0 {
+			return nil, fmt.Errorf("invalid input: resetPeriodSeconds cannot be negative")
+		}
+		for _, action := range input.Recovery.Actions {
+			switch action.Type {
+			case RecoveryNone, RecoveryRestart, RecoveryReboot:
+			default:
+				return nil, fmt.Errorf("invalid input: recovery action type must be %v, %v or %v", RecoveryNone, RecoveryRestart, RecoveryReboot)
+			}
+			if action.DelaySeconds < 0 {
+				return nil, fmt.Errorf("invalid input: recovery action delaySeconds cannot be negative")
+			}
+		}
+	}
+	if input.Logon != nil && strings.TrimSpace(input.Logon.Account) == "" {
+		return nil, fmt.Errorf("invalid input: logon account must be set")
+	}
+	return &input, nil
+}
+
+// ensureService changes the configuration and state of the service that differ from the input, reporting each drift.
+// The configuration is changed before the state so that a started service runs with it.
+func ensureService(log log.T, manager serviceManager, input *WindowsServicePluginInput, output iohandler.IOHandler) error {
+	status, err := manager.Query(input.Name)
+	if err != nil {
+		return fmt.Errorf("failed to query service %v: %v", input.Name, err)
+	}
+
+	drift := false
+	if input.StartupType != "" && input.StartupType != status.StartupType {
+		drift = true
+		output.AppendInfof("Drift: startup type is %v, expected %v", status.StartupType, input.StartupType)
+		if err = manager.SetStartupType(input.Name, input.StartupType); err != nil {
+			return fmt.Errorf("failed to set the startup type of service %v: %v", input.Name, err)
+		}
+	}
+	// the password cannot be read back, so only a different account is drift
+	if input.Logon != nil && !strings.EqualFold(input.Logon.Account, status.Account) {
+		drift = true
+		output.AppendInfof("Drift: logon account is %v, expected %v", status.Account, input.Logon.Account)
+		if err = manager.SetLogonAccount(input.Name, *input.Logon); err != nil {
+			return fmt.Errorf("failed to set the logon account of service %v: %v", input.Name, err)
+		}
+	}
+	if input.Recovery != nil && !equalRecovery(*input.Recovery, status.Recovery) {
+		drift = true
+		output.AppendInfof("Drift: recovery is %v, expected %v", formatRecovery(status.Recovery), formatRecovery(*input.Recovery))
+		if err = manager.SetRecovery(input.Name, *input.Recovery); err != nil {
+			return fmt.Errorf("failed to set the recovery actions of service %v: %v", input.Name, err)
+		}
+	}
+	if input.State != "" && input.State != status.State {
+		drift = true
+		output.AppendInfof("Drift: state is %v, expected %v", status.State, input.State)
+		if input.State == StateRunning {
+			err = manager.Start(input.Name)
+		} else {
+			err = manager.Stop(input.Name)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to change the state of service %v to %v: %v", input.Name, input.State, err)
+		}
+	}
+
+	if !drift {
+		output.AppendInfof("Service %v is in the desired state", input.Name)
+		return nil
+	}
+	if status, err = manager.Query(input.Name); err != nil {
+		return fmt.Errorf("failed to query service %v: %v", input.Name, err)
+	}
+	log.Infof("Service %v corrected to state %v, startup type %v", input.Name, status.State, status.StartupType)
+	output.AppendInfof("Service %v is %v, startup type %v, logon account %v", input.Name, status.State, status.StartupType, status.Account)
+	return nil
+}
+
+// equalRecovery returns true if the recovery settings are the same, trailing None actions are ignored.
+func equalRecovery(left, right Recovery) bool {
+	return left.ResetPeriodSeconds == right.ResetPeriodSeconds &&
+		reflect.DeepEqual(trimRecoveryActions(left.Actions), trimRecoveryActions(right.Actions))
+}
+
+// trimRecoveryActions removes the trailing None actions, which are the same as no action.
+func trimRecoveryActions(actions []RecoveryAction) []RecoveryAction {
+	for len(actions) > 0 && actions[len(actions)-1].Type == RecoveryNone {
+		actions = actions[:len(actions)-1]
+	}
+	if len(actions) == 0 {
+		return nil
+	}
+	return actions
+}
+
+// formatRecovery returns a description of the recovery settings for the step output.
+func formatRecovery(recovery Recovery) string {
+	var actions []string
+	for _, action := range recovery.Actions {
+		actions = append(actions, fmt.Sprintf("%v after %vs", action.Type, action.DelaySeconds))
+	}
+	if len(actions) == 0 {
+		actions = append(actions, RecoveryNone)
+	}
+	return fmt.Sprintf("[%v] reset after %vs", strings.Join(actions, ", "), recovery.ResetPeriodSeconds)
+}
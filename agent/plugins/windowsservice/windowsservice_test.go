@@ -0,0 +1,150 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package windowsservice implements the aws:windowsService plugin
+package windowsservice
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = log.NewMockLog()
+
+// fakeServiceManager keeps the status of a single service in memory and records the changes
+type fakeServiceManager struct {
+	status  serviceStatus
+	changes []string
+	err     error
+}
+
+func (m *fakeServiceManager) Query(name string) (serviceStatus, error) {
+	return m.status, nil
+}
+
+func (m *fakeServiceManager) Start(name string) error {
+	m.changes = append(m.changes, "start")
+	m.status.State = StateRunning
+	return m.err
+}
+
+func (m *fakeServiceManager) Stop(name string) error {
+	m.changes = append(m.changes, "stop")
+	m.status.State = StateStopped
+	return m.err
+}
+
+func (m *fakeServiceManager) SetStartupType(name string, startupType string) error {
+	m.changes = append(m.changes, "startupType "+startupType)
+	m.status.StartupType = startupType
+	return m.err
+}
+
+func (m *fakeServiceManager) SetLogonAccount(name string, account LogonAccount) error {
+	m.changes = append(m.changes, "logon "+account.Account)
+	m.status.Account = account.Account
+	return m.err
+}
+
+func (m *fakeServiceManager) SetRecovery(name string, recovery Recovery) error {
+	m.changes = append(m.changes, "recovery")
+	m.status.Recovery = recovery
+	return m.err
+}
+
+func (m *fakeServiceManager) Close() {}
+
+func TestParseAndValidateInput(t *testing.T) {
+	input, err := parseAndValidateInput(map[string]interface{}{
+		"name":        "W3SVC",
+		"state":       "Running",
+		"startupType": "Automatic",
+		"recovery": map[string]interface{}{
+			"actions":            []interface{}{map[string]interface{}{"type": "Restart", "delaySeconds": 60}},
+			"resetPeriodSeconds": 86400,
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "W3SVC", input.Name)
+	assert.Equal(t, []RecoveryAction{{Type: RecoveryRestart, DelaySeconds: 60}}, input.Recovery.Actions)
+
+	invalid := []map[string]interface{}{
+		{"state": "Running"},
+		{"name": "W3SVC", "state": "Paused"},
+		{"name": "W3SVC", "startupType": "Delayed"},
+		{"name": "W3SVC", "state": "Running", "startupType": "Disabled"},
+		{"name": "W3SVC", "recovery": map[string]interface{}{"actions": []interface{}{map[string]interface{}{"type": "RunCommand"}}}},
+		{"name": "W3SVC", "logon": map[string]interface{}{"password": "secret"}},
+	}
+	for _, properties := range invalid {
+		_, err = parseAndValidateInput(properties)
+		assert.Error(t, err, "%v", properties)
+	}
+}
+
+func TestEnsureServiceCorrectsDrift(t *testing.T) {
+	manager := &fakeServiceManager{status: serviceStatus{State: StateStopped, StartupType: StartupTypeManual, Account: "LocalSystem"}}
+	input := &WindowsServicePluginInput{
+		Name:        "W3SVC",
+		State:       StateRunning,
+		StartupType: StartupTypeAutomatic,
+		Logon:       &LogonAccount{Account: "NT AUTHORITY\\NetworkService"},
+		Recovery:    &Recovery{Actions: []RecoveryAction{{Type: RecoveryRestart, DelaySeconds: 30}}, ResetPeriodSeconds: 3600},
+	}
+	output := iohandler.NewDefaultIOHandler(logger, contracts.IOConfiguration{})
+
+	assert.NoError(t, ensureService(logger, manager, input, output))
+
+	assert.Equal(t, []string{"startupType Automatic", "logon NT AUTHORITY\\NetworkService", "recovery", "start"}, manager.changes)
+	assert.Contains(t, output.GetStdout(), "Drift: state is Stopped, expected Running")
+	assert.Contains(t, output.GetStdout(), "Drift: startup type is Manual, expected Automatic")
+	assert.Contains(t, output.GetStdout(), "Service W3SVC is Running, startup type Automatic")
+}
+
+func TestEnsureServiceIsIdempotent(t *testing.T) {
+	manager := &fakeServiceManager{status: serviceStatus{
+		State:       StateRunning,
+		StartupType: StartupTypeAutomatic,
+		Account:     "localsystem",
+		Recovery:    Recovery{Actions: []RecoveryAction{{Type: RecoveryRestart, DelaySeconds: 30}, {Type: RecoveryNone}}},
+	}}
+	input := &WindowsServicePluginInput{
+		Name:        "W3SVC",
+		State:       StateRunning,
+		StartupType: StartupTypeAutomatic,
+		Logon:       &LogonAccount{Account: "LocalSystem"},
+		Recovery:    &Recovery{Actions: []RecoveryAction{{Type: RecoveryRestart, DelaySeconds: 30}}},
+	}
+	output := iohandler.NewDefaultIOHandler(logger, contracts.IOConfiguration{})
+
+	assert.NoError(t, ensureService(logger, manager, input, output))
+
+	assert.Empty(t, manager.changes)
+	assert.Equal(t, "Service W3SVC is in the desired state", output.GetStdout())
+}
+
+func TestEnsureServiceFailsWhenChangeFails(t *testing.T) {
+	manager := &fakeServiceManager{status: serviceStatus{State: StateRunning}, err: fmt.Errorf("access denied")}
+	input := &WindowsServicePluginInput{Name: "W3SVC", State: StateStopped}
+	output := iohandler.NewDefaultIOHandler(logger, contracts.IOConfiguration{})
+
+	err := ensureService(logger, manager, input, output)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "access denied")
+}
@@ -16,6 +16,7 @@ package proxyconfig
 
 import (
 	"errors"
+	"net"
 	"net/url"
 	"os"
 	"strings"
@@ -171,7 +172,10 @@ func SetProxySettings(log log.T) {
 				continue
 			}
 			url, err := ValidateHost(s)
-			if err == nil {
+			if err == nil && url.Port() == "" {
+				// no_proxy matches IPv6 addresses without port only when they are not bracketed
+				bypassList = append(bypassList, url.Hostname())
+			} else if err == nil {
 				bypassList = append(bypassList, url.Host)
 			} else {
 				log.Warnf("SetProxySettings invalid URL or host for no_proxy: %v", err.Error())
@@ -343,6 +347,11 @@ func ValidateHost(s string) (*url.URL, error) {
 		return nil, errors.New(s + " host not supported, skipped")
 	}
 
+	// Brackets IPv6 addresses without port, for example 2001:db8::1, as url.Parse rejects them otherwise
+	if ip := net.ParseIP(strings.TrimPrefix(s, "//")); ip != nil && ip.To4() == nil {
+		s = "[" + strings.TrimPrefix(s, "//") + "]"
+	}
+
 	// Helps url.Parse to validate an IP for example 127.0.0.1
 	if strings.Index(s, "//") == 0 {
 		s = "http:" + s
@@ -0,0 +1,52 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package rip contains AWS services regional endpoints.
+package rip
+
+import (
+	"net"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// useDualStackEndpoint returns true if the IP address preference requires an endpoint reachable over IPv6.
+func useDualStackEndpoint(ipAddressPreference string) bool {
+	return ipAddressPreference == appconfig.IPAddressPreferenceIPv6 ||
+		ipAddressPreference == appconfig.IPAddressPreferenceDualStack
+}
+
+// GetDualStackEndpoint returns the endpoint of the service in the region resolving to both IPv4 and IPv6 addresses.
+func GetDualStackEndpoint(region string, service string) string {
+	if strings.HasPrefix(region, "cn-") {
+		return service + "." + region + ".api.amazonwebservices.com.cn"
+	}
+	return service + "." + region + ".api.aws"
+}
+
+// ipv6LiteralHost returns the endpoint as a url host enclosing the address in square brackets
+// if the endpoint is an IPv6 address, optionally bracketed and followed by a port.
+func ipv6LiteralHost(endpoint string) (host string, ok bool) {
+	address, port := strings.TrimSuffix(strings.TrimPrefix(endpoint, "["), "]"), ""
+	if splitAddress, splitPort, err := net.SplitHostPort(endpoint); err == nil {
+		address, port = splitAddress, splitPort
+	}
+	if ip := net.ParseIP(address); ip == nil || ip.To4() != nil {
+		return "", false
+	}
+	if port == "" {
+		return "[" + address + "]", true
+	}
+	return net.JoinHostPort(address, port), true
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package rip contains AWS services regional endpoints.
+package rip
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// fipsRegions are the regions where the services used by the agent offer FIPS 140-2 validated endpoints.
+var fipsRegions = map[string]bool{
+	"ca-central-1":  true,
+	"us-east-1":     true,
+	"us-east-2":     true,
+	"us-gov-east-1": true,
+	"us-gov-west-1": true,
+	"us-west-1":     true,
+	"us-west-2":     true,
+}
+
+var (
+	fipsEnabledOnce sync.Once
+	fipsEnabled     bool
+)
+
+// FipsEnabled returns true if the agent is configured to use FIPS endpoints and FIPS approved cryptography.
+// It is a variable so that unit tests can enable FIPS.
+var FipsEnabled = fipsEnabledInConfig
+
+// fipsEnabledInConfig reads FipsEnabled from the app config once, it cannot change without restarting the agent.
+func fipsEnabledInConfig() bool {
+	fipsEnabledOnce.Do(func() {
+		appConfig, err := appconfig.Config(false)
+		fipsEnabled = err == nil && appConfig.Agent.FipsEnabled
+	})
+	return fipsEnabled
+}
+
+// GetFipsEndpoint returns the FIPS endpoint of the service in the region.
+// An error is returned if the region has no FIPS endpoints, callers must not fall back to the regular endpoint.
+func GetFipsEndpoint(region string, service string) (string, error) {
+	if !fipsRegions[region] {
+		return "", fmt.Errorf("FIPS is enabled but %s has no FIPS endpoint in region %s", service, region)
+	}
+	return service + "-fips." + region + ".amazonaws.com", nil
+}
+
+// UseFipsEndpoint routes the clients created from the session to the FIPS endpoint of the service in the region
+// if FIPS is enabled, and returns false if it is not. When the region has no FIPS endpoint, the requests of the
+// clients fail instead of being sent to the regular endpoint.
+func UseFipsEndpoint(sess *session.Session, region string, service string) bool {
+	if !FipsEnabled() {
+		return false
+	}
+	if fipsEndpoint, err := GetFipsEndpoint(region, service); err != nil {
+		sess.Handlers.Validate.PushBackNamed(FipsEndpointUnavailableHandler(err))
+	} else {
+		sess.Config.Endpoint = &fipsEndpoint
+	}
+	return true
+}
+
+// FipsEndpointUnavailableHandler returns a request handler failing every request of an SDK client with err,
+// used by clients that cannot return the error of GetFipsEndpoint when they are created
+func FipsEndpointUnavailableHandler(err error) request.NamedHandler {
+	return request.NamedHandler{
+		Name: "FipsEndpointUnavailable",
+		Fn: func(r *request.Request) {
+			r.Error = err
+		},
+	}
+}
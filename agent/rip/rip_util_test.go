@@ -17,8 +17,14 @@
 package rip
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -35,3 +41,68 @@ func TestGetMgsEndpointForCnRegion(t *testing.T) {
 
 	assert.Equal(t, expected, endpoint)
 }
+
+func TestGetFipsEndpoint(t *testing.T) {
+	endpoint, err := GetFipsEndpoint("us-east-1", "ssm")
+	assert.NoError(t, err)
+	assert.Equal(t, "ssm-fips.us-east-1.amazonaws.com", endpoint)
+
+	endpoint, err = GetFipsEndpoint("us-gov-west-1", "kms")
+	assert.NoError(t, err)
+	assert.Equal(t, "kms-fips.us-gov-west-1.amazonaws.com", endpoint)
+}
+
+func TestGetFipsEndpointForRegionWithoutFipsEndpoints(t *testing.T) {
+	endpoint, err := GetFipsEndpoint("eu-west-1", "ssm")
+	assert.Error(t, err)
+	assert.Equal(t, "", endpoint)
+}
+
+func TestUseFipsEndpoint(t *testing.T) {
+	FipsEnabled = func() bool { return false }
+	defer func() { FipsEnabled = fipsEnabledInConfig }()
+	sess := session.New(&aws.Config{Region: aws.String("us-east-1")})
+	assert.False(t, UseFipsEndpoint(sess, "us-east-1", "s3"))
+	assert.Nil(t, sess.Config.Endpoint)
+
+	FipsEnabled = func() bool { return true }
+	assert.True(t, UseFipsEndpoint(sess, "us-east-1", "s3"))
+	assert.Equal(t, "s3-fips.us-east-1.amazonaws.com", aws.StringValue(sess.Config.Endpoint))
+	assert.Equal(t, "https://s3-fips.us-east-1.amazonaws.com", s3.New(sess).Endpoint)
+
+	// requests fail rather than using the regular endpoint of regions without FIPS endpoints
+	sess = session.New(&aws.Config{Region: aws.String("eu-west-1"), Credentials: credentials.AnonymousCredentials})
+	assert.True(t, UseFipsEndpoint(sess, "eu-west-1", "s3"))
+	_, err := s3.New(sess).ListBuckets(&s3.ListBucketsInput{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "has no FIPS endpoint in region eu-west-1")
+}
+
+func TestFipsEndpointUnavailableHandler(t *testing.T) {
+	err := errors.New("no FIPS endpoint")
+	req := &request.Request{}
+	FipsEndpointUnavailableHandler(err).Fn(req)
+	assert.Equal(t, err, req.Error)
+}
+
+func TestGetDualStackEndpoint(t *testing.T) {
+	assert.Equal(t, "ssmmessages.us-east-1.api.aws", GetDualStackEndpoint("us-east-1", MgsServiceName))
+	assert.Equal(t, "ssmmessages.cn-north-1.api.amazonwebservices.com.cn", GetDualStackEndpoint("cn-north-1", MgsServiceName))
+}
+
+func TestIPv6LiteralHost(t *testing.T) {
+	for endpoint, expected := range map[string]string{
+		"2001:db8::1":       "[2001:db8::1]",
+		"[2001:db8::1]":     "[2001:db8::1]",
+		"[2001:db8::1]:443": "[2001:db8::1]:443",
+	} {
+		host, ok := ipv6LiteralHost(endpoint)
+		assert.True(t, ok)
+		assert.Equal(t, expected, host)
+	}
+
+	for _, endpoint := range []string{"192.0.2.1", "192.0.2.1:443", "ssmmessages.us-east-1.amazonaws.com", "https://[2001:db8::1]"} {
+		_, ok := ipv6LiteralHost(endpoint)
+		assert.False(t, ok)
+	}
+}
@@ -52,13 +52,26 @@ var awsMessageGatewayServiceEndpointMap = map[string]string{
 }
 
 /* This function returns the mgs endpoint specified by the user in appconfig.
-If the user didn't specify one, it will return the Amazon MGS endpoint in a certain region
+If the user didn't specify one, it will return the Amazon MGS endpoint in a certain region,
+or an empty endpoint if FIPS is enabled and the region has no FIPS endpoint
 */
 func GetMgsEndpoint(region string) (mgsEndpoint string) {
 	if appConfig, err := appconfig.Config(false); err == nil {
+		if appConfig.Mgs.Endpoint == "" && FipsEnabled() {
+			// no endpoint is returned rather than the regular endpoint if the region has no FIPS endpoint
+			fipsEndpoint, _ := GetFipsEndpoint(region, MgsServiceName)
+			return fipsEndpoint
+		}
+		if appConfig.Mgs.Endpoint == "" && region != "" && useDualStackEndpoint(appConfig.Mgs.IPAddressPreference) {
+			return GetDualStackEndpoint(region, MgsServiceName)
+		}
 		if appConfig.Mgs.Endpoint != "" {
 			// use net/url package to parse endpoint, if endpoint doesn't contain protocol,
 			// fullUrl.Host is empty, should return fullUrl.Path. For backwards compatible, return the non-empty one.
+			// IPv6 addresses without protocol are returned directly as url.Parse rejects them.
+			if host, ok := ipv6LiteralHost(appConfig.Mgs.Endpoint); ok {
+				return host
+			}
 			fullUrl, err := url.Parse(appConfig.Mgs.Endpoint)
 			if err != nil {
 				return ""
@@ -25,6 +25,8 @@ import (
 // CancelPayload represents the json structure of a cancel command MDS message payload.
 type CancelPayload struct {
 	CancelMessageID string `json:"CancelMessageId"`
+	// PluginID cancels only the step with this id, the steps after it still run
+	PluginID string `json:"PluginId"`
 }
 
 // SendCommandPayload parallels the structure of a send command MDS message payload.
@@ -37,6 +39,7 @@ type SendCommandPayload struct {
 	OutputS3BucketName      string                    `json:"OutputS3BucketName"`
 	CloudWatchLogGroupName  string                    `json:"CloudWatchLogGroupName"`
 	CloudWatchOutputEnabled string                    `json:"CloudWatchOutputEnabled"`
+	DryRun                  bool                      `json:"DryRun"`
 }
 
 // SendReplyPayload represents the json structure of a reply sent to MDS.
@@ -29,6 +29,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/rip"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -87,6 +88,7 @@ var clientBasedErrorMessages, serverBasedErrorMessages []string
 func NewService(region string, endpoint string, creds *credentials.Credentials, connectionTimeout time.Duration) Service {
 
 	config := sdkutil.AwsConfig()
+	var fipsErr error
 
 	if region != "" {
 		config.Region = &region
@@ -96,7 +98,12 @@ func NewService(region string, endpoint string, creds *credentials.Credentials,
 		config.Endpoint = &endpoint
 	} else {
 		if region, err := platform.Region(); err == nil {
-			if defaultEndpoint := platform.GetDefaultEndPoint(region, "ec2messages"); defaultEndpoint != "" {
+			if rip.FipsEnabled() {
+				var fipsEndpoint string
+				if fipsEndpoint, fipsErr = rip.GetFipsEndpoint(region, "ec2messages"); fipsErr == nil {
+					config.Endpoint = &fipsEndpoint
+				}
+			} else if defaultEndpoint := platform.GetDefaultEndPoint(region, "ec2messages"); defaultEndpoint != "" {
 				config.Endpoint = &defaultEndpoint
 			}
 		}
@@ -120,6 +127,9 @@ func NewService(region string, endpoint string, creds *credentials.Credentials,
 	appConfig, _ := appconfig.Config(false)
 	sess := session.New(config)
 	sess.Handlers.Build.PushBack(request.MakeAddToUserAgentHandler(appConfig.Agent.Name, appConfig.Agent.Version))
+	if fipsErr != nil {
+		sess.Handlers.Validate.PushBackNamed(rip.FipsEndpointUnavailableHandler(fipsErr))
+	}
 
 	msgSvc := ssmmds.New(sess)
 
@@ -104,7 +104,12 @@ func parseCancelCommandMessage(context context.T, msg *ssmmds.Message, messagesO
 	commandID, _ := messageContracts.GetCommandID(payload.CancelMessageID)
 
 	cancelCommand.CancelCommandID = commandID
-	cancelCommand.DebugInfo = fmt.Sprintf("Command %v is yet to be cancelled", commandID)
+	cancelCommand.CancelPluginID = payload.PluginID
+	if payload.PluginID != "" {
+		cancelCommand.DebugInfo = fmt.Sprintf("Step %v of command %v is yet to be cancelled", payload.PluginID, commandID)
+	} else {
+		cancelCommand.DebugInfo = fmt.Sprintf("Command %v is yet to be cancelled", commandID)
+	}
 
 	var documentType contracts.DocumentType
 	if strings.HasPrefix(*msg.Topic, string(CancelCommandTopicPrefixOffline)) {
@@ -130,24 +135,41 @@ func generateCloudWatchLogStreamPrefix(commandID string) (string, error) {
 	return fmt.Sprintf("%s/%s", commandID, instanceID), nil
 }
 
-func generateCloudWatchConfigFromPayload(parsedMessage messageContracts.SendCommandPayload) (contracts.CloudWatchConfiguration, error) {
+// generateCloudWatchConfigFromPayload creates the cloudWatch output config of the command. The output is streamed to the log group
+// of the command if it enables CloudWatch output, otherwise to the RunCommandOutputLogGroup of the agent, if any.
+func generateCloudWatchConfigFromPayload(ssmConfig appconfig.SsmCfg, parsedMessage messageContracts.SendCommandPayload) (contracts.CloudWatchConfiguration, error) {
 	cloudWatchOutputEnabled, err := strconv.ParseBool(parsedMessage.CloudWatchOutputEnabled)
 	cloudWatchConfig := contracts.CloudWatchConfiguration{}
+	var logGroupName string
 	if err != nil || !cloudWatchOutputEnabled {
-		return cloudWatchConfig, err
+		if ssmConfig.RunCommandOutputLogGroup == "" {
+			return cloudWatchConfig, err
+		}
+		logGroupName = ssmConfig.RunCommandOutputLogGroup
+	} else if parsedMessage.CloudWatchLogGroupName != "" {
+		logGroupName = parsedMessage.CloudWatchLogGroupName
+	} else {
+		logGroupName = fmt.Sprintf("%s%s", CloudWatchLogGroupNamePrefix, parsedMessage.DocumentName)
 	}
 	cloudWatchConfig.LogStreamPrefix, err = generateCloudWatchLogStreamPrefix(parsedMessage.CommandID)
 	if err != nil {
-		return cloudWatchConfig, err
-	}
-	if parsedMessage.CloudWatchLogGroupName != "" {
-		cloudWatchConfig.LogGroupName = parsedMessage.CloudWatchLogGroupName
-	} else {
-		cloudWatchConfig.LogGroupName = fmt.Sprintf("%s%s", CloudWatchLogGroupNamePrefix, parsedMessage.DocumentName)
+		return contracts.CloudWatchConfiguration{}, err
 	}
+	cloudWatchConfig.LogGroupName = logGroupName
+	cloudWatchConfig.FlushIntervalSeconds = outputFlushIntervalSeconds(ssmConfig, parsedMessage.DocumentContent)
 	return cloudWatchConfig, nil
 }
 
+// outputFlushIntervalSeconds returns the flush interval of the document when it sets one within the allowed range,
+// otherwise the RunCommandOutputFlushIntervalSeconds of the agent.
+func outputFlushIntervalSeconds(ssmConfig appconfig.SsmCfg, documentContent contracts.DocumentContent) int {
+	interval := documentContent.OutputFlushIntervalSeconds
+	if interval < appconfig.DefaultRunCommandOutputFlushIntervalSecondsMin || interval > appconfig.DefaultRunCommandOutputFlushIntervalSecondsMax {
+		return ssmConfig.RunCommandOutputFlushIntervalSeconds
+	}
+	return interval
+}
+
 func parseSendCommandMessage(context context.T, msg *ssmmds.Message, messagesOrchestrationRootDir string) (*contracts.DocumentState, error) {
 	log := context.Log()
 	commandID, _ := messageContracts.GetCommandID(*msg.MessageId)
@@ -167,7 +189,7 @@ func parseSendCommandMessage(context context.T, msg *ssmmds.Message, messagesOrc
 	// adapt plugin configuration format from MDS to plugin expected format
 	s3KeyPrefix := path.Join(parsedMessage.OutputS3KeyPrefix, parsedMessage.CommandID, *msg.Destination)
 
-	cloudWatchConfig, err := generateCloudWatchConfigFromPayload(parsedMessage)
+	cloudWatchConfig, err := generateCloudWatchConfigFromPayload(context.AppConfig().Ssm, parsedMessage)
 	if err != nil {
 		log.Errorf("Encountered error while generating cloudWatch config from send command payload, err: %s", err)
 	}
@@ -188,6 +210,8 @@ func parseSendCommandMessage(context context.T, msg *ssmmds.Message, messagesOrc
 		MessageId:        documentInfo.MessageID,
 		DocumentId:       documentInfo.DocumentID,
 		CloudWatchConfig: cloudWatchConfig,
+		SpillS3Bucket:    context.AppConfig().Ssm.RunCommandOutputSpillS3BucketName,
+		DryRun:           parsedMessage.DryRun,
 	}
 
 	docContent := &docparser.DocContent{
@@ -195,7 +219,8 @@ func parseSendCommandMessage(context context.T, msg *ssmmds.Message, messagesOrc
 		Description:   parsedMessage.DocumentContent.Description,
 		RuntimeConfig: parsedMessage.DocumentContent.RuntimeConfig,
 		MainSteps:     parsedMessage.DocumentContent.MainSteps,
-		Parameters:    parsedMessage.DocumentContent.Parameters}
+		Parameters:    parsedMessage.DocumentContent.Parameters,
+		RunAsUser:     parsedMessage.DocumentContent.RunAsUser}
 	//Data format persisted in Current Folder is defined by the struct - CommandState
 	docState, err := docparser.InitializeDocState(log, documentType, docContent, documentInfo, parserInfo, parsedMessage.Parameters)
 	if err != nil {
@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
 	messageContracts "github.com/aws/amazon-ssm-agent/agent/runcommand/contracts"
 	"github.com/stretchr/testify/assert"
@@ -35,7 +36,7 @@ func TestGenerateCloudWatchConfigWithOutputEnabled(t *testing.T) {
 	expectedLogStreamName := fmt.Sprintf("%s/%s", testCommandID, testInstanceID)
 	mockParsedMessage := getSampleParsedMessage("", "true")
 
-	cloudWatchConfig, err := generateCloudWatchConfigFromPayload(mockParsedMessage)
+	cloudWatchConfig, err := generateCloudWatchConfigFromPayload(appconfig.SsmCfg{}, mockParsedMessage)
 	assert.Nil(t, err)
 	assert.Equal(t, expectedLogGroupName, cloudWatchConfig.LogGroupName)
 	assert.Equal(t, expectedLogStreamName, cloudWatchConfig.LogStreamPrefix)
@@ -47,7 +48,7 @@ func TestGenerateCloudWatchConfigWithLogGroupNameAndOutputEnabled(t *testing.T)
 	expectedLogGroupName := "myLogGroupName"
 	mockParsedMessage := getSampleParsedMessage(expectedLogGroupName, "true")
 
-	cloudWatchConfig, err := generateCloudWatchConfigFromPayload(mockParsedMessage)
+	cloudWatchConfig, err := generateCloudWatchConfigFromPayload(appconfig.SsmCfg{}, mockParsedMessage)
 	assert.Nil(t, err)
 	assert.Equal(t, expectedLogGroupName, cloudWatchConfig.LogGroupName)
 	assert.Equal(t, expectedLogStreamName, cloudWatchConfig.LogStreamPrefix)
@@ -55,21 +56,21 @@ func TestGenerateCloudWatchConfigWithLogGroupNameAndOutputEnabled(t *testing.T)
 
 func TestGenerateCloudWatchConfigWithOutputNotEnabled(t *testing.T) {
 	mockParsedMessage := getSampleParsedMessage("", "false")
-	cloudWatchConfig, err := generateCloudWatchConfigFromPayload(mockParsedMessage)
+	cloudWatchConfig, err := generateCloudWatchConfigFromPayload(appconfig.SsmCfg{}, mockParsedMessage)
 	assert.Nil(t, err)
 	assert.Equal(t, contracts.CloudWatchConfiguration{}, cloudWatchConfig)
 }
 
 func TestGenerateCloudWatchConfigWithLogGroupNameAndOutputNotEnabled(t *testing.T) {
 	mockParsedMessage := getSampleParsedMessage(testLogGroupName, "false")
-	cloudWatchConfig, err := generateCloudWatchConfigFromPayload(mockParsedMessage)
+	cloudWatchConfig, err := generateCloudWatchConfigFromPayload(appconfig.SsmCfg{}, mockParsedMessage)
 	assert.Nil(t, err)
 	assert.Equal(t, contracts.CloudWatchConfiguration{}, cloudWatchConfig)
 }
 
 func TestGenerateCloudWatchConfigWithEmptyCloudWatchConfigInPayload(t *testing.T) {
 	mockParsedMessage := getSampleParsedMessage("", "")
-	cloudWatchConfig, err := generateCloudWatchConfigFromPayload(mockParsedMessage)
+	cloudWatchConfig, err := generateCloudWatchConfigFromPayload(appconfig.SsmCfg{}, mockParsedMessage)
 	assert.Equal(t, contracts.CloudWatchConfiguration{}, cloudWatchConfig)
 	assert.NotNil(t, err)
 }
@@ -79,11 +80,48 @@ func TestGenerateCloudWatchConfigWithoutEmptyValuesInParsedMessage(t *testing.T)
 		CommandID:    testCommandID,
 		DocumentName: testDocumentName,
 	}
-	cloudWatchConfig, err := generateCloudWatchConfigFromPayload(emptyParsedMessage)
+	cloudWatchConfig, err := generateCloudWatchConfigFromPayload(appconfig.SsmCfg{}, emptyParsedMessage)
 	assert.Equal(t, contracts.CloudWatchConfiguration{}, cloudWatchConfig)
 	assert.NotNil(t, err)
 }
 
+func TestGenerateCloudWatchConfigWithAgentLogGroupAndOutputNotEnabled(t *testing.T) {
+	systemInfo = &systemStub{}
+	ssmConfig := appconfig.SsmCfg{RunCommandOutputLogGroup: "agentLogGroup", RunCommandOutputFlushIntervalSeconds: 1}
+	expectedLogStreamName := fmt.Sprintf("%s/%s", testCommandID, testInstanceID)
+
+	for _, outputEnabled := range []string{"false", ""} {
+		cloudWatchConfig, err := generateCloudWatchConfigFromPayload(ssmConfig, getSampleParsedMessage(testLogGroupName, outputEnabled))
+		assert.Nil(t, err)
+		assert.Equal(t, "agentLogGroup", cloudWatchConfig.LogGroupName)
+		assert.Equal(t, expectedLogStreamName, cloudWatchConfig.LogStreamPrefix)
+		assert.Equal(t, 1, cloudWatchConfig.FlushIntervalSeconds)
+	}
+}
+
+func TestGenerateCloudWatchConfigWithAgentLogGroupAndOutputEnabled(t *testing.T) {
+	systemInfo = &systemStub{}
+	ssmConfig := appconfig.SsmCfg{RunCommandOutputLogGroup: "agentLogGroup", RunCommandOutputFlushIntervalSeconds: 10}
+
+	cloudWatchConfig, err := generateCloudWatchConfigFromPayload(ssmConfig, getSampleParsedMessage(testLogGroupName, "true"))
+	assert.Nil(t, err)
+	assert.Equal(t, testLogGroupName, cloudWatchConfig.LogGroupName)
+	assert.Equal(t, 10, cloudWatchConfig.FlushIntervalSeconds)
+}
+
+func TestGenerateCloudWatchConfigWithDocumentFlushInterval(t *testing.T) {
+	systemInfo = &systemStub{}
+	ssmConfig := appconfig.SsmCfg{RunCommandOutputFlushIntervalSeconds: 10}
+
+	for documentInterval, expectedInterval := range map[int]int{0: 10, 1: 1, 30: 30, -1: 10, 3600: 10} {
+		parsedMessage := getSampleParsedMessage(testLogGroupName, "true")
+		parsedMessage.DocumentContent.OutputFlushIntervalSeconds = documentInterval
+		cloudWatchConfig, err := generateCloudWatchConfigFromPayload(ssmConfig, parsedMessage)
+		assert.Nil(t, err)
+		assert.Equal(t, expectedInterval, cloudWatchConfig.FlushIntervalSeconds, "document interval %v", documentInterval)
+	}
+}
+
 //getSampleParsedMessage returns a mocked SendCommandPayload
 func getSampleParsedMessage(logGroupName string, outputEnabled string) messageContracts.SendCommandPayload {
 
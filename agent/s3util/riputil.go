@@ -18,6 +18,7 @@ package s3util
 
 import "github.com/aws/amazon-ssm-agent/agent/appconfig"
 import "github.com/aws/amazon-ssm-agent/agent/platform"
+import "github.com/aws/amazon-ssm-agent/agent/rip"
 
 var awsS3EndpointMap = map[string]string{
 	//AUTOGEN_START
@@ -41,13 +42,19 @@ var awsS3EndpointMap = map[string]string{
 }
 
 /* This function returns the s3 endpoint specified by the user in appconfig.
-If the user didn't specify one, it will return the Amazon S3 endpoint in a certain region
+If the user didn't specify one, it will return the Amazon S3 endpoint in a certain region,
+or an empty endpoint if FIPS is enabled and the region has no FIPS endpoint
 */
 func GetS3Endpoint(region string) (s3Endpoint string) {
 	if appConfig, err := appconfig.Config(false); err == nil {
 		if appConfig.S3.Endpoint != "" {
 			return appConfig.S3.Endpoint
 		}
+		if rip.FipsEnabled() {
+			// no endpoint is returned rather than the regular endpoint if the region has no FIPS endpoint
+			fipsEndpoint, _ := rip.GetFipsEndpoint(region, "s3")
+			return fipsEndpoint
+		}
 	}
 
 	if s3Endpoint, ok := awsS3EndpointMap[region]; ok {
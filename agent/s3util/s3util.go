@@ -24,6 +24,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/rip"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/request"
@@ -48,10 +49,20 @@ type AmazonS3Util struct {
 }
 
 func NewAmazonS3Util(log log.T, bucketName string) *AmazonS3Util {
+	return &AmazonS3Util{
+		myUploader: NewUploader(log, bucketName),
+	}
+}
 
+// NewUploader returns an uploader to the bucket configured with the endpoint of the agent config and the bucket region
+func NewUploader(log log.T, bucketName string) *s3manager.Uploader {
 	httpProvider := HttpProviderImpl{}
 	bucketRegion := GetBucketRegion(log, bucketName, httpProvider)
+	return newUploader(log, bucketRegion)
+}
 
+// newUploader returns an uploader to buckets in the region, using the FIPS endpoint of the region if FIPS is enabled
+func newUploader(log log.T, bucketRegion string) *s3manager.Uploader {
 	config := sdkutil.AwsConfig()
 	var appConfig appconfig.SsmagentConfig
 	appConfig, errConfig := appconfig.Config(false)
@@ -74,10 +85,11 @@ func NewAmazonS3Util(log log.T, bucketName string) *AmazonS3Util {
 
 	sess := session.New(config)
 	sess.Handlers.Build.PushBack(request.MakeAddToUserAgentHandler(appConfig.Agent.Name, appConfig.Agent.Version))
-
-	return &AmazonS3Util{
-		myUploader: s3manager.NewUploader(sess),
+	if appConfig.S3.Endpoint == "" {
+		rip.UseFipsEndpoint(sess, bucketRegion, "s3")
 	}
+
+	return s3manager.NewUploader(sess)
 }
 
 // S3Upload uploads a file to s3.
@@ -164,6 +176,10 @@ func GetS3Header(log log.T, bucketName string, instanceRegion string, httpProvid
 	var region string
 
 	s3Endpoint := GetS3Endpoint(instanceRegion)
+	if s3Endpoint == "" {
+		log.Errorf("No S3 endpoint found for region %s", instanceRegion)
+		return ""
+	}
 	// Fail over to the generic regional end point, if different from the regional end point
 	genericEndPoint := GetS3GenericEndPoint(instanceRegion)
 	for retryCount := 1; retryCount <= 5; retryCount++ {
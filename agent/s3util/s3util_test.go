@@ -7,6 +7,9 @@ import (
 	"errors"
 
 	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/rip"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -172,3 +175,19 @@ func (m *MockedHttpProvider) Head(url string) (*http.Response, error) {
 	args := m.Called(url)
 	return args.Get(0).(*http.Response), args.Error(1)
 }
+
+func TestNewUploaderUsesFipsEndpoint(t *testing.T) {
+	platform.SetRegion("us-east-1")
+	fipsEnabled := rip.FipsEnabled
+	rip.FipsEnabled = func() bool { return true }
+	defer func() { rip.FipsEnabled = fipsEnabled }()
+
+	uploader := newUploader(log.NewMockLog(), "us-west-2")
+	assert.Equal(t, "https://s3-fips.us-west-2.amazonaws.com", uploader.S3.(*s3.S3).Endpoint)
+
+	// uploads to regions without FIPS endpoints fail instead of using the regular endpoint
+	uploader = newUploader(log.NewMockLog(), "eu-west-1")
+	_, err := uploader.S3.(*s3.S3).ListBuckets(&s3.ListBucketsInput{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no FIPS endpoint")
+}
@@ -0,0 +1,64 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// communicator package implement base communicator for network connections.
+package communicator
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// fipsCipherSuites are the TLS 1.2 cipher suites offered to the service in FIPS mode.
+var fipsCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+// fipsApprovedCipherSuites also holds the TLS 1.3 cipher suites approved for FIPS,
+// since TLS 1.3 cipher suites are not configurable and are checked once the connection is established.
+var fipsApprovedCipherSuites = map[uint16]bool{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256: true,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256:   true,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384: true,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384:   true,
+	tls.TLS_AES_128_GCM_SHA256:                  true,
+	tls.TLS_AES_256_GCM_SHA384:                  true,
+}
+
+// fipsTLSConfig returns the TLS configuration of websocket connections in FIPS mode based on the given configuration.
+func fipsTLSConfig(base *tls.Config) *tls.Config {
+	config := &tls.Config{}
+	if base != nil {
+		config = base.Clone()
+	}
+	config.MinVersion = tls.VersionTLS12
+	config.CipherSuites = fipsCipherSuites
+	return config
+}
+
+// checkFipsConnection returns an error if the connection is not encrypted with a FIPS approved TLS cipher suite.
+func checkFipsConnection(conn net.Conn) error {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return errors.New("connection is not encrypted with TLS")
+	}
+	if cipherSuite := tlsConn.ConnectionState().CipherSuite; !fipsApprovedCipherSuites[cipherSuite] {
+		return fmt.Errorf("TLS cipher suite %#04x is not FIPS approved", cipherSuite)
+	}
+	return nil
+}
@@ -15,8 +15,10 @@
 package communicator
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"path"
@@ -66,6 +68,21 @@ type WebSocketChannel struct {
 	Region       string
 	IsOpen       bool
 	writeLock    *sync.Mutex
+	// CompressionEnabled negotiates permessage-deflate with the service, messages are sent
+	// compressed at CompressionLevel when the service accepts it
+	CompressionEnabled bool
+	CompressionLevel   int
+	// FipsEnabled refuses connections that are not encrypted with FIPS approved cryptography
+	FipsEnabled bool
+	// IPAddressPreference restricts the connection to IPv4 or IPv6 addresses
+	IPAddressPreference string
+	// TLSConfig holds the server name and certificate pins of the endpoint, nil for the defaults
+	TLSConfig *tls.Config
+	// PingInterval is how often pings are sent, WebSocketPingInterval if zero
+	PingInterval time.Duration
+	// PongTimeout is how long after a ping a pong or any message must be received before the connection
+	// is considered dead, zero disables dead-connection detection
+	PongTimeout time.Duration
 }
 
 // Initialize a WebSocketChannel object.
@@ -110,6 +127,18 @@ func (webSocketChannel *WebSocketChannel) Initialize(context context.T,
 	webSocketChannel.ChannelToken = channelToken
 	webSocketChannel.OnError = onErrorHandler
 	webSocketChannel.OnMessage = onMessageHandler
+	webSocketChannel.CompressionEnabled = context.AppConfig().Mgs.WebSocketCompressionEnabled
+	webSocketChannel.CompressionLevel = context.AppConfig().Mgs.WebSocketCompressionLevel
+	webSocketChannel.FipsEnabled = context.AppConfig().Agent.FipsEnabled
+	webSocketChannel.IPAddressPreference = context.AppConfig().Mgs.IPAddressPreference
+	webSocketChannel.TLSConfig = mgsconfig.GetEndpointTLSConfig(context.AppConfig().Mgs)
+	if channelType == mgsconfig.ControlChannel {
+		webSocketChannel.PingInterval = time.Duration(context.AppConfig().Mgs.ControlChannelPingIntervalSeconds) * time.Second
+		webSocketChannel.PongTimeout = time.Duration(context.AppConfig().Mgs.ControlChannelPongTimeoutSeconds) * time.Second
+	} else {
+		webSocketChannel.PingInterval = time.Duration(context.AppConfig().Mgs.DataChannelPingIntervalSeconds) * time.Second
+		webSocketChannel.PongTimeout = time.Duration(context.AppConfig().Mgs.DataChannelPongTimeoutSeconds) * time.Second
+	}
 
 	return nil
 }
@@ -156,14 +185,50 @@ func (webSocketChannel *WebSocketChannel) Open(log log.T) error {
 		log.Errorf("Failed to get the v4 signature, %v", err)
 	}
 
-	ws, err := websocketutil.NewWebsocketUtil(log, nil).OpenConnection(webSocketChannel.Url, header)
+	dialer := *websocket.DefaultDialer
+	dialer.EnableCompression = webSocketChannel.CompressionEnabled
+	dialer.TLSClientConfig = webSocketChannel.TLSConfig
+	if webSocketChannel.FipsEnabled {
+		dialer.TLSClientConfig = fipsTLSConfig(webSocketChannel.TLSConfig)
+	}
+	if network := mgsconfig.GetDialNetwork(webSocketChannel.IPAddressPreference); network != "tcp" {
+		// the network applies to the proxy connection when a proxy is used
+		dialer.NetDial = func(_, address string) (net.Conn, error) {
+			return net.Dial(network, address)
+		}
+	}
+	ws, err := websocketutil.NewWebsocketUtil(log, &dialer).OpenConnection(webSocketChannel.Url, header)
 	if err != nil {
 		return err
 	}
+	if webSocketChannel.FipsEnabled {
+		if err = checkFipsConnection(ws.UnderlyingConn()); err != nil {
+			ws.Close()
+			return fmt.Errorf("refusing websocket connection without FIPS approved cryptography: %v", err)
+		}
+	}
+	if webSocketChannel.CompressionEnabled && webSocketChannel.CompressionLevel != 0 {
+		if err = ws.SetCompressionLevel(webSocketChannel.CompressionLevel); err != nil {
+			log.Warnf("Unable to set websocket compression level %d: %v", webSocketChannel.CompressionLevel, err)
+		}
+	}
 
 	webSocketChannel.Connection = ws
 	webSocketChannel.IsOpen = true
-	webSocketChannel.StartPings(log, mgsconfig.WebSocketPingInterval)
+	pingInterval := webSocketChannel.PingInterval
+	if pingInterval == 0 {
+		pingInterval = mgsconfig.WebSocketPingInterval
+	}
+	if webSocketChannel.PongTimeout > 0 {
+		// reads fail once nothing is received for a ping interval plus the pong timeout,
+		// so that a dead connection is reported to OnError and reconnected
+		readTimeout := pingInterval + webSocketChannel.PongTimeout
+		ws.SetReadDeadline(time.Now().Add(readTimeout))
+		ws.SetPongHandler(func(string) error {
+			return ws.SetReadDeadline(time.Now().Add(readTimeout))
+		})
+	}
+	webSocketChannel.StartPings(log, pingInterval)
 
 	// spin up a different routine to listen to the incoming traffic
 	go func() {
@@ -203,6 +268,9 @@ func (webSocketChannel *WebSocketChannel) Open(log log.T) error {
 
 			} else {
 				retryCount = 0
+				if webSocketChannel.PongTimeout > 0 {
+					webSocketChannel.Connection.SetReadDeadline(time.Now().Add(pingInterval + webSocketChannel.PongTimeout))
+				}
 
 				webSocketChannel.OnMessage(rawMessage)
 			}
@@ -15,13 +15,19 @@
 package communicator
 
 import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
@@ -93,6 +99,184 @@ func TestInitialize(t *testing.T) {
 	assert.Equal(t, signer, webDataChannel.Signer)
 }
 
+func TestOpenWebSocketChannelWithCompression(t *testing.T) {
+	extensions := make(chan string, 1)
+	compressionUpgrader := websocket.Upgrader{EnableCompression: true}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		extensions <- req.Header.Get("Sec-Websocket-Extensions")
+		conn, err := compressionUpgrader.Upgrade(w, req, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.ReadMessage()
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	u.Scheme = "ws"
+	var log = log.NewMockLog()
+
+	websocketchannel := WebSocketChannel{
+		Url:                u.String(),
+		CompressionEnabled: true,
+		CompressionLevel:   9,
+	}
+
+	err := websocketchannel.Open(log)
+	assert.Nil(t, err, "Error opening the websocket connection.")
+	assert.Contains(t, <-extensions, "permessage-deflate")
+	websocketchannel.Close(log)
+}
+
+func TestOpenWebSocketChannelWithIPAddressPreference(t *testing.T) {
+	// httptest servers listen on the IPv4 loopback address
+	srv := httptest.NewServer(http.HandlerFunc(handlerToBeTested))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	u.Scheme = "ws"
+	var log = log.NewMockLog()
+
+	ipv4Channel := WebSocketChannel{
+		Url:                 u.String(),
+		IPAddressPreference: appconfig.IPAddressPreferenceIPv4,
+	}
+	err := ipv4Channel.Open(log)
+	assert.Nil(t, err)
+	ipv4Channel.Close(log)
+
+	ipv6Channel := WebSocketChannel{
+		Url:                 u.String(),
+		IPAddressPreference: appconfig.IPAddressPreferenceIPv6,
+	}
+	err = ipv6Channel.Open(log)
+	assert.NotNil(t, err)
+}
+
+func TestOpenWebSocketChannelWithCertificatePins(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(handlerToBeTested))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	u.Scheme = "wss"
+	var log = log.NewMockLog()
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(srv.Certificate())
+	hash := sha256.Sum256(srv.Certificate().RawSubjectPublicKeyInfo)
+
+	for pin, expectOpen := range map[string]bool{
+		base64.StdEncoding.EncodeToString(hash[:]):                   true,
+		base64.StdEncoding.EncodeToString(make([]byte, sha256.Size)): false,
+	} {
+		tlsConfig := mgsConfig.GetEndpointTLSConfig(appconfig.MgsConfig{
+			EndpointServerName:      "example.com",
+			EndpointCertificatePins: []string{pin},
+		})
+		tlsConfig.RootCAs = rootCAs
+		websocketchannel := WebSocketChannel{
+			Url:       u.String(),
+			TLSConfig: tlsConfig,
+		}
+
+		err := websocketchannel.Open(log)
+		assert.Equal(t, expectOpen, err == nil)
+		if err == nil {
+			websocketchannel.Close(log)
+		}
+	}
+}
+
+func TestOpenWebSocketChannelRefusesUnencryptedConnectionInFipsMode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(handlerToBeTested))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	u.Scheme = "ws"
+	var log = log.NewMockLog()
+
+	websocketchannel := WebSocketChannel{
+		Url:         u.String(),
+		FipsEnabled: true,
+	}
+
+	err := websocketchannel.Open(log)
+	assert.NotNil(t, err)
+	assert.False(t, websocketchannel.IsOpen)
+}
+
+func TestFipsTLSConfigOffersApprovedCipherSuites(t *testing.T) {
+	config := fipsTLSConfig(&tls.Config{ServerName: "gateway.example.com"})
+
+	assert.Equal(t, "gateway.example.com", config.ServerName)
+	assert.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+	for _, cipherSuite := range config.CipherSuites {
+		assert.True(t, fipsApprovedCipherSuites[cipherSuite])
+	}
+	assert.False(t, fipsApprovedCipherSuites[tls.TLS_CHACHA20_POLY1305_SHA256])
+}
+
+func TestOpenWebSocketChannelDetectsDeadConnection(t *testing.T) {
+	// the server never reads, so pings are not answered
+	blocked := make(chan bool)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := upgrader.Upgrade(w, req, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		<-blocked
+	}))
+	defer srv.Close()
+	defer close(blocked)
+	u, _ := url.Parse(srv.URL)
+	u.Scheme = "ws"
+	var log = log.NewMockLog()
+
+	errors := make(chan error, 1)
+	websocketchannel := WebSocketChannel{
+		Url:          u.String(),
+		PingInterval: 100 * time.Millisecond,
+		PongTimeout:  100 * time.Millisecond,
+		OnError: func(err error) {
+			errors <- err
+		},
+	}
+
+	err := websocketchannel.Open(log)
+	assert.Nil(t, err)
+	select {
+	case err = <-errors:
+		assert.NotNil(t, err)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "dead connection was not detected")
+	}
+	websocketchannel.Close(log)
+}
+
+func TestOpenWebSocketChannelKeepsAnsweredConnection(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(handlerToBeTested))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	u.Scheme = "ws"
+	var log = log.NewMockLog()
+
+	errors := make(chan error, 1)
+	websocketchannel := WebSocketChannel{
+		Url:          u.String(),
+		PingInterval: 100 * time.Millisecond,
+		PongTimeout:  100 * time.Millisecond,
+		OnError: func(err error) {
+			errors <- err
+		},
+	}
+
+	err := websocketchannel.Open(log)
+	assert.Nil(t, err)
+	select {
+	case err = <-errors:
+		assert.Fail(t, "connection answering pings was considered dead", err.Error())
+	case <-time.After(time.Second):
+	}
+	websocketchannel.Close(log)
+}
+
 func TestGetChannelToken(t *testing.T) {
 	webControlChannel := &WebSocketChannel{ChannelToken: token}
 
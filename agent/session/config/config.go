@@ -15,9 +15,12 @@
 package config
 
 import (
+	"math/rand"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/rip"
+	"github.com/aws/amazon-ssm-agent/agent/session/retry"
 )
 
 const (
@@ -43,7 +46,10 @@ const (
 
 	// Buffer capacity of 100000 items with each buffer item of 1024 bytes leads to max usage of 100MB (100000 * 1024 bytes = 100MB) of instance memory.
 	// When changing StreamDataPayloadSize, make corresponding change to buffer capacity to ensure no more than 100MB of instance memory is used.
+	// Sessions using a different payload size, like port sessions using PortStreamDataPayloadSize for bulk throughput,
+	// scale the outgoing buffer capacity to keep the same memory usage.
 	StreamDataPayloadSize         = 1024
+	PortStreamDataPayloadSize     = 8192
 	OutgoingMessageBufferCapacity = 100000
 	IncomingMessageBufferCapacity = 100000
 
@@ -68,12 +74,60 @@ const (
 	ScreenBufferSize = 30000
 	Exit             = "exit"
 
+	// TranscriptFileName is the plain text transcript of the session output streamed to CloudWatch
+	TranscriptFileName = "transcriptTempFile"
+
+	// EncryptedLogFileExtension is appended to session logs that are encrypted on the instance before upload
+	EncryptedLogFileExtension = ".encrypted"
+	// EncryptionContextSessionIdKey is the KMS encryption context key holding the session id
+	EncryptionContextSessionIdKey = "aws:ssm:SessionId"
+
 	// ResumeReadExitCode indicates to resume reading from established connection.
 	ResumeReadExitCode = -1
 	// LocalPortForwarding is one of types supported by port plugin and is used to differentiate handling of error
 	// with scenario of sshd server port forwarding.
 	LocalPortForwarding = "LocalPortForwarding"
 
+	// PortReadyProbeInterval is the interval between connection attempts while waiting for a port to start listening.
+	PortReadyProbeInterval = 2 * time.Second
+	// PortReconnectInitialDelayMillis is the delay before the first retry when reconnecting to a port.
+	PortReconnectInitialDelayMillis = 100
+	// DefaultPortReadyTimeout is the default time to wait for a port to start listening before failing the session.
+	DefaultPortReadyTimeout = 5 * time.Minute
+	// PortReadyProgressMsg is shown to the user while waiting for a port to start listening.
+	PortReadyProgressMsg = "Waiting for port %s to start listening, %v remaining.\r\n"
+
+	// IdleTimeoutCheckInterval is the interval at which shell sessions are checked for inactivity.
+	IdleTimeoutCheckInterval = 10 * time.Second
+	// IdleTimeoutWarningPeriod is how long before an idle shell session is terminated the user is warned.
+	IdleTimeoutWarningPeriod = 1 * time.Minute
+	IdleTimeoutWarningMsg    = "\r\nThis session will be terminated in %d seconds due to inactivity.\r\n"
+
+	// InputThrottledWarningMsg is shown to the user when the input of a shell session is throttled.
+	InputThrottledWarningMsg = "\r\nInput is being throttled to %d bytes per second.\r\n"
+	// InputTooLargeWarningMsg is shown to the user when an input message is discarded for exceeding the maximum size.
+	InputTooLargeWarningMsg = "\r\nInput of %d bytes was discarded as it exceeds the maximum of %d bytes.\r\n"
+
+	// SessionBannerMaxSize is the maximum number of bytes of the banner shown at the start of shell sessions.
+	SessionBannerMaxSize = 8 * 1024
+
+	// CloudWatchStreamingCompleteRetries is the number of upload intervals to wait at the end of a session
+	// for the remaining streamed session output to be uploaded to CloudWatch.
+	CloudWatchStreamingCompleteRetries = 10
+
+	// FileTransferChunkSize is the number of file bytes carried by each file transfer data message,
+	// leaving room for base64 encoding within PortStreamDataPayloadSize.
+	FileTransferChunkSize = 4096
+	// FileTransferPartialFileExtension is appended to files being uploaded until their checksum is verified.
+	FileTransferPartialFileExtension = ".partial"
+
+	// ExternalSessionPluginsFolderName is the folder within the plugins directory holding external session plugins.
+	ExternalSessionPluginsFolderName = "session"
+	// ExternalSessionPluginHandshakeTimeout is how long an external session plugin has to answer the handshake.
+	ExternalSessionPluginHandshakeTimeout = 30 * time.Second
+	// ExternalSessionPluginStopTimeout is how long an external session plugin has to exit after the session is closed.
+	ExternalSessionPluginStopTimeout = 5 * time.Second
+
 	CloudWatchEncryptionErrorMsg = "We couldn't start the session because encryption is not set up on the selected CloudWatch Logs log group. Either encrypt the log group or choose an option to enable logging without encryption."
 	S3EncryptionErrorMsg         = "We couldn't start the session because encryption is not set up on the selected Amazon S3 bucket. Either encrypt the bucket or choose an option to enable logging without encryption."
 )
@@ -81,3 +135,69 @@ const (
 var GetMgsEndpointFromRip = func(region string) string {
 	return rip.GetMgsEndpoint(region)
 }
+
+// GetDialNetwork returns the network used to connect to the message gateway service for the IP address preference.
+func GetDialNetwork(ipAddressPreference string) string {
+	switch ipAddressPreference {
+	case appconfig.IPAddressPreferenceIPv4:
+		return "tcp4"
+	case appconfig.IPAddressPreferenceIPv6:
+		return "tcp6"
+	default:
+		return "tcp"
+	}
+}
+
+// NewControlChannelRetryer returns the retryer reconnecting the control channel with the backoff of the configuration.
+func NewControlChannelRetryer(mgs appconfig.MgsConfig, callable func() (interface{}, error)) retry.ExponentialRetryer {
+	return newRetryer(callable,
+		intOrDefault(mgs.ControlChannelRetryInitialDelayMillis, ControlChannelRetryInitialDelayMillis),
+		floatOrDefault(mgs.ControlChannelRetryMultiplier, RetryGeometricRatio),
+		intOrDefault(mgs.ControlChannelRetryMaxDelayMillis, ControlChannelRetryMaxIntervalMillis),
+		mgs.ControlChannelRetryJitter,
+		ControlChannelNumMaxRetries)
+}
+
+// NewDataChannelRetryer returns the retryer reconnecting a data channel with the backoff of the configuration.
+func NewDataChannelRetryer(mgs appconfig.MgsConfig, callable func() (interface{}, error)) retry.ExponentialRetryer {
+	return newRetryer(callable,
+		intOrDefault(mgs.DataChannelRetryInitialDelayMillis, DataChannelRetryInitialDelayMillis),
+		floatOrDefault(mgs.DataChannelRetryMultiplier, RetryGeometricRatio),
+		intOrDefault(mgs.DataChannelRetryMaxDelayMillis, DataChannelRetryMaxIntervalMillis),
+		mgs.DataChannelRetryJitter,
+		DataChannelNumMaxAttempts)
+}
+
+// newRetryer returns an exponential retryer whose initial delay is randomized up to twice as long.
+func newRetryer(callable func() (interface{}, error),
+	initialDelayMillis int,
+	multiplier float64,
+	maxDelayMillis int,
+	jitter float64,
+	maxAttempts int) retry.ExponentialRetryer {
+
+	return retry.ExponentialRetryer{
+		CallableFunc:        callable,
+		GeometricRatio:      multiplier,
+		InitialDelayInMilli: rand.Intn(initialDelayMillis) + initialDelayMillis,
+		MaxDelayInMilli:     maxDelayMillis,
+		MaxAttempts:         maxAttempts,
+		JitterRatio:         jitter,
+	}
+}
+
+// intOrDefault returns the default for settings missing from the configuration.
+func intOrDefault(value int, defaultValue int) int {
+	if value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// floatOrDefault returns the default for settings missing from the configuration.
+func floatOrDefault(value float64, defaultValue float64) float64 {
+	if value <= 0 {
+		return defaultValue
+	}
+	return value
+}
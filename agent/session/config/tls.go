@@ -0,0 +1,59 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// config package implement configuration retrieval for the session package.
+package config
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// GetEndpointTLSConfig returns the TLS configuration of connections to the message gateway service,
+// nil when neither a server name nor certificate pins are configured.
+func GetEndpointTLSConfig(mgs appconfig.MgsConfig) *tls.Config {
+	if mgs.EndpointServerName == "" && len(mgs.EndpointCertificatePins) == 0 {
+		return nil
+	}
+	config := &tls.Config{
+		ServerName: mgs.EndpointServerName,
+	}
+	if len(mgs.EndpointCertificatePins) > 0 {
+		config.VerifyPeerCertificate = verifyCertificatePins(mgs.EndpointCertificatePins)
+	}
+	return config
+}
+
+// verifyCertificatePins returns a function checking that the verified certificate chains
+// hold a public key whose base64 encoded SHA-256 hash is one of the pins.
+func verifyCertificatePins(pins []string) func([][]byte, [][]*x509.Certificate) error {
+	return func(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+		for _, chain := range verifiedChains {
+			for _, certificate := range chain {
+				hash := sha256.Sum256(certificate.RawSubjectPublicKeyInfo)
+				publicKeyPin := base64.StdEncoding.EncodeToString(hash[:])
+				for _, pin := range pins {
+					if pin == publicKeyPin {
+						return nil
+					}
+				}
+			}
+		}
+		return errors.New("no certificate of the message gateway service matches the configured certificate pins")
+	}
+}
@@ -0,0 +1,311 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build windows
+
+// Package conpty starts processes on a Windows pseudo console (ConPTY), available from Windows 10 1809 and Server 2019.
+package conpty
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/aws/amazon-ssm-agent/agent/session/winpty"
+)
+
+const (
+	procThreadAttributePseudoConsole = 0x00020016
+	extendedStartupInfoPresent       = 0x00080000
+	createUnicodeEnvironment         = 0x00000400
+	maximumAllowed                   = 0x02000000
+	securityImpersonation            = 2
+	tokenPrimary                     = 1
+)
+
+var (
+	kernel32                              = syscall.NewLazyDLL("kernel32.dll")
+	advapi32                              = syscall.NewLazyDLL("advapi32.dll")
+	createPseudoConsoleProc               = kernel32.NewProc("CreatePseudoConsole")
+	resizePseudoConsoleProc               = kernel32.NewProc("ResizePseudoConsole")
+	closePseudoConsoleProc                = kernel32.NewProc("ClosePseudoConsole")
+	initializeProcThreadAttributeListProc = kernel32.NewProc("InitializeProcThreadAttributeList")
+	updateProcThreadAttributeProc         = kernel32.NewProc("UpdateProcThreadAttribute")
+	deleteProcThreadAttributeListProc     = kernel32.NewProc("DeleteProcThreadAttributeList")
+	createProcessProc                     = kernel32.NewProc("CreateProcessW")
+	createProcessAsUserProc               = advapi32.NewProc("CreateProcessAsUserW")
+	duplicateTokenExProc                  = advapi32.NewProc("DuplicateTokenEx")
+)
+
+// startupInfoEx is the STARTUPINFOEX structure passing the pseudo console to the new process
+type startupInfoEx struct {
+	startupInfo   syscall.StartupInfo
+	attributeList *byte
+}
+
+// ConPTY is a pseudo console and the process started on it
+type ConPTY struct {
+	StdIn  *os.File
+	StdOut *os.File
+
+	console      uintptr
+	closeConsole sync.Once
+	closed       bool
+
+	// processLock guards the process handle, which is closed once the process exits
+	processLock sync.Mutex
+	process     syscall.Handle
+	exited      chan struct{}
+	exitCode    uint32
+	exitErr     error
+}
+
+// IsAvailable returns true if the OS supports pseudo consoles
+func IsAvailable() bool {
+	return createPseudoConsoleProc.Find() == nil
+}
+
+// Start creates a pseudo console of the given size and starts the command line on it.
+// env holds NAME=value pairs added to the environment of the process, nil inherits the agent environment.
+// The process runs as the user of token, or as the agent if token is zero.
+func Start(cmdLine string, env []string, cols, rows uint32, token syscall.Handle) (*ConPTY, error) {
+	if !IsAvailable() {
+		return nil, errors.New("pseudo consoles are not supported by this version of Windows")
+	}
+	if cols == 0 || rows == 0 {
+		return nil, fmt.Errorf("Invalid console size. Cannot set cols %d and rows %d", cols, rows)
+	}
+
+	// the pseudo console reads the input of the process from inputRead and writes its output to outputWrite
+	var inputRead, inputWrite, outputRead, outputWrite syscall.Handle
+	if err := syscall.CreatePipe(&inputRead, &inputWrite, nil, 0); err != nil {
+		return nil, fmt.Errorf("Unable to create input pipe. %s", err)
+	}
+	if err := syscall.CreatePipe(&outputRead, &outputWrite, nil, 0); err != nil {
+		syscall.CloseHandle(inputRead)
+		syscall.CloseHandle(inputWrite)
+		return nil, fmt.Errorf("Unable to create output pipe. %s", err)
+	}
+	// the pseudo console holds its own references to the pipe ends it uses
+	defer syscall.CloseHandle(inputRead)
+	defer syscall.CloseHandle(outputWrite)
+
+	conpty := &ConPTY{
+		StdIn:  os.NewFile(uintptr(inputWrite), "stdin"),
+		StdOut: os.NewFile(uintptr(outputRead), "stdout"),
+		exited: make(chan struct{}),
+	}
+	if hr, _, _ := createPseudoConsoleProc.Call(
+		coord(cols, rows),
+		uintptr(inputRead),
+		uintptr(outputWrite),
+		0,
+		uintptr(unsafe.Pointer(&conpty.console))); hr != 0 {
+		conpty.StdIn.Close()
+		conpty.StdOut.Close()
+		return nil, fmt.Errorf("Unable to create pseudo console. HRESULT 0x%x", hr)
+	}
+
+	if err := conpty.spawnProcess(cmdLine, env, token); err != nil {
+		close(conpty.exited)
+		conpty.Close()
+		return nil, err
+	}
+	go conpty.waitProcess()
+	return conpty, nil
+}
+
+// waitProcess waits for the process to exit, records its exit code and closes the pseudo console,
+// so that reading StdOut returns EOF once the remaining output is read
+func (conpty *ConPTY) waitProcess() {
+	defer close(conpty.exited)
+
+	conpty.processLock.Lock()
+	process := conpty.process
+	conpty.processLock.Unlock()
+
+	if event, err := syscall.WaitForSingleObject(process, syscall.INFINITE); event != syscall.WAIT_OBJECT_0 {
+		conpty.exitErr = fmt.Errorf("Unable to wait for the process. %s", err)
+	} else if err = syscall.GetExitCodeProcess(process, &conpty.exitCode); err != nil {
+		conpty.exitErr = fmt.Errorf("Unable to get the exit code of the process. %s", err)
+	}
+
+	conpty.processLock.Lock()
+	syscall.CloseHandle(conpty.process)
+	conpty.process = 0
+	conpty.processLock.Unlock()
+
+	conpty.closePseudoConsole()
+}
+
+// Wait waits for the process to exit and returns its exit code
+func (conpty *ConPTY) Wait() (exitCode uint32, err error) {
+	<-conpty.exited
+	return conpty.exitCode, conpty.exitErr
+}
+
+// closePseudoConsole closes the pseudo console once, which terminates the processes still attached to it
+func (conpty *ConPTY) closePseudoConsole() {
+	conpty.closeConsole.Do(func() {
+		if conpty.console != 0 {
+			closePseudoConsoleProc.Call(conpty.console)
+		}
+	})
+}
+
+// spawnProcess starts the command line attached to the pseudo console
+func (conpty *ConPTY) spawnProcess(cmdLine string, env []string, token syscall.Handle) error {
+	var attributeListSize uintptr
+	initializeProcThreadAttributeListProc.Call(0, 1, 0, uintptr(unsafe.Pointer(&attributeListSize)))
+	attributeList := make([]byte, attributeListSize)
+	if rc, _, err := initializeProcThreadAttributeListProc.Call(
+		uintptr(unsafe.Pointer(&attributeList[0])),
+		1,
+		0,
+		uintptr(unsafe.Pointer(&attributeListSize))); rc == 0 {
+		return fmt.Errorf("Unable to initialize process attributes. %s", err)
+	}
+	defer deleteProcThreadAttributeListProc.Call(uintptr(unsafe.Pointer(&attributeList[0])))
+
+	if rc, _, err := updateProcThreadAttributeProc.Call(
+		uintptr(unsafe.Pointer(&attributeList[0])),
+		0,
+		procThreadAttributePseudoConsole,
+		conpty.console,
+		unsafe.Sizeof(conpty.console),
+		0,
+		0); rc == 0 {
+		return fmt.Errorf("Unable to set pseudo console attribute. %s", err)
+	}
+
+	var startupInfo startupInfoEx
+	startupInfo.startupInfo.Cb = uint32(unsafe.Sizeof(startupInfo))
+	startupInfo.attributeList = &attributeList[0]
+
+	cmdLineUTF16, err := syscall.UTF16FromString(cmdLine)
+	if err != nil {
+		return fmt.Errorf("Failed to convert cmd to pointer. %s", err)
+	}
+	var envBlock []uint16
+	var envBlockPtr uintptr
+	if len(env) > 0 {
+		envBlock = winpty.CreateEnvironmentBlock(append(os.Environ(), env...))
+		envBlockPtr = uintptr(unsafe.Pointer(&envBlock[0]))
+	}
+
+	var processInfo syscall.ProcessInformation
+	creationFlags := uintptr(extendedStartupInfoPresent | createUnicodeEnvironment)
+	var rc uintptr
+	if token == 0 {
+		rc, _, err = createProcessProc.Call(
+			0,
+			uintptr(unsafe.Pointer(&cmdLineUTF16[0])),
+			0,
+			0,
+			0,
+			creationFlags,
+			envBlockPtr,
+			0,
+			uintptr(unsafe.Pointer(&startupInfo)),
+			uintptr(unsafe.Pointer(&processInfo)))
+	} else {
+		var primaryToken syscall.Handle
+		if primaryToken, err = duplicatePrimaryToken(token); err != nil {
+			return err
+		}
+		defer syscall.CloseHandle(primaryToken)
+		rc, _, err = createProcessAsUserProc.Call(
+			uintptr(primaryToken),
+			0,
+			uintptr(unsafe.Pointer(&cmdLineUTF16[0])),
+			0,
+			0,
+			0,
+			creationFlags,
+			envBlockPtr,
+			0,
+			uintptr(unsafe.Pointer(&startupInfo)),
+			uintptr(unsafe.Pointer(&processInfo)))
+	}
+	runtime.KeepAlive(envBlock)
+	runtime.KeepAlive(attributeList)
+	if rc == 0 {
+		return fmt.Errorf("Unable to create process. %s", err)
+	}
+
+	syscall.CloseHandle(processInfo.Thread)
+	conpty.process = processInfo.Process
+	return nil
+}
+
+// duplicatePrimaryToken returns a primary token of the user, as required to create a process for an impersonation token
+func duplicatePrimaryToken(token syscall.Handle) (primaryToken syscall.Handle, err error) {
+	if rc, _, ec := duplicateTokenExProc.Call(
+		uintptr(token),
+		maximumAllowed,
+		0,
+		securityImpersonation,
+		tokenPrimary,
+		uintptr(unsafe.Pointer(&primaryToken))); rc == 0 {
+		return 0, fmt.Errorf("Unable to duplicate token. %s", ec)
+	}
+	return primaryToken, nil
+}
+
+// coord packs the console size into a COORD structure passed by value
+func coord(cols, rows uint32) uintptr {
+	return uintptr(uint16(cols)) | uintptr(uint16(rows))<<16
+}
+
+// Stdio returns the input and output of the pseudo console
+func (conpty *ConPTY) Stdio() (stdin *os.File, stdout *os.File) {
+	return conpty.StdIn, conpty.StdOut
+}
+
+// SetSize resizes the pseudo console
+func (conpty *ConPTY) SetSize(ws_col, ws_row uint32) error {
+	if ws_col == 0 || ws_row == 0 {
+		return nil
+	}
+	if hr, _, _ := resizePseudoConsoleProc.Call(conpty.console, coord(ws_col, ws_row)); hr != 0 {
+		return fmt.Errorf("Unable to resize pseudo console. HRESULT 0x%x", hr)
+	}
+	return nil
+}
+
+// Close closes the pseudo console, which terminates the processes attached to it, and closes stdin and stdout.
+// The process is terminated if it is still running.
+func (conpty *ConPTY) Close() error {
+	if conpty == nil || conpty.closed {
+		return nil
+	}
+	conpty.closed = true
+
+	conpty.closePseudoConsole()
+	conpty.processLock.Lock()
+	if conpty.process != 0 {
+		syscall.TerminateProcess(conpty.process, 1)
+	}
+	conpty.processLock.Unlock()
+	if err := conpty.StdIn.Close(); err != nil {
+		return fmt.Errorf("Unable to close stdin. %s", err)
+	}
+	if err := conpty.StdOut.Close(); err != nil {
+		return fmt.Errorf("Unable to close stdout. %s", err)
+	}
+	return nil
+}
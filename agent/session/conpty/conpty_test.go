@@ -0,0 +1,75 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build windows
+
+package conpty
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartExitClose(t *testing.T) {
+	if !IsAvailable() {
+		t.Skip("Pseudo consoles are not supported by this version of Windows")
+	}
+
+	console, err := Start("cmd.exe /c echo conpty&& exit 3", nil, 80, 25, 0)
+	assert.NoError(t, err)
+
+	// the pseudo console is closed when the process exits, so reading the output ends
+	output := make(chan string, 1)
+	go func() {
+		content, _ := ioutil.ReadAll(console.StdOut)
+		output <- string(content)
+	}()
+	select {
+	case content := <-output:
+		assert.True(t, strings.Contains(content, "conpty"))
+	case <-time.After(30 * time.Second):
+		assert.Fail(t, "output did not end after the process exited")
+	}
+
+	exitCode, err := console.Wait()
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(3), exitCode)
+	assert.NoError(t, console.Close())
+	assert.NoError(t, console.Close())
+}
+
+func TestCloseTerminatesProcess(t *testing.T) {
+	if !IsAvailable() {
+		t.Skip("Pseudo consoles are not supported by this version of Windows")
+	}
+
+	console, err := Start("cmd.exe /k", nil, 80, 25, 0)
+	assert.NoError(t, err)
+	go ioutil.ReadAll(console.StdOut)
+
+	assert.NoError(t, console.Close())
+	exited := make(chan bool, 1)
+	go func() {
+		console.Wait()
+		exited <- true
+	}()
+	select {
+	case <-exited:
+	case <-time.After(30 * time.Second):
+		assert.Fail(t, "process did not exit after the console was closed")
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"path/filepath"
 	"strings"
 	"time"
@@ -53,6 +54,8 @@ type AgentMessage struct {
 	PayloadDigest  []byte
 	PayloadType    uint32
 	PayloadLength  uint32
+	StreamId       uint32
+	Checksum       uint32
 	Payload        []byte
 }
 
@@ -64,15 +67,31 @@ type AgentMessage struct {
 // Flags is an 8 byte unsigned integer containing a packed array of control flags:
 //   Bit 0 is SYN - SYN is set (1) when the recipient should consider Seq to be the first message number in the stream
 //   Bit 1 is FIN - FIN is set (1) when this message is the final message in the sequence.
+//   Bit 2 is COMP - COMP is set (1) when the payload is compressed with the algorithm negotiated in the handshake.
+//   Bit 3 is CRC - CRC is set (1) when Checksum holds the CRC32C of the payload.
 // MessageId is a 40 byte UTF-8 string containing a random UUID identifying this message.
 // Payload digest is a 32 byte containing the SHA-256 hash of the payload.
 // Payload Type is a 4 byte integer containing the payload type.
 // Payload length is an 4 byte unsigned integer containing the byte length of data in the Payload field.
+// StreamId is an optional 4 byte unsigned integer identifying the logical stream of the payload when multiplexing
+// was negotiated in the handshake. It is present when HL covers it and omitted for the default stream zero,
+// parsers unaware of it skip it as the payload always starts after HL plus the payload length field.
+// Checksum is an optional 4 byte unsigned integer containing the CRC32C of the payload, present after StreamId when CRC is set.
+// It detects payloads corrupted in transit, which are not acknowledged so that they are retransmitted.
 // Payload is a variable length byte data.
 //
 // | HL|         MessageType           |Ver|  CD   |  Seq  | Flags |
 // |         MessageId                     |           Digest              |PayType| PayLen|
-// |         Payload      			|
+// |StreamId (optional)|Checksum (optional)|         Payload      			|
+
+// AgentMessage_CompressedFlag is the COMP bit of Flags.
+const AgentMessage_CompressedFlag uint64 = 1 << 2
+
+// AgentMessage_ChecksumFlag is the CRC bit of Flags.
+const AgentMessage_ChecksumFlag uint64 = 1 << 3
+
+// AgentMessage_DefaultStreamId is the stream of messages without StreamId, the only stream unless multiplexing was negotiated.
+const AgentMessage_DefaultStreamId uint32 = 0
 
 const (
 	AgentMessage_HLLength             = 4
@@ -85,6 +104,8 @@ const (
 	AgentMessage_PayloadDigestLength  = 32
 	AgentMessage_PayloadTypeLength    = 4
 	AgentMessage_PayloadLengthLength  = 4
+	AgentMessage_StreamIdLength       = 4
+	AgentMessage_ChecksumLength       = 4
 )
 
 const (
@@ -99,8 +120,13 @@ const (
 	AgentMessage_PayloadTypeOffset    = AgentMessage_PayloadDigestOffset + AgentMessage_PayloadDigestLength
 	AgentMessage_PayloadLengthOffset  = AgentMessage_PayloadTypeOffset + AgentMessage_PayloadTypeLength
 	AgentMessage_PayloadOffset        = AgentMessage_PayloadLengthOffset + AgentMessage_PayloadLengthLength
+	AgentMessage_StreamIdOffset       = AgentMessage_PayloadOffset
+	AgentMessage_ChecksumOffset       = AgentMessage_StreamIdOffset + AgentMessage_StreamIdLength
 )
 
+// checksumTable is the CRC32C (Castagnoli) table of payload checksums
+var checksumTable = crc32.MakeTable(crc32.Castagnoli)
+
 // Deserialize deserializes the byte array into an AgentMessage message.
 // * Payload is a variable length byte data.
 // * | HL|         MessageType           |Ver|  CD   |  Seq  | Flags |
@@ -158,6 +184,21 @@ func (agentMessage *AgentMessage) Deserialize(log logger.T, input []byte) (err e
 	}
 
 	agentMessage.HeaderLength = headerLength
+	agentMessage.StreamId = AgentMessage_DefaultStreamId
+	if headerLength >= AgentMessage_PayloadLengthOffset+AgentMessage_StreamIdLength {
+		if agentMessage.StreamId, err = getUInteger(log, input, AgentMessage_StreamIdOffset); err != nil {
+			log.Errorf("Could not deserialize field StreamId with error: %v", err)
+			return err
+		}
+	}
+	agentMessage.Checksum = 0
+	if agentMessage.Flags&AgentMessage_ChecksumFlag != 0 &&
+		headerLength >= AgentMessage_PayloadLengthOffset+AgentMessage_StreamIdLength+AgentMessage_ChecksumLength {
+		if agentMessage.Checksum, err = getUInteger(log, input, AgentMessage_ChecksumOffset); err != nil {
+			log.Errorf("Could not deserialize field Checksum with error: %v", err)
+			return err
+		}
+	}
 	agentMessage.Payload = input[headerLength+AgentMessage_PayloadLengthLength:]
 
 	return nil
@@ -171,6 +212,13 @@ func (agentMessage *AgentMessage) Deserialize(log logger.T, input []byte) (err e
 func (agentMessage *AgentMessage) Serialize(log logger.T) (result []byte, err error) {
 	payloadLength := uint32(len(agentMessage.Payload))
 	headerLength := uint32(AgentMessage_PayloadLengthOffset)
+	// The stream id is only serialized for streams other than the default one, or before the checksum
+	hasChecksum := agentMessage.Flags&AgentMessage_ChecksumFlag != 0
+	if hasChecksum {
+		headerLength += AgentMessage_StreamIdLength + AgentMessage_ChecksumLength
+	} else if agentMessage.StreamId != AgentMessage_DefaultStreamId {
+		headerLength += AgentMessage_StreamIdLength
+	}
 	// If the payloadinfo length is incorrect, fix it.
 	if payloadLength != agentMessage.PayloadLength {
 		log.Debugf("Payload length will be adjusted: ", agentMessage.PayloadLength)
@@ -237,8 +285,23 @@ func (agentMessage *AgentMessage) Serialize(log logger.T) (result []byte, err er
 		return make([]byte, 1), err
 	}
 
-	startPosition = AgentMessage_PayloadOffset
-	endPosition = AgentMessage_PayloadOffset + int(payloadLength) - 1
+	if agentMessage.StreamId != AgentMessage_DefaultStreamId {
+		if err = putUInteger(log, result, AgentMessage_StreamIdOffset, agentMessage.StreamId); err != nil {
+			log.Errorf("Could not serialize StreamId with error: %v", err)
+			return make([]byte, 1), err
+		}
+	}
+
+	if hasChecksum {
+		agentMessage.Checksum = crc32.Checksum(agentMessage.Payload, checksumTable)
+		if err = putUInteger(log, result, AgentMessage_ChecksumOffset, agentMessage.Checksum); err != nil {
+			log.Errorf("Could not serialize Checksum with error: %v", err)
+			return make([]byte, 1), err
+		}
+	}
+
+	startPosition = int(headerLength) + AgentMessage_PayloadLengthLength
+	endPosition = startPosition + int(payloadLength) - 1
 	if err = putBytes(log, result, startPosition, endPosition, agentMessage.Payload); err != nil {
 		log.Errorf("Could not serialize Payload with error: %v", err)
 		return make([]byte, 1), err
@@ -258,6 +321,11 @@ func (agentMessage *AgentMessage) Validate() error {
 	if agentMessage.CreatedDate == 0 {
 		return errors.New("CreatedDate is missing")
 	}
+	if agentMessage.Flags&AgentMessage_ChecksumFlag != 0 {
+		if checksum := crc32.Checksum(agentMessage.Payload, checksumTable); checksum != agentMessage.Checksum {
+			return fmt.Errorf("Payload checksum %08x does not match %08x, the payload was corrupted", checksum, agentMessage.Checksum)
+		}
+	}
 	return nil
 }
 
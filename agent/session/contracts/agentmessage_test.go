@@ -182,6 +182,71 @@ func TestSerializeAndDeserializeAgentMessage(t *testing.T) {
 	assert.Equal(t, uint64(2), deserializedAgentMessage.Flags)
 	assert.Equal(t, int64(1), deserializedAgentMessage.SequenceNumber)
 	assert.True(t, reflect.DeepEqual(payload, deserializedAgentMessage.Payload))
+	assert.Equal(t, uint32(0), deserializedAgentMessage.StreamId)
+	assert.Equal(t, uint32(AgentMessage_PayloadLengthOffset), deserializedAgentMessage.HeaderLength)
+}
+
+func TestSerializeAndDeserializeAgentMessageWithStreamId(t *testing.T) {
+	u, _ := uuid.Parse(messageId)
+
+	agentMessage := &AgentMessage{
+		MessageType:    messageType,
+		SchemaVersion:  schemaVersion,
+		CreatedDate:    createdDate,
+		SequenceNumber: 1,
+		MessageId:      u,
+		StreamId:       7,
+		Payload:        payload,
+	}
+
+	serializedBytes, err := agentMessage.Serialize(log.NewMockLog())
+	assert.Nil(t, err)
+	assert.Equal(t, AgentMessage_PayloadOffset+AgentMessage_StreamIdLength+len(payload), len(serializedBytes))
+
+	// the payload length stays at its fixed offset for parsers unaware of stream ids
+	serializedPayloadLength, err := getUInteger(log.NewMockLog(), serializedBytes, AgentMessage_PayloadLengthOffset)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(len(payload)), serializedPayloadLength)
+
+	deserializedAgentMessage := &AgentMessage{}
+	err = deserializedAgentMessage.Deserialize(log.NewMockLog(), serializedBytes)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(7), deserializedAgentMessage.StreamId)
+	assert.Equal(t, int64(1), deserializedAgentMessage.SequenceNumber)
+	assert.True(t, reflect.DeepEqual(payload, deserializedAgentMessage.Payload))
+}
+
+func TestSerializeAndDeserializeAgentMessageWithChecksum(t *testing.T) {
+	u, _ := uuid.Parse(messageId)
+
+	agentMessage := &AgentMessage{
+		MessageType:    messageType,
+		SchemaVersion:  schemaVersion,
+		CreatedDate:    createdDate,
+		SequenceNumber: 1,
+		Flags:          AgentMessage_ChecksumFlag,
+		MessageId:      u,
+		Payload:        payload,
+	}
+
+	serializedBytes, err := agentMessage.Serialize(log.NewMockLog())
+	assert.Nil(t, err)
+	assert.Equal(t, AgentMessage_PayloadOffset+AgentMessage_StreamIdLength+AgentMessage_ChecksumLength+len(payload), len(serializedBytes))
+
+	deserializedAgentMessage := &AgentMessage{}
+	err = deserializedAgentMessage.Deserialize(log.NewMockLog(), serializedBytes)
+	assert.Nil(t, err)
+	assert.Equal(t, AgentMessage_DefaultStreamId, deserializedAgentMessage.StreamId)
+	assert.Equal(t, agentMessage.Checksum, deserializedAgentMessage.Checksum)
+	assert.True(t, reflect.DeepEqual(payload, deserializedAgentMessage.Payload))
+	assert.Nil(t, deserializedAgentMessage.Validate())
+
+	// corrupt the last payload byte
+	serializedBytes[len(serializedBytes)-1] ^= 0xff
+	corruptedAgentMessage := &AgentMessage{}
+	err = corruptedAgentMessage.Deserialize(log.NewMockLog(), serializedBytes)
+	assert.Nil(t, err)
+	assert.NotNil(t, corruptedAgentMessage.Validate())
 }
 
 func TestParseAgentMessage(t *testing.T) {
@@ -223,14 +223,43 @@ const (
 	EncChallengeRequest  PayloadType = 8
 	EncChallengeResponse PayloadType = 9
 	Flag                 PayloadType = 10
+	ConnectionStatistics PayloadType = 11
+	LatencyProbeRequest  PayloadType = 12
+	LatencyProbeResponse PayloadType = 13
+	Signal               PayloadType = 14
 )
 
 type PayloadTypeFlag uint32
 
 const (
-	DisconnectToPort PayloadTypeFlag = 1
+	DisconnectToPort            PayloadTypeFlag = 1
+	ConnectionStatisticsRequest PayloadTypeFlag = 2
 )
 
+// ConnectionStatisticsPayload is sent by the agent in response to a ConnectionStatisticsRequest flag
+type ConnectionStatisticsPayload struct {
+	BytesSent         uint64 `json:"BytesSent"`
+	BytesReceived     uint64 `json:"BytesReceived"`
+	RoundTripTimeMs   int64  `json:"RoundTripTimeMs"`
+	ReconnectCount    uint64 `json:"ReconnectCount"`
+	SessionDurationMs int64  `json:"SessionDurationMs"`
+}
+
+// LatencyProbeRequestPayload is sent by the client to measure the end-to-end latency of the session
+type LatencyProbeRequestPayload struct {
+	ProbeId         string `json:"ProbeId"`
+	ClientTimestamp int64  `json:"ClientTimestamp"`
+}
+
+// LatencyProbeResponsePayload is sent by the agent in response to a LatencyProbeRequest.
+// Timestamps are in milliseconds since the epoch.
+type LatencyProbeResponsePayload struct {
+	ProbeId                string `json:"ProbeId"`
+	ClientTimestamp        int64  `json:"ClientTimestamp"`
+	AgentReceivedTimestamp int64  `json:"AgentReceivedTimestamp"`
+	AgentSentTimestamp     int64  `json:"AgentSentTimestamp"`
+}
+
 type SessionStatus string
 
 const (
@@ -243,6 +272,18 @@ type SizeData struct {
 	Rows uint32 `json:"rows"`
 }
 
+// SignalData is the payload of a Signal message, asking the agent to deliver a terminal signal to the session
+type SignalData struct {
+	Signal string `json:"signal"`
+}
+
+// Terminal signals clients can send to sessions
+const (
+	SignalInterrupt = "SIGINT"
+	SignalStop      = "SIGTSTP"
+	SignalQuit      = "SIGQUIT"
+)
+
 // ActionType used in Handshake to determine action requested by the agent
 type ActionType string
 
@@ -253,6 +294,25 @@ const (
 	SessionType ActionType = "SessionType"
 )
 
+// Capability is an optional protocol feature negotiated in the handshake.
+// A capability is enabled for the session only when both the agent and the client support it.
+type Capability string
+
+const (
+	// ChaCha20-Poly1305 instead of AES-GCM for session encryption.
+	ChaCha20Poly1305Capability Capability = "ChaCha20Poly1305"
+	// CRC32C checksums of stream data payloads, corrupted payloads are retransmitted.
+	MessageChecksumCapability Capability = "MessageChecksum"
+	// Gzip compression of stream data payloads.
+	GzipCompressionCapability Capability = "GzipCompression"
+	// Multiplexing of several streams on the data channel.
+	MultiplexingCapability Capability = "Multiplexing"
+	// Signal payloads delivering terminal signals to the session.
+	SignalPayloadCapability Capability = "SignalPayload"
+	// Progress messages sent as output before a port session is ready.
+	ProgressMessageCapability Capability = "ProgressMessage"
+)
+
 type ActionStatus int
 
 const (
@@ -281,6 +341,7 @@ type SessionTypeRequest struct {
 type HandshakeRequestPayload struct {
 	AgentVersion           string                  `json:"AgentVersion"`
 	RequestedClientActions []RequestedClientAction `json:"RequestedClientActions"`
+	Capabilities           []Capability            `json:"Capabilities"`
 }
 
 // An action requested by the agent to the plugin
@@ -302,6 +363,7 @@ type HandshakeResponsePayload struct {
 	ClientVersion          string                  `json:"ClientVersion"`
 	ProcessedClientActions []ProcessedClientAction `json:"ProcessedClientActions"`
 	Errors                 []string                `json:"Errors"`
+	Capabilities           []Capability            `json:"Capabilities"`
 }
 
 // This is sent by the agent as a challenge to the client. The challenge field
@@ -323,4 +385,5 @@ type EncryptionChallengeResponse struct {
 type HandshakeCompletePayload struct {
 	HandshakeTimeToComplete time.Duration `json:"HandshakeTimeToComplete"`
 	CustomerMessage         string        `json:"CustomerMessage"`
+	Capabilities            []Capability  `json:"Capabilities"`
 }
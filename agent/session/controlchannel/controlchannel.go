@@ -17,7 +17,6 @@ package controlchannel
 import (
 	"encoding/json"
 	"fmt"
-	"math/rand"
 	"path/filepath"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
@@ -29,7 +28,6 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/session/communicator"
 	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
 	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
-	"github.com/aws/amazon-ssm-agent/agent/session/retry"
 	"github.com/aws/amazon-ssm-agent/agent/session/service"
 	"github.com/aws/amazon-ssm-agent/agent/times"
 	"github.com/aws/amazon-ssm-agent/agent/version"
@@ -109,13 +107,7 @@ func (controlChannel *ControlChannel) SetWebSocket(context context.T,
 			}
 			return controlChannel, nil
 		}
-		retryer := retry.ExponentialRetryer{
-			CallableFunc:        callable,
-			GeometricRatio:      mgsConfig.RetryGeometricRatio,
-			InitialDelayInMilli: rand.Intn(mgsConfig.ControlChannelRetryInitialDelayMillis) + mgsConfig.ControlChannelRetryInitialDelayMillis,
-			MaxDelayInMilli:     mgsConfig.ControlChannelRetryMaxIntervalMillis,
-			MaxAttempts:         mgsConfig.ControlChannelNumMaxRetries,
-		}
+		retryer := mgsConfig.NewControlChannelRetryer(context.AppConfig().Mgs, callable)
 
 		if _, err := retryer.Call(); err != nil {
 			// should never happen
@@ -0,0 +1,127 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package datachannel implements data channel which is used to interactively run commands.
+package datachannel
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+)
+
+// maxDecompressedPayloadLength limits the size of decompressed payloads received from the client
+const maxDecompressedPayloadLength = 4 * 1024 * 1024
+
+// payloadCompressor compresses stream data payloads with the algorithm of a compression capability
+type payloadCompressor interface {
+	compress(payload []byte) ([]byte, error)
+	decompress(payload []byte) ([]byte, error)
+}
+
+// payloadCompressors are the compression algorithms supported by the agent, in order of preference
+var payloadCompressors = []struct {
+	capability mgsContracts.Capability
+	compressor payloadCompressor
+}{
+	{mgsContracts.GzipCompressionCapability, gzipCompressor{}},
+}
+
+// selectCompressor returns the preferred compressor among the negotiated capabilities, nil if compression was not negotiated
+func selectCompressor(capabilities []mgsContracts.Capability) payloadCompressor {
+	for _, supported := range payloadCompressors {
+		for _, capability := range capabilities {
+			if capability == supported.capability {
+				return supported.compressor
+			}
+		}
+	}
+	return nil
+}
+
+// isCompressionCapability returns true if the capability enables the compression of stream data payloads
+func isCompressionCapability(capability mgsContracts.Capability) bool {
+	for _, supported := range payloadCompressors {
+		if capability == supported.capability {
+			return true
+		}
+	}
+	return false
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) compress(payload []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(payload); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+func (gzipCompressor) decompress(payload []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	decompressed, err := ioutil.ReadAll(io.LimitReader(reader, maxDecompressedPayloadLength+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(decompressed) > maxDecompressedPayloadLength {
+		return nil, fmt.Errorf("decompressed payload is larger than %d bytes", maxDecompressedPayloadLength)
+	}
+	return decompressed, nil
+}
+
+// compressPayload returns the compressed payload if it is larger than the compression threshold and compressing makes it smaller
+func (dataChannel *DataChannel) compressPayload(payload []byte) (compressed []byte, ok bool) {
+	compressor := dataChannel.negotiatedCompressor()
+	if compressor == nil || len(payload) <= dataChannel.compressionThreshold {
+		return payload, false
+	}
+	compressed, err := compressor.compress(payload)
+	if err != nil || len(compressed) >= len(payload) {
+		return payload, false
+	}
+	return compressed, true
+}
+
+// decompressPayload returns the payload of the message, decompressed if the client compressed it
+func (dataChannel *DataChannel) decompressPayload(streamDataMessage mgsContracts.AgentMessage) ([]byte, error) {
+	if streamDataMessage.Flags&mgsContracts.AgentMessage_CompressedFlag == 0 {
+		return streamDataMessage.Payload, nil
+	}
+	compressor := dataChannel.negotiatedCompressor()
+	if compressor == nil {
+		return nil, fmt.Errorf("payload is compressed but compression was not negotiated")
+	}
+	return compressor.decompress(streamDataMessage.Payload)
+}
+
+// negotiatedCompressor returns the compressor selected in the handshake, nil if compression was not negotiated
+func (dataChannel *DataChannel) negotiatedCompressor() payloadCompressor {
+	dataChannel.handshake.capabilitiesLock.RLock()
+	defer dataChannel.handshake.capabilitiesLock.RUnlock()
+	return dataChannel.compressor
+}
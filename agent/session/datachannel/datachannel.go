@@ -25,8 +25,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/crypto"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
 	"github.com/aws/amazon-ssm-agent/agent/rip"
@@ -35,6 +37,7 @@ import (
 	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/session/retry"
 	"github.com/aws/amazon-ssm-agent/agent/session/service"
+	"github.com/aws/amazon-ssm-agent/agent/session/telemetry"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/aws/amazon-ssm-agent/agent/version"
 	"github.com/aws/aws-sdk-go/aws"
@@ -50,6 +53,15 @@ const (
 	handshakeTimeout = 15 * time.Second
 )
 
+// agentCapabilities are the optional protocol features supported by the agent, offered to the client in the handshake.
+var agentCapabilities = []mgsContracts.Capability{
+	mgsContracts.SignalPayloadCapability,
+	mgsContracts.GzipCompressionCapability,
+	mgsContracts.MultiplexingCapability,
+	mgsContracts.MessageChecksumCapability,
+	mgsContracts.ProgressMessageCapability,
+}
+
 type IDataChannel interface {
 	Initialize(context context.T, mgsService service.Service, sessionId string, clientId string, instanceId string, role string, cancelFlag task.CancelFlag, inputStreamMessageHandler InputStreamMessageHandler)
 	SetWebSocket(context context.T, mgsService service.Service, sessionId string, clientId string, onMessageHandler func(input []byte)) error
@@ -58,6 +70,7 @@ type IDataChannel interface {
 	Reconnect(log log.T) error
 	SendMessage(log log.T, input []byte, inputType int) error
 	SendStreamDataMessage(log log.T, dataType mgsContracts.PayloadType, inputData []byte) error
+	SendStreamDataMessageToStream(log log.T, streamId uint32, dataType mgsContracts.PayloadType, inputData []byte) error
 	ResendStreamDataMessageScheduler(log log.T) error
 	ProcessAcknowledgedMessage(log log.T, acknowledgeMessageContent mgsContracts.AcknowledgeContent)
 	SendAcknowledgeMessage(log log.T, agentMessage mgsContracts.AgentMessage) error
@@ -68,6 +81,11 @@ type IDataChannel interface {
 	RemoveDataFromIncomingMessageBuffer(sequenceNumber int64)
 	SkipHandshake(log log.T)
 	PerformHandshake(log log.T, kmsKeyId string, encryptionEnabled bool, sessionTypeRequest mgsContracts.SessionTypeRequest) (err error)
+	GetRoundTripTime() time.Duration
+	SetStreamDataPayloadSize(payloadSize int)
+	IsCapabilityEnabled(capability mgsContracts.Capability) bool
+	WaitForSendWindow()
+	GetChannelQuality() telemetry.ChannelQuality
 }
 
 // DataChannel used for session communication between the message gateway service and the agent.
@@ -96,6 +114,9 @@ type DataChannel struct {
 	RoundTripTimeVariation float64
 	//timeout used for resending unacknowledged message
 	RetransmissionTimeout time.Duration
+	// rttLock guards RoundTripTime, RoundTripTimeVariation and RetransmissionTimeout, the acknowledge handler
+	// updates them while the resend scheduler and the telemetry read them
+	rttLock sync.Mutex
 	//cancelFlag is used for passing cancel signal to plugin in when channel_closed message is received over data channel
 	cancelFlag task.CancelFlag
 	//inputStreamMessageHandler is responsible for handling plugin specific input_stream_data message
@@ -106,6 +127,36 @@ type DataChannel struct {
 	blockCipher crypto.IBlockCipher
 	// Indicates whether encryption was enabled
 	encryptionEnabled bool
+	// preferredCipher is the session encryption cipher offered to the client in addition to AES-GCM
+	preferredCipher string
+	// Indicates the data channel dropped and the session waits to be resumed
+	detached bool
+	// Indicates the data channel dropped and is being reconnected, stream data messages are buffered until it is restored
+	reconnecting bool
+	// outageBuffer holds the messages sent while reconnecting that do not fit in OutgoingMessageBuffer, nil if disabled
+	outageBuffer *outageBuffer
+	// compressor compresses stream data payloads if compression was negotiated in the handshake, guarded by the
+	// capabilitiesLock of the handshake
+	compressor payloadCompressor
+	// Stream data payloads not larger than compressionThreshold are not compressed
+	compressionThreshold int
+	// retransmissionWindow is the outgoing message buffer capacity for payloads of StreamDataPayloadSize
+	retransmissionWindow int
+	// maxInFlightMessages makes WaitForSendWindow block while that many messages are unacknowledged, zero disables it
+	maxInFlightMessages int
+	// sendWindow is signaled when messages leave the outgoing message buffer or the data channel closes
+	sendWindow *sync.Cond
+	// closed releases WaitForSendWindow and stops the resend scheduler, guarded by the OutgoingMessageBuffer mutex
+	closed bool
+	// retransmission timeout is the round trip time plus the round trip time variation scaled by rttVariationMultiplier,
+	// capped at maxRetransmissionTimeout
+	rttVariationMultiplier   float64
+	maxRetransmissionTimeout time.Duration
+	// statistics counts stream data traffic for network quality telemetry
+	statistics channelStatistics
+	// sendLock serializes stream data messages sent concurrently on different streams,
+	// control payloads are sent before output waiting to be sent
+	sendLock prioritySendLock
 }
 
 type ListMessageBuffer struct {
@@ -139,7 +190,12 @@ type Handshake struct {
 	// Indicates handshake is complete (Handshake Complete message sent to client)
 	complete bool
 	// Indiciates if handshake has been skipped
-	skipped            bool
+	skipped bool
+	// capabilitiesLock guards capabilities and the compressor of the data channel, they are set by the handshake
+	// response handler while stream data messages are sent and received
+	capabilitiesLock sync.RWMutex
+	// Capabilities supported by both the agent and the client
+	capabilities       []mgsContracts.Capability
 	handshakeStartTime time.Time
 	handshakeEndTime   time.Time
 }
@@ -202,6 +258,7 @@ func NewDataChannel(context context.T,
 		return nil, fmt.Errorf("failed to open datachannel with error: %s", err)
 	}
 	dataChannel.ResendStreamDataMessageScheduler(log)
+	go dataChannel.reportChannelQuality(log)
 	return dataChannel, nil
 }
 
@@ -224,11 +281,17 @@ func (dataChannel *DataChannel) Initialize(context context.T,
 	dataChannel.Pause = false
 	dataChannel.ExpectedSequenceNumber = 0
 	dataChannel.StreamDataSequenceNumber = 0
+	dataChannel.initializeRetransmission(context.AppConfig().Mgs)
+	dataChannel.reconnecting = false
+	dataChannel.outageBuffer = newOutageBuffer(sessionId,
+		context.AppConfig().Mgs.DataChannelOutageBufferSizeMB*1024*1024,
+		context.AppConfig().Mgs.DataChannelOutageSpillSizeMB*1024*1024)
 	dataChannel.OutgoingMessageBuffer = ListMessageBuffer{
 		list.New(),
-		mgsConfig.OutgoingMessageBufferCapacity,
+		dataChannel.retransmissionWindow,
 		&sync.Mutex{},
 	}
+	dataChannel.sendWindow = sync.NewCond(dataChannel.OutgoingMessageBuffer.Mutex)
 	dataChannel.IncomingMessageBuffer = MapMessageBuffer{
 		make(map[int64]StreamingMessage),
 		mgsConfig.IncomingMessageBufferCapacity,
@@ -240,6 +303,12 @@ func (dataChannel *DataChannel) Initialize(context context.T,
 	dataChannel.wsChannel = &communicator.WebSocketChannel{}
 	dataChannel.cancelFlag = cancelFlag
 	dataChannel.inputStreamMessageHandler = inputStreamMessageHandler
+	dataChannel.compressionThreshold = context.AppConfig().Mgs.SessionCompressionThresholdBytes
+	if !context.AppConfig().Agent.FipsEnabled {
+		// ChaCha20-Poly1305 is not FIPS approved
+		dataChannel.preferredCipher = context.AppConfig().Mgs.SessionEncryptionCipher
+	}
+	dataChannel.statistics.startTime = time.Now()
 	dataChannel.handshake = Handshake{
 		responseChan:            make(chan bool),
 		encryptionConfirmedChan: make(chan bool),
@@ -259,8 +328,7 @@ func (dataChannel *DataChannel) SetWebSocket(context context.T,
 	onMessageHandler func(input []byte)) error {
 
 	log := context.Log()
-	uuid.SwitchFormat(uuid.CleanHyphen)
-	requestId := uuid.NewV4().String()
+	requestId := newUUID().String()
 
 	log.Infof("Setting up datachannel for session: %s, requestId: %s, clientId: %s", sessionId, requestId, clientId)
 	tokenValue, err := getDataChannelToken(log, mgsService, sessionId, requestId, clientId)
@@ -270,8 +338,11 @@ func (dataChannel *DataChannel) SetWebSocket(context context.T,
 	}
 
 	onErrorHandler := func(err error) {
-		uuid.SwitchFormat(uuid.CleanHyphen)
-		requestId := uuid.NewV4().String()
+		// The reconnecting flag is set under the send lock like Reconnect clears it, senders read it under the lock
+		dataChannel.sendLock.Lock(true)
+		dataChannel.reconnecting = true
+		dataChannel.sendLock.Unlock()
+		requestId := newUUID().String()
 		callable := func() (channel interface{}, err error) {
 			tokenValue, err := getDataChannelToken(log, mgsService, sessionId, requestId, clientId)
 			if err != nil {
@@ -283,12 +354,10 @@ func (dataChannel *DataChannel) SetWebSocket(context context.T,
 			}
 			return dataChannel, nil
 		}
-		retryer := retry.ExponentialRetryer{
-			CallableFunc:        callable,
-			GeometricRatio:      mgsConfig.RetryGeometricRatio,
-			InitialDelayInMilli: rand.Intn(mgsConfig.DataChannelRetryInitialDelayMillis) + mgsConfig.DataChannelRetryInitialDelayMillis,
-			MaxDelayInMilli:     mgsConfig.DataChannelRetryMaxIntervalMillis,
-			MaxAttempts:         mgsConfig.DataChannelNumMaxAttempts,
+		retryer := mgsConfig.NewDataChannelRetryer(context.AppConfig().Mgs, callable)
+		if gracePeriod := time.Duration(context.AppConfig().Mgs.SessionDetachGracePeriodSeconds) * time.Second; gracePeriod > 0 {
+			dataChannel.detach(log, gracePeriod, retryer)
+			return
 		}
 		if _, err := retryer.Call(); err != nil {
 			log.Error(err)
@@ -310,6 +379,55 @@ func (dataChannel *DataChannel) SetWebSocket(context context.T,
 	return nil
 }
 
+// detach keeps the session alive while trying to reconnect the data channel for the grace period, so that the client
+// can resume the session. Output is buffered until the session is resumed, and the session is cancelled if
+// the data channel does not reconnect within the grace period.
+func (dataChannel *DataChannel) detach(log log.T, gracePeriod time.Duration, retryer retry.ExponentialRetryer) {
+	log.Infof("Datachannel disconnected, session %s is detached for up to %v", dataChannel.ChannelId, gracePeriod)
+	dataChannel.setDetached(true)
+	defer dataChannel.setDetached(false)
+
+	deadline := time.Now().Add(gracePeriod)
+	// reconnection attempts do not start after the grace period
+	retryer.Deadline = deadline
+	for {
+		_, err := retryer.Call()
+		if err == nil {
+			log.Infof("Session %s resumed", dataChannel.ChannelId)
+			return
+		}
+		if !time.Now().Before(deadline) {
+			log.Errorf("Session %s was not resumed within %v, terminating the session: %v", dataChannel.ChannelId, gracePeriod, err)
+			dataChannel.cancelFlag.Set(task.Canceled)
+			return
+		}
+	}
+}
+
+// setDetached sets whether the session is detached under the send lock, detaching also pauses sending.
+func (dataChannel *DataChannel) setDetached(detached bool) {
+	dataChannel.sendLock.Lock(true)
+	defer dataChannel.sendLock.Unlock()
+	if detached {
+		dataChannel.Pause = true
+	}
+	dataChannel.detached = detached
+}
+
+// setPause sets the pause status of the data channel under the send lock.
+func (dataChannel *DataChannel) setPause(pause bool) {
+	dataChannel.sendLock.Lock(true)
+	defer dataChannel.sendLock.Unlock()
+	dataChannel.Pause = pause
+}
+
+// isPaused returns the pause status of the data channel, read under the send lock.
+func (dataChannel *DataChannel) isPaused() bool {
+	dataChannel.sendLock.Lock(true)
+	defer dataChannel.sendLock.Unlock()
+	return dataChannel.Pause
+}
+
 // Open opens the websocket connection and sends the token for service to acknowledge the connection.
 func (dataChannel *DataChannel) Open(log log.T) error {
 	// Opens websocket connection
@@ -318,8 +436,7 @@ func (dataChannel *DataChannel) Open(log log.T) error {
 	}
 
 	// finalize handshake
-	uuid.SwitchFormat(uuid.CleanHyphen)
-	uid := uuid.NewV4().String()
+	uid := newUUID().String()
 
 	openDataChannelInput := service.OpenDataChannelInput{
 		MessageSchemaVersion: aws.String(mgsConfig.MessageSchemaVersion),
@@ -353,29 +470,202 @@ func (dataChannel *DataChannel) Reconnect(log log.T) error {
 		return fmt.Errorf("failed to reconnect datachannel with error: %s", err)
 	}
 
+	// The reconnecting flag is cleared under the send lock so that no stream data message is sent
+	// before the buffered messages are replayed
+	dataChannel.sendLock.Lock(true)
+	defer dataChannel.sendLock.Unlock()
 	dataChannel.Pause = false
+	dataChannel.reconnecting = false
 	log.Debugf("Successfully reconnected to datachannel %s", dataChannel.ChannelId)
+	dataChannel.replayOutgoingMessages(log)
 	return nil
 }
 
+// replayOutgoingMessages resends the messages buffered while the data channel was reconnecting in sequence order,
+// then sends the messages waiting in the outage buffer as the outgoing message buffer has room for them.
+// The caller holds the send lock.
+func (dataChannel *DataChannel) replayOutgoingMessages(log log.T) {
+	if dataChannel.outageBuffer == nil {
+		return
+	}
+	dataChannel.OutgoingMessageBuffer.Mutex.Lock()
+	unacknowledged := []StreamingMessage{}
+	for element := dataChannel.OutgoingMessageBuffer.Messages.Front(); element != nil; element = element.Next() {
+		streamMessage := element.Value.(StreamingMessage)
+		streamMessage.LastSentTime = time.Now()
+		element.Value = streamMessage
+		unacknowledged = append(unacknowledged, streamMessage)
+	}
+	dataChannel.OutgoingMessageBuffer.Mutex.Unlock()
+
+	log.Debugf("Replaying %d stream data messages and %d messages of the outage buffer",
+		len(unacknowledged), dataChannel.outageBuffer.Len())
+	for _, streamMessage := range unacknowledged {
+		if err := dataChannel.SendMessage(log, streamMessage.Content, websocket.BinaryMessage); err != nil {
+			log.Errorf("Unable to replay stream data message %d: %s", streamMessage.SequenceNumber, err)
+			return
+		}
+	}
+	dataChannel.drainOutageBuffer(log)
+}
+
+// drainOutageBuffer moves messages from the outage buffer to the outgoing message buffer while it has room, sending them.
+// The caller holds the send lock, so that later messages are not sent before the last message of the outage buffer.
+func (dataChannel *DataChannel) drainOutageBuffer(log log.T) {
+	if dataChannel.outageBuffer == nil || dataChannel.reconnecting {
+		return
+	}
+	for dataChannel.OutgoingMessageBuffer.Messages.Len() < dataChannel.OutgoingMessageBuffer.Capacity {
+		streamMessage, ok, err := dataChannel.outageBuffer.Pop()
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+		if !ok {
+			return
+		}
+		if !dataChannel.Pause {
+			if err = dataChannel.SendMessage(log, streamMessage.Content, websocket.BinaryMessage); err != nil {
+				log.Errorf("Error sending stream data message %v", err)
+			}
+		}
+		streamMessage.LastSentTime = time.Now()
+		dataChannel.AddDataToOutgoingMessageBuffer(streamMessage)
+	}
+}
+
+// GetRoundTripTime returns the smoothed round trip time of acknowledged stream data messages.
+func (dataChannel *DataChannel) GetRoundTripTime() time.Duration {
+	dataChannel.rttLock.Lock()
+	defer dataChannel.rttLock.Unlock()
+	return time.Duration(dataChannel.RoundTripTime)
+}
+
+// getRoundTripTimeVariation returns the smoothed round trip time variation of acknowledged stream data messages.
+func (dataChannel *DataChannel) getRoundTripTimeVariation() time.Duration {
+	dataChannel.rttLock.Lock()
+	defer dataChannel.rttLock.Unlock()
+	return time.Duration(dataChannel.RoundTripTimeVariation)
+}
+
+// getRetransmissionTimeout returns the time after which unacknowledged stream data messages are resent.
+func (dataChannel *DataChannel) getRetransmissionTimeout() time.Duration {
+	dataChannel.rttLock.Lock()
+	defer dataChannel.rttLock.Unlock()
+	return dataChannel.RetransmissionTimeout
+}
+
+// initializeRetransmission applies the configured retransmission tuning, using the defaults for values not set
+func (dataChannel *DataChannel) initializeRetransmission(mgs appconfig.MgsConfig) {
+	dataChannel.retransmissionWindow = mgs.DataChannelRetransmissionWindow
+	if dataChannel.retransmissionWindow <= 0 {
+		dataChannel.retransmissionWindow = mgsConfig.OutgoingMessageBufferCapacity
+	}
+	dataChannel.maxInFlightMessages = mgs.DataChannelMaxInFlightMessages
+	dataChannel.rttVariationMultiplier = float64(mgs.DataChannelRetransmissionTimeoutMultiplier)
+	if dataChannel.rttVariationMultiplier <= 0 {
+		dataChannel.rttVariationMultiplier = appconfig.DefaultDataChannelRetransmissionTimeoutMultiplier
+	}
+	dataChannel.maxRetransmissionTimeout = time.Duration(mgs.DataChannelMaxRetransmissionTimeoutMillis) * time.Millisecond
+	if dataChannel.maxRetransmissionTimeout <= 0 {
+		dataChannel.maxRetransmissionTimeout = mgsConfig.MaxTransmissionTimeout
+	}
+}
+
+// SetStreamDataPayloadSize sets the maximum size of the stream data payloads sent over the data channel and
+// scales the outgoing message buffer capacity so that the buffer keeps using the same amount of memory.
+func (dataChannel *DataChannel) SetStreamDataPayloadSize(payloadSize int) {
+	if payloadSize <= 0 {
+		return
+	}
+	dataChannel.OutgoingMessageBuffer.Mutex.Lock()
+	dataChannel.OutgoingMessageBuffer.Capacity = dataChannel.retransmissionWindow * mgsConfig.StreamDataPayloadSize / payloadSize
+	dataChannel.OutgoingMessageBuffer.Mutex.Unlock()
+}
+
+// GetStreamDataPayloadSize returns the maximum size of the stream data payloads sent by the given session plugin.
+// Port forwarding and file transfer use larger payloads for throughput while interactive sessions keep small payloads for latency,
+// unless the plugin payload size is overridden in the agent configuration.
+func GetStreamDataPayloadSize(mgs appconfig.MgsConfig, pluginName string) int {
+	if payloadSize := mgs.StreamDataPayloadSizeOverrides[pluginName]; payloadSize > 0 {
+		return payloadSize
+	}
+	if pluginName == appconfig.PluginNamePort || pluginName == appconfig.PluginNameFileTransfer {
+		return mgsConfig.PortStreamDataPayloadSize
+	}
+	if mgs.StreamDataPayloadSize > 0 {
+		return mgs.StreamDataPayloadSize
+	}
+	return mgsConfig.StreamDataPayloadSize
+}
+
 // Close closes datachannel - its web socket connection.
 func (dataChannel *DataChannel) Close(log log.T) error {
 	log.Infof("Closing datachannel with channel Id %s", dataChannel.ChannelId)
+	dataChannel.OutgoingMessageBuffer.Mutex.Lock()
+	dataChannel.closed = true
+	if dataChannel.sendWindow != nil {
+		dataChannel.sendWindow.Broadcast()
+	}
+	dataChannel.OutgoingMessageBuffer.Mutex.Unlock()
+	if quality, err := jsonutil.Marshal(dataChannel.GetChannelQuality()); err == nil {
+		log.Infof("Data channel quality: %s", quality)
+	}
+	telemetry.Remove(log, dataChannel.ChannelId)
+	if dataChannel.outageBuffer != nil {
+		dataChannel.outageBuffer.Clear()
+	}
 	return dataChannel.wsChannel.Close(log)
 }
 
 // SendStreamDataMessage sends a data message in a form of AgentMessage for streaming.
 func (dataChannel *DataChannel) SendStreamDataMessage(log log.T, payloadType mgsContracts.PayloadType, inputData []byte) (err error) {
+	return dataChannel.sendStreamDataMessage(log, mgsContracts.AgentMessage_DefaultStreamId, payloadType, inputData)
+}
+
+// SendStreamDataMessageToStream sends a data message on a logical stream of the data channel.
+// Streams other than the default one require multiplexing to be negotiated in the handshake.
+func (dataChannel *DataChannel) SendStreamDataMessageToStream(log log.T, streamId uint32, payloadType mgsContracts.PayloadType, inputData []byte) (err error) {
+	if streamId != mgsContracts.AgentMessage_DefaultStreamId && !dataChannel.IsCapabilityEnabled(mgsContracts.MultiplexingCapability) {
+		return fmt.Errorf("unable to send on stream %d, multiplexing was not negotiated with the client", streamId)
+	}
+	return dataChannel.sendStreamDataMessage(log, streamId, payloadType, inputData)
+}
+
+// sendStreamDataMessage sends a stream data message on the given stream, assigning it the next sequence number of the data channel.
+func (dataChannel *DataChannel) sendStreamDataMessage(log log.T, streamId uint32, payloadType mgsContracts.PayloadType, inputData []byte) (err error) {
 	if len(inputData) == 0 {
 		log.Debugf("Ignoring empty stream data payload. PayloadType: %d", payloadType)
 		return nil
 	}
 
+	priority := isPriorityPayload(payloadType)
+	dataChannel.sendLock.Lock(priority)
+	defer dataChannel.sendLock.Unlock()
+
+	// Output of a detached session waits for the session to be resumed instead of being dropped when the buffer is full,
+	// the send lock is released while waiting so that the data channel can reconnect and replay the buffered messages.
+	// Control payloads do not wait, they are few and must take effect promptly.
+	for !priority && dataChannel.detached && !dataChannel.bufferDuringOutage() &&
+		dataChannel.OutgoingMessageBuffer.Messages.Len() >= dataChannel.OutgoingMessageBuffer.Capacity {
+		dataChannel.sendLock.Unlock()
+		time.Sleep(mgsConfig.ResendSleepInterval)
+		dataChannel.sendLock.Lock(priority)
+	}
+
 	var flag uint64 = 0
 	if dataChannel.StreamDataSequenceNumber == 0 {
 		flag = 1
 	}
 
+	// Compression is only negotiated for sessions that are not encrypted, see offeredCapabilities
+	if payloadType == mgsContracts.Output {
+		var compressed bool
+		if inputData, compressed = dataChannel.compressPayload(inputData); compressed {
+			flag |= mgsContracts.AgentMessage_CompressedFlag
+		}
+	}
+
 	// If encryption has been enabled, encrypt the payload
 	if dataChannel.encryptionEnabled && payloadType == mgsContracts.Output {
 		if inputData, err = dataChannel.blockCipher.EncryptWithAESGCM(inputData); err != nil {
@@ -383,8 +673,12 @@ func (dataChannel *DataChannel) SendStreamDataMessage(log log.T, payloadType mgs
 		}
 	}
 
-	uuid.SwitchFormat(uuid.CleanHyphen)
-	messageId := uuid.NewV4()
+	// The checksum covers the payload as sent so that the client verifies it before decrypting
+	if dataChannel.IsCapabilityEnabled(mgsContracts.MessageChecksumCapability) {
+		flag |= mgsContracts.AgentMessage_ChecksumFlag
+	}
+
+	messageId := newUUID()
 	agentMessage := &mgsContracts.AgentMessage{
 		MessageType:    mgsContracts.OutputStreamDataMessage,
 		SchemaVersion:  1,
@@ -393,6 +687,7 @@ func (dataChannel *DataChannel) SendStreamDataMessage(log log.T, payloadType mgs
 		Flags:          flag,
 		MessageId:      messageId,
 		PayloadType:    uint32(payloadType),
+		StreamId:       streamId,
 		Payload:        inputData,
 	}
 	msg, err := agentMessage.Serialize(log)
@@ -400,7 +695,19 @@ func (dataChannel *DataChannel) SendStreamDataMessage(log log.T, payloadType mgs
 		return fmt.Errorf("cannot serialize StreamData message %v", agentMessage)
 	}
 
-	if dataChannel.Pause {
+	// While reconnecting, messages not fitting in the outgoing message buffer wait in the outage buffer,
+	// as do later messages until it is drained so that messages are sent in order
+	if dataChannel.bufferDuringOutage() {
+		if err = dataChannel.outageBuffer.Push(StreamingMessage{msg, dataChannel.StreamDataSequenceNumber, time.Now()}); err != nil {
+			return err
+		}
+		log.Tracef("Add stream data to outage buffer. Sequence Number: %d", dataChannel.StreamDataSequenceNumber)
+		dataChannel.statistics.recordSent(len(msg))
+		dataChannel.StreamDataSequenceNumber = dataChannel.StreamDataSequenceNumber + 1
+		return nil
+	}
+
+	if dataChannel.Pause || dataChannel.reconnecting {
 		log.Tracef("Sending stream data message has been paused, saving stream data message sequence %d to local map: ", dataChannel.StreamDataSequenceNumber)
 	} else {
 		log.Tracef("Send stream data message sequence number %d", dataChannel.StreamDataSequenceNumber)
@@ -416,34 +723,63 @@ func (dataChannel *DataChannel) SendStreamDataMessage(log log.T, payloadType mgs
 	}
 
 	log.Tracef("Add stream data to OutgoingMessageBuffer. Sequence Number: %d", streamingMessage.SequenceNumber)
-	dataChannel.AddDataToOutgoingMessageBuffer(streamingMessage)
+	if priority {
+		// Control payloads are kept for retransmission even when output fills the buffer
+		dataChannel.pushToOutgoingMessageBuffer(streamingMessage)
+	} else {
+		dataChannel.AddDataToOutgoingMessageBuffer(streamingMessage)
+	}
+	dataChannel.statistics.recordSent(len(msg))
 	dataChannel.StreamDataSequenceNumber = dataChannel.StreamDataSequenceNumber + 1
 	return nil
 }
 
+// bufferDuringOutage returns true if the next stream data message goes to the outage buffer.
+func (dataChannel *DataChannel) bufferDuringOutage() bool {
+	if dataChannel.outageBuffer == nil {
+		return false
+	}
+	if dataChannel.outageBuffer.Len() > 0 {
+		return true
+	}
+	// output of detached sessions waits for the session to be resumed instead
+	return dataChannel.reconnecting && !dataChannel.detached &&
+		dataChannel.OutgoingMessageBuffer.Messages.Len() >= dataChannel.OutgoingMessageBuffer.Capacity
+}
+
 // ResendStreamDataMessageScheduler spawns a separate go thread which keeps checking OutgoingMessageBuffer at fixed interval
-// and resends first message if time elapsed since lastSentTime of the message is more than acknowledge wait time
+// and resends first message if time elapsed since lastSentTime of the message is more than acknowledge wait time,
+// until the data channel is closed
 func (dataChannel *DataChannel) ResendStreamDataMessageScheduler(log log.T) error {
 	go func() {
 		for {
 			time.Sleep(mgsConfig.ResendSleepInterval)
-			if dataChannel.Pause {
+			if dataChannel.isClosed() {
+				return
+			}
+			if dataChannel.isPaused() {
 				log.Tracef("Resend stream data message has been paused")
 				continue
 			}
+			dataChannel.OutgoingMessageBuffer.Mutex.Lock()
 			streamMessageElement := dataChannel.OutgoingMessageBuffer.Messages.Front()
 			if streamMessageElement == nil {
+				dataChannel.OutgoingMessageBuffer.Mutex.Unlock()
 				continue
 			}
-
 			streamMessage := streamMessageElement.Value.(StreamingMessage)
-			if time.Since(streamMessage.LastSentTime) > dataChannel.RetransmissionTimeout {
+			dataChannel.OutgoingMessageBuffer.Mutex.Unlock()
+
+			if time.Since(streamMessage.LastSentTime) > dataChannel.getRetransmissionTimeout() {
 				log.Tracef("Resend stream data message: %d", streamMessage.SequenceNumber)
 				if err := dataChannel.SendMessage(log, streamMessage.Content, websocket.BinaryMessage); err != nil {
 					log.Errorf("Unable to send stream data message: %s", err)
 				}
 				streamMessage.LastSentTime = time.Now()
+				dataChannel.OutgoingMessageBuffer.Mutex.Lock()
 				streamMessageElement.Value = streamMessage
+				dataChannel.OutgoingMessageBuffer.Mutex.Unlock()
+				dataChannel.statistics.recordRetransmission()
 			}
 		}
 	}()
@@ -453,18 +789,31 @@ func (dataChannel *DataChannel) ResendStreamDataMessageScheduler(log log.T) erro
 // ProcessAcknowledgedMessage processes acknowledge messages by deleting them from OutgoingMessageBuffer.
 func (dataChannel *DataChannel) ProcessAcknowledgedMessage(log log.T, acknowledgeMessageContent mgsContracts.AcknowledgeContent) {
 	acknowledgeSequenceNumber := acknowledgeMessageContent.SequenceNumber
+	// The buffer is searched under its mutex, the resend scheduler updates the messages concurrently
+	var acknowledgedElement *list.Element
+	var acknowledgedMessage StreamingMessage
+	dataChannel.OutgoingMessageBuffer.Mutex.Lock()
 	for streamMessageElement := dataChannel.OutgoingMessageBuffer.Messages.Front(); streamMessageElement != nil; streamMessageElement = streamMessageElement.Next() {
 		streamMessage := streamMessageElement.Value.(StreamingMessage)
 		if streamMessage.SequenceNumber == acknowledgeSequenceNumber {
-
-			//Calculate retransmission timeout based on latest round trip time of message
-			dataChannel.calculateRetransmissionTimeout(log, streamMessage)
-
-			log.Tracef("Delete stream data from OutgoingMessageBuffer. Sequence Number: %d", streamMessage.SequenceNumber)
-			dataChannel.RemoveDataFromOutgoingMessageBuffer(streamMessageElement)
+			acknowledgedElement, acknowledgedMessage = streamMessageElement, streamMessage
 			break
 		}
 	}
+	dataChannel.OutgoingMessageBuffer.Mutex.Unlock()
+
+	if acknowledgedElement != nil {
+		//Calculate retransmission timeout based on latest round trip time of message
+		dataChannel.calculateRetransmissionTimeout(log, acknowledgedMessage)
+
+		log.Tracef("Delete stream data from OutgoingMessageBuffer. Sequence Number: %d", acknowledgedMessage.SequenceNumber)
+		dataChannel.RemoveDataFromOutgoingMessageBuffer(acknowledgedElement)
+	}
+	if dataChannel.outageBuffer != nil {
+		dataChannel.sendLock.Lock(false)
+		defer dataChannel.sendLock.Unlock()
+		dataChannel.drainOutageBuffer(log)
+	}
 }
 
 // SendAcknowledgeMessage sends acknowledge message for stream data over data channel
@@ -514,8 +863,7 @@ func (dataChannel *DataChannel) SendAgentSessionStateMessage(log log.T, sessionS
 
 // sendAgentMessage sends agent message for given messageType and content
 func (dataChannel *DataChannel) sendAgentMessage(log log.T, messageType string, messageContent []byte) error {
-	uuid.SwitchFormat(uuid.CleanHyphen)
-	messageId := uuid.NewV4()
+	messageId := newUUID()
 	agentMessage := &mgsContracts.AgentMessage{
 		MessageType:    messageType,
 		SchemaVersion:  schemaVersion,
@@ -542,9 +890,14 @@ func (dataChannel *DataChannel) sendAgentMessage(log log.T, messageType string,
 
 // AddDataToOutgoingMessageBuffer adds given message at the end of OutputMessageBuffer if it has capacity.
 func (dataChannel *DataChannel) AddDataToOutgoingMessageBuffer(streamMessage StreamingMessage) {
-	if dataChannel.OutgoingMessageBuffer.Messages.Len() == dataChannel.OutgoingMessageBuffer.Capacity {
+	if dataChannel.OutgoingMessageBuffer.Messages.Len() >= dataChannel.OutgoingMessageBuffer.Capacity {
 		return
 	}
+	dataChannel.pushToOutgoingMessageBuffer(streamMessage)
+}
+
+// pushToOutgoingMessageBuffer adds given message at the end of OutputMessageBuffer regardless of its capacity.
+func (dataChannel *DataChannel) pushToOutgoingMessageBuffer(streamMessage StreamingMessage) {
 	dataChannel.OutgoingMessageBuffer.Mutex.Lock()
 	dataChannel.OutgoingMessageBuffer.Messages.PushBack(streamMessage)
 	dataChannel.OutgoingMessageBuffer.Mutex.Unlock()
@@ -554,9 +907,25 @@ func (dataChannel *DataChannel) AddDataToOutgoingMessageBuffer(streamMessage Str
 func (dataChannel *DataChannel) RemoveDataFromOutgoingMessageBuffer(streamMessageElement *list.Element) {
 	dataChannel.OutgoingMessageBuffer.Mutex.Lock()
 	dataChannel.OutgoingMessageBuffer.Messages.Remove(streamMessageElement)
+	if dataChannel.sendWindow != nil {
+		dataChannel.sendWindow.Broadcast()
+	}
 	dataChannel.OutgoingMessageBuffer.Mutex.Unlock()
 }
 
+// WaitForSendWindow blocks while the number of unacknowledged stream data messages has reached the configured maximum.
+// Plugins call it before reading more output to send so that they stop reading until the client catches up.
+func (dataChannel *DataChannel) WaitForSendWindow() {
+	if dataChannel.maxInFlightMessages <= 0 || dataChannel.sendWindow == nil {
+		return
+	}
+	dataChannel.OutgoingMessageBuffer.Mutex.Lock()
+	defer dataChannel.OutgoingMessageBuffer.Mutex.Unlock()
+	for dataChannel.OutgoingMessageBuffer.Messages.Len() >= dataChannel.maxInFlightMessages && !dataChannel.closed {
+		dataChannel.sendWindow.Wait()
+	}
+}
+
 // AddDataToIncomingMessageBuffer adds given message to IncomingMessageBuffer if it has capacity.
 func (dataChannel *DataChannel) AddDataToIncomingMessageBuffer(streamMessage StreamingMessage) {
 	if len(dataChannel.IncomingMessageBuffer.Messages) == dataChannel.IncomingMessageBuffer.Capacity {
@@ -584,6 +953,7 @@ func (dataChannel *DataChannel) dataChannelIncomingMessageHandler(log log.T, raw
 		return err
 	}
 
+	// Invalid messages such as corrupted payloads are not acknowledged so that the client retransmits them
 	if err := streamDataMessage.Validate(); err != nil {
 		log.Errorf("Invalid StreamDataMessage, err: %v.", err)
 		return err
@@ -613,6 +983,8 @@ func (dataChannel *DataChannel) dataChannelIncomingMessageHandler(log log.T, raw
 func (dataChannel *DataChannel) calculateRetransmissionTimeout(log log.T, streamingMessage StreamingMessage) {
 	newRoundTripTime := float64(time.Since(streamingMessage.LastSentTime))
 
+	dataChannel.rttLock.Lock()
+	defer dataChannel.rttLock.Unlock()
 	dataChannel.RoundTripTimeVariation = ((1 - mgsConfig.RTTVConstant) * dataChannel.RoundTripTimeVariation) +
 		(mgsConfig.RTTVConstant * math.Abs(dataChannel.RoundTripTime-newRoundTripTime))
 
@@ -620,11 +992,11 @@ func (dataChannel *DataChannel) calculateRetransmissionTimeout(log log.T, stream
 		(mgsConfig.RTTConstant * newRoundTripTime)
 
 	dataChannel.RetransmissionTimeout = time.Duration(dataChannel.RoundTripTime +
-		math.Max(float64(mgsConfig.ClockGranularity), float64(4*dataChannel.RoundTripTimeVariation)))
+		math.Max(float64(mgsConfig.ClockGranularity), dataChannel.rttVariationMultiplier*dataChannel.RoundTripTimeVariation))
 
 	// Ensure RetransmissionTimeout do not exceed maximum timeout defined
-	if dataChannel.RetransmissionTimeout > mgsConfig.MaxTransmissionTimeout {
-		dataChannel.RetransmissionTimeout = mgsConfig.MaxTransmissionTimeout
+	if dataChannel.RetransmissionTimeout > dataChannel.maxRetransmissionTimeout {
+		dataChannel.RetransmissionTimeout = dataChannel.maxRetransmissionTimeout
 	}
 
 	log.Tracef("Retransmission timeout calculated in mills. "+
@@ -640,7 +1012,7 @@ func (dataChannel *DataChannel) handleStreamDataMessage(log log.T,
 	streamDataMessage mgsContracts.AgentMessage,
 	rawMessage []byte) (err error) {
 
-	dataChannel.Pause = false
+	dataChannel.setPause(false)
 	// On receiving expected stream data message, send acknowledgement, process it and increment expected sequence number by 1.
 	// Further process messages from IncomingMessageBuffer
 	if streamDataMessage.SequenceNumber == dataChannel.ExpectedSequenceNumber {
@@ -650,6 +1022,7 @@ func (dataChannel *DataChannel) handleStreamDataMessage(log log.T,
 
 		// Message is acknowledged so increment expected sequence number
 		dataChannel.ExpectedSequenceNumber = dataChannel.ExpectedSequenceNumber + 1
+		dataChannel.statistics.recordReceived(len(rawMessage), false)
 
 		log.Tracef("Process new incoming stream data message. Sequence Number: %d", streamDataMessage.SequenceNumber)
 		if err = dataChannel.processStreamDataMessage(log, streamDataMessage); err != nil {
@@ -676,6 +1049,14 @@ func (dataChannel *DataChannel) handleStreamDataMessage(log log.T,
 				time.Now(),
 			}
 
+			// Messages resent by the client while buffered are not counted again
+			dataChannel.IncomingMessageBuffer.Mutex.Lock()
+			_, buffered := dataChannel.IncomingMessageBuffer.Messages[streamDataMessage.SequenceNumber]
+			dataChannel.IncomingMessageBuffer.Mutex.Unlock()
+			if !buffered {
+				dataChannel.statistics.recordReceived(len(rawMessage), true)
+			}
+
 			//Add message to buffer for future processing
 			log.Debugf("Add stream data to IncomingMessageBuffer. Sequence Number: %d", streamDataMessage.SequenceNumber)
 			dataChannel.AddDataToIncomingMessageBuffer(streamingMessage)
@@ -689,7 +1070,7 @@ func (dataChannel *DataChannel) handleStreamDataMessage(log log.T,
 
 // handleAcknowledgeMessage deserialize acknowledge content and process it.
 func (dataChannel *DataChannel) handleAcknowledgeMessage(log log.T, streamDataMessage mgsContracts.AgentMessage) (err error) {
-	dataChannel.Pause = false
+	dataChannel.setPause(false)
 	acknowledgeMessage := &mgsContracts.AcknowledgeContent{}
 	if err = acknowledgeMessage.Deserialize(log, streamDataMessage); err != nil {
 		log.Errorf("Cannot deserialize payload to AcknowledgeMessage: %s, err: %v.", string(streamDataMessage.Payload), err)
@@ -716,14 +1097,14 @@ func (dataChannel *DataChannel) handleChannelClosedMessage(log log.T, streamData
 
 // handlePausePublicationMessage sets pause status of datachannel to true.
 func (dataChannel *DataChannel) handlePausePublicationMessage(log log.T, streamDataMessage mgsContracts.AgentMessage) {
-	dataChannel.Pause = true
-	log.Debugf("Processed %s message. Datachannel pause status set to %s", streamDataMessage.MessageType, dataChannel.Pause)
+	dataChannel.setPause(true)
+	log.Debugf("Processed %s message. Datachannel pause status set to true", streamDataMessage.MessageType)
 }
 
 // handleStartPublicationMessage sets pause status of datachannel to false.
 func (dataChannel *DataChannel) handleStartPublicationMessage(log log.T, streamDataMessage mgsContracts.AgentMessage) {
-	dataChannel.Pause = false
-	log.Debugf("Processed %s message. Datachannel pause status set to %s", streamDataMessage.MessageType, dataChannel.Pause)
+	dataChannel.setPause(false)
+	log.Debugf("Processed %s message. Datachannel pause status set to false", streamDataMessage.MessageType)
 }
 
 // processIncomingMessageBufferItems checks if new expected sequence stream data is present in IncomingMessageBuffer.
@@ -767,6 +1148,9 @@ func (dataChannel *DataChannel) processStreamDataMessage(log log.T, streamDataMe
 			return fmt.Errorf("Error decrypting stream data message sequence %d, err: %v", streamDataMessage.SequenceNumber, err)
 		}
 	}
+	if streamDataMessage.Payload, err = dataChannel.decompressPayload(streamDataMessage); err != nil {
+		return fmt.Errorf("Error decompressing stream data message sequence %d, err: %v", streamDataMessage.SequenceNumber, err)
+	}
 
 	switch mgsContracts.PayloadType(streamDataMessage.PayloadType) {
 	case mgsContracts.HandshakeResponse:
@@ -783,6 +1167,13 @@ func (dataChannel *DataChannel) processStreamDataMessage(log log.T, streamDataMe
 				return fmt.Errorf("processing of EncryptionChallengeReponse message failed, %v", err)
 			}
 		}
+	case mgsContracts.LatencyProbeRequest:
+		{
+			// LatencyProbeRequest is answered by the data channel so that probes measure the channel, not the plugin
+			if err = dataChannel.handleLatencyProbeRequest(log, streamDataMessage); err != nil {
+				return fmt.Errorf("processing of LatencyProbeRequest message failed, %v", err)
+			}
+		}
 	default:
 		// Ignore stream data message if handshake is neither skipped nor completed
 		if !dataChannel.handshake.skipped && !dataChannel.handshake.complete {
@@ -798,6 +1189,24 @@ func (dataChannel *DataChannel) processStreamDataMessage(log log.T, streamDataMe
 	return nil
 }
 
+// handleLatencyProbeRequest is the handler for payload type LatencyProbeRequest.
+// It immediately reflects the probe back to the client with the agent timestamps.
+func (dataChannel *DataChannel) handleLatencyProbeRequest(log log.T, streamDataMessage mgsContracts.AgentMessage) error {
+	receivedTimestamp := time.Now().UnixNano() / int64(time.Millisecond)
+	var probeRequest mgsContracts.LatencyProbeRequestPayload
+	if err := json.Unmarshal(streamDataMessage.Payload, &probeRequest); err != nil {
+		return fmt.Errorf("unmarshalling of LatencyProbeRequest message failed, %v", err)
+	}
+
+	probeResponse := mgsContracts.LatencyProbeResponsePayload{
+		ProbeId:                probeRequest.ProbeId,
+		ClientTimestamp:        probeRequest.ClientTimestamp,
+		AgentReceivedTimestamp: receivedTimestamp,
+		AgentSentTimestamp:     time.Now().UnixNano() / int64(time.Millisecond),
+	}
+	return dataChannel.sendStreamDataMessageJson(log, mgsContracts.LatencyProbeResponse, probeResponse)
+}
+
 // handleHandshakeResponse is the handler for payload type HandshakeResponse
 func (dataChannel *DataChannel) handleHandshakeResponse(log log.T, streamDataMessage mgsContracts.AgentMessage) error {
 	log.Debug("Received Handshake Response.")
@@ -806,6 +1215,10 @@ func (dataChannel *DataChannel) handleHandshakeResponse(log log.T, streamDataMes
 		return fmt.Errorf("Unmarshalling of HandshakeResponse message failed, %s", err)
 	}
 
+	capabilities := negotiateCapabilities(dataChannel.offeredCapabilities(), handshakeResponse.Capabilities)
+	log.Debugf("Negotiated capabilities: %v", capabilities)
+	dataChannel.setCapabilities(capabilities)
+
 	for _, action := range handshakeResponse.ProcessedClientActions {
 		var err error
 		if action.ActionStatus != mgsContracts.Success {
@@ -859,6 +1272,62 @@ func (dataChannel *DataChannel) handleEncryptionChallengeResponse(log log.T, str
 	return nil
 }
 
+// negotiateCapabilities returns the agent capabilities also supported by the client.
+// Clients older than capability negotiation send none, so no optional feature is enabled for them.
+func negotiateCapabilities(agent []mgsContracts.Capability, client []mgsContracts.Capability) []mgsContracts.Capability {
+	negotiated := []mgsContracts.Capability{}
+	for _, capability := range agent {
+		for _, clientCapability := range client {
+			if capability == clientCapability {
+				negotiated = append(negotiated, capability)
+				break
+			}
+		}
+	}
+	return negotiated
+}
+
+// offeredCapabilities returns the agent capabilities offered to the client, including the preferred cipher.
+// Compression is not offered for encrypted sessions, as the length of compressed and then encrypted payloads
+// reveals whether the output repeats content, which lets attacker controlled input guess secrets in the output.
+func (dataChannel *DataChannel) offeredCapabilities() []mgsContracts.Capability {
+	capabilities := []mgsContracts.Capability{}
+	for _, capability := range agentCapabilities {
+		if !dataChannel.encryptionEnabled || !isCompressionCapability(capability) {
+			capabilities = append(capabilities, capability)
+		}
+	}
+	if dataChannel.preferredCipher == appconfig.SessionEncryptionCipherChaCha20Poly1305 {
+		capabilities = append(capabilities, mgsContracts.ChaCha20Poly1305Capability)
+	}
+	return capabilities
+}
+
+// setCapabilities records the capabilities negotiated with the client and the compressor they select.
+func (dataChannel *DataChannel) setCapabilities(capabilities []mgsContracts.Capability) {
+	dataChannel.handshake.capabilitiesLock.Lock()
+	defer dataChannel.handshake.capabilitiesLock.Unlock()
+	dataChannel.handshake.capabilities = capabilities
+	dataChannel.compressor = selectCompressor(capabilities)
+}
+
+// negotiatedCapabilities returns the capabilities negotiated with the client, nil before the handshake response.
+func (dataChannel *DataChannel) negotiatedCapabilities() []mgsContracts.Capability {
+	dataChannel.handshake.capabilitiesLock.RLock()
+	defer dataChannel.handshake.capabilitiesLock.RUnlock()
+	return dataChannel.handshake.capabilities
+}
+
+// IsCapabilityEnabled returns true if the capability was negotiated with the client in the handshake.
+func (dataChannel *DataChannel) IsCapabilityEnabled(capability mgsContracts.Capability) bool {
+	for _, negotiated := range dataChannel.negotiatedCapabilities() {
+		if negotiated == capability {
+			return true
+		}
+	}
+	return false
+}
+
 // SkipHandshake is used to skip handshake if the plugin decides it is not necessary
 func (dataChannel *DataChannel) SkipHandshake(log log.T) {
 	log.Info("Skipping handshake.")
@@ -873,6 +1342,15 @@ func (dataChannel *DataChannel) finalizeKMSEncryption(log log.T, actionResult js
 		return err
 	}
 
+	cipherName := appconfig.SessionEncryptionCipherAESGCM
+	if dataChannel.IsCapabilityEnabled(mgsContracts.ChaCha20Poly1305Capability) {
+		cipherName = appconfig.SessionEncryptionCipherChaCha20Poly1305
+	}
+	if err := dataChannel.blockCipher.SetCipher(cipherName); err != nil {
+		return err
+	}
+	log.Debugf("Session encryption cipher: %s", cipherName)
+
 	sessionId := dataChannel.ChannelId // ChannelId is SessionId
 	if err := dataChannel.blockCipher.UpdateEncryptionKey(log, encryptionResponse.KMSCipherTextKey, sessionId, dataChannel.InstanceId); err != nil {
 		return fmt.Errorf("Fetching data key failed: %s", err)
@@ -962,6 +1440,7 @@ func (dataChannel *DataChannel) buildHandshakeRequestPayload(log log.T,
 			ActionType:       mgsContracts.SessionType,
 			ActionParameters: request,
 		}}
+	handshakeRequest.Capabilities = dataChannel.offeredCapabilities()
 	if encryptionRequested {
 		handshakeRequest.RequestedClientActions = append(handshakeRequest.RequestedClientActions,
 			mgsContracts.RequestedClientAction{
@@ -980,6 +1459,7 @@ func (dataChannel *DataChannel) buildHandshakeCompletePayload(log log.T) mgsCont
 	handshakeComplete.HandshakeTimeToComplete =
 		dataChannel.handshake.handshakeEndTime.Sub(dataChannel.handshake.handshakeStartTime)
 
+	handshakeComplete.Capabilities = dataChannel.negotiatedCapabilities()
 	if dataChannel.encryptionEnabled == true {
 		handshakeComplete.CustomerMessage = "This session is encrypted using AWS KMS."
 	}
@@ -1050,6 +1530,16 @@ func (dataChannel *DataChannel) sendStreamDataMessageJson(log log.T,
 	return err
 }
 
+// uuidFormatOnce sets the format of the ids of the data channel once, as uuid.SwitchFormat changes a global
+// that concurrent senders would race on
+var uuidFormatOnce sync.Once
+
+// newUUID returns a new random id, formatted as lower case hex with hyphens
+func newUUID() uuid.UUID {
+	uuidFormatOnce.Do(func() { uuid.SwitchFormat(uuid.CleanHyphen) })
+	return uuid.NewV4()
+}
+
 // getDataChannelToken calls CreateDataChannel to get the token for this session.
 func getDataChannelToken(log log.T,
 	mgsService service.Service,
@@ -18,7 +18,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -31,6 +35,7 @@ import (
 	communicatorMocks "github.com/aws/amazon-ssm-agent/agent/session/communicator/mocks"
 	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
 	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/session/retry"
 	"github.com/aws/amazon-ssm-agent/agent/session/service"
 	serviceMock "github.com/aws/amazon-ssm-agent/agent/session/service/mocks"
 	"github.com/aws/amazon-ssm-agent/agent/task"
@@ -122,6 +127,55 @@ func TestSetWebSocket(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestSendStreamDataMessageWhileReconnecting(t *testing.T) {
+	testLog := log.NewMockLog()
+	dataChannel := getDataChannel()
+	mgsService := &serviceMock.Service{}
+	wsChannel := &communicatorMocks.IWebSocketChannel{}
+	dataChannel.wsChannel = wsChannel
+
+	createDataChannelOutput := service.CreateDataChannelOutput{TokenValue: &token}
+	mgsService.On("CreateDataChannel", mock.Anything, mock.Anything, mock.Anything).Return(&createDataChannelOutput, nil)
+	mgsService.On("GetRegion").Return(region)
+	mgsService.On("GetV4Signer").Return(signer)
+	var onErrorHandler func(error)
+	wsChannel.On("Initialize", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		onErrorHandler = args.Get(8).(func(error))
+	})
+	wsChannel.On("SetChannelToken", mock.Anything).Return()
+	wsChannel.On("Close", mock.Anything).Return(nil)
+	// the data channel reconnects once the plugin has sent its output
+	outputSent := make(chan bool)
+	wsChannel.On("Open", mock.Anything).Return(nil).Run(func(args mock.Arguments) { <-outputSent })
+	wsChannel.On("GetChannelToken").Return(token)
+	wsChannel.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	assert.Nil(t, dataChannel.SetWebSocket(mockContext, mgsService, sessionId, clientId, onMessageHandler))
+
+	// the plugin sends output before the data channel drops and while it reconnects,
+	// run with -race to check that the reconnecting flag is not changed under the senders
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		time.Sleep(10 * time.Millisecond)
+		onErrorHandler(errors.New("connection closed"))
+	}()
+	for i := 0; i < 10; i++ {
+		if i == 1 {
+			time.Sleep(20 * time.Millisecond)
+		}
+		assert.Nil(t, dataChannel.SendStreamDataMessage(testLog, mgsContracts.Output, []byte("output"+strconv.Itoa(i))))
+	}
+	close(outputSent)
+	wg.Wait()
+
+	// output sent while reconnecting is kept for retransmission, only the first output and the open request were sent
+	assert.False(t, dataChannel.reconnecting)
+	assert.Equal(t, 10, dataChannel.OutgoingMessageBuffer.Messages.Len())
+	wsChannel.AssertNumberOfCalls(t, "SendMessage", 2)
+}
+
 func TestOpen(t *testing.T) {
 	dataChannel := getDataChannel()
 
@@ -153,6 +207,76 @@ func TestReconnect(t *testing.T) {
 	mockWsChannel.AssertExpectations(t)
 }
 
+func TestDetachResumesSession(t *testing.T) {
+	dataChannel := getDataChannel()
+	cancelFlag := &task.MockCancelFlag{}
+	dataChannel.cancelFlag = cancelFlag
+	attempts := 0
+	retryer := retry.ExponentialRetryer{
+		CallableFunc: func() (interface{}, error) {
+			if attempts++; attempts < 3 {
+				assert.True(t, dataChannel.detached)
+				assert.True(t, dataChannel.Pause)
+				return nil, errors.New("unable to reconnect")
+			}
+			dataChannel.Pause = false
+			return dataChannel, nil
+		},
+		GeometricRatio:      mgsConfig.RetryGeometricRatio,
+		InitialDelayInMilli: 1,
+		MaxDelayInMilli:     1,
+	}
+
+	dataChannel.detach(mockLog, time.Minute, retryer)
+
+	assert.Equal(t, 3, attempts)
+	assert.False(t, dataChannel.detached)
+	assert.False(t, dataChannel.Pause)
+	cancelFlag.AssertNotCalled(t, "Set", task.Canceled)
+}
+
+func TestDetachCancelsSessionNotResumed(t *testing.T) {
+	dataChannel := getDataChannel()
+	cancelFlag := &task.MockCancelFlag{}
+	cancelFlag.On("Set", task.Canceled).Return()
+	dataChannel.cancelFlag = cancelFlag
+	retryer := retry.ExponentialRetryer{
+		CallableFunc: func() (interface{}, error) {
+			return nil, errors.New("unable to reconnect")
+		},
+		GeometricRatio:      mgsConfig.RetryGeometricRatio,
+		InitialDelayInMilli: 1,
+		MaxDelayInMilli:     1,
+	}
+
+	dataChannel.detach(mockLog, 10*time.Millisecond, retryer)
+
+	assert.False(t, dataChannel.detached)
+	cancelFlag.AssertExpectations(t)
+}
+
+func TestDetachStopsRetryingAtGracePeriod(t *testing.T) {
+	dataChannel := getDataChannel()
+	cancelFlag := &task.MockCancelFlag{}
+	cancelFlag.On("Set", task.Canceled).Return()
+	dataChannel.cancelFlag = cancelFlag
+	retryer := retry.ExponentialRetryer{
+		CallableFunc: func() (interface{}, error) {
+			return nil, errors.New("unable to reconnect")
+		},
+		GeometricRatio:      mgsConfig.RetryGeometricRatio,
+		InitialDelayInMilli: 10000,
+		MaxDelayInMilli:     10000,
+		MaxAttempts:         10,
+	}
+
+	start := time.Now()
+	dataChannel.detach(mockLog, 50*time.Millisecond, retryer)
+
+	assert.True(t, time.Since(start) < time.Second)
+	cancelFlag.AssertExpectations(t)
+}
+
 func TestClose(t *testing.T) {
 	dataChannel := getDataChannel()
 
@@ -165,6 +289,34 @@ func TestClose(t *testing.T) {
 	mockWsChannel.AssertExpectations(t)
 }
 
+func TestSetStreamDataPayloadSize(t *testing.T) {
+	dataChannel := getDataChannel()
+
+	dataChannel.SetStreamDataPayloadSize(mgsConfig.PortStreamDataPayloadSize)
+	assert.Equal(t, mgsConfig.OutgoingMessageBufferCapacity/8, dataChannel.OutgoingMessageBuffer.Capacity)
+
+	dataChannel.SetStreamDataPayloadSize(mgsConfig.StreamDataPayloadSize)
+	assert.Equal(t, mgsConfig.OutgoingMessageBufferCapacity, dataChannel.OutgoingMessageBuffer.Capacity)
+}
+
+func TestGetStreamDataPayloadSize(t *testing.T) {
+	mgs := appconfig.MgsConfig{}
+	assert.Equal(t, mgsConfig.PortStreamDataPayloadSize, GetStreamDataPayloadSize(mgs, appconfig.PluginNamePort))
+	assert.Equal(t, mgsConfig.PortStreamDataPayloadSize, GetStreamDataPayloadSize(mgs, appconfig.PluginNameFileTransfer))
+	assert.Equal(t, mgsConfig.StreamDataPayloadSize, GetStreamDataPayloadSize(mgs, appconfig.PluginNameStandardStream))
+}
+
+func TestGetConfiguredStreamDataPayloadSize(t *testing.T) {
+	mgs := appconfig.MgsConfig{
+		StreamDataPayloadSize:          2048,
+		StreamDataPayloadSizeOverrides: map[string]int{appconfig.PluginNameInteractiveCommands: 16384, appconfig.PluginNamePort: 32768},
+	}
+	assert.Equal(t, 2048, GetStreamDataPayloadSize(mgs, appconfig.PluginNameStandardStream))
+	assert.Equal(t, 16384, GetStreamDataPayloadSize(mgs, appconfig.PluginNameInteractiveCommands))
+	assert.Equal(t, 32768, GetStreamDataPayloadSize(mgs, appconfig.PluginNamePort))
+	assert.Equal(t, mgsConfig.PortStreamDataPayloadSize, GetStreamDataPayloadSize(mgs, appconfig.PluginNameFileTransfer))
+}
+
 func TestSendStreamDataMessage(t *testing.T) {
 	dataChannel := getDataChannel()
 
@@ -208,6 +360,13 @@ func TestResendStreamDataMessageScheduler(t *testing.T) {
 	dataChannel.ResendStreamDataMessageScheduler(mockLog)
 
 	wg.Wait()
+
+	// stop the scheduler before checking the shared mocks
+	dataChannel.OutgoingMessageBuffer.Mutex.Lock()
+	dataChannel.closed = true
+	dataChannel.OutgoingMessageBuffer.Mutex.Unlock()
+	time.Sleep(2 * mgsConfig.ResendSleepInterval)
+
 	mockWsChannel.AssertExpectations(t)
 }
 
@@ -226,6 +385,126 @@ func TestProcessAcknowledgedMessage(t *testing.T) {
 	assert.Equal(t, 0, dataChannel.OutgoingMessageBuffer.Messages.Len())
 }
 
+func TestGetRoundTripTimeWhileProcessingAcknowledgedMessage(t *testing.T) {
+	testLog := log.NewMockLog()
+	dataChannel := getDataChannel()
+	dataChannel.AddDataToOutgoingMessageBuffer(StreamingMessage{streamingMessages[0].Content, 0, time.Now()})
+
+	// the port plugin reads the round trip time before and after the acknowledge handler updates it,
+	// run with -race to check that it is not changed under the readers
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		time.Sleep(10 * time.Millisecond)
+		dataChannel.ProcessAcknowledgedMessage(testLog, mgsContracts.AcknowledgeContent{SequenceNumber: 0})
+	}()
+	assert.Equal(t, mgsConfig.DefaultRoundTripTime, dataChannel.GetRoundTripTime())
+	time.Sleep(20 * time.Millisecond)
+	roundTripTime := dataChannel.GetRoundTripTime()
+	wg.Wait()
+
+	assert.NotEqual(t, mgsConfig.DefaultRoundTripTime, roundTripTime)
+	assert.Equal(t, 0, dataChannel.OutgoingMessageBuffer.Messages.Len())
+}
+
+func TestWaitForSendWindow(t *testing.T) {
+	dataChannel := getDataChannel()
+	dataChannel.maxInFlightMessages = 2
+	dataChannel.AddDataToOutgoingMessageBuffer(streamingMessages[0])
+	dataChannel.AddDataToOutgoingMessageBuffer(streamingMessages[1])
+
+	windowAvailable := make(chan bool, 1)
+	go func() {
+		dataChannel.WaitForSendWindow()
+		windowAvailable <- true
+	}()
+
+	select {
+	case <-windowAvailable:
+		assert.Fail(t, "send window should be full until a message is acknowledged")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	dataChannel.ProcessAcknowledgedMessage(mockLog, mgsContracts.AcknowledgeContent{SequenceNumber: streamingMessages[0].SequenceNumber})
+	select {
+	case <-windowAvailable:
+	case <-time.After(time.Second):
+		assert.Fail(t, "send window should be available after the acknowledgement")
+	}
+}
+
+func TestSendPriorityPayloadWithFullSendWindow(t *testing.T) {
+	dataChannel := getDataChannel()
+	dataChannel.detached = true
+	dataChannel.maxInFlightMessages = 2
+	dataChannel.OutgoingMessageBuffer.Capacity = 2
+	dataChannel.AddDataToOutgoingMessageBuffer(streamingMessages[0])
+	dataChannel.AddDataToOutgoingMessageBuffer(streamingMessages[1])
+	mockChannel := &communicatorMocks.IWebSocketChannel{}
+	mockChannel.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	dataChannel.wsChannel = mockChannel
+
+	sent := make(chan error, 1)
+	go func() {
+		sent <- dataChannel.SendStreamDataMessage(log.NewMockLog(), mgsContracts.Flag, []byte{0, 0, 0, 1})
+	}()
+
+	select {
+	case err := <-sent:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		assert.Fail(t, "flag payload should not wait for the send window")
+	}
+	mockChannel.AssertNumberOfCalls(t, "SendMessage", 1)
+	// the flag payload is kept for retransmission beyond the capacity of the buffer
+	assert.Equal(t, 3, dataChannel.OutgoingMessageBuffer.Messages.Len())
+	last := dataChannel.OutgoingMessageBuffer.Messages.Back().Value.(StreamingMessage)
+	assert.Equal(t, dataChannel.StreamDataSequenceNumber-1, last.SequenceNumber)
+}
+
+func TestWaitForSendWindowEndsWhenClosed(t *testing.T) {
+	dataChannel := getDataChannel()
+	dataChannel.maxInFlightMessages = 1
+	dataChannel.AddDataToOutgoingMessageBuffer(streamingMessages[0])
+	mockChannel := &communicatorMocks.IWebSocketChannel{}
+	mockChannel.On("Close", mock.Anything).Return(nil)
+	dataChannel.wsChannel = mockChannel
+
+	windowAvailable := make(chan bool, 1)
+	go func() {
+		dataChannel.WaitForSendWindow()
+		windowAvailable <- true
+	}()
+	dataChannel.Close(mockLog)
+
+	select {
+	case <-windowAvailable:
+	case <-time.After(time.Second):
+		assert.Fail(t, "waiting for the send window should end when the data channel closes")
+	}
+}
+
+func TestConfiguredRetransmissionTimeout(t *testing.T) {
+	dataChannel := getDataChannel()
+	dataChannel.initializeRetransmission(appconfig.MgsConfig{
+		DataChannelRetransmissionWindow:            500,
+		DataChannelRetransmissionTimeoutMultiplier: 2,
+		DataChannelMaxRetransmissionTimeoutMillis:  300,
+	})
+	assert.Equal(t, 500, dataChannel.retransmissionWindow)
+
+	dataChannel.RoundTripTime = float64(100 * time.Millisecond)
+	dataChannel.RoundTripTimeVariation = float64(100 * time.Millisecond)
+	// the round trip time of the message is the same as the smoothed one, the variation is reduced by a quarter
+	dataChannel.calculateRetransmissionTimeout(mockLog, StreamingMessage{LastSentTime: time.Now().Add(-100 * time.Millisecond)})
+	assert.InDelta(t, float64(100*time.Millisecond+2*75*time.Millisecond), float64(dataChannel.RetransmissionTimeout), float64(10*time.Millisecond))
+
+	dataChannel.RoundTripTimeVariation = float64(time.Second)
+	dataChannel.calculateRetransmissionTimeout(mockLog, StreamingMessage{LastSentTime: time.Now().Add(-100 * time.Millisecond)})
+	assert.Equal(t, 300*time.Millisecond, dataChannel.RetransmissionTimeout)
+}
+
 func TestSendAcknowledgeMessage(t *testing.T) {
 	dataChannel := getDataChannel()
 
@@ -351,6 +630,45 @@ func TestDataChannelIncomingMessageHandlerForExpectedInputStreamDataMessage(t *t
 	assert.Equal(t, int64(6), bufferedStreamMessage.SequenceNumber)
 }
 
+func TestDataChannelIncomingMessageHandlerForCorruptedInputStreamDataMessage(t *testing.T) {
+	dataChannel := getDataChannel()
+	mockChannel := &communicatorMocks.IWebSocketChannel{}
+	dataChannel.wsChannel = mockChannel
+	mockChannel.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	agentMessage := getAgentMessage(0, mgsContracts.InputStreamDataMessage, uint32(mgsContracts.Output), payload)
+	agentMessage.Flags |= mgsContracts.AgentMessage_ChecksumFlag
+	serializedAgentMessage, _ := agentMessage.Serialize(mockLog)
+	corruptedAgentMessage := append([]byte{}, serializedAgentMessage...)
+	corruptedAgentMessage[len(corruptedAgentMessage)-1] ^= 0xff
+
+	// corrupted messages are neither acknowledged nor processed so that the client retransmits them
+	err := dataChannel.dataChannelIncomingMessageHandler(mockLog, corruptedAgentMessage)
+	assert.NotNil(t, err)
+	assert.Equal(t, int64(0), dataChannel.ExpectedSequenceNumber)
+	mockChannel.AssertNumberOfCalls(t, "SendMessage", 0)
+
+	err = dataChannel.dataChannelIncomingMessageHandler(mockLog, serializedAgentMessage)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), dataChannel.ExpectedSequenceNumber)
+	mockChannel.AssertNumberOfCalls(t, "SendMessage", 1)
+}
+
+func TestSendStreamDataMessageWithChecksum(t *testing.T) {
+	dataChannel := getDataChannel()
+	dataChannel.handshake.capabilities = []mgsContracts.Capability{mgsContracts.MessageChecksumCapability}
+	mockWsChannel.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	err := dataChannel.SendStreamDataMessage(mockLog, mgsContracts.Output, payload)
+	assert.Nil(t, err)
+
+	agentMessage := mgsContracts.AgentMessage{}
+	agentMessage.Deserialize(mockLog, dataChannel.OutgoingMessageBuffer.Messages.Front().Value.(StreamingMessage).Content)
+	assert.NotEqual(t, uint64(0), agentMessage.Flags&mgsContracts.AgentMessage_ChecksumFlag)
+	assert.Nil(t, agentMessage.Validate())
+	assert.Equal(t, payload, agentMessage.Payload)
+}
+
 func TestDataChannelIncomingMessageHandlerForUnexpectedInputStreamDataMessage(t *testing.T) {
 	dataChannel := getDataChannel()
 	dataChannel.Pause = true
@@ -382,6 +700,28 @@ func TestDataChannelIncomingMessageHandlerForUnexpectedInputStreamDataMessage(t
 	assert.Nil(t, bufferedStreamMessage.Content)
 }
 
+func TestGetChannelQuality(t *testing.T) {
+	dataChannel := getDataChannel()
+	mockChannel := &communicatorMocks.IWebSocketChannel{}
+	dataChannel.wsChannel = mockChannel
+
+	mockChannel.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	dataChannel.SendStreamDataMessage(mockLog, mgsContracts.Output, payload)
+	dataChannel.dataChannelIncomingMessageHandler(mockLog, serializedAgentMessages[1])
+	dataChannel.dataChannelIncomingMessageHandler(mockLog, serializedAgentMessages[2])
+	dataChannel.dataChannelIncomingMessageHandler(mockLog, serializedAgentMessages[2])
+
+	quality := dataChannel.GetChannelQuality()
+	assert.Equal(t, sessionId, quality.SessionId)
+	assert.Equal(t, uint64(1), quality.MessagesSent)
+	assert.True(t, quality.BytesSent > uint64(len(payload)))
+	assert.Equal(t, uint64(2), quality.MessagesReceived)
+	assert.Equal(t, uint64(2), quality.OutOfOrderMessages)
+	assert.Equal(t, 1.0, quality.OutOfOrderRate)
+	assert.Equal(t, uint64(len(serializedAgentMessages[1])+len(serializedAgentMessages[2])), quality.BytesReceived)
+}
+
 func TestDataChannelIncomingMessageHandlerForAcknowledgeMessage(t *testing.T) {
 	dataChannel := getDataChannel()
 	dataChannel.Pause = true
@@ -474,6 +814,7 @@ func TestDataChannelHandshakeResponse(t *testing.T) {
 		uint32(mgsContracts.HandshakeResponse), handshakeResponsePayload).Serialize(mockLog)
 
 	mockChannel.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockCipher.On("SetCipher", appconfig.SessionEncryptionCipherAESGCM).Return(nil)
 	mockCipher.On("UpdateEncryptionKey", mockLog, datakey, sessionId, instanceId).Return(nil)
 
 	err := dataChannel.dataChannelIncomingMessageHandler(mockLog, agentMessageBytes)
@@ -532,6 +873,7 @@ func TestDataChannelHandshakeResponseEncryptionAgentFailure(t *testing.T) {
 	mockChannel.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	// Throw error when processing handshake response
 	errorString := "Failed to update encryption key. Something bad happened."
+	mockCipher.On("SetCipher", appconfig.SessionEncryptionCipherAESGCM).Return(nil)
 	mockCipher.On("UpdateEncryptionKey", mockLog, datakey, sessionId, instanceId).Return(errors.New(errorString))
 
 	mockCancelFlag.On("Set", task.Canceled).Return()
@@ -546,6 +888,257 @@ func TestDataChannelHandshakeResponseEncryptionAgentFailure(t *testing.T) {
 	mockCancelFlag.AssertExpectations(t)
 }
 
+func TestDataChannelHandshakeResponseChaCha20Poly1305(t *testing.T) {
+	dataChannel := getDataChannel()
+	dataChannel.preferredCipher = appconfig.SessionEncryptionCipherChaCha20Poly1305
+
+	mockChannel := &communicatorMocks.IWebSocketChannel{}
+	dataChannel.wsChannel = mockChannel
+	mockCipher := &cryptoMocks.IBlockCipher{}
+	dataChannel.blockCipher = mockCipher
+	// Default channel is not buffered, this causes a deadlock. Make the channel buffered for test.
+	dataChannel.handshake.responseChan = make(chan bool, 1)
+
+	handshakeResponse := buildHandshakeResponse()
+	handshakeResponse.Capabilities = []mgsContracts.Capability{mgsContracts.ChaCha20Poly1305Capability}
+	handshakeResponsePayload, _ := json.Marshal(handshakeResponse)
+	agentMessageBytes, _ := getAgentMessage(int64(0), mgsContracts.InputStreamDataMessage,
+		uint32(mgsContracts.HandshakeResponse), handshakeResponsePayload).Serialize(mockLog)
+
+	mockChannel.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockCipher.On("SetCipher", appconfig.SessionEncryptionCipherChaCha20Poly1305).Return(nil)
+	mockCipher.On("UpdateEncryptionKey", mockLog, datakey, sessionId, instanceId).Return(nil)
+
+	err := dataChannel.dataChannelIncomingMessageHandler(mockLog, agentMessageBytes)
+
+	assert.Nil(t, err)
+	assert.True(t, <-dataChannel.handshake.responseChan)
+	assert.True(t, dataChannel.encryptionEnabled)
+	assert.True(t, dataChannel.IsCapabilityEnabled(mgsContracts.ChaCha20Poly1305Capability))
+	mockCipher.AssertExpectations(t)
+}
+
+func TestOfferedCapabilities(t *testing.T) {
+	dataChannel := getDataChannel()
+
+	assert.NotContains(t, dataChannel.offeredCapabilities(), mgsContracts.ChaCha20Poly1305Capability)
+
+	dataChannel.preferredCipher = appconfig.SessionEncryptionCipherChaCha20Poly1305
+	assert.Contains(t, dataChannel.offeredCapabilities(), mgsContracts.ChaCha20Poly1305Capability)
+	assert.NotContains(t, agentCapabilities, mgsContracts.ChaCha20Poly1305Capability)
+}
+
+func TestOfferedCapabilitiesWithEncryption(t *testing.T) {
+	dataChannel := getDataChannel()
+	assert.Contains(t, dataChannel.offeredCapabilities(), mgsContracts.GzipCompressionCapability)
+
+	dataChannel.encryptionEnabled = true
+	offered := dataChannel.offeredCapabilities()
+
+	assert.NotContains(t, offered, mgsContracts.GzipCompressionCapability)
+	assert.Contains(t, offered, mgsContracts.MultiplexingCapability)
+	assert.Nil(t, selectCompressor(negotiateCapabilities(offered, agentCapabilities)))
+}
+
+func TestDataChannelHandshakeResponseCapabilities(t *testing.T) {
+	dataChannel := getDataChannel()
+
+	mockChannel := &communicatorMocks.IWebSocketChannel{}
+	dataChannel.wsChannel = mockChannel
+	// Default channel is not buffered, this causes a deadlock. Make the channel buffered for test.
+	dataChannel.handshake.responseChan = make(chan bool, 1)
+
+	handshakeResponse := mgsContracts.HandshakeResponsePayload{
+		ClientVersion: versionString,
+		Capabilities:  []mgsContracts.Capability{mgsContracts.SignalPayloadCapability, "UnknownCapability"},
+	}
+	handshakeResponsePayload, _ := json.Marshal(handshakeResponse)
+	agentMessageBytes, _ := getAgentMessage(int64(0), mgsContracts.InputStreamDataMessage,
+		uint32(mgsContracts.HandshakeResponse), handshakeResponsePayload).Serialize(mockLog)
+	mockChannel.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	err := dataChannel.dataChannelIncomingMessageHandler(mockLog, agentMessageBytes)
+
+	assert.Nil(t, err)
+	assert.True(t, <-dataChannel.handshake.responseChan)
+	assert.True(t, dataChannel.IsCapabilityEnabled(mgsContracts.SignalPayloadCapability))
+	assert.False(t, dataChannel.IsCapabilityEnabled(mgsContracts.GzipCompressionCapability))
+	assert.False(t, dataChannel.IsCapabilityEnabled("UnknownCapability"))
+	assert.Equal(t, []mgsContracts.Capability{mgsContracts.SignalPayloadCapability},
+		dataChannel.buildHandshakeCompletePayload(mockLog).Capabilities)
+}
+
+func TestSendStreamDataMessageWhileHandlingHandshakeResponse(t *testing.T) {
+	testLog := log.NewMockLog()
+	dataChannel := getDataChannel()
+	mockChannel := &communicatorMocks.IWebSocketChannel{}
+	dataChannel.wsChannel = mockChannel
+	dataChannel.handshake.responseChan = make(chan bool, 1)
+	mockChannel.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	handshakeResponse := mgsContracts.HandshakeResponsePayload{
+		ClientVersion: versionString,
+		Capabilities:  []mgsContracts.Capability{mgsContracts.MessageChecksumCapability},
+	}
+	handshakeResponsePayload, _ := json.Marshal(handshakeResponse)
+	agentMessageBytes, _ := getAgentMessage(int64(0), mgsContracts.InputStreamDataMessage,
+		uint32(mgsContracts.HandshakeResponse), handshakeResponsePayload).Serialize(testLog)
+
+	// output is sent before and after the capabilities are negotiated,
+	// run with -race to check that the capabilities are not changed under the senders
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		time.Sleep(10 * time.Millisecond)
+		assert.Nil(t, dataChannel.dataChannelIncomingMessageHandler(testLog, agentMessageBytes))
+	}()
+	for i := 0; i < 5; i++ {
+		if i == 1 {
+			time.Sleep(20 * time.Millisecond)
+		}
+		assert.Nil(t, dataChannel.SendStreamDataMessage(testLog, mgsContracts.Output, []byte("output"+strconv.Itoa(i))))
+	}
+	wg.Wait()
+
+	assert.True(t, <-dataChannel.handshake.responseChan)
+	assert.True(t, dataChannel.IsCapabilityEnabled(mgsContracts.MessageChecksumCapability))
+}
+
+func TestNegotiateCapabilitiesWithOlderClient(t *testing.T) {
+	negotiated := negotiateCapabilities(agentCapabilities, nil)
+
+	assert.Empty(t, negotiated)
+}
+
+func TestSendStreamDataMessageCompression(t *testing.T) {
+	dataChannel := getDataChannel()
+	dataChannel.compressor = gzipCompressor{}
+	dataChannel.compressionThreshold = 64
+	mockWsChannel.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	smallPayload := []byte("ls -l\r")
+	largePayload := []byte(strings.Repeat("compressible stream data ", 100))
+
+	dataChannel.SendStreamDataMessage(mockLog, mgsContracts.Output, smallPayload)
+	dataChannel.SendStreamDataMessage(mockLog, mgsContracts.Output, largePayload)
+
+	sent := []mgsContracts.AgentMessage{}
+	for element := dataChannel.OutgoingMessageBuffer.Messages.Front(); element != nil; element = element.Next() {
+		agentMessage := mgsContracts.AgentMessage{}
+		agentMessage.Deserialize(mockLog, element.Value.(StreamingMessage).Content)
+		sent = append(sent, agentMessage)
+	}
+	assert.Equal(t, 2, len(sent))
+	// small interactive payloads are sent as is
+	assert.Equal(t, uint64(0), sent[0].Flags&mgsContracts.AgentMessage_CompressedFlag)
+	assert.Equal(t, smallPayload, sent[0].Payload)
+	assert.NotEqual(t, uint64(0), sent[1].Flags&mgsContracts.AgentMessage_CompressedFlag)
+	assert.True(t, len(sent[1].Payload) < len(largePayload))
+	decompressed, err := dataChannel.decompressPayload(sent[1])
+	assert.Nil(t, err)
+	assert.Equal(t, largePayload, decompressed)
+}
+
+func TestSendStreamDataMessageToStream(t *testing.T) {
+	dataChannel := getDataChannel()
+	mockWsChannel.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	err := dataChannel.SendStreamDataMessageToStream(mockLog, 3, mgsContracts.Output, payload)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, dataChannel.OutgoingMessageBuffer.Messages.Len())
+
+	dataChannel.handshake.capabilities = []mgsContracts.Capability{mgsContracts.MultiplexingCapability}
+	err = dataChannel.SendStreamDataMessageToStream(mockLog, 3, mgsContracts.Output, payload)
+	assert.Nil(t, err)
+	err = dataChannel.SendStreamDataMessage(mockLog, mgsContracts.Output, payload)
+	assert.Nil(t, err)
+
+	sent := []mgsContracts.AgentMessage{}
+	for element := dataChannel.OutgoingMessageBuffer.Messages.Front(); element != nil; element = element.Next() {
+		agentMessage := mgsContracts.AgentMessage{}
+		agentMessage.Deserialize(mockLog, element.Value.(StreamingMessage).Content)
+		sent = append(sent, agentMessage)
+	}
+	assert.Equal(t, 2, len(sent))
+	assert.Equal(t, uint32(3), sent[0].StreamId)
+	assert.Equal(t, payload, sent[0].Payload)
+	assert.Equal(t, mgsContracts.AgentMessage_DefaultStreamId, sent[1].StreamId)
+	// streams share the sequence numbers of the data channel
+	assert.Equal(t, sent[0].SequenceNumber+1, sent[1].SequenceNumber)
+}
+
+func TestStreamRouter(t *testing.T) {
+	received := map[string]uint32{}
+	handlerFor := func(name string) InputStreamMessageHandler {
+		return func(log log.T, streamDataMessage mgsContracts.AgentMessage) error {
+			received[name] = streamDataMessage.StreamId
+			return nil
+		}
+	}
+	router := NewStreamRouter(handlerFor("default"))
+	router.Register(5, handlerFor("stream5"))
+
+	assert.Nil(t, router.HandleMessage(mockLog, mgsContracts.AgentMessage{StreamId: 0}))
+	assert.Nil(t, router.HandleMessage(mockLog, mgsContracts.AgentMessage{StreamId: 5}))
+	assert.Nil(t, router.HandleMessage(mockLog, mgsContracts.AgentMessage{StreamId: 6}))
+	assert.Equal(t, map[string]uint32{"default": 0, "stream5": 5}, received)
+
+	router.Unregister(5)
+	delete(received, "stream5")
+	assert.Nil(t, router.HandleMessage(mockLog, mgsContracts.AgentMessage{StreamId: 5}))
+	assert.Equal(t, map[string]uint32{"default": 0}, received)
+}
+
+func TestProcessCompressedStreamDataMessage(t *testing.T) {
+	dataChannel := getDataChannel()
+	dataChannel.handshake.complete = true
+	var received []byte
+	dataChannel.inputStreamMessageHandler = func(log log.T, streamDataMessage mgsContracts.AgentMessage) error {
+		received = streamDataMessage.Payload
+		return nil
+	}
+	compressedPayload, _ := gzipCompressor{}.compress(payload)
+	agentMessage := getAgentMessage(int64(0), mgsContracts.InputStreamDataMessage, uint32(mgsContracts.Output), compressedPayload)
+	agentMessage.Flags |= mgsContracts.AgentMessage_CompressedFlag
+
+	// compressed payloads are refused unless compression was negotiated
+	assert.NotNil(t, dataChannel.processStreamDataMessage(mockLog, *agentMessage))
+	assert.Nil(t, received)
+
+	dataChannel.compressor = selectCompressor([]mgsContracts.Capability{mgsContracts.GzipCompressionCapability})
+	assert.Nil(t, dataChannel.processStreamDataMessage(mockLog, *agentMessage))
+	assert.Equal(t, payload, received)
+}
+
+func TestDataChannelLatencyProbeRequest(t *testing.T) {
+	dataChannel := getDataChannel()
+	mockChannel := &communicatorMocks.IWebSocketChannel{}
+	dataChannel.wsChannel = mockChannel
+
+	probeRequestPayload, _ := json.Marshal(mgsContracts.LatencyProbeRequestPayload{ProbeId: "probe-1", ClientTimestamp: 1000})
+	agentMessageBytes, _ := getAgentMessage(int64(0), mgsContracts.InputStreamDataMessage,
+		uint32(mgsContracts.LatencyProbeRequest), probeRequestPayload).Serialize(mockLog)
+
+	probeResponseMatcher := func(sentData []byte) bool {
+		agentMessage := mgsContracts.AgentMessage{}
+		agentMessage.Deserialize(mockLog, sentData)
+		var probeResponse mgsContracts.LatencyProbeResponsePayload
+		json.Unmarshal(agentMessage.Payload, &probeResponse)
+		return agentMessage.PayloadType == uint32(mgsContracts.LatencyProbeResponse) &&
+			probeResponse.ProbeId == "probe-1" &&
+			probeResponse.ClientTimestamp == 1000 &&
+			probeResponse.AgentReceivedTimestamp > 0 &&
+			probeResponse.AgentSentTimestamp >= probeResponse.AgentReceivedTimestamp
+	}
+	// Account for acknowledgements being sent along with the probe response
+	mockChannel.On("SendMessage", mockLog, mock.Anything, mock.Anything).Return(nil)
+
+	err := dataChannel.dataChannelIncomingMessageHandler(mockLog, agentMessageBytes)
+
+	assert.Nil(t, err)
+	mockChannel.AssertCalled(t, "SendMessage", mockLog, mock.MatchedBy(probeResponseMatcher), mock.Anything)
+}
+
 func TestDataCHannelHandshakeInitiate(t *testing.T) {
 	dataChannel := getDataChannel()
 	mockChannel := &communicatorMocks.IWebSocketChannel{}
@@ -692,3 +1285,156 @@ func buildHandshakeResponseEncryptionFailed() mgsContracts.HandshakeResponsePayl
 	handshakeResponse.ProcessedClientActions = append(handshakeResponse.ProcessedClientActions, processedAction)
 	return handshakeResponse
 }
+
+func TestPrioritySendLock(t *testing.T) {
+	lock := prioritySendLock{}
+	lock.Lock(false)
+
+	acquired := make(chan string, 2)
+	go func() {
+		lock.Lock(false)
+		acquired <- "bulk"
+		lock.Unlock()
+	}()
+	go func() {
+		lock.Lock(true)
+		acquired <- "priority"
+		lock.Unlock()
+	}()
+	for waiting := 0; waiting == 0; {
+		time.Sleep(time.Millisecond)
+		lock.mutex.Lock()
+		waiting = lock.waitingPriority
+		lock.mutex.Unlock()
+	}
+	lock.Unlock()
+
+	assert.Equal(t, "priority", <-acquired)
+	assert.Equal(t, "bulk", <-acquired)
+	assert.True(t, isPriorityPayload(mgsContracts.Flag))
+	assert.False(t, isPriorityPayload(mgsContracts.Output))
+}
+
+func TestOutageBufferSpillsToDisk(t *testing.T) {
+	spillRoot, _ := ioutil.TempDir("", "outage")
+	defer os.RemoveAll(spillRoot)
+	outageSpillRoot = spillRoot
+	_, streamingMessages := getAgentAndStreamingMessageList(4)
+	messageSize := len(streamingMessages[0].Content)
+
+	buffer := newOutageBuffer(sessionId, messageSize, 2*messageSize)
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, buffer.Push(streamingMessages[i]))
+	}
+	assert.NotNil(t, buffer.Push(streamingMessages[3]))
+	assert.Equal(t, 3, buffer.Len())
+	spilled, _ := ioutil.ReadDir(filepath.Join(spillRoot, sessionId))
+	assert.Equal(t, 2, len(spilled))
+
+	for i := 0; i < 3; i++ {
+		message, ok, err := buffer.Pop()
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, streamingMessages[i].SequenceNumber, message.SequenceNumber)
+		assert.Equal(t, streamingMessages[i].Content, message.Content)
+	}
+	_, ok, _ := buffer.Pop()
+	assert.False(t, ok)
+
+	buffer.Push(streamingMessages[0])
+	buffer.Push(streamingMessages[1])
+	buffer.Clear()
+	assert.Equal(t, 0, buffer.Len())
+	_, err := os.Stat(filepath.Join(spillRoot, sessionId))
+	assert.True(t, os.IsNotExist(err))
+	assert.Nil(t, newOutageBuffer(sessionId, 0, 0))
+}
+
+func TestSendStreamDataMessageDuringOutage(t *testing.T) {
+	dataChannel := getDataChannel()
+	mockChannel := &communicatorMocks.IWebSocketChannel{}
+	dataChannel.wsChannel = mockChannel
+	mockChannel.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	dataChannel.OutgoingMessageBuffer.Capacity = 2
+	dataChannel.outageBuffer = newOutageBuffer(sessionId, 1024*1024, 0)
+	dataChannel.reconnecting = true
+
+	for i := 0; i < 4; i++ {
+		assert.Nil(t, dataChannel.SendStreamDataMessage(mockLog, mgsContracts.Output, []byte("output"+strconv.Itoa(i))))
+	}
+	mockChannel.AssertNumberOfCalls(t, "SendMessage", 0)
+	assert.Equal(t, 2, dataChannel.OutgoingMessageBuffer.Messages.Len())
+	assert.Equal(t, 2, dataChannel.outageBuffer.Len())
+
+	// messages are replayed once the data channel is restored and sent from the outage buffer as they are acknowledged
+	dataChannel.reconnecting = false
+	dataChannel.replayOutgoingMessages(mockLog)
+	mockChannel.AssertNumberOfCalls(t, "SendMessage", 2)
+
+	dataChannel.ProcessAcknowledgedMessage(mockLog, mgsContracts.AcknowledgeContent{SequenceNumber: 0})
+	mockChannel.AssertNumberOfCalls(t, "SendMessage", 3)
+	assert.Equal(t, 1, dataChannel.outageBuffer.Len())
+
+	// later messages wait for the outage buffer to be drained so that they are sent in order
+	assert.Nil(t, dataChannel.SendStreamDataMessage(mockLog, mgsContracts.Output, []byte("output4")))
+	assert.Equal(t, 2, dataChannel.outageBuffer.Len())
+	sequenceNumbers := []int64{}
+	for element := dataChannel.OutgoingMessageBuffer.Messages.Front(); element != nil; element = element.Next() {
+		sequenceNumbers = append(sequenceNumbers, element.Value.(StreamingMessage).SequenceNumber)
+	}
+	assert.Equal(t, []int64{1, 2}, sequenceNumbers)
+}
+
+func TestDrainOutageBufferWhileSending(t *testing.T) {
+	testLog := log.NewMockLog()
+	dataChannel := getDataChannel()
+	mockChannel := &communicatorMocks.IWebSocketChannel{}
+	dataChannel.wsChannel = mockChannel
+	dataChannel.OutgoingMessageBuffer.Capacity = 2
+	dataChannel.outageBuffer = newOutageBuffer(sessionId, 1024*1024, 0)
+	dataChannel.reconnecting = true
+	for i := 0; i < 6; i++ {
+		assert.Nil(t, dataChannel.SendStreamDataMessage(testLog, mgsContracts.Output, []byte("output"+strconv.Itoa(i))))
+	}
+	dataChannel.reconnecting = false
+
+	// the plugin sends output while the last message of the outage buffer is being sent
+	lastDrained := make(chan bool)
+	nextSent := make(chan bool)
+	sent := []int64{}
+	mockChannel.On("SendMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		agentMessage := &mgsContracts.AgentMessage{}
+		agentMessage.Deserialize(testLog, args.Get(1).([]byte))
+		sent = append(sent, agentMessage.SequenceNumber)
+		if agentMessage.SequenceNumber == 5 {
+			close(lastDrained)
+			select {
+			case <-nextSent:
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 4; i++ {
+			dataChannel.ProcessAcknowledgedMessage(testLog, mgsContracts.AcknowledgeContent{SequenceNumber: int64(i)})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		<-lastDrained
+		assert.Nil(t, dataChannel.SendStreamDataMessage(testLog, mgsContracts.Output, []byte("output6")))
+		close(nextSent)
+	}()
+	wg.Wait()
+
+	assert.Equal(t, []int64{2, 3, 4, 5, 6}, sent)
+	sequenceNumbers := []int64{}
+	for element := dataChannel.OutgoingMessageBuffer.Messages.Front(); element != nil; element = element.Next() {
+		sequenceNumbers = append(sequenceNumbers, element.Value.(StreamingMessage).SequenceNumber)
+	}
+	assert.Equal(t, []int64{4, 5}, sequenceNumbers)
+}
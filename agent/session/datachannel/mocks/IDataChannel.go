@@ -21,7 +21,9 @@ import "container/list"
 import "github.com/aws/amazon-ssm-agent/agent/log"
 import "github.com/stretchr/testify/mock"
 import "github.com/aws/amazon-ssm-agent/agent/session/service"
+import "github.com/aws/amazon-ssm-agent/agent/session/telemetry"
 import "github.com/aws/amazon-ssm-agent/agent/task"
+import "time"
 
 // IDataChannel is an autogenerated mock type for the IDataChannel type
 type IDataChannel struct {
@@ -52,6 +54,39 @@ func (_m *IDataChannel) Close(_a0 log.T) error {
 	return r0
 }
 
+// GetRoundTripTime provides a mock function with given fields:
+func (_m *IDataChannel) GetRoundTripTime() time.Duration {
+	ret := _m.Called()
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	return r0
+}
+
+// SetStreamDataPayloadSize provides a mock function with given fields: payloadSize
+func (_m *IDataChannel) SetStreamDataPayloadSize(payloadSize int) {
+	_m.Called(payloadSize)
+}
+
+// IsCapabilityEnabled provides a mock function with given fields: capability
+func (_m *IDataChannel) IsCapabilityEnabled(capability contracts.Capability) bool {
+	ret := _m.Called(capability)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(contracts.Capability) bool); ok {
+		r0 = rf(capability)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // Initialize provides a mock function with given fields: _a0, mgsService, sessionId, clientId, instanceId, role, cancelFlag, inputStreamMessageHandler
 func (_m *IDataChannel) Initialize(_a0 context.T, mgsService service.Service, sessionId string, clientId string, instanceId string, role string, cancelFlag task.CancelFlag, inputStreamMessageHandler datachannel.InputStreamMessageHandler) {
 	_m.Called(_a0, mgsService, sessionId, clientId, instanceId, role, cancelFlag, inputStreamMessageHandler)
@@ -184,6 +219,20 @@ func (_m *IDataChannel) SendStreamDataMessage(_a0 log.T, dataType contracts.Payl
 	return r0
 }
 
+// SendStreamDataMessageToStream provides a mock function with given fields: _a0, streamId, dataType, inputData
+func (_m *IDataChannel) SendStreamDataMessageToStream(_a0 log.T, streamId uint32, dataType contracts.PayloadType, inputData []byte) error {
+	ret := _m.Called(_a0, streamId, dataType, inputData)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(log.T, uint32, contracts.PayloadType, []byte) error); ok {
+		r0 = rf(_a0, streamId, dataType, inputData)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetWebSocket provides a mock function with given fields: _a0, mgsService, sessionId, clientId, onMessageHandler
 func (_m *IDataChannel) SetWebSocket(_a0 context.T, mgsService service.Service, sessionId string, clientId string, onMessageHandler func([]byte)) error {
 	ret := _m.Called(_a0, mgsService, sessionId, clientId, onMessageHandler)
@@ -202,3 +251,22 @@ func (_m *IDataChannel) SetWebSocket(_a0 context.T, mgsService service.Service,
 func (_m *IDataChannel) SkipHandshake(_a0 log.T) {
 	_m.Called(_a0)
 }
+
+// WaitForSendWindow provides a mock function with given fields:
+func (_m *IDataChannel) WaitForSendWindow() {
+	_m.Called()
+}
+
+// GetChannelQuality provides a mock function with given fields:
+func (_m *IDataChannel) GetChannelQuality() telemetry.ChannelQuality {
+	ret := _m.Called()
+
+	var r0 telemetry.ChannelQuality
+	if rf, ok := ret.Get(0).(func() telemetry.ChannelQuality); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(telemetry.ChannelQuality)
+	}
+
+	return r0
+}
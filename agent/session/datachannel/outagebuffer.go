@@ -0,0 +1,133 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package datachannel implements data channel which is used to interactively run commands.
+package datachannel
+
+import (
+	"container/list"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+)
+
+var outageSpillRoot = filepath.Join(appconfig.DefaultDataStorePath, "session", "outage")
+
+// outageBuffer holds the stream data messages sent while the data channel reconnects that do not fit
+// in the outgoing message buffer, in sequence order. Messages are kept in memory up to maxMemoryBytes,
+// later ones are spilled to files of the session up to maxSpillBytes.
+type outageBuffer struct {
+	mutex          sync.Mutex
+	memory         *list.List
+	memoryBytes    int
+	maxMemoryBytes int
+	spillDir       string
+	spilled        []int64
+	spilledBytes   int
+	maxSpillBytes  int
+}
+
+// newOutageBuffer returns the outage buffer of the session, nil if outage buffering is disabled.
+func newOutageBuffer(sessionId string, maxMemoryBytes int, maxSpillBytes int) *outageBuffer {
+	if maxMemoryBytes <= 0 {
+		return nil
+	}
+	return &outageBuffer{
+		memory:         list.New(),
+		maxMemoryBytes: maxMemoryBytes,
+		spillDir:       filepath.Join(outageSpillRoot, sessionId),
+		maxSpillBytes:  maxSpillBytes,
+	}
+}
+
+// Len returns the number of messages in the buffer.
+func (buffer *outageBuffer) Len() int {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+	return buffer.memory.Len() + len(buffer.spilled)
+}
+
+// Push appends the message, returning an error if the buffer is full.
+func (buffer *outageBuffer) Push(message StreamingMessage) error {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	// once messages are spilled, later messages are spilled too so that they stay in order
+	if len(buffer.spilled) == 0 && buffer.memoryBytes+len(message.Content) <= buffer.maxMemoryBytes {
+		buffer.memory.PushBack(message)
+		buffer.memoryBytes += len(message.Content)
+		return nil
+	}
+	if buffer.spilledBytes+len(message.Content) > buffer.maxSpillBytes {
+		return fmt.Errorf("outage buffer is full, dropping stream data message sequence %d", message.SequenceNumber)
+	}
+
+	if err := fileutil.MakeDirs(buffer.spillDir); err != nil {
+		return fmt.Errorf("unable to create outage buffer directory %s: %v", buffer.spillDir, err)
+	}
+	if err := ioutil.WriteFile(buffer.spillPath(message.SequenceNumber), message.Content, appconfig.ReadWriteAccess); err != nil {
+		return fmt.Errorf("unable to spill stream data message sequence %d: %v", message.SequenceNumber, err)
+	}
+	buffer.spilled = append(buffer.spilled, message.SequenceNumber)
+	buffer.spilledBytes += len(message.Content)
+	return nil
+}
+
+// Pop removes and returns the oldest message, ok is false if the buffer is empty.
+func (buffer *outageBuffer) Pop() (message StreamingMessage, ok bool, err error) {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	if element := buffer.memory.Front(); element != nil {
+		message = buffer.memory.Remove(element).(StreamingMessage)
+		buffer.memoryBytes -= len(message.Content)
+		return message, true, nil
+	}
+	if len(buffer.spilled) == 0 {
+		return message, false, nil
+	}
+
+	sequenceNumber := buffer.spilled[0]
+	buffer.spilled = buffer.spilled[1:]
+	spillPath := buffer.spillPath(sequenceNumber)
+	defer os.Remove(spillPath)
+	content, err := ioutil.ReadFile(spillPath)
+	if err != nil {
+		return message, false, fmt.Errorf("unable to read spilled stream data message sequence %d: %v", sequenceNumber, err)
+	}
+	buffer.spilledBytes -= len(content)
+	return StreamingMessage{Content: content, SequenceNumber: sequenceNumber}, true, nil
+}
+
+// Clear removes all messages and the spilled files of the session.
+func (buffer *outageBuffer) Clear() {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	buffer.memory.Init()
+	buffer.memoryBytes = 0
+	buffer.spilled = nil
+	buffer.spilledBytes = 0
+	os.RemoveAll(buffer.spillDir)
+}
+
+// spillPath returns the file holding the spilled message of the sequence number.
+func (buffer *outageBuffer) spillPath(sequenceNumber int64) string {
+	return filepath.Join(buffer.spillDir, strconv.FormatInt(sequenceNumber, 10))
+}
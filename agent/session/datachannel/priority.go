@@ -0,0 +1,65 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package datachannel implements data channel which is used to interactively run commands.
+package datachannel
+
+import (
+	"sync"
+
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+)
+
+// prioritySendLock serializes stream data messages like a mutex, except that waiting high priority senders
+// take the lock before waiting bulk senders. This keeps flag, resize and handshake messages from queueing
+// behind output of sessions sending at full speed. The zero value is an unlocked lock.
+type prioritySendLock struct {
+	mutex           sync.Mutex
+	released        *sync.Cond
+	locked          bool
+	waitingPriority int
+}
+
+// Lock waits until the lock is free and no high priority sender is waiting, unless priority is set.
+func (lock *prioritySendLock) Lock(priority bool) {
+	lock.mutex.Lock()
+	defer lock.mutex.Unlock()
+	if lock.released == nil {
+		lock.released = sync.NewCond(&lock.mutex)
+	}
+
+	if priority {
+		lock.waitingPriority++
+		defer func() { lock.waitingPriority-- }()
+	}
+	for lock.locked || (!priority && lock.waitingPriority > 0) {
+		lock.released.Wait()
+	}
+	lock.locked = true
+}
+
+// Unlock releases the lock to the waiting senders.
+func (lock *prioritySendLock) Unlock() {
+	lock.mutex.Lock()
+	defer lock.mutex.Unlock()
+	lock.locked = false
+	if lock.released != nil {
+		lock.released.Broadcast()
+	}
+}
+
+// isPriorityPayload returns true for control payloads, which are sent before output and errors waiting to be sent.
+// Control payloads are sent even when the outgoing message buffer is full, so they do not wait for the send window.
+func isPriorityPayload(payloadType mgsContracts.PayloadType) bool {
+	return payloadType != mgsContracts.Output && payloadType != mgsContracts.Error
+}
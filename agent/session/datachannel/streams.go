@@ -0,0 +1,70 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package datachannel implements data channel which is used to interactively run commands.
+package datachannel
+
+import (
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+)
+
+// StreamRouter dispatches incoming stream data messages to the handler of their logical stream,
+// letting a session plugin carry several independent byte streams on its data channel.
+// Its HandleMessage method is used as the InputStreamMessageHandler of the data channel.
+type StreamRouter struct {
+	mutex          sync.RWMutex
+	defaultHandler InputStreamMessageHandler
+	handlers       map[uint32]InputStreamMessageHandler
+}
+
+// NewStreamRouter returns a StreamRouter passing messages of the default stream to defaultHandler.
+func NewStreamRouter(defaultHandler InputStreamMessageHandler) *StreamRouter {
+	return &StreamRouter{
+		defaultHandler: defaultHandler,
+		handlers:       make(map[uint32]InputStreamMessageHandler),
+	}
+}
+
+// Register sets the handler of messages received on the stream, replacing its previous handler.
+func (router *StreamRouter) Register(streamId uint32, handler InputStreamMessageHandler) {
+	router.mutex.Lock()
+	defer router.mutex.Unlock()
+	router.handlers[streamId] = handler
+}
+
+// Unregister removes the handler of the stream, messages later received on it are dropped.
+func (router *StreamRouter) Unregister(streamId uint32) {
+	router.mutex.Lock()
+	defer router.mutex.Unlock()
+	delete(router.handlers, streamId)
+}
+
+// HandleMessage passes the stream data message to the handler of its stream.
+func (router *StreamRouter) HandleMessage(log log.T, streamDataMessage mgsContracts.AgentMessage) error {
+	if streamDataMessage.StreamId == mgsContracts.AgentMessage_DefaultStreamId {
+		return router.defaultHandler(log, streamDataMessage)
+	}
+
+	router.mutex.RLock()
+	handler, ok := router.handlers[streamDataMessage.StreamId]
+	router.mutex.RUnlock()
+	if !ok {
+		log.Debugf("Dropping stream data message sequence %d of unknown stream %d",
+			streamDataMessage.SequenceNumber, streamDataMessage.StreamId)
+		return nil
+	}
+	return handler(log, streamDataMessage)
+}
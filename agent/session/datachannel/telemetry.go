@@ -0,0 +1,110 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package datachannel implements data channel which is used to interactively run commands.
+package datachannel
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/session/telemetry"
+)
+
+// channelStatistics counts the stream data traffic of a data channel for network quality telemetry.
+type channelStatistics struct {
+	mutex              sync.Mutex
+	startTime          time.Time
+	messagesSent       uint64
+	bytesSent          uint64
+	retransmissions    uint64
+	messagesReceived   uint64
+	bytesReceived      uint64
+	outOfOrderMessages uint64
+}
+
+// recordSent counts a stream data message sent for the first time.
+func (statistics *channelStatistics) recordSent(size int) {
+	statistics.mutex.Lock()
+	defer statistics.mutex.Unlock()
+	statistics.messagesSent++
+	statistics.bytesSent += uint64(size)
+}
+
+// recordRetransmission counts a stream data message sent again for lack of acknowledgement.
+func (statistics *channelStatistics) recordRetransmission() {
+	statistics.mutex.Lock()
+	defer statistics.mutex.Unlock()
+	statistics.retransmissions++
+}
+
+// recordReceived counts a new incoming stream data message, outOfOrder if it arrived before a preceding message.
+func (statistics *channelStatistics) recordReceived(size int, outOfOrder bool) {
+	statistics.mutex.Lock()
+	defer statistics.mutex.Unlock()
+	statistics.messagesReceived++
+	statistics.bytesReceived += uint64(size)
+	if outOfOrder {
+		statistics.outOfOrderMessages++
+	}
+}
+
+// GetChannelQuality returns the network quality of the data channel since it was initialized.
+func (dataChannel *DataChannel) GetChannelQuality() telemetry.ChannelQuality {
+	statistics := &dataChannel.statistics
+	statistics.mutex.Lock()
+	defer statistics.mutex.Unlock()
+
+	duration := time.Since(statistics.startTime)
+	quality := telemetry.ChannelQuality{
+		SessionId:                dataChannel.ChannelId,
+		RoundTripTimeMs:          int64(dataChannel.GetRoundTripTime() / time.Millisecond),
+		RoundTripTimeVariationMs: int64(dataChannel.getRoundTripTimeVariation() / time.Millisecond),
+		MessagesSent:             statistics.messagesSent,
+		Retransmissions:          statistics.retransmissions,
+		MessagesReceived:         statistics.messagesReceived,
+		OutOfOrderMessages:       statistics.outOfOrderMessages,
+		BytesSent:                statistics.bytesSent,
+		BytesReceived:            statistics.bytesReceived,
+		DurationMs:               int64(duration / time.Millisecond),
+	}
+	if statistics.messagesReceived > 0 {
+		quality.OutOfOrderRate = float64(statistics.outOfOrderMessages) / float64(statistics.messagesReceived)
+	}
+	if seconds := duration.Seconds(); seconds > 0 {
+		quality.SendThroughputBytesPerSecond = float64(statistics.bytesSent) / seconds
+		quality.ReceiveThroughputBytesPerSecond = float64(statistics.bytesReceived) / seconds
+	}
+	return quality
+}
+
+// reportChannelQuality writes the quality of the data channel at every telemetry report interval until it is closed.
+func (dataChannel *DataChannel) reportChannelQuality(log log.T) {
+	for {
+		time.Sleep(telemetry.ReportInterval)
+		if dataChannel.isClosed() {
+			return
+		}
+		if err := telemetry.Write(dataChannel.GetChannelQuality()); err != nil {
+			log.Debugf("Unable to write data channel quality: %v", err)
+		}
+	}
+}
+
+// isClosed returns true once the data channel has been closed.
+func (dataChannel *DataChannel) isClosed() bool {
+	dataChannel.OutgoingMessageBuffer.Mutex.Lock()
+	defer dataChannel.OutgoingMessageBuffer.Mutex.Unlock()
+	return dataChannel.closed
+}
@@ -0,0 +1,56 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package external runs session plugins shipped as executables in the session plugins directory.
+package external
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
+)
+
+// PluginsDirectory returns the directory external session plugins are discovered from
+func PluginsDirectory() string {
+	return filepath.Join(appconfig.DefaultPluginPath, mgsConfig.ExternalSessionPluginsFolderName)
+}
+
+// DiscoverPlugins returns the paths of the external session plugins in the directory, indexed by plugin name.
+// The name of a plugin is the name of its executable, which is the session type it serves.
+func DiscoverPlugins(log log.T, directory string) map[string]string {
+	plugins := make(map[string]string)
+	files, err := ioutil.ReadDir(directory)
+	if err != nil {
+		log.Debugf("No external session plugins loaded from %s: %v", directory, err)
+		return plugins
+	}
+	if !trustedDirectory(directory) {
+		log.Warnf("Ignoring external session plugins in %s as the directory is not owned by root or is writable by other users", directory)
+		return plugins
+	}
+	for _, file := range files {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+		name, ok := pluginName(file)
+		if !ok {
+			log.Warnf("Ignoring %s in external session plugins directory as it is not a valid plugin executable", file.Name())
+			continue
+		}
+		plugins[name] = filepath.Join(directory, file.Name())
+	}
+	return plugins
+}
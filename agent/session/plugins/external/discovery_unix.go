@@ -0,0 +1,44 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build darwin freebsd linux netbsd openbsd
+
+// Package external runs session plugins shipped as executables in the session plugins directory.
+package external
+
+import (
+	"os"
+	"syscall"
+)
+
+// pluginOwnerUid is the user that has to own the plugin executables and their directory, as the agent runs them as root
+var pluginOwnerUid uint32 = 0
+
+// pluginName returns the name of the plugin served by the file,
+// which has to be executable, owned by root and not writable by group or other users.
+func pluginName(file os.FileInfo) (string, bool) {
+	return file.Name(), file.Mode().Perm()&0111 != 0 && ownedByRoot(file)
+}
+
+// trustedDirectory returns true if the plugins directory is owned by root and not writable by group or other users,
+// so that other users cannot replace the plugin executables.
+func trustedDirectory(directory string) bool {
+	info, err := os.Stat(directory)
+	return err == nil && info.IsDir() && ownedByRoot(info)
+}
+
+// ownedByRoot returns true if the file is owned by root and not writable by group or other users
+func ownedByRoot(file os.FileInfo) bool {
+	stat, ok := file.Sys().(*syscall.Stat_t)
+	return ok && stat.Uid == pluginOwnerUid && file.Mode().Perm()&0022 == 0
+}
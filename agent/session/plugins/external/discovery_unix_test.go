@@ -0,0 +1,56 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build darwin freebsd linux netbsd openbsd
+
+// Package external runs session plugins shipped as executables in the session plugins directory.
+package external
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscoverPlugins(t *testing.T) {
+	pluginOwnerUid = uint32(os.Getuid())
+	defer func() { pluginOwnerUid = 0 }()
+	directory, err := ioutil.TempDir("", "sessionplugins")
+	assert.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, "Database"), []byte{}, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, "README"), []byte{}, 0644))
+	assert.NoError(t, os.Chmod(filepath.Join(directory, "README"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, "Writable"), []byte{}, 0777))
+	assert.NoError(t, os.Chmod(filepath.Join(directory, "Writable"), 0777))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(directory, "GroupWritable"), []byte{}, 0775))
+	assert.NoError(t, os.Chmod(filepath.Join(directory, "GroupWritable"), 0775))
+	assert.NoError(t, os.Mkdir(filepath.Join(directory, "Folder"), 0755))
+
+	plugins := DiscoverPlugins(mockLog, directory)
+	assert.Equal(t, map[string]string{"Database": filepath.Join(directory, "Database")}, plugins)
+	assert.Empty(t, DiscoverPlugins(mockLog, filepath.Join(directory, "missing")))
+
+	// plugins of a directory writable by other users are ignored
+	assert.NoError(t, os.Chmod(directory, 0777))
+	assert.Empty(t, DiscoverPlugins(mockLog, directory))
+
+	// plugins not owned by root are ignored
+	assert.NoError(t, os.Chmod(directory, 0755))
+	pluginOwnerUid = uint32(os.Getuid()) + 1
+	assert.Empty(t, DiscoverPlugins(mockLog, directory))
+}
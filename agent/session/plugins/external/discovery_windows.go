@@ -0,0 +1,34 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build windows
+
+// Package external runs session plugins shipped as executables in the session plugins directory.
+package external
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// pluginName returns the name of the plugin served by the file, which has to be an executable
+func pluginName(file os.FileInfo) (string, bool) {
+	extension := filepath.Ext(file.Name())
+	return strings.TrimSuffix(file.Name(), extension), strings.EqualFold(extension, ".exe")
+}
+
+// trustedDirectory returns true as the plugins directory is under Program Files, which only Administrators can modify
+func trustedDirectory(directory string) bool {
+	return true
+}
@@ -0,0 +1,323 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package external runs session plugins shipped as executables in the session plugins directory.
+//
+// The agent starts the executable of the session type for every session and talks to it
+// over stdin and stdout using the protocol of the sdk package.
+package external
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	agentContracts "github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/session/datachannel"
+	"github.com/aws/amazon-ssm-agent/agent/session/plugins/external/sdk"
+	"github.com/aws/amazon-ssm-agent/agent/session/plugins/sessionplugin"
+	"github.com/aws/amazon-ssm-agent/agent/session/utility"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+// ExternalPlugin is the type for session plugins running in a separate process.
+type ExternalPlugin struct {
+	name        string
+	path        string
+	dataChannel datachannel.IDataChannel
+	mutex       sync.Mutex
+	encoder     *json.Encoder
+}
+
+// pluginProcess is a running external session plugin.
+type pluginProcess struct {
+	stdin  io.WriteCloser
+	stdout io.Reader
+	kill   func() error
+	wait   func() error
+}
+
+// startPluginProcess starts the executable of an external session plugin as the session user
+var startPluginProcess = func(log log.T, name string, path string, userName string, defaultUser bool) (*pluginProcess, error) {
+	cmd := exec.Command(path)
+	if err := setSessionUser(log, cmd, userName, defaultUser); err != nil {
+		return nil, err
+	}
+	cmd.Stderr = &logWriter{log: log, name: name}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &pluginProcess{
+		stdin:  stdin,
+		stdout: stdout,
+		kill:   cmd.Process.Kill,
+		wait:   cmd.Wait,
+	}, nil
+}
+
+// logWriter writes what an external session plugin logs to stderr to the agent log
+type logWriter struct {
+	log  log.T
+	name string
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.log.Infof("Session plugin %s: %s", w.name, strings.TrimSpace(string(p)))
+	return len(p), nil
+}
+
+// NewPluginFunc returns the constructor of the external session plugin with the given name and executable.
+func NewPluginFunc(name string, path string) sessionplugin.NewPluginFunc {
+	return func() (sessionplugin.ISessionPlugin, error) {
+		return &ExternalPlugin{name: name, path: path}, nil
+	}
+}
+
+// Returns parameters required for CLI to start session
+func (p *ExternalPlugin) GetPluginParameters(parameters interface{}) interface{} {
+	return parameters
+}
+
+// External plugins require handshake to establish session
+func (p *ExternalPlugin) RequireHandshake() bool {
+	return true
+}
+
+// Execute starts the external session plugin and relays the data of the session between the data channel and the plugin
+func (p *ExternalPlugin) Execute(context context.T,
+	config agentContracts.Configuration,
+	cancelFlag task.CancelFlag,
+	output iohandler.IOHandler,
+	dataChannel datachannel.IDataChannel) {
+
+	p.dataChannel = dataChannel
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+	} else {
+		p.execute(context, config, cancelFlag, output)
+	}
+}
+
+// execute runs the external session plugin until it exits or the session is cancelled
+func (p *ExternalPlugin) execute(context context.T,
+	config agentContracts.Configuration,
+	cancelFlag task.CancelFlag,
+	output iohandler.IOHandler) {
+
+	log := context.Log()
+
+	userName, defaultUser, err := sessionUser(config)
+	if err == nil {
+		err = utility.CheckRunAsPolicy(context.AppConfig().Mgs, userName)
+	}
+	if err != nil {
+		errorString := fmt.Errorf("Unable to start session plugin %s: %v", p.name, err)
+		log.Error(errorString)
+		output.MarkAsFailed(errorString)
+		return
+	}
+
+	process, err := startPluginProcess(log, p.name, p.path, userName, defaultUser)
+	if err != nil {
+		errorString := fmt.Errorf("Unable to start session plugin %s: %v", p.name, err)
+		log.Error(errorString)
+		output.MarkAsFailed(errorString)
+		return
+	}
+	defer func() {
+		process.stdin.Close()
+		if err := process.wait(); err != nil {
+			log.Debugf("Session plugin %s exited: %v", p.name, err)
+		}
+	}()
+
+	decoder := json.NewDecoder(process.stdout)
+	if err = p.handshake(config, process, decoder); err != nil {
+		process.kill()
+		errorString := fmt.Errorf("Handshake with session plugin %s failed: %v", p.name, err)
+		log.Error(errorString)
+		output.MarkAsFailed(errorString)
+		return
+	}
+
+	cancelled := make(chan bool, 1)
+	go func() {
+		cancelState := cancelFlag.Wait()
+		if cancelFlag.Canceled() {
+			cancelled <- true
+			log.Debug("Cancel flag set to cancelled in session")
+		}
+		log.Debugf("Cancel flag set to %v in session", cancelState)
+	}()
+
+	exited := make(chan sdk.Message, 1)
+	go func() {
+		exited <- p.readMessages(log, decoder)
+	}()
+
+	log.Infof("Plugin %s started", p.name)
+
+	select {
+	case <-cancelled:
+		if err = p.send(sdk.Message{Type: sdk.Close}); err != nil {
+			log.Debugf("Unable to close session plugin %s: %v", p.name, err)
+		}
+		select {
+		case <-exited:
+		case <-time.After(mgsConfig.ExternalSessionPluginStopTimeout):
+			log.Warnf("Session plugin %s did not exit after the session was closed", p.name)
+			process.kill()
+		}
+		output.SetExitCode(appconfig.SuccessExitCode)
+		output.SetStatus(agentContracts.ResultStatusSuccess)
+		log.Info("The session was cancelled")
+
+	case exit := <-exited:
+		if exit.ExitCode != 0 || exit.Error != "" {
+			output.SetExitCode(appconfig.ErrorExitCode)
+			output.SetStatus(agentContracts.ResultStatusFailed)
+			output.SetOutput(mgsContracts.SessionPluginResultOutput{Output: exit.Error})
+		} else {
+			output.SetExitCode(appconfig.SuccessExitCode)
+			output.SetStatus(agentContracts.ResultStatusSuccess)
+		}
+		if cancelFlag.Canceled() {
+			log.Errorf("The cancellation failed to stop the session.")
+		}
+	}
+
+	log.Debugf("Session plugin %s finished", p.name)
+}
+
+// handshake sends the session to the external session plugin and waits for it to be accepted
+func (p *ExternalPlugin) handshake(config agentContracts.Configuration, process *pluginProcess, decoder *json.Decoder) error {
+	p.mutex.Lock()
+	p.encoder = json.NewEncoder(process.stdin)
+	p.mutex.Unlock()
+
+	request := sdk.HandshakeRequest{
+		ProtocolVersion: sdk.ProtocolVersion,
+		SessionId:       config.SessionId,
+		SessionType:     p.name,
+		RunAsUser:       config.RunAsUser,
+		Properties:      config.Properties,
+	}
+	if err := p.send(sdk.Message{Type: sdk.Handshake, HandshakeRequest: &request}); err != nil {
+		return err
+	}
+
+	responses := make(chan error, 1)
+	go func() {
+		var message sdk.Message
+		if err := decoder.Decode(&message); err != nil {
+			responses <- err
+			return
+		}
+		if message.Type != sdk.Handshake || message.HandshakeResponse == nil {
+			responses <- fmt.Errorf("expected handshake but received %s message", message.Type)
+			return
+		}
+		if message.HandshakeResponse.Error != "" {
+			responses <- errors.New(message.HandshakeResponse.Error)
+			return
+		}
+		if majorVersion(message.HandshakeResponse.ProtocolVersion) != majorVersion(sdk.ProtocolVersion) {
+			responses <- fmt.Errorf("unsupported protocol version %s", message.HandshakeResponse.ProtocolVersion)
+			return
+		}
+		responses <- nil
+	}()
+
+	select {
+	case err := <-responses:
+		return err
+	case <-time.After(mgsConfig.ExternalSessionPluginHandshakeTimeout):
+		return errors.New("timed out waiting for handshake response")
+	}
+}
+
+// readMessages relays the data sent by the external session plugin to the data channel until the plugin exits
+func (p *ExternalPlugin) readMessages(log log.T, decoder *json.Decoder) sdk.Message {
+	for {
+		var message sdk.Message
+		if err := decoder.Decode(&message); err != nil {
+			return sdk.Message{
+				Type:     sdk.Exit,
+				ExitCode: appconfig.ErrorExitCode,
+				Error:    fmt.Sprintf("Session plugin %s exited unexpectedly: %v", p.name, err),
+			}
+		}
+		switch message.Type {
+		case sdk.Data:
+			if err := p.dataChannel.SendStreamDataMessage(log, message.PayloadType, message.Payload); err != nil {
+				log.Errorf("Unable to send stream data message: %v", err)
+			}
+		case sdk.Exit:
+			return message
+		default:
+			log.Warnf("Ignoring %s message from session plugin %s", message.Type, p.name)
+		}
+	}
+}
+
+// InputStreamMessageHandler passes the data received from the client to the external session plugin
+func (p *ExternalPlugin) InputStreamMessageHandler(log log.T, streamDataMessage mgsContracts.AgentMessage) error {
+	p.mutex.Lock()
+	started := p.encoder != nil
+	p.mutex.Unlock()
+	if !started {
+		// Packets are rejected until the plugin has been started, the client resends them
+		log.Tracef("Session plugin %s unavailable. Reject incoming message packet", p.name)
+		return nil
+	}
+
+	return p.send(sdk.Message{
+		Type:        sdk.Data,
+		PayloadType: mgsContracts.PayloadType(streamDataMessage.PayloadType),
+		Payload:     streamDataMessage.Payload,
+	})
+}
+
+// send writes a message to the stdin of the external session plugin
+func (p *ExternalPlugin) send(message sdk.Message) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.encoder.Encode(message)
+}
+
+// majorVersion returns the major version of a protocol version
+func majorVersion(version string) string {
+	return strings.SplitN(version, ".", 2)[0]
+}
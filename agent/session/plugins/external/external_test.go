@@ -0,0 +1,166 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package external runs session plugins shipped as executables in the session plugins directory.
+package external
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	agentContracts "github.com/aws/amazon-ssm-agent/agent/contracts"
+	iohandlermocks "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+	dataChannelMock "github.com/aws/amazon-ssm-agent/agent/session/datachannel/mocks"
+	"github.com/aws/amazon-ssm-agent/agent/session/plugins/external/sdk"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var mockLog = log.NewMockLog()
+
+// echoPlugin greets the client and echoes its input until it receives exit
+type echoPlugin struct {
+	handshakeErr error
+	input        chan []byte
+}
+
+func (p *echoPlugin) Handshake(request sdk.HandshakeRequest) error {
+	return p.handshakeErr
+}
+
+func (p *echoPlugin) Execute(dataChannel sdk.DataChannel, closed <-chan struct{}) (int, error) {
+	dataChannel.SendStreamDataMessage(mgsContracts.Output, []byte("hello"))
+	for {
+		select {
+		case input := <-p.input:
+			if string(input) == "exit" {
+				return 0, nil
+			}
+			dataChannel.SendStreamDataMessage(mgsContracts.Output, input)
+		case <-closed:
+			return 0, nil
+		}
+	}
+}
+
+func (p *echoPlugin) InputStreamMessageHandler(payloadType mgsContracts.PayloadType, payload []byte) error {
+	p.input <- payload
+	return nil
+}
+
+// serveInProcess runs the plugin in the test process instead of starting an executable
+func serveInProcess(plugin sdk.SessionPlugin) {
+	startPluginProcess = func(log log.T, name string, path string, userName string, defaultUser bool) (*pluginProcess, error) {
+		stdinReader, stdinWriter := io.Pipe()
+		stdoutReader, stdoutWriter := io.Pipe()
+		done := make(chan error, 1)
+		go func() {
+			done <- sdk.ServeIO(plugin, stdinReader, stdoutWriter)
+			stdoutWriter.Close()
+		}()
+		return &pluginProcess{
+			stdin:  stdinWriter,
+			stdout: stdoutReader,
+			kill:   stdoutWriter.Close,
+			wait:   func() error { return <-done },
+		}, nil
+	}
+}
+
+func TestExecuteRelaysData(t *testing.T) {
+	serveInProcess(&echoPlugin{input: make(chan []byte, 1)})
+
+	received := make(chan []byte, 2)
+	mockDataChannel := &dataChannelMock.IDataChannel{}
+	mockDataChannel.On("SendStreamDataMessage", mock.Anything, mgsContracts.Output, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		received <- args.Get(2).([]byte)
+	})
+	mockCancelFlag := &task.MockCancelFlag{}
+	mockCancelFlag.On("ShutDown").Return(false)
+	mockCancelFlag.On("Canceled").Return(false)
+	mockCancelFlag.On("Wait").Return(task.Completed)
+	mockIohandler := new(iohandlermocks.MockIOHandler)
+	mockIohandler.On("SetExitCode", 0).Return()
+	mockIohandler.On("SetStatus", agentContracts.ResultStatusSuccess).Return()
+
+	newPlugin, _ := NewPluginFunc("Echo", "echo")()
+	plugin := newPlugin.(*ExternalPlugin)
+	done := make(chan bool)
+	go func() {
+		plugin.Execute(context.NewMockDefault(), agentContracts.Configuration{SessionId: "session"}, mockCancelFlag, mockIohandler, mockDataChannel)
+		done <- true
+	}()
+
+	assert.Equal(t, []byte("hello"), <-received)
+	assert.NoError(t, plugin.InputStreamMessageHandler(mockLog, mgsContracts.AgentMessage{PayloadType: uint32(mgsContracts.Output), Payload: []byte("ls")}))
+	assert.Equal(t, []byte("ls"), <-received)
+	assert.NoError(t, plugin.InputStreamMessageHandler(mockLog, mgsContracts.AgentMessage{PayloadType: uint32(mgsContracts.Output), Payload: []byte("exit")}))
+	<-done
+
+	mockIohandler.AssertExpectations(t)
+}
+
+func TestExecuteHandshakeRejected(t *testing.T) {
+	serveInProcess(&echoPlugin{handshakeErr: errors.New("database unavailable")})
+
+	mockCancelFlag := &task.MockCancelFlag{}
+	mockCancelFlag.On("ShutDown").Return(false)
+	mockCancelFlag.On("Canceled").Return(false)
+	mockIohandler := new(iohandlermocks.MockIOHandler)
+	mockIohandler.On("MarkAsFailed", mock.Anything).Return()
+
+	newPlugin, _ := NewPluginFunc("Echo", "echo")()
+	newPlugin.Execute(context.NewMockDefault(), agentContracts.Configuration{}, mockCancelFlag, mockIohandler, &dataChannelMock.IDataChannel{})
+
+	mockIohandler.AssertExpectations(t)
+	err := mockIohandler.Calls[0].Arguments.Get(0).(error)
+	assert.Contains(t, err.Error(), "database unavailable")
+}
+
+func TestExecuteRunAsDenied(t *testing.T) {
+	started := false
+	startPluginProcess = func(log log.T, name string, path string, userName string, defaultUser bool) (*pluginProcess, error) {
+		started = true
+		return nil, errors.New("unexpected start")
+	}
+
+	config := appconfig.DefaultConfig()
+	config.Mgs.SessionRunAsDeniedUsers = []string{"admin"}
+	mockContext := new(context.Mock)
+	mockContext.On("AppConfig").Return(config)
+	mockContext.On("Log").Return(mockLog)
+	mockCancelFlag := &task.MockCancelFlag{}
+	mockCancelFlag.On("ShutDown").Return(false)
+	mockCancelFlag.On("Canceled").Return(false)
+	mockIohandler := new(iohandlermocks.MockIOHandler)
+	mockIohandler.On("MarkAsFailed", mock.Anything).Return()
+
+	newPlugin, _ := NewPluginFunc("Echo", "echo")()
+	newPlugin.Execute(mockContext, agentContracts.Configuration{RunAsEnabled: true, RunAsUser: "admin"}, mockCancelFlag, mockIohandler, &dataChannelMock.IDataChannel{})
+
+	mockIohandler.AssertExpectations(t)
+	assert.False(t, started)
+	err := mockIohandler.Calls[0].Arguments.Get(0).(error)
+	assert.Contains(t, err.Error(), "not permitted")
+}
+
+func TestInputRejectedBeforeStart(t *testing.T) {
+	newPlugin, _ := NewPluginFunc("Echo", "echo")()
+	assert.NoError(t, newPlugin.InputStreamMessageHandler(mockLog, mgsContracts.AgentMessage{Payload: []byte("ls")}))
+}
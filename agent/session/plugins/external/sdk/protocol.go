@@ -0,0 +1,68 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package sdk is used to build session plugins that run outside of the agent.
+//
+// An external session plugin is an executable placed in the session plugins directory of the agent.
+// The agent starts it for every session of the session type matching its file name and exchanges
+// json encoded Messages with it over stdin and stdout. The first message sent by the agent is a
+// handshake carrying the session properties, which the plugin answers before any data is exchanged.
+package sdk
+
+import (
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+)
+
+// ProtocolVersion is the version of the protocol spoken between the agent and external session plugins.
+const ProtocolVersion = "1.0"
+
+// MessageType is the type of a message exchanged between the agent and an external session plugin.
+type MessageType string
+
+const (
+	// Handshake is sent by the agent when the plugin is started and answered by the plugin.
+	Handshake MessageType = "handshake"
+	// Data carries a stream data payload, either from the client to the plugin or from the plugin to the client.
+	Data MessageType = "data"
+	// Close is sent by the agent when the session is terminated.
+	Close MessageType = "close"
+	// Exit is the last message sent by the plugin before it exits.
+	Exit MessageType = "exit"
+)
+
+// HandshakeRequest is sent by the agent to an external session plugin once the session is established.
+type HandshakeRequest struct {
+	ProtocolVersion string      `json:"protocolVersion"`
+	SessionId       string      `json:"sessionId"`
+	SessionType     string      `json:"sessionType"`
+	RunAsUser       string      `json:"runAsUser,omitempty"`
+	Properties      interface{} `json:"properties,omitempty"`
+}
+
+// HandshakeResponse is returned by an external session plugin to accept or reject a session.
+type HandshakeResponse struct {
+	ProtocolVersion string `json:"protocolVersion"`
+	Error           string `json:"error,omitempty"`
+}
+
+// Message is exchanged between the agent and an external session plugin, one json object per line.
+// Payload is base64 encoded in the json message.
+type Message struct {
+	Type              MessageType              `json:"type"`
+	HandshakeRequest  *HandshakeRequest        `json:"handshakeRequest,omitempty"`
+	HandshakeResponse *HandshakeResponse       `json:"handshakeResponse,omitempty"`
+	PayloadType       mgsContracts.PayloadType `json:"payloadType,omitempty"`
+	Payload           []byte                   `json:"payload,omitempty"`
+	ExitCode          int                      `json:"exitCode,omitempty"`
+	Error             string                   `json:"error,omitempty"`
+}
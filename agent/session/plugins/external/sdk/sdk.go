@@ -0,0 +1,118 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package sdk is used to build session plugins that run outside of the agent.
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+)
+
+// DataChannel sends data to the session manager client of the session.
+type DataChannel interface {
+	SendStreamDataMessage(payloadType mgsContracts.PayloadType, payload []byte) error
+}
+
+// SessionPlugin is implemented by external session plugins.
+type SessionPlugin interface {
+	// Handshake accepts the session, an error rejects it.
+	Handshake(request HandshakeRequest) error
+	// Execute runs the session until it ends or closed is signaled, and returns the exit code of the session.
+	Execute(dataChannel DataChannel, closed <-chan struct{}) (exitCode int, err error)
+	// InputStreamMessageHandler handles the data received from the client.
+	InputStreamMessageHandler(payloadType mgsContracts.PayloadType, payload []byte) error
+}
+
+// Serve runs an external session plugin over the stdin and stdout of the process.
+// Anything a plugin logs must be written to stderr.
+func Serve(plugin SessionPlugin) error {
+	return ServeIO(plugin, os.Stdin, os.Stdout)
+}
+
+// ServeIO runs an external session plugin over the given reader and writer.
+func ServeIO(plugin SessionPlugin, in io.Reader, out io.Writer) error {
+	channel := &stdioDataChannel{encoder: json.NewEncoder(out)}
+	decoder := json.NewDecoder(in)
+
+	var message Message
+	if err := decoder.Decode(&message); err != nil {
+		return fmt.Errorf("Unable to read handshake: %v", err)
+	}
+	if message.Type != Handshake || message.HandshakeRequest == nil {
+		return fmt.Errorf("Expected handshake but received %s message", message.Type)
+	}
+	response := HandshakeResponse{ProtocolVersion: ProtocolVersion}
+	err := plugin.Handshake(*message.HandshakeRequest)
+	if err != nil {
+		response.Error = err.Error()
+	}
+	if sendErr := channel.send(Message{Type: Handshake, HandshakeResponse: &response}); sendErr != nil {
+		return sendErr
+	}
+	if err != nil {
+		return err
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			var message Message
+			if err := decoder.Decode(&message); err != nil {
+				return
+			}
+			switch message.Type {
+			case Data:
+				if err := plugin.InputStreamMessageHandler(message.PayloadType, message.Payload); err != nil {
+					fmt.Fprintf(os.Stderr, "Unable to handle input: %v\n", err)
+				}
+			case Close:
+				return
+			}
+		}
+	}()
+
+	exitCode, err := plugin.Execute(channel, closed)
+	exit := Message{Type: Exit, ExitCode: exitCode}
+	if err != nil {
+		exit.Error = err.Error()
+	}
+	return channel.send(exit)
+}
+
+// stdioDataChannel sends the messages of the plugin to the agent.
+type stdioDataChannel struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+}
+
+// SendStreamDataMessage sends data to the client through the agent.
+func (c *stdioDataChannel) SendStreamDataMessage(payloadType mgsContracts.PayloadType, payload []byte) error {
+	if len(payload) == 0 {
+		return errors.New("Payload is empty")
+	}
+	return c.send(Message{Type: Data, PayloadType: payloadType, Payload: payload})
+}
+
+func (c *stdioDataChannel) send(message Message) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.encoder.Encode(message)
+}
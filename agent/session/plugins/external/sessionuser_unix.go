@@ -0,0 +1,86 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+//
+// +build darwin freebsd linux netbsd openbsd
+
+// Package external runs session plugins shipped as executables in the session plugins directory.
+package external
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	agentContracts "github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/session/utility"
+)
+
+// sessionUser returns the user the plugin of the session runs as, which is the RunAs user or ssm-user
+func sessionUser(config agentContracts.Configuration) (userName string, defaultUser bool, err error) {
+	if !config.RunAsEnabled {
+		return appconfig.DefaultRunAsUserName, true, nil
+	}
+	if userName = strings.TrimSpace(config.RunAsUser); userName == "" {
+		return "", false, errors.New("please set the RunAs default user")
+	}
+	return userName, false, nil
+}
+
+// setSessionUser makes the command run with the uid, gid and groups of the user, creating ssm-user if it does not exist yet.
+// An agent that does not run as root cannot switch identity and runs the plugin as itself.
+func setSessionUser(log log.T, cmd *exec.Cmd, userName string, defaultUser bool) error {
+	if os.Geteuid() != 0 {
+		log.Debugf("Session plugin runs with the identity of the agent instead of user %s", userName)
+		return nil
+	}
+	if defaultUser {
+		u := &utility.SessionUtil{}
+		u.CreateLocalAdminUser(log)
+	}
+
+	sessionUser, err := user.Lookup(userName)
+	if err != nil {
+		return fmt.Errorf("RunAs user %s does not exist. %v", userName, err)
+	}
+	uid, err := strconv.ParseUint(sessionUser.Uid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("Invalid uid of user %s. %v", userName, err)
+	}
+	gid, err := strconv.ParseUint(sessionUser.Gid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("Invalid gid of user %s. %v", userName, err)
+	}
+	groupIds, err := sessionUser.GroupIds()
+	if err != nil {
+		return fmt.Errorf("Unable to retrieve the groups of user %s. %v", userName, err)
+	}
+	groups := []uint32{}
+	for _, groupId := range groupIds {
+		if group, err := strconv.ParseUint(groupId, 10, 32); err == nil {
+			groups = append(groups, uint32(group))
+		}
+	}
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{}
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid), Groups: groups, NoSetGroups: false}
+	cmd.Env = append(os.Environ(), "HOME="+sessionUser.HomeDir)
+	return nil
+}
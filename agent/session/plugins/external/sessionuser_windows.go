@@ -0,0 +1,43 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+//
+// +build windows
+
+// Package external runs session plugins shipped as executables in the session plugins directory.
+package external
+
+import (
+	"errors"
+	"os/exec"
+
+	agentContracts "github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// elevatedUserName is the user session plugins run as on Windows
+const elevatedUserName = "SYSTEM"
+
+// sessionUser returns SYSTEM, which session plugins run as on this platform, so that the runAs policy is checked
+// against it. Sessions with RunAs enabled are refused as the plugin cannot be started as the RunAs user.
+func sessionUser(config agentContracts.Configuration) (userName string, defaultUser bool, err error) {
+	if config.RunAsEnabled {
+		return "", false, errors.New("session plugins cannot run as the RunAs user on this platform")
+	}
+	return elevatedUserName, false, nil
+}
+
+// setSessionUser leaves the command running with the identity of the agent
+func setSessionUser(log log.T, cmd *exec.Cmd, userName string, defaultUser bool) error {
+	return nil
+}
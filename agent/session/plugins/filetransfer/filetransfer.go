@@ -0,0 +1,539 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package filetransfer implements session manager's file transfer plugin.
+//
+// The client drives the transfer with json encoded FileTransferMessages sent as Output payloads.
+// A put uploads a file in chunks to a partial file that is moved into place once its checksum is verified,
+// so an interrupted upload is resumed from the size of the partial file.
+// A get downloads a file in chunks starting at the requested offset.
+//
+// Files are transferred with the identity of the session user, within a root directory that must be one of the
+// FileTransferAllowedRootDirectories configured in appconfig or the home directory of the session user.
+// Uploads are limited to the FileTransferMaxFileSizeMB configured in appconfig.
+package filetransfer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/context"
+	agentContracts "github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/session/datachannel"
+	"github.com/aws/amazon-ssm-agent/agent/session/plugins/sessionplugin"
+	"github.com/aws/amazon-ssm-agent/agent/session/utility"
+	"github.com/aws/amazon-ssm-agent/agent/task"
+)
+
+// Actions of file transfer messages
+const (
+	// sent by the client
+	ActionPut      = "put"
+	ActionGet      = "get"
+	ActionData     = "data"
+	ActionComplete = "complete"
+	ActionClose    = "close"
+
+	// sent by the agent, in addition to data
+	ActionReady = "ready"
+	ActionAck   = "ack"
+	ActionDone  = "done"
+	ActionError = "error"
+)
+
+// FileTransferParameters contains inputs required to execute file transfer plugin.
+type FileTransferParameters struct {
+	RootDirectory string `json:"rootDirectory" yaml:"rootDirectory"`
+}
+
+// FileTransferMessage is exchanged between the client and the agent to transfer a file.
+// Data is base64 encoded in the json message.
+type FileTransferMessage struct {
+	Action   string `json:"action"`
+	Path     string `json:"path,omitempty"`
+	Offset   int64  `json:"offset"`
+	Size     int64  `json:"size,omitempty"`
+	Checksum string `json:"checksum,omitempty"`
+	Data     []byte `json:"data,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+// FileTransferPlugin is the type for the file transfer plugin.
+type FileTransferPlugin struct {
+	mutex         sync.Mutex
+	dataChannel   datachannel.IDataChannel
+	rootDirectory string
+	homeDirectory string
+	// maxFileSize is the largest file accepted by uploads in bytes, zero means unlimited
+	maxFileSize int64
+	// runFileOperations runs file operations with the identity of the session user
+	runFileOperations func(operations func() error) error
+	// upload in progress
+	putFile     *os.File
+	putPath     string
+	putOffset   int64
+	putChecksum string
+	closed      chan bool
+}
+
+// Returns parameters required for CLI to start session
+func (p *FileTransferPlugin) GetPluginParameters(parameters interface{}) interface{} {
+	return parameters
+}
+
+// File transfer plugin requires handshake to establish session
+func (p *FileTransferPlugin) RequireHandshake() bool {
+	return true
+}
+
+// NewPlugin returns a new instance of the File Transfer Plugin.
+func NewPlugin() (sessionplugin.ISessionPlugin, error) {
+	var plugin = FileTransferPlugin{
+		closed:            make(chan bool, 1),
+		runFileOperations: sessionUserNotInitialized,
+	}
+	return &plugin, nil
+}
+
+// name returns the name of File Transfer Plugin
+func (p *FileTransferPlugin) name() string {
+	return appconfig.PluginNameFileTransfer
+}
+
+// Execute serves file transfer requests received over the data channel until the client closes the session
+func (p *FileTransferPlugin) Execute(context context.T,
+	config agentContracts.Configuration,
+	cancelFlag task.CancelFlag,
+	output iohandler.IOHandler,
+	dataChannel datachannel.IDataChannel) {
+
+	p.dataChannel = dataChannel
+	p.maxFileSize = int64(context.AppConfig().Mgs.FileTransferMaxFileSizeMB) * 1024 * 1024
+
+	if cancelFlag.ShutDown() {
+		output.MarkAsShutdown()
+	} else if cancelFlag.Canceled() {
+		output.MarkAsCancelled()
+	} else {
+		p.execute(context, config, cancelFlag, output)
+	}
+}
+
+// execute serves file transfer requests received over the data channel until the client closes the session
+func (p *FileTransferPlugin) execute(context context.T,
+	config agentContracts.Configuration,
+	cancelFlag task.CancelFlag,
+	output iohandler.IOHandler) {
+
+	log := context.Log()
+	sessionPluginResultOutput := mgsContracts.SessionPluginResultOutput{}
+
+	err := p.initializeSessionUser(log, context.AppConfig().Mgs, config)
+	if err == nil {
+		err = p.initializeParameters(config.Properties, context.AppConfig().Mgs.FileTransferAllowedRootDirectories)
+	}
+	if err != nil {
+		log.Error(err)
+		output.SetExitCode(appconfig.ErrorExitCode)
+		output.SetStatus(agentContracts.ResultStatusFailed)
+		sessionPluginResultOutput.Output = err.Error()
+		output.SetOutput(sessionPluginResultOutput)
+		return
+	}
+	defer p.closePutFile(log)
+
+	cancelled := make(chan bool, 1)
+	go func() {
+		cancelState := cancelFlag.Wait()
+		if cancelFlag.Canceled() {
+			cancelled <- true
+			log.Debug("Cancel flag set to cancelled in session")
+		}
+		log.Debugf("Cancel flag set to %v in session", cancelState)
+	}()
+
+	log.Infof("Plugin %s started", p.name())
+
+	select {
+	case <-cancelled:
+		log.Info("The session was cancelled")
+	case <-p.closed:
+		log.Info("The session was closed by the client")
+	}
+	output.SetExitCode(appconfig.SuccessExitCode)
+	output.SetStatus(agentContracts.ResultStatusSuccess)
+
+	log.Debug("File transfer session execution complete")
+}
+
+// sessionUserNotInitialized refuses file operations until the session user is known
+func sessionUserNotInitialized(operations func() error) error {
+	return errors.New("The session user is not initialized")
+}
+
+// initializeSessionUser checks the runAs policy against the user files are transferred as, which is the RunAs user
+// or ssm-user, and prepares file operations to run with the identity of the user
+func (p *FileTransferPlugin) initializeSessionUser(log log.T, mgsConfig appconfig.MgsConfig, config agentContracts.Configuration) (err error) {
+	userName := appconfig.DefaultRunAsUserName
+	if config.RunAsEnabled {
+		if userName = strings.TrimSpace(config.RunAsUser); userName == "" {
+			return errors.New("please set the RunAs default user")
+		}
+	}
+	if err = utility.CheckRunAsPolicy(mgsConfig, userName); err != nil {
+		return err
+	}
+	p.runFileOperations, p.homeDirectory, err = sessionUserFileOperations(log, userName, !config.RunAsEnabled)
+	return err
+}
+
+// initializeParameters reads the root directory files are transferred within, which must be within one of the
+// allowed root directories, or within the home directory of the session user when none are allowed
+func (p *FileTransferPlugin) initializeParameters(parameters interface{}, allowedRootDirectories []string) (err error) {
+	var fileTransferParameters FileTransferParameters
+	if err = jsonutil.Remarshal(parameters, &fileTransferParameters); err != nil {
+		return fmt.Errorf("Unable to remarshal session properties. %v", err)
+	}
+	if strings.TrimSpace(fileTransferParameters.RootDirectory) == "" {
+		return errors.New("Root directory is empty in session properties")
+	}
+
+	var rootDirectory string
+	if err = p.runFileOperations(func() (err error) {
+		if rootDirectory, err = filepath.Abs(fileTransferParameters.RootDirectory); err == nil {
+			rootDirectory, err = filepath.EvalSymlinks(rootDirectory)
+		}
+		return err
+	}); err != nil {
+		return fmt.Errorf("Invalid root directory %s. %v", fileTransferParameters.RootDirectory, err)
+	}
+
+	allowed := false
+	for _, allowedRootDirectory := range allowedRootDirectories {
+		if allowedRootDirectory, err = filepath.EvalSymlinks(strings.TrimSpace(allowedRootDirectory)); err == nil && withinDirectory(allowedRootDirectory, rootDirectory) {
+			allowed = true
+		}
+	}
+	if len(allowedRootDirectories) == 0 && p.homeDirectory != "" {
+		if homeDirectory, err := filepath.EvalSymlinks(p.homeDirectory); err == nil {
+			allowed = withinDirectory(homeDirectory, rootDirectory)
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("Root directory %s is not permitted, file transfer sessions are limited to the FileTransferAllowedRootDirectories configured in appconfig or the home directory of the session user",
+			fileTransferParameters.RootDirectory)
+	}
+	p.rootDirectory = rootDirectory
+	return nil
+}
+
+// InputStreamMessageHandler processes the file transfer messages sent by the client
+func (p *FileTransferPlugin) InputStreamMessageHandler(log log.T, streamDataMessage mgsContracts.AgentMessage) error {
+	if p.rootDirectory == "" {
+		// This is to handle scenario when cli starts sending data but the plugin has not been started yet
+		// Since packets are rejected, cli will resend these packets until the plugin starts successfully
+		log.Tracef("File transfer unavailable. Reject incoming message packet")
+		return nil
+	}
+	if mgsContracts.PayloadType(streamDataMessage.PayloadType) != mgsContracts.Output {
+		return nil
+	}
+
+	var message FileTransferMessage
+	if err := json.Unmarshal(streamDataMessage.Payload, &message); err != nil {
+		return p.sendError(log, fmt.Errorf("Invalid file transfer message. %v", err))
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var err error
+	switch message.Action {
+	case ActionPut:
+		err = p.runFileOperations(func() error { return p.startPut(log, message) })
+	case ActionData:
+		err = p.writePutData(log, message)
+	case ActionComplete:
+		err = p.runFileOperations(func() error { return p.completePut(log) })
+	case ActionGet:
+		err = p.runFileOperations(func() error { return p.startGet(log, message) })
+	case ActionClose:
+		select {
+		case p.closed <- true:
+		default:
+		}
+	default:
+		err = fmt.Errorf("Unsupported file transfer action %s", message.Action)
+	}
+	if err != nil {
+		return p.sendError(log, err)
+	}
+	return nil
+}
+
+// resolvePath returns the path of the file within the root directory with symbolic links resolved, so that
+// a link cannot point outside of the root directory. The file of an upload may not exist yet, in which case
+// its directory is resolved instead.
+func (p *FileTransferPlugin) resolvePath(path string, upload bool) (string, error) {
+	if strings.TrimSpace(path) == "" {
+		return "", errors.New("File path is empty")
+	}
+	absPath := filepath.Join(p.rootDirectory, filepath.FromSlash(path))
+	if filepath.IsAbs(path) {
+		absPath = filepath.Clean(path)
+	}
+	if !withinDirectory(p.rootDirectory, absPath) {
+		return "", fmt.Errorf("File %s is outside of the root directory", path)
+	}
+
+	realPath, err := filepath.EvalSymlinks(absPath)
+	if err != nil && upload && os.IsNotExist(err) {
+		var realDirectory string
+		if realDirectory, err = filepath.EvalSymlinks(filepath.Dir(absPath)); err == nil {
+			realPath = filepath.Join(realDirectory, filepath.Base(absPath))
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	if !withinDirectory(p.rootDirectory, realPath) {
+		return "", fmt.Errorf("File %s is outside of the root directory", path)
+	}
+	return realPath, nil
+}
+
+// withinDirectory returns true if the path is the directory or is within it
+func withinDirectory(directory string, path string) bool {
+	relPath, err := filepath.Rel(directory, path)
+	return err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
+
+// startPut opens the partial file of the upload and tells the client the offset to resume from
+func (p *FileTransferPlugin) startPut(log log.T, message FileTransferMessage) (err error) {
+	p.closePutFile(log)
+
+	if p.putPath, err = p.resolvePath(message.Path, true); err != nil {
+		return err
+	}
+	if message.Checksum == "" {
+		return errors.New("Checksum of the uploaded file is required")
+	}
+	if p.exceedsMaxFileSize(message.Size) {
+		return fmt.Errorf("Size %d of %s exceeds the maximum file size of %d bytes", message.Size, message.Path, p.maxFileSize)
+	}
+	partialPath := p.putPath + mgsConfig.FileTransferPartialFileExtension
+	if fileInfo, err := os.Lstat(partialPath); err == nil && fileInfo.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("Partial file of %s is a symbolic link", message.Path)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY
+	if message.Offset == 0 {
+		flags |= os.O_TRUNC
+	}
+	if p.putFile, err = os.OpenFile(partialPath, flags, appconfig.ReadWriteAccess); err != nil {
+		return err
+	}
+	if p.putOffset, err = p.putFile.Seek(0, io.SeekEnd); err != nil {
+		p.closePutFile(log)
+		return err
+	}
+	p.putChecksum = strings.ToLower(message.Checksum)
+
+	log.Debugf("Uploading %s from offset %d", p.putPath, p.putOffset)
+	return p.sendMessage(log, FileTransferMessage{Action: ActionReady, Path: message.Path, Offset: p.putOffset})
+}
+
+// writePutData appends the chunk to the partial file of the upload
+func (p *FileTransferPlugin) writePutData(log log.T, message FileTransferMessage) error {
+	if p.putFile == nil {
+		return errors.New("No upload in progress")
+	}
+	if message.Offset != p.putOffset {
+		return fmt.Errorf("Unexpected offset %d, expected %d", message.Offset, p.putOffset)
+	}
+	if p.exceedsMaxFileSize(p.putOffset + int64(len(message.Data))) {
+		partialPath := p.putFile.Name()
+		p.closePutFile(log)
+		os.Remove(partialPath)
+		return fmt.Errorf("Upload of %s exceeds the maximum file size of %d bytes", p.putPath, p.maxFileSize)
+	}
+	n, err := p.putFile.Write(message.Data)
+	p.putOffset += int64(n)
+	if err != nil {
+		return err
+	}
+	return p.sendMessage(log, FileTransferMessage{Action: ActionAck, Offset: p.putOffset})
+}
+
+// completePut verifies the checksum of the uploaded file and moves it into place
+func (p *FileTransferPlugin) completePut(log log.T) (err error) {
+	if p.putFile == nil {
+		return errors.New("No upload in progress")
+	}
+	partialPath := p.putFile.Name()
+	p.closePutFile(log)
+
+	checksum, err := fileChecksum(partialPath)
+	if err != nil {
+		return err
+	}
+	if checksum != p.putChecksum {
+		os.Remove(partialPath)
+		return fmt.Errorf("Checksum mismatch, expected %s but received %s", p.putChecksum, checksum)
+	}
+	if err = os.Rename(partialPath, p.putPath); err != nil {
+		return err
+	}
+
+	log.Infof("Uploaded %s", p.putPath)
+	return p.sendMessage(log, FileTransferMessage{Action: ActionDone, Offset: p.putOffset, Checksum: checksum})
+}
+
+// exceedsMaxFileSize returns true if a file of the size may not be uploaded
+func (p *FileTransferPlugin) exceedsMaxFileSize(size int64) bool {
+	return p.maxFileSize > 0 && size > p.maxFileSize
+}
+
+// closePutFile closes the partial file of the upload in progress
+func (p *FileTransferPlugin) closePutFile(log log.T) {
+	if p.putFile == nil {
+		return
+	}
+	if err := p.putFile.Close(); err != nil {
+		log.Debugf("Unable to close %s. %v", p.putFile.Name(), err)
+	}
+	p.putFile = nil
+}
+
+// startGet tells the client the size and checksum of the file and starts sending it from the requested offset.
+// The file is opened here, with the identity of the session user, and read by sendFile.
+func (p *FileTransferPlugin) startGet(log log.T, message FileTransferMessage) error {
+	path, err := p.resolvePath(message.Path, false)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	fileInfo, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if fileInfo.IsDir() {
+		file.Close()
+		return fmt.Errorf("%s is a directory", message.Path)
+	}
+	if message.Offset < 0 || message.Offset > fileInfo.Size() {
+		file.Close()
+		return fmt.Errorf("Offset %d is beyond the size of the file", message.Offset)
+	}
+	checksum, err := readChecksum(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	if err = p.sendMessage(log, FileTransferMessage{Action: ActionReady, Path: message.Path, Offset: message.Offset, Size: fileInfo.Size(), Checksum: checksum}); err != nil {
+		file.Close()
+		return err
+	}
+	go p.sendFile(log, file, message.Offset, checksum)
+	return nil
+}
+
+// sendFile sends the file in chunks starting at the offset and closes it
+func (p *FileTransferPlugin) sendFile(log log.T, file *os.File, offset int64, checksum string) {
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		p.sendError(log, err)
+		return
+	}
+
+	chunk := make([]byte, mgsConfig.FileTransferChunkSize)
+	for {
+		// Stop reading the file while the client has not acknowledged enough of the data sent
+		p.dataChannel.WaitForSendWindow()
+		n, err := file.Read(chunk)
+		if n > 0 {
+			if err := p.sendMessage(log, FileTransferMessage{Action: ActionData, Offset: offset, Data: chunk[:n]}); err != nil {
+				return
+			}
+			offset += int64(n)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			p.sendError(log, err)
+			return
+		}
+	}
+
+	log.Infof("Downloaded %s", file.Name())
+	p.sendMessage(log, FileTransferMessage{Action: ActionDone, Offset: offset, Checksum: checksum})
+}
+
+// sendError reports the error to the client
+func (p *FileTransferPlugin) sendError(log log.T, err error) error {
+	log.Error(err)
+	return p.sendMessage(log, FileTransferMessage{Action: ActionError, Message: err.Error()})
+}
+
+// sendMessage sends the file transfer message to the client
+func (p *FileTransferPlugin) sendMessage(log log.T, message FileTransferMessage) error {
+	messageBytes, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("Unable to marshal file transfer message. %v", err)
+	}
+	if err = p.dataChannel.SendStreamDataMessage(log, mgsContracts.Output, messageBytes); err != nil {
+		log.Errorf("Unable to send stream data message: %v", err)
+		return err
+	}
+	return nil
+}
+
+// fileChecksum returns the hex encoded sha256 checksum of the file
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	return readChecksum(file)
+}
+
+// readChecksum returns the hex encoded sha256 checksum of the content of the file read from its current offset
+func readChecksum(file io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
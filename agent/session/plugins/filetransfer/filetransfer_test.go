@@ -0,0 +1,328 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package filetransfer implements session manager's file transfer plugin.
+package filetransfer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+	dataChannelMock "github.com/aws/amazon-ssm-agent/agent/session/datachannel/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var mockLog = log.NewMockLog()
+
+// sentMessages collects the file transfer messages sent by the plugin
+type sentMessages struct {
+	mutex    sync.Mutex
+	messages []FileTransferMessage
+}
+
+func (s *sentMessages) get() []FileTransferMessage {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]FileTransferMessage{}, s.messages...)
+}
+
+func newTestPlugin(t *testing.T) (plugin *FileTransferPlugin, sent *sentMessages, rootDirectory string) {
+	rootDirectory, err := ioutil.TempDir("", "filetransfer")
+	assert.NoError(t, err)
+
+	sent = &sentMessages{}
+	mockDataChannel := &dataChannelMock.IDataChannel{}
+	mockDataChannel.On("SendStreamDataMessage", mock.Anything, mgsContracts.Output, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		var message FileTransferMessage
+		json.Unmarshal(args.Get(2).([]byte), &message)
+		sent.mutex.Lock()
+		sent.messages = append(sent.messages, message)
+		sent.mutex.Unlock()
+	})
+	mockDataChannel.On("WaitForSendWindow").Return()
+
+	newPlugin, _ := NewPlugin()
+	plugin = newPlugin.(*FileTransferPlugin)
+	plugin.dataChannel = mockDataChannel
+	plugin.runFileOperations = func(operations func() error) error { return operations() }
+	assert.NoError(t, plugin.initializeParameters(map[string]interface{}{"rootDirectory": rootDirectory}, []string{rootDirectory}))
+	return plugin, sent, rootDirectory
+}
+
+// assertOutsideRootDirectory asserts that the only message sent is the error of a file outside of the root directory
+func assertOutsideRootDirectory(t *testing.T, sent *sentMessages) {
+	messages := sent.get()
+	assert.Equal(t, 1, len(messages))
+	assert.Equal(t, ActionError, messages[0].Action)
+	assert.True(t, strings.Contains(messages[0].Message, "outside of the root directory"))
+}
+
+func sendMessage(t *testing.T, plugin *FileTransferPlugin, message FileTransferMessage) {
+	payload, err := json.Marshal(message)
+	assert.NoError(t, err)
+	agentMessage := mgsContracts.AgentMessage{PayloadType: uint32(mgsContracts.Output), Payload: payload}
+	assert.NoError(t, plugin.InputStreamMessageHandler(mockLog, agentMessage))
+}
+
+func checksum(content []byte) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+func TestPutResumesAndVerifiesChecksum(t *testing.T) {
+	plugin, sent, rootDirectory := newTestPlugin(t)
+	defer os.RemoveAll(rootDirectory)
+	content := []byte("file transferred over the data channel")
+
+	// an earlier interrupted upload left part of the file
+	partialPath := filepath.Join(rootDirectory, "file.txt"+mgsConfig.FileTransferPartialFileExtension)
+	assert.NoError(t, ioutil.WriteFile(partialPath, content[:10], 0600))
+
+	sendMessage(t, plugin, FileTransferMessage{Action: ActionPut, Path: "file.txt", Offset: 10, Checksum: checksum(content)})
+	sendMessage(t, plugin, FileTransferMessage{Action: ActionData, Offset: 10, Data: content[10:]})
+	sendMessage(t, plugin, FileTransferMessage{Action: ActionComplete})
+
+	messages := sent.get()
+	assert.Equal(t, 3, len(messages))
+	assert.Equal(t, FileTransferMessage{Action: ActionReady, Path: "file.txt", Offset: 10}, messages[0])
+	assert.Equal(t, ActionAck, messages[1].Action)
+	assert.Equal(t, ActionDone, messages[2].Action)
+
+	uploaded, err := ioutil.ReadFile(filepath.Join(rootDirectory, "file.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, content, uploaded)
+	_, err = os.Stat(partialPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPutChecksumMismatch(t *testing.T) {
+	plugin, sent, rootDirectory := newTestPlugin(t)
+	defer os.RemoveAll(rootDirectory)
+
+	sendMessage(t, plugin, FileTransferMessage{Action: ActionPut, Path: "file.txt", Checksum: checksum([]byte("expected"))})
+	sendMessage(t, plugin, FileTransferMessage{Action: ActionData, Data: []byte("corrupted")})
+	sendMessage(t, plugin, FileTransferMessage{Action: ActionComplete})
+
+	messages := sent.get()
+	assert.Equal(t, ActionError, messages[len(messages)-1].Action)
+	_, err := os.Stat(filepath.Join(rootDirectory, "file.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPutExceedingMaxFileSize(t *testing.T) {
+	plugin, sent, rootDirectory := newTestPlugin(t)
+	defer os.RemoveAll(rootDirectory)
+	plugin.maxFileSize = 10
+	content := []byte("larger than the maximum file size")
+
+	sendMessage(t, plugin, FileTransferMessage{Action: ActionPut, Path: "file.txt", Size: int64(len(content)), Checksum: checksum(content)})
+	messages := sent.get()
+	assert.Equal(t, 1, len(messages))
+	assert.Equal(t, ActionError, messages[0].Action)
+	assert.True(t, strings.Contains(messages[0].Message, "exceeds the maximum file size"))
+
+	// the client may not announce the size, so the data received is limited as well
+	sendMessage(t, plugin, FileTransferMessage{Action: ActionPut, Path: "file.txt", Checksum: checksum(content)})
+	sendMessage(t, plugin, FileTransferMessage{Action: ActionData, Data: content[:10]})
+	sendMessage(t, plugin, FileTransferMessage{Action: ActionData, Offset: 10, Data: content[10:]})
+	messages = sent.get()
+	assert.Equal(t, 4, len(messages))
+	assert.Equal(t, ActionAck, messages[2].Action)
+	assert.Equal(t, ActionError, messages[3].Action)
+	assert.True(t, strings.Contains(messages[3].Message, "exceeds the maximum file size"))
+	assert.Nil(t, plugin.putFile)
+	_, err := os.Stat(filepath.Join(rootDirectory, "file.txt"+mgsConfig.FileTransferPartialFileExtension))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPutOutsideRootDirectory(t *testing.T) {
+	plugin, sent, rootDirectory := newTestPlugin(t)
+	defer os.RemoveAll(rootDirectory)
+
+	sendMessage(t, plugin, FileTransferMessage{Action: ActionPut, Path: "../escaped.txt", Checksum: checksum(nil)})
+
+	assertOutsideRootDirectory(t, sent)
+}
+
+func TestGetAbsolutePathOutsideRootDirectory(t *testing.T) {
+	plugin, sent, rootDirectory := newTestPlugin(t)
+	defer os.RemoveAll(rootDirectory)
+	outsideDirectory, err := ioutil.TempDir("", "outside")
+	assert.NoError(t, err)
+	defer os.RemoveAll(outsideDirectory)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(outsideDirectory, "secret.txt"), []byte("secret"), 0600))
+
+	sendMessage(t, plugin, FileTransferMessage{Action: ActionGet, Path: filepath.Join(outsideDirectory, "secret.txt")})
+
+	assertOutsideRootDirectory(t, sent)
+}
+
+func TestGetSymlinkOutsideRootDirectory(t *testing.T) {
+	plugin, sent, rootDirectory := newTestPlugin(t)
+	defer os.RemoveAll(rootDirectory)
+	outsideDirectory, err := ioutil.TempDir("", "outside")
+	assert.NoError(t, err)
+	defer os.RemoveAll(outsideDirectory)
+	secretPath := filepath.Join(outsideDirectory, "secret.txt")
+	assert.NoError(t, ioutil.WriteFile(secretPath, []byte("secret"), 0600))
+	if err = os.Symlink(secretPath, filepath.Join(rootDirectory, "link.txt")); err != nil {
+		t.Skipf("Unable to create symbolic link. %v", err)
+	}
+
+	sendMessage(t, plugin, FileTransferMessage{Action: ActionGet, Path: "link.txt"})
+
+	assertOutsideRootDirectory(t, sent)
+}
+
+func TestPutThroughSymlinkedDirectoryOutsideRootDirectory(t *testing.T) {
+	plugin, sent, rootDirectory := newTestPlugin(t)
+	defer os.RemoveAll(rootDirectory)
+	outsideDirectory, err := ioutil.TempDir("", "outside")
+	assert.NoError(t, err)
+	defer os.RemoveAll(outsideDirectory)
+	if err = os.Symlink(outsideDirectory, filepath.Join(rootDirectory, "link")); err != nil {
+		t.Skipf("Unable to create symbolic link. %v", err)
+	}
+
+	sendMessage(t, plugin, FileTransferMessage{Action: ActionPut, Path: "link/escaped.txt", Checksum: checksum(nil)})
+
+	assertOutsideRootDirectory(t, sent)
+	files, err := ioutil.ReadDir(outsideDirectory)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestPutSymlinkedPartialFile(t *testing.T) {
+	plugin, sent, rootDirectory := newTestPlugin(t)
+	defer os.RemoveAll(rootDirectory)
+	outsideDirectory, err := ioutil.TempDir("", "outside")
+	assert.NoError(t, err)
+	defer os.RemoveAll(outsideDirectory)
+	partialPath := filepath.Join(rootDirectory, "file.txt"+mgsConfig.FileTransferPartialFileExtension)
+	if err = os.Symlink(filepath.Join(outsideDirectory, "target.txt"), partialPath); err != nil {
+		t.Skipf("Unable to create symbolic link. %v", err)
+	}
+
+	sendMessage(t, plugin, FileTransferMessage{Action: ActionPut, Path: "file.txt", Checksum: checksum(nil)})
+
+	messages := sent.get()
+	assert.Equal(t, 1, len(messages))
+	assert.Equal(t, ActionError, messages[0].Action)
+	_, err = os.Stat(filepath.Join(outsideDirectory, "target.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGetFromOffset(t *testing.T) {
+	plugin, sent, rootDirectory := newTestPlugin(t)
+	defer os.RemoveAll(rootDirectory)
+	content := []byte(strings.Repeat("0123456789", mgsConfig.FileTransferChunkSize/5))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(rootDirectory, "file.txt"), content, 0600))
+
+	sendMessage(t, plugin, FileTransferMessage{Action: ActionGet, Path: "file.txt", Offset: 5})
+
+	var messages []FileTransferMessage
+	for i := 0; i < 100; i++ {
+		if messages = sent.get(); len(messages) > 0 && messages[len(messages)-1].Action == ActionDone {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, ActionReady, messages[0].Action)
+	assert.Equal(t, int64(len(content)), messages[0].Size)
+	assert.Equal(t, checksum(content), messages[0].Checksum)
+
+	var received []byte
+	for _, message := range messages[1 : len(messages)-1] {
+		assert.Equal(t, ActionData, message.Action)
+		assert.Equal(t, int64(5+len(received)), message.Offset)
+		received = append(received, message.Data...)
+	}
+	assert.Equal(t, content[5:], received)
+	assert.Equal(t, ActionDone, messages[len(messages)-1].Action)
+	// the send window is waited for before every read, including the one reaching the end of the file
+	plugin.dataChannel.(*dataChannelMock.IDataChannel).AssertNumberOfCalls(t, "WaitForSendWindow", len(messages)-1)
+}
+
+func TestInitializeParametersWithoutRootDirectory(t *testing.T) {
+	plugin := &FileTransferPlugin{}
+	assert.Error(t, plugin.initializeParameters(map[string]interface{}{}, nil))
+}
+
+func TestInitializeParametersRootDirectoryNotAllowed(t *testing.T) {
+	allowedDirectory, err := ioutil.TempDir("", "allowed")
+	assert.NoError(t, err)
+	defer os.RemoveAll(allowedDirectory)
+	otherDirectory, err := ioutil.TempDir("", "other")
+	assert.NoError(t, err)
+	defer os.RemoveAll(otherDirectory)
+	assert.NoError(t, os.Mkdir(filepath.Join(allowedDirectory, "sub"), 0700))
+
+	newPlugin := func(homeDirectory string) *FileTransferPlugin {
+		plugin, _ := NewPlugin()
+		fileTransferPlugin := plugin.(*FileTransferPlugin)
+		fileTransferPlugin.homeDirectory = homeDirectory
+		fileTransferPlugin.runFileOperations = func(operations func() error) error { return operations() }
+		return fileTransferPlugin
+	}
+	parameters := func(rootDirectory string) map[string]interface{} {
+		return map[string]interface{}{"rootDirectory": rootDirectory}
+	}
+
+	// allowed root directories and their subdirectories
+	assert.NoError(t, newPlugin("").initializeParameters(parameters(filepath.Join(allowedDirectory, "sub")), []string{allowedDirectory}))
+	assert.Error(t, newPlugin("").initializeParameters(parameters(otherDirectory), []string{allowedDirectory}))
+	assert.Error(t, newPlugin("").initializeParameters(parameters(filepath.Join(allowedDirectory, "..")), []string{allowedDirectory}))
+
+	// the home directory of the session user when no root directories are allowed
+	assert.NoError(t, newPlugin(allowedDirectory).initializeParameters(parameters(allowedDirectory), nil))
+	assert.Error(t, newPlugin(allowedDirectory).initializeParameters(parameters(otherDirectory), nil))
+	assert.Error(t, newPlugin("").initializeParameters(parameters(allowedDirectory), nil))
+
+	// a root directory linking outside of the allowed root directories
+	if err = os.Symlink(otherDirectory, filepath.Join(allowedDirectory, "link")); err == nil {
+		plugin := newPlugin("")
+		assert.Error(t, plugin.initializeParameters(parameters(filepath.Join(allowedDirectory, "link")), []string{allowedDirectory}))
+		assert.Empty(t, plugin.rootDirectory)
+	}
+}
+
+func TestInitializeSessionUserDeniedByPolicy(t *testing.T) {
+	newPlugin, _ := NewPlugin()
+	plugin := newPlugin.(*FileTransferPlugin)
+	config := contracts.Configuration{RunAsEnabled: true, RunAsUser: "root"}
+
+	err := plugin.initializeSessionUser(mockLog, appconfig.MgsConfig{SessionRunAsDeniedUsers: []string{"root"}}, config)
+
+	assert.Error(t, err)
+	// file operations are refused until the session user is initialized
+	assert.Error(t, plugin.runFileOperations(func() error { return nil }))
+}
+
+func TestInitializeSessionUserWithoutRunAsUser(t *testing.T) {
+	plugin := &FileTransferPlugin{}
+	assert.Error(t, plugin.initializeSessionUser(mockLog, appconfig.MgsConfig{}, contracts.Configuration{RunAsEnabled: true}))
+}
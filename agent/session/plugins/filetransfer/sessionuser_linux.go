@@ -0,0 +1,87 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+// +build linux
+
+package filetransfer
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"runtime"
+	"strconv"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/aws/amazon-ssm-agent/agent/session/utility"
+	"golang.org/x/sys/unix"
+)
+
+// sessionUserFileOperations returns a function running file operations with the file system identity of the user,
+// and the home directory of the user. The default ssm-user is created if it does not exist yet.
+func sessionUserFileOperations(log log.T, userName string, defaultUser bool) (runFileOperations func(operations func() error) error, homeDirectory string, err error) {
+	if defaultUser {
+		u := &utility.SessionUtil{}
+		u.CreateLocalAdminUser(log)
+	}
+
+	var sessionUser *user.User
+	if sessionUser, err = user.Lookup(userName); err != nil {
+		return nil, "", fmt.Errorf("RunAs user %s does not exist. %v", userName, err)
+	}
+	var uid, gid int
+	if uid, err = strconv.Atoi(sessionUser.Uid); err != nil {
+		return nil, "", fmt.Errorf("Invalid uid of user %s. %v", userName, err)
+	}
+	if gid, err = strconv.Atoi(sessionUser.Gid); err != nil {
+		return nil, "", fmt.Errorf("Invalid gid of user %s. %v", userName, err)
+	}
+	var groupIds []string
+	if groupIds, err = sessionUser.GroupIds(); err != nil {
+		return nil, "", fmt.Errorf("Unable to retrieve the groups of user %s. %v", userName, err)
+	}
+	groups := []int{}
+	for _, groupId := range groupIds {
+		if group, err := strconv.Atoi(groupId); err == nil {
+			groups = append(groups, group)
+		}
+	}
+
+	runFileOperations = func(operations func() error) error {
+		return runAsUser(uid, gid, groups, operations)
+	}
+	return runFileOperations, sessionUser.HomeDir, nil
+}
+
+// runAsUser runs the operations on an OS thread whose file system uid, gid and supplementary groups are those
+// of the user, so that the file permissions of the user apply and created files are owned by the user.
+// The thread is never unlocked, so it exits with the goroutine instead of running other goroutines with the identity
+// of the user. An agent that does not run as root cannot switch identity and runs the operations as itself.
+func runAsUser(uid int, gid int, groups []int, operations func() error) error {
+	if os.Geteuid() != 0 {
+		return operations()
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		// setgroups, setfsgid and setfsuid are raw system calls which change the credentials of this thread only
+		if err := unix.Setgroups(groups); err != nil {
+			result <- fmt.Errorf("Unable to set the groups of the session user. %v", err)
+			return
+		}
+		unix.Setfsgid(gid)
+		unix.Setfsuid(uid)
+		result <- operations()
+	}()
+	return <-result
+}
@@ -0,0 +1,67 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// +build linux
+
+package filetransfer
+
+import (
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunAsUser(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Switching the file system identity requires root")
+	}
+	nobody, err := user.Lookup("nobody")
+	if err != nil {
+		t.Skip("User nobody does not exist")
+	}
+	uid, _ := strconv.Atoi(nobody.Uid)
+	gid, _ := strconv.Atoi(nobody.Gid)
+
+	directory, err := ioutil.TempDir("", "sessionuser")
+	assert.NoError(t, err)
+	defer os.RemoveAll(directory)
+	assert.NoError(t, os.Chmod(directory, 0777))
+	secretPath := filepath.Join(directory, "secret.txt")
+	assert.NoError(t, ioutil.WriteFile(secretPath, []byte("secret"), 0600))
+
+	// the operations are subject to the file permissions of the user
+	err = runAsUser(uid, gid, []int{}, func() error {
+		_, err := ioutil.ReadFile(secretPath)
+		return err
+	})
+	assert.True(t, os.IsPermission(err))
+
+	// created files are owned by the user
+	createdPath := filepath.Join(directory, "created.txt")
+	assert.NoError(t, runAsUser(uid, gid, []int{}, func() error {
+		return ioutil.WriteFile(createdPath, []byte("created"), 0600)
+	}))
+	fileInfo, err := os.Stat(createdPath)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(uid), fileInfo.Sys().(*syscall.Stat_t).Uid)
+
+	// the identity of the agent is unchanged
+	_, err = ioutil.ReadFile(secretPath)
+	assert.NoError(t, err)
+}
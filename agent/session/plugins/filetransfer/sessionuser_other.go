@@ -0,0 +1,33 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+// +build !linux
+
+package filetransfer
+
+import (
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// sessionUserFileOperations returns a function running file operations with the identity of the agent, as the
+// identity of a single thread cannot be switched to the user on this platform. No home directory is returned,
+// so file transfer sessions are refused unless their root directory is within the FileTransferAllowedRootDirectories
+// configured in appconfig.
+func sessionUserFileOperations(log log.T, userName string, defaultUser bool) (runFileOperations func(operations func() error) error, homeDirectory string, err error) {
+	log.Debugf("File operations run with the identity of the agent instead of user %s on this platform", userName)
+	return runAsAgent, "", nil
+}
+
+// runAsAgent runs the operations with the identity of the agent
+func runAsAgent(operations func() error) error {
+	return operations()
+}
@@ -0,0 +1,105 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package port implements session manager's port plugin
+package port
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher"
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/cloudwatchlogsinterface"
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/auditlog"
+	agentContracts "github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// auditLogFileName is the name of the local file port session audit records are appended to
+const auditLogFileName = "port_audit.log"
+
+var auditLogDir = log.DefaultLogDir
+
+// auditLogMaxSize and auditLogMaxRolls roll the audit log over like the agent log
+var auditLogMaxSize int64 = log.LogFileMaxSize
+var auditLogMaxRolls = log.LogFileMaxRolls
+
+var newCloudWatchLogsService = func() cloudwatchlogsinterface.ICloudWatchLogsService {
+	return cloudwatchlogspublisher.NewCloudWatchLogsService()
+}
+
+// portAuditRecord is the audit record written for every port session
+type portAuditRecord struct {
+	SessionId     string `json:"sessionId"`
+	ClientId      string `json:"clientId"`
+	RunAsUser     string `json:"runAsUser,omitempty"`
+	Host          string `json:"host"`
+	PortNumber    string `json:"portNumber"`
+	DevicePath    string `json:"devicePath,omitempty"`
+	PipePath      string `json:"pipePath,omitempty"`
+	Type          string `json:"type,omitempty"`
+	BytesSent     uint64 `json:"bytesSent"`
+	BytesReceived uint64 `json:"bytesReceived"`
+	StartTime     string `json:"startTime"`
+	EndTime       string `json:"endTime"`
+	Status        string `json:"status"`
+}
+
+// writeAuditRecord writes the audit record of the port session to the local audit file and,
+// if a log group is configured, to CloudWatch Logs
+func (p *PortPlugin) writeAuditRecord(log log.T,
+	config agentContracts.Configuration,
+	mgsConfig appconfig.MgsConfig,
+	output iohandler.IOHandler) {
+
+	if !mgsConfig.PortForwardingAuditLogEnabled {
+		return
+	}
+
+	record := portAuditRecord{
+		SessionId:     config.SessionId,
+		ClientId:      config.ClientId,
+		Host:          p.host,
+		PortNumber:    p.portNumber,
+		DevicePath:    p.devicePath,
+		PipePath:      p.pipePath,
+		Type:          p.portType,
+		BytesSent:     p.bytesSent(),
+		BytesReceived: p.bytesReceived(),
+		StartTime:     p.startTime.UTC().Format(time.RFC3339),
+		EndTime:       time.Now().UTC().Format(time.RFC3339),
+		Status:        string(output.GetStatus()),
+	}
+	if config.RunAsEnabled {
+		record.RunAsUser = config.RunAsUser
+	}
+
+	content, err := jsonutil.Marshal(record)
+	if err != nil {
+		log.Errorf("Unable to marshal port audit record: %v", err)
+		return
+	}
+
+	if err = auditlog.AppendRolled(filepath.Join(auditLogDir, auditLogFileName), content, auditLogMaxSize, auditLogMaxRolls); err != nil {
+		log.Errorf("Unable to write port audit record: %v", err)
+	}
+
+	if mgsConfig.PortForwardingAuditLogGroup != "" {
+		if err = auditlog.Publish(log, newCloudWatchLogsService(), mgsConfig.PortForwardingAuditLogGroup, config.SessionId, content); err != nil {
+			log.Errorf("Unable to publish port audit record to CloudWatch Logs: %v", err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package port implements session port plugin.
+package port
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/cloudwatchlogsinterface"
+	cloudwatchlogspublisher_mock "github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/mock"
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	iohandlermocks "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWriteAuditRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "portaudit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	auditLogDir = dir
+
+	cwl := new(cloudwatchlogspublisher_mock.CloudWatchLogsServiceMock)
+	cwl.On("CreateLogGroup", mockLog, "audit-group").Return(nil)
+	cwl.On("CreateLogStream", mockLog, "audit-group", "session-id").Return(nil)
+	cwl.On("PutLogEvents", mockLog, mock.Anything, "audit-group", "session-id", mock.Anything).Return(nil, nil)
+	newCloudWatchLogsService = func() cloudwatchlogsinterface.ICloudWatchLogsService {
+		return cwl
+	}
+
+	mockIohandler := new(iohandlermocks.MockIOHandler)
+	mockIohandler.On("GetStatus").Return(contracts.ResultStatusSuccess)
+
+	plugin := &PortPlugin{host: "localhost", portNumber: "22", sentBytes: 10, receivedBytes: 20, startTime: time.Now()}
+	plugin.writeAuditRecord(mockLog,
+		contracts.Configuration{SessionId: "session-id", ClientId: "client-id"},
+		appconfig.MgsConfig{PortForwardingAuditLogEnabled: true, PortForwardingAuditLogGroup: "audit-group"},
+		mockIohandler)
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, auditLogFileName))
+	assert.NoError(t, err)
+	var record portAuditRecord
+	assert.NoError(t, jsonutil.Unmarshal(strings.TrimSpace(string(content)), &record))
+	assert.Equal(t, "session-id", record.SessionId)
+	assert.Equal(t, "client-id", record.ClientId)
+	assert.Equal(t, "22", record.PortNumber)
+	assert.Equal(t, uint64(10), record.BytesSent)
+	assert.Equal(t, uint64(20), record.BytesReceived)
+	assert.Equal(t, string(contracts.ResultStatusSuccess), record.Status)
+	cwl.AssertExpectations(t)
+}
+
+func TestWriteAuditRecordRollsAuditLogOver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "portaudit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	auditLogDir = dir
+	auditLogMaxSize = 1
+	defer func() { auditLogMaxSize = log.LogFileMaxSize }()
+
+	mockIohandler := new(iohandlermocks.MockIOHandler)
+	mockIohandler.On("GetStatus").Return(contracts.ResultStatusSuccess)
+
+	for _, sessionId := range []string{"session-1", "session-2"} {
+		plugin := &PortPlugin{host: "localhost", portNumber: "22", startTime: time.Now()}
+		plugin.writeAuditRecord(mockLog,
+			contracts.Configuration{SessionId: sessionId},
+			appconfig.MgsConfig{PortForwardingAuditLogEnabled: true},
+			mockIohandler)
+	}
+
+	for fileName, sessionId := range map[string]string{auditLogFileName: "session-2", auditLogFileName + ".1": "session-1"} {
+		content, err := ioutil.ReadFile(filepath.Join(dir, fileName))
+		assert.NoError(t, err)
+		var record portAuditRecord
+		assert.NoError(t, jsonutil.Unmarshal(strings.TrimSpace(string(content)), &record))
+		assert.Equal(t, sessionId, record.SessionId)
+	}
+}
+
+func TestWriteAuditRecordWhenDisabled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "portaudit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	auditLogDir = dir
+
+	plugin := &PortPlugin{host: "localhost", portNumber: "22"}
+	plugin.writeAuditRecord(mockLog, contracts.Configuration{}, appconfig.MgsConfig{}, new(iohandlermocks.MockIOHandler))
+
+	_, err = os.Stat(filepath.Join(dir, auditLogFileName))
+	assert.True(t, os.IsNotExist(err))
+}
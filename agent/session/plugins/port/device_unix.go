@@ -0,0 +1,27 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build darwin freebsd linux netbsd openbsd
+
+// Package port implements session manager's port plugin
+package port
+
+import (
+	"os"
+	"syscall"
+)
+
+// openDevice opens the character device without making it the controlling terminal of the session worker
+func openDevice(devicePath string) (*os.File, error) {
+	return os.OpenFile(devicePath, os.O_RDWR|syscall.O_NOCTTY, 0)
+}
@@ -0,0 +1,161 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build windows
+
+// Package port implements session manager's port plugin
+package port
+
+import (
+	"io"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// Windows APIs
+var (
+	kernel32                = syscall.NewLazyDLL("kernel32.dll")
+	procCreateEventW        = kernel32.NewProc("CreateEventW")
+	procGetOverlappedResult = kernel32.NewProc("GetOverlappedResult")
+)
+
+const errorPipeNotConnected syscall.Errno = 233
+
+// The pipe is opened with the identification impersonation level,
+// so that the process serving the pipe cannot impersonate the agent
+const (
+	securitySqosPresent    = 0x00100000
+	securityIdentification = 0x00010000
+)
+
+// pipeConn is a client connection to a named pipe opened for overlapped I/O,
+// so that reads and writes from separate go routines do not block each other
+type pipeConn struct {
+	handle     syscall.Handle
+	readEvent  syscall.Handle
+	writeEvent syscall.Handle
+	closeOnce  sync.Once
+}
+
+// dialPipe connects to the named pipe
+func dialPipe(pipePath string) (io.ReadWriteCloser, error) {
+	path, err := syscall.UTF16PtrFromString(pipePath)
+	if err != nil {
+		return nil, err
+	}
+
+	handle, err := syscall.CreateFile(path,
+		syscall.GENERIC_READ|syscall.GENERIC_WRITE,
+		0,
+		nil,
+		syscall.OPEN_EXISTING,
+		syscall.FILE_FLAG_OVERLAPPED|securitySqosPresent|securityIdentification,
+		0)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &pipeConn{handle: handle}
+	if conn.readEvent, err = createEvent(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if conn.writeEvent, err = createEvent(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Read reads from the named pipe
+func (c *pipeConn) Read(b []byte) (int, error) {
+	return c.overlappedIO(b, c.readEvent, syscall.ReadFile)
+}
+
+// Write writes to the named pipe
+func (c *pipeConn) Write(b []byte) (written int, err error) {
+	for written < len(b) {
+		var n int
+		if n, err = c.overlappedIO(b[written:], c.writeEvent, syscall.WriteFile); err != nil {
+			return written, err
+		}
+		written += n
+	}
+	return written, nil
+}
+
+// Close cancels pending I/O and closes the named pipe
+func (c *pipeConn) Close() (err error) {
+	c.closeOnce.Do(func() {
+		syscall.CancelIoEx(c.handle, nil)
+		err = syscall.CloseHandle(c.handle)
+		if c.readEvent != 0 {
+			syscall.CloseHandle(c.readEvent)
+		}
+		if c.writeEvent != 0 {
+			syscall.CloseHandle(c.writeEvent)
+		}
+	})
+	return err
+}
+
+// overlappedIO starts the read or write operation and waits for its completion
+func (c *pipeConn) overlappedIO(b []byte,
+	event syscall.Handle,
+	operation func(syscall.Handle, []byte, *uint32, *syscall.Overlapped) error) (int, error) {
+
+	overlapped := syscall.Overlapped{HEvent: event}
+	var done uint32
+	err := operation(c.handle, b, &done, &overlapped)
+	if err == syscall.ERROR_IO_PENDING {
+		err = getOverlappedResult(c.handle, &overlapped, &done)
+	}
+
+	switch err {
+	case nil:
+		return int(done), nil
+	case syscall.ERROR_BROKEN_PIPE, errorPipeNotConnected, syscall.ERROR_OPERATION_ABORTED:
+		return int(done), io.EOF
+	default:
+		return int(done), err
+	}
+}
+
+// createEvent creates a manual reset event used to wait for overlapped I/O
+func createEvent() (syscall.Handle, error) {
+	r1, _, e1 := procCreateEventW.Call(0, 1, 0, 0)
+	if r1 == 0 {
+		if e1 != nil {
+			return 0, error(e1)
+		}
+		return 0, syscall.EINVAL
+	}
+	return syscall.Handle(r1), nil
+}
+
+// getOverlappedResult waits for the overlapped operation to complete
+func getOverlappedResult(handle syscall.Handle, overlapped *syscall.Overlapped, done *uint32) error {
+	r1, _, e1 := procGetOverlappedResult.Call(
+		uintptr(handle),
+		uintptr(unsafe.Pointer(overlapped)),
+		uintptr(unsafe.Pointer(done)),
+		1)
+	if r1 == 0 {
+		if e1 != nil {
+			return error(e1)
+		}
+		return syscall.EINVAL
+	}
+	return nil
+}
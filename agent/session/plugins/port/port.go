@@ -17,11 +17,17 @@ package port
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net"
-	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
@@ -34,27 +40,90 @@ import (
 	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/session/datachannel"
 	"github.com/aws/amazon-ssm-agent/agent/session/plugins/sessionplugin"
+	"github.com/aws/amazon-ssm-agent/agent/session/retry"
+	"github.com/aws/amazon-ssm-agent/agent/session/sessionlimit"
+	"github.com/aws/amazon-ssm-agent/agent/session/telemetry"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
 
-var DialCall = func(network string, address string) (net.Conn, error) {
-	return net.Dial(network, address)
+const (
+	defaultHost = "localhost"
+
+	pipePathPrefix = `\\.\pipe\`
+
+	portSessionCategory = "port"
+	sshSessionCategory  = "ssh"
+)
+
+var DialCall = func(dialer *net.Dialer, network string, address string) (net.Conn, error) {
+	return dialer.Dial(network, address)
 }
 
+var OpenDeviceCall = func(devicePath string) (io.ReadWriteCloser, error) {
+	device, err := openDevice(devicePath)
+	if err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+var DialPipeCall = func(pipePath string) (io.ReadWriteCloser, error) {
+	return dialPipe(pipePath)
+}
+
+// recordCrash reports a panic recovered in the plugin with the agent health
+var recordCrash = telemetry.RecordCrash
+
+// acquireSession registers a session against a limit of concurrent sessions
+var acquireSession = sessionlimit.Acquire
+
 // PortParameters contains inputs required to execute port plugin.
 type PortParameters struct {
-	PortNumber string `json:"portNumber" yaml:"portNumber"`
-	Type       string `json:"type"`
+	PortNumber                 string `json:"portNumber" yaml:"portNumber"`
+	Host                       string `json:"host" yaml:"host"`
+	DevicePath                 string `json:"devicePath" yaml:"devicePath"`
+	PipeName                   string `json:"pipeName" yaml:"pipeName"`
+	Type                       string `json:"type"`
+	WaitForReady               string `json:"waitForReady" yaml:"waitForReady"`
+	WaitForReadyTimeoutSeconds string `json:"waitForReadyTimeoutSeconds" yaml:"waitForReadyTimeoutSeconds"`
+	LocalAddress               string `json:"localAddress" yaml:"localAddress"`
 }
 
 // Plugin is the type for the port plugin.
 type PortPlugin struct {
-	tcpConn            net.Conn
+	// byte counters are accessed atomically and kept first in the struct for 64-bit alignment
+	sentBytes          uint64
+	receivedBytes      uint64
+	reconnectCount     uint64
+	startTime          time.Time
+	conn               io.ReadWriteCloser
 	dataChannel        datachannel.IDataChannel
 	portNumber         string
+	host               string
+	devicePath         string
+	pipePath           string
+	hostOverrides      map[string]string
 	portType           string
 	reconnectToPort    bool
 	reconnectToPortErr chan (error)
+	reconnectAttempts  int
+	reconnectMaxDelay  int
+	connErr            error
+	waitForReady       bool
+	readyTimeout       time.Duration
+	dialer             net.Dialer
+	tcpNoDelay         bool
+	receiveBufferSize  int
+	sendBufferSize     int
+	// streamDataPayloadSize is the largest payload read from the port and sent over the data channel
+	streamDataPayloadSize int
+	// connLock guards conn, reconnectToPort, reconnecting and the payloads received while reconnecting
+	connLock        sync.Mutex
+	reconnecting    bool
+	pendingPayloads [][]byte
+	// sessionEnded is closed when the session ends, so that a writePump waiting for reconnection returns
+	sessionEnded chan struct{}
+	sessionId    string
 }
 
 // Returns parameters required for CLI to start session
@@ -70,7 +139,7 @@ func (p *PortPlugin) RequireHandshake() bool {
 // NewPlugin returns a new instance of the Port Plugin.
 func NewPlugin() (sessionplugin.ISessionPlugin, error) {
 	var plugin = PortPlugin{
-		reconnectToPortErr: make(chan error),
+		reconnectToPortErr: make(chan error, 1),
 	}
 	return &plugin, nil
 }
@@ -89,14 +158,7 @@ func (p *PortPlugin) Execute(context context.T,
 	output iohandler.IOHandler,
 	dataChannel datachannel.IDataChannel) {
 
-	log := context.Log()
 	p.dataChannel = dataChannel
-	defer func() {
-		if err := recover(); err != nil {
-			log.Errorf("Error occurred while executing plugin %s: \n%v", p.name(), err)
-			os.Exit(1)
-		}
-	}()
 
 	if cancelFlag.ShutDown() {
 		output.MarkAsShutdown()
@@ -119,11 +181,29 @@ func (p *PortPlugin) execute(context context.T,
 	var err error
 	sessionPluginResultOutput := mgsContracts.SessionPluginResultOutput{}
 
+	p.sessionId = config.SessionId
+	p.sessionEnded = make(chan struct{})
 	defer func() {
+		close(p.sessionEnded)
 		p.stop(log)
 	}()
 
-	if err = p.initializeParameters(log, config.Properties); err != nil {
+	if err = p.initializeParameters(log, config.Properties, context.AppConfig()); err != nil {
+		log.Error(err)
+		output.SetExitCode(appconfig.ErrorExitCode)
+		output.SetStatus(agentContracts.ResultStatusFailed)
+		sessionPluginResultOutput.Output = err.Error()
+		output.SetOutput(sessionPluginResultOutput)
+		return
+	}
+
+	p.startTime = time.Now()
+	defer func() {
+		p.writeAuditRecord(log, config, context.AppConfig().Mgs, output)
+	}()
+
+	releaseSession, err := p.acquireSessionSlot(log, config.SessionId, context.AppConfig().Mgs)
+	if err != nil {
 		log.Error(err)
 		output.SetExitCode(appconfig.ErrorExitCode)
 		output.SetStatus(agentContracts.ResultStatusFailed)
@@ -131,8 +211,14 @@ func (p *PortPlugin) execute(context context.T,
 		output.SetOutput(sessionPluginResultOutput)
 		return
 	}
+	defer releaseSession()
 
-	if err = p.startTCPConn(log); err != nil {
+	if p.waitForReady {
+		err = p.waitForPort(log, cancelFlag)
+	} else {
+		err = p.startConn(log)
+	}
+	if err != nil {
 		log.Error(err)
 		output.SetExitCode(appconfig.ErrorExitCode)
 		output.SetStatus(agentContracts.ResultStatusFailed)
@@ -171,6 +257,10 @@ func (p *PortPlugin) execute(context context.T,
 		if exitCode == 1 {
 			output.SetExitCode(appconfig.ErrorExitCode)
 			output.SetStatus(agentContracts.ResultStatusFailed)
+			if p.connErr != nil {
+				sessionPluginResultOutput.Output = p.connErr.Error()
+				output.SetOutput(sessionPluginResultOutput)
+			}
 		} else {
 			output.SetExitCode(appconfig.SuccessExitCode)
 			output.SetStatus(agentContracts.ResultStatusSuccess)
@@ -185,7 +275,7 @@ func (p *PortPlugin) execute(context context.T,
 
 // InputStreamMessageHandler passes payload byte stream to port
 func (p *PortPlugin) InputStreamMessageHandler(log log.T, streamDataMessage mgsContracts.AgentMessage) error {
-	if p.tcpConn == nil {
+	if p.connection() == nil {
 		// This is to handle scenario when cli/console starts sending data but port has not been opened yet
 		// Since packets are rejected, cli/console will resend these packets until tcp starts successfully in separate thread
 		log.Tracef("TCP connection unavailable. Reject incoming message packet")
@@ -196,43 +286,150 @@ func (p *PortPlugin) InputStreamMessageHandler(log log.T, streamDataMessage mgsC
 	case mgsContracts.Output:
 		log.Tracef("Output message received: %d", streamDataMessage.SequenceNumber)
 
-		if p.reconnectToPort {
-			log.Debugf("Reconnect to port: %s", p.portNumber)
-			err := p.startTCPConn(log)
-
-			// Pass err to reconnectToPortErr chan to unblock writePump go routine to resume reading from localhost:p.portNumber
-			p.reconnectToPortErr <- err
-			if err != nil {
-				return err
+		p.connLock.Lock()
+		if p.reconnectToPort || p.reconnecting {
+			// the payloads received while reconnecting are written in order once the connection is reopened
+			p.pendingPayloads = append(p.pendingPayloads, streamDataMessage.Payload)
+			if !p.reconnecting {
+				log.Debugf("Reconnect to port: %s", p.portNumber)
+				p.reconnecting = true
+				go p.reconnectToPortInBackground(log)
 			}
-
-			p.reconnectToPort = false
+			p.connLock.Unlock()
+			return nil
 		}
+		conn := p.conn
+		p.connLock.Unlock()
 
-		if _, err := p.tcpConn.Write(streamDataMessage.Payload); err != nil {
-			log.Errorf("Unable to write to port, err: %v.", err)
-			return err
-		}
+		return p.writeToPort(log, conn, streamDataMessage.Payload)
 	case mgsContracts.Flag:
 		var flag mgsContracts.PayloadTypeFlag
 		buf := bytes.NewBuffer(streamDataMessage.Payload)
 		binary.Read(buf, binary.BigEndian, &flag)
 
-		// DisconnectToPort flag is sent by client when tcp connection on client side is closed.
-		// In this case agent should also close tcp connection with server and wait for new data from client to reconnect.
-		if flag == mgsContracts.DisconnectToPort {
+		switch flag {
+		case mgsContracts.DisconnectToPort:
+			// DisconnectToPort flag is sent by client when tcp connection on client side is closed.
+			// In this case agent should also close tcp connection with server and wait for new data from client to reconnect.
 			log.Debugf("DisconnectToPort flag received: %d", streamDataMessage.SequenceNumber)
 			p.stop(log)
+		case mgsContracts.ConnectionStatisticsRequest:
+			// ConnectionStatisticsRequest flag is sent by client to display live statistics of the session.
+			log.Debugf("ConnectionStatisticsRequest flag received: %d", streamDataMessage.SequenceNumber)
+			return p.sendConnectionStatistics(log)
 		}
 	}
 	return nil
 }
 
+// writeToPort writes the payload received from the data channel to the connection
+func (p *PortPlugin) writeToPort(log log.T, conn io.Writer, payload []byte) error {
+	numBytes, err := conn.Write(payload)
+	atomic.AddUint64(&p.receivedBytes, uint64(numBytes))
+	if err != nil {
+		log.Errorf("Unable to write to port, err: %v.", err)
+		return err
+	}
+	return nil
+}
+
+// reconnectToPortInBackground reopens the connection with backoff without blocking the data channel, writes the payloads
+// received meanwhile and passes the result to the writePump waiting for the reconnection.
+// The reconnection state is reset whatever the result, the payloads are dropped if the connection cannot be reopened.
+func (p *PortPlugin) reconnectToPortInBackground(log log.T) {
+	err := p.reconnect(log)
+	if err == nil {
+		atomic.AddUint64(&p.reconnectCount, 1)
+	}
+	for {
+		p.connLock.Lock()
+		if err != nil || len(p.pendingPayloads) == 0 {
+			p.pendingPayloads = nil
+			p.reconnecting = false
+			p.reconnectToPort = false
+			p.connLock.Unlock()
+			break
+		}
+		payloads, conn := p.pendingPayloads, p.conn
+		p.pendingPayloads = nil
+		p.connLock.Unlock()
+
+		for _, payload := range payloads {
+			if err = p.writeToPort(log, conn, payload); err != nil {
+				break
+			}
+		}
+	}
+
+	// Pass err to reconnectToPortErr chan to unblock writePump go routine to resume reading from localhost:p.portNumber,
+	// the channel is buffered as the writePump does not wait once the session ended
+	p.reconnectToPortErr <- err
+}
+
+// acquireSessionSlot registers the session against the configured limit of concurrent port or SSH sessions.
+// Device and named pipe sessions count as port sessions.
+func (p *PortPlugin) acquireSessionSlot(log log.T, sessionId string, config appconfig.MgsConfig) (release func(), err error) {
+	category, limit := portSessionCategory, config.PortSessionsLimit
+	if p.portType != mgsConfig.LocalPortForwarding && p.devicePath == "" && p.pipePath == "" {
+		category, limit = sshSessionCategory, config.SSHSessionsLimit
+	}
+
+	if release, err = acquireSession(log, category, sessionId, limit); err == sessionlimit.ErrLimitReached {
+		return release, errors.New(fmt.Sprintf("Maximum number of concurrent %s sessions (%d) reached on this instance", category, limit))
+	}
+	return release, err
+}
+
+// sendConnectionStatistics sends the current statistics of the session to the client
+func (p *PortPlugin) sendConnectionStatistics(log log.T) error {
+	statistics := mgsContracts.ConnectionStatisticsPayload{
+		BytesSent:         p.bytesSent(),
+		BytesReceived:     p.bytesReceived(),
+		RoundTripTimeMs:   int64(p.dataChannel.GetRoundTripTime() / time.Millisecond),
+		ReconnectCount:    atomic.LoadUint64(&p.reconnectCount),
+		SessionDurationMs: int64(time.Since(p.startTime) / time.Millisecond),
+	}
+
+	statisticsBytes, err := json.Marshal(statistics)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Unable to marshal connection statistics. %v", err))
+	}
+	if err = p.dataChannel.SendStreamDataMessage(log, mgsContracts.ConnectionStatistics, statisticsBytes); err != nil {
+		log.Errorf("Unable to send connection statistics: %v", err)
+		return err
+	}
+	return nil
+}
+
+// bytesSent returns the number of bytes read from the port and sent over the data channel
+func (p *PortPlugin) bytesSent() uint64 {
+	return atomic.LoadUint64(&p.sentBytes)
+}
+
+// bytesReceived returns the number of bytes received from the data channel and written to the port
+func (p *PortPlugin) bytesReceived() uint64 {
+	return atomic.LoadUint64(&p.receivedBytes)
+}
+
+// connection returns the connection to the port
+func (p *PortPlugin) connection() io.ReadWriteCloser {
+	p.connLock.Lock()
+	defer p.connLock.Unlock()
+	return p.conn
+}
+
+// setConnection sets the connection to the port
+func (p *PortPlugin) setConnection(conn io.ReadWriteCloser) {
+	p.connLock.Lock()
+	defer p.connLock.Unlock()
+	p.conn = conn
+}
+
 // Stop closes the TCP Connection to the instance
 func (p *PortPlugin) stop(log log.T) {
-	if p.tcpConn != nil {
+	if conn := p.connection(); conn != nil {
 		log.Debug("Closing TCP connection")
-		if err := p.tcpConn.Close(); err != nil {
+		if err := conn.Close(); err != nil {
 			log.Debugf("Unable to close connection to port. %v", err)
 		}
 	}
@@ -242,14 +439,20 @@ func (p *PortPlugin) stop(log log.T) {
 func (p *PortPlugin) writePump(log log.T) (errorCode int) {
 	defer func() {
 		if err := recover(); err != nil {
-			fmt.Println("WritePump thread crashed with message: \n", err)
+			log.Errorf("WritePump thread crashed with message: \n%v", err)
+			stackTrace := debug.Stack()
+			log.Errorf("%s: %s", err, stackTrace)
+			recordCrash(log, p.sessionId, p.name(), err, stackTrace)
+			errorCode = appconfig.ErrorExitCode
 		}
 	}()
 
-	packet := make([]byte, mgsConfig.StreamDataPayloadSize)
+	packet := make([]byte, p.streamDataPayloadSize)
 
 	for {
-		numBytes, err := p.tcpConn.Read(packet)
+		// Stop reading from the port while the client has not acknowledged enough of the data sent
+		p.dataChannel.WaitForSendWindow()
+		numBytes, err := p.connection().Read(packet)
 		if err != nil {
 			var exitCode int
 			if exitCode = p.handleTCPReadError(log, err); exitCode == mgsConfig.ResumeReadExitCode {
@@ -263,6 +466,7 @@ func (p *PortPlugin) writePump(log log.T) (errorCode int) {
 			log.Errorf("Unable to send stream data message: %v", err)
 			return appconfig.ErrorExitCode
 		}
+		atomic.AddUint64(&p.sentBytes, uint64(numBytes))
 		// Wait for TCP to process more data
 		time.Sleep(time.Millisecond)
 	}
@@ -295,13 +499,21 @@ func (p *PortPlugin) handlePortError(log log.T, err error) int {
 	// web socket channel to trigger reconnection to localhost:p.portNumber.
 	log.Debugf("Encountered error while reading from port %v, %v", p.portNumber, err)
 	p.stop(log)
+	p.connLock.Lock()
 	p.reconnectToPort = true
+	p.connLock.Unlock()
 
 	log.Debugf("Waiting for reconnection to port!!")
-	err = <-p.reconnectToPortErr
+	select {
+	case err = <-p.reconnectToPortErr:
+	case <-p.sessionEnded:
+		log.Debugf("Session ended while waiting for reconnection to port %v", p.portNumber)
+		return appconfig.ErrorExitCode
+	}
 
 	if err != nil {
 		log.Error(err)
+		p.connErr = err
 		return appconfig.ErrorExitCode
 	}
 
@@ -309,27 +521,301 @@ func (p *PortPlugin) handlePortError(log log.T, err error) int {
 	return mgsConfig.ResumeReadExitCode
 }
 
+// reconnect reopens the connection with exponential backoff and fails after the configured number of attempts
+func (p *PortPlugin) reconnect(log log.T) (err error) {
+	retryer := retry.ExponentialRetryer{
+		CallableFunc: func() (interface{}, error) {
+			return nil, p.startConn(log)
+		},
+		GeometricRatio:      mgsConfig.RetryGeometricRatio,
+		InitialDelayInMilli: mgsConfig.PortReconnectInitialDelayMillis,
+		MaxDelayInMilli:     p.reconnectMaxDelay,
+		// the retryer makes one call more than MaxAttempts
+		MaxAttempts: p.reconnectAttempts - 1,
+	}
+	if _, err = retryer.Call(); err != nil {
+		return errors.New(fmt.Sprintf("Reconnection failed after %d attempts. %v", p.reconnectAttempts, err))
+	}
+	return nil
+}
+
+// startConn opens the connection to the specified device or port
+func (p *PortPlugin) startConn(log log.T) (err error) {
+	if p.devicePath != "" {
+		return p.startDeviceConn(log)
+	}
+	if p.pipePath != "" {
+		return p.startPipeConn(log)
+	}
+	return p.startTCPConn(log)
+}
+
+// startPipeConn connects to the specified named pipe
+func (p *PortPlugin) startPipeConn(log log.T) (err error) {
+	log.Debugf("Connecting to named pipe %s", p.pipePath)
+	var conn io.ReadWriteCloser
+	if conn, err = DialPipeCall(p.pipePath); err != nil {
+		return errors.New(fmt.Sprintf("Unable to connect to specified pipe: %v", err))
+	}
+	p.setConnection(conn)
+
+	return nil
+}
+
+// startDeviceConn opens the specified character device for reading and writing
+func (p *PortPlugin) startDeviceConn(log log.T) (err error) {
+	log.Debugf("Opening device %s", p.devicePath)
+	var conn io.ReadWriteCloser
+	if conn, err = OpenDeviceCall(p.devicePath); err != nil {
+		return errors.New(fmt.Sprintf("Unable to open specified device: %v", err))
+	}
+	p.setConnection(conn)
+
+	return nil
+}
+
 // startTCPConn starts TCP connection to the specified port
 func (p *PortPlugin) startTCPConn(log log.T) (err error) {
-	if p.tcpConn, err = DialCall("tcp", "localhost:"+p.portNumber); err != nil {
+	var conn net.Conn
+	if conn, err = DialCall(&p.dialer, "tcp", net.JoinHostPort(p.resolveHost(log), p.portNumber)); err != nil {
 		return errors.New(fmt.Sprintf("Unable to connect to specified port: %v", err))
 	}
+	p.setSocketOptions(log, conn)
+	p.setConnection(conn)
 
 	return nil
 }
 
+// setSocketOptions applies the configured Nagle and socket buffer settings to a TCP connection
+func (p *PortPlugin) setSocketOptions(log log.T, conn net.Conn) {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return
+	}
+	if err := tcpConn.SetNoDelay(p.tcpNoDelay); err != nil {
+		log.Warnf("Unable to set TCP_NODELAY on connection to port: %v", err)
+	}
+	if p.receiveBufferSize > 0 {
+		if err := tcpConn.SetReadBuffer(p.receiveBufferSize); err != nil {
+			log.Warnf("Unable to set receive buffer size on connection to port: %v", err)
+		}
+	}
+	if p.sendBufferSize > 0 {
+		if err := tcpConn.SetWriteBuffer(p.sendBufferSize); err != nil {
+			log.Warnf("Unable to set send buffer size on connection to port: %v", err)
+		}
+	}
+}
+
+// resolveHost returns the address configured in the host overrides for the destination host, or the host itself
+func (p *PortPlugin) resolveHost(log log.T) string {
+	if address, ok := p.hostOverrides[strings.ToLower(p.host)]; ok {
+		log.Debugf("Using override address %s for host %s", address, p.host)
+		return address
+	}
+	return p.host
+}
+
+// waitForPort probes the specified port until it accepts a connection, the ready timeout elapses or the session is cancelled.
+// The first successful connection is kept and used for the session.
+func (p *PortPlugin) waitForPort(log log.T, cancelFlag task.CancelFlag) (err error) {
+	deadline := time.Now().Add(p.readyTimeout)
+	for attempt := 1; ; attempt++ {
+		if err = p.startConn(log); err == nil {
+			log.Infof("Port %s is ready after %d attempt(s)", p.portNumber, attempt)
+			return nil
+		}
+		if cancelFlag.Canceled() || cancelFlag.ShutDown() {
+			return errors.New(fmt.Sprintf("Session was cancelled while waiting for port %s to become ready", p.portNumber))
+		}
+
+		remaining := deadline.Sub(time.Now())
+		if remaining <= 0 {
+			return errors.New(fmt.Sprintf("Port %s did not become ready within %v. %v", p.portNumber, p.readyTimeout, err))
+		}
+		log.Infof("Waiting for port %s to start listening, attempt %d, %v remaining", p.portNumber, attempt, remaining.Round(time.Second))
+		p.sendProgress(log, fmt.Sprintf(mgsConfig.PortReadyProgressMsg, p.portNumber, remaining.Round(time.Second)))
+
+		if remaining > mgsConfig.PortReadyProbeInterval {
+			remaining = mgsConfig.PortReadyProbeInterval
+		}
+		time.Sleep(remaining)
+	}
+}
+
+// sendProgress shows the progress message to the user if the client supports progress messages.
+// Other clients would forward the message to the port of the session.
+func (p *PortPlugin) sendProgress(log log.T, message string) {
+	if !p.dataChannel.IsCapabilityEnabled(mgsContracts.ProgressMessageCapability) {
+		return
+	}
+	if err := p.dataChannel.SendStreamDataMessage(log, mgsContracts.Output, []byte(message)); err != nil {
+		log.Warnf("Unable to send progress message: %v", err)
+	}
+}
+
 // initializeParameters initializes PortPlugin with input parameters
-func (p *PortPlugin) initializeParameters(log log.T, parameters interface{}) (err error) {
+func (p *PortPlugin) initializeParameters(log log.T, parameters interface{}, appConfig appconfig.SsmagentConfig) (err error) {
 	var portParameters PortParameters
 	if err = jsonutil.Remarshal(parameters, &portParameters); err != nil {
 		return errors.New(fmt.Sprintf("Unable to remarshal session properties. %v", err))
 	}
 
-	if portParameters.PortNumber == "" {
+	if portParameters.DevicePath != "" {
+		if !isDeviceAllowed(portParameters.DevicePath, appConfig.Mgs.PortForwardingAllowedDevices) {
+			return errors.New(fmt.Sprintf("Device %s is not in the list of devices allowed for forwarding", portParameters.DevicePath))
+		}
+		p.devicePath = filepath.Clean(portParameters.DevicePath)
+	} else if portParameters.PipeName != "" {
+		pipeName := trimPipePathPrefix(portParameters.PipeName)
+		if !isPipeAllowed(pipeName, appConfig.Mgs.PortForwardingAllowedPipes) {
+			return errors.New(fmt.Sprintf("Pipe %s is not in the list of pipes allowed for forwarding", portParameters.PipeName))
+		}
+		p.pipePath = pipePathPrefix + pipeName
+	} else if portParameters.PortNumber == "" {
 		return errors.New(fmt.Sprintf("Port number is empty in session properties. %v", parameters))
 	}
 	p.portNumber = portParameters.PortNumber
 	p.portType = portParameters.Type
 
+	p.reconnectAttempts = appConfig.Mgs.PortReconnectMaxAttempts
+	if p.reconnectAttempts < 1 {
+		p.reconnectAttempts = appconfig.DefaultPortReconnectMaxAttempts
+	}
+	p.reconnectMaxDelay = appConfig.Mgs.PortReconnectMaxIntervalMillis
+	if p.reconnectMaxDelay <= 0 {
+		p.reconnectMaxDelay = appconfig.DefaultPortReconnectMaxIntervalMillis
+	}
+	p.tcpNoDelay = appConfig.Mgs.PortForwardingTcpNoDelay
+	p.receiveBufferSize = appConfig.Mgs.PortForwardingReceiveBufferSize
+	p.sendBufferSize = appConfig.Mgs.PortForwardingSendBufferSize
+	p.streamDataPayloadSize = datachannel.GetStreamDataPayloadSize(appConfig.Mgs, appconfig.PluginNamePort)
+
+	p.host = defaultHost
+	if portParameters.Host != "" {
+		if !isHostAllowed(portParameters.Host, appConfig.Mgs.PortForwardingAllowedHosts) {
+			return errors.New(fmt.Sprintf("Host %s is not in the list of hosts allowed for forwarding", portParameters.Host))
+		}
+		p.host = portParameters.Host
+	}
+	p.hostOverrides = make(map[string]string)
+	for host, address := range appConfig.Mgs.PortForwardingHostOverrides {
+		if net.ParseIP(address) == nil {
+			return errors.New(fmt.Sprintf("Host override for %s is not a valid IP address: %s", host, address))
+		}
+		p.hostOverrides[strings.ToLower(host)] = address
+	}
+
+	if portParameters.WaitForReady != "" {
+		if p.waitForReady, err = strconv.ParseBool(portParameters.WaitForReady); err != nil {
+			return errors.New(fmt.Sprintf("Invalid value for waitForReady in session properties. %v", err))
+		}
+	}
+	p.readyTimeout = mgsConfig.DefaultPortReadyTimeout
+	if portParameters.WaitForReadyTimeoutSeconds != "" {
+		var timeoutSeconds int
+		if timeoutSeconds, err = strconv.Atoi(portParameters.WaitForReadyTimeoutSeconds); err != nil || timeoutSeconds <= 0 {
+			return errors.New(fmt.Sprintf("Invalid value for waitForReadyTimeoutSeconds in session properties: %s", portParameters.WaitForReadyTimeoutSeconds))
+		}
+		p.readyTimeout = time.Duration(timeoutSeconds) * time.Second
+	}
+
+	localAddress := appConfig.Mgs.PortForwardingLocalAddress
+	if portParameters.LocalAddress != "" {
+		localAddress = portParameters.LocalAddress
+	}
+	if localAddress != "" {
+		var localIP net.IP
+		if localIP, err = resolveLocalAddress(localAddress); err != nil {
+			return err
+		}
+		log.Debugf("Binding port connections to local address %s", localIP)
+		p.dialer.LocalAddr = &net.TCPAddr{IP: localIP}
+	}
+
 	return nil
 }
+
+// trimPipePathPrefix returns the name of the named pipe, the pipe name can be given with or without the \\.\pipe\ prefix
+func trimPipePathPrefix(pipeName string) string {
+	if strings.HasPrefix(strings.ToLower(pipeName), strings.ToLower(pipePathPrefix)) {
+		return pipeName[len(pipePathPrefix):]
+	}
+	return pipeName
+}
+
+// isPipeAllowed checks whether the pipe name is one of the pipes allowed in appconfig.
+// Names with path separators or .. are rejected, as Windows would resolve them to paths outside of \\.\pipe\.
+func isPipeAllowed(pipeName string, allowedPipes []string) bool {
+	if pipeName == "" || strings.ContainsAny(pipeName, `\/`) || strings.Contains(pipeName, "..") {
+		return false
+	}
+	for _, allowedPipe := range allowedPipes {
+		if strings.EqualFold(trimPipePathPrefix(allowedPipe), pipeName) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDeviceAllowed checks whether the device path is one of the devices allowed in appconfig
+func isDeviceAllowed(devicePath string, allowedDevices []string) bool {
+	devicePath = filepath.Clean(devicePath)
+	for _, allowedDevice := range allowedDevices {
+		if filepath.Clean(allowedDevice) == devicePath {
+			return true
+		}
+	}
+	return false
+}
+
+// isHostAllowed returns true if the host is a loopback host or is in the list of allowed hosts
+func isHostAllowed(host string, allowedHosts []string) bool {
+	if strings.EqualFold(host, defaultHost) {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return true
+	}
+	for _, allowedHost := range allowedHosts {
+		if strings.EqualFold(allowedHost, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveLocalAddress returns the IP for the given local address, which is either an IP address or the name of a network interface.
+// For an interface name the first IPv4 address of the interface is preferred.
+func resolveLocalAddress(localAddress string) (net.IP, error) {
+	if ip := net.ParseIP(localAddress); ip != nil {
+		return ip, nil
+	}
+
+	iface, err := net.InterfaceByName(localAddress)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Local address %s is neither an IP address nor a network interface. %v", localAddress, err))
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to get addresses of network interface %s. %v", localAddress, err))
+	}
+
+	var firstIP net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			return ipNet.IP, nil
+		}
+		if firstIP == nil {
+			firstIP = ipNet.IP
+		}
+	}
+	if firstIP == nil {
+		return nil, errors.New(fmt.Sprintf("Network interface %s has no IP address", localAddress))
+	}
+	return firstIP, nil
+}
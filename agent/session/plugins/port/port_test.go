@@ -15,9 +15,13 @@
 package port
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"io"
 	"net"
+	"strings"
 	"testing"
 	"time"
 
@@ -29,6 +33,7 @@ import (
 	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
 	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
 	dataChannelMock "github.com/aws/amazon-ssm-agent/agent/session/datachannel/mocks"
+	"github.com/aws/amazon-ssm-agent/agent/session/sessionlimit"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -67,14 +72,15 @@ func (suite *PortTestSuite) SetupTest() {
 	suite.mockDataChannel = mockDataChannel
 	suite.mockIohandler = mockIohandler
 	suite.plugin = &PortPlugin{
-		dataChannel:        mockDataChannel,
-		reconnectToPortErr: make(chan error),
+		dataChannel:           mockDataChannel,
+		reconnectToPortErr:    make(chan error, 1),
+		streamDataPayloadSize: mgsConfig.PortStreamDataPayloadSize,
 	}
 }
 
 func (suite *PortTestSuite) TearDownTest() {
-	if suite.plugin.tcpConn != nil {
-		suite.plugin.tcpConn.Close()
+	if suite.plugin.conn != nil {
+		suite.plugin.conn.Close()
 	}
 }
 
@@ -145,7 +151,7 @@ func (suite *PortTestSuite) TestExecuteUnableToStartTCP() {
 	suite.mockIohandler.On("SetExitCode", 1).Return(nil)
 	suite.mockIohandler.On("SetOutput", mock.Anything).Return()
 
-	DialCall = func(network string, address string) (net.Conn, error) {
+	DialCall = func(dialer *net.Dialer, network string, address string) (net.Conn, error) {
 		return nil, errors.New("unable to connect")
 	}
 
@@ -168,9 +174,10 @@ func (suite *PortTestSuite) TestExecute() {
 	suite.mockIohandler.On("SetExitCode", 0).Return(nil)
 	suite.mockIohandler.On("SetStatus", contracts.ResultStatusSuccess).Return()
 	suite.mockDataChannel.On("SendStreamDataMessage", mock.Anything, mgsContracts.Output, payload).Return(nil)
+	suite.mockDataChannel.On("WaitForSendWindow").Return()
 
 	out, in := net.Pipe()
-	DialCall = func(network string, address string) (net.Conn, error) {
+	DialCall = func(dialer *net.Dialer, network string, address string) (net.Conn, error) {
 		return out, nil
 	}
 
@@ -193,9 +200,307 @@ func (suite *PortTestSuite) TestExecute() {
 	suite.mockDataChannel.AssertExpectations(suite.T())
 }
 
+// Testing waitForPort when the port starts listening after a failed attempt
+func (suite *PortTestSuite) TestWaitForPortSucceedsAfterRetry() {
+	suite.mockCancelFlag.On("Canceled").Return(false)
+	suite.mockCancelFlag.On("ShutDown").Return(false)
+	suite.mockDataChannel.On("IsCapabilityEnabled", mgsContracts.ProgressMessageCapability).Return(true)
+	progressMessage := mock.MatchedBy(func(message []byte) bool {
+		return strings.HasPrefix(string(message), "Waiting for port 22 to start listening")
+	})
+	suite.mockDataChannel.On("SendStreamDataMessage", suite.mockLog, mgsContracts.Output, progressMessage).Return(nil)
+
+	out, in := net.Pipe()
+	defer in.Close()
+	attempts := 0
+	DialCall = func(dialer *net.Dialer, network string, address string) (net.Conn, error) {
+		attempts++
+		if attempts < 2 {
+			return nil, errors.New("connection refused")
+		}
+		return out, nil
+	}
+
+	suite.plugin.portNumber = "22"
+	suite.plugin.readyTimeout = 5 * time.Second
+	assert.NoError(suite.T(), suite.plugin.waitForPort(suite.mockLog, suite.mockCancelFlag))
+	assert.Equal(suite.T(), 2, attempts)
+	assert.Equal(suite.T(), out, suite.plugin.conn)
+	suite.mockDataChannel.AssertNumberOfCalls(suite.T(), "SendStreamDataMessage", 1)
+}
+
+// Testing waitForPort when the port never starts listening
+func (suite *PortTestSuite) TestWaitForPortTimesOut() {
+	suite.mockCancelFlag.On("Canceled").Return(false)
+	suite.mockCancelFlag.On("ShutDown").Return(false)
+
+	suite.mockDataChannel.On("IsCapabilityEnabled", mgsContracts.ProgressMessageCapability).Return(false)
+
+	DialCall = func(dialer *net.Dialer, network string, address string) (net.Conn, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	suite.plugin.portNumber = "22"
+	suite.plugin.readyTimeout = 100 * time.Millisecond
+	assert.Error(suite.T(), suite.plugin.waitForPort(suite.mockLog, suite.mockCancelFlag))
+	// progress messages are not sent to clients that would forward them to the port
+	suite.mockDataChannel.AssertNotCalled(suite.T(), "SendStreamDataMessage", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// Testing initializeParameters with wait for ready options
+func (suite *PortTestSuite) TestInitializeParametersWithWaitForReady() {
+	err := suite.plugin.initializeParameters(suite.mockLog,
+		map[string]interface{}{"portNumber": "22", "waitForReady": "true", "waitForReadyTimeoutSeconds": "30"},
+		appconfig.SsmagentConfig{})
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), suite.plugin.waitForReady)
+	assert.Equal(suite.T(), 30*time.Second, suite.plugin.readyTimeout)
+
+	err = suite.plugin.initializeParameters(suite.mockLog,
+		map[string]interface{}{"portNumber": "22", "waitForReady": "true", "waitForReadyTimeoutSeconds": "abc"},
+		appconfig.SsmagentConfig{})
+	assert.Error(suite.T(), err)
+}
+
+// Testing initializeParameters with local address from appconfig and session properties
+func (suite *PortTestSuite) TestInitializeParametersWithLocalAddress() {
+	appConfig := appconfig.SsmagentConfig{Mgs: appconfig.MgsConfig{PortForwardingLocalAddress: "127.0.0.1"}}
+	assert.NoError(suite.T(), suite.plugin.initializeParameters(suite.mockLog, configuration.Properties, appConfig))
+	assert.Equal(suite.T(), "127.0.0.1:0", suite.plugin.dialer.LocalAddr.String())
+
+	assert.NoError(suite.T(), suite.plugin.initializeParameters(suite.mockLog,
+		map[string]interface{}{"portNumber": "22", "localAddress": "::1"}, appConfig))
+	assert.Equal(suite.T(), "[::1]:0", suite.plugin.dialer.LocalAddr.String())
+
+	assert.Error(suite.T(), suite.plugin.initializeParameters(suite.mockLog,
+		map[string]interface{}{"portNumber": "22", "localAddress": "nonexistent-interface0"}, appConfig))
+}
+
+// Testing startTCPConn honors host overrides from appconfig
+func (suite *PortTestSuite) TestStartTCPConnWithHostOverride() {
+	appConfig := appconfig.SsmagentConfig{Mgs: appconfig.MgsConfig{
+		PortForwardingAllowedHosts:  []string{"db.internal"},
+		PortForwardingHostOverrides: map[string]string{"DB.internal": "10.0.0.5"}}}
+	assert.NoError(suite.T(), suite.plugin.initializeParameters(suite.mockLog,
+		map[string]interface{}{"portNumber": "5432", "host": "db.internal"}, appConfig))
+
+	out, in := net.Pipe()
+	defer in.Close()
+	var dialedAddress string
+	DialCall = func(dialer *net.Dialer, network string, address string) (net.Conn, error) {
+		dialedAddress = address
+		return out, nil
+	}
+
+	assert.NoError(suite.T(), suite.plugin.startTCPConn(suite.mockLog))
+	assert.Equal(suite.T(), "10.0.0.5:5432", dialedAddress)
+}
+
+// Testing startTCPConn applies the socket options from appconfig to the connection
+func (suite *PortTestSuite) TestStartTCPConnWithSocketOptions() {
+	appConfig := appconfig.SsmagentConfig{Mgs: appconfig.MgsConfig{
+		PortForwardingTcpNoDelay:        true,
+		PortForwardingReceiveBufferSize: 65536,
+		PortForwardingSendBufferSize:    65536}}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(suite.T(), err)
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	assert.NoError(suite.T(), suite.plugin.initializeParameters(suite.mockLog,
+		map[string]interface{}{"portNumber": port, "host": "127.0.0.1"}, appConfig))
+	assert.True(suite.T(), suite.plugin.tcpNoDelay)
+	assert.Equal(suite.T(), 65536, suite.plugin.receiveBufferSize)
+	assert.Equal(suite.T(), 65536, suite.plugin.sendBufferSize)
+
+	DialCall = func(dialer *net.Dialer, network string, address string) (net.Conn, error) {
+		return dialer.Dial(network, address)
+	}
+	assert.NoError(suite.T(), suite.plugin.startTCPConn(suite.mockLog))
+	assert.IsType(suite.T(), &net.TCPConn{}, suite.plugin.conn)
+	suite.plugin.conn.Close()
+}
+
+// Testing initializeParameters allows only loopback hosts when no hosts are allowed in appconfig
+func (suite *PortTestSuite) TestInitializeParametersWithHost() {
+	for _, host := range []string{"localhost", "LOCALHOST", "127.0.0.1", "127.0.0.53", "::1"} {
+		assert.NoError(suite.T(), suite.plugin.initializeParameters(suite.mockLog,
+			map[string]interface{}{"portNumber": "22", "host": host}, appconfig.SsmagentConfig{}), host)
+		assert.Equal(suite.T(), host, suite.plugin.host)
+	}
+	for _, host := range []string{"10.0.0.5", "169.254.169.254", "db.internal", "localhost.example.com"} {
+		assert.Error(suite.T(), suite.plugin.initializeParameters(suite.mockLog,
+			map[string]interface{}{"portNumber": "22", "host": host}, appconfig.SsmagentConfig{}), host)
+	}
+}
+
+// Testing initializeParameters allows the hosts listed in appconfig
+func (suite *PortTestSuite) TestInitializeParametersWithAllowedHost() {
+	appConfig := appconfig.SsmagentConfig{Mgs: appconfig.MgsConfig{PortForwardingAllowedHosts: []string{"DB.internal", "10.0.0.5"}}}
+	for _, host := range []string{"db.internal", "10.0.0.5"} {
+		assert.NoError(suite.T(), suite.plugin.initializeParameters(suite.mockLog,
+			map[string]interface{}{"portNumber": "5432", "host": host}, appConfig), host)
+		assert.Equal(suite.T(), host, suite.plugin.host)
+	}
+	assert.Error(suite.T(), suite.plugin.initializeParameters(suite.mockLog,
+		map[string]interface{}{"portNumber": "5432", "host": "10.0.0.6"}, appConfig))
+}
+
+// Testing initializeParameters rejects host overrides that are not IP addresses
+func (suite *PortTestSuite) TestInitializeParametersWithInvalidHostOverride() {
+	appConfig := appconfig.SsmagentConfig{Mgs: appconfig.MgsConfig{
+		PortForwardingHostOverrides: map[string]string{"db.internal": "not-an-ip"}}}
+	assert.Error(suite.T(), suite.plugin.initializeParameters(suite.mockLog, configuration.Properties, appConfig))
+}
+
+// Testing acquireSessionSlot without configured limits
+func (suite *PortTestSuite) TestAcquireSessionSlotUnlimited() {
+	suite.plugin.portType = mgsConfig.LocalPortForwarding
+	release, err := suite.plugin.acquireSessionSlot(suite.mockLog, "sessionId", appconfig.MgsConfig{})
+	assert.NoError(suite.T(), err)
+	release()
+}
+
+// Testing acquireSessionSlot counts device and named pipe sessions as port sessions
+func (suite *PortTestSuite) TestAcquireSessionSlotCategory() {
+	var acquiredCategory string
+	acquireSession = func(log log.T, category string, sessionId string, limit int) (func(), error) {
+		acquiredCategory = category
+		return func() {}, nil
+	}
+	defer func() { acquireSession = sessionlimit.Acquire }()
+
+	testCases := []struct {
+		plugin   *PortPlugin
+		category string
+	}{
+		{&PortPlugin{portNumber: "22"}, sshSessionCategory},
+		{&PortPlugin{portNumber: "80", portType: mgsConfig.LocalPortForwarding}, portSessionCategory},
+		{&PortPlugin{devicePath: "/dev/ttyS0"}, portSessionCategory},
+		{&PortPlugin{pipePath: `\\.\pipe\docker_engine`}, portSessionCategory},
+	}
+	for _, testCase := range testCases {
+		release, err := testCase.plugin.acquireSessionSlot(suite.mockLog, "sessionId", appconfig.MgsConfig{})
+		assert.NoError(suite.T(), err)
+		release()
+		assert.Equal(suite.T(), testCase.category, acquiredCategory)
+	}
+}
+
+// Testing initializeParameters with device path
+func (suite *PortTestSuite) TestInitializeParametersWithDevicePath() {
+	appConfig := appconfig.SsmagentConfig{Mgs: appconfig.MgsConfig{PortForwardingAllowedDevices: []string{"/dev/ttyS0"}}}
+	assert.NoError(suite.T(), suite.plugin.initializeParameters(suite.mockLog,
+		map[string]interface{}{"devicePath": "/dev/ttyS0"}, appConfig))
+	assert.Equal(suite.T(), "/dev/ttyS0", suite.plugin.devicePath)
+
+	assert.Error(suite.T(), suite.plugin.initializeParameters(suite.mockLog,
+		map[string]interface{}{"devicePath": "/dev/ttyUSB0"}, appConfig))
+}
+
+// Testing startConn opens the device when device path is set
+func (suite *PortTestSuite) TestStartConnWithDevicePath() {
+	out, in := net.Pipe()
+	defer in.Close()
+	var openedPath string
+	OpenDeviceCall = func(devicePath string) (io.ReadWriteCloser, error) {
+		openedPath = devicePath
+		return out, nil
+	}
+	DialCall = func(dialer *net.Dialer, network string, address string) (net.Conn, error) {
+		return nil, errors.New("should not dial")
+	}
+
+	suite.plugin.devicePath = "/dev/ttyS0"
+	assert.NoError(suite.T(), suite.plugin.startConn(suite.mockLog))
+	assert.Equal(suite.T(), "/dev/ttyS0", openedPath)
+	assert.Equal(suite.T(), out, suite.plugin.conn)
+}
+
+// Testing initializeParameters with pipe name
+func (suite *PortTestSuite) TestInitializeParametersWithPipeName() {
+	appConfig := appconfig.SsmagentConfig{Mgs: appconfig.MgsConfig{PortForwardingAllowedPipes: []string{"docker_engine", `\\.\pipe\openssh-ssh-agent`}}}
+	assert.NoError(suite.T(), suite.plugin.initializeParameters(suite.mockLog,
+		map[string]interface{}{"pipeName": "docker_engine"}, appConfig))
+	assert.Equal(suite.T(), `\\.\pipe\docker_engine`, suite.plugin.pipePath)
+
+	assert.NoError(suite.T(), suite.plugin.initializeParameters(suite.mockLog,
+		map[string]interface{}{"pipeName": `\\.\PIPE\openssh-ssh-agent`}, appConfig))
+	assert.Equal(suite.T(), `\\.\pipe\openssh-ssh-agent`, suite.plugin.pipePath)
+
+	// pipes not in the list are not forwarded
+	assert.Error(suite.T(), suite.plugin.initializeParameters(suite.mockLog,
+		map[string]interface{}{"pipeName": "lsass"}, appConfig))
+	assert.Error(suite.T(), suite.plugin.initializeParameters(suite.mockLog,
+		map[string]interface{}{"pipeName": "docker_engine"}, appconfig.SsmagentConfig{}))
+}
+
+// Testing initializeParameters rejects pipe names resolving outside of the pipe namespace
+func (suite *PortTestSuite) TestInitializeParametersWithPipeNameTraversal() {
+	appConfig := appconfig.SsmagentConfig{Mgs: appconfig.MgsConfig{PortForwardingAllowedPipes: []string{"docker_engine"}}}
+	for _, pipeName := range []string{
+		`..\..\C:\Windows\System32\config\SAM`,
+		`\\.\pipe\..\..\C:\Windows\win.ini`,
+		`docker_engine\..\..\C:\Windows\win.ini`,
+		`docker_engine/../x`,
+		`..`,
+	} {
+		assert.Error(suite.T(), suite.plugin.initializeParameters(suite.mockLog,
+			map[string]interface{}{"pipeName": pipeName}, appConfig), pipeName)
+	}
+	assert.False(suite.T(), isPipeAllowed(`docker_engine\..\docker_engine`, []string{`docker_engine\..\docker_engine`}))
+}
+
+// Testing startConn connects to the named pipe when pipe name is set
+func (suite *PortTestSuite) TestStartConnWithPipeName() {
+	out, in := net.Pipe()
+	defer in.Close()
+	DialPipeCall = func(pipePath string) (io.ReadWriteCloser, error) {
+		return out, nil
+	}
+
+	suite.plugin.pipePath = `\\.\pipe\docker_engine`
+	assert.NoError(suite.T(), suite.plugin.startConn(suite.mockLog))
+	assert.Equal(suite.T(), out, suite.plugin.conn)
+}
+
+// Testing reconnect succeeds after failed attempts
+func (suite *PortTestSuite) TestReconnectWithRetry() {
+	out, in := net.Pipe()
+	defer in.Close()
+	attempts := 0
+	DialCall = func(dialer *net.Dialer, network string, address string) (net.Conn, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("connection refused")
+		}
+		return out, nil
+	}
+
+	suite.plugin.reconnectAttempts = 3
+	suite.plugin.reconnectMaxDelay = 10
+	assert.NoError(suite.T(), suite.plugin.reconnect(suite.mockLog))
+	assert.Equal(suite.T(), 3, attempts)
+}
+
+// Testing reconnect fails when attempts are exhausted
+func (suite *PortTestSuite) TestReconnectAttemptsExhausted() {
+	attempts := 0
+	DialCall = func(dialer *net.Dialer, network string, address string) (net.Conn, error) {
+		attempts++
+		return nil, errors.New("connection refused")
+	}
+
+	suite.plugin.reconnectAttempts = 2
+	suite.plugin.reconnectMaxDelay = 10
+	assert.Error(suite.T(), suite.plugin.reconnect(suite.mockLog))
+	assert.Equal(suite.T(), 2, attempts)
+}
+
 // Testing writepump separately
 func (suite *PortTestSuite) TestWritePump() {
 	suite.mockDataChannel.On("SendStreamDataMessage", suite.mockLog, mgsContracts.Output, payload).Return(nil)
+	suite.mockDataChannel.On("WaitForSendWindow").Return()
 
 	out, in := net.Pipe()
 	defer out.Close()
@@ -205,7 +510,7 @@ func (suite *PortTestSuite) TestWritePump() {
 		in.Close()
 	}()
 
-	suite.plugin.tcpConn = out
+	suite.plugin.conn = out
 	suite.plugin.writePump(suite.mockLog)
 
 	// Assert if SendStreamDataMessage function was called with same data from stdout
@@ -231,7 +536,7 @@ func (suite *PortTestSuite) TestHandleTCPReadErrorWhenReconnectionToPortFailedFo
 	defer out.Close()
 
 	suite.plugin.portType = mgsConfig.LocalPortForwarding
-	suite.plugin.tcpConn = out
+	suite.plugin.conn = out
 	suite.plugin.reconnectToPort = false
 
 	go func() {
@@ -251,7 +556,7 @@ func (suite *PortTestSuite) TestHandleTCPReadErrorWhenReconnectionToPortIsSucces
 	defer out.Close()
 
 	suite.plugin.portType = mgsConfig.LocalPortForwarding
-	suite.plugin.tcpConn = out
+	suite.plugin.conn = out
 	suite.plugin.reconnectToPort = false
 
 	go func() {
@@ -267,7 +572,7 @@ func (suite *PortTestSuite) TestHandleTCPReadErrorWhenReconnectionToPortIsSucces
 // Testing InputStreamHandler
 func (suite *PortTestSuite) TestInputStreamHandler() {
 	out, in := net.Pipe()
-	suite.plugin.tcpConn = in
+	suite.plugin.conn = in
 	defer in.Close()
 	defer out.Close()
 
@@ -283,7 +588,7 @@ func (suite *PortTestSuite) TestInputStreamHandler() {
 
 func (suite *PortTestSuite) TestInputStreamHandlerWriteFailed() {
 	out, in := net.Pipe()
-	suite.plugin.tcpConn = in
+	suite.plugin.conn = in
 	defer out.Close()
 	// Close the write pipe
 	in.Close()
@@ -299,30 +604,113 @@ func (suite *PortTestSuite) TestInputStreamHandlerWithNilTCPConn() {
 // Testing InputStreamHandler when ReconnectToPort is true
 func (suite *PortTestSuite) TestInputStreamHandlerWithReconnectToPortSetToTrue() {
 	prevConnOut, prevConnIn := net.Pipe()
-	suite.plugin.tcpConn = prevConnIn
+	suite.plugin.conn = prevConnIn
 	prevConnIn.Close()
 	prevConnOut.Close()
 
 	out, in := net.Pipe()
 	defer in.Close()
 	defer out.Close()
-	DialCall = func(network string, address string) (net.Conn, error) {
+	DialCall = func(dialer *net.Dialer, network string, address string) (net.Conn, error) {
 		return out, nil
 	}
 
-	suite.plugin.reconnectToPort = false
+	suite.plugin.reconnectToPort = true
+	suite.plugin.reconnectAttempts = 1
 
-	output := make([]byte, 100)
+	read := make(chan []byte)
 	go func() {
-		<-suite.plugin.reconnectToPortErr
-
-		time.Sleep(10 * time.Millisecond)
-		n, _ := out.Read(output)
-		assert.Equal(suite.T(), payload, output[:n])
+		output := make([]byte, 100)
+		n, _ := in.Read(output)
+		read <- output[:n]
 	}()
 
-	suite.plugin.InputStreamMessageHandler(suite.mockLog, getAgentMessage(uint32(mgsContracts.Output), payload))
+	// the handler returns without waiting for the reconnection
+	assert.NoError(suite.T(),
+		suite.plugin.InputStreamMessageHandler(suite.mockLog, getAgentMessage(uint32(mgsContracts.Output), payload)))
+	assert.NoError(suite.T(), <-suite.plugin.reconnectToPortErr)
+	assert.Equal(suite.T(), payload, <-read)
+	assert.Equal(suite.T(), false, suite.plugin.reconnectToPort)
+	assert.Equal(suite.T(), false, suite.plugin.reconnecting)
+	assert.Equal(suite.T(), uint64(1), suite.plugin.reconnectCount)
+}
+
+// Testing InputStreamHandler when the reconnection to port fails
+func (suite *PortTestSuite) TestInputStreamHandlerWhenReconnectionToPortFails() {
+	prevConnOut, prevConnIn := net.Pipe()
+	suite.plugin.conn = prevConnIn
+	prevConnIn.Close()
+	prevConnOut.Close()
+
+	dialed := make(chan bool)
+	DialCall = func(dialer *net.Dialer, network string, address string) (net.Conn, error) {
+		<-dialed
+		return nil, errors.New("connection refused")
+	}
+
+	suite.plugin.reconnectToPort = true
+	suite.plugin.reconnectAttempts = 2
+	suite.plugin.reconnectMaxDelay = 10
+
+	// the payloads received while reconnecting are queued without blocking the data channel
+	assert.NoError(suite.T(),
+		suite.plugin.InputStreamMessageHandler(suite.mockLog, getAgentMessage(uint32(mgsContracts.Output), payload)))
+	assert.NoError(suite.T(),
+		suite.plugin.InputStreamMessageHandler(suite.mockLog, getAgentMessage(uint32(mgsContracts.Output), payload)))
+	close(dialed)
+
+	assert.Error(suite.T(), <-suite.plugin.reconnectToPortErr)
 	assert.Equal(suite.T(), false, suite.plugin.reconnectToPort)
+	assert.Equal(suite.T(), false, suite.plugin.reconnecting)
+	assert.Empty(suite.T(), suite.plugin.pendingPayloads)
+	assert.Equal(suite.T(), uint64(0), suite.plugin.reconnectCount)
+
+	// once the reconnection failed, payloads are written to the closed connection instead of starting another reconnection
+	assert.Error(suite.T(),
+		suite.plugin.InputStreamMessageHandler(suite.mockLog, getAgentMessage(uint32(mgsContracts.Output), payload)))
+}
+
+// Testing handleTCPReadError returns when the session ends while waiting for reconnection
+func (suite *PortTestSuite) TestHandleTCPReadErrorWhenSessionEndsWhileWaitingForReconnection() {
+	out, in := net.Pipe()
+	defer in.Close()
+	defer out.Close()
+
+	suite.plugin.portType = mgsConfig.LocalPortForwarding
+	suite.plugin.conn = out
+	suite.plugin.sessionEnded = make(chan struct{})
+	close(suite.plugin.sessionEnded)
+
+	returnCode := suite.plugin.handleTCPReadError(suite.mockLog, errors.New("some error!!"))
+	assert.Equal(suite.T(), appconfig.ErrorExitCode, returnCode)
+}
+
+// Testing InputStreamHandler with connection statistics request flag
+func (suite *PortTestSuite) TestInputStreamHandlerWithConnectionStatisticsRequest() {
+	out, in := net.Pipe()
+	suite.plugin.conn = in
+	defer in.Close()
+	defer out.Close()
+
+	suite.plugin.sentBytes = 100
+	suite.plugin.receivedBytes = 50
+	suite.plugin.startTime = time.Now()
+	suite.mockDataChannel.On("GetRoundTripTime").Return(20 * time.Millisecond)
+	suite.mockDataChannel.On("SendStreamDataMessage", suite.mockLog, mgsContracts.ConnectionStatistics, mock.Anything).
+		Run(func(args mock.Arguments) {
+			var statistics mgsContracts.ConnectionStatisticsPayload
+			assert.NoError(suite.T(), json.Unmarshal(args.Get(2).([]byte), &statistics))
+			assert.Equal(suite.T(), uint64(100), statistics.BytesSent)
+			assert.Equal(suite.T(), uint64(50), statistics.BytesReceived)
+			assert.Equal(suite.T(), int64(20), statistics.RoundTripTimeMs)
+		}).Return(nil)
+
+	flagBuf := new(bytes.Buffer)
+	binary.Write(flagBuf, binary.BigEndian, mgsContracts.ConnectionStatisticsRequest)
+
+	assert.NoError(suite.T(),
+		suite.plugin.InputStreamMessageHandler(suite.mockLog, getAgentMessage(uint32(mgsContracts.Flag), flagBuf.Bytes())))
+	suite.mockDataChannel.AssertExpectations(suite.T())
 }
 
 // Execute the test suite
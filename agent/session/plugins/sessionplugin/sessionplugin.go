@@ -17,6 +17,7 @@ package sessionplugin
 import (
 	"fmt"
 	"math/rand"
+	"runtime/debug"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
@@ -27,6 +28,7 @@ import (
 	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/session/datachannel"
 	"github.com/aws/amazon-ssm-agent/agent/session/retry"
+	"github.com/aws/amazon-ssm-agent/agent/session/telemetry"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
 
@@ -51,7 +53,8 @@ func NewPlugin(newPluginFunc NewPluginFunc) (*SessionPlugin, error) {
 	return &SessionPlugin{sessionPlugin}, err
 }
 
-// Execute sets up datachannel and starts execution of session manager plugin like shell
+// Execute sets up datachannel and starts execution of session manager plugin like shell.
+// A panic in the plugin fails the session instead of crashing the session worker.
 func (p *SessionPlugin) Execute(context context.T,
 	config contracts.Configuration,
 	cancelFlag task.CancelFlag,
@@ -60,6 +63,18 @@ func (p *SessionPlugin) Execute(context context.T,
 	log := context.Log()
 	kmsKeyId := config.KmsKeyId
 
+	// registered first so that it runs after the data channel of the session has been closed
+	defer func() {
+		if msg := recover(); msg != nil {
+			errorString := fmt.Errorf("Session plugin %s crashed: %v", config.PluginName, msg)
+			output.MarkAsFailed(errorString)
+			log.Error(errorString)
+			stackTrace := debug.Stack()
+			log.Errorf("%s: %s", msg, stackTrace)
+			recordCrash(log, config.SessionId, config.PluginName, msg, stackTrace)
+		}
+	}()
+
 	dataChannel, err := getDataChannelForSessionPlugin(context, config.SessionId, config.ClientId, cancelFlag, p.sessionPlugin.InputStreamMessageHandler)
 	if err != nil {
 		errorString := fmt.Errorf("Setting up data channel with id %s failed: %s", config.SessionId, err)
@@ -68,6 +83,7 @@ func (p *SessionPlugin) Execute(context context.T,
 		return
 	}
 	defer dataChannel.Close(log)
+	dataChannel.SetStreamDataPayloadSize(datachannel.GetStreamDataPayloadSize(context.AppConfig().Mgs, config.PluginName))
 
 	if err = dataChannel.SendAgentSessionStateMessage(context.Log(), mgsContracts.Connected); err != nil {
 		log.Errorf("Unable to send AgentSessionState message with session status %s. %s", mgsContracts.Connected, err)
@@ -98,6 +114,9 @@ func (p *SessionPlugin) isEncryptionEnabled(kmsKeyId string, pluginName string)
 	return kmsKeyId != "" && pluginName != appconfig.PluginNamePort
 }
 
+// recordCrash reports a panic recovered in the plugin with the agent health
+var recordCrash = telemetry.RecordCrash
+
 // getDataChannelForSessionPlugin opens new data channel to MGS service
 var getDataChannelForSessionPlugin = func(context context.T, sessionId string, clientId string, cancelFlag task.CancelFlag, inputStreamMessageHandler datachannel.InputStreamMessageHandler) (datachannel.IDataChannel, error) {
 	retryer := retry.ExponentialRetryer{
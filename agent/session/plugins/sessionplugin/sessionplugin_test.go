@@ -27,7 +27,9 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/session/datachannel"
 	dataChannelMock "github.com/aws/amazon-ssm-agent/agent/session/datachannel/mocks"
 	sessionPluginMock "github.com/aws/amazon-ssm-agent/agent/session/plugins/sessionplugin/mocks"
+	"github.com/aws/amazon-ssm-agent/agent/session/telemetry"
 	"github.com/aws/amazon-ssm-agent/agent/task"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
@@ -69,6 +71,7 @@ func (suite *SessionPluginTestSuite) TestExecute() {
 		}
 	suite.mockDataChannel.On("SendAgentSessionStateMessage", suite.mockContext.Log(), mgsContracts.Connected).Return(nil)
 	suite.mockDataChannel.On("Close", suite.mockContext.Log()).Return(nil)
+	suite.mockDataChannel.On("SetStreamDataPayloadSize", mock.Anything).Return()
 	suite.mockSessionPlugin.On("Execute", suite.mockContext, mock.Anything, suite.mockCancelFlag, suite.mockIohandler, suite.mockDataChannel).Return()
 	suite.mockSessionPlugin.On("RequireHandshake").Return(false)
 	suite.mockSessionPlugin.On("GetPluginParameters", config.Properties).Return(nil)
@@ -93,6 +96,7 @@ func (suite *SessionPluginTestSuite) TestExecuteHandshakeEncryptionDisabled() {
 		}
 	suite.mockDataChannel.On("SendAgentSessionStateMessage", suite.mockContext.Log(), mgsContracts.Connected).Return(nil)
 	suite.mockDataChannel.On("Close", suite.mockContext.Log()).Return(nil)
+	suite.mockDataChannel.On("SetStreamDataPayloadSize", mock.Anything).Return()
 	suite.mockSessionPlugin.On("Execute", suite.mockContext, mock.Anything, suite.mockCancelFlag, suite.mockIohandler, suite.mockDataChannel).Return()
 	suite.mockSessionPlugin.On("RequireHandshake").Return(true)
 	suite.mockSessionPlugin.On("GetPluginParameters", config.Properties).Return(sessionProperties)
@@ -119,6 +123,7 @@ func (suite *SessionPluginTestSuite) TestExecuteHandshakeEncryptionEnabledPortPl
 		}
 	suite.mockDataChannel.On("SendAgentSessionStateMessage", suite.mockContext.Log(), mgsContracts.Connected).Return(nil)
 	suite.mockDataChannel.On("Close", suite.mockContext.Log()).Return(nil)
+	suite.mockDataChannel.On("SetStreamDataPayloadSize", mock.Anything).Return()
 	suite.mockSessionPlugin.On("Execute", suite.mockContext, mock.Anything, suite.mockCancelFlag, suite.mockIohandler, suite.mockDataChannel).Return()
 	suite.mockSessionPlugin.On("RequireHandshake").Return(true)
 	suite.mockSessionPlugin.On("GetPluginParameters", config.Properties).Return(sessionProperties)
@@ -144,6 +149,7 @@ func (suite *SessionPluginTestSuite) TestExecuteEncryptionHandshakeSuccess() {
 		}
 	suite.mockDataChannel.On("SendAgentSessionStateMessage", suite.mockContext.Log(), mgsContracts.Connected).Return(nil)
 	suite.mockDataChannel.On("Close", suite.mockContext.Log()).Return(nil)
+	suite.mockDataChannel.On("SetStreamDataPayloadSize", mock.Anything).Return()
 	suite.mockSessionPlugin.On("Execute", suite.mockContext, mock.Anything, suite.mockCancelFlag, suite.mockIohandler, suite.mockDataChannel).Return()
 	suite.mockSessionPlugin.On("RequireHandshake").Return(false)
 	suite.mockSessionPlugin.On("GetPluginParameters", config.Properties).Return(nil)
@@ -169,6 +175,7 @@ func (suite *SessionPluginTestSuite) TestExecuteEncryptionHandshakeFailed() {
 		}
 	suite.mockDataChannel.On("SendAgentSessionStateMessage", suite.mockContext.Log(), mgsContracts.Connected).Return(nil)
 	suite.mockDataChannel.On("Close", suite.mockContext.Log()).Return(nil)
+	suite.mockDataChannel.On("SetStreamDataPayloadSize", mock.Anything).Return()
 	suite.mockSessionPlugin.On("RequireHandshake").Return(false)
 	suite.mockSessionPlugin.On("GetPluginParameters", config.Properties).Return(nil)
 
@@ -184,3 +191,40 @@ func (suite *SessionPluginTestSuite) TestExecuteEncryptionHandshakeFailed() {
 	suite.mockDataChannel.AssertExpectations(suite.T())
 	suite.mockSessionPlugin.AssertExpectations(suite.T())
 }
+
+func (suite *SessionPluginTestSuite) TestExecutePluginPanic() {
+	config := contracts.Configuration{PluginName: appconfig.PluginNameStandardStream}
+
+	getDataChannelForSessionPlugin =
+		func(context context.T, sessionId string, clientId string, cancelFlag task.CancelFlag, inputStreamMessageHandler datachannel.InputStreamMessageHandler) (datachannel.IDataChannel, error) {
+			return suite.mockDataChannel, nil
+		}
+	suite.mockDataChannel.On("SendAgentSessionStateMessage", suite.mockContext.Log(), mgsContracts.Connected).Return(nil)
+	suite.mockDataChannel.On("Close", suite.mockContext.Log()).Return(nil)
+	suite.mockDataChannel.On("SetStreamDataPayloadSize", mock.Anything).Return()
+	suite.mockDataChannel.On("SkipHandshake", suite.mockContext.Log()).Return()
+	suite.mockSessionPlugin.On("RequireHandshake").Return(false)
+	suite.mockSessionPlugin.On("GetPluginParameters", config.Properties).Return(nil)
+	suite.mockSessionPlugin.On("Execute", suite.mockContext, mock.Anything, suite.mockCancelFlag, suite.mockIohandler, suite.mockDataChannel).
+		Run(func(args mock.Arguments) { panic("plugin failure") })
+	suite.mockIohandler.On("MarkAsFailed", mock.Anything).Return()
+	var crashes []interface{}
+	recordCrash = func(log log.T, sessionId string, pluginName string, msg interface{}, stackTrace []byte) {
+		assert.Equal(suite.T(), appconfig.PluginNameStandardStream, pluginName)
+		assert.NotEmpty(suite.T(), stackTrace)
+		crashes = append(crashes, msg)
+	}
+	defer func() { recordCrash = telemetry.RecordCrash }()
+
+	suite.NotPanics(func() {
+		suite.sessionPlugin.Execute(suite.mockContext,
+			config,
+			suite.mockCancelFlag,
+			suite.mockIohandler)
+	})
+
+	suite.mockDataChannel.AssertExpectations(suite.T())
+	suite.mockSessionPlugin.AssertExpectations(suite.T())
+	suite.mockIohandler.AssertCalled(suite.T(), "MarkAsFailed", mock.Anything)
+	assert.Equal(suite.T(), []interface{}{"plugin failure"}, crashes)
+}
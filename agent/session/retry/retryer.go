@@ -16,6 +16,7 @@ package retry
 
 import (
 	"math"
+	"math/rand"
 	"time"
 )
 
@@ -31,6 +32,12 @@ type ExponentialRetryer struct {
 	InitialDelayInMilli int
 	MaxDelayInMilli     int
 	MaxAttempts         int
+	// JitterRatio reduces each delay by a random fraction of up to JitterRatio, from 0 to 1,
+	// so that agents disconnected at the same time do not retry in sync
+	JitterRatio float64
+	// Deadline stops the retries once it is reached, delays are shortened so that no retry starts after it.
+	// The zero value retries until MaxAttempts.
+	Deadline time.Time
 }
 
 // NextSleepTime calculates the next delay of retry.
@@ -53,7 +60,25 @@ func (retryer *ExponentialRetryer) Call() (channel interface{}, err error) {
 		} else {
 			attempt++
 		}
+		sleep = retryer.jitter(sleep)
+		if !retryer.Deadline.IsZero() {
+			remaining := time.Until(retryer.Deadline)
+			if remaining <= 0 {
+				return channel, err
+			}
+			if sleep > remaining {
+				sleep = remaining
+			}
+		}
 		time.Sleep(sleep)
 		failedAttemptsSoFar++
 	}
 }
+
+// jitter reduces the delay by a random fraction of up to JitterRatio.
+func (retryer *ExponentialRetryer) jitter(sleep time.Duration) time.Duration {
+	if retryer.JitterRatio <= 0 {
+		return sleep
+	}
+	return sleep - time.Duration(rand.Float64()*math.Min(retryer.JitterRatio, 1)*float64(sleep))
+}
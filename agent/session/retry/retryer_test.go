@@ -17,6 +17,7 @@ package retry
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -44,6 +45,8 @@ func TestRepeatableExponentialRetryerRetriesForGivenNumberOfMaxAttempts(t *testi
 		initialDelayInMilli,
 		maxDelayInMilli,
 		maxAttempts,
+		0,
+		time.Time{},
 	}
 
 	retryCounterInterface, err := retryer.Call()
@@ -52,3 +55,36 @@ func TestRepeatableExponentialRetryerRetriesForGivenNumberOfMaxAttempts(t *testi
 	assert.NotNil(t, err)
 	assert.Equal(t, retryCounter.TotalAttempts, maxAttempts+1)
 }
+
+func TestExponentialRetryerJitter(t *testing.T) {
+	retryer := ExponentialRetryer{JitterRatio: 0.5}
+	for i := 0; i < 100; i++ {
+		sleep := retryer.jitter(time.Second)
+		assert.True(t, sleep > 500*time.Millisecond && sleep <= time.Second)
+	}
+
+	retryer.JitterRatio = 0
+	assert.Equal(t, time.Second, retryer.jitter(time.Second))
+}
+
+func TestExponentialRetryerStopsAtDeadline(t *testing.T) {
+	attempts := 0
+	retryer := ExponentialRetryer{
+		CallableFunc: func() (interface{}, error) {
+			attempts++
+			return nil, errors.New("error occured in callable function")
+		},
+		GeometricRatio:      retryGeometricRatio,
+		InitialDelayInMilli: 1000,
+		MaxDelayInMilli:     10000,
+		MaxAttempts:         100,
+		Deadline:            time.Now().Add(50 * time.Millisecond),
+	}
+
+	start := time.Now()
+	_, err := retryer.Call()
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.True(t, time.Since(start) < time.Second)
+}
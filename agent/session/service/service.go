@@ -92,13 +92,18 @@ func NewService(log log.T, mgsConfig appconfig.MgsConfig, connectionTimeout time
 	}
 
 	// capture Transport so we can use it to cancel requests
+	dialer := &net.Dialer{
+		Timeout:   connectionTimeout,
+		KeepAlive: 0,
+	}
+	network := mgsconfig.GetDialNetwork(mgsConfig.IPAddressPreference)
 	tr := &http.Transport{
 		Proxy: http.ProxyFromEnvironment,
-		Dial: (&net.Dialer{
-			Timeout:   connectionTimeout,
-			KeepAlive: 0,
-		}).Dial,
+		Dial: func(_, address string) (net.Conn, error) {
+			return dialer.Dial(network, address)
+		},
 		TLSHandshakeTimeout: 10 * time.Second,
+		TLSClientConfig:     mgsconfig.GetEndpointTLSConfig(mgsConfig),
 	}
 
 	return &MessageGatewayService{
@@ -109,7 +114,7 @@ func NewService(log log.T, mgsConfig appconfig.MgsConfig, connectionTimeout time
 }
 
 // makeRestcall triggers rest api call.
-var makeRestcall = func(request []byte, methodType string, url string, region string, signer *v4.Signer) ([]byte, error) {
+var makeRestcall = func(request []byte, methodType string, url string, region string, signer *v4.Signer, tr *http.Transport) ([]byte, error) {
 	httpRequest, err := http.NewRequest(methodType, url, bytes.NewBuffer(request))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create http request: %s", err)
@@ -122,7 +127,8 @@ var makeRestcall = func(request []byte, methodType string, url string, region st
 	}
 
 	client := &http.Client{
-		Timeout: mgsClientTimeout,
+		Timeout:   mgsClientTimeout,
+		Transport: tr,
 	}
 
 	resp, err := client.Do(httpRequest)
@@ -203,7 +209,7 @@ func (mgsService *MessageGatewayService) CreateControlChannel(log log.T, createC
 		return nil, errors.New("unable to marshal the createControlChannelInput")
 	}
 
-	resp, err := makeRestcall(jsonValue, "POST", url, mgsService.region, mgsService.signer)
+	resp, err := makeRestcall(jsonValue, "POST", url, mgsService.region, mgsService.signer, mgsService.tr)
 	if err != nil {
 		return nil, fmt.Errorf("createControlChannel request failed: %s", err)
 	}
@@ -235,7 +241,7 @@ func (mgsService *MessageGatewayService) CreateDataChannel(log log.T, createData
 		return nil, errors.New("unable to marshal the createDataChannelInput")
 	}
 
-	resp, err := makeRestcall(jsonValue, "POST", url, mgsService.region, mgsService.signer)
+	resp, err := makeRestcall(jsonValue, "POST", url, mgsService.region, mgsService.signer, mgsService.tr)
 	if err != nil {
 		return nil, fmt.Errorf("createDataChannel request failed: %s", err)
 	}
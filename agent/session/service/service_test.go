@@ -16,6 +16,7 @@ package service
 
 import (
 	"encoding/xml"
+	"net/http"
 	"testing"
 
 	"github.com/aws/amazon-ssm-agent/agent/log"
@@ -62,7 +63,7 @@ func TestCreateControlChannel(t *testing.T) {
 	mgsConfig.GetMgsEndpointFromRip = func(region string) string {
 		return mgsHost
 	}
-	makeRestcall = func(request []byte, methodType string, url string, region string, signer *v4.Signer) ([]byte, error) {
+	makeRestcall = func(request []byte, methodType string, url string, region string, signer *v4.Signer, tr *http.Transport) ([]byte, error) {
 		output := &CreateControlChannelOutput{
 			TokenValue:           aws.String(token),
 			MessageSchemaVersion: aws.String(mgsConfig.MessageSchemaVersion),
@@ -85,7 +86,7 @@ func TestCreateDataChannel(t *testing.T) {
 	mgsConfig.GetMgsEndpointFromRip = func(region string) string {
 		return mgsHost
 	}
-	makeRestcall = func(request []byte, methodType string, url string, region string, signer *v4.Signer) ([]byte, error) {
+	makeRestcall = func(request []byte, methodType string, url string, region string, signer *v4.Signer, tr *http.Transport) ([]byte, error) {
 		output := &CreateDataChannelOutput{
 			TokenValue:           aws.String(token),
 			MessageSchemaVersion: aws.String(mgsConfig.MessageSchemaVersion),
@@ -18,7 +18,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"math/rand"
 	"runtime/debug"
 	"time"
 
@@ -33,7 +32,6 @@ import (
 	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
 	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/session/controlchannel"
-	"github.com/aws/amazon-ssm-agent/agent/session/retry"
 	"github.com/aws/amazon-ssm-agent/agent/session/service"
 	"github.com/gorilla/websocket"
 	"github.com/twinj/uuid"
@@ -131,25 +129,19 @@ func (s *Session) ModuleName() string {
 }
 
 var setupControlChannel = func(context context.T, service service.Service, processor processor.Processor, instanceId string) (controlchannel.IControlChannel, error) {
-	retryer := retry.ExponentialRetryer{
-		CallableFunc: func() (channel interface{}, err error) {
-			controlChannel := &controlchannel.ControlChannel{}
-			controlChannel.Initialize(context, service, processor, instanceId)
-			if err := controlChannel.SetWebSocket(context, service, processor, instanceId); err != nil {
-				return nil, err
-			}
+	retryer := mgsConfig.NewControlChannelRetryer(context.AppConfig().Mgs, func() (channel interface{}, err error) {
+		controlChannel := &controlchannel.ControlChannel{}
+		controlChannel.Initialize(context, service, processor, instanceId)
+		if err := controlChannel.SetWebSocket(context, service, processor, instanceId); err != nil {
+			return nil, err
+		}
 
-			if err := controlChannel.Open(context.Log()); err != nil {
-				return nil, err
-			}
+		if err := controlChannel.Open(context.Log()); err != nil {
+			return nil, err
+		}
 
-			return controlChannel, nil
-		},
-		GeometricRatio:      mgsConfig.RetryGeometricRatio,
-		InitialDelayInMilli: rand.Intn(mgsConfig.ControlChannelRetryInitialDelayMillis) + mgsConfig.ControlChannelRetryInitialDelayMillis,
-		MaxDelayInMilli:     mgsConfig.ControlChannelRetryMaxIntervalMillis,
-		MaxAttempts:         mgsConfig.ControlChannelNumMaxRetries,
-	}
+		return controlChannel, nil
+	})
 
 	channel, err := retryer.Call()
 	if err != nil {
@@ -0,0 +1,121 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package sessionlimit enforces limits on the number of concurrent sessions of a given category.
+// Sessions run in separate worker processes, so active sessions are tracked with marker files
+// that record the process id of the owning session worker.
+package sessionlimit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil/filelock"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/proc"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	lockFileName = ".lock"
+
+	// lockTimeoutSeconds is the time after which a lock left behind by a crashed worker is expired
+	lockTimeoutSeconds = 10
+	lockAttempts       = 5
+)
+
+var sessionLimitRoot = filepath.Join(appconfig.DefaultDataStorePath, "sessionlimits")
+
+var isProcessExists = func(log log.T, pid int) bool {
+	return proc.IsProcessExists(log, pid, time.Time{})
+}
+
+// ErrLimitReached is returned by Acquire when the number of active sessions reached the limit
+var ErrLimitReached = errors.New("limit of concurrent sessions reached")
+
+// Acquire registers the session in the given category if fewer than limit sessions of that category are active.
+// A limit less than or equal to zero means unlimited. The returned release function unregisters the session.
+func Acquire(log log.T, category string, sessionId string, limit int) (release func(), err error) {
+	release = func() {}
+	if limit <= 0 {
+		return release, nil
+	}
+
+	categoryDir := filepath.Join(sessionLimitRoot, category)
+	if err = fileutil.MakeDirs(categoryDir); err != nil {
+		return release, fmt.Errorf("unable to create session limit directory %s: %v", categoryDir, err)
+	}
+
+	lockPath := filepath.Join(categoryDir, lockFileName)
+	ownerId := filelock.GetOwnerIdForProcess()
+	var locked bool
+	if locked, err = filelock.LockFileWithRetry(lockPath, ownerId, lockTimeoutSeconds, lockAttempts); err != nil {
+		return release, err
+	}
+	if !locked {
+		return release, fmt.Errorf("unable to acquire session limit lock %s", lockPath)
+	}
+	defer filelock.UnlockFile(lockPath, ownerId)
+
+	active, err := countActiveSessions(log, categoryDir)
+	if err != nil {
+		return release, err
+	}
+	if active >= limit {
+		log.Infof("%d %s sessions are active, limit is %d", active, category, limit)
+		return release, ErrLimitReached
+	}
+
+	markerPath := filepath.Join(categoryDir, sessionId)
+	if err = fileutil.WriteAllText(markerPath, strconv.Itoa(os.Getpid())); err != nil {
+		return release, fmt.Errorf("unable to register session %s: %v", sessionId, err)
+	}
+
+	return func() {
+		if err := os.Remove(markerPath); err != nil && !os.IsNotExist(err) {
+			log.Warnf("Unable to unregister session %s: %v", sessionId, err)
+		}
+	}, nil
+}
+
+// countActiveSessions counts the sessions registered in the directory whose worker process is alive,
+// removing the markers of sessions whose worker exited without unregistering
+func countActiveSessions(log log.T, categoryDir string) (active int, err error) {
+	var fileNames []string
+	if fileNames, err = fileutil.GetFileNames(categoryDir); err != nil {
+		return 0, fmt.Errorf("unable to list sessions in %s: %v", categoryDir, err)
+	}
+
+	for _, fileName := range fileNames {
+		if fileName == lockFileName {
+			continue
+		}
+		markerPath := filepath.Join(categoryDir, fileName)
+		content, err := fileutil.ReadAllText(markerPath)
+		if err != nil {
+			continue
+		}
+		if pid, err := strconv.Atoi(content); err == nil && isProcessExists(log, pid) {
+			active++
+			continue
+		}
+		log.Debugf("Removing stale session marker %s", markerPath)
+		os.Remove(markerPath)
+	}
+	return active, nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package sessionlimit enforces limits on the number of concurrent sessions of a given category.
+package sessionlimit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var mockLog = log.NewMockLog()
+
+func setupSessionLimitRoot(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "sessionlimit")
+	assert.NoError(t, err)
+	sessionLimitRoot = dir
+	isProcessExists = func(log log.T, pid int) bool {
+		return pid == os.Getpid()
+	}
+	return func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestAcquireUnlimited(t *testing.T) {
+	defer setupSessionLimitRoot(t)()
+
+	release, err := Acquire(mockLog, "port", "session1", 0)
+	assert.NoError(t, err)
+	release()
+
+	_, err = os.Stat(filepath.Join(sessionLimitRoot, "port"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestAcquireLimitReached(t *testing.T) {
+	defer setupSessionLimitRoot(t)()
+
+	release, err := Acquire(mockLog, "port", "session1", 1)
+	assert.NoError(t, err)
+
+	_, err = Acquire(mockLog, "port", "session2", 1)
+	assert.Equal(t, ErrLimitReached, err)
+
+	release()
+	release, err = Acquire(mockLog, "port", "session2", 1)
+	assert.NoError(t, err)
+	release()
+}
+
+func TestAcquireIgnoresStaleSessions(t *testing.T) {
+	defer setupSessionLimitRoot(t)()
+
+	categoryDir := filepath.Join(sessionLimitRoot, "ssh")
+	assert.NoError(t, os.MkdirAll(categoryDir, 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(categoryDir, "staleSession"), []byte("-1"), 0600))
+
+	release, err := Acquire(mockLog, "ssh", "session1", 1)
+	assert.NoError(t, err)
+	release()
+
+	_, err = os.Stat(filepath.Join(categoryDir, "staleSession"))
+	assert.True(t, os.IsNotExist(err))
+}
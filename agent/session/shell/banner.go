@@ -0,0 +1,98 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+)
+
+// sessionBanner returns the banner configured for interactive sessions.
+// The banner file takes precedence over the banner text, which is used if the file cannot be read.
+func sessionBanner(log log.T, mgs appconfig.MgsConfig) string {
+	if path := strings.TrimSpace(mgs.SessionBannerFile); path != "" {
+		banner, err := readBannerFile(path)
+		if err == nil {
+			return banner
+		}
+		log.Warnf("Unable to read session banner file %s: %v", path, err)
+	}
+	return mgs.SessionBanner
+}
+
+// readBannerFile reads at most SessionBannerMaxSize bytes of the banner file
+func readBannerFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	banner := make([]byte, mgsConfig.SessionBannerMaxSize)
+	n, err := io.ReadFull(file, banner)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	return string(banner[:n]), nil
+}
+
+// renderBanner renders the banner as plain terminal text.
+// Line endings are converted for the terminal and other control characters, which could inject
+// terminal escape sequences, are removed.
+func renderBanner(banner string) string {
+	if len(banner) > mgsConfig.SessionBannerMaxSize {
+		banner = banner[:mgsConfig.SessionBannerMaxSize]
+	}
+	banner = strings.Replace(banner, "\r\n", "\n", -1)
+
+	var rendered bytes.Buffer
+	for _, r := range banner {
+		switch {
+		case r == '\n':
+			rendered.WriteString("\r\n")
+		case r == '\t' || !unicode.IsControl(r):
+			rendered.WriteRune(r)
+		}
+	}
+	if text := rendered.String(); strings.TrimSpace(text) != "" {
+		if !strings.HasSuffix(text, "\r\n") {
+			text += "\r\n"
+		}
+		return text
+	}
+	return ""
+}
+
+// showBanner writes the configured banner to the terminal of interactive sessions before the prompt
+func (p *ShellPlugin) showBanner(log log.T, mgs appconfig.MgsConfig) {
+	if p.name != appconfig.PluginNameStandardStream {
+		return
+	}
+	banner := renderBanner(sessionBanner(log, mgs))
+	if banner == "" {
+		return
+	}
+	if err := p.dataChannel.SendStreamDataMessage(log, mgsContracts.Output, []byte(banner)); err != nil {
+		log.Warnf("Unable to show session banner: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+	dataChannelMock "github.com/aws/amazon-ssm-agent/agent/session/datachannel/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRenderBanner(t *testing.T) {
+	assert.Equal(t, "Authorized use only.\r\nHost: prod-db\r\n", renderBanner("Authorized use only.\nHost: prod-db"))
+	assert.Equal(t, "line\r\n", renderBanner("line\r\n"))
+	assert.Equal(t, "[31mred\tdone\r\n", renderBanner("\x1b[31mred\tdone\x07"))
+	assert.Equal(t, "", renderBanner(" \n "))
+	assert.Equal(t, mgsConfig.SessionBannerMaxSize+2, len(renderBanner(strings.Repeat("a", 2*mgsConfig.SessionBannerMaxSize))))
+}
+
+func TestSessionBanner(t *testing.T) {
+	file, err := ioutil.TempFile("", "banner")
+	assert.NoError(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString("from file")
+	file.Close()
+
+	assert.Equal(t, "from file", sessionBanner(mockLog, appconfig.MgsConfig{SessionBanner: "text", SessionBannerFile: file.Name()}))
+	assert.Equal(t, "text", sessionBanner(mockLog, appconfig.MgsConfig{SessionBanner: "text", SessionBannerFile: file.Name() + ".missing"}))
+	assert.Equal(t, "text", sessionBanner(mockLog, appconfig.MgsConfig{SessionBanner: "text"}))
+}
+
+func TestShowBanner(t *testing.T) {
+	mockDataChannel := &dataChannelMock.IDataChannel{}
+	mockDataChannel.On("SendStreamDataMessage", mock.Anything, mgsContracts.Output, []byte("Welcome\r\n")).Return(nil)
+	mgs := appconfig.MgsConfig{SessionBanner: "Welcome"}
+
+	plugin := &ShellPlugin{name: appconfig.PluginNameStandardStream, dataChannel: mockDataChannel}
+	plugin.showBanner(mockLog, mgs)
+	mockDataChannel.AssertNumberOfCalls(t, "SendStreamDataMessage", 1)
+
+	// the banner is not shown to sessions running commands
+	plugin = &ShellPlugin{name: appconfig.PluginNameInteractiveCommands, dataChannel: mockDataChannel}
+	plugin.showBanner(mockLog, mgs)
+	mockDataChannel.AssertNumberOfCalls(t, "SendStreamDataMessage", 1)
+}
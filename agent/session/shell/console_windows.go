@@ -0,0 +1,69 @@
+// Copyright 2018 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build windows
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+const utf8CodePage = 65001
+
+// localePattern matches culture names such as en-US, zh-Hans-CN or de-DE-1996
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// consoleSetup returns the command setting the console code page and locale of an interactive session,
+// so that non-ASCII output of the shell and of native programs is not garbled in the client.
+// The locale is only applied to PowerShell as cmd has no per session culture.
+func consoleSetup(mgs appconfig.MgsConfig, shell string) string {
+	codePage := mgs.SessionWindowsCodePage
+	if isCmdShell(shell) {
+		if codePage <= 0 {
+			return ""
+		}
+		return fmt.Sprintf("chcp %d > nul", codePage)
+	}
+
+	var commands []string
+	if codePage > 0 {
+		encoding := fmt.Sprintf("[System.Text.Encoding]::GetEncoding(%d)", codePage)
+		if codePage == utf8CodePage {
+			// UTF-8 without byte order mark, which would otherwise be sent to the input of native programs
+			encoding = "(New-Object System.Text.UTF8Encoding $false)"
+		}
+		commands = append(commands,
+			fmt.Sprintf("$null = chcp.com %d", codePage),
+			fmt.Sprintf("[Console]::InputEncoding = [Console]::OutputEncoding = $OutputEncoding = %s", encoding))
+	}
+	if locale := strings.TrimSpace(mgs.SessionWindowsLocale); locale != "" {
+		if localePattern.MatchString(locale) {
+			commands = append(commands, fmt.Sprintf(
+				"[System.Threading.Thread]::CurrentThread.CurrentCulture = [System.Threading.Thread]::CurrentThread.CurrentUICulture = '%s'", locale))
+		}
+	}
+	return strings.Join(commands, "; ")
+}
+
+// isCmdShell returns true if the session shell is the command prompt
+func isCmdShell(shell string) bool {
+	name := strings.ToLower(filepath.Base(shell))
+	return name == "cmd" || name == "cmd.exe"
+}
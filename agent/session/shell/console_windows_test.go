@@ -0,0 +1,41 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build windows
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsoleSetup(t *testing.T) {
+	utf8 := appconfig.MgsConfig{SessionWindowsCodePage: 65001}
+	assert.Equal(t, "$null = chcp.com 65001; "+
+		"[Console]::InputEncoding = [Console]::OutputEncoding = $OutputEncoding = (New-Object System.Text.UTF8Encoding $false)",
+		consoleSetup(utf8, ""))
+	assert.Equal(t, "chcp 65001 > nul", consoleSetup(utf8, `C:\Windows\System32\cmd.exe`))
+
+	localized := appconfig.MgsConfig{SessionWindowsCodePage: 932, SessionWindowsLocale: "ja-JP"}
+	assert.Equal(t, "$null = chcp.com 932; "+
+		"[Console]::InputEncoding = [Console]::OutputEncoding = $OutputEncoding = [System.Text.Encoding]::GetEncoding(932); "+
+		"[System.Threading.Thread]::CurrentThread.CurrentCulture = [System.Threading.Thread]::CurrentThread.CurrentUICulture = 'ja-JP'",
+		consoleSetup(localized, "pwsh"))
+
+	assert.Equal(t, "", consoleSetup(appconfig.MgsConfig{}, ""))
+	assert.Equal(t, "", consoleSetup(appconfig.MgsConfig{SessionWindowsLocale: "en-US'; Remove-Item"}, ""))
+}
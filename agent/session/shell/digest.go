@@ -0,0 +1,170 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	agentContracts "github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	commandDigestAuditEvent = "CommandDigest"
+
+	// commandDigestKeySize is the size in bytes of generated keys, shorter configured keys are refused
+	commandDigestKeySize = 32
+)
+
+// defaultCommandDigestKeyFile holds the command digest key when no key file is configured
+var defaultCommandDigestKeyFile = filepath.Join(appconfig.DefaultDataStorePath, "session", "command_digest.key")
+
+// commandDigester appends an HMAC-SHA256 digest of each command line typed in the session to the audit log.
+// Auditors holding the key can prove which commands were typed without the audit log storing the keystrokes.
+type commandDigester struct {
+	key       []byte
+	editor    lineEditor
+	sessionId string
+	clientId  string
+	runAsUser string
+}
+
+// newCommandDigester returns a digester of the session commands if command digests are enabled, nil otherwise
+func newCommandDigester(log log.T, mgs appconfig.MgsConfig, config agentContracts.Configuration, runAsUser string) (*commandDigester, error) {
+	switch mgs.SessionCommandDigestMode {
+	case appconfig.SessionCommandDigestWithTranscripts, appconfig.SessionCommandDigestOnly:
+	case "":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown command digest mode %s", mgs.SessionCommandDigestMode)
+	}
+
+	keyFile := mgs.SessionCommandDigestKeyFile
+	if keyFile == "" {
+		keyFile = defaultCommandDigestKeyFile
+	}
+	key, err := loadCommandDigestKey(log, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load command digest key: %v", err)
+	}
+	return &commandDigester{
+		key:       key,
+		sessionId: config.SessionId,
+		clientId:  config.ClientId,
+		runAsUser: runAsUser,
+	}, nil
+}
+
+// loadCommandDigestKey reads the hex encoded key from the file, generating a new key if the file does not exist
+func loadCommandDigestKey(log log.T, keyFile string) ([]byte, error) {
+	if err := createCommandDigestKey(log, keyFile); err != nil && !os.IsExist(err) {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil, fmt.Errorf("key file %s is not hex encoded", keyFile)
+	}
+	if len(key) < commandDigestKeySize/2 {
+		return nil, fmt.Errorf("key in %s is shorter than %d bytes", keyFile, commandDigestKeySize/2)
+	}
+	return key, nil
+}
+
+// createCommandDigestKey writes a random key to the file, failing if the file already exists
+func createCommandDigestKey(log log.T, keyFile string) (err error) {
+	if err = fileutil.MakeDirs(filepath.Dir(keyFile)); err != nil {
+		return
+	}
+
+	var file *os.File
+	if file, err = os.OpenFile(keyFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY, appconfig.ReadWriteAccess); err != nil {
+		return
+	}
+	key := make([]byte, commandDigestKeySize)
+	if _, err = rand.Read(key); err == nil {
+		_, err = file.WriteString(hex.EncodeToString(key))
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(keyFile)
+		return
+	}
+
+	log.Infof("Generated command digest key %s", keyFile)
+	return fileutil.Harden(keyFile)
+}
+
+// process reconstructs the command lines from the keystrokes and logs the digest of each completed line
+func (d *commandDigester) process(log log.T, data []byte) {
+	for _, b := range data {
+		if b == ctrlCByte {
+			d.editor.take()
+			continue
+		}
+		if d.editor.edit(b) != lineCompleted {
+			continue
+		}
+		if command := strings.TrimSpace(d.editor.take()); command != "" {
+			d.writeAuditRecord(log, command)
+		}
+	}
+}
+
+// digest returns the hex encoded HMAC-SHA256 of the command
+func (d *commandDigester) digest(command string) string {
+	mac := hmac.New(sha256.New, d.key)
+	mac.Write([]byte(command))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// writeAuditRecord appends the digest of the command to the local audit file
+func (d *commandDigester) writeAuditRecord(log log.T, command string) {
+	record := shellAuditRecord{
+		SessionId: d.sessionId,
+		ClientId:  d.clientId,
+		RunAsUser: d.runAsUser,
+		Event:     commandDigestAuditEvent,
+		Digest:    d.digest(command),
+		Time:      time.Now().UTC().Format(time.RFC3339),
+	}
+
+	content, err := jsonutil.Marshal(record)
+	if err != nil {
+		log.Errorf("Unable to marshal shell audit record: %v", err)
+		return
+	}
+	if err = appendAuditRecord(content); err != nil {
+		log.Errorf("Unable to write shell audit record: %v", err)
+	}
+}
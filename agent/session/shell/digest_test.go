@@ -0,0 +1,105 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func expectedDigest(key []byte, command string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(command))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestCommandDigesterLogsTypedCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shellaudit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	auditLogDir = dir
+
+	mgs := appconfig.MgsConfig{
+		SessionCommandDigestMode:    appconfig.SessionCommandDigestOnly,
+		SessionCommandDigestKeyFile: filepath.Join(dir, "keys", "command_digest.key"),
+	}
+	digester, err := newCommandDigester(log.NewMockLog(), mgs,
+		contracts.Configuration{SessionId: "session-id", ClientId: "client-id"}, "ssm-user")
+	assert.NoError(t, err)
+
+	// the arrow key and the erased character are not part of the command, the interrupted line is discarded
+	digester.process(log.NewMockLog(), []byte("ls -lx\x7f\x1b[A"))
+	digester.process(log.NewMockLog(), []byte("a\r\r  \rrm -rf /\x03whoami\r"))
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, auditLogFileName))
+	assert.NoError(t, err)
+	assert.False(t, strings.Contains(string(content), "ls -la"))
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Equal(t, 2, len(lines))
+
+	var record shellAuditRecord
+	assert.NoError(t, jsonutil.Unmarshal(lines[0], &record))
+	assert.Equal(t, "session-id", record.SessionId)
+	assert.Equal(t, "ssm-user", record.RunAsUser)
+	assert.Equal(t, commandDigestAuditEvent, record.Event)
+	assert.Equal(t, "", record.Command)
+	assert.Equal(t, expectedDigest(digester.key, "ls -la"), record.Digest)
+	assert.NoError(t, jsonutil.Unmarshal(lines[1], &record))
+	assert.Equal(t, expectedDigest(digester.key, "whoami"), record.Digest)
+}
+
+func TestLoadCommandDigestKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "digestkey")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "command_digest.key")
+
+	// the generated key is reused by the following sessions
+	key, err := loadCommandDigestKey(log.NewMockLog(), keyFile)
+	assert.NoError(t, err)
+	assert.Equal(t, commandDigestKeySize, len(key))
+	reloaded, err := loadCommandDigestKey(log.NewMockLog(), keyFile)
+	assert.NoError(t, err)
+	assert.Equal(t, key, reloaded)
+
+	assert.NoError(t, ioutil.WriteFile(keyFile, []byte("00112233"), 0600))
+	_, err = loadCommandDigestKey(log.NewMockLog(), keyFile)
+	assert.Error(t, err)
+
+	assert.NoError(t, ioutil.WriteFile(keyFile, []byte("not hex"), 0600))
+	_, err = loadCommandDigestKey(log.NewMockLog(), keyFile)
+	assert.Error(t, err)
+}
+
+func TestNewCommandDigesterModes(t *testing.T) {
+	digester, err := newCommandDigester(log.NewMockLog(), appconfig.MgsConfig{}, contracts.Configuration{}, "")
+	assert.NoError(t, err)
+	assert.Nil(t, digester)
+
+	_, err = newCommandDigester(log.NewMockLog(), appconfig.MgsConfig{SessionCommandDigestMode: "Keystrokes"}, contracts.Configuration{}, "")
+	assert.Error(t, err)
+}
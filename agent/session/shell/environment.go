@@ -0,0 +1,78 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// sessionEnvironmentVariables returns the environment variables of the session document the shell is started with.
+// Restricted command mode ignores them, as they could change what the allowed commands run.
+func sessionEnvironmentVariables(log log.T, mgsConfig appconfig.MgsConfig, envVars map[string]string) map[string]string {
+	if mgsConfig.SessionRestrictedCommandsEnabled {
+		if len(envVars) > 0 {
+			log.Warnf("Ignoring the environment variables of the session document in restricted command mode")
+		}
+		return make(map[string]string)
+	}
+	return filterEnvironmentVariables(log, envVars, mgsConfig.SessionEnvironmentVariablesDenylist)
+}
+
+// filterEnvironmentVariables removes the variables that are denied or whose names are invalid
+func filterEnvironmentVariables(log log.T, envVars map[string]string, denylist []string) map[string]string {
+	filtered := make(map[string]string)
+	for name, value := range envVars {
+		if name == "" || strings.ContainsAny(name, "=\x00") || strings.ContainsRune(value, '\x00') {
+			log.Warnf("Ignoring invalid environment variable %q", name)
+			continue
+		}
+		if deniedName(denylist, name) {
+			log.Warnf("Ignoring environment variable %s which sessions are not permitted to set", name)
+			continue
+		}
+		filtered[name] = value
+	}
+	return filtered
+}
+
+// environmentVariables returns the variables as NAME=value pairs sorted by name
+func environmentVariables(envVars map[string]string) []string {
+	env := make([]string, 0, len(envVars))
+	for name, value := range envVars {
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// deniedName returns true if the denylist contains the name ignoring case, or a prefix of the name followed by *
+func deniedName(denylist []string, name string) bool {
+	for _, denied := range denylist {
+		denied = strings.TrimSpace(denied)
+		if strings.HasSuffix(denied, "*") {
+			prefix := strings.TrimSuffix(denied, "*")
+			if len(name) >= len(prefix) && strings.EqualFold(name[:len(prefix)], prefix) {
+				return true
+			}
+		} else if strings.EqualFold(denied, name) {
+			return true
+		}
+	}
+	return false
+}
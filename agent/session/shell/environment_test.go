@@ -0,0 +1,55 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterEnvironmentVariables(t *testing.T) {
+	envVars := map[string]string{
+		"STAGE":           "prod",
+		"ld_preload":      "/tmp/evil.so",
+		"PATH":            "/tmp/evil:/usr/bin",
+		"BASH_FUNC_ls%%":  "() { /tmp/evil; }",
+		"bash_func_cat()": "() { /tmp/evil; }",
+		"BASH_FUNCTIONS":  "value",
+		"BAD=NAME":        "value",
+		"":                "value",
+	}
+
+	filtered := filterEnvironmentVariables(log.NewMockLog(), envVars, appconfig.DefaultSessionEnvironmentVariablesDenylist)
+	assert.Equal(t, map[string]string{"STAGE": "prod", "BASH_FUNCTIONS": "value"}, filtered)
+}
+
+func TestSessionEnvironmentVariablesRestricted(t *testing.T) {
+	envVars := map[string]string{"STAGE": "prod"}
+
+	mgsConfig := appconfig.MgsConfig{SessionEnvironmentVariablesDenylist: appconfig.DefaultSessionEnvironmentVariablesDenylist}
+	assert.Equal(t, envVars, sessionEnvironmentVariables(log.NewMockLog(), mgsConfig, envVars))
+
+	// restricted command mode drops the document environment variables entirely
+	mgsConfig.SessionRestrictedCommandsEnabled = true
+	assert.Equal(t, map[string]string{}, sessionEnvironmentVariables(log.NewMockLog(), mgsConfig, envVars))
+}
+
+func TestEnvironmentVariables(t *testing.T) {
+	env := environmentVariables(map[string]string{"STAGE": "prod", "REGION": "us-east-1"})
+	assert.Equal(t, []string{"REGION=us-east-1", "STAGE=prod"}, env)
+}
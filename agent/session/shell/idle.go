@@ -0,0 +1,78 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+)
+
+// idleCheckInterval is the interval at which the session is checked for inactivity
+var idleCheckInterval = mgsConfig.IdleTimeoutCheckInterval
+
+// updateLastInputTime records that the user sent keyboard input
+func (p *ShellPlugin) updateLastInputTime() {
+	atomic.StoreInt64(&p.lastInputTime, time.Now().UnixNano())
+}
+
+// idleTime returns how long the session has been without keyboard input
+func (p *ShellPlugin) idleTime() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&p.lastInputTime)))
+}
+
+// monitorIdleTimeout signals timedOut once the session has been without keyboard input for idleTimeout.
+// The user is warned on the terminal before the session is terminated.
+func (p *ShellPlugin) monitorIdleTimeout(log log.T, idleTimeout time.Duration, timedOut chan<- bool, stop <-chan bool) {
+	warningPeriod := mgsConfig.IdleTimeoutWarningPeriod
+	if warningPeriod > idleTimeout/2 {
+		warningPeriod = idleTimeout / 2
+	}
+
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+
+	warned := false
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		idleTime := p.idleTime()
+		if idleTime >= idleTimeout {
+			log.Infof("Session has been idle for %v, terminating the session", idleTime)
+			timedOut <- true
+			return
+		}
+		if idleTime < idleTimeout-warningPeriod {
+			warned = false
+			continue
+		}
+		if !warned {
+			warned = true
+			remaining := int((idleTimeout - idleTime) / time.Second)
+			warning := fmt.Sprintf(mgsConfig.IdleTimeoutWarningMsg, remaining)
+			if err := p.dataChannel.SendStreamDataMessage(log, mgsContracts.Output, []byte(warning)); err != nil {
+				log.Warnf("Unable to send idle timeout warning: %v", err)
+			}
+		}
+	}
+}
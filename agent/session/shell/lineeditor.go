@@ -0,0 +1,99 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"unicode/utf8"
+)
+
+const (
+	ctrlCByte     = 0x03
+	ctrlDByte     = 0x04
+	backspaceByte = 0x08
+	escapeByte    = 0x1b
+	deleteByte    = 0x7f
+)
+
+// terminal escape sequence parsing states of the line editor
+const (
+	escapeNone = iota
+	escapeStarted
+	escapeSequence
+)
+
+// lineEdit is the effect of a keystroke on the command line
+type lineEdit int
+
+const (
+	lineUnchanged lineEdit = iota
+	lineAppended
+	lineErased
+	lineCompleted
+)
+
+// lineEditor reconstructs the command line typed by the user from keystrokes.
+// Backspace erases the last character, other control characters and escape sequences such as arrow keys are ignored.
+type lineEditor struct {
+	line        []byte
+	escapeState int
+}
+
+// edit applies the keystroke to the command line
+func (e *lineEditor) edit(b byte) lineEdit {
+	switch e.escapeState {
+	case escapeStarted:
+		e.escapeState = escapeNone
+		if b == '[' || b == 'O' {
+			e.escapeState = escapeSequence
+		}
+		return lineUnchanged
+	case escapeSequence:
+		// escape sequences such as arrow keys end with a byte in the range @ to ~
+		if b >= 0x40 && b <= 0x7e {
+			e.escapeState = escapeNone
+		}
+		return lineUnchanged
+	}
+
+	switch {
+	case b == '\r' || b == '\n':
+		return lineCompleted
+	case b == backspaceByte || b == deleteByte:
+		if len(e.line) == 0 {
+			return lineUnchanged
+		}
+		_, size := utf8.DecodeLastRune(e.line)
+		e.line = e.line[:len(e.line)-size]
+		return lineErased
+	case b == escapeByte:
+		e.escapeState = escapeStarted
+		return lineUnchanged
+	case b < 0x20:
+		return lineUnchanged
+	}
+	e.line = append(e.line, b)
+	return lineAppended
+}
+
+// take returns the command line and clears it
+func (e *lineEditor) take() string {
+	line := string(e.line)
+	e.line = nil
+	return line
+}
+
+func (e *lineEditor) empty() bool {
+	return len(e.line) == 0
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+)
+
+// sleep waits before throttled input is written
+var sleep = time.Sleep
+
+// rateLimiterNow returns the time at which the rate limiter refills
+var rateLimiterNow = time.Now
+
+// inputRateLimiter is a token bucket limiting the number of input bytes written per second.
+// The bucket holds one second of input, larger messages are delayed until the bucket has refilled.
+type inputRateLimiter struct {
+	mutex      sync.Mutex
+	rate       float64
+	tokens     float64
+	lastRefill time.Time
+}
+
+// newInputRateLimiter creates a rate limiter allowing bytesPerSecond bytes of input per second
+func newInputRateLimiter(bytesPerSecond int) *inputRateLimiter {
+	return &inputRateLimiter{
+		rate:       float64(bytesPerSecond),
+		tokens:     float64(bytesPerSecond),
+		lastRefill: rateLimiterNow(),
+	}
+}
+
+// reserve takes the tokens for n bytes and returns how long to wait before writing them
+func (l *inputRateLimiter) reserve(n int) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := rateLimiterNow()
+	l.tokens += now.Sub(l.lastRefill).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.lastRefill = now
+
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// limitInput returns false if the input message exceeds the maximum size and has to be discarded,
+// otherwise it waits until the input rate limit allows the message to be written.
+// The user is warned when input is discarded and when throttling starts.
+func (p *ShellPlugin) limitInput(log log.T, data []byte) bool {
+	if p.maxInputMessageSize > 0 && len(data) > p.maxInputMessageSize {
+		log.Warnf("Discarding input of %d bytes exceeding the maximum of %d bytes", len(data), p.maxInputMessageSize)
+		p.sendWarning(log, fmt.Sprintf(mgsConfig.InputTooLargeWarningMsg, len(data), p.maxInputMessageSize))
+		return false
+	}
+	if p.inputRateLimiter == nil {
+		return true
+	}
+
+	delay := p.inputRateLimiter.reserve(len(data))
+	if delay == 0 {
+		p.inputThrottled = false
+		return true
+	}
+	if !p.inputThrottled {
+		p.inputThrottled = true
+		log.Debugf("Throttling input for %v", delay)
+		p.sendWarning(log, fmt.Sprintf(mgsConfig.InputThrottledWarningMsg, int(p.inputRateLimiter.rate)))
+	}
+	sleep(delay)
+	return true
+}
+
+// sendWarning shows the warning on the terminal of the user
+func (p *ShellPlugin) sendWarning(log log.T, warning string) {
+	if err := p.dataChannel.SendStreamDataMessage(log, mgsContracts.Output, []byte(warning)); err != nil {
+		log.Warnf("Unable to send warning: %v", err)
+	}
+}
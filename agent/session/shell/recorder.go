@@ -0,0 +1,227 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+const (
+	castFileExtension       = ".cast"
+	compressedFileExtension = ".gz"
+	castVersion             = 2
+
+	// default terminal size recorded in the header until the client sends its size
+	castDefaultWidth  = 80
+	castDefaultHeight = 24
+
+	castOutputEvent = "o"
+	castInputEvent  = "i"
+	castResizeEvent = "r"
+)
+
+// castHeader is the first line of an asciinema v2 cast file
+type castHeader struct {
+	Version   int    `json:"version"`
+	Width     uint32 `json:"width"`
+	Height    uint32 `json:"height"`
+	Timestamp int64  `json:"timestamp"`
+	Title     string `json:"title,omitempty"`
+}
+
+// castRecorder records the terminal I/O of a shell session to asciinema v2 cast files.
+// When a cast file reaches the maximum size a new file is started. Only the most recent files are kept as is,
+// older files of the session are compressed so that no part of the recording is lost.
+type castRecorder struct {
+	mutex       sync.Mutex
+	dir         string
+	sessionId   string
+	startTime   time.Time
+	width       uint32
+	height      uint32
+	maxFileSize int64
+	maxFiles    int
+	file        *os.File
+	fileIndex   int
+	fileSize    int64
+}
+
+// newCastRecorder creates the first cast file of the session in the given directory
+func newCastRecorder(dir string, sessionId string, maxFileSizeMB int, maxFiles int) (recorder *castRecorder, err error) {
+	if maxFileSizeMB <= 0 {
+		maxFileSizeMB = appconfig.DefaultSessionRecordingMaxFileSizeMB
+	}
+	if maxFiles <= 0 {
+		maxFiles = appconfig.DefaultSessionRecordingMaxFiles
+	}
+	recorder = &castRecorder{
+		dir:         dir,
+		sessionId:   sessionId,
+		startTime:   time.Now(),
+		width:       castDefaultWidth,
+		height:      castDefaultHeight,
+		maxFileSize: int64(maxFileSizeMB) * 1024 * 1024,
+		maxFiles:    maxFiles,
+	}
+	if err = os.MkdirAll(dir, appconfig.ReadWriteExecuteAccess); err != nil {
+		return nil, err
+	}
+	if err = recorder.openFile(); err != nil {
+		return nil, err
+	}
+	return recorder, nil
+}
+
+// recordOutput records data written by the shell to the terminal
+func (r *castRecorder) recordOutput(data []byte) error {
+	return r.writeEvent(castOutputEvent, string(data))
+}
+
+// recordInput records data typed by the user
+func (r *castRecorder) recordInput(data []byte) error {
+	return r.writeEvent(castInputEvent, string(data))
+}
+
+// recordResize records a change of the terminal size
+func (r *castRecorder) recordResize(cols uint32, rows uint32) error {
+	r.mutex.Lock()
+	r.width, r.height = cols, rows
+	r.mutex.Unlock()
+	return r.writeEvent(castResizeEvent, fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// close closes the current cast file
+func (r *castRecorder) close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// writeEvent appends an event line to the current cast file, starting a new file if the current one is full
+func (r *castRecorder) writeEvent(eventType string, data string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	elapsed := time.Since(r.startTime).Seconds()
+	line, err := json.Marshal([]interface{}{elapsed, eventType, data})
+	if err != nil {
+		return err
+	}
+	if r.fileSize+int64(len(line))+1 > r.maxFileSize && r.fileSize > 0 {
+		if err = r.rotate(); err != nil {
+			return err
+		}
+	}
+	return r.writeLine(line)
+}
+
+// rotate closes the current cast file, opens the next one and compresses files beyond the retention count
+func (r *castRecorder) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+	r.fileIndex++
+	if expired := r.fileIndex - r.maxFiles; expired >= 0 {
+		if err := compressFile(r.filePath(expired)); err != nil {
+			return err
+		}
+	}
+	return r.openFile()
+}
+
+// openFile creates the cast file for the current index and writes its header
+func (r *castRecorder) openFile() (err error) {
+	if r.file, err = os.OpenFile(r.filePath(r.fileIndex), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, appconfig.ReadWriteAccess); err != nil {
+		return err
+	}
+	r.fileSize = 0
+
+	header, err := json.Marshal(castHeader{
+		Version:   castVersion,
+		Width:     r.width,
+		Height:    r.height,
+		Timestamp: time.Now().Unix(),
+		Title:     r.sessionId,
+	})
+	if err != nil {
+		return err
+	}
+	return r.writeLine(header)
+}
+
+// writeLine writes a single line to the current cast file
+func (r *castRecorder) writeLine(line []byte) error {
+	n, err := r.file.Write(append(line, '\n'))
+	r.fileSize += int64(n)
+	return err
+}
+
+// filePath returns the path of the cast file with the given index.
+// The first file is named after the session, later files carry their index.
+func (r *castRecorder) filePath(index int) string {
+	if index == 0 {
+		return filepath.Join(r.dir, r.sessionId+castFileExtension)
+	}
+	return filepath.Join(r.dir, fmt.Sprintf("%s-%d%s", r.sessionId, index, castFileExtension))
+}
+
+// compressFile replaces the file at the given path with a gzip compressed copy
+func compressFile(path string) (err error) {
+	source, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer source.Close()
+
+	target, err := os.OpenFile(path+compressedFileExtension, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, appconfig.ReadWriteAccess)
+	if err != nil {
+		return err
+	}
+	writer := gzip.NewWriter(target)
+	if _, err = io.Copy(writer, source); err == nil {
+		err = writer.Close()
+	}
+	if closeErr := target.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path + compressedFileExtension)
+		return err
+	}
+	source.Close()
+	return os.Remove(path)
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCastRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "castrecorder")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	recorder, err := newCastRecorder(dir, "session-id", 1, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, recorder.recordResize(120, 40))
+	assert.NoError(t, recorder.recordInput([]byte("ls\r")))
+	assert.NoError(t, recorder.recordOutput([]byte("file.txt\r\n")))
+	assert.NoError(t, recorder.close())
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "session-id.cast"))
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Equal(t, 4, len(lines))
+
+	var header castHeader
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &header))
+	assert.Equal(t, castVersion, header.Version)
+	assert.Equal(t, "session-id", header.Title)
+
+	var event []interface{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &event))
+	assert.Equal(t, []interface{}{castResizeEvent, "120x40"}, event[1:])
+	assert.NoError(t, json.Unmarshal([]byte(lines[2]), &event))
+	assert.Equal(t, []interface{}{castInputEvent, "ls\r"}, event[1:])
+	assert.NoError(t, json.Unmarshal([]byte(lines[3]), &event))
+	assert.Equal(t, []interface{}{castOutputEvent, "file.txt\r\n"}, event[1:])
+}
+
+func TestCastRecorderRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "castrecorder")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	recorder, err := newCastRecorder(dir, "session-id", 1, 2)
+	assert.NoError(t, err)
+	chunk := []byte(strings.Repeat("a", 600*1024))
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, recorder.recordOutput(chunk))
+	}
+	assert.NoError(t, recorder.close())
+
+	// every chunk fills a file, only the two most recent files are kept uncompressed
+	for _, name := range []string{"session-id.cast", "session-id-1.cast"} {
+		_, err = os.Stat(filepath.Join(dir, name))
+		assert.True(t, os.IsNotExist(err))
+
+		compressed, err := os.Open(filepath.Join(dir, name+compressedFileExtension))
+		assert.NoError(t, err)
+		reader, err := gzip.NewReader(compressed)
+		assert.NoError(t, err)
+		content, err := ioutil.ReadAll(reader)
+		assert.NoError(t, err)
+		compressed.Close()
+		assert.True(t, strings.HasPrefix(string(content), `{"version":2`))
+		assert.Contains(t, string(content), string(chunk))
+	}
+
+	for _, name := range []string{"session-id-2.cast", "session-id-3.cast"} {
+		content, err := ioutil.ReadFile(filepath.Join(dir, name))
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(content), `{"version":2`))
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+)
+
+// setSize resizes the terminal of the session
+var setSize = SetSize
+
+// terminalResizer limits the size of the terminal and debounces resize messages,
+// so that a storm of resize messages sent while a window is dragged results in a single resize.
+type terminalResizer struct {
+	mutex    sync.Mutex
+	debounce time.Duration
+	maxCols  uint32
+	maxRows  uint32
+	pending  mgsContracts.SizeData
+	timer    *time.Timer
+	// apply resizes the terminal, it is called without holding the mutex
+	apply func(size mgsContracts.SizeData) error
+}
+
+// newTerminalResizer creates a resizer, zero values disable debouncing and the size limits
+func newTerminalResizer(debounce time.Duration, maxCols int, maxRows int, apply func(size mgsContracts.SizeData) error) *terminalResizer {
+	return &terminalResizer{
+		debounce: debounce,
+		maxCols:  uint32(maxCols),
+		maxRows:  uint32(maxRows),
+		apply:    apply,
+	}
+}
+
+// resize limits the requested size and applies it once no further resize has been requested for the debounce period.
+// Without debouncing the size is applied immediately and the error of resizing the terminal is returned.
+func (r *terminalResizer) resize(log log.T, size mgsContracts.SizeData) error {
+	if r.maxCols > 0 && size.Cols > r.maxCols {
+		log.Debugf("Limiting terminal columns from %d to %d", size.Cols, r.maxCols)
+		size.Cols = r.maxCols
+	}
+	if r.maxRows > 0 && size.Rows > r.maxRows {
+		log.Debugf("Limiting terminal rows from %d to %d", size.Rows, r.maxRows)
+		size.Rows = r.maxRows
+	}
+	if r.debounce == 0 {
+		return r.apply(size)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.pending = size
+	if r.timer == nil {
+		r.timer = time.AfterFunc(r.debounce, func() {
+			r.applyPending(log)
+		})
+	} else {
+		r.timer.Reset(r.debounce)
+	}
+	return nil
+}
+
+// applyPending applies the last requested size
+func (r *terminalResizer) applyPending(log log.T) {
+	r.mutex.Lock()
+	size := r.pending
+	r.timer = nil
+	r.mutex.Unlock()
+
+	if err := r.apply(size); err != nil {
+		log.Errorf("Unable to set pty size: %s", err)
+	}
+}
+
+// stop discards a pending resize
+func (r *terminalResizer) stop() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+}
+
+// processResize resizes the terminal of the session to the size requested by the client
+func (p *ShellPlugin) processResize(log log.T, size mgsContracts.SizeData) error {
+	if p.resizer == nil {
+		return p.applySize(log, size)
+	}
+	return p.resizer.resize(log, size)
+}
+
+// applySize resizes the terminal and records the resize
+func (p *ShellPlugin) applySize(log log.T, size mgsContracts.SizeData) error {
+	if err := setSize(log, size.Cols, size.Rows); err != nil {
+		return err
+	}
+	p.recordResize(log, size)
+	return nil
+}
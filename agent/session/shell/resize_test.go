@@ -0,0 +1,77 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+// appliedSizes collects the sizes applied by a terminal resizer
+type appliedSizes struct {
+	mutex sync.Mutex
+	sizes []mgsContracts.SizeData
+}
+
+func (a *appliedSizes) apply(size mgsContracts.SizeData) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.sizes = append(a.sizes, size)
+	return nil
+}
+
+func (a *appliedSizes) get() []mgsContracts.SizeData {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return append([]mgsContracts.SizeData{}, a.sizes...)
+}
+
+func TestResizeLimitsTerminalSize(t *testing.T) {
+	applied := &appliedSizes{}
+	resizer := newTerminalResizer(0, 200, 0, applied.apply)
+
+	assert.NoError(t, resizer.resize(mockLog, mgsContracts.SizeData{Cols: 80, Rows: 24}))
+	assert.NoError(t, resizer.resize(mockLog, mgsContracts.SizeData{Cols: 5000, Rows: 3000}))
+	assert.Equal(t, []mgsContracts.SizeData{{Cols: 80, Rows: 24}, {Cols: 200, Rows: 3000}}, applied.get())
+}
+
+func TestResizeDebounced(t *testing.T) {
+	applied := &appliedSizes{}
+	resizer := newTerminalResizer(50*time.Millisecond, 0, 0, applied.apply)
+	defer resizer.stop()
+
+	for cols := uint32(80); cols <= 120; cols++ {
+		assert.NoError(t, resizer.resize(mockLog, mgsContracts.SizeData{Cols: cols, Rows: 24}))
+	}
+	assert.Empty(t, applied.get())
+
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, []mgsContracts.SizeData{{Cols: 120, Rows: 24}}, applied.get())
+}
+
+func TestResizeStopDiscardsPendingResize(t *testing.T) {
+	applied := &appliedSizes{}
+	resizer := newTerminalResizer(50*time.Millisecond, 0, 0, applied.apply)
+
+	assert.NoError(t, resizer.resize(mockLog, mgsContracts.SizeData{Cols: 80, Rows: 24}))
+	resizer.stop()
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, applied.get())
+}
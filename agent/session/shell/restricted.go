@@ -0,0 +1,318 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	agentContracts "github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+)
+
+const (
+	restrictedPrompt      = "> "
+	restrictedExitCommand = "exit"
+
+	restrictedCommandAllowedAuditEvent = "RestrictedCommandAllowed"
+	restrictedCommandDeniedAuditEvent  = "RestrictedCommandDenied"
+)
+
+// shellOperators chain or substitute commands, command lines containing them are always denied
+// so that an allowed command cannot be combined with commands that are not allowed
+var shellOperators = []string{";", "&", "|", "`", "$(", "<", ">", "\n"}
+
+// compileAllowedCommands compiles the allowed command patterns, each matching whole command lines
+func compileAllowedCommands(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		if strings.TrimSpace(pattern) == "" {
+			continue
+		}
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed command pattern %q: %v", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	if len(compiled) == 0 {
+		return nil, errors.New("restricted command mode is enabled but no command is allowed")
+	}
+	return compiled, nil
+}
+
+// isCommandAllowed returns true if the command line matches one of the patterns and does not contain shell operators
+func isCommandAllowed(patterns []*regexp.Regexp, command string) bool {
+	for _, operator := range shellOperators {
+		if strings.Contains(command, operator) {
+			return false
+		}
+	}
+	for _, pattern := range patterns {
+		if pattern.MatchString(command) {
+			return true
+		}
+	}
+	return false
+}
+
+// restrictedShell takes the place of the pty in restricted command mode: the plugin writes the input of the user
+// to its input pipe and reads its output pipe. The agent edits and echoes the command line, and starts the commands
+// matching an allowed pattern one at a time, each on its own pty.
+type restrictedShell struct {
+	patterns     []*regexp.Regexp
+	startCommand func(command string) (stdin *os.File, stdout *os.File, err error)
+	endCommand   func()
+	audit        func(command string, allowed bool)
+
+	inputReader  *os.File
+	outputWriter *os.File
+
+	mutex sync.Mutex
+	// editor holds the command line being typed
+	editor lineEditor
+	// commandStdin is the input of the running command, nil when the shell waits for a command
+	commandStdin *os.File
+}
+
+// newRestrictedShell creates a restricted shell, stdin and stdout are used by the plugin in place of the pty
+func newRestrictedShell(patterns []*regexp.Regexp,
+	startCommand func(command string) (*os.File, *os.File, error),
+	endCommand func(),
+	audit func(command string, allowed bool)) (shell *restrictedShell, stdin *os.File, stdout *os.File, err error) {
+
+	shell = &restrictedShell{
+		patterns:     patterns,
+		startCommand: startCommand,
+		endCommand:   endCommand,
+		audit:        audit,
+	}
+	if shell.inputReader, stdin, err = os.Pipe(); err != nil {
+		return nil, nil, nil, err
+	}
+	if stdout, shell.outputWriter, err = os.Pipe(); err != nil {
+		shell.inputReader.Close()
+		stdin.Close()
+		return nil, nil, nil, err
+	}
+	return shell, stdin, stdout, nil
+}
+
+// run reads the input of the user until the user exits, closing the output of the shell which ends the session
+func (r *restrictedShell) run(log log.T) {
+	defer r.outputWriter.Close()
+
+	r.write(restrictedPrompt)
+	buffer := make([]byte, 1024)
+	for {
+		n, err := r.inputReader.Read(buffer)
+		if err != nil {
+			log.Debugf("Restricted shell input closed: %v", err)
+			return
+		}
+		if r.processInput(log, buffer[:n]) {
+			log.Info("Restricted shell exited")
+			return
+		}
+	}
+}
+
+// processInput forwards the input to the running command, or edits the command line and runs completed commands.
+// It returns true when the user exits the shell.
+func (r *restrictedShell) processInput(log log.T, data []byte) (exit bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, b := range data {
+		if r.commandStdin != nil {
+			if _, err := r.commandStdin.Write(data[i:]); err != nil {
+				log.Debugf("Unable to write to restricted command: %v", err)
+			}
+			return false
+		}
+
+		if b == ctrlCByte {
+			r.editor.take()
+			r.write("^C\r\n" + restrictedPrompt)
+			continue
+		}
+		if b == ctrlDByte && r.editor.empty() {
+			return true
+		}
+
+		switch r.editor.edit(b) {
+		case lineAppended:
+			r.outputWriter.Write([]byte{b})
+		case lineErased:
+			r.write("\b \b")
+		case lineCompleted:
+			r.write("\r\n")
+			command := strings.TrimSpace(r.editor.take())
+			if command == restrictedExitCommand {
+				return true
+			}
+			r.runCommand(log, command)
+		}
+	}
+	return false
+}
+
+// runCommand starts the command if it is allowed, the mutex must be held
+func (r *restrictedShell) runCommand(log log.T, command string) {
+	if command == "" {
+		r.write(restrictedPrompt)
+		return
+	}
+
+	allowed := isCommandAllowed(r.patterns, command)
+	r.audit(command, allowed)
+	if !allowed {
+		log.Warnf("Denied restricted command: %s", command)
+		r.write(fmt.Sprintf("Command not permitted: %s\r\n%s", command, restrictedPrompt))
+		return
+	}
+
+	log.Infof("Running restricted command: %s", command)
+	stdin, stdout, err := r.startCommand(command)
+	if err != nil {
+		log.Errorf("Unable to run restricted command: %v", err)
+		r.write(fmt.Sprintf("Unable to run command: %v\r\n%s", err, restrictedPrompt))
+		return
+	}
+	r.commandStdin = stdin
+	go r.waitCommand(stdout)
+}
+
+// waitCommand copies the output of the command until it exits, then shows the prompt
+func (r *restrictedShell) waitCommand(stdout *os.File) {
+	io.Copy(r.outputWriter, stdout)
+	r.endCommand()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.commandStdin = nil
+	r.write(restrictedPrompt)
+}
+
+// close closes the input of the shell, which makes it exit
+func (r *restrictedShell) close() {
+	r.inputReader.Close()
+}
+
+func (r *restrictedShell) write(text string) {
+	r.outputWriter.Write([]byte(text))
+}
+
+// startRestricted starts the session in restricted command mode. The commands of a session document run
+// like in an unrestricted session if they are allowed, otherwise the restricted shell is started.
+// commandStarted is called each time a command starts as the session user.
+func (p *ShellPlugin) startRestricted(log log.T,
+	config agentContracts.Configuration,
+	shellProps mgsContracts.ShellProperties,
+	shell string,
+	allowedCommands []string,
+	commandStarted func()) (stdin *os.File, stdout *os.File, err error) {
+
+	patterns, err := compileAllowedCommands(allowedCommands)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	commands := strings.TrimSpace(sessionCommands(shellProps))
+	if commands == "" {
+		return p.startRestrictedShell(log, config, shellProps, shell, patterns, commandStarted)
+	}
+
+	// the commands of the session document must be allowed as a whole
+	allowed := isCommandAllowed(patterns, commands)
+	writeRestrictedCommandAuditRecord(log, config, sessionIdentity(shellProps, config), commands, allowed)
+	if !allowed {
+		return nil, nil, fmt.Errorf("command not permitted: %s", commands)
+	}
+	if stdin, stdout, err = startPty(log, shellProps, false, config, shell); err == nil {
+		commandStarted()
+	}
+	return stdin, stdout, err
+}
+
+// startRestrictedShell starts the restricted shell taking the place of the pty.
+// Its commands are started like the commands of a session document, as the session user.
+func (p *ShellPlugin) startRestrictedShell(log log.T,
+	config agentContracts.Configuration,
+	shellProps mgsContracts.ShellProperties,
+	shell string,
+	patterns []*regexp.Regexp,
+	commandStarted func()) (stdin *os.File, stdout *os.File, err error) {
+
+	runAsUser := sessionIdentity(shellProps, config)
+	p.restricted, stdin, stdout, err = newRestrictedShell(patterns,
+		func(command string) (*os.File, *os.File, error) {
+			commandStdin, commandStdout, err := startPty(log, withSessionCommands(shellProps, command), false, config, shell)
+			if err == nil {
+				commandStarted()
+			}
+			return commandStdin, commandStdout, err
+		},
+		func() {
+			// kill the processes left behind by the command
+			if err := terminate(log); err != nil {
+				log.Debugf("Unable to terminate restricted command: %v", err)
+			}
+			if err := Stop(log); err != nil {
+				log.Debugf("Unable to stop restricted command pty: %v", err)
+			}
+		},
+		func(command string, allowed bool) {
+			writeRestrictedCommandAuditRecord(log, config, runAsUser, command, allowed)
+		})
+	if err != nil {
+		return nil, nil, err
+	}
+	go p.restricted.run(log)
+	return stdin, stdout, nil
+}
+
+// writeRestrictedCommandAuditRecord appends an audit record of the command typed in restricted command mode to the local audit file
+func writeRestrictedCommandAuditRecord(log log.T, config agentContracts.Configuration, userName string, command string, allowed bool) {
+	record := shellAuditRecord{
+		SessionId: config.SessionId,
+		ClientId:  config.ClientId,
+		RunAsUser: userName,
+		Event:     restrictedCommandAllowedAuditEvent,
+		Command:   command,
+		Time:      time.Now().UTC().Format(time.RFC3339),
+	}
+	if !allowed {
+		record.Event = restrictedCommandDeniedAuditEvent
+		record.Reason = "command does not match an allowed pattern"
+	}
+
+	content, err := jsonutil.Marshal(record)
+	if err != nil {
+		log.Errorf("Unable to marshal shell audit record: %v", err)
+		return
+	}
+	if err = appendAuditRecord(content); err != nil {
+		log.Errorf("Unable to write shell audit record: %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCommand is the command started by the restricted shell in tests, its output is written by the test
+type testCommand struct {
+	mutex         sync.Mutex
+	started       []string
+	audited       map[string]bool
+	inputReader   *os.File
+	inputWriter   *os.File
+	outputReader  *os.File
+	outputWriter  *os.File
+	ended         chan bool
+	shell         *restrictedShell
+	shellStdout   *os.File
+	shellStdinEnd *os.File
+}
+
+func newTestRestrictedShell(t *testing.T) *testCommand {
+	patterns, err := compileAllowedCommands([]string{`ls( -l)?`, `systemctl status \S+`})
+	assert.NoError(t, err)
+
+	command := &testCommand{audited: make(map[string]bool), ended: make(chan bool, 1)}
+	command.inputReader, command.inputWriter, _ = os.Pipe()
+	command.outputReader, command.outputWriter, _ = os.Pipe()
+	command.shell, command.shellStdinEnd, command.shellStdout, err = newRestrictedShell(patterns,
+		func(line string) (*os.File, *os.File, error) {
+			command.mutex.Lock()
+			defer command.mutex.Unlock()
+			command.started = append(command.started, line)
+			return command.inputWriter, command.outputReader, nil
+		},
+		func() { command.ended <- true },
+		func(line string, allowed bool) { command.audited[line] = allowed })
+	assert.NoError(t, err)
+	return command
+}
+
+// output closes the restricted shell output and returns everything written to it
+func (c *testCommand) output() string {
+	c.shell.outputWriter.Close()
+	content, _ := ioutil.ReadAll(c.shellStdout)
+	return string(content)
+}
+
+func (c *testCommand) running() bool {
+	c.shell.mutex.Lock()
+	defer c.shell.mutex.Unlock()
+	return c.shell.commandStdin != nil
+}
+
+func TestCompileAllowedCommands(t *testing.T) {
+	_, err := compileAllowedCommands([]string{"ls", "("})
+	assert.Error(t, err)
+
+	_, err = compileAllowedCommands([]string{" "})
+	assert.Error(t, err)
+
+	patterns, err := compileAllowedCommands([]string{"ls"})
+	assert.NoError(t, err)
+	assert.True(t, isCommandAllowed(patterns, "ls"))
+	assert.False(t, isCommandAllowed(patterns, "ls /root"))
+	assert.False(t, isCommandAllowed(patterns, "cat /etc/shadow && ls"))
+}
+
+func TestIsCommandAllowedDeniesShellOperators(t *testing.T) {
+	patterns, _ := compileAllowedCommands([]string{`systemctl status .*`})
+
+	assert.True(t, isCommandAllowed(patterns, "systemctl status nginx"))
+	for _, command := range []string{
+		"systemctl status nginx; reboot",
+		"systemctl status nginx && reboot",
+		"systemctl status nginx | sh",
+		"systemctl status $(reboot)",
+		"systemctl status `reboot`",
+		"systemctl status nginx > /etc/passwd",
+	} {
+		assert.False(t, isCommandAllowed(patterns, command), command)
+	}
+}
+
+func TestRestrictedShellDeniesCommand(t *testing.T) {
+	command := newTestRestrictedShell(t)
+
+	exit := command.shell.processInput(mockLog, []byte("rm -rf /\r"))
+
+	assert.False(t, exit)
+	assert.Empty(t, command.started)
+	assert.Equal(t, map[string]bool{"rm -rf /": false}, command.audited)
+	assert.Equal(t, "rm -rf /\r\nCommand not permitted: rm -rf /\r\n"+restrictedPrompt, command.output())
+}
+
+func TestRestrictedShellRunsAllowedCommand(t *testing.T) {
+	command := newTestRestrictedShell(t)
+
+	// arrow keys are ignored and backspace edits the line
+	command.shell.processInput(mockLog, []byte("lx\x7fs\x1b[A -l\ry"))
+
+	assert.Equal(t, []string{"ls -l"}, command.started)
+	assert.Equal(t, map[string]bool{"ls -l": true}, command.audited)
+	assert.True(t, command.running())
+
+	// input typed while the command runs is forwarded to the command
+	forwarded := make([]byte, 1)
+	command.inputReader.Read(forwarded)
+	assert.Equal(t, "y", string(forwarded))
+
+	command.outputWriter.Write([]byte("total 0\r\n"))
+	command.outputWriter.Close()
+	select {
+	case <-command.ended:
+	case <-time.After(time.Second):
+		assert.Fail(t, "command did not end")
+	}
+	for i := 0; i < 100 && command.running(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.False(t, command.running())
+
+	assert.Equal(t, "lx\b \bs -l\r\ntotal 0\r\n"+restrictedPrompt, command.output())
+}
+
+func TestRestrictedShellExit(t *testing.T) {
+	command := newTestRestrictedShell(t)
+
+	assert.True(t, command.shell.processInput(mockLog, []byte("exit\r")))
+	assert.True(t, command.shell.processInput(mockLog, []byte{ctrlDByte}))
+	assert.False(t, command.shell.processInput(mockLog, []byte{'l', ctrlDByte}))
+	assert.Empty(t, command.started)
+}
+
+func TestWriteRestrictedCommandAuditRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shellaudit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	auditLogDir = dir
+
+	writeRestrictedCommandAuditRecord(mockLog, contracts.Configuration{SessionId: "session-id"}, "ssm-user", "reboot", false)
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, auditLogFileName))
+	assert.NoError(t, err)
+	var record shellAuditRecord
+	assert.NoError(t, jsonutil.Unmarshal(strings.TrimSpace(string(content)), &record))
+	assert.Equal(t, "session-id", record.SessionId)
+	assert.Equal(t, "ssm-user", record.RunAsUser)
+	assert.Equal(t, restrictedCommandDeniedAuditEvent, record.Event)
+	assert.Equal(t, "reboot", record.Command)
+}
@@ -0,0 +1,273 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build windows
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	logon32LogonService      = uintptr(5)
+	securityLogonTypeNetwork = uintptr(3)
+	s4uLogonMessageType      = uint32(12)
+	kerberosPackageName      = "Kerberos"
+	msv1_0PackageName        = "MICROSOFT_AUTHENTICATION_PACKAGE_V1_0"
+	logonProcessName         = "amazon-ssm-agent"
+	tokenSourceName          = "SSMAgent"
+	localDomainName          = "."
+)
+
+var (
+	secur32                            = syscall.NewLazyDLL("secur32.dll")
+	lsaRegisterLogonProcessProc        = secur32.NewProc("LsaRegisterLogonProcess")
+	lsaDeregisterLogonProcessProc      = secur32.NewProc("LsaDeregisterLogonProcess")
+	lsaLookupAuthenticationPackageProc = secur32.NewProc("LsaLookupAuthenticationPackage")
+	lsaLogonUserProc                   = secur32.NewProc("LsaLogonUser")
+	lsaFreeReturnBufferProc            = secur32.NewProc("LsaFreeReturnBuffer")
+	lsaNtStatusToWinErrorProc          = advapi32.NewProc("LsaNtStatusToWinError")
+	allocateLocallyUniqueIdProc        = advapi32.NewProc("AllocateLocallyUniqueId")
+)
+
+// lsaString is the LSA_STRING structure used by the LSA authentication functions
+type lsaString struct {
+	Length        uint16
+	MaximumLength uint16
+	Buffer        *byte
+}
+
+// unicodeString is the UNICODE_STRING structure
+type unicodeString struct {
+	Length        uint16
+	MaximumLength uint16
+	Buffer        *uint16
+}
+
+// s4uLogon is the layout shared by KERB_S4U_LOGON and MSV1_0_S4U_LOGON
+type s4uLogon struct {
+	MessageType       uint32
+	Flags             uint32
+	UserPrincipalName unicodeString
+	DomainName        unicodeString
+}
+
+// luid is the LUID structure
+type luid struct {
+	LowPart  uint32
+	HighPart int32
+}
+
+// tokenSource is the TOKEN_SOURCE structure
+type tokenSource struct {
+	SourceName       [8]byte
+	SourceIdentifier luid
+}
+
+// quotaLimits is the QUOTA_LIMITS structure
+type quotaLimits struct {
+	PagedPoolLimit        uintptr
+	NonPagedPoolLimit     uintptr
+	MinimumWorkingSetSize uintptr
+	MaximumWorkingSetSize uintptr
+	PagefileLimit         uintptr
+	TimeLimit             int64
+}
+
+// parseAccountName splits a DOMAIN\user or user@domain account name into user and domain.
+// Accounts without a domain are local accounts.
+func parseAccountName(account string) (user string, domain string) {
+	account = strings.TrimSpace(account)
+	if i := strings.Index(account, `\`); i >= 0 {
+		return account[i+1:], account[:i]
+	}
+	if i := strings.LastIndex(account, "@"); i >= 0 {
+		return account[:i], account[i+1:]
+	}
+	return account, localDomainName
+}
+
+// isManagedServiceAccount returns true for group Managed Service Accounts, whose names end with $
+func isManagedServiceAccount(user string) bool {
+	return strings.HasSuffix(user, "$")
+}
+
+// startPtyAsRunAsUser starts a pty process in the context of the runAs account without its password.
+// Group Managed Service Accounts are logged on as a service, their password is retrieved by LSA.
+// Other domain and local accounts are logged on with Service for User (S4U).
+func startPtyAsRunAsUser(log log.T, account string, shellCmd string, env []string) (err error) {
+	user, domain := parseAccountName(account)
+
+	var token syscall.Handle
+	if isManagedServiceAccount(user) {
+		log.Debugf("Logging on group managed service account %s\\%s", domain, user)
+		token, err = logonUserWithType(user, domain, "", logon32LogonService)
+	} else {
+		log.Debugf("Logging on %s\\%s with S4U", domain, user)
+		token, err = s4uLogonUser(user, domain)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to log on runAs user %s: %v", account, err)
+	}
+	defer mustCloseHandle(log, token)
+
+	return startPtyWithToken(log, token, shellCmd, env)
+}
+
+// startPtyWithToken starts a pty process as the user of the token
+func startPtyWithToken(log log.T, token syscall.Handle, shellCmd string, env []string) (err error) {
+	pty, err = startConsole(log, shellCmd, env, token)
+	return
+}
+
+// s4uLogonUser creates a network logon token for the account using Service for User,
+// Kerberos is used for domain accounts and MSV1_0 for local accounts.
+func s4uLogonUser(user string, domain string) (token syscall.Handle, err error) {
+	packageName := kerberosPackageName
+	if domain == localDomainName {
+		packageName = msv1_0PackageName
+	}
+
+	var lsaHandle syscall.Handle
+	processName := newLsaString(logonProcessName)
+	var securityMode uint32
+	if status, _, _ := lsaRegisterLogonProcessProc.Call(
+		uintptr(unsafe.Pointer(&processName)),
+		uintptr(unsafe.Pointer(&lsaHandle)),
+		uintptr(unsafe.Pointer(&securityMode))); status != 0 {
+		return 0, ntStatusError(status)
+	}
+	defer lsaDeregisterLogonProcessProc.Call(uintptr(lsaHandle))
+
+	var authPackage uint32
+	authPackageName := newLsaString(packageName)
+	if status, _, _ := lsaLookupAuthenticationPackageProc.Call(
+		uintptr(lsaHandle),
+		uintptr(unsafe.Pointer(&authPackageName)),
+		uintptr(unsafe.Pointer(&authPackage))); status != 0 {
+		return 0, ntStatusError(status)
+	}
+
+	authInfo, err := newS4uLogonBuffer(user, domain)
+	if err != nil {
+		return 0, err
+	}
+
+	var source tokenSource
+	copy(source.SourceName[:], tokenSourceName)
+	if rc, _, ec := allocateLocallyUniqueIdProc.Call(uintptr(unsafe.Pointer(&source.SourceIdentifier))); rc == 0 {
+		return 0, error(ec)
+	}
+
+	var profileBuffer uintptr
+	var profileBufferLength uint32
+	var logonId luid
+	var quotas quotaLimits
+	var subStatus int32
+	if status, _, _ := lsaLogonUserProc.Call(
+		uintptr(lsaHandle),
+		uintptr(unsafe.Pointer(&processName)),
+		securityLogonTypeNetwork,
+		uintptr(authPackage),
+		uintptr(unsafe.Pointer(&authInfo[0])),
+		uintptr(len(authInfo)),
+		0,
+		uintptr(unsafe.Pointer(&source)),
+		uintptr(unsafe.Pointer(&profileBuffer)),
+		uintptr(unsafe.Pointer(&profileBufferLength)),
+		uintptr(unsafe.Pointer(&logonId)),
+		uintptr(unsafe.Pointer(&token)),
+		uintptr(unsafe.Pointer(&quotas)),
+		uintptr(unsafe.Pointer(&subStatus))); status != 0 {
+		return 0, ntStatusError(status)
+	}
+	runtime.KeepAlive(authInfo)
+	if profileBuffer != 0 {
+		lsaFreeReturnBufferProc.Call(profileBuffer)
+	}
+	return token, nil
+}
+
+// newS4uLogonBuffer builds the S4U logon structure followed by the strings it points to in a single buffer,
+// as LsaLogonUser requires the authentication information to be contiguous.
+func newS4uLogonBuffer(user string, domain string) (buffer []byte, err error) {
+	var userUtf16, domainUtf16 []uint16
+	if userUtf16, err = syscall.UTF16FromString(user); err != nil {
+		return nil, err
+	}
+	if domain == localDomainName {
+		domain = ""
+	}
+	if domainUtf16, err = syscall.UTF16FromString(domain); err != nil {
+		return nil, err
+	}
+
+	headerSize := int(unsafe.Sizeof(s4uLogon{}))
+	userSize := (len(userUtf16) - 1) * 2
+	domainSize := (len(domainUtf16) - 1) * 2
+	buffer = make([]byte, headerSize+userSize+domainSize)
+
+	logon := (*s4uLogon)(unsafe.Pointer(&buffer[0]))
+	logon.MessageType = s4uLogonMessageType
+	if userSize > 0 {
+		copy(buffer[headerSize:], utf16Bytes(userUtf16[:len(userUtf16)-1]))
+		logon.UserPrincipalName = unicodeString{
+			Length:        uint16(userSize),
+			MaximumLength: uint16(userSize),
+			Buffer:        (*uint16)(unsafe.Pointer(&buffer[headerSize])),
+		}
+	}
+	if domainSize > 0 {
+		copy(buffer[headerSize+userSize:], utf16Bytes(domainUtf16[:len(domainUtf16)-1]))
+		logon.DomainName = unicodeString{
+			Length:        uint16(domainSize),
+			MaximumLength: uint16(domainSize),
+			Buffer:        (*uint16)(unsafe.Pointer(&buffer[headerSize+userSize])),
+		}
+	}
+	return buffer, nil
+}
+
+// utf16Bytes returns the little endian bytes of the utf16 string
+func utf16Bytes(s []uint16) []byte {
+	b := make([]byte, len(s)*2)
+	for i, c := range s {
+		b[2*i] = byte(c)
+		b[2*i+1] = byte(c >> 8)
+	}
+	return b
+}
+
+// newLsaString creates an LSA_STRING for the ansi string
+func newLsaString(s string) lsaString {
+	b := append([]byte(s), 0)
+	return lsaString{
+		Length:        uint16(len(s)),
+		MaximumLength: uint16(len(b)),
+		Buffer:        &b[0],
+	}
+}
+
+// ntStatusError converts the NTSTATUS returned by LSA functions to a windows error
+func ntStatusError(status uintptr) error {
+	rc, _, _ := lsaNtStatusToWinErrorProc.Call(status)
+	return syscall.Errno(rc)
+}
@@ -0,0 +1,58 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+//
+// +build windows
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAccountName(t *testing.T) {
+	testCases := []struct {
+		account string
+		user    string
+		domain  string
+	}{
+		{`CORP\admin`, "admin", "CORP"},
+		{"admin@corp.example.com", "admin", "corp.example.com"},
+		{`CORP\svc-shell$`, "svc-shell$", "CORP"},
+		{"localadmin", "localadmin", localDomainName},
+	}
+	for _, tc := range testCases {
+		user, domain := parseAccountName(tc.account)
+		assert.Equal(t, tc.user, user, tc.account)
+		assert.Equal(t, tc.domain, domain, tc.account)
+	}
+}
+
+func TestIsManagedServiceAccount(t *testing.T) {
+	assert.True(t, isManagedServiceAccount("svc-shell$"))
+	assert.False(t, isManagedServiceAccount("admin"))
+}
+
+func TestNewS4uLogonBuffer(t *testing.T) {
+	buffer, err := newS4uLogonBuffer("admin", "CORP")
+	assert.NoError(t, err)
+
+	logon := (*s4uLogon)(unsafe.Pointer(&buffer[0]))
+	assert.Equal(t, s4uLogonMessageType, logon.MessageType)
+	assert.Equal(t, uint16(len("admin")*2), logon.UserPrincipalName.Length)
+	assert.Equal(t, uint16(len("CORP")*2), logon.DomainName.Length)
+	assert.Equal(t, int(unsafe.Sizeof(s4uLogon{}))+len("adminCORP")*2, len(buffer))
+}
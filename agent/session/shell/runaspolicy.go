@@ -0,0 +1,94 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/auditlog"
+	agentContracts "github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/session/utility"
+)
+
+const (
+	// auditLogFileName is the name of the local file shell session audit records are appended to
+	auditLogFileName = "shell_audit.log"
+
+	runAsDeniedAuditEvent = "RunAsDenied"
+)
+
+var auditLogDir = log.DefaultLogDir
+
+// shellAuditRecord is the audit record written when a shell session is refused or runs a restricted command
+type shellAuditRecord struct {
+	SessionId string `json:"sessionId"`
+	ClientId  string `json:"clientId"`
+	RunAsUser string `json:"runAsUser"`
+	Event     string `json:"event"`
+	Command   string `json:"command,omitempty"`
+	Digest    string `json:"digest,omitempty"`
+	Reason    string `json:"reason"`
+	Time      string `json:"time"`
+}
+
+// sessionIdentity returns the user the customer shell runs as, which is root or SYSTEM when the session runs elevated
+func sessionIdentity(shellProps mgsContracts.ShellProperties, config agentContracts.Configuration) string {
+	if runAsUser := sessionRunAsUser(shellProps, config); runAsUser != "" {
+		return runAsUser
+	}
+	return elevatedUserName
+}
+
+// checkSessionRunAsPolicy checks the runAs policy against the identity the session runs as, including elevated
+// sessions, and records refused sessions in the local audit file
+func checkSessionRunAsPolicy(log log.T, mgsConfig appconfig.MgsConfig, shellProps mgsContracts.ShellProperties, config agentContracts.Configuration) error {
+	identity := sessionIdentity(shellProps, config)
+	if err := utility.CheckRunAsPolicy(mgsConfig, identity); err != nil {
+		writeRunAsDeniedAuditRecord(log, config, identity, err)
+		return err
+	}
+	return nil
+}
+
+// writeRunAsDeniedAuditRecord appends an audit record of the refused session to the local audit file
+func writeRunAsDeniedAuditRecord(log log.T, config agentContracts.Configuration, userName string, reason error) {
+	record := shellAuditRecord{
+		SessionId: config.SessionId,
+		ClientId:  config.ClientId,
+		RunAsUser: userName,
+		Event:     runAsDeniedAuditEvent,
+		Reason:    reason.Error(),
+		Time:      time.Now().UTC().Format(time.RFC3339),
+	}
+
+	content, err := jsonutil.Marshal(record)
+	if err != nil {
+		log.Errorf("Unable to marshal shell audit record: %v", err)
+		return
+	}
+	if err = appendAuditRecord(content); err != nil {
+		log.Errorf("Unable to write shell audit record: %v", err)
+	}
+}
+
+// appendAuditRecord appends the record as a single line to the local shell audit file
+func appendAuditRecord(content string) error {
+	return auditlog.Append(filepath.Join(auditLogDir, auditLogFileName), content)
+}
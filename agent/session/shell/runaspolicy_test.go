@@ -0,0 +1,90 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/jsonutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteRunAsDeniedAuditRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shellaudit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	auditLogDir = dir
+
+	writeRunAsDeniedAuditRecord(log.NewMockLog(),
+		contracts.Configuration{SessionId: "session-id", ClientId: "client-id"},
+		"root",
+		errors.New("sessions are not permitted to run as user root"))
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, auditLogFileName))
+	assert.NoError(t, err)
+	var record shellAuditRecord
+	assert.NoError(t, jsonutil.Unmarshal(strings.TrimSpace(string(content)), &record))
+	assert.Equal(t, "session-id", record.SessionId)
+	assert.Equal(t, "client-id", record.ClientId)
+	assert.Equal(t, "root", record.RunAsUser)
+	assert.Equal(t, runAsDeniedAuditEvent, record.Event)
+}
+
+func TestCheckSessionRunAsPolicyElevated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shellaudit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	auditLogDir = dir
+
+	var shellProps mgsContracts.ShellProperties
+	shellProps.Linux.RunAsElevated = true
+	shellProps.Windows.RunAsElevated = true
+	config := contracts.Configuration{SessionId: "session-id", RunAsEnabled: true, RunAsUser: "ssm-user"}
+
+	// elevated sessions are checked against root or SYSTEM rather than skipping the policy
+	assert.Equal(t, elevatedUserName, sessionIdentity(shellProps, config))
+	err = checkSessionRunAsPolicy(log.NewMockLog(), appconfig.MgsConfig{SessionRunAsAllowedUsers: []string{"ssm-user"}}, shellProps, config)
+	assert.Error(t, err)
+	err = checkSessionRunAsPolicy(log.NewMockLog(), appconfig.MgsConfig{SessionRunAsDeniedUsers: []string{elevatedUserName}}, shellProps, config)
+	assert.Error(t, err)
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, auditLogFileName))
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Equal(t, 2, len(lines))
+	var record shellAuditRecord
+	assert.NoError(t, jsonutil.Unmarshal(lines[0], &record))
+	assert.Equal(t, elevatedUserName, record.RunAsUser)
+	assert.Equal(t, runAsDeniedAuditEvent, record.Event)
+
+	assert.NoError(t, checkSessionRunAsPolicy(log.NewMockLog(), appconfig.MgsConfig{SessionRunAsAllowedUsers: []string{elevatedUserName}}, shellProps, config))
+}
+
+func TestCheckSessionRunAsPolicyRunAsUser(t *testing.T) {
+	var shellProps mgsContracts.ShellProperties
+	config := contracts.Configuration{RunAsEnabled: true, RunAsUser: "ssm-user"}
+
+	assert.Equal(t, "ssm-user", sessionIdentity(shellProps, config))
+	assert.NoError(t, checkSessionRunAsPolicy(log.NewMockLog(), appconfig.MgsConfig{SessionRunAsAllowedUsers: []string{"ssm-user"}}, shellProps, config))
+}
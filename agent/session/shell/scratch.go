@@ -0,0 +1,109 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/outofproc/proc"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// sessionTmpEnvVariable holds the path of the scratch directory of the session
+	sessionTmpEnvVariable = "SSM_SESSION_TMP"
+
+	// scratchOwnerExtension is the extension of the files recording the process id of the session worker owning a scratch directory
+	scratchOwnerExtension = ".pid"
+)
+
+// scratchRoot is the directory holding the scratch directories of the sessions
+var scratchRoot = filepath.Join(os.TempDir(), "ssm-session-tmp")
+
+var isProcessExists = func(log log.T, pid int) bool {
+	return proc.IsProcessExists(log, pid, time.Time{})
+}
+
+// createScratchDirectory creates the scratch directory of the session, recording the session worker owning it
+// so that the directory is removed by a later session if the worker exits without cleaning up.
+// The returned remove function recursively removes the directory.
+func createScratchDirectory(log log.T, sessionId string) (dir string, remove func(), err error) {
+	remove = func() {}
+	if sessionId == "" || filepath.Base(sessionId) != sessionId || strings.HasPrefix(sessionId, ".") {
+		return "", remove, fmt.Errorf("invalid session id %q", sessionId)
+	}
+	if err = prepareScratchRoot(scratchRoot); err != nil {
+		return "", remove, fmt.Errorf("unable to create scratch root directory %s: %v", scratchRoot, err)
+	}
+	removeStaleScratchDirectories(log)
+
+	dir = filepath.Join(scratchRoot, sessionId)
+	ownerPath := dir + scratchOwnerExtension
+	// the owner is recorded before the directory is created so that no directory is left without owner
+	if err = fileutil.WriteAllText(ownerPath, strconv.Itoa(os.Getpid())); err != nil {
+		return "", remove, fmt.Errorf("unable to record owner of scratch directory %s: %v", dir, err)
+	}
+	if err = os.RemoveAll(dir); err == nil {
+		err = os.Mkdir(dir, scratchDirectoryAccess)
+	}
+	if err != nil {
+		os.Remove(ownerPath)
+		return "", remove, fmt.Errorf("unable to create scratch directory %s: %v", dir, err)
+	}
+
+	return dir, func() {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Warnf("Unable to remove scratch directory %s: %v", dir, err)
+			return
+		}
+		os.Remove(ownerPath)
+	}, nil
+}
+
+// removeStaleScratchDirectories removes the scratch directories whose session worker is no longer running
+func removeStaleScratchDirectories(log log.T) {
+	fileNames, err := fileutil.GetFileNames(scratchRoot)
+	if err != nil {
+		log.Warnf("Unable to list scratch directories in %s: %v", scratchRoot, err)
+		return
+	}
+
+	for _, fileName := range fileNames {
+		if !strings.HasSuffix(fileName, scratchOwnerExtension) {
+			continue
+		}
+		ownerPath := filepath.Join(scratchRoot, fileName)
+		content, err := fileutil.ReadAllText(ownerPath)
+		if err != nil {
+			continue
+		}
+		if pid, err := strconv.Atoi(content); err == nil && isProcessExists(log, pid) {
+			continue
+		}
+		dir := strings.TrimSuffix(ownerPath, scratchOwnerExtension)
+		log.Debugf("Removing stale scratch directory %s", dir)
+		if err = os.RemoveAll(dir); err != nil {
+			log.Warnf("Unable to remove stale scratch directory %s: %v", dir, err)
+			continue
+		}
+		os.Remove(ownerPath)
+	}
+}
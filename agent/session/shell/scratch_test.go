@@ -0,0 +1,84 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func setScratchRoot(t *testing.T) (restore func()) {
+	tempDir, err := ioutil.TempDir("", "scratch")
+	assert.NoError(t, err)
+	originalRoot, originalIsProcessExists := scratchRoot, isProcessExists
+	scratchRoot = filepath.Join(tempDir, "root")
+	return func() {
+		scratchRoot, isProcessExists = originalRoot, originalIsProcessExists
+		os.RemoveAll(tempDir)
+	}
+}
+
+func TestCreateScratchDirectory(t *testing.T) {
+	defer setScratchRoot(t)()
+
+	dir, remove, err := createScratchDirectory(mockLog, "session-id")
+
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(scratchRoot, "session-id"), dir)
+	assert.True(t, fileutil.IsDirectory(dir))
+	owner, _ := fileutil.ReadAllText(dir + scratchOwnerExtension)
+	assert.Equal(t, strconv.Itoa(os.Getpid()), owner)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "file"), []byte("content"), 0600))
+	remove()
+	assert.False(t, fileutil.Exists(dir))
+	assert.False(t, fileutil.Exists(dir+scratchOwnerExtension))
+}
+
+func TestCreateScratchDirectoryRemovesStaleDirectories(t *testing.T) {
+	defer setScratchRoot(t)()
+	activeDir, _, err := createScratchDirectory(mockLog, "active-session")
+	assert.NoError(t, err)
+	assert.NoError(t, fileutil.WriteAllText(activeDir+scratchOwnerExtension, "1"))
+	staleDir, _, err := createScratchDirectory(mockLog, "stale-session")
+	assert.NoError(t, err)
+	assert.NoError(t, fileutil.WriteAllText(staleDir+scratchOwnerExtension, "2"))
+	isProcessExists = func(log log.T, pid int) bool { return pid != 2 }
+
+	_, remove, err := createScratchDirectory(mockLog, "session-id")
+	defer remove()
+
+	assert.NoError(t, err)
+	assert.True(t, fileutil.IsDirectory(activeDir))
+	assert.False(t, fileutil.Exists(staleDir))
+	assert.False(t, fileutil.Exists(staleDir+scratchOwnerExtension))
+}
+
+func TestCreateScratchDirectoryInvalidSessionId(t *testing.T) {
+	defer setScratchRoot(t)()
+
+	for _, sessionId := range []string{"", "..", "../session-id", "dir/session-id"} {
+		_, _, err := createScratchDirectory(mockLog, sessionId)
+		assert.Error(t, err, sessionId)
+	}
+	assert.False(t, fileutil.Exists(scratchRoot))
+}
@@ -0,0 +1,67 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//
+// +build darwin freebsd linux netbsd openbsd
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// scratchRootAccess lets session users traverse the scratch root without listing the directories of other sessions
+	scratchRootAccess      os.FileMode = 0711
+	scratchDirectoryAccess os.FileMode = 0700
+)
+
+// prepareScratchRoot creates the scratch root directory, refusing to use a directory
+// created in the shared temporary directory by another user.
+func prepareScratchRoot(root string) error {
+	if err := os.MkdirAll(root, scratchRootAccess); err != nil {
+		return err
+	}
+	fi, err := os.Lstat(root)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", root)
+	}
+	if stat, ok := fi.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Geteuid() {
+		return fmt.Errorf("%s is owned by uid %d", root, stat.Uid)
+	}
+	if fi.Mode().Perm() != scratchRootAccess {
+		return os.Chmod(root, scratchRootAccess)
+	}
+	return nil
+}
+
+// setScratchOwner gives the ownership of the scratch directory to the user the session runs as,
+// the directory stays owned by root if the session runs elevated.
+func setScratchOwner(log log.T, dir string, user string) error {
+	if user == "" {
+		return nil
+	}
+	uid, gid, _, err := getUserCredentials(log, user)
+	if err != nil {
+		return err
+	}
+	return os.Chown(dir, int(uid), int(gid))
+}
@@ -0,0 +1,49 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+//
+// +build windows
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"os"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	acl "github.com/hectane/go-acl"
+)
+
+const scratchDirectoryAccess os.FileMode = appconfig.ReadWriteExecuteAccess
+
+// scratchFullAccessMask grants generic read, write, execute and all access
+const scratchFullAccessMask = uint32(15) << 28
+
+// prepareScratchRoot creates the scratch root directory
+func prepareScratchRoot(root string) error {
+	return os.MkdirAll(root, appconfig.ReadWriteExecuteAccess)
+}
+
+// setScratchOwner restricts the scratch directory to administrators and the user the session runs as,
+// only administrators can access it if the session runs elevated.
+func setScratchOwner(log log.T, dir string, user string) error {
+	if err := fileutil.Harden(dir); err != nil {
+		return err
+	}
+	if user == "" {
+		return nil
+	}
+	return acl.Apply(dir, false, false, acl.GrantName(scratchFullAccessMask, user))
+}
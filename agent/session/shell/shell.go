@@ -17,10 +17,15 @@ package shell
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
 	"time"
 	"unicode/utf8"
 
@@ -29,6 +34,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	agentContracts "github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/crypto"
 	"github.com/aws/amazon-ssm-agent/agent/fileutil"
 	"github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler"
 	"github.com/aws/amazon-ssm-agent/agent/log"
@@ -36,17 +42,46 @@ import (
 	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
 	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/session/datachannel"
+	"github.com/aws/amazon-ssm-agent/agent/session/sessionlimit"
+	"github.com/aws/amazon-ssm-agent/agent/session/telemetry"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 )
 
+// shellSessionCategory is the category of interactive shell sessions counted against the concurrent session limit
+const shellSessionCategory = "shell"
+
+// acquireSession registers a session against a limit of concurrent sessions
+var acquireSession = sessionlimit.Acquire
+
+// recordCrash reports a panic recovered in the plugin with the agent health
+var recordCrash = telemetry.RecordCrash
+
 // Plugin is the type for the plugin.
 type ShellPlugin struct {
-	name        string
-	stdin       *os.File
-	stdout      *os.File
-	ipcFilePath string
-	logFilePath string
-	dataChannel datachannel.IDataChannel
+	// lastInputTime is accessed atomically and kept first in the struct for 64-bit alignment
+	lastInputTime int64
+	name          string
+	stdin         *os.File
+	stdout        *os.File
+	ipcFilePath   string
+	logFilePath   string
+	dataChannel   datachannel.IDataChannel
+	recorder      *castRecorder
+	// input limits are only used by the goroutine handling incoming messages
+	inputRateLimiter    *inputRateLimiter
+	inputThrottled      bool
+	maxInputMessageSize int
+	resizer             *terminalResizer
+	// restricted takes the place of the interactive shell in restricted command mode
+	restricted *restrictedShell
+	// commandDigester logs the digests of the typed commands if command digests are enabled
+	commandDigester *commandDigester
+	// streamDataPayloadSize is the largest output payload sent over the data channel
+	streamDataPayloadSize int
+	// transcriptFilePath is the plain text transcript of the output streamed to CloudWatch, empty if streaming is disabled
+	transcriptFilePath string
+	transcript         *transcriptWriter
+	sessionId          string
 }
 
 type IShellPlugin interface {
@@ -60,6 +95,11 @@ func NewPlugin(name string) (*ShellPlugin, error) {
 	return &plugin, nil
 }
 
+// newKMSService creates the KMS client used for client side encryption of session logs
+var newKMSService = func(log log.T) (crypto.IKMSService, error) {
+	return crypto.NewKMSService(log)
+}
+
 // validate validates the cloudwatch and s3 encryption configuration.
 func (p *ShellPlugin) validate(context context.T,
 	config agentContracts.Configuration,
@@ -96,11 +136,6 @@ func (p *ShellPlugin) Execute(context context.T,
 		if err := Stop(log); err != nil {
 			log.Errorf("Error occurred while closing pty: %v", err)
 		}
-		if err := recover(); err != nil {
-			log.Errorf("Error occurred while executing plugin %s: \n%v", p.name, err)
-			log.Flush()
-			os.Exit(1)
-		}
 	}()
 
 	if cancelFlag.ShutDown() {
@@ -112,8 +147,26 @@ func (p *ShellPlugin) Execute(context context.T,
 	}
 }
 
-var startPty = func(log log.T, shellProps mgsContracts.ShellProperties, isSessionLogger bool, config agentContracts.Configuration) (stdin *os.File, stdout *os.File, err error) {
-	return StartPty(log, shellProps, isSessionLogger, config)
+var startPty = func(log log.T, shellProps mgsContracts.ShellProperties, isSessionLogger bool, config agentContracts.Configuration, shell string) (stdin *os.File, stdout *os.File, err error) {
+	return StartPty(log, shellProps, isSessionLogger, config, shell)
+}
+
+// lookPath finds the shells configured for sessions on the instance
+var lookPath = exec.LookPath
+
+// resolveShell returns the path of the first configured shell found on the instance,
+// or an empty string to start the platform default shell
+func resolveShell(log log.T, shells []string) string {
+	for _, shell := range shells {
+		if strings.TrimSpace(shell) == "" {
+			continue
+		}
+		if path, err := lookPath(shell); err == nil {
+			return path
+		}
+		log.Warnf("Configured shell %s is not available", shell)
+	}
+	return ""
 }
 
 // execute starts pseudo terminal.
@@ -128,6 +181,15 @@ func (p *ShellPlugin) execute(context context.T,
 	log := context.Log()
 	var err error
 	sessionPluginResultOutput := mgsContracts.SessionPluginResultOutput{}
+	p.sessionId = config.SessionId
+
+	// Command digests take the place of the session transcripts
+	digestOnly := context.AppConfig().Mgs.SessionCommandDigestMode == appconfig.SessionCommandDigestOnly
+	if digestOnly && (config.OutputS3BucketName != "" || config.CloudWatchLogGroup != "") {
+		log.Info("Session logs are not uploaded since only command digests are logged")
+		config.OutputS3BucketName = ""
+		config.CloudWatchLogGroup = ""
+	}
 
 	var cwl cloudwatchlogsinterface.ICloudWatchLogsService
 	var s3Util s3util.IAmazonS3Util
@@ -146,14 +208,110 @@ func (p *ShellPlugin) execute(context context.T,
 		return
 	}
 
-	p.stdin, p.stdout, err = startPty(log, shellProps, false, config)
+	if err = checkSessionRunAsPolicy(log, context.AppConfig().Mgs, shellProps, config); err != nil {
+		errorString := fmt.Errorf("Unable to start shell: %s", err)
+		log.Error(errorString)
+		output.MarkAsFailed(errorString)
+		return
+	}
+
+	releaseSession, err := p.acquireSessionSlot(log, config.SessionId, context.AppConfig().Mgs)
 	if err != nil {
+		log.Error(err)
+		output.SetExitCode(appconfig.ErrorExitCode)
+		output.SetStatus(agentContracts.ResultStatusFailed)
+		sessionPluginResultOutput.Output = err.Error()
+		output.SetOutput(sessionPluginResultOutput)
+		return
+	}
+	defer releaseSession()
+
+	if p.commandDigester, err = newCommandDigester(log, context.AppConfig().Mgs, config, sessionIdentity(shellProps, config)); err != nil {
 		errorString := fmt.Errorf("Unable to start shell: %s", err)
 		log.Error(errorString)
 		output.MarkAsFailed(errorString)
 		return
 	}
 
+	config.EnvironmentVariables = sessionEnvironmentVariables(log, context.AppConfig().Mgs, config.EnvironmentVariables)
+
+	// Give the session a scratch directory removed when the session ends
+	scratchDir, removeScratchDir, err := createScratchDirectory(log, config.SessionId)
+	if err != nil {
+		log.Warnf("Starting session without scratch directory: %v", err)
+	} else {
+		defer removeScratchDir()
+		config.EnvironmentVariables[sessionTmpEnvVariable] = scratchDir
+	}
+
+	p.streamDataPayloadSize = datachannel.GetStreamDataPayloadSize(context.AppConfig().Mgs, p.name)
+
+	// Limit the size and rate of the input written to the shell
+	p.maxInputMessageSize = context.AppConfig().Mgs.SessionMaxInputMessageSize
+	if rate := context.AppConfig().Mgs.SessionInputRateLimitBytesPerSecond; rate > 0 {
+		p.inputRateLimiter = newInputRateLimiter(rate)
+	}
+
+	// Limit the terminal size and debounce resizing
+	p.resizer = newTerminalResizer(time.Duration(context.AppConfig().Mgs.SessionResizeDebounceMilliseconds)*time.Millisecond,
+		context.AppConfig().Mgs.SessionMaxTerminalColumns,
+		context.AppConfig().Mgs.SessionMaxTerminalRows,
+		func(size mgsContracts.SizeData) error {
+			return p.applySize(log, size)
+		})
+
+	// The session user is created when the shell starts
+	var scratchOwnerOnce sync.Once
+	setupScratchOwner := func() {
+		scratchOwnerOnce.Do(func() {
+			if scratchDir == "" {
+				return
+			}
+			if err := setScratchOwner(log, scratchDir, sessionRunAsUser(shellProps, config)); err != nil {
+				log.Warnf("Unable to set the owner of scratch directory %s: %v", scratchDir, err)
+			}
+		})
+	}
+
+	shell := resolveShell(log, configuredShells(context.AppConfig().Mgs))
+	if context.AppConfig().Mgs.SessionRestrictedCommandsEnabled {
+		p.stdin, p.stdout, err = p.startRestricted(log, config, shellProps, shell,
+			context.AppConfig().Mgs.SessionAllowedCommands, setupScratchOwner)
+	} else {
+		p.stdin, p.stdout, err = startPty(log, shellProps, false, config, shell)
+	}
+	if err != nil {
+		errorString := fmt.Errorf("Unable to start shell: %s", err)
+		log.Error(errorString)
+		output.MarkAsFailed(errorString)
+		return
+	}
+
+	// Shell profiles would be typed as commands of the restricted shell
+	if p.restricted != nil {
+		defer p.restricted.close()
+	} else {
+		setupScratchOwner()
+		if err = p.runShellProfile(log, shellProfiles(context.AppConfig().Mgs, shellProps, config, shell)); err != nil {
+			errorString := fmt.Errorf("Unable to run shell profile: %s", err)
+			log.Error(errorString)
+			output.MarkAsFailed(errorString)
+			return
+		}
+	}
+
+	if mgsSettings := context.AppConfig().Mgs; mgsSettings.SessionRecordingEnabled && !digestOnly {
+		if p.recorder, err = newCastRecorder(config.OrchestrationDirectory,
+			config.SessionId,
+			mgsSettings.SessionRecordingMaxFileSizeMB,
+			mgsSettings.SessionRecordingMaxFiles); err != nil {
+			log.Errorf("Unable to start session recording: %v", err)
+		} else {
+			defer p.recorder.close()
+		}
+	}
+	defer p.resizer.stop()
+
 	// Generate ipc file path
 	p.ipcFilePath = filepath.Join(config.OrchestrationDirectory, mgsConfig.IpcFileName+mgsConfig.LogFileExtension)
 
@@ -171,6 +329,34 @@ func (p *ShellPlugin) execute(context context.T,
 		log.Debugf("Cancel flag set to %v in session", cancelState)
 	}()
 
+	// Stream the session output to CloudWatch while the session is running if streaming is enabled
+	streamingEnabled := config.CloudWatchLogGroup != "" && config.CloudWatchStreamingEnabled
+	if streamingEnabled {
+		log.Debugf("Streaming session output to CloudWatch log group %s", config.CloudWatchLogGroup)
+		p.transcriptFilePath = filepath.Join(config.OrchestrationDirectory, mgsConfig.TranscriptFileName+mgsConfig.LogFileExtension)
+		go cwl.StreamData(log, config.CloudWatchLogGroup, config.SessionId, p.transcriptFilePath, false, false)
+	}
+
+	// Terminate the session after the configured time without keyboard input
+	idleTimedOut := make(chan bool, 1)
+	if idleTimeout := time.Duration(context.AppConfig().Mgs.SessionIdleTimeoutMinutes) * time.Minute; idleTimeout > 0 {
+		p.updateLastInputTime()
+		stopIdleMonitor := make(chan bool)
+		defer close(stopIdleMonitor)
+		go p.monitorIdleTimeout(log, idleTimeout, idleTimedOut, stopIdleMonitor)
+	}
+
+	// Terminate commands running longer than the configured timeout
+	var commandTimedOut <-chan time.Time
+	commandTimeout := time.Duration(context.AppConfig().Mgs.InteractiveCommandsTimeoutSeconds) * time.Second
+	if p.name == appconfig.PluginNameInteractiveCommands && commandTimeout > 0 {
+		commandTimer := time.NewTimer(commandTimeout)
+		defer commandTimer.Stop()
+		commandTimedOut = commandTimer.C
+	}
+
+	p.showBanner(log, context.AppConfig().Mgs)
+
 	log.Debugf("Start separate go routine to read from pty stdout and write to data channel")
 	done := make(chan int, 1)
 	go func() {
@@ -187,6 +373,25 @@ func (p *ShellPlugin) execute(context context.T,
 		output.SetStatus(agentContracts.ResultStatusSuccess)
 		log.Info("The session was cancelled")
 
+	case <-idleTimedOut:
+		if err = p.dataChannel.SendAgentSessionStateMessage(log, mgsContracts.Terminating); err != nil {
+			log.Errorf("Unable to send AgentSessionState message with session status %s. %v", mgsContracts.Terminating, err)
+		}
+		output.SetExitCode(appconfig.SuccessExitCode)
+		output.SetStatus(agentContracts.ResultStatusSuccess)
+		log.Info("The session was terminated due to inactivity")
+
+	case <-commandTimedOut:
+		if err = terminate(log); err != nil {
+			log.Errorf("Unable to terminate the commands of the session: %v", err)
+		}
+		output.SetExitCode(appconfig.CommandStoppedPreemptivelyExitCode)
+		output.SetStatus(agentContracts.ResultStatusTimedOut)
+		output.SetOutput(mgsContracts.SessionPluginResultOutput{
+			Output: fmt.Sprintf("The commands did not complete within the timeout of %v", commandTimeout),
+		})
+		log.Infof("The commands of the session timed out after %v", commandTimeout)
+
 	case exitCode := <-done:
 		if exitCode == 1 {
 			output.SetExitCode(appconfig.ErrorExitCode)
@@ -214,14 +419,21 @@ func (p *ShellPlugin) execute(context context.T,
 		log.Debug("Starting S3 logging")
 		if config.OutputS3BucketName != "" {
 			s3KeyPrefix := fileutil.BuildS3Path(config.OutputS3KeyPrefix, logFileName)
+			if config.S3ClientSideKmsKeyId != "" {
+				s3KeyPrefix += mgsConfig.EncryptedLogFileExtension
+			}
 			p.uploadShellSessionLogsToS3(log, s3Util, config, s3KeyPrefix)
 			sessionPluginResultOutput.S3Bucket = config.OutputS3BucketName
 			sessionPluginResultOutput.S3UrlSuffix = s3KeyPrefix
 		}
 
 		log.Debug("Starting CloudWatch logging")
-		if config.CloudWatchLogGroup != "" {
+		if streamingEnabled {
+			p.finishCloudWatchStreaming(log, cwl)
+		} else if config.CloudWatchLogGroup != "" {
 			cwl.StreamData(log, config.CloudWatchLogGroup, config.SessionId, p.logFilePath, true, false)
+		}
+		if config.CloudWatchLogGroup != "" {
 			sessionPluginResultOutput.CwlGroup = config.CloudWatchLogGroup
 			sessionPluginResultOutput.CwlStream = config.SessionId
 		}
@@ -235,20 +447,53 @@ func (p *ShellPlugin) execute(context context.T,
 func (p *ShellPlugin) uploadShellSessionLogsToS3(log log.T, s3UploaderUtil s3util.IAmazonS3Util, config agentContracts.Configuration, s3KeyPrefix string) {
 	log.Debugf("Preparing to upload session logs to S3 bucket %s and prefix %s", config.OutputS3BucketName, s3KeyPrefix)
 
-	if err := s3UploaderUtil.S3Upload(log, config.OutputS3BucketName, s3KeyPrefix, p.logFilePath); err != nil {
+	uploadFilePath := p.logFilePath
+	if config.S3ClientSideKmsKeyId != "" {
+		// Never upload the plaintext logs when client side encryption is requested
+		uploadFilePath = p.logFilePath + mgsConfig.EncryptedLogFileExtension
+		if err := p.encryptShellSessionLogs(log, config, uploadFilePath); err != nil {
+			log.Errorf("Failed to encrypt shell session logs, skipping upload to S3: %s", err)
+			return
+		}
+		defer os.Remove(uploadFilePath)
+	}
+
+	if err := s3UploaderUtil.S3Upload(log, config.OutputS3BucketName, s3KeyPrefix, uploadFilePath); err != nil {
 		log.Errorf("Failed to upload shell session logs to S3: %s", err)
 	}
 }
 
+// encryptShellSessionLogs envelope encrypts the shell session logs with a data key generated under the configured KMS key
+func (p *ShellPlugin) encryptShellSessionLogs(log log.T, config agentContracts.Configuration, encryptedFilePath string) error {
+	kmsService, err := newKMSService(log)
+	if err != nil {
+		return err
+	}
+	encryptionContext := map[string]string{mgsConfig.EncryptionContextSessionIdKey: config.SessionId}
+	return crypto.EnvelopeEncryptFile(kmsService, config.S3ClientSideKmsKeyId, encryptionContext, p.logFilePath, encryptedFilePath)
+}
+
+// finishCloudWatchStreaming marks the streamed session output complete and waits for the remaining output to be uploaded.
+func (p *ShellPlugin) finishCloudWatchStreaming(log log.T, cwl cloudwatchlogsinterface.ICloudWatchLogsService) {
+	cwl.SetIsFileComplete(true)
+	if !cwl.WaitForUploadComplete(time.Duration(mgsConfig.CloudWatchStreamingCompleteRetries) * cloudwatchlogspublisher.UploadFrequency) {
+		log.Warn("Timed out waiting for session output to be streamed to CloudWatch")
+	}
+}
+
 // writePump reads from pty stdout and writes to data channel.
 func (p *ShellPlugin) writePump(log log.T) (errorCode int) {
 	defer func() {
 		if err := recover(); err != nil {
-			fmt.Println("WritePump thread crashed with message: \n", err)
+			log.Errorf("WritePump thread crashed with message: \n%v", err)
+			stackTrace := debug.Stack()
+			log.Errorf("%s: %s", err, stackTrace)
+			recordCrash(log, p.sessionId, p.name, err, stackTrace)
+			errorCode = appconfig.ErrorExitCode
 		}
 	}()
 
-	stdoutBytes := make([]byte, mgsConfig.StreamDataPayloadSize)
+	stdoutBytes := make([]byte, p.streamDataPayloadSize)
 	reader := bufio.NewReader(p.stdout)
 
 	// Create ipc file
@@ -259,11 +504,25 @@ func (p *ShellPlugin) writePump(log log.T) (errorCode int) {
 	}
 	defer file.Close()
 
+	// Create the transcript streamed to CloudWatch
+	if p.transcriptFilePath != "" {
+		transcriptFile, err := os.Create(p.transcriptFilePath)
+		if err != nil {
+			log.Errorf("Encountered an error while creating transcript file: %s", err)
+			return appconfig.ErrorExitCode
+		}
+		defer transcriptFile.Close()
+		p.transcript = newTranscriptWriter(transcriptFile)
+		defer p.transcript.Close()
+	}
+
 	// Wait for all input commands to run.
 	time.Sleep(time.Second)
 
 	var unprocessedBuf bytes.Buffer
 	for {
+		// Stop reading output while the client has not acknowledged enough of the output sent
+		p.dataChannel.WaitForSendWindow()
 		stdoutBytesLen, err := reader.Read(stdoutBytes)
 		if err != nil {
 			// Terminating session
@@ -330,10 +589,22 @@ func (p *ShellPlugin) processStdoutData(
 		return processedBuf, fmt.Errorf("unable to send stream data message: %s", err)
 	}
 
+	if p.recorder != nil {
+		if err := p.recorder.recordOutput(processedBuf.Bytes()); err != nil {
+			log.Warnf("Unable to record session output: %v", err)
+		}
+	}
+
 	if _, err := file.Write(processedBuf.Bytes()); err != nil {
 		return processedBuf, fmt.Errorf("encountered an error while writing to file: %s", err)
 	}
 
+	if p.transcript != nil {
+		if _, err := p.transcript.Write(processedBuf.Bytes()); err != nil {
+			return processedBuf, fmt.Errorf("encountered an error while writing to transcript file: %s", err)
+		}
+	}
+
 	// return incomplete utf8 encoded unicode bytes to be processed with next batch of stdoutBytes
 	unprocessedBuf.Reset()
 	if i < unprocessedBytesLen {
@@ -341,3 +612,80 @@ func (p *ShellPlugin) processStdoutData(
 	}
 	return unprocessedBuf, nil
 }
+
+// recordInput records data typed by the user if session recording is enabled
+func (p *ShellPlugin) recordInput(log log.T, data []byte) {
+	if p.recorder == nil {
+		return
+	}
+	if err := p.recorder.recordInput(data); err != nil {
+		log.Warnf("Unable to record session input: %v", err)
+	}
+}
+
+// digestInput logs the digests of the commands typed by the user if command digests are enabled
+func (p *ShellPlugin) digestInput(log log.T, data []byte) {
+	if p.commandDigester == nil {
+		return
+	}
+	p.commandDigester.process(log, data)
+}
+
+// recordResize records a terminal size change if session recording is enabled
+func (p *ShellPlugin) recordResize(log log.T, size mgsContracts.SizeData) {
+	if p.recorder == nil {
+		return
+	}
+	if err := p.recorder.recordResize(size.Cols, size.Rows); err != nil {
+		log.Warnf("Unable to record terminal resize: %v", err)
+	}
+}
+
+// acquireSessionSlot registers interactive shell sessions against the configured limit of concurrent sessions,
+// so that a runaway automation cannot exhaust the pseudo terminals and memory of the instance
+func (p *ShellPlugin) acquireSessionSlot(log log.T, sessionId string, config appconfig.MgsConfig) (release func(), err error) {
+	if p.name != appconfig.PluginNameStandardStream {
+		return func() {}, nil
+	}
+
+	limit := config.StandardStreamSessionsLimit
+	if release, err = acquireSession(log, shellSessionCategory, sessionId, limit); err == sessionlimit.ErrLimitReached {
+		return release, fmt.Errorf("Maximum number of concurrent %s sessions (%d) reached on this instance", p.name, limit)
+	}
+	return release, err
+}
+
+// terminate kills the processes of the session
+var terminate = Terminate
+
+// sendSignal delivers the terminal signal to the processes running in the session
+var sendSignal = SendSignal
+
+// processSignal delivers the signal requested by the client to the session
+func (p *ShellPlugin) processSignal(log log.T, payload []byte) error {
+	var signal mgsContracts.SignalData
+	if err := json.Unmarshal(payload, &signal); err != nil {
+		log.Errorf("Invalid signal message: %s", err)
+		return err
+	}
+	log.Debugf("Signal %s received", signal.Signal)
+	if err := sendSignal(log, signal.Signal); err != nil {
+		log.Errorf("Unable to deliver signal %s: %v", signal.Signal, err)
+		return err
+	}
+	return nil
+}
+
+// runShellProfile writes the shell profile commands to the shell before the user gets control of it
+func (p *ShellPlugin) runShellProfile(log log.T, profiles []string) error {
+	for _, profile := range profiles {
+		if strings.TrimSpace(profile) == "" {
+			continue
+		}
+		log.Debugf("Running shell profile")
+		if _, err := p.stdin.Write([]byte(profile + newLineCharacter)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -16,19 +16,28 @@ package shell
 
 import (
 	"bytes"
+	"errors"
 	"io/ioutil"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 	"time"
 
 	cloudwatchlogspublisher_mock "github.com/aws/amazon-ssm-agent/agent/agentlogstocloudwatch/cloudwatchlogspublisher/mock"
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/context"
 	"github.com/aws/amazon-ssm-agent/agent/contracts"
+	"github.com/aws/amazon-ssm-agent/agent/crypto"
+	cryptoMocks "github.com/aws/amazon-ssm-agent/agent/crypto/mocks"
 	iohandlermocks "github.com/aws/amazon-ssm-agent/agent/framework/processor/executer/iohandler/mock"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/s3util"
+	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
 	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
 	dataChannelMock "github.com/aws/amazon-ssm-agent/agent/session/datachannel/mocks"
+	"github.com/aws/amazon-ssm-agent/agent/session/sessionlimit"
+	"github.com/aws/amazon-ssm-agent/agent/session/telemetry"
 	"github.com/aws/amazon-ssm-agent/agent/task"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -128,10 +137,13 @@ func (suite *ShellTestSuite) TestExecute() {
 	suite.mockIohandler.On("SetExitCode", 0).Return(nil)
 	suite.mockIohandler.On("SetStatus", contracts.ResultStatusSuccess).Return()
 	suite.mockIohandler.On("SetOutput", mock.Anything).Return()
+	suite.mockDataChannel.On("SendStreamDataMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.mockDataChannel.On("SendAgentSessionStateMessage", mock.Anything, mgsContracts.Terminating).Return(nil)
+	suite.mockDataChannel.On("WaitForSendWindow").Return()
 
 	stdout, stdin, _ := os.Pipe()
 	stdin.Write(payload)
-	startPty = func(log log.T, shellProps mgsContracts.ShellProperties, isSessionLogger bool, config contracts.Configuration) (stdin *os.File, stdout *os.File, err error) {
+	startPty = func(log log.T, shellProps mgsContracts.ShellProperties, isSessionLogger bool, config contracts.Configuration, shell string) (stdin *os.File, stdout *os.File, err error) {
 		return stdin, stdout, nil
 	}
 	plugin := &ShellPlugin{
@@ -153,6 +165,45 @@ func (suite *ShellTestSuite) TestExecute() {
 	stdout.Close()
 }
 
+// Testing Execute of commands exceeding the timeout
+func (suite *ShellTestSuite) TestExecuteCommandTimeout() {
+	config := appconfig.DefaultConfig()
+	config.Mgs.InteractiveCommandsTimeoutSeconds = 1
+	mockContext := new(context.Mock)
+	mockContext.On("AppConfig").Return(config)
+	mockContext.On("Log").Return(mockLog)
+
+	suite.mockCancelFlag.On("Canceled").Return(false)
+	suite.mockCancelFlag.On("ShutDown").Return(false)
+	suite.mockCancelFlag.On("Wait").Return(task.Completed)
+	suite.mockIohandler.On("SetExitCode", appconfig.CommandStoppedPreemptivelyExitCode).Return()
+	suite.mockIohandler.On("SetStatus", contracts.ResultStatusTimedOut).Return()
+	suite.mockIohandler.On("SetOutput", mock.Anything).Return()
+
+	stdout, stdin, _ := os.Pipe()
+	startPty = func(log log.T, shellProps mgsContracts.ShellProperties, isSessionLogger bool, config contracts.Configuration, shell string) (*os.File, *os.File, error) {
+		return stdin, stdout, nil
+	}
+	terminated := false
+	terminate = func(log log.T) error {
+		terminated = true
+		return stdin.Close()
+	}
+	defer func() { terminate = Terminate }()
+
+	plugin := &ShellPlugin{name: appconfig.PluginNameInteractiveCommands}
+	plugin.Execute(mockContext,
+		contracts.Configuration{},
+		suite.mockCancelFlag,
+		suite.mockIohandler,
+		suite.mockDataChannel,
+		mgsContracts.ShellProperties{})
+
+	assert.True(suite.T(), terminated)
+	suite.mockIohandler.AssertExpectations(suite.T())
+	stdout.Close()
+}
+
 // Testing writepump separately
 func (suite *ShellTestSuite) TestWritePump() {
 	stdout, stdin, _ := os.Pipe()
@@ -161,11 +212,13 @@ func (suite *ShellTestSuite) TestWritePump() {
 	//suite.mockDataChannel := &dataChannelMock.IDataChannel{}
 	suite.mockDataChannel.On("SendStreamDataMessage", mock.Anything, mock.Anything, payload).Return(nil)
 	suite.mockDataChannel.On("SendAgentSessionStateMessage", mock.Anything, mgsContracts.Terminating).Return(nil)
+	suite.mockDataChannel.On("WaitForSendWindow").Return()
 
 	plugin := &ShellPlugin{
-		stdout:      stdout,
-		ipcFilePath: "test.log",
-		dataChannel: suite.mockDataChannel,
+		stdout:                stdout,
+		ipcFilePath:           "test.log",
+		dataChannel:           suite.mockDataChannel,
+		streamDataPayloadSize: mgsConfig.StreamDataPayloadSize,
 	}
 
 	// Spawning a separate go routine to close read and write pipes after a few seconds.
@@ -181,6 +234,37 @@ func (suite *ShellTestSuite) TestWritePump() {
 	suite.mockDataChannel.AssertExpectations(suite.T())
 }
 
+// Testing that a crash of writepump is recorded with its stack trace
+func (suite *ShellTestSuite) TestWritePumpRecordsCrash() {
+	stdout, stdin, _ := os.Pipe()
+	defer stdout.Close()
+	defer stdin.Close()
+	stdin.Write(payload)
+
+	suite.mockDataChannel.On("WaitForSendWindow").Return()
+	suite.mockDataChannel.On("SendStreamDataMessage", mock.Anything, mock.Anything, payload).
+		Run(func(args mock.Arguments) { panic("send failure") })
+
+	var crashedSessionId string
+	recordCrash = func(log log.T, sessionId string, pluginName string, msg interface{}, stackTrace []byte) {
+		crashedSessionId = sessionId
+		assert.Equal(suite.T(), "send failure", msg)
+		assert.NotEmpty(suite.T(), stackTrace)
+	}
+	defer func() { recordCrash = telemetry.RecordCrash }()
+
+	plugin := &ShellPlugin{
+		stdout:                stdout,
+		ipcFilePath:           "test.log",
+		dataChannel:           suite.mockDataChannel,
+		streamDataPayloadSize: mgsConfig.StreamDataPayloadSize,
+		sessionId:             "sessionId",
+	}
+
+	assert.Equal(suite.T(), appconfig.ErrorExitCode, plugin.writePump(suite.mockLog))
+	assert.Equal(suite.T(), "sessionId", crashedSessionId)
+}
+
 // Testing writepump for scenario when shell can give non utf8 characters
 func (suite *ShellTestSuite) TestWritePumpForInvalidUtf8Character() {
 	// invalidUtf8Payload contains 200 which is an invalid utf8 character
@@ -192,11 +276,13 @@ func (suite *ShellTestSuite) TestWritePumpForInvalidUtf8Character() {
 	//suite.mockDataChannel := &dataChannelMock.IDataChannel{}
 	suite.mockDataChannel.On("SendStreamDataMessage", mock.Anything, mock.Anything, invalidUtf8Payload).Return(nil)
 	suite.mockDataChannel.On("SendAgentSessionStateMessage", mock.Anything, mgsContracts.Terminating).Return(nil)
+	suite.mockDataChannel.On("WaitForSendWindow").Return()
 
 	plugin := &ShellPlugin{
-		stdout:      stdout,
-		ipcFilePath: "test.log",
-		dataChannel: suite.mockDataChannel,
+		stdout:                stdout,
+		ipcFilePath:           "test.log",
+		dataChannel:           suite.mockDataChannel,
+		streamDataPayloadSize: mgsConfig.StreamDataPayloadSize,
 	}
 
 	// Spawning a separate go routine to close read and write pipes after a few seconds.
@@ -249,6 +335,129 @@ func (suite *ShellTestSuite) TestProcessStreamMessage() {
 	assert.Equal(suite.T(), "testPayload", string(stdinFileContent))
 }
 
+func (suite *ShellTestSuite) TestRunShellProfile() {
+	stdinFile, _ := ioutil.TempFile("/tmp", "stdin")
+	defer os.Remove(stdinFile.Name())
+	plugin := &ShellPlugin{
+		stdin: stdinFile,
+	}
+	mgs := appconfig.MgsConfig{
+		DefaultShellProfileLinux:   "umask 027",
+		DefaultShellProfileWindows: "umask 027",
+	}
+	config := contracts.Configuration{
+		ShellProfile: contracts.ShellProfileConfig{Linux: "cd ~", Windows: "cd ~"},
+	}
+
+	profiles := shellProfiles(mgs, mgsContracts.ShellProperties{}, config, "")
+	assert.Nil(suite.T(), plugin.runShellProfile(mockLog, profiles))
+
+	stdinFileContent, _ := ioutil.ReadFile(stdinFile.Name())
+	assert.Equal(suite.T(), "umask 027"+newLineCharacter+"cd ~"+newLineCharacter, string(stdinFileContent))
+}
+
+func (suite *ShellTestSuite) TestShellProfilesSkippedForCommands() {
+	mgs := appconfig.MgsConfig{
+		DefaultShellProfileLinux:   "umask 027",
+		DefaultShellProfileWindows: "umask 027",
+	}
+	shellProps := mgsContracts.ShellProperties{
+		Linux:   mgsContracts.ShellConfig{Commands: "ls"},
+		Windows: mgsContracts.ShellConfig{Commands: "ls"},
+	}
+
+	assert.Empty(suite.T(), shellProfiles(mgs, shellProps, contracts.Configuration{}, ""))
+}
+
+func (suite *ShellTestSuite) TestResolveShell() {
+	lookPath = func(file string) (string, error) {
+		if file == "bash" {
+			return "/bin/bash", nil
+		}
+		return "", errors.New("executable file not found")
+	}
+	defer func() { lookPath = exec.LookPath }()
+
+	assert.Equal(suite.T(), "/bin/bash", resolveShell(mockLog, []string{"zsh", "bash", "fish"}))
+	assert.Equal(suite.T(), "", resolveShell(mockLog, []string{"zsh"}))
+	assert.Equal(suite.T(), "", resolveShell(mockLog, nil))
+}
+
+func (suite *ShellTestSuite) TestProcessSignal() {
+	var delivered string
+	sendSignal = func(log log.T, name string) error {
+		delivered = name
+		return nil
+	}
+	defer func() { sendSignal = SendSignal }()
+
+	plugin := &ShellPlugin{}
+	assert.Nil(suite.T(), plugin.processSignal(mockLog, []byte(`{"signal":"SIGINT"}`)))
+	assert.Equal(suite.T(), mgsContracts.SignalInterrupt, delivered)
+	assert.NotNil(suite.T(), plugin.processSignal(mockLog, []byte("not json")))
+}
+
+func (suite *ShellTestSuite) TestSendUnsupportedSignal() {
+	assert.NotNil(suite.T(), SendSignal(mockLog, "SIGKILL"))
+}
+
+func (suite *ShellTestSuite) TestLimitInputDiscardsLargeInput() {
+	suite.mockDataChannel.On("SendStreamDataMessage", mock.Anything, mgsContracts.Output, mock.Anything).Return(nil)
+	suite.plugin.dataChannel = suite.mockDataChannel
+	suite.plugin.maxInputMessageSize = 4
+
+	assert.True(suite.T(), suite.plugin.limitInput(mockLog, []byte("ls")))
+	assert.False(suite.T(), suite.plugin.limitInput(mockLog, []byte("pasted text")))
+	suite.mockDataChannel.AssertNumberOfCalls(suite.T(), "SendStreamDataMessage", 1)
+}
+
+func (suite *ShellTestSuite) TestLimitInputThrottles() {
+	var delays []time.Duration
+	sleep = func(d time.Duration) { delays = append(delays, d) }
+	defer func() { sleep = time.Sleep }()
+	start := time.Now()
+	rateLimiterNow = func() time.Time { return start }
+	defer func() { rateLimiterNow = time.Now }()
+	suite.mockDataChannel.On("SendStreamDataMessage", mock.Anything, mgsContracts.Output, mock.Anything).Return(nil)
+	suite.plugin.dataChannel = suite.mockDataChannel
+	suite.plugin.inputRateLimiter = newInputRateLimiter(10)
+
+	// the first second of input is written without delay
+	assert.True(suite.T(), suite.plugin.limitInput(mockLog, make([]byte, 10)))
+	assert.Empty(suite.T(), delays)
+
+	// input above the rate is delayed and the user is warned only once
+	assert.True(suite.T(), suite.plugin.limitInput(mockLog, make([]byte, 5)))
+	assert.True(suite.T(), suite.plugin.limitInput(mockLog, make([]byte, 5)))
+	assert.Equal(suite.T(), 2, len(delays))
+	assert.True(suite.T(), delays[1] > delays[0])
+	suite.mockDataChannel.AssertNumberOfCalls(suite.T(), "SendStreamDataMessage", 1)
+}
+
+func (suite *ShellTestSuite) TestAcquireSessionSlotLimitReached() {
+	acquireSession = func(log log.T, category string, sessionId string, limit int) (func(), error) {
+		assert.Equal(suite.T(), shellSessionCategory, category)
+		assert.Equal(suite.T(), 2, limit)
+		return func() {}, sessionlimit.ErrLimitReached
+	}
+	defer func() { acquireSession = sessionlimit.Acquire }()
+
+	plugin := &ShellPlugin{name: appconfig.PluginNameStandardStream}
+	_, err := plugin.acquireSessionSlot(mockLog, "sessionId", appconfig.MgsConfig{StandardStreamSessionsLimit: 2})
+	assert.Equal(suite.T(), "Maximum number of concurrent Standard_Stream sessions (2) reached on this instance", err.Error())
+}
+
+func (suite *ShellTestSuite) TestAcquireSessionSlotNotLimitedForCommands() {
+	acquireSession = func(log log.T, category string, sessionId string, limit int) (func(), error) {
+		return func() {}, sessionlimit.ErrLimitReached
+	}
+	defer func() { acquireSession = sessionlimit.Acquire }()
+
+	plugin := &ShellPlugin{name: appconfig.PluginNameInteractiveCommands}
+	_, err := plugin.acquireSessionSlot(mockLog, "sessionId", appconfig.MgsConfig{StandardStreamSessionsLimit: 2})
+	assert.Nil(suite.T(), err)
+}
+
 //Execute the test suite
 func TestShellTestSuite(t *testing.T) {
 	suite.Run(t, new(ShellTestSuite))
@@ -270,6 +479,59 @@ func getAgentMessage(payloadType uint32, payload []byte) *mgsContracts.AgentMess
 	return &agentMessage
 }
 
+func (suite *ShellTestSuite) TestFinishCloudWatchStreaming() {
+	cwMock := new(cloudwatchlogspublisher_mock.CloudWatchLogsServiceMock)
+	cwMock.On("SetIsFileComplete", true).Return()
+	cwMock.On("WaitForUploadComplete", mock.Anything).Return(true)
+
+	suite.plugin.finishCloudWatchStreaming(suite.mockLog, cwMock)
+
+	cwMock.AssertExpectations(suite.T())
+}
+
+func (suite *ShellTestSuite) TestUploadShellSessionLogsToS3WithClientSideEncryption() {
+	dir, _ := ioutil.TempDir("", "shellsessionlogs")
+	defer os.RemoveAll(dir)
+	suite.plugin.logFilePath = filepath.Join(dir, "session-id.log")
+	ioutil.WriteFile(suite.plugin.logFilePath, []byte("session transcript"), 0600)
+
+	kmsService := &cryptoMocks.IKMSService{}
+	kmsService.On("GenerateDataKey", "kms-key", mock.Anything).Return([]byte("cipherTextKey"), bytes.Repeat([]byte{7}, 32), nil)
+	newKMSService = func(log log.T) (crypto.IKMSService, error) {
+		return kmsService, nil
+	}
+	s3Mock := new(s3util.MockS3Uploader)
+	s3Mock.On("S3Upload", "bucket", "prefix.encrypted", suite.plugin.logFilePath+mgsConfig.EncryptedLogFileExtension).Return(nil)
+
+	suite.plugin.uploadShellSessionLogsToS3(suite.mockLog, s3Mock,
+		contracts.Configuration{SessionId: "session-id", OutputS3BucketName: "bucket", S3ClientSideKmsKeyId: "kms-key"},
+		"prefix.encrypted")
+
+	kmsService.AssertExpectations(suite.T())
+	s3Mock.AssertExpectations(suite.T())
+}
+
+func (suite *ShellTestSuite) TestUploadShellSessionLogsToS3WhenClientSideEncryptionFails() {
+	dir, _ := ioutil.TempDir("", "shellsessionlogs")
+	defer os.RemoveAll(dir)
+	suite.plugin.logFilePath = filepath.Join(dir, "session-id.log")
+	ioutil.WriteFile(suite.plugin.logFilePath, []byte("session transcript"), 0600)
+
+	kmsService := &cryptoMocks.IKMSService{}
+	kmsService.On("GenerateDataKey", "kms-key", mock.Anything).Return(nil, nil, errors.New("access denied"))
+	newKMSService = func(log log.T) (crypto.IKMSService, error) {
+		return kmsService, nil
+	}
+	s3Mock := new(s3util.MockS3Uploader)
+
+	suite.plugin.uploadShellSessionLogsToS3(suite.mockLog, s3Mock,
+		contracts.Configuration{SessionId: "session-id", OutputS3BucketName: "bucket", S3ClientSideKmsKeyId: "kms-key"},
+		"prefix.encrypted")
+
+	// plaintext logs are never uploaded
+	s3Mock.AssertNotCalled(suite.T(), "S3Upload", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func (suite *ShellTestSuite) TestValidateCWLogGroupNotEncrypted() {
 	testCwLogGroupName := "testCW"
 	configuration := contracts.Configuration{
@@ -360,3 +622,50 @@ func (suite *ShellTestSuite) TestValidateBypassS3BucketEncryptionCheck() {
 	err := suite.plugin.validate(suite.mockContext, configuration, suite.mockCWL, suite.mockS3)
 	assert.Nil(suite.T(), err)
 }
+
+func (suite *ShellTestSuite) TestMonitorIdleTimeout() {
+	idleCheckInterval = 10 * time.Millisecond
+	defer func() { idleCheckInterval = mgsConfig.IdleTimeoutCheckInterval }()
+
+	suite.mockDataChannel.On("SendStreamDataMessage", mock.Anything, mgsContracts.Output, mock.Anything).Return(nil)
+	plugin := &ShellPlugin{dataChannel: suite.mockDataChannel}
+	plugin.updateLastInputTime()
+
+	timedOut := make(chan bool, 1)
+	stop := make(chan bool)
+	defer close(stop)
+	go plugin.monitorIdleTimeout(suite.mockLog, 200*time.Millisecond, timedOut, stop)
+
+	select {
+	case <-timedOut:
+	case <-time.After(2 * time.Second):
+		assert.Fail(suite.T(), "idle session was not timed out")
+	}
+	// the user is warned once before the session is terminated
+	suite.mockDataChannel.AssertNumberOfCalls(suite.T(), "SendStreamDataMessage", 1)
+}
+
+func (suite *ShellTestSuite) TestMonitorIdleTimeoutWithInput() {
+	idleCheckInterval = 10 * time.Millisecond
+	defer func() { idleCheckInterval = mgsConfig.IdleTimeoutCheckInterval }()
+
+	plugin := &ShellPlugin{dataChannel: suite.mockDataChannel}
+	plugin.updateLastInputTime()
+
+	timedOut := make(chan bool, 1)
+	stop := make(chan bool)
+	stopped := make(chan bool)
+	go func() {
+		plugin.monitorIdleTimeout(suite.mockLog, 200*time.Millisecond, timedOut, stop)
+		close(stopped)
+	}()
+
+	for i := 0; i < 10; i++ {
+		time.Sleep(30 * time.Millisecond)
+		plugin.updateLastInputTime()
+	}
+	close(stop)
+	<-stopped
+
+	assert.Equal(suite.T(), 0, len(timedOut))
+}
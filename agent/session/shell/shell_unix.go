@@ -26,6 +26,7 @@ import (
 	"strings"
 	"syscall"
 	"time"
+	"unsafe"
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	agentContracts "github.com/aws/amazon-ssm-agent/agent/contracts"
@@ -38,6 +39,9 @@ import (
 
 var ptyFile *os.File
 
+// ptyProcess is the shell started on the pty, it leads the process group of the session
+var ptyProcess *os.Process
+
 const (
 	termEnvVariable       = "TERM=xterm-256color"
 	langEnvVariable       = "LANG=C.UTF-8"
@@ -48,21 +52,66 @@ const (
 	homeEnvVariable       = "HOME=/home/"
 )
 
+// shellProfiles returns the profiles run at the start of an interactive shell session.
+// The agent profile runs before the profile provided by the session document.
+func shellProfiles(mgs appconfig.MgsConfig, shellProps mgsContracts.ShellProperties, config agentContracts.Configuration, shell string) []string {
+	if strings.TrimSpace(shellProps.Linux.Commands) != "" {
+		return nil
+	}
+	return []string{mgs.DefaultShellProfileLinux, config.ShellProfile.Linux}
+}
+
+// elevatedUserName is the user sessions run as when they run elevated
+const elevatedUserName = "root"
+
+// sessionRunAsUser returns the user the customer shell is started as, or an empty string if it runs elevated
+func sessionRunAsUser(shellProps mgsContracts.ShellProperties, config agentContracts.Configuration) string {
+	if shellProps.Linux.RunAsElevated {
+		return ""
+	}
+	if config.RunAsEnabled {
+		return strings.TrimSpace(config.RunAsUser)
+	}
+	return appconfig.DefaultRunAsUserName
+}
+
+// sessionCommands returns the commands of the session document
+func sessionCommands(shellProps mgsContracts.ShellProperties) string {
+	return shellProps.Linux.Commands
+}
+
+// withSessionCommands returns the shell properties running the commands instead of an interactive shell
+func withSessionCommands(shellProps mgsContracts.ShellProperties, commands string) mgsContracts.ShellProperties {
+	shellProps.Linux.Commands = commands
+	return shellProps
+}
+
+// configuredShells returns the shells configured for sessions in order of preference
+func configuredShells(mgs appconfig.MgsConfig) []string {
+	return mgs.SessionShellsLinux
+}
+
 //StartPty starts pty and provides handles to stdin and stdout
 // isSessionLogger determines whether its a customer shell or shell used for logging.
+// shell is the shell started for the customer, sh is used when it is empty.
 func StartPty(
 	log log.T,
 	shellProps mgsContracts.ShellProperties,
 	isSessionLogger bool,
-	config agentContracts.Configuration) (stdin *os.File, stdout *os.File, err error) {
+	config agentContracts.Configuration,
+	shell string) (stdin *os.File, stdout *os.File, err error) {
 	log.Info("Starting pty")
+	if shell == "" || isSessionLogger {
+		shell = "sh"
+	}
+
 	//Start the command with a pty
 	var cmd *exec.Cmd
 	if strings.TrimSpace(shellProps.Linux.Commands) == "" || isSessionLogger {
-		cmd = exec.Command("sh")
+		cmd = exec.Command(shell)
 	} else {
 		commandArgs := append(utility.ShellPluginCommandArgs, shellProps.Linux.Commands)
-		cmd = exec.Command("sh", commandArgs...)
+		cmd = exec.Command(shell, commandArgs...)
 	}
 
 	//TERM is set as linux by pty which has an issue where vi editor screen does not get cleared.
@@ -76,6 +125,10 @@ func StartPty(
 		cmd.Env = append(cmd.Env, langEnvVariable)
 	}
 
+	if !isSessionLogger {
+		cmd.Env = append(cmd.Env, environmentVariables(config.EnvironmentVariables)...)
+	}
+
 	if !shellProps.Linux.RunAsElevated && !isSessionLogger {
 		// We get here only when its a customer shell that needs to be started in a specific user mode.
 
@@ -119,10 +172,63 @@ func StartPty(
 		log.Errorf("Failed to start pty: %s\n", err)
 		return nil, nil, fmt.Errorf("Failed to start pty: %s\n", err)
 	}
+	ptyProcess = cmd.Process
 
 	return ptyFile, ptyFile, nil
 }
 
+// terminalSignals maps the signals clients can send to the signals delivered to the session
+var terminalSignals = map[string]syscall.Signal{
+	mgsContracts.SignalInterrupt: syscall.SIGINT,
+	mgsContracts.SignalStop:      syscall.SIGTSTP,
+	mgsContracts.SignalQuit:      syscall.SIGQUIT,
+}
+
+// SendSignal delivers the signal to the foreground process group of the pty, as the terminal driver does for control characters.
+func SendSignal(log log.T, name string) error {
+	sig, ok := terminalSignals[name]
+	if !ok {
+		return fmt.Errorf("unsupported signal %s", name)
+	}
+	if ptyFile == nil {
+		return errors.New("pty is not started")
+	}
+
+	rawConn, err := ptyFile.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var pgrp int32
+	var errno syscall.Errno
+	if err = rawConn.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, uintptr(syscall.TIOCGPGRP), uintptr(unsafe.Pointer(&pgrp)))
+	}); err != nil {
+		return err
+	}
+	if errno != 0 {
+		return fmt.Errorf("unable to get the foreground process group: %v", errno)
+	}
+
+	log.Debugf("Sending %s to process group %d", name, pgrp)
+	return syscall.Kill(-int(pgrp), sig)
+}
+
+// Terminate kills the process group of the shell, including the processes started by the commands of the session,
+// and reaps the shell.
+func Terminate(log log.T) error {
+	if ptyProcess == nil {
+		return errors.New("pty is not started")
+	}
+	log.Debugf("Killing process group %d", ptyProcess.Pid)
+	if err := syscall.Kill(-ptyProcess.Pid, syscall.SIGKILL); err != nil {
+		return fmt.Errorf("unable to kill process group %d: %v", ptyProcess.Pid, err)
+	}
+	if _, err := ptyProcess.Wait(); err != nil {
+		log.Debugf("Unable to reap shell process %d: %v", ptyProcess.Pid, err)
+	}
+	return nil
+}
+
 //Stop closes pty file.
 func Stop(log log.T) (err error) {
 	log.Info("Stopping pty")
@@ -219,7 +325,7 @@ func getUserCredentials(log log.T, sessionUser string) (uint32, uint32, []uint32
 
 // generateLogData generates a log file with the executed commands.
 func (p *ShellPlugin) generateLogData(log log.T, config agentContracts.Configuration) error {
-	shadowShellInput, _, err := StartPty(log, mgsContracts.ShellProperties{}, true, config)
+	shadowShellInput, _, err := StartPty(log, mgsContracts.ShellProperties{}, true, config, "")
 	if err != nil {
 		return err
 	}
@@ -294,10 +400,16 @@ func (p *ShellPlugin) InputStreamMessageHandler(log log.T, streamDataMessage mgs
 	switch mgsContracts.PayloadType(streamDataMessage.PayloadType) {
 	case mgsContracts.Output:
 		log.Tracef("Output message received: %d", streamDataMessage.SequenceNumber)
+		p.updateLastInputTime()
+		if !p.limitInput(log, streamDataMessage.Payload) {
+			return nil
+		}
 		if _, err := p.stdin.Write(streamDataMessage.Payload); err != nil {
 			log.Errorf("Unable to write to stdin, err: %v.", err)
 			return err
 		}
+		p.recordInput(log, streamDataMessage.Payload)
+		p.digestInput(log, streamDataMessage.Payload)
 	case mgsContracts.Size:
 		var size mgsContracts.SizeData
 		if err := json.Unmarshal(streamDataMessage.Payload, &size); err != nil {
@@ -305,10 +417,13 @@ func (p *ShellPlugin) InputStreamMessageHandler(log log.T, streamDataMessage mgs
 			return err
 		}
 		log.Tracef("Resize data received: cols: %d, rows: %d", size.Cols, size.Rows)
-		if err := SetSize(log, size.Cols, size.Rows); err != nil {
+		if err := p.processResize(log, size); err != nil {
 			log.Errorf("Unable to set pty size: %s", err)
 			return err
 		}
+	case mgsContracts.Signal:
+		log.Tracef("Signal message received: %d", streamDataMessage.SequenceNumber)
+		return p.processSignal(log, streamDataMessage.Payload)
 	}
 	return nil
 }
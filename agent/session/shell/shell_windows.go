@@ -19,6 +19,7 @@ package shell
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -39,12 +40,23 @@ import (
 	mgsConfig "github.com/aws/amazon-ssm-agent/agent/session/config"
 	mgsContracts "github.com/aws/amazon-ssm-agent/agent/session/contracts"
 	"github.com/aws/amazon-ssm-agent/agent/session/utility"
+	"github.com/aws/amazon-ssm-agent/agent/session/conpty"
 	"github.com/aws/amazon-ssm-agent/agent/session/winpty"
 )
 
-var pty *winpty.WinPTY
+// pseudoConsole is the console the shell of the session is attached to, implemented by ConPTY and winpty
+type pseudoConsole interface {
+	Stdio() (stdin *os.File, stdout *os.File)
+	SetSize(ws_col, ws_row uint32) error
+	Close() error
+}
+
+var pty pseudoConsole
 var u = &utility.SessionUtil{}
 
+// defaultUserEnabled is set when the session runs as ssm-user, which is disabled again when the session stops
+var defaultUserEnabled bool
+
 const (
 	defaultConsoleCol      = 200
 	defaultConsoleRow      = 60
@@ -67,26 +79,86 @@ var (
 	winptyDllFilePath = filepath.Join(winptyDllDir, winptyDllName)
 )
 
-//StartPty starts winpty agent and provides handles to stdin and stdout.
+// shellProfiles returns the profiles run at the start of an interactive shell session.
+// The console code page and locale are set up first, then the agent profile runs before the profile provided by the session document.
+func shellProfiles(mgs appconfig.MgsConfig, shellProps mgsContracts.ShellProperties, config agentContracts.Configuration, shell string) []string {
+	if strings.TrimSpace(shellProps.Windows.Commands) != "" {
+		return nil
+	}
+	return []string{consoleSetup(mgs, shell), mgs.DefaultShellProfileWindows, config.ShellProfile.Windows}
+}
+
+// elevatedUserName is the user sessions run as when they run elevated
+const elevatedUserName = "SYSTEM"
+
+// sessionRunAsUser returns the user the customer shell is started as, or an empty string if it runs elevated
+func sessionRunAsUser(shellProps mgsContracts.ShellProperties, config agentContracts.Configuration) string {
+	if shellProps.Windows.RunAsElevated {
+		return ""
+	}
+	if config.RunAsEnabled {
+		return strings.TrimSpace(config.RunAsUser)
+	}
+	return appconfig.DefaultRunAsUserName
+}
+
+// sessionCommands returns the commands of the session document
+func sessionCommands(shellProps mgsContracts.ShellProperties) string {
+	return shellProps.Windows.Commands
+}
+
+// withSessionCommands returns the shell properties running the commands instead of an interactive shell
+func withSessionCommands(shellProps mgsContracts.ShellProperties, commands string) mgsContracts.ShellProperties {
+	shellProps.Windows.Commands = commands
+	return shellProps
+}
+
+// configuredShells returns the shells configured for sessions in order of preference
+func configuredShells(mgs appconfig.MgsConfig) []string {
+	return mgs.SessionShellsWindows
+}
+
+//StartPty starts the shell on a pseudo console and provides handles to stdin and stdout.
 // isSessionLogger determines whether its a customer shell or shell used for logging.
+// shell is the shell started for the customer, powershell is used when it is empty.
 func StartPty(
 	log log.T,
 	shellProps mgsContracts.ShellProperties,
 	isSessionLogger bool,
-	config agentContracts.Configuration) (stdin *os.File, stdout *os.File, err error) {
-	log.Info("Starting winpty")
-	if _, err := os.Stat(winptyDllFilePath); os.IsNotExist(err) {
-		return nil, nil, fmt.Errorf("Missing %s file.", winptyDllFilePath)
+	config agentContracts.Configuration,
+	shell string) (stdin *os.File, stdout *os.File, err error) {
+	log.Info("Starting pty")
+	finalCmd := winptyCmd
+	commandSeparator := " "
+	if shell != "" && !isSessionLogger {
+		finalCmd = syscall.EscapeArg(shell)
+		// configured shells such as pwsh run their first argument as a script file unless -Command is given
+		commandSeparator = " -Command "
+	}
+	if strings.TrimSpace(shellProps.Windows.Commands) != "" && !isSessionLogger {
+		finalCmd = finalCmd + commandSeparator + shellProps.Windows.Commands
 	}
 
-	var finalCmd string
-	if strings.TrimSpace(shellProps.Windows.Commands) == "" || isSessionLogger {
-		finalCmd = winptyCmd
-	} else {
-		finalCmd = winptyCmd + " " + shellProps.Windows.Commands
+	var env []string
+	if !isSessionLogger {
+		env = environmentVariables(config.EnvironmentVariables)
 	}
 
-	if !shellProps.Windows.RunAsElevated && !isSessionLogger {
+	if !shellProps.Windows.RunAsElevated && !isSessionLogger && config.RunAsEnabled {
+		if strings.TrimSpace(config.RunAsUser) == "" {
+			return nil, nil, errors.New("please set the RunAs default user")
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err = startPtyAsRunAsUser(log, config.RunAsUser, finalCmd, env)
+		}()
+		wg.Wait()
+	} else if !shellProps.Windows.RunAsElevated && !isSessionLogger {
+		defaultUserEnabled = true
+
 		// Reset password for default ssm user
 		var newPassword string
 		newPassword, err = u.GeneratePasswordForDefaultUser()
@@ -115,99 +187,160 @@ func StartPty(
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			err = startPtyAsUser(log, appconfig.DefaultRunAsUserName, newPassword, finalCmd)
+			err = startPtyAsUser(log, appconfig.DefaultRunAsUserName, newPassword, finalCmd, env)
 		}()
 		wg.Wait()
+	} else if isSessionLogger {
+		// the output of the session logger shell is never read, which blocks a pseudo console once its output pipe is full
+		pty, err = startWinpty(log, finalCmd, env, 0)
 	} else {
-		pty, err = winpty.Start(winptyDllFilePath, finalCmd, defaultConsoleCol, defaultConsoleRow, winpty.DEFAULT_WINPTY_FLAGS)
+		pty, err = startConsole(log, finalCmd, env, 0)
 	}
 
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return pty.StdIn, pty.StdOut, err
+	stdin, stdout = pty.Stdio()
+	return stdin, stdout, err
 }
 
-//Stop closes winpty process handle and stdin/stdout.
-func Stop(log log.T) (err error) {
-	log.Info("Stopping winpty")
-	if err = pty.Close(); err != nil {
-		return fmt.Errorf("Stop winpty failed: %s", err)
+// startConsole starts the command on a ConPTY pseudo console when the OS supports it (Windows Server 2019 and later),
+// and falls back to winpty otherwise or when ConPTY fails to start.
+// The process runs as the user of token, or as the agent if token is zero.
+func startConsole(log log.T, cmd string, env []string, token syscall.Handle) (pseudoConsole, error) {
+	if conpty.IsAvailable() {
+		console, err := conpty.Start(cmd, env, defaultConsoleCol, defaultConsoleRow, token)
+		if err == nil {
+			log.Debug("Started pty with ConPTY")
+			return console, nil
+		}
+		log.Warnf("Failed to start pty with ConPTY, falling back to winpty: %v", err)
 	}
-
-	log.Debugf("Disabling ssm-user")
-	u.DisableLocalUser(log)
-	return nil
+	return startWinpty(log, cmd, env, token)
 }
 
-//SetSize sets size of console terminal window.
-func SetSize(log log.T, ws_col, ws_row uint32) (err error) {
-	if err = pty.SetSize(ws_col, ws_row); err != nil {
-		return fmt.Errorf("Set winpty size failed: %s", err)
+// startWinpty starts the command on a winpty console, impersonating the user of token if it is not zero.
+func startWinpty(log log.T, cmd string, env []string, token syscall.Handle) (pseudoConsole, error) {
+	if _, err := os.Stat(winptyDllFilePath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("Missing %s file.", winptyDllFilePath)
 	}
 
-	return nil
-}
+	if token == 0 {
+		return checkWinpty(winpty.Start(winptyDllFilePath, cmd, env, defaultConsoleCol, defaultConsoleRow, winpty.DEFAULT_WINPTY_FLAGS))
+	}
 
-//startPtyAsUser starts a winpty process in runas user context.
-func startPtyAsUser(log log.T, user string, pass string, shellCmd string) (err error) {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
 
-	log.Debugf("Impersonating %s", appconfig.DefaultRunAsUserName)
-	if err = impersonate(log, user, pass); err != nil {
-		log.Error(err)
-		return
+	if rc, _, ec := syscall.Syscall(impersonateProc.Addr(), 1, uintptr(token), 0, 0); rc == 0 {
+		return nil, error(ec)
 	}
+	defer func() {
+		if revertErr := revertToSelf(); revertErr != nil {
+			log.Error(revertErr)
+			return
+		}
+		log.Debug("Reverted to system profile.")
+	}()
 
 	// Start Winpty under the user context thread.
-	if pty, err = winpty.Start(winptyDllFilePath, shellCmd, defaultConsoleCol, defaultConsoleRow, winpty.WINPTY_FLAG_IMPERSONATE_THREAD); err != nil {
-		log.Error(err)
-		return
+	return checkWinpty(winpty.Start(winptyDllFilePath, cmd, env, defaultConsoleCol, defaultConsoleRow, winpty.WINPTY_FLAG_IMPERSONATE_THREAD))
+}
+
+// checkWinpty avoids returning a nil winpty as a non nil pseudoConsole
+func checkWinpty(console *winpty.WinPTY, err error) (pseudoConsole, error) {
+	if err != nil {
+		return nil, err
 	}
+	return console, nil
+}
 
-	if err = revertToSelf(); err != nil {
-		log.Error(err)
-		return
+// ctrlCCharacter is translated by the pseudo console into a CTRL_C_EVENT for the console processes
+const ctrlCCharacter = "\x03"
+
+// SendSignal delivers the signal to the console processes of the session.
+// Windows consoles only have an equivalent for SIGINT.
+func SendSignal(log log.T, name string) error {
+	if name != mgsContracts.SignalInterrupt {
+		return fmt.Errorf("unsupported signal %s", name)
+	}
+	if pty == nil {
+		return errors.New("pty is not started")
 	}
-	log.Debug("Reverted to system profile.")
+	stdin, _ := pty.Stdio()
+	_, err := stdin.Write([]byte(ctrlCCharacter))
+	return err
+}
 
-	return
+// Terminate closes the console of the session, which terminates the shell and the processes attached to the console.
+func Terminate(log log.T) error {
+	if pty == nil {
+		return errors.New("pty is not started")
+	}
+	log.Debugf("Closing pty console")
+	return pty.Close()
 }
 
-//impersonate attempts to impersonate the user.
-func impersonate(log log.T, user string, pass string) error {
-	token, err := logonUser(user, pass)
-	if err != nil {
-		return err
+//Stop closes the pseudo console and stdin/stdout.
+func Stop(log log.T) (err error) {
+	log.Info("Stopping pty")
+	if pty != nil {
+		if err = pty.Close(); err != nil {
+			return fmt.Errorf("Stop pty failed: %s", err)
+		}
 	}
-	defer mustCloseHandle(log, token)
 
-	if rc, _, ec := syscall.Syscall(impersonateProc.Addr(), 1, uintptr(token), 0, 0); rc == 0 {
-		return error(ec)
+	if defaultUserEnabled {
+		log.Debugf("Disabling ssm-user")
+		u.DisableLocalUser(log)
 	}
 	return nil
 }
 
-//logonUser attempts to log a user on to the local computer to generate a token.
-func logonUser(user, pass string) (token syscall.Handle, err error) {
-	// ".\0" meaning "this computer:
-	domain := [2]uint16{uint16('.'), 0}
+//SetSize sets size of console terminal window.
+func SetSize(log log.T, ws_col, ws_row uint32) (err error) {
+	if err = pty.SetSize(ws_col, ws_row); err != nil {
+		return fmt.Errorf("Set pty size failed: %s", err)
+	}
+
+	return nil
+}
+
+//startPtyAsUser starts a pty process in runas user context.
+func startPtyAsUser(log log.T, user string, pass string, shellCmd string, env []string) (err error) {
+	log.Debugf("Logging on %s", user)
+	token, err := logonUserWithType(user, localDomainName, pass, logon32LogonNetwork)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	defer mustCloseHandle(log, token)
+
+	if pty, err = startConsole(log, shellCmd, env, token); err != nil {
+		log.Error(err)
+	}
+	return
+}
 
-	var pu, pp []uint16
+//logonUserWithType attempts to log a user on to generate a token, the domain "." meaning this computer.
+func logonUserWithType(user, domain, pass string, logonType uintptr) (token syscall.Handle, err error) {
+	var pu, pd, pp []uint16
 	if pu, err = syscall.UTF16FromString(user); err != nil {
 		return
 	}
+	if pd, err = syscall.UTF16FromString(domain); err != nil {
+		return
+	}
 	if pp, err = syscall.UTF16FromString(pass); err != nil {
 		return
 	}
 
 	if rc, _, ec := syscall.Syscall6(logonProc.Addr(), 6,
 		uintptr(unsafe.Pointer(&pu[0])),
-		uintptr(unsafe.Pointer(&domain[0])),
+		uintptr(unsafe.Pointer(&pd[0])),
 		uintptr(unsafe.Pointer(&pp[0])),
-		logon32LogonNetwork,
+		logonType,
 		logon32ProviderDefault,
 		uintptr(unsafe.Pointer(&token))); rc == 0 {
 		err = error(ec)
@@ -276,7 +409,7 @@ func generateTranscriptFile(
 	loggerFile string,
 	enableVirtualTerminalProcessingForWindows bool,
 	config agentContracts.Configuration) error {
-	shadowShellInput, _, err := StartPty(log, mgsContracts.ShellProperties{}, true, config)
+	shadowShellInput, _, err := StartPty(log, mgsContracts.ShellProperties{}, true, config, "")
 	if err != nil {
 		return err
 	}
@@ -377,6 +510,10 @@ func (p *ShellPlugin) InputStreamMessageHandler(log log.T, streamDataMessage mgs
 	switch mgsContracts.PayloadType(streamDataMessage.PayloadType) {
 	case mgsContracts.Output:
 		log.Tracef("Output message received: %d", streamDataMessage.SequenceNumber)
+		p.updateLastInputTime()
+		if !p.limitInput(log, streamDataMessage.Payload) {
+			return nil
+		}
 
 		// deal with powershell nextline issue https://github.com/lzybkr/PSReadLine/issues/579
 		payloadString := string(streamDataMessage.Payload)
@@ -392,6 +529,8 @@ func (p *ShellPlugin) InputStreamMessageHandler(log log.T, streamDataMessage mgs
 			log.Errorf("Unable to write to stdin, err: %v.", err)
 			return err
 		}
+		p.recordInput(log, []byte(payloadString))
+		p.digestInput(log, []byte(payloadString))
 	case mgsContracts.Size:
 		var size mgsContracts.SizeData
 		if err := json.Unmarshal(streamDataMessage.Payload, &size); err != nil {
@@ -399,10 +538,13 @@ func (p *ShellPlugin) InputStreamMessageHandler(log log.T, streamDataMessage mgs
 			return err
 		}
 		log.Tracef("Resize data received: cols: %d, rows: %d", size.Cols, size.Rows)
-		if err := SetSize(log, size.Cols, size.Rows); err != nil {
+		if err := p.processResize(log, size); err != nil {
 			log.Errorf("Unable to set pty size: %s", err)
 			return err
 		}
+	case mgsContracts.Signal:
+		log.Tracef("Signal message received: %d", streamDataMessage.SequenceNumber)
+		return p.processSignal(log, streamDataMessage.Payload)
 	}
 	return nil
 }
@@ -0,0 +1,129 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package shell
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// maxTranscriptLineSize is the size after which an unterminated line of output is written to the transcript
+const maxTranscriptLineSize = 4096
+
+// terminal escape sequence parsing states of the transcript writer, in addition to those of the line editor
+const (
+	escapeCharset = iota + escapeSequence + 1
+	escapeOsc
+	escapeOscEnd
+)
+
+// transcriptWriter writes the plain text of the terminal output, as shown to the user, to the transcript streamed to CloudWatch.
+// Escape sequences and control characters are removed and backspaces erase the last character of the current line.
+type transcriptWriter struct {
+	writer      io.Writer
+	line        []byte
+	escapeState int
+}
+
+// newTranscriptWriter creates a transcriptWriter writing the plain text of the output to writer
+func newTranscriptWriter(writer io.Writer) *transcriptWriter {
+	return &transcriptWriter{writer: writer}
+}
+
+// Write writes the plain text of the complete lines of output, keeping the last line until it is complete
+func (t *transcriptWriter) Write(output []byte) (int, error) {
+	for _, b := range output {
+		if !t.endsLine(b) {
+			continue
+		}
+		if err := t.flush(); err != nil {
+			return 0, err
+		}
+	}
+	if len(t.line) >= maxTranscriptLineSize {
+		if err := t.flush(); err != nil {
+			return 0, err
+		}
+	}
+	return len(output), nil
+}
+
+// Close writes the last line of output even if it is not complete
+func (t *transcriptWriter) Close() error {
+	return t.flush()
+}
+
+// endsLine applies the output byte to the current line and returns whether it completes the line
+func (t *transcriptWriter) endsLine(b byte) bool {
+	switch t.escapeState {
+	case escapeStarted:
+		switch b {
+		case '[':
+			t.escapeState = escapeSequence
+		case ']':
+			t.escapeState = escapeOsc
+		case '(', ')':
+			t.escapeState = escapeCharset
+		default:
+			t.escapeState = escapeNone
+		}
+		return false
+	case escapeSequence:
+		// control sequences such as colors and cursor movements end with a byte in the range @ to ~
+		if b >= 0x40 && b <= 0x7e {
+			t.escapeState = escapeNone
+		}
+		return false
+	case escapeCharset:
+		t.escapeState = escapeNone
+		return false
+	case escapeOsc:
+		// operating system commands such as window titles end with BEL or ESC \
+		if b == 0x07 {
+			t.escapeState = escapeNone
+		} else if b == escapeByte {
+			t.escapeState = escapeOscEnd
+		}
+		return false
+	case escapeOscEnd:
+		t.escapeState = escapeNone
+		return false
+	}
+
+	switch {
+	case b == '\n':
+		t.line = append(t.line, b)
+		return true
+	case b == backspaceByte || b == deleteByte:
+		if len(t.line) > 0 {
+			_, size := utf8.DecodeLastRune(t.line)
+			t.line = t.line[:len(t.line)-size]
+		}
+	case b == escapeByte:
+		t.escapeState = escapeStarted
+	case b == '\t' || b >= 0x20:
+		t.line = append(t.line, b)
+	}
+	return false
+}
+
+// flush writes the current line to the transcript
+func (t *transcriptWriter) flush() error {
+	if len(t.line) == 0 {
+		return nil
+	}
+	_, err := t.writer.Write(t.line)
+	t.line = t.line[:0]
+	return err
+}
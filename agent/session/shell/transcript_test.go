@@ -0,0 +1,65 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package shell implements session shell plugin.
+package shell
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranscriptWriter(t *testing.T) {
+	testCases := []struct {
+		name     string
+		output   []string
+		expected string
+	}{
+		{"plain lines", []string{"ls\r\n", "file1  file2\r\n"}, "ls\nfile1  file2\n"},
+		{"colors", []string{"\x1b[01;34mdir\x1b[0m\r\n"}, "dir\n"},
+		{"sequence split across writes", []string{"a\x1b[", "1;3", "1mb\r\n"}, "ab\n"},
+		{"window title", []string{"\x1b]0;user@host: ~\x07$ \x1b]2;title\x1b\\ls\r\n"}, "$ ls\n"},
+		{"charset", []string{"\x1b(Bx\r\n"}, "x\n"},
+		{"backspace", []string{"lx\b \bs\r\n"}, "ls\n"},
+		{"multibyte backspace", []string{"é\x7fe\r\n"}, "e\n"},
+		{"control characters", []string{"a\x07\tb\x00\r\n"}, "a\tb\n"},
+		{"unterminated line", []string{"$ "}, "$ "},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var transcript bytes.Buffer
+			writer := newTranscriptWriter(&transcript)
+			for _, output := range tc.output {
+				n, err := writer.Write([]byte(output))
+				assert.NoError(t, err)
+				assert.Equal(t, len(output), n)
+			}
+			assert.NoError(t, writer.Close())
+			assert.Equal(t, tc.expected, transcript.String())
+		})
+	}
+}
+
+func TestTranscriptWriterKeepsIncompleteLine(t *testing.T) {
+	var transcript bytes.Buffer
+	writer := newTranscriptWriter(&transcript)
+
+	writer.Write([]byte("line\r\n$ "))
+	assert.Equal(t, "line\n", transcript.String())
+
+	writer.Write([]byte(strings.Repeat("a", maxTranscriptLineSize)))
+	assert.Equal(t, "line\n$ "+strings.Repeat("a", maxTranscriptLineSize), transcript.String())
+}
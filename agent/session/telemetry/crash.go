@@ -0,0 +1,114 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+// crashFolderName is the folder within the telemetry directory holding the crash reports of session plugins
+const crashFolderName = "crashes"
+
+// PluginCrash describes a panic recovered in the plugin of a session.
+type PluginCrash struct {
+	SessionId  string `json:"SessionId"`
+	PluginName string `json:"PluginName"`
+	Message    string `json:"Message"`
+	StackTrace string `json:"StackTrace"`
+	Time       int64  `json:"Time"`
+}
+
+// crashRoot returns the directory holding the crash reports
+func crashRoot() string {
+	return filepath.Join(telemetryRoot, crashFolderName)
+}
+
+// RecordCrash records the panic recovered in the plugin of the session with its stack trace,
+// the agent reports it with its health. The crash is logged if it cannot be recorded.
+func RecordCrash(log log.T, sessionId string, pluginName string, msg interface{}, stackTrace []byte) {
+	crash := PluginCrash{
+		SessionId:  sessionId,
+		PluginName: pluginName,
+		Message:    fmt.Sprint(msg),
+		StackTrace: string(stackTrace),
+		Time:       time.Now().UnixNano(),
+	}
+	if err := writeCrash(crash); err != nil {
+		log.Warnf("Unable to record crash of session %s: %v", sessionId, err)
+	}
+}
+
+// writeCrash writes the crash report to a file named after the session and the time of the crash
+func writeCrash(crash PluginCrash) error {
+	root := crashRoot()
+	if err := fileutil.MakeDirs(root); err != nil {
+		return fmt.Errorf("unable to create crash report directory %s: %v", root, err)
+	}
+	content, err := json.Marshal(crash)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so that the agent never reads a partial report
+	reportPath := filepath.Join(root, crash.SessionId+"-"+strconv.FormatInt(crash.Time, 10))
+	tempPath := reportPath + tempFileExtension
+	if _, err = fileutil.WriteIntoFileWithPermissions(tempPath, string(content), appconfig.ReadWriteAccess); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, reportPath)
+}
+
+// ReadCrashes returns the crashes recorded since the last call ordered by time, deleting their reports
+// so that every crash is reported once.
+func ReadCrashes(log log.T) []PluginCrash {
+	var crashes []PluginCrash
+	root := crashRoot()
+	fileNames, err := fileutil.GetFileNames(root)
+	if err != nil {
+		return crashes
+	}
+
+	for _, fileName := range fileNames {
+		if filepath.Ext(fileName) == tempFileExtension {
+			continue
+		}
+		reportPath := filepath.Join(root, fileName)
+		content, err := fileutil.ReadAllText(reportPath)
+		os.Remove(reportPath)
+		if err != nil {
+			continue
+		}
+		var crash PluginCrash
+		if err = json.Unmarshal([]byte(content), &crash); err != nil {
+			log.Debugf("Ignoring invalid crash report %s: %v", reportPath, err)
+			continue
+		}
+		crashes = append(crashes, crash)
+	}
+
+	sort.Slice(crashes, func(i, j int) bool {
+		return crashes[i].Time < crashes[j].Time
+	})
+	return crashes
+}
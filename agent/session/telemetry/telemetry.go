@@ -0,0 +1,122 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package telemetry shares the network quality of session data channels and the crashes of session plugins with the agent.
+// Sessions run in separate worker processes, so each data channel periodically writes its quality
+// to a file named after the session that the agent reads when reporting health.
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/fileutil"
+	"github.com/aws/amazon-ssm-agent/agent/log"
+)
+
+const (
+	// ReportInterval is the interval at which data channels write their quality
+	ReportInterval = time.Minute
+
+	// staleReportAge is the age after which the report of a session whose worker exited without removing it is deleted
+	staleReportAge = 3 * ReportInterval
+
+	tempFileExtension = ".tmp"
+)
+
+var telemetryRoot = filepath.Join(appconfig.DefaultDataStorePath, "session", "telemetry")
+
+// ChannelQuality describes the network quality of the data channel of a session.
+type ChannelQuality struct {
+	SessionId                       string  `json:"SessionId"`
+	RoundTripTimeMs                 int64   `json:"RoundTripTimeMs"`
+	RoundTripTimeVariationMs        int64   `json:"RoundTripTimeVariationMs"`
+	MessagesSent                    uint64  `json:"MessagesSent"`
+	Retransmissions                 uint64  `json:"Retransmissions"`
+	MessagesReceived                uint64  `json:"MessagesReceived"`
+	OutOfOrderMessages              uint64  `json:"OutOfOrderMessages"`
+	OutOfOrderRate                  float64 `json:"OutOfOrderRate"`
+	BytesSent                       uint64  `json:"BytesSent"`
+	BytesReceived                   uint64  `json:"BytesReceived"`
+	SendThroughputBytesPerSecond    float64 `json:"SendThroughputBytesPerSecond"`
+	ReceiveThroughputBytesPerSecond float64 `json:"ReceiveThroughputBytesPerSecond"`
+	DurationMs                      int64   `json:"DurationMs"`
+}
+
+// Write records the quality of the data channel of the session, replacing its previous report.
+func Write(quality ChannelQuality) error {
+	if err := fileutil.MakeDirs(telemetryRoot); err != nil {
+		return fmt.Errorf("unable to create telemetry directory %s: %v", telemetryRoot, err)
+	}
+	content, err := json.Marshal(quality)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so that the agent never reads a partial report
+	reportPath := filepath.Join(telemetryRoot, quality.SessionId)
+	tempPath := reportPath + tempFileExtension
+	if _, err = fileutil.WriteIntoFileWithPermissions(tempPath, string(content), appconfig.ReadWriteAccess); err != nil {
+		return fmt.Errorf("unable to write telemetry of session %s: %v", quality.SessionId, err)
+	}
+	return os.Rename(tempPath, reportPath)
+}
+
+// Remove deletes the report of the session once its data channel is closed.
+func Remove(log log.T, sessionId string) {
+	if err := os.Remove(filepath.Join(telemetryRoot, sessionId)); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Unable to remove telemetry of session %s: %v", sessionId, err)
+	}
+}
+
+// ReadAll returns the quality of the data channels of active sessions ordered by session id,
+// deleting the reports of sessions that are no longer updated.
+func ReadAll(log log.T) []ChannelQuality {
+	var qualities []ChannelQuality
+	fileNames, err := fileutil.GetFileNames(telemetryRoot)
+	if err != nil {
+		return qualities
+	}
+
+	for _, fileName := range fileNames {
+		if filepath.Ext(fileName) == tempFileExtension {
+			continue
+		}
+		reportPath := filepath.Join(telemetryRoot, fileName)
+		if modificationTime, err := fileutil.GetFileModificationTime(reportPath); err != nil || time.Since(modificationTime) > staleReportAge {
+			log.Debugf("Removing stale session telemetry %s", reportPath)
+			os.Remove(reportPath)
+			continue
+		}
+		content, err := fileutil.ReadAllText(reportPath)
+		if err != nil {
+			continue
+		}
+		var quality ChannelQuality
+		if err = json.Unmarshal([]byte(content), &quality); err != nil {
+			log.Debugf("Ignoring invalid session telemetry %s: %v", reportPath, err)
+			continue
+		}
+		qualities = append(qualities, quality)
+	}
+
+	sort.Slice(qualities, func(i, j int) bool {
+		return qualities[i].SessionId < qualities[j].SessionId
+	})
+	return qualities
+}
@@ -0,0 +1,89 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package telemetry shares the network quality of session data channels with the agent.
+package telemetry
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ssm-agent/agent/log"
+	"github.com/stretchr/testify/assert"
+)
+
+var mockLog = log.NewMockLog()
+
+func setupTelemetryRoot(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "telemetry")
+	assert.NoError(t, err)
+	telemetryRoot = dir
+	return func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestWriteAndReadAll(t *testing.T) {
+	defer setupTelemetryRoot(t)()
+
+	assert.NoError(t, Write(ChannelQuality{SessionId: "session2", Retransmissions: 3}))
+	assert.NoError(t, Write(ChannelQuality{SessionId: "session1", RoundTripTimeMs: 40}))
+	assert.NoError(t, Write(ChannelQuality{SessionId: "session1", RoundTripTimeMs: 50}))
+
+	qualities := ReadAll(mockLog)
+	assert.Equal(t, []ChannelQuality{
+		{SessionId: "session1", RoundTripTimeMs: 50},
+		{SessionId: "session2", Retransmissions: 3},
+	}, qualities)
+
+	Remove(mockLog, "session1")
+	qualities = ReadAll(mockLog)
+	assert.Equal(t, 1, len(qualities))
+	assert.Equal(t, "session2", qualities[0].SessionId)
+}
+
+func TestReadAllRemovesStaleReports(t *testing.T) {
+	defer setupTelemetryRoot(t)()
+
+	assert.NoError(t, Write(ChannelQuality{SessionId: "staleSession"}))
+	stalePath := filepath.Join(telemetryRoot, "staleSession")
+	staleTime := time.Now().Add(-2 * staleReportAge)
+	assert.NoError(t, os.Chtimes(stalePath, staleTime, staleTime))
+
+	assert.Empty(t, ReadAll(mockLog))
+	_, err := os.Stat(stalePath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRecordAndReadCrashes(t *testing.T) {
+	defer setupTelemetryRoot(t)()
+
+	RecordCrash(mockLog, "session1", "Standard_Stream", "first failure", []byte("stack1"))
+	RecordCrash(mockLog, "session2", "Port", fmt.Errorf("second failure"), []byte("stack2"))
+
+	crashes := ReadCrashes(mockLog)
+	assert.Equal(t, 2, len(crashes))
+	assert.Equal(t, "session1", crashes[0].SessionId)
+	assert.Equal(t, "Standard_Stream", crashes[0].PluginName)
+	assert.Equal(t, "first failure", crashes[0].Message)
+	assert.Equal(t, "stack1", crashes[0].StackTrace)
+	assert.Equal(t, "second failure", crashes[1].Message)
+
+	// crashes are reported once and are not mistaken for channel quality reports
+	assert.Empty(t, ReadCrashes(mockLog))
+	assert.Empty(t, ReadAll(mockLog))
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// utility package implements all the shared methods between clients.
+package utility
+
+import (
+	"fmt"
+	"os/user"
+	"strings"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+)
+
+// LookupUserGroups returns the names of the groups the user is a member of
+var LookupUserGroups = func(userName string) (groups []string, err error) {
+	var u *user.User
+	if u, err = user.Lookup(userName); err != nil {
+		return nil, err
+	}
+	var groupIds []string
+	if groupIds, err = u.GroupIds(); err != nil {
+		return nil, err
+	}
+	for _, groupId := range groupIds {
+		if group, err := user.LookupGroupId(groupId); err == nil {
+			groups = append(groups, group.Name)
+		}
+	}
+	return groups, nil
+}
+
+// CheckRunAsPolicy returns an error if the configured runAs policy does not permit sessions to run as the user.
+// Denied users and groups take precedence; if any allowed users or groups are configured the user must match one of them.
+func CheckRunAsPolicy(mgsConfig appconfig.MgsConfig, userName string) error {
+	if containsName(mgsConfig.SessionRunAsDeniedUsers, userName) {
+		return fmt.Errorf("sessions are not permitted to run as user %s", userName)
+	}
+
+	var groups []string
+	if len(mgsConfig.SessionRunAsDeniedGroups) > 0 || len(mgsConfig.SessionRunAsAllowedGroups) > 0 {
+		var err error
+		if groups, err = LookupUserGroups(userName); err != nil {
+			return fmt.Errorf("unable to retrieve the groups of user %s to check the runAs policy: %v", userName, err)
+		}
+	}
+	for _, group := range groups {
+		if containsName(mgsConfig.SessionRunAsDeniedGroups, group) {
+			return fmt.Errorf("sessions are not permitted to run as members of group %s", group)
+		}
+	}
+
+	if len(mgsConfig.SessionRunAsAllowedUsers) == 0 && len(mgsConfig.SessionRunAsAllowedGroups) == 0 {
+		return nil
+	}
+	if containsName(mgsConfig.SessionRunAsAllowedUsers, userName) {
+		return nil
+	}
+	for _, group := range groups {
+		if containsName(mgsConfig.SessionRunAsAllowedGroups, group) {
+			return nil
+		}
+	}
+	return fmt.Errorf("sessions are not permitted to run as user %s", userName)
+}
+
+// containsName returns true if the list contains the name, ignoring case
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(strings.TrimSpace(n), name) {
+			return true
+		}
+	}
+	return false
+}
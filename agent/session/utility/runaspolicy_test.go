@@ -0,0 +1,69 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// utility package implements all the shared methods between clients.
+package utility
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckRunAsPolicy(t *testing.T) {
+	lookupUserGroups := LookupUserGroups
+	defer func() { LookupUserGroups = lookupUserGroups }()
+	LookupUserGroups = func(userName string) ([]string, error) {
+		switch userName {
+		case "dev":
+			return []string{"developers"}, nil
+		case "contractor":
+			return []string{"developers", "contractors"}, nil
+		}
+		return nil, nil
+	}
+
+	testCases := []struct {
+		name      string
+		mgsConfig appconfig.MgsConfig
+		user      string
+		allowed   bool
+	}{
+		{"no policy", appconfig.MgsConfig{}, "root", true},
+		{"denied user", appconfig.MgsConfig{SessionRunAsDeniedUsers: []string{"Root"}}, "root", false},
+		{"allowed user", appconfig.MgsConfig{SessionRunAsAllowedUsers: []string{"ssm-user"}}, "ssm-user", true},
+		{"user not allowed", appconfig.MgsConfig{SessionRunAsAllowedUsers: []string{"ssm-user"}}, "root", false},
+		{"allowed group", appconfig.MgsConfig{SessionRunAsAllowedGroups: []string{"developers"}}, "dev", true},
+		{"group not allowed", appconfig.MgsConfig{SessionRunAsAllowedGroups: []string{"developers"}}, "root", false},
+		{"denied group wins", appconfig.MgsConfig{
+			SessionRunAsAllowedGroups: []string{"developers"},
+			SessionRunAsDeniedGroups:  []string{"contractors"}}, "contractor", false},
+	}
+	for _, tc := range testCases {
+		err := CheckRunAsPolicy(tc.mgsConfig, tc.user)
+		assert.Equal(t, tc.allowed, err == nil, tc.name)
+	}
+}
+
+func TestCheckRunAsPolicyGroupLookupFailure(t *testing.T) {
+	lookupUserGroups := LookupUserGroups
+	defer func() { LookupUserGroups = lookupUserGroups }()
+	LookupUserGroups = func(userName string) ([]string, error) {
+		return nil, errors.New("unknown user")
+	}
+
+	err := CheckRunAsPolicy(appconfig.MgsConfig{SessionRunAsAllowedGroups: []string{"developers"}}, "dev")
+	assert.Error(t, err)
+}
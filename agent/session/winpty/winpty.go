@@ -19,6 +19,7 @@ package winpty
 import (
 	"fmt"
 	"os"
+	"runtime"
 	"strings"
 	"syscall"
 	"unicode/utf16"
@@ -44,8 +45,9 @@ type WinPTY struct {
 	closed        bool
 }
 
-//Start launches winpty agent as a separate process
-func Start(winptyDllFilePath, cmdLine string, window_size_cols, window_size_rows uint32, winptyFlag int32) (*WinPTY, error) {
+//Start launches winpty agent as a separate process.
+// env holds NAME=value pairs added to the environment of the process, nil inherits the agent environment.
+func Start(winptyDllFilePath, cmdLine string, env []string, window_size_cols, window_size_rows uint32, winptyFlag int32) (*WinPTY, error) {
 
 	var winpty WinPTY = WinPTY{}
 
@@ -64,7 +66,7 @@ func Start(winptyDllFilePath, cmdLine string, window_size_cols, window_size_rows
 		return nil, err
 	}
 
-	if err := winpty.spawnProcess(cmdLine); err != nil {
+	if err := winpty.spawnProcess(cmdLine, env); err != nil {
 		return nil, err
 	}
 
@@ -163,7 +165,7 @@ func (winpty *WinPTY) getIOPipes() (err error) {
 }
 
 //spawnProcess creates a new winpty agent process.
-func (winpty *WinPTY) spawnProcess(cmdLine string) (err error) {
+func (winpty *WinPTY) spawnProcess(cmdLine string, env []string) (err error) {
 	var errorPtr uintptr
 	defer winpty_error_free.Call(errorPtr)
 
@@ -172,13 +174,21 @@ func (winpty *WinPTY) spawnProcess(cmdLine string) (err error) {
 		return fmt.Errorf("Failed to convert cmd to pointer. %s", err)
 	}
 
+	var envBlock []uint16
+	var envBlockPtr uintptr
+	if len(env) > 0 {
+		envBlock = CreateEnvironmentBlock(append(os.Environ(), env...))
+		envBlockPtr = uintptr(unsafe.Pointer(&envBlock[0]))
+	}
+
 	spawnConfig, _, lastErr := winpty_spawn_config_new.Call(
 		uintptr(uint64(WINPTY_SPAWN_FLAG_AUTO_SHUTDOWN)),
 		uintptr(0),
 		uintptr(unsafe.Pointer(cmdLineUTF16Ptr)),
 		uintptr(0),
-		uintptr(0),
+		envBlockPtr,
 		uintptr(unsafe.Pointer(&errorPtr)))
+	runtime.KeepAlive(envBlock)
 	if spawnConfig == uintptr(NIL_POINTER_VALUE) {
 		return winpty.getFormattedErrorMessage(
 			"Unable to create process config.",
@@ -214,6 +224,40 @@ func (winpty *WinPTY) spawnProcess(cmdLine string) (err error) {
 	return nil
 }
 
+//CreateEnvironmentBlock creates a utf16 environment block of NAME=value strings each terminated by a null character
+// and followed by a final null character. Later variables replace earlier variables with the same name, ignoring case.
+func CreateEnvironmentBlock(env []string) []uint16 {
+	var names []string
+	values := make(map[string]string)
+	for _, variable := range env {
+		i := strings.Index(variable, "=")
+		if i == 0 {
+			// variables of drive current directories such as =C:=C:\ start with =
+			i = strings.Index(variable[1:], "=") + 1
+		}
+		if i <= 0 {
+			continue
+		}
+		key := strings.ToUpper(variable[:i])
+		if _, ok := values[key]; !ok {
+			names = append(names, key)
+		}
+		values[key] = variable
+	}
+
+	var block []uint16
+	for _, name := range names {
+		block = append(block, utf16.Encode([]rune(values[name]))...)
+		block = append(block, 0)
+	}
+	return append(block, 0)
+}
+
+//Stdio returns stdin and stdout of the console.
+func (winpty *WinPTY) Stdio() (stdin *os.File, stdout *os.File) {
+	return winpty.StdIn, winpty.StdOut
+}
+
 //SetSize sets given console window size.
 func (winpty *WinPTY) SetSize(ws_col, ws_row uint32) (err error) {
 	var errorPtr uintptr
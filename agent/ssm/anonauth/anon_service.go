@@ -19,6 +19,7 @@ import (
 
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/rip"
 	"github.com/aws/amazon-ssm-agent/agent/ssm/util"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -71,6 +72,9 @@ func NewAnonymousService(region string) AnonymousService {
 	// Create a session to share service client config and handlers with
 	ssmSess := session.New(awsConfig)
 	ssmSess.Handlers.Build.PushBack(request.MakeAddToUserAgentHandler(appConfig.Agent.Name, appConfig.Agent.Version))
+	if err == nil && appConfig.Ssm.Endpoint == "" {
+		rip.UseFipsEndpoint(ssmSess, region, "ssm")
+	}
 
 	ssmService := ssm.New(ssmSess)
 	return &sdkService{sdk: ssmService}
@@ -0,0 +1,37 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package anonauth
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/rip"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAnonymousServiceUsesFipsEndpoint(t *testing.T) {
+	platform.SetRegion("us-east-1")
+	fipsEnabled := rip.FipsEnabled
+	rip.FipsEnabled = func() bool { return true }
+	defer func() { rip.FipsEnabled = fipsEnabled }()
+
+	service := NewAnonymousService("us-west-2").(*sdkService)
+	assert.Equal(t, "https://ssm-fips.us-west-2.amazonaws.com", service.sdk.Endpoint)
+
+	// registrations in regions without FIPS endpoints fail instead of using the regular endpoint
+	_, err := NewAnonymousService("eu-west-1").RegisterManagedInstance("code", "id", "key", "Rsa", "fingerprint")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no FIPS endpoint")
+}
@@ -16,6 +16,7 @@ package rsaauth
 
 import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
+	"github.com/aws/amazon-ssm-agent/agent/rip"
 	"github.com/aws/amazon-ssm-agent/agent/ssm/util"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -43,10 +44,13 @@ func NewRsaService(serverId string, region string, encodedPrivateKey string) Rsa
 	awsConfig.Region = &region
 	awsConfig.Credentials = credentials.NewStaticCredentials(serverId, encodedPrivateKey, "")
 
-	appConfig, _ := appconfig.Config(false)
+	appConfig, err := appconfig.Config(false)
 	// Create a session to share service client config and handlers with
 	ssmSess, _ := session.NewSession(awsConfig)
 	ssmSess.Handlers.Build.PushBack(request.MakeAddToUserAgentHandler(appConfig.Agent.Name, appConfig.Agent.Version))
+	if err == nil && appConfig.Ssm.Endpoint == "" {
+		rip.UseFipsEndpoint(ssmSess, region, "ssm")
+	}
 
 	ssmService := ssm.New(ssmSess)
 
@@ -0,0 +1,37 @@
+// Copyright 2016 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may not
+// use this file except in compliance with the License. A copy of the
+// License is located at
+//
+// http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package rsaauth
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/rip"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRsaServiceUsesFipsEndpoint(t *testing.T) {
+	platform.SetRegion("us-east-1")
+	fipsEnabled := rip.FipsEnabled
+	rip.FipsEnabled = func() bool { return true }
+	defer func() { rip.FipsEnabled = fipsEnabled }()
+
+	service := NewRsaService("mi-1234567890abcdef0", "us-west-2", "key").(*sdkService)
+	assert.Equal(t, "https://ssm-fips.us-west-2.amazonaws.com", service.sdk.Endpoint)
+
+	// regions without FIPS endpoints fail instead of using the regular endpoint
+	_, err := NewRsaService("mi-1234567890abcdef0", "eu-west-1", "key").RequestManagedInstanceRoleToken("fingerprint")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no FIPS endpoint")
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/appconfig"
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/platform"
+	"github.com/aws/amazon-ssm-agent/agent/rip"
 	"github.com/aws/amazon-ssm-agent/agent/sdkutil"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/request"
@@ -89,6 +90,7 @@ func NewService() Service {
 	}
 
 	awsConfig := sdkutil.AwsConfig()
+	var fipsErr error
 	// parse appConfig overrides
 	appConfig, err := appconfig.Config(false)
 	if err == nil {
@@ -96,7 +98,12 @@ func NewService() Service {
 			awsConfig.Endpoint = &appConfig.Ssm.Endpoint
 		} else {
 			if region, err := platform.Region(); err == nil {
-				if defaultEndpoint := platform.GetDefaultEndPoint(region, "ssm"); defaultEndpoint != "" {
+				if rip.FipsEnabled() {
+					var fipsEndpoint string
+					if fipsEndpoint, fipsErr = rip.GetFipsEndpoint(region, "ssm"); fipsErr == nil {
+						awsConfig.Endpoint = &fipsEndpoint
+					}
+				} else if defaultEndpoint := platform.GetDefaultEndPoint(region, "ssm"); defaultEndpoint != "" {
 					awsConfig.Endpoint = &defaultEndpoint
 				}
 			}
@@ -116,6 +123,9 @@ func NewService() Service {
 	}
 	sess := session.New(awsConfig)
 	sess.Handlers.Build.PushBack(request.MakeAddToUserAgentHandler(appConfig.Agent.Name, appConfig.Agent.Version))
+	if fipsErr != nil {
+		sess.Handlers.Validate.PushBackNamed(rip.FipsEndpointUnavailableHandler(fipsErr))
+	}
 
 	ssmService := ssm.New(sess)
 	return NewSSMService(ssmService)
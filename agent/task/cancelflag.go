@@ -60,12 +60,30 @@ type CancelFlag interface {
 	Wait() (state State)
 }
 
+// StepCancelFlag is a CancelFlag which can also cancel single steps of its job.
+// The job keeps running the steps after a canceled step, so cleanup steps still run.
+type StepCancelFlag interface {
+	CancelFlag
+
+	// CancelStep cancels the step with the given id. A step which has not started yet is canceled as soon as it starts.
+	CancelStep(stepID string)
+
+	// StepFlag returns the cancel flag passed to the step, which is canceled when either the step or the job is canceled.
+	StepFlag(stepID string) CancelFlag
+
+	// NotifyStepCanceled calls the handler with the id of each canceled step, including the steps canceled before.
+	NotifyStepCanceled(handler func(stepID string))
+}
+
 // ChanneledCancelFlag is a default implementation of the task.CancelFlag interface.
 type ChanneledCancelFlag struct {
 	state  State
 	ch     chan struct{}
 	closed bool
 	m      sync.RWMutex
+
+	steps        map[string]*ChanneledCancelFlag
+	stepHandlers []func(stepID string)
 }
 
 // NewChanneledCancelFlag creates a new instance of ChanneledCancelFlag.
@@ -114,3 +132,95 @@ func (t *ChanneledCancelFlag) Set(state State) {
 		t.closed = true
 	}
 }
+
+// CancelStep cancels the step with the given id without canceling the job.
+func (t *ChanneledCancelFlag) CancelStep(stepID string) {
+	t.m.Lock()
+	step := t.stepFlag(stepID)
+	handlers := append([]func(string){}, t.stepHandlers...)
+	t.m.Unlock()
+
+	step.Set(Canceled)
+	for _, handler := range handlers {
+		handler(stepID)
+	}
+}
+
+// StepFlag returns the cancel flag of the step with the given id.
+func (t *ChanneledCancelFlag) StepFlag(stepID string) CancelFlag {
+	t.m.Lock()
+	defer t.m.Unlock()
+	return &stepCancelFlag{job: t, step: t.stepFlag(stepID)}
+}
+
+// NotifyStepCanceled calls the handler with the id of each canceled step.
+func (t *ChanneledCancelFlag) NotifyStepCanceled(handler func(stepID string)) {
+	t.m.Lock()
+	t.stepHandlers = append(t.stepHandlers, handler)
+	var canceled []string
+	for stepID, step := range t.steps {
+		if step.Canceled() {
+			canceled = append(canceled, stepID)
+		}
+	}
+	t.m.Unlock()
+
+	for _, stepID := range canceled {
+		handler(stepID)
+	}
+}
+
+// stepFlag returns the flag of the step, creating it if the step was neither started nor canceled yet.
+// The caller must hold the lock of the job flag.
+func (t *ChanneledCancelFlag) stepFlag(stepID string) *ChanneledCancelFlag {
+	if t.steps == nil {
+		t.steps = make(map[string]*ChanneledCancelFlag)
+	}
+	step, found := t.steps[stepID]
+	if !found {
+		step = NewChanneledCancelFlag()
+		t.steps[stepID] = step
+	}
+	return step
+}
+
+// stepCancelFlag is the cancel flag of a step, it is canceled when either the step or its job is canceled.
+type stepCancelFlag struct {
+	job  *ChanneledCancelFlag
+	step *ChanneledCancelFlag
+}
+
+// Canceled returns true if either the step or the job has been canceled.
+func (f *stepCancelFlag) Canceled() bool {
+	return f.job.Canceled() || (f.step.Canceled() && !f.job.ShutDown())
+}
+
+// ShutDown returns true if the job has been shut down.
+func (f *stepCancelFlag) ShutDown() bool {
+	return f.job.ShutDown()
+}
+
+// State returns the state of the job when it was canceled or shut down, and the state of the step otherwise.
+func (f *stepCancelFlag) State() State {
+	if state := f.job.State(); state == Canceled || state == ShutDown {
+		return state
+	}
+	if state := f.step.State(); state != 0 {
+		return state
+	}
+	return f.job.State()
+}
+
+// Set sets the state of the step.
+func (f *stepCancelFlag) Set(state State) {
+	f.step.Set(state)
+}
+
+// Wait blocks until either the flag of the step or the flag of the job is set. Returns the state.
+func (f *stepCancelFlag) Wait() (state State) {
+	select {
+	case <-f.job.ch:
+	case <-f.step.ch:
+	}
+	return f.State()
+}
@@ -14,6 +14,7 @@
 package task
 
 import (
+	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -67,3 +68,53 @@ func testWait(t *testing.T, state State) {
 	assert.Equal(t, state, <-ch)
 	assert.Equal(t, flag.Canceled(), state == Canceled)
 }
+
+// TestCancelStep tests that canceling a step cancels only the flag of that step
+func TestCancelStep(t *testing.T) {
+	job := NewChanneledCancelFlag()
+	var notified []string
+	job.NotifyStepCanceled(func(stepID string) { notified = append(notified, stepID) })
+
+	stuck := job.StepFlag("stuck")
+	cleanup := job.StepFlag("cleanup")
+	job.CancelStep("stuck")
+
+	assert.True(t, stuck.Canceled())
+	assert.Equal(t, Canceled, stuck.State())
+	assert.Equal(t, Canceled, stuck.Wait())
+	assert.False(t, cleanup.Canceled())
+	assert.False(t, job.Canceled())
+	assert.Equal(t, []string{"stuck"}, notified)
+
+	// a step canceled before it starts is canceled as soon as it gets its flag
+	job.CancelStep("pending")
+	assert.True(t, job.StepFlag("pending").Canceled())
+
+	// handlers registered late are told about the steps canceled before
+	var lateNotified []string
+	job.NotifyStepCanceled(func(stepID string) { lateNotified = append(lateNotified, stepID) })
+	sort.Strings(lateNotified)
+	assert.Equal(t, []string{"pending", "stuck"}, lateNotified)
+}
+
+// TestStepFlagFollowsJob tests that the flags of the steps are canceled and shut down with their job
+func TestStepFlagFollowsJob(t *testing.T) {
+	job := NewChanneledCancelFlag()
+	step := job.StepFlag("step")
+
+	ch := make(chan State)
+	go func() {
+		ch <- step.Wait()
+	}()
+	job.Set(Canceled)
+	assert.Equal(t, Canceled, <-ch)
+	assert.True(t, step.Canceled())
+
+	job = NewChanneledCancelFlag()
+	step = job.StepFlag("step")
+	job.CancelStep("step")
+	job.Set(ShutDown)
+	assert.True(t, step.ShutDown())
+	assert.False(t, step.Canceled())
+	assert.Equal(t, ShutDown, step.State())
+}
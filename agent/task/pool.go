@@ -35,6 +35,10 @@ type Pool interface {
 	// Returns true if the job has been found and canceled, false if the job was not found.
 	Cancel(jobID string) bool
 
+	// CancelStep cancels a single step of the given job, the job keeps running the steps after it.
+	// Returns true if the job has been found, false if the job was not found.
+	CancelStep(jobID string, stepID string) bool
+
 	// Shutdown cancels all the jobs and shuts down the workers.
 	Shutdown()
 
@@ -203,6 +207,17 @@ func (p *pool) Cancel(jobID string) (canceled bool) {
 	return true
 }
 
+// CancelStep cancels the step of the job with the given id.
+func (p *pool) CancelStep(jobID string, stepID string) (canceled bool) {
+	jobToken, found := p.jobStore.GetJob(jobID)
+	if !found {
+		return false
+	}
+
+	jobToken.cancelFlag.CancelStep(stepID)
+	return true
+}
+
 // CancelAll cancels all the running jobs.
 func (p *pool) CancelAll() {
 	// remove jobs from task and save them to a local variable
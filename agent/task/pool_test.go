@@ -22,6 +22,7 @@ import (
 	"github.com/aws/amazon-ssm-agent/agent/log"
 	"github.com/aws/amazon-ssm-agent/agent/times"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 var logger = log.NewMockLog()
@@ -119,3 +120,26 @@ func exercisePool(t *testing.T, pool Pool, jobID string, shouldCancel bool) {
 	// see that job completes
 	assert.True(t, <-jobState)
 }
+
+func TestPoolCancelStep(t *testing.T) {
+	clock := times.NewMockedClock()
+	waitTimeout := 100 * time.Millisecond
+	shutdownTimeout := 10000 * time.Millisecond
+	clock.On("After", mock.Anything).Return(clock.AfterChannel)
+
+	pool := NewPool(logger, 1, waitTimeout, clock)
+	stepCanceled := make(chan bool)
+	err := pool.Submit(logger, "job", func(cancelFlag CancelFlag) {
+		stepFlag := cancelFlag.(StepCancelFlag).StepFlag("step")
+		stepCanceled <- Canceled == stepFlag.Wait()
+		stepCanceled <- cancelFlag.Canceled()
+	})
+	assert.Nil(t, err)
+
+	assert.False(t, pool.CancelStep("unknown", "step"))
+	assert.True(t, pool.CancelStep("job", "step"))
+	assert.True(t, <-stepCanceled)
+	// the job itself keeps running
+	assert.False(t, <-stepCanceled)
+	assert.True(t, pool.ShutdownAndWait(shutdownTimeout))
+}
@@ -38,6 +38,11 @@ func (mockPool *MockedPool) Cancel(jobID string) bool {
 	return mockPool.Called(jobID).Bool(0)
 }
 
+// CancelStep mocks the method with the same name.
+func (mockPool *MockedPool) CancelStep(jobID string, stepID string) bool {
+	return mockPool.Called(jobID, stepID).Bool(0)
+}
+
 // Shutdown mocks the method with the same name.
 func (mockPool *MockedPool) Shutdown() {
 	mockPool.Called()
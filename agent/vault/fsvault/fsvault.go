@@ -83,6 +83,20 @@ func Retrieve(key string) (data []byte, err error) {
 	return
 }
 
+// Exists checks whether data is stored for the key.
+func Exists(key string) (exists bool, err error) {
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err = ensureInitialized(); err != nil {
+		return
+	}
+
+	_, exists = manifest[key]
+	return
+}
+
 // Remove data.
 func Remove(key string) (err error) {
 
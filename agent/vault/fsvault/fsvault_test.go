@@ -63,6 +63,8 @@ func TestSuite(t *testing.T) {
 	retrieveErrorEnsureInitTest(t)
 	retrieveErrorFileMissingTest(t)
 	retrieveErrorReadDataTest(t)
+	exists(t)
+	existsErrorEnsureInitTest(t)
 	remove(t)
 	removeNotExists(t)
 	removeErrorEnsureInitTest(t)
@@ -192,6 +194,39 @@ func retrieveErrorNotExists(t *testing.T) {
 	reset()
 }
 
+func exists(t *testing.T) {
+	// arrange
+	initialized = true // skip initialization
+	manifest = map[string]string{key: storePath}
+
+	// act
+	found, err := Exists(key)
+	notFound, notFoundErr := Exists("other-key")
+
+	// assert
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.NoError(t, notFoundErr)
+	assert.False(t, notFound)
+
+	// clean up
+	reset()
+}
+
+func existsErrorEnsureInitTest(t *testing.T) {
+	// arrange
+	ensureInitialized = func() error { return errors.New("err") }
+
+	// act
+	_, err := Exists(key)
+
+	// assert
+	assert.Error(t, err)
+
+	// clean up
+	reset()
+}
+
 func retrieveErrorEnsureInitTest(t *testing.T) {
 	// arrange
 	ensureInitialized = func() error { return errors.New("err") }